@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// autoOrderCheckInterval is how often startAutoOrder looks for profiles due
+// for re-ordering.
+const autoOrderCheckInterval = time.Hour
+
+// startAutoOrder re-orders the providers of profiles with auto_order enabled,
+// now and every hour, until the returned func is called. Nothing is started
+// when no profile has it enabled or there is no log database.
+func startAutoOrder(logger *log.Logger) (stop func()) {
+	db := proxy.GetGlobalLogDB()
+	if db == nil || !slices.ContainsFunc(config.ListProfiles(), autoOrderEnabled) {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(autoOrderCheckInterval)
+		defer ticker.Stop()
+		for {
+			autoOrderProfiles(db, time.Now(), logger)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// autoOrderEnabled reports whether profile has auto ordering enabled.
+func autoOrderEnabled(profile string) bool {
+	pc := config.GetOwnProfileConfig(profile)
+	return pc != nil && pc.AutoOrder != nil && pc.AutoOrder.Enabled
+}
+
+// autoOrderProfiles re-orders the providers of every profile with auto
+// ordering enabled whose interval has elapsed, and saves the order if it
+// changed. Running proxies pick it up when they reload the config.
+func autoOrderProfiles(db *proxy.LogDB, now time.Time, logger *log.Logger) {
+	for _, name := range config.ListProfiles() {
+		if !autoOrderEnabled(name) {
+			continue
+		}
+		pc := config.GetOwnProfileConfig(name)
+		ao := pc.AutoOrder
+		claimed, err := db.ClaimAutoOrderRun(name, now, ao.Interval())
+		if err != nil {
+			logger.Printf("[auto-order] profile %s: %v", name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		stats, err := db.ProviderStats(now.Add(-ao.Window()))
+		if err != nil {
+			logger.Printf("[auto-order] profile %s: %v", name, err)
+			continue
+		}
+
+		order := proxy.OptimizeProviderOrder(pc.Providers, stats, ao)
+		if slices.Equal(order, pc.Providers) {
+			continue
+		}
+		logger.Printf("[auto-order] profile %s: %v -> %v", name, pc.Providers, order)
+		updated := *pc
+		updated.Providers = order
+		if err := config.SetProfileConfig(name, &updated); err != nil {
+			logger.Printf("[auto-order] profile %s: save failed: %v", name, err)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -13,13 +15,19 @@ var bindCmd = &cobra.Command{
 	Use:   "bind [profile]",
 	Short: "Bind current directory to a profile and/or CLI",
 	Long: `Bind the current directory to a profile and/or CLI.
-After binding, running 'opencc' in this directory will automatically use the bound settings.
+After binding, running 'opencc' in this directory or any of its subdirectories
+will automatically use the bound settings; the nearest bound directory wins.
+Use --exact to bind this directory only. --provider and --model pin a single
+provider and the model sent to it, without creating a profile for them.
 
 Examples:
   opencc bind work              # Bind to profile 'work'
   opencc bind --cli codex       # Bind to use Codex CLI
   opencc bind work --cli codex  # Bind to profile 'work' with Codex CLI
-  opencc bind --cli ""          # Clear CLI binding (use default)`,
+  opencc bind --cli ""          # Clear CLI binding (use default)
+  opencc bind work --exact      # Don't apply to subdirectories
+  opencc bind --provider ollama --model qwen3-coder  # Use a local model here
+  opencc bind --provider ""     # Clear the pinned provider`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runBind,
 }
@@ -40,10 +48,36 @@ var statusCmd = &cobra.Command{
 	RunE:  runStatus,
 }
 
-var bindCLI string
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Let the project file of the current directory set env vars",
+	Long: `Let the .opencc.json of the project the current directory is in set env
+vars for the CLI. Project files come with the repository, so their env is
+ignored until the project is trusted.`,
+	Args: cobra.NoArgs,
+	RunE: runTrust,
+}
+
+var untrustCmd = &cobra.Command{
+	Use:   "untrust",
+	Short: "Stop the project file of the current directory setting env vars",
+	Long:  `Stop the .opencc.json of the project the current directory is in setting env vars.`,
+	Args:  cobra.NoArgs,
+	RunE:  runUntrust,
+}
+
+var (
+	bindCLI      string
+	bindExact    bool
+	bindProvider string
+	bindModel    string
+)
 
 func init() {
 	bindCmd.Flags().StringVar(&bindCLI, "cli", "", "CLI to use (claude, codex, opencode)")
+	bindCmd.Flags().BoolVar(&bindExact, "exact", false, "apply to this directory only, not its subdirectories")
+	bindCmd.Flags().StringVar(&bindProvider, "provider", "", "use only this provider, ignoring the profile's chain and routing")
+	bindCmd.Flags().StringVar(&bindModel, "model", "", "send this model for every request")
 }
 
 func runBind(cmd *cobra.Command, args []string) error {
@@ -55,9 +89,12 @@ func runBind(cmd *cobra.Command, args []string) error {
 	// Check if --cli flag was explicitly set
 	cliSet := cmd.Flags().Changed("cli")
 
+	exactSet := cmd.Flags().Changed("exact")
+	pinSet := cmd.Flags().Changed("provider") || cmd.Flags().Changed("model")
+
 	// If neither profile nor CLI specified, show usage
-	if profile == "" && !cliSet {
-		return fmt.Errorf("specify a profile name and/or --cli flag")
+	if profile == "" && !cliSet && !exactSet && !pinSet {
+		return errors.New(i18n.T("specify a profile name and/or --cli flag"))
 	}
 
 	// Get current directory (absolute path)
@@ -78,12 +115,28 @@ func runBind(cmd *cobra.Command, args []string) error {
 		if !cliSet {
 			bindCLI = existing.CLI
 		}
+		if !cmd.Flags().Changed("provider") {
+			bindProvider = existing.Provider
+		}
+		if !cmd.Flags().Changed("model") {
+			bindModel = existing.Model
+		}
 	}
 
 	// Bind the project
 	if err := config.BindProject(cwd, profile, bindCLI); err != nil {
 		return err
 	}
+	if exactSet {
+		if err := config.SetProjectBindingExact(cwd, bindExact); err != nil {
+			return err
+		}
+	}
+	if pinSet {
+		if err := config.SetProjectBindingPin(cwd, bindProvider, bindModel); err != nil {
+			return err
+		}
+	}
 
 	// Build status message
 	var msg string
@@ -93,6 +146,17 @@ func runBind(cmd *cobra.Command, args []string) error {
 		msg = fmt.Sprintf("profile '%s'", profile)
 	} else if bindCLI != "" {
 		msg = fmt.Sprintf("CLI '%s'", bindCLI)
+	} else {
+		msg = "the default profile"
+	}
+	if bindProvider != "" {
+		msg += fmt.Sprintf(", provider '%s'", bindProvider)
+	}
+	if bindModel != "" {
+		msg += fmt.Sprintf(", model '%s'", bindModel)
+	}
+	if b := config.GetProjectBinding(cwd); b != nil && b.Exact {
+		msg += " (this directory only)"
 	}
 
 	fmt.Printf("Bound %s to %s\n", cwd, msg)
@@ -133,6 +197,39 @@ func runUnbind(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runTrust(cmd *cobra.Command, args []string) error {
+	return setProjectTrusted(true)
+}
+
+func runUntrust(cmd *cobra.Command, args []string) error {
+	return setProjectTrusted(false)
+}
+
+// setProjectTrusted records whether the project file found from the current
+// directory may set env vars.
+func setProjectTrusted(trusted bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	_, path, err := config.FindProjectConfig(filepath.Clean(cwd))
+	if path == "" {
+		return fmt.Errorf(i18n.T("no %s found in %s or its parents"), config.ProjectFile, cwd)
+	}
+	if err != nil {
+		return err
+	}
+	if err := config.SetProjectTrusted(filepath.Dir(path), trusted); err != nil {
+		return err
+	}
+	if trusted {
+		fmt.Printf("Trusted %s\n", path)
+	} else {
+		fmt.Printf("Untrusted %s\n", path)
+	}
+	return nil
+}
+
 func runStatus(cmd *cobra.Command, args []string) error {
 	// Get current directory (absolute path)
 	cwd, err := os.Getwd()
@@ -143,10 +240,22 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	// Clean the path
 	cwd = filepath.Clean(cwd)
 
-	// Check for binding
-	binding := config.GetProjectBinding(cwd)
+	// Check for binding and project file
+	binding := projectBinding(cwd)
 
 	fmt.Printf("Directory: %s\n", cwd)
+	if dir, _ := config.FindProjectBinding(cwd); dir != "" && dir != cwd {
+		fmt.Printf("Binding:   inherited from %s\n", dir)
+	}
+	if config.GetProjectFiles() != config.ProjectFilesIgnore {
+		if _, path, _ := config.FindProjectConfig(cwd); path != "" {
+			if config.IsProjectTrusted(filepath.Dir(path)) {
+				fmt.Printf("Project:   %s (trusted)\n", path)
+			} else {
+				fmt.Printf("Project:   %s\n", path)
+			}
+		}
+	}
 	if binding != nil {
 		if binding.Profile != "" {
 			fmt.Printf("Profile:   %s\n", binding.Profile)
@@ -162,6 +271,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		} else {
 			fmt.Printf("CLI:       (default)\n")
 		}
+		if binding.Provider != "" {
+			fmt.Printf("Provider:  %s (pinned)\n", binding.Provider)
+		}
+		if binding.Model != "" {
+			fmt.Printf("Model:     %s (pinned)\n", binding.Model)
+		}
 	} else {
 		fmt.Printf("Profile:   (not bound, will use default)\n")
 		fmt.Printf("CLI:       (not bound, will use default)\n")
@@ -171,9 +286,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	bindings := config.GetAllProjectBindings()
 	if len(bindings) > 0 {
 		fmt.Printf("\nAll project bindings:\n")
+		bound, _ := config.FindProjectBinding(cwd)
 		for path, b := range bindings {
 			marker := "  "
-			if path == cwd {
+			if path == bound {
 				marker = "> "
 			}
 			var info string
@@ -184,6 +300,15 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			} else if b.CLI != "" {
 				info = fmt.Sprintf("(CLI: %s)", b.CLI)
 			}
+			if b.Provider != "" {
+				info += fmt.Sprintf(" [provider: %s]", b.Provider)
+			}
+			if b.Model != "" {
+				info += fmt.Sprintf(" [model: %s]", b.Model)
+			}
+			if b.Exact {
+				info += " [exact]"
+			}
 			fmt.Printf("%s%s -> %s\n", marker, path, info)
 		}
 	}
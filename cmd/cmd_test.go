@@ -1,10 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
 )
 
 func TestCompleteConfigNames(t *testing.T) {
@@ -51,3 +61,771 @@ func TestRunCompletion(t *testing.T) {
 		})
 	}
 }
+
+func TestRunProviderCooldown(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "alpha", &config.ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok"})
+
+	t.Cleanup(func() { cooldownFor, cooldownClear = 0, false })
+	cooldownFor = 2 * time.Hour
+	if err := runProviderCooldown(providerCooldownCmd, []string{"alpha"}); err != nil {
+		t.Fatalf("runProviderCooldown() error: %v", err)
+	}
+	// A running proxy rebuilds its providers when the config file changes
+	providers, err := buildProviders([]string{"alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason := providers[0].Unavailable(time.Now()); !strings.Contains(reason, "cooling down") {
+		t.Errorf("running provider not in cooldown, reason = %q", reason)
+	}
+
+	cooldownFor, cooldownClear = 0, true
+	if err := runProviderCooldown(providerCooldownCmd, []string{"alpha"}); err != nil {
+		t.Fatalf("clear error: %v", err)
+	}
+	if providers, err = buildProviders([]string{"alpha"}); err != nil {
+		t.Fatal(err)
+	}
+	if reason := providers[0].Unavailable(time.Now()); reason != "" {
+		t.Errorf("cooldown not cleared, reason = %q", reason)
+	}
+
+	cooldownClear = false
+	if err := runProviderCooldown(providerCooldownCmd, []string{"alpha"}); err == nil {
+		t.Error("expected error without --for or --clear")
+	}
+	cooldownFor = time.Hour
+	if err := runProviderCooldown(providerCooldownCmd, []string{"missing"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestDrillProfileFailover(t *testing.T) {
+	setTestHome(t)
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": "msg_1", "type": "message", "model": req.Model})
+	}))
+	defer backup.Close()
+
+	// The primary is never contacted; its requests go to the simulated outage.
+	writeTestProvider(t, "primary", &config.ProviderConfig{
+		BaseURL: "https://primary.invalid", AuthToken: "tok",
+		ClaudeEnvVars: map[string]string{"API_TIMEOUT_MS": "600000"},
+	})
+	writeTestProvider(t, "backup", &config.ProviderConfig{
+		BaseURL: backup.URL, AuthToken: "tok", SonnetModel: "backup-sonnet",
+		ClaudeEnvVars: map[string]string{"API_TIMEOUT_MS": "30000", "DISABLE_PROMPT_CACHING": "1"},
+	})
+
+	res, err := drillProfileFailover([]string{"primary", "backup"}, nil, "claude")
+	if err != nil {
+		t.Fatalf("drillProfileFailover() error: %v", err)
+	}
+	if res.ServedBy != "backup" || res.Status != http.StatusOK {
+		t.Fatalf("served by %q (%d), want backup (200); trace:\n%s", res.ServedBy, res.Status, strings.Join(res.Trace, "\n"))
+	}
+	if res.WantModel != "backup-sonnet" || res.GotModel != "backup-sonnet" {
+		t.Errorf("model = want %q got %q, want backup-sonnet", res.WantModel, res.GotModel)
+	}
+	if res.Env["DISABLE_PROMPT_CACHING"] != "1" {
+		t.Errorf("env = %v, want backup's DISABLE_PROMPT_CACHING merged", res.Env)
+	}
+	if len(res.EnvIssues) != 1 || !strings.Contains(res.EnvIssues[0], "API_TIMEOUT_MS=30000") {
+		t.Errorf("env issues = %v, want the overridden API_TIMEOUT_MS", res.EnvIssues)
+	}
+	if !strings.Contains(strings.Join(res.Trace, "\n"), "[primary] got 503") {
+		t.Errorf("trace missing simulated outage:\n%s", strings.Join(res.Trace, "\n"))
+	}
+}
+
+func TestDrillProfileFailoverNoFallbackAnswers(t *testing.T) {
+	setTestHome(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	writeTestProvider(t, "primary", &config.ProviderConfig{BaseURL: "https://primary.invalid", AuthToken: "tok"})
+	writeTestProvider(t, "backup", &config.ProviderConfig{BaseURL: down.URL, AuthToken: "tok"})
+
+	res, err := drillProfileFailover([]string{"primary", "backup"}, nil, "claude")
+	if err != nil {
+		t.Fatalf("drillProfileFailover() error: %v", err)
+	}
+	if res.ServedBy != "" {
+		t.Errorf("served by %q, want none", res.ServedBy)
+	}
+
+	if _, err := drillProfileFailover([]string{"primary"}, nil, "claude"); err == nil {
+		t.Error("expected error for a profile without fallback")
+	}
+}
+
+func TestRunDoctorChecks(t *testing.T) {
+	home := setTestHome(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"up-sonnet"},{"id":"claude-haiku-4-5-20251001"}]}`))
+	}))
+	defer upstream.Close()
+
+	models := func(sonnet, haiku string) *config.ProviderConfig {
+		return &config.ProviderConfig{BaseURL: upstream.URL, AuthToken: "good",
+			Model: sonnet, ReasoningModel: sonnet, OpusModel: sonnet, SonnetModel: sonnet, HaikuModel: haiku}
+	}
+	writeTestConfig(t, &config.OpenCCConfig{
+		DefaultCLI: "sh",
+		Providers: map[string]*config.ProviderConfig{
+			"ok":       models("up-sonnet", "claude-haiku-4-5"),
+			"unmapped": models("up-opus", "claude-haiku-4-5"),
+			"badtoken": {BaseURL: upstream.URL, AuthToken: "bad"},
+			"notoken":  {BaseURL: upstream.URL},
+		},
+		Profiles: map[string]*config.ProfileConfig{
+			"default": {Providers: []string{"ok", "unmapped"}},
+			"broken":  {Providers: []string{"ok", "ghost"}},
+		},
+		ProjectBindings: map[string]*config.ProjectBinding{
+			home:                          {Profile: "default"},
+			home + "/gone":                {Profile: "default"},
+			home + "/missing-profile-dir": {Profile: "nope"},
+		},
+	})
+
+	got := make(map[string]doctorStatus)
+	for _, s := range runDoctorChecks(context.Background(), false, 5*time.Second) {
+		for _, c := range s.Checks {
+			got[c.Name] = c.Status
+		}
+	}
+	want := map[string]doctorStatus{
+		"config file":                 doctorPass,
+		"ok":                          doctorPass,
+		"ok models":                   doctorPass,
+		"unmapped":                    doctorPass,
+		"unmapped models":             doctorWarn,
+		"badtoken":                    doctorFail,
+		"notoken":                     doctorFail,
+		"default profile":             doctorPass,
+		"broken":                      doctorFail,
+		home:                          doctorPass,
+		home + "/gone":                doctorWarn,
+		home + "/missing-profile-dir": doctorFail,
+		"sh":                          doctorPass,
+	}
+	for name, status := range want {
+		if s, ok := got[name]; !ok || s != status {
+			t.Errorf("check %q = %v (reported %v), want %v", name, s, ok, status)
+		}
+	}
+
+	var out strings.Builder
+	if failed := printDoctorReport(&out, runDoctorChecks(context.Background(), true, time.Second)); failed != 3 {
+		t.Errorf("offline failures = %d, want 3 (notoken, broken, binding to a missing profile):\n%s", failed, out.String())
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		listen  string
+		want    string
+		wantErr bool
+	}{
+		{"", "127.0.0.1:19841", false},
+		{"0.0.0.0", "0.0.0.0:19841", false},
+		{"0.0.0.0:8080", "0.0.0.0:8080", false},
+		{":9000", ":9000", false},
+		{"localhost", "localhost:19841", false},
+		{"::", "[::]:19841", false},
+		{"[::1]:7000", "[::1]:7000", false},
+		{"0.0.0.0:http", "", true},
+		{"a:b:c", "", true},
+	}
+	for _, tt := range tests {
+		got, err := listenAddr(tt.listen)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("listenAddr(%q) error = %v, wantErr %v", tt.listen, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("listenAddr(%q) = %q, want %q", tt.listen, got, tt.want)
+		}
+	}
+}
+
+func TestJSONLogWriter(t *testing.T) {
+	var buf strings.Builder
+	logger := log.New(jsonLogWriter{&buf}, "", 0)
+	logger.Printf("[%s] request failed", "primary")
+	logger.Printf("second")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var entry struct {
+		Time  time.Time `json:"time"`
+		Level string    `json:"level"`
+		Msg   string    `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[0], err)
+	}
+	if entry.Msg != "[primary] request failed" || entry.Level != "info" || entry.Time.IsZero() {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestHeadlessRefusesInteractive(t *testing.T) {
+	setTestHome(t)
+	t.Setenv(headlessEnv, "1")
+	mockStdin(t, "y\n")
+	writeTestProvider(t, "a", &config.ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "tok"})
+
+	if err := checkHeadless(configAddProviderCmd, nil); err == nil {
+		t.Error("expected 'config add provider' to be refused in headless mode")
+	}
+	if err := checkHeadless(serveCmd, nil); err != nil {
+		t.Errorf("serve refused in headless mode: %v", err)
+	}
+	if err := checkHeadless(configExportCmd, nil); err != nil {
+		t.Errorf("config export refused in headless mode: %v", err)
+	}
+	if _, _, _, err := resolveProviderNamesAndCLI(" ", ""); err == nil {
+		t.Error("expected error for profile picker in headless mode")
+	}
+	if _, _, _, err := resolveProviderNamesAndCLI("", ""); err == nil || err.Error() == "cancelled" {
+		t.Errorf("expected misconfiguration error without default profile, got %v", err)
+	}
+	if _, err := validateProviderNames([]string{"a", "missing"}, "default"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected missing provider error, got %v", err)
+	}
+}
+
+func TestRequestTemplates(t *testing.T) {
+	setTestHome(t)
+
+	tests := []struct {
+		name    string
+		tmpl    requestTemplate
+		wantErr bool
+	}{
+		{"tools", requestTemplate{Path: "/v1/messages", Body: json.RawMessage(`{"model":"m"}`)}, false},
+		{"with.dots_and-dashes", requestTemplate{Path: "/v1/chat/completions", Body: json.RawMessage(`{}`)}, false},
+		{"../escape", requestTemplate{Path: "/v1/messages", Body: json.RawMessage(`{}`)}, true},
+		{"bad-json", requestTemplate{Path: "/v1/messages", Body: json.RawMessage(`{"model":`)}, true},
+		{"bad-path", requestTemplate{Path: "v1/messages", Body: json.RawMessage(`{}`)}, true},
+	}
+	for _, tt := range tests {
+		err := saveRequestTemplate(tt.name, &tt.tmpl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("saveRequestTemplate(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	names, err := requestTemplateNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "tools,with.dots_and-dashes" {
+		t.Errorf("names = %v", names)
+	}
+	tmpl, err := loadRequestTemplate("tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body strings.Builder
+	json.NewEncoder(&body).Encode(tmpl.Body)
+	if tmpl.Path != "/v1/messages" || strings.TrimSpace(body.String()) != `{"model":"m"}` {
+		t.Errorf("loaded template = %+v", tmpl)
+	}
+	if _, err := loadRequestTemplate("missing"); err == nil {
+		t.Error("expected error for missing template")
+	}
+}
+
+func TestSendRequestTemplate(t *testing.T) {
+	setTestHome(t)
+	var gotModel, gotBeta string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		gotBeta = r.Header.Get("anthropic-beta")
+		w.Write([]byte(`{"id":"msg_1","model":"served"}`))
+	}))
+	defer ok.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	writeTestProvider(t, "primary", &config.ProviderConfig{BaseURL: down.URL, AuthToken: "tok"})
+	writeTestProvider(t, "backup", &config.ProviderConfig{BaseURL: ok.URL, AuthToken: "tok", SonnetModel: "backup-sonnet"})
+
+	tmpl := &requestTemplate{
+		Path:    "/v1/messages",
+		Headers: map[string]string{"anthropic-beta": "interleaved-thinking-2025-05-14"},
+		Body:    json.RawMessage(`{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`),
+	}
+	res, err := sendRequestTemplate(tmpl, []string{"primary", "backup"}, nil, "claude")
+	if err != nil {
+		t.Fatalf("sendRequestTemplate() error: %v", err)
+	}
+	if res.Status != http.StatusOK || res.ServedBy != "backup" {
+		t.Errorf("status = %d, served by %q", res.Status, res.ServedBy)
+	}
+	if gotModel != "backup-sonnet" || gotBeta != "interleaved-thinking-2025-05-14" {
+		t.Errorf("upstream got model %q, anthropic-beta %q", gotModel, gotBeta)
+	}
+	if !strings.Contains(string(res.Body), "\n  \"id\": \"msg_1\"") {
+		t.Errorf("body not indented: %s", res.Body)
+	}
+
+	res, err = sendRequestTemplate(tmpl, []string{"primary"}, nil, "claude")
+	if err != nil {
+		t.Fatalf("sendRequestTemplate() error: %v", err)
+	}
+	if res.Status < 500 || res.ServedBy != "" {
+		t.Errorf("single failing provider: status = %d, served by %q", res.Status, res.ServedBy)
+	}
+}
+
+func TestPlanProfileModels(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "primary", &config.ProviderConfig{BaseURL: "https://primary.example.com", AuthToken: "tok", OpusModel: "big"})
+	writeTestProvider(t, "backup", &config.ProviderConfig{BaseURL: "https://backup.example.com", AuthToken: "tok", ReasoningModel: "deep"})
+
+	scenario, plan, err := planProfileModels([]string{"primary", "backup"}, "work", nil, "claude", "claude-opus-4-5", true)
+	if err != nil {
+		t.Fatalf("planProfileModels() error: %v", err)
+	}
+	if scenario != config.ScenarioDefault || len(plan) != 2 {
+		t.Fatalf("scenario = %q, plan = %+v", scenario, plan)
+	}
+	// Thinking wins over the model type
+	if plan[0].Model != proxy.DefaultReasoningModel || plan[1].Model != "deep" {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+// wordTokenizer counts one token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestExplainRoute(t *testing.T) {
+	setTestHome(t)
+	proxy.SetTokenizer(wordTokenizer{})
+	defer proxy.SetTokenizer(nil)
+	writeTestConfig(t, &config.OpenCCConfig{
+		Providers: map[string]*config.ProviderConfig{
+			"primary": {BaseURL: "https://primary.example.com", AuthToken: "tok", SonnetModel: "mid", ReasoningModel: "mid-think",
+				EnvVars: map[string]string{"CLAUDE_CODE_MAX_OUTPUT_TOKENS": "64000"}},
+			"deep": {BaseURL: "https://deep.example.com", AuthToken: "tok", ReasoningModel: "thinker"},
+		},
+	})
+	pc := &config.ProfileConfig{
+		Providers:            []string{"primary"},
+		LongContextThreshold: 1000,
+		Routing: map[config.Scenario]*config.ScenarioRoute{
+			config.ScenarioThink: {Providers: []*config.ProviderRoute{{Name: "deep"}}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		data         string
+		think, image bool
+		tokens       int
+		wantScenario config.Scenario
+		wantRouted   bool
+		wantChain    []string
+	}{
+		{name: "default", wantScenario: config.ScenarioDefault, wantChain: []string{"primary:mid"}},
+		{name: "think", think: true, wantScenario: config.ScenarioThink, wantRouted: true, wantChain: []string{"deep:thinker", "primary:mid-think"}},
+		{name: "image without route", image: true, wantScenario: config.ScenarioImage, wantChain: []string{"primary:mid"}},
+		{name: "long context without route", tokens: 2000, wantScenario: config.ScenarioLongContext, wantChain: []string{"primary:mid"}},
+		{name: "body", data: `{"model":"claude-haiku-4-5","thinking":{"type":"enabled"},"messages":[]}`,
+			wantScenario: config.ScenarioThink, wantRouted: true, wantChain: []string{"deep:thinker", "primary:mid-think"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := buildExplainBody([]byte(tt.data), "", tt.think, tt.image, tt.tokens)
+			if err != nil {
+				t.Fatalf("buildExplainBody() error: %v", err)
+			}
+			e, err := explainRoute([]string{"primary"}, "work", pc, "claude", body)
+			if err != nil {
+				t.Fatalf("explainRoute() error: %v", err)
+			}
+			var chain []string
+			for _, m := range e.Plan {
+				chain = append(chain, m.Provider+":"+m.Model)
+			}
+			if e.Scenario != tt.wantScenario || e.Routed != tt.wantRouted || !slices.Equal(chain, tt.wantChain) {
+				t.Errorf("scenario = %s, routed = %v, chain = %v", e.Scenario, e.Routed, chain)
+			}
+			if got := e.Headers["primary"]["x-env-claude-code-max-output-tokens"]; got != "64000" {
+				t.Errorf("primary env header = %q", got)
+			}
+		})
+	}
+
+	if _, err := buildExplainBody([]byte("[1]"), "", false, false, 0); err == nil {
+		t.Error("buildExplainBody() accepted a non-object body")
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	db, err := proxy.OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Insert(proxy.LogEntry{Timestamp: time.Now(), Level: proxy.LogLevelInfo, Provider: "primary", Method: "POST", Path: "/v1/messages", StatusCode: 200, LatencyMs: 1200, Message: "ok"})
+	db.Insert(proxy.LogEntry{Timestamp: time.Now(), Level: proxy.LogLevelError, Provider: "backup", StatusCode: 500, Message: "failed", Error: "overloaded"})
+	time.Sleep(700 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- followLogs(ctx, db, proxy.LogFilter{Limit: 50}, true, 50*time.Millisecond, func(e proxy.LogEntry) {
+			lines <- formatLogEntry(e)
+		})
+	}()
+
+	want := []string{
+		"[primary] POST /v1/messages 200 1.2s ok",
+		"[backup] 500 failed error=overloaded",
+		"[primary] new",
+	}
+	for i, w := range want {
+		if i == 2 {
+			db.Insert(proxy.LogEntry{Timestamp: time.Now(), Level: proxy.LogLevelInfo, Provider: "primary", Message: "new"})
+		}
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Errorf("line %d = %q, want %q", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("line %d not printed", i)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("followLogs() error: %v", err)
+	}
+}
+
+func TestCompareProvidersPrompt(t *testing.T) {
+	setTestHome(t)
+	var gotModel string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		w.Write([]byte(`{"model":"fast-sonnet","content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":12,"output_tokens":3}}`))
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad model"}}`))
+	}))
+	defer bad.Close()
+	writeTestProvider(t, "fast", &config.ProviderConfig{BaseURL: ok.URL, AuthToken: "tok", SonnetModel: "fast-sonnet"})
+	writeTestProvider(t, "broken", &config.ProviderConfig{BaseURL: bad.URL, AuthToken: "tok"})
+
+	results, err := compareProvidersPrompt([]string{"fast", "broken"}, "hi", "claude-sonnet-4-5", 16)
+	if err != nil {
+		t.Fatalf("compareProvidersPrompt() error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if gotModel != "fast-sonnet" {
+		t.Errorf("upstream got model %q", gotModel)
+	}
+	got := results[0]
+	if got.Provider != "fast" || got.Status != http.StatusOK || got.Text != "hello" || got.InputTokens != 12 || got.OutputTokens != 3 || got.Model != "fast-sonnet" {
+		t.Errorf("fast = %+v", got)
+	}
+	if got := results[1]; got.Provider != "broken" || got.Status != http.StatusBadRequest || !strings.Contains(got.Text, "bad model") {
+		t.Errorf("broken = %+v", got)
+	}
+}
+
+func TestPrintSmokeTest(t *testing.T) {
+	results := []compareResult{
+		{Provider: "good", Model: "claude-haiku-4-5-20251001", Status: 200, Latency: 420 * time.Millisecond, InputTokens: 14, OutputTokens: 2, Text: "OK"},
+		{Provider: "bad", Status: 401, Text: "error: invalid x-api-key\ndetails"},
+	}
+	var out strings.Builder
+	if failed := printSmokeTest(&out, results); failed != 1 {
+		t.Errorf("failed = %d, want 1", failed)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	for _, want := range []string{"good", "ok", "200", "420ms", "14", "claude-haiku-4-5-20251001"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("line %q missing %q", lines[1], want)
+		}
+	}
+	if !strings.Contains(lines[2], "FAIL") || !strings.HasSuffix(lines[2], "error: invalid x-api-key") {
+		t.Errorf("line %q, want FAIL with the first line of the error", lines[2])
+	}
+}
+
+func TestTestProvider(t *testing.T) {
+	setTestHome(t)
+	var messages int
+	upstream := func(probeStatus, status int, body string) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(probeStatus)
+				return
+			}
+			messages++
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+	writeTestProvider(t, "good", &config.ProviderConfig{BaseURL: upstream(200, 200, `{"model":"claude-haiku-4-5","content":[{"type":"text","text":"OK"}],"usage":{"input_tokens":14,"output_tokens":2}}`), AuthToken: "tok"})
+	writeTestProvider(t, "rejected", &config.ProviderConfig{BaseURL: upstream(401, 200, `{}`), AuthToken: "tok"})
+	writeTestProvider(t, "failing", &config.ProviderConfig{BaseURL: upstream(200, 400, `{"type":"error","error":{"message":"bad model"}}`), AuthToken: "tok"})
+
+	tests := []struct {
+		name         string
+		wantOK       bool
+		wantStatus   int
+		wantError    string
+		wantMessages int
+	}{
+		{"good", true, 200, "", 1},
+		{"rejected", false, 0, "rejected credentials (401)", 0},
+		{"failing", false, 400, "error: bad model", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages = 0
+			got := testProvider(context.Background(), tt.name)
+			if got.Provider != tt.name || got.OK != tt.wantOK || got.Status != tt.wantStatus || got.Error != tt.wantError {
+				t.Errorf("testProvider = %+v", got)
+			}
+			if messages != tt.wantMessages {
+				t.Errorf("sent %d requests, want %d", messages, tt.wantMessages)
+			}
+		})
+	}
+	if got := testProvider(context.Background(), "good"); got.Model != "claude-haiku-4-5" || got.InputTokens != 14 || got.ProbeStatus != 200 {
+		t.Errorf("good = %+v", got)
+	}
+}
+
+func TestFormatSideBySide(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []compareColumn
+		width   int
+		want    string
+	}{
+		{
+			name:    "wraps and pads",
+			columns: []compareColumn{{Title: "a", Text: "one two three"}, {Title: "b", Text: "x"}},
+			width:   19,
+			want:    "a        │ b\n──────── │ ────────\none two  │ x\nthree    │\n",
+		},
+		{
+			name:    "splits long words",
+			columns: []compareColumn{{Title: "a", Text: "abcdefghij"}, {Title: "b", Text: ""}},
+			width:   11,
+			want:    "a    │ b\n──── │ ────\nabcd │\nefgh │\nij   │\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSideBySide(tt.columns, tt.width); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaultInjectionEnv(t *testing.T) {
+	setTestHome(t)
+	var primaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.Write([]byte(`{"id":"msg_1"}`))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"msg_2"}`))
+	}))
+	defer backup.Close()
+	writeTestProvider(t, "primary", &config.ProviderConfig{BaseURL: primary.URL, AuthToken: "tok"})
+	writeTestProvider(t, "backup", &config.ProviderConfig{BaseURL: backup.URL, AuthToken: "tok"})
+	tmpl := &requestTemplate{Path: "/v1/messages", Body: json.RawMessage(`{"model":"claude-sonnet-4-5","messages":[]}`)}
+
+	t.Setenv(proxy.FaultsEnv, "primary=500")
+	res, err := sendRequestTemplate(tmpl, []string{"primary", "backup"}, nil, "claude")
+	if err != nil {
+		t.Fatalf("sendRequestTemplate() error: %v", err)
+	}
+	if res.ServedBy != "backup" || primaryCalls != 0 {
+		t.Errorf("served by %q, primary calls = %d", res.ServedBy, primaryCalls)
+	}
+
+	t.Setenv(proxy.FaultsEnv, "primary=ok")
+	if _, err := sendRequestTemplate(tmpl, []string{"primary", "backup"}, nil, "claude"); err == nil || !strings.Contains(err.Error(), proxy.FaultsEnv) {
+		t.Errorf("invalid spec error = %v", err)
+	}
+}
+
+func TestFormatRateLimitWindow(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		window *proxy.RateLimitWindow
+		want   string
+	}{
+		{nil, "-"},
+		{&proxy.RateLimitWindow{Limit: 50, Remaining: 4, Reset: now.Add(30 * time.Second)}, "4/50 (30s)"},
+		{&proxy.RateLimitWindow{Limit: 50, Remaining: 50, Reset: now.Add(-time.Minute)}, "50/50"},
+	}
+	for _, tt := range tests {
+		if got := formatRateLimitWindow(tt.window, now); got != tt.want {
+			t.Errorf("formatRateLimitWindow(%+v) = %q, want %q", tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics []proxy.ProviderMetrics
+		want    []string
+	}{
+		{"empty", nil, []string{"No requests logged"}},
+		{"provider row", []proxy.ProviderMetrics{{
+			Provider: "main", Requests: 10, Errors: 1, SuccessRate: 0.9,
+			P50LatencyMs: 850, P95LatencyMs: 2100, InputTokens: 12000, OutputTokens: 300,
+		}}, []string{"PROVIDER", "main", "90.0%", "850ms", "2.1s", "12000", "300"}},
+		{"no latency", []proxy.ProviderMetrics{{Provider: "down", Requests: 2, Errors: 2}}, []string{"down", "0.0%", "-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			printStats(&sb, tt.metrics)
+			for _, s := range tt.want {
+				if !strings.Contains(sb.String(), s) {
+					t.Errorf("output missing %q:\n%s", s, sb.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSharedProxyURL(t *testing.T) {
+	srv := proxy.NewProxyServer(nil, log.New(io.Discard, "", 0))
+	srv.Profile = "work"
+	srv.CLI = "claude"
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedAddr := strings.TrimPrefix(closed.URL, "http://")
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		addr    string
+		profile string
+		cli     string
+		want    bool
+	}{
+		{"same profile and cli", addr, "work", "claude", true},
+		{"other profile", addr, "home", "claude", false},
+		{"other cli", addr, "work", "codex", false},
+		{"not running", closedAddr, "work", "claude", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, ok := sharedProxyURL(tt.addr, tt.profile, tt.cli)
+			if ok != tt.want {
+				t.Fatalf("sharedProxyURL() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && url != ts.URL {
+				t.Errorf("sharedProxyURL() = %q, want %q", url, ts.URL)
+			}
+		})
+	}
+}
+
+func TestAutoOrderProfiles(t *testing.T) {
+	setTestHome(t)
+	db, err := proxy.OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		db.Insert(proxy.LogEntry{Timestamp: now, Level: proxy.LogLevelError, Provider: "flaky", StatusCode: 500, Message: "server error"})
+		db.Insert(proxy.LogEntry{Timestamp: now, Level: proxy.LogLevelInfo, Provider: "steady", StatusCode: 200, Message: "success 200"})
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	profiles := map[string]*config.ProfileConfig{
+		"auto":   {Providers: []string{"flaky", "steady"}, AutoOrder: &config.AutoOrderConfig{Enabled: true}},
+		"manual": {Providers: []string{"flaky", "steady"}},
+		"recent": {Providers: []string{"flaky", "steady"}, AutoOrder: &config.AutoOrderConfig{Enabled: true}},
+	}
+	for name, pc := range profiles {
+		if err := config.SetProfileConfig(name, pc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another session evaluated "recent" an hour ago
+	db.ClaimAutoOrderRun("recent", now.Add(-time.Hour), 24*time.Hour)
+
+	logger := log.New(io.Discard, "", 0)
+	autoOrderProfiles(db, now, logger)
+
+	want := map[string]string{"auto": "steady,flaky", "manual": "flaky,steady", "recent": "flaky,steady"}
+	for name, order := range want {
+		pc := config.GetProfileConfig(name)
+		if got := strings.Join(pc.Providers, ","); got != order {
+			t.Errorf("%s order = %s, want %s", name, got, order)
+		}
+	}
+
+	// An unchanged order isn't saved again
+	config.SetProfileConfig("recent", &config.ProfileConfig{Providers: []string{"steady", "flaky"}, AutoOrder: &config.AutoOrderConfig{Enabled: true}})
+	before, err := os.ReadFile(config.ConfigFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	autoOrderProfiles(db, now.Add(48*time.Hour), logger)
+	after, _ := os.ReadFile(config.ConfigFilePath())
+	if string(after) != string(before) {
+		t.Error("config rewritten although no order changed")
+	}
+}
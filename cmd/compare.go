@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// compareMinColumn is the narrowest column for side-by-side output; below it
+// responses are printed one after another.
+const compareMinColumn = 24
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <prompt>",
+	Short: "Send one prompt to several providers and compare the answers",
+	Long: `Send the same prompt to several providers concurrently, each through the
+full model mapping and transform path, and print the responses side by side
+with latency and token counts. Use "-" to read the prompt from stdin.
+
+Each provider is asked on its own, without failover, and receives a real
+request that uses tokens.
+
+Examples:
+  opencc compare "Explain CRDTs in two sentences" -p work
+  opencc compare "Write a haiku" --providers primary,backup --model claude-opus-4-5`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCompare,
+}
+
+var (
+	compareProfile   string
+	compareProviders []string
+	compareModel     string
+	compareMaxTokens int
+	compareWidth     int
+	compareStacked   bool
+)
+
+func init() {
+	compareCmd.Flags().StringVarP(&compareProfile, "profile", "p", "", "compare the providers of this profile (default: bound or default profile)")
+	compareCmd.Flags().StringSliceVar(&compareProviders, "providers", nil, "comma-separated providers to compare instead of a profile")
+	compareCmd.Flags().StringVar(&compareModel, "model", "claude-sonnet-4-5", "model to request; each provider maps it as usual")
+	compareCmd.Flags().IntVar(&compareMaxTokens, "max-tokens", 1024, "max_tokens of the request")
+	compareCmd.Flags().IntVar(&compareWidth, "width", 0, "output width (default: $COLUMNS or 120)")
+	compareCmd.Flags().BoolVar(&compareStacked, "stacked", false, "print responses one after another instead of side by side")
+}
+
+// compareResult is one provider's answer to the compared prompt.
+type compareResult struct {
+	Provider     string
+	Model        string // model reported in the response
+	Status       int
+	Latency      time.Duration
+	InputTokens  int
+	OutputTokens int
+	Text         string // response text, or the error message
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	if compareProfile != "" && len(compareProviders) > 0 {
+		return errors.New(i18n.T("use either --providers or --profile, not both"))
+	}
+	prompt := args[0]
+	if prompt == "-" {
+		data, err := io.ReadAll(stdinReader)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		return errors.New(i18n.T("prompt is empty"))
+	}
+
+	names := compareProviders
+	if len(names) == 0 {
+		var err error
+		names, _, _, err = resolveProviderNamesAndCLI(compareProfile, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := compareProvidersPrompt(names, prompt, compareModel, compareMaxTokens)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-16s %6s %9s %7s %7s  %s\n", "PROVIDER", "STATUS", "LATENCY", "IN", "OUT", "MODEL")
+	for _, r := range results {
+		fmt.Printf("%-16s %6d %9s %7d %7d  %s\n", r.Provider, r.Status, r.Latency.Round(time.Millisecond), r.InputTokens, r.OutputTokens, r.Model)
+	}
+	fmt.Println()
+
+	width := compareWidth
+	if width <= 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if width <= 0 {
+		width = 120
+	}
+	columns := make([]compareColumn, len(results))
+	for i, r := range results {
+		columns[i] = compareColumn{Title: r.Provider, Text: r.Text}
+	}
+	if compareStacked || len(columns) == 1 || (width-3*(len(columns)-1))/len(columns) < compareMinColumn {
+		for _, c := range columns {
+			fmt.Printf("── %s ──\n%s\n\n", c.Title, c.Text)
+		}
+		return nil
+	}
+	fmt.Print(formatSideBySide(columns, width))
+	return nil
+}
+
+// compareProvidersPrompt sends the prompt to each provider concurrently,
+// each through its own in-process proxy, and returns the results in order.
+func compareProvidersPrompt(names []string, prompt, model string, maxTokens int) ([]compareResult, error) {
+	providers, err := buildProviders(names)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      model,
+		"max_tokens": maxTokens,
+		"messages":   []interface{}{map[string]interface{}{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]compareResult, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		srv, err := buildProxyServer(providers[i:i+1], "", nil, config.DefaultCLIName, log.New(io.Discard, "", 0))
+		if err != nil {
+			return nil, err
+		}
+		srv.ClientName = "compare"
+		srv.StructuredLogger = nil
+		srv.LogDB = nil
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("anthropic-version", "2023-06-01")
+			rec := httptest.NewRecorder()
+			start := time.Now()
+			srv.ServeHTTP(rec, req)
+			results[i] = parseCompareResponse(name, rec.Code, rec.Body.Bytes())
+			results[i].Latency = time.Since(start)
+		}(i, p.Name)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// parseCompareResponse extracts the text, model and usage of an Anthropic
+// Messages response, or the error message of a failed one.
+func parseCompareResponse(provider string, status int, body []byte) compareResult {
+	res := compareResult{Provider: provider, Status: status}
+	var resp struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		res.Text = strings.TrimSpace(string(body))
+		return res
+	}
+	res.Model = resp.Model
+	res.InputTokens = resp.Usage.InputTokens
+	res.OutputTokens = resp.Usage.OutputTokens
+	if status >= 300 {
+		res.Text = "error: " + resp.Error.Message
+		return res
+	}
+	var parts []string
+	for _, c := range resp.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	res.Text = strings.TrimSpace(strings.Join(parts, "\n"))
+	return res
+}
+
+// compareColumn is one titled column of side-by-side output.
+type compareColumn struct {
+	Title string
+	Text  string
+}
+
+// formatSideBySide lays the columns out next to each other within width,
+// wrapping each column's text to fit.
+func formatSideBySide(columns []compareColumn, width int) string {
+	const sep = " │ "
+	colWidth := (width - len([]rune(sep))*(len(columns)-1)) / len(columns)
+
+	wrapped := make([][]string, len(columns))
+	rows := 0
+	for i, c := range columns {
+		lines := append([]string{c.Title, strings.Repeat("─", colWidth)}, wrapText(c.Text, colWidth)...)
+		wrapped[i] = lines
+		if len(lines) > rows {
+			rows = len(lines)
+		}
+	}
+
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		cells := make([]string, len(columns))
+		for i := range columns {
+			cell := ""
+			if row < len(wrapped[i]) {
+				cell = wrapped[i][row]
+			}
+			if i < len(columns)-1 {
+				cell += strings.Repeat(" ", colWidth-len([]rune(cell)))
+			}
+			cells[i] = cell
+		}
+		b.WriteString(strings.TrimRight(strings.Join(cells, sep), " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// wrapText wraps text at word boundaries into lines of at most width runes,
+// splitting words longer than a line.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := []rune{}
+		for _, word := range strings.Fields(para) {
+			w := []rune(word)
+			for len(w) > width {
+				if len(line) > 0 {
+					lines = append(lines, string(line))
+					line = line[:0]
+				}
+				lines = append(lines, string(w[:width]))
+				w = w[width:]
+			}
+			if len(line) > 0 && len(line)+1+len(w) > width {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, w...)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
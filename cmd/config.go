@@ -7,11 +7,15 @@ import (
 	"strings"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/dopejs/opencc/tui"
 	"github.com/spf13/cobra"
 )
 
-var configLegacyUI bool
+var (
+	configLegacyUI bool
+	providerPreset string
+)
 
 var configCmd = &cobra.Command{
 	Use:   "config",
@@ -66,12 +70,24 @@ var configAddCmd = &cobra.Command{
 var configAddProviderCmd = &cobra.Command{
 	Use:   "provider [name]",
 	Short: "Add a new provider",
+	Long: `Add a new provider in the editor. With --preset the editor starts filled in
+with a well-known endpoint's API type, base URL and model mappings, so only
+the token is left to enter.
+
+Presets: ` + strings.Join(config.ProviderPresetNames(), ", ") + `
+
+Examples:
+  opencc config add provider                        # Start from a blank form
+  opencc config add provider ds --preset deepseek   # Add 'ds' from the DeepSeek preset`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var name string
 		if len(args) > 0 {
 			name = args[0]
 		}
-		_, err := tui.RunAddProvider(name)
+		if providerPreset != "" && config.GetProviderPreset(providerPreset) == nil {
+			return fmt.Errorf("unknown preset %q (available: %s)", providerPreset, strings.Join(config.ProviderPresetNames(), ", "))
+		}
+		_, err := tui.RunAddProviderFromPreset(name, providerPreset)
 		if err != nil && err.Error() == "cancelled" {
 			return nil
 		}
@@ -232,6 +248,318 @@ func confirmDelete(name string) bool {
 	return input == "y" || input == "yes"
 }
 
+// --- export/import subcommands ---
+
+var (
+	exportNoSecrets bool
+	exportProviders []string
+	exportProfiles  []string
+	exportFormat    string
+	importConflict  string
+)
+
+var configExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export providers and profiles to a shareable file",
+	Long: `Export providers and profiles to a JSON, YAML or TOML file that others can
+import with 'opencc config import'. The format follows the file extension
+(.yaml or .yml for YAML, .toml for TOML) unless --format is given; "-" writes
+to stdout.
+
+Profiles are exported with the providers they use. Without --provider or
+--profile everything is exported. Tokens and cloud credentials are included
+unless --no-secrets is given.
+
+Examples:
+  opencc config export backup.json                           # Everything, with tokens
+  opencc config export team.yaml --profile work --no-secrets # Share the 'work' profile`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.ExactArgs(1),
+	RunE:        runConfigExport,
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import providers and profiles from an exported file",
+	Long: `Import the providers and profiles of a file written by 'opencc config export'.
+For each one whose name is already taken you're asked whether to overwrite it,
+skip it or import it under another name; --on-conflict answers for all of
+them. An overwritten provider keeps its token if the file has none.
+
+Examples:
+  opencc config import team.yaml                         # Ask about each conflict
+  opencc config import team.yaml --on-conflict overwrite # Update existing entries`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.ExactArgs(1),
+	RunE:        runConfigImport,
+}
+
+func runConfigExport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	format := exportFormat
+	if format == "" {
+		format = config.FormatForPath(path)
+	}
+	if format != config.FormatJSON && format != config.FormatYAML && format != config.FormatTOML {
+		return fmt.Errorf("invalid --format %q (json, yaml or toml)", format)
+	}
+
+	doc, err := config.ExportConfig(exportProviders, exportProfiles, !exportNoSecrets)
+	if err != nil {
+		return err
+	}
+	data, err := doc.Encode(format)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	note := ""
+	if exportNoSecrets {
+		note = " (without secrets)"
+	}
+	fmt.Printf("Exported %d provider(s) and %d profile(s) to %s%s\n", len(doc.Providers), len(doc.Profiles), path, note)
+	return nil
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	switch importConflict {
+	case "ask", "overwrite", "skip":
+	default:
+		return fmt.Errorf("invalid --on-conflict %q (ask, overwrite or skip)", importConflict)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	doc, err := config.DecodeExportDocument(data, config.FormatForPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	result, err := config.ImportConfig(doc, func(kind, name string) (string, error) {
+		switch importConflict {
+		case "overwrite":
+			return name, nil
+		case "skip":
+			return "", nil
+		}
+		if isHeadless() {
+			return "", fmt.Errorf("%s '%s' already exists; pass --on-conflict overwrite or skip in headless mode", kind, name)
+		}
+		return promptImportConflict(reader, kind, name), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(result.Providers) > 0 {
+		fmt.Printf("Imported providers: %s\n", strings.Join(result.Providers, ", "))
+	}
+	if len(result.Profiles) > 0 {
+		fmt.Printf("Imported profiles: %s\n", strings.Join(result.Profiles, ", "))
+	}
+	if len(result.Skipped) > 0 {
+		fmt.Printf("Skipped: %s\n", strings.Join(result.Skipped, ", "))
+	}
+	for _, name := range result.Providers {
+		p := config.GetProvider(name)
+		switch p.GetType() {
+		case config.ProviderTypeLocal, config.ProviderTypeBedrock, config.ProviderTypeVertex:
+			continue
+		}
+		if p.AuthToken == "" && (p.OAuth == nil || p.OAuth.RefreshToken == "") {
+			fmt.Printf("Provider '%s' has no auth token; set one with 'opencc config edit provider %s'.\n", name, name)
+		}
+	}
+	return nil
+}
+
+// promptImportConflict asks what to do with an imported provider or profile
+// whose name is taken, returning the name to import it under or "" to skip.
+func promptImportConflict(reader *bufio.Reader, kind, name string) string {
+	for {
+		fmt.Printf("%s '%s' already exists. [o]verwrite, [s]kip or [r]ename? ", strings.ToUpper(kind[:1])+kind[1:], name)
+		input, err := reader.ReadString('\n')
+		if err != nil && input == "" {
+			return ""
+		}
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "o", "overwrite":
+			return name
+		case "s", "skip":
+			return ""
+		case "r", "rename":
+			fmt.Print("New name: ")
+			renamed, _ := reader.ReadString('\n')
+			if renamed = strings.TrimSpace(renamed); renamed != "" {
+				return renamed
+			}
+		}
+	}
+}
+
+// --- secrets subcommand ---
+
+var configSecretsCmd = &cobra.Command{
+	Use:   "secrets [plain|keychain|encrypted]",
+	Short: "Show or change where provider tokens are saved",
+	Long: `Show or change where provider tokens are saved. Changing it moves the tokens
+of all providers, and tokens saved later go to the same place:
+
+  plain      in opencc.json as they are (the default)
+  keychain   in the OS keychain (macOS Keychain, Secret Service on Linux,
+             Windows Credential Manager); opencc.json refers to them
+  encrypted  in opencc.json, encrypted with the passphrase in ` + config.PassphraseEnv + `,
+             which must then be set whenever opencc runs
+
+Examples:
+  opencc config secrets              # Show where tokens are saved
+  opencc config secrets keychain     # Move tokens to the OS keychain`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.MaximumNArgs(1),
+	ValidArgs:   []string{"plain", "keychain", "encrypted"},
+	RunE:        runConfigSecrets,
+}
+
+func runConfigSecrets(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		store := config.GetSecretStore()
+		if store == config.SecretStorePlain {
+			store = "plain"
+		}
+		fmt.Printf("Provider tokens are saved: %s\n", store)
+		return nil
+	}
+	store := args[0]
+	if store == "plain" {
+		store = config.SecretStorePlain
+	}
+	if !config.IsValidSecretStore(store) {
+		return fmt.Errorf("invalid secret store %q (plain, keychain or encrypted)", args[0])
+	}
+	if store == config.SecretStoreEncrypted && os.Getenv(config.PassphraseEnv) == "" {
+		return fmt.Errorf("set %s to the passphrase to encrypt tokens with", config.PassphraseEnv)
+	}
+	if err := config.SetSecretStore(store); err != nil {
+		return err
+	}
+	fmt.Printf("Moved provider tokens to %s storage.\n", args[0])
+	return nil
+}
+
+// --- backups subcommands ---
+
+var configBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List or restore backups of the config file",
+	Long: `opencc backs up opencc.json to ~/.opencc/backups before deleting a provider or
+profile and before importing, keeping the newest 20 (backup_retention).
+
+Examples:
+  opencc config backups                              # List backups, newest first
+  opencc config backups restore 20260102-150405.000  # Restore one`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.NoArgs,
+	RunE:        runConfigBackupsList,
+}
+
+var configBackupsListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List backups of the config file, newest first",
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.NoArgs,
+	RunE:        runConfigBackupsList,
+}
+
+var configBackupsRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Replace the config file with a backup",
+	Long: `Replace opencc.json with a backup. The config being replaced is backed up
+first, so a restore can be undone the same way.`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		backups, _ := config.ListBackups()
+		ids := make([]string, 0, len(backups))
+		for _, b := range backups {
+			ids = append(ids, b.ID)
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: runConfigBackupsRestore,
+}
+
+func runConfigBackupsList(cmd *cobra.Command, args []string) error {
+	backups, err := config.ListBackups()
+	if err != nil {
+		return err
+	}
+	w := cmd.OutOrStdout()
+	if len(backups) == 0 {
+		fmt.Fprintln(w, "No backups yet.")
+		return nil
+	}
+	fmt.Fprintf(w, "%-24s %-19s  %s\n", "ID", "TIME", "REASON")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%-24s %-19s  %s\n", b.ID, b.Time.Local().Format("2006-01-02 15:04:05"), b.Reason)
+	}
+	return nil
+}
+
+func runConfigBackupsRestore(cmd *cobra.Command, args []string) error {
+	if err := config.RestoreBackup(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Restored backup %s; the replaced config was backed up first.\n", args[0])
+	return nil
+}
+
+// --- validate subcommand ---
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the config file for problems",
+	Long: `Check the config file, or the given one, read as JSON, YAML or TOML by its
+extension, and report every problem at once:
+unknown or repeated fields, values of the wrong type, profiles, routes and
+bindings referring to providers, profiles or scenarios that don't exist,
+invalid URLs and conflicting settings. Exits non-zero if there are any.
+
+Examples:
+  opencc config validate                 # Check the config file in ~/.opencc
+  opencc config validate new-config.json # Check a file before using it`,
+	Annotations: map[string]string{interactiveAnnotation: "false"},
+	Args:        cobra.MaximumNArgs(1),
+	RunE:        runConfigValidate,
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := config.ConfigFilePath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	problems, err := config.ValidateConfigPath(path)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: no problems found\n", path)
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", p)
+	}
+	return fmt.Errorf(i18n.T("%d problem(s) found in %s"), len(problems), path)
+}
+
 // --- edit subcommands ---
 
 var configEditCmd = &cobra.Command{
@@ -338,6 +666,10 @@ func editGroup(name string) error {
 func init() {
 	configCmd.Flags().BoolVar(&configLegacyUI, "legacy", false, "use legacy TUI interface")
 
+	configAddProviderCmd.Flags().StringVar(&providerPreset, "preset", "", "start from a built-in preset (e.g. anthropic, openrouter, deepseek, ollama)")
+	configAddProviderCmd.RegisterFlagCompletionFunc("preset", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.ProviderPresetNames(), cobra.ShellCompDirectiveNoFileComp
+	})
 	configAddCmd.AddCommand(configAddProviderCmd)
 	configAddCmd.AddCommand(configAddGroupCmd)
 
@@ -347,7 +679,21 @@ func init() {
 	configEditCmd.AddCommand(configEditProviderCmd)
 	configEditCmd.AddCommand(configEditGroupCmd)
 
+	configExportCmd.Flags().BoolVar(&exportNoSecrets, "no-secrets", false, "leave out tokens and cloud credentials")
+	configExportCmd.Flags().StringSliceVar(&exportProviders, "provider", nil, "providers to export (comma-separated)")
+	configExportCmd.Flags().StringSliceVar(&exportProfiles, "profile", nil, "profiles to export, with the providers they use (comma-separated)")
+	configExportCmd.Flags().StringVar(&exportFormat, "format", "", "json, yaml or toml (default: from the file extension)")
+	configImportCmd.Flags().StringVar(&importConflict, "on-conflict", "ask", "what to do with names already taken: ask, overwrite or skip")
+
 	configCmd.AddCommand(configAddCmd)
 	configCmd.AddCommand(configDeleteCmd)
 	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	configCmd.AddCommand(configSecretsCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	configBackupsCmd.AddCommand(configBackupsListCmd)
+	configBackupsCmd.AddCommand(configBackupsRestoreCmd)
+	configCmd.AddCommand(configBackupsCmd)
 }
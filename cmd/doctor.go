@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration, providers and CLIs for problems",
+	Long: `Check the whole setup and report what is wrong: the config file parses,
+each provider's host resolves and answers a probe of its model list, tokens
+are accepted, mapped models are listed by the provider, the default profile
+has providers, profiles and project bindings refer to what exists, and the
+CLIs in use are on PATH.
+
+The probes don't send prompts or use tokens. Exits non-zero if any check fails.
+
+Examples:
+  opencc doctor              # Run all checks
+  opencc doctor --offline    # Skip the provider probes`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var (
+	doctorOffline bool
+	doctorTimeout time.Duration
+)
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "don't contact providers")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "how long each provider probe may take")
+}
+
+// doctorStatus is the outcome of a check.
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorCheck is one line of the doctor report.
+type doctorCheck struct {
+	Name   string
+	Status doctorStatus
+	Detail string
+}
+
+// doctorSection groups the checks of one part of the setup.
+type doctorSection struct {
+	Title  string
+	Checks []doctorCheck
+}
+
+func (s *doctorSection) add(name string, status doctorStatus, format string, args ...interface{}) {
+	s.Checks = append(s.Checks, doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	sections := runDoctorChecks(context.Background(), doctorOffline, doctorTimeout)
+	failed := printDoctorReport(cmd.OutOrStdout(), sections)
+	if failed > 0 {
+		return fmt.Errorf(i18n.T("doctor found %d problem(s)"), failed)
+	}
+	return nil
+}
+
+// runDoctorChecks checks the setup. Providers are probed concurrently, each
+// within timeout, unless offline is set.
+func runDoctorChecks(ctx context.Context, offline bool, timeout time.Duration) []doctorSection {
+	cfg := doctorSection{Title: "Config"}
+	if err := config.CheckConfig(); err != nil {
+		cfg.add("config file", doctorFail, "%v", err)
+		return []doctorSection{cfg}
+	}
+	cfg.add("config file", doctorPass, "%s", config.ConfigFilePath())
+
+	return []doctorSection{
+		cfg,
+		doctorProviders(ctx, offline, timeout),
+		doctorProfiles(),
+		doctorBindings(),
+		doctorCLIs(),
+	}
+}
+
+// doctorProviders builds each provider the way the proxy does and probes it.
+func doctorProviders(ctx context.Context, offline bool, timeout time.Duration) doctorSection {
+	section := doctorSection{Title: "Providers"}
+	names := config.ProviderNames()
+	if len(names) == 0 {
+		section.add("providers", doctorFail, "none configured; run 'opencc config add provider'")
+		return section
+	}
+
+	results := make([][]doctorCheck, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = doctorProvider(ctx, name, offline, timeout)
+		}()
+	}
+	wg.Wait()
+	for _, checks := range results {
+		section.Checks = append(section.Checks, checks...)
+	}
+	return section
+}
+
+// doctorProvider checks one provider: that it builds, its host resolves, and
+// the probe reaches it with accepted credentials and lists its mapped models.
+func doctorProvider(ctx context.Context, name string, offline bool, timeout time.Duration) []doctorCheck {
+	s := doctorSection{}
+	providers, err := buildProviders([]string{name})
+	if err != nil {
+		s.add(name, doctorFail, "%v", err)
+		return s.Checks
+	}
+	p := providers[0]
+	if offline {
+		s.add(name, doctorPass, "configured (%s)", p.BaseURL)
+		return s.Checks
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// Hosts behind an upstream proxy may only resolve there
+	if pc := config.GetProvider(name); pc != nil && pc.ProxyURL == "" && net.ParseIP(p.BaseURL.Hostname()) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, p.BaseURL.Hostname()); err != nil {
+			s.add(name, doctorFail, "can't resolve %s: %v", p.BaseURL.Hostname(), err)
+			return s.Checks
+		}
+	}
+
+	result, models := proxy.ProbeModels(ctx, p.HTTPClient(http.DefaultClient), p)
+	latency := result.Latency.Round(time.Millisecond)
+	switch {
+	case result.Err != nil:
+		s.add(name, doctorFail, "unreachable: %v", result.Err)
+		return s.Checks
+	case result.StatusCode == http.StatusUnauthorized || result.StatusCode == http.StatusForbidden:
+		s.add(name, doctorFail, "token rejected (%d)", result.StatusCode)
+		return s.Checks
+	case result.StatusCode >= 500 || result.StatusCode == http.StatusTooManyRequests:
+		s.add(name, doctorWarn, "reachable, but %s", result.Problem())
+		return s.Checks
+	case result.StatusCode != http.StatusOK:
+		s.add(name, doctorWarn, "reachable (%d, %v); token not verified, the provider has no model list", result.StatusCode, latency)
+		return s.Checks
+	}
+	s.add(name, doctorPass, "reachable, token accepted (%v)", latency)
+
+	if len(models) > 0 {
+		var missing []string
+		for _, m := range mappedModels(p) {
+			if !modelListed(models, m) {
+				missing = append(missing, m)
+			}
+		}
+		if len(missing) > 0 {
+			s.add(name+" models", doctorWarn, "not listed by the provider: %s", strings.Join(missing, ", "))
+		} else {
+			s.add(name+" models", doctorPass, "all mapped models listed")
+		}
+	}
+	return s.Checks
+}
+
+// mappedModels returns the distinct models requests to p are mapped to.
+func mappedModels(p *proxy.Provider) []string {
+	var models []string
+	for _, m := range []string{p.Model, p.ReasoningModel, p.HaikuModel, p.OpusModel, p.SonnetModel} {
+		if m != "" && !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// modelListed reports whether model is in the provider's model list, by its
+// ID or as an alias of a dated or tagged ID ("claude-sonnet-4-5" for
+// "claude-sonnet-4-5-20250929", "qwen3-coder" for "qwen3-coder:latest").
+func modelListed(listed []string, model string) bool {
+	for _, id := range listed {
+		if id == model || strings.HasPrefix(id, model+"-") || strings.HasPrefix(id, model+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// doctorProfiles checks that the default profile has providers and that
+// every profile uses only configured providers.
+func doctorProfiles() doctorSection {
+	section := doctorSection{Title: "Profiles"}
+	def := config.GetDefaultProfile()
+	if names, err := config.ReadProfileOrder(def); err != nil || len(names) == 0 {
+		section.add("default profile", doctorFail, "'%s' has no providers", def)
+	} else {
+		section.add("default profile", doctorPass, "'%s': %s", def, strings.Join(names, ", "))
+	}
+
+	for _, profile := range config.ListProfiles() {
+		pc := config.GetProfileConfig(profile)
+		if pc == nil {
+			continue
+		}
+		refs := slices.Clone(pc.Providers)
+		for _, route := range pc.Routing {
+			for _, pr := range route.Providers {
+				refs = append(refs, pr.Name)
+			}
+		}
+		var missing []string
+		for _, ref := range refs {
+			if config.GetProvider(ref) == nil && !slices.Contains(missing, ref) {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			section.add(profile, doctorFail, "uses missing provider(s): %s", strings.Join(missing, ", "))
+		} else {
+			section.add(profile, doctorPass, "%d provider(s)", len(pc.Providers))
+		}
+	}
+	return section
+}
+
+// doctorBindings checks that project bindings refer to existing profiles
+// and directories.
+func doctorBindings() doctorSection {
+	section := doctorSection{Title: "Project bindings"}
+	bindings := config.GetAllProjectBindings()
+	paths := make([]string, 0, len(bindings))
+	for path := range bindings {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		b := bindings[path]
+		switch {
+		case b.Profile != "" && config.GetProfileConfig(b.Profile) == nil:
+			section.add(path, doctorFail, "bound to missing profile '%s'", b.Profile)
+		case !dirExists(path):
+			section.add(path, doctorWarn, "directory no longer exists; remove with 'opencc unbind %s'", path)
+		default:
+			section.add(path, doctorPass, "%s", describeBinding(b))
+		}
+	}
+	return section
+}
+
+func describeBinding(b *config.ProjectBinding) string {
+	var parts []string
+	if b.Profile != "" {
+		parts = append(parts, "profile '"+b.Profile+"'")
+	}
+	if b.CLI != "" {
+		parts = append(parts, "cli "+b.CLI)
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// doctorCLIs checks that the default CLI and the CLIs projects are bound to
+// are on PATH.
+func doctorCLIs() doctorSection {
+	section := doctorSection{Title: "CLIs"}
+	clis := []string{config.GetDefaultCLI()}
+	for _, b := range config.GetAllProjectBindings() {
+		if b.CLI != "" && !slices.Contains(clis, b.CLI) {
+			clis = append(clis, b.CLI)
+		}
+	}
+	sort.Strings(clis[1:])
+	for _, cli := range clis {
+		if path, err := exec.LookPath(cli); err != nil {
+			section.add(cli, doctorFail, "not found in PATH")
+		} else {
+			section.add(cli, doctorPass, "%s", path)
+		}
+	}
+	return section
+}
+
+// printDoctorReport writes the report and returns the number of failed
+// checks.
+func printDoctorReport(w io.Writer, sections []doctorSection) int {
+	marks := map[doctorStatus]string{
+		doctorPass: lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("✓"),
+		doctorWarn: lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("!"),
+		doctorFail: lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("✗"),
+	}
+	bold := lipgloss.NewStyle().Bold(true)
+
+	counts := make(map[doctorStatus]int)
+	for _, s := range sections {
+		if len(s.Checks) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, bold.Render(s.Title))
+		for _, c := range s.Checks {
+			counts[c.Status]++
+			fmt.Fprintf(w, "  %s %-24s %s\n", marks[c.Status], c.Name, c.Detail)
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d warning(s), %d failed\n", counts[doctorPass], counts[doctorWarn], counts[doctorFail])
+	return counts[doctorFail]
+}
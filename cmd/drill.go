@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// drillModel is the model the drill request asks for. Providers map it to
+// their sonnet model.
+const drillModel = "claude-sonnet-4-5"
+
+var drillCmd = &cobra.Command{
+	Use:   "drill",
+	Short: "Simulate a primary provider outage and test failover",
+	Long: `Simulate an outage of a profile's primary provider and send a small test
+request through the proxy, to verify that failover, model mapping and env var
+merging work on the fallback providers before a real outage.
+
+The primary provider is never contacted: its requests go to a local endpoint
+that answers 503. The fallback providers receive a real request, which uses a
+few tokens. Scenario routes are not exercised.
+
+Examples:
+  opencc drill -p work          # Drill the 'work' profile
+  opencc drill -p work -y       # Skip the confirmation prompt`,
+	Args: cobra.NoArgs,
+	RunE: runDrill,
+}
+
+var (
+	drillProfile string
+	drillCLI     string
+	drillYes     bool
+)
+
+func init() {
+	drillCmd.Flags().StringVarP(&drillProfile, "profile", "p", "", "profile to drill (default: bound or default profile)")
+	drillCmd.Flags().StringVar(&drillCLI, "cli", "", "CLI whose env vars to check (claude, codex, opencode)")
+	drillCmd.Flags().BoolVarP(&drillYes, "yes", "y", false, "don't ask for confirmation")
+}
+
+// drillResult is the outcome of a failover drill.
+type drillResult struct {
+	Primary   string
+	ServedBy  string // provider that answered; "" if none did
+	Status    int
+	WantModel string   // model the serving provider should have been sent
+	GotModel  string   // model reported in the response
+	Trace     []string // proxy log lines for the request
+	Env       map[string]string
+	EnvIssues []string
+}
+
+func runDrill(cmd *cobra.Command, args []string) error {
+	names, profile, cli, err := resolveProviderNamesAndCLI(drillProfile, drillCLI)
+	if err != nil {
+		return err
+	}
+	if cli == "" {
+		cli = "claude"
+	}
+	if len(names) < 2 {
+		return fmt.Errorf(i18n.T("profile '%s' has no fallback provider to fail over to"), profile)
+	}
+
+	if !drillYes && isHeadless() {
+		return errors.New(i18n.T("drill sends a real request; pass --yes to confirm in headless mode"))
+	}
+	if !drillYes {
+		fmt.Printf("Simulate an outage of '%s' and send a test request to the fallbacks of profile '%s'? (y/n): ", names[0], profile)
+		line, err := bufio.NewReader(stdinReader).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		answer := strings.TrimSpace(strings.ToLower(line))
+		if answer != "y" && answer != "yes" {
+			return errors.New(i18n.T("aborted"))
+		}
+	}
+
+	res, err := drillProfileFailover(names, config.GetProfileConfig(profile), cli)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Drill: profile '%s' (%s), simulating outage of '%s'\n", profile, cli, res.Primary)
+	for _, line := range res.Trace {
+		fmt.Printf("  %s\n", line)
+	}
+	if res.ServedBy == "" {
+		return fmt.Errorf(i18n.T("failover failed: no fallback provider answered (status %d)"), res.Status)
+	}
+	fmt.Printf("Failover: served by '%s' (%d)\n", res.ServedBy, res.Status)
+	fmt.Printf("Model:    %s → %s (response reports %s)\n", drillModel, res.WantModel, res.GotModel)
+
+	keys := make([]string, 0, len(res.Env))
+	for k := range res.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Printf("Env:      %d variable(s) for %s\n", len(keys), cli)
+	for _, k := range keys {
+		fmt.Printf("  %s=%s\n", k, maskEnvValue(k, res.Env[k]))
+	}
+	for _, issue := range res.EnvIssues {
+		fmt.Printf("Warning: %s\n", issue)
+	}
+	return nil
+}
+
+// drillProfileFailover runs the proxy in-process with the first provider
+// pointed at a local endpoint that always answers 503, and sends one request.
+func drillProfileFailover(names []string, pc *config.ProfileConfig, cli string) (*drillResult, error) {
+	providers, err := buildProviders(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) < 2 {
+		return nil, errors.New(i18n.T("no fallback provider to fail over to"))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start simulated outage: %w", err)
+	}
+	defer ln.Close()
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"type":"error","error":{"type":"api_error","message":"opencc drill: simulated outage"}}`))
+	}))
+
+	primary := providers[0]
+	primary.BaseURL = &url.URL{Scheme: "http", Host: ln.Addr().String()}
+
+	var trace, access bytes.Buffer
+	srv := proxy.NewProxyServer(providers, log.New(&trace, "", 0))
+	srv.StructuredLogger = nil
+	srv.LogDB = nil
+	srv.FailoverPolicies = config.GetFailoverPolicies()
+	srv.AccessLog = proxy.NewAccessLoggerWriter(&access, config.AccessLogJSON)
+
+	body := fmt.Sprintf(`{"model":%q,"max_tokens":16,"messages":[{"role":"user","content":"Reply with OK."}]}`, drillModel)
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	res := &drillResult{Primary: primary.Name, Status: rec.Code}
+	for _, line := range strings.Split(strings.TrimSpace(trace.String()), "\n") {
+		if line != "" {
+			res.Trace = append(res.Trace, line)
+		}
+	}
+
+	var entry struct {
+		Provider string `json:"provider"`
+	}
+	json.Unmarshal(access.Bytes(), &entry)
+	if rec.Code >= 300 || entry.Provider == "" || entry.Provider == primary.Name {
+		return res, nil
+	}
+	res.ServedBy = entry.Provider
+
+	var resp struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	res.GotModel = resp.Model
+	if res.GotModel == "" {
+		res.GotModel = "no model"
+	}
+
+	var served *proxy.Provider
+	for _, p := range providers {
+		if p.Name == res.ServedBy {
+			served = p
+		}
+	}
+	res.WantModel = served.SonnetModel
+
+	res.Env = mergeProviderEnvVarsForCLI(providers, cli, pc.GetEnvVarsForCLI(cli))
+	res.EnvIssues = drillEnvIssues(served, cli, res.Env)
+	return res, nil
+}
+
+// drillEnvIssues reports env vars of the serving provider that the merged
+// session environment sets differently. The session env is fixed at launch,
+// so these values don't follow a failover.
+func drillEnvIssues(served *proxy.Provider, cli string, merged map[string]string) []string {
+	own := served.GetEnvVarsForCLI(cli)
+	keys := make([]string, 0, len(own))
+	for k := range own {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var issues []string
+	for _, k := range keys {
+		if own[k] == "" || merged[k] == own[k] {
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("'%s' sets %s=%s but the session uses %s",
+			served.Name, k, maskEnvValue(k, own[k]), maskEnvValue(k, merged[k])))
+	}
+	return issues
+}
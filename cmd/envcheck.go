@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envConflict is a variable already set in the shell that affects the
+// launched CLI.
+type envConflict struct {
+	Key      string
+	Value    string
+	Override bool // opencc sets the variable itself; otherwise it may interfere
+}
+
+// proxyEnvKeys returns the variables setupCLIEnvironment sets for a CLI.
+func proxyEnvKeys(cliType CLIType) []string {
+	switch cliType {
+	case CLICodex:
+		return []string{"OPENAI_BASE_URL", "OPENAI_API_KEY"}
+	case CLIOpenCode:
+		return []string{"ANTHROPIC_BASE_URL", "ANTHROPIC_API_KEY", "OPENAI_BASE_URL", "OPENAI_API_KEY"}
+	default:
+		return []string{"ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN"}
+	}
+}
+
+// interferingEnvKeys lists variables opencc doesn't set but that change how a
+// CLI authenticates or where it sends requests, bypassing the proxy.
+var interferingEnvKeys = map[CLIType][]string{
+	CLIClaude: {"ANTHROPIC_API_KEY", "CLAUDE_CODE_USE_BEDROCK", "CLAUDE_CODE_USE_VERTEX"},
+}
+
+// detectEnvConflicts compares the current environment with the variables
+// opencc is about to set for cliBin (the proxy variables plus managed, the
+// merged provider and profile env_vars). It must run before any of them are set.
+func detectEnvConflicts(cliBin string, managed map[string]string) []envConflict {
+	cliType := GetCLIType(cliBin)
+	seen := make(map[string]bool)
+	var conflicts []envConflict
+
+	add := func(key string, override bool) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			conflicts = append(conflicts, envConflict{Key: key, Value: v, Override: override})
+		}
+	}
+
+	for _, key := range proxyEnvKeys(cliType) {
+		add(key, true)
+	}
+	keys := make([]string, 0, len(managed))
+	for k := range managed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if os.Getenv(k) == managed[k] {
+			seen[k] = true // already has the value opencc would set
+			continue
+		}
+		add(k, true)
+	}
+	for _, key := range interferingEnvKeys[cliType] {
+		add(key, false)
+	}
+	return conflicts
+}
+
+// formatEnvConflicts renders conflicts as an indented report. Values of
+// secret-looking variables are hidden.
+func formatEnvConflicts(conflicts []envConflict) string {
+	var b strings.Builder
+	b.WriteString("shell environment conflicts with opencc:\n")
+	for _, c := range conflicts {
+		value := maskEnvValue(c.Key, c.Value)
+		effect := "may interfere"
+		if c.Override {
+			effect = "overridden by opencc"
+		}
+		fmt.Fprintf(&b, "  %s=%s (%s)\n", c.Key, value, effect)
+	}
+	return b.String()
+}
+
+// maskEnvValue hides the value of secret-looking variables.
+func maskEnvValue(key, value string) string {
+	if strings.Contains(key, "KEY") || strings.Contains(key, "TOKEN") {
+		return "(set)"
+	}
+	return value
+}
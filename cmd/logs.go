@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// logsPollInterval is how often --follow checks for new entries.
+const logsPollInterval = time.Second
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the proxy request log",
+	Long: `Show the request log every proxy writes, oldest first, filtered by provider,
+client, request, level or status. With --follow, keep printing new entries as
+they are logged until interrupted.
+
+Examples:
+  opencc logs                             # The last 50 entries
+  opencc logs --provider backup --errors  # Errors and warnings of 'backup'
+  opencc logs --since 1h --follow         # The last hour, then new entries
+  opencc logs --request req_123 --json    # One request, as JSON lines`,
+	Args: cobra.NoArgs,
+	RunE: runLogs,
+}
+
+var (
+	logsProvider string
+	logsClient   string
+	logsRequest  string
+	logsLevel    string
+	logsErrors   bool
+	logsStatus   int
+	logsSince    time.Duration
+	logsLimit    int
+	logsFollow   bool
+	logsJSON     bool
+)
+
+func init() {
+	logsCmd.Flags().StringVar(&logsProvider, "provider", "", "only entries of this provider")
+	logsCmd.Flags().StringVar(&logsClient, "client", "", "only entries of this client")
+	logsCmd.Flags().StringVar(&logsRequest, "request", "", "only entries of this request ID")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "only entries of this level (info, warn, error)")
+	logsCmd.Flags().BoolVar(&logsErrors, "errors", false, "only errors and warnings")
+	logsCmd.Flags().IntVar(&logsStatus, "status", 0, "only entries with this status code")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "only entries from this far back (e.g. 30m, 1h)")
+	logsCmd.Flags().IntVarP(&logsLimit, "limit", "n", 50, "how many of the newest entries to show; 0 = all")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "keep printing new entries")
+	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "print entries as JSON lines")
+	logsCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.ProviderNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	filter, err := logsFilter()
+	if err != nil {
+		return err
+	}
+	db, err := proxy.OpenLogDB(config.ConfigDirPath())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return followLogs(ctx, db, filter, logsFollow, logsPollInterval, newLogPrinter(cmd.OutOrStdout(), logsJSON))
+}
+
+// logsFilter returns the filter the flags select.
+func logsFilter() (proxy.LogFilter, error) {
+	filter := proxy.LogFilter{
+		RequestID:  logsRequest,
+		Provider:   logsProvider,
+		Client:     logsClient,
+		Level:      proxy.LogLevel(logsLevel),
+		ErrorsOnly: logsErrors,
+		StatusCode: logsStatus,
+		Limit:      logsLimit,
+	}
+	switch filter.Level {
+	case "", proxy.LogLevelInfo, proxy.LogLevelWarn, proxy.LogLevelError:
+	default:
+		return filter, fmt.Errorf(i18n.T("unknown log level '%s', expected info, warn or error"), logsLevel)
+	}
+	if logsSince < 0 {
+		return filter, errors.New(i18n.T("--since must be a positive duration"))
+	}
+	if logsSince > 0 {
+		filter.Since = time.Now().Add(-logsSince)
+	}
+	return filter, nil
+}
+
+// followLogs prints the newest entries matching filter and, with follow,
+// checks for new ones every interval until ctx is done.
+func followLogs(ctx context.Context, db *proxy.LogDB, filter proxy.LogFilter, follow bool, interval time.Duration, print func(proxy.LogEntry)) error {
+	entries, lastID, err := db.Tail(filter, 0)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		print(e)
+	}
+	if !follow {
+		return nil
+	}
+
+	filter.Limit = 0
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		entries, lastID, err = db.Tail(filter, lastID)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			print(e)
+		}
+	}
+}
+
+// newLogPrinter returns a func writing entries to w, as JSON lines or
+// formatted for the terminal.
+func newLogPrinter(w io.Writer, asJSON bool) func(proxy.LogEntry) {
+	if asJSON {
+		return func(e proxy.LogEntry) {
+			if line, err := e.ToJSON(); err == nil {
+				fmt.Fprintf(w, "%s\n", line)
+			}
+		}
+	}
+	levels := map[proxy.LogLevel]lipgloss.Style{
+		proxy.LogLevelInfo:  lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+		proxy.LogLevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+		proxy.LogLevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("1")),
+	}
+	return func(e proxy.LogEntry) {
+		level := fmt.Sprintf("%-5s", strings.ToUpper(string(e.Level)))
+		if style, ok := levels[e.Level]; ok {
+			level = style.Render(level)
+		}
+		fmt.Fprintf(w, "%s %s %s\n", e.Timestamp.Local().Format("2006-01-02 15:04:05"), level, formatLogEntry(e))
+	}
+}
+
+// formatLogEntry renders the fields of an entry after its time and level.
+func formatLogEntry(e proxy.LogEntry) string {
+	var parts []string
+	if e.Provider != "" {
+		parts = append(parts, "["+e.Provider+"]")
+	}
+	if e.Method != "" && e.Path != "" {
+		parts = append(parts, e.Method+" "+e.Path)
+	}
+	if e.StatusCode > 0 {
+		parts = append(parts, fmt.Sprintf("%d", e.StatusCode))
+	}
+	if e.LatencyMs > 0 {
+		parts = append(parts, formatLatencyMs(e.LatencyMs))
+	}
+	if e.Model != "" {
+		parts = append(parts, "model="+e.Model)
+	}
+	if e.InputTokens > 0 || e.OutputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("tokens=%d/%d", e.InputTokens, e.OutputTokens))
+	}
+	if e.Client != "" {
+		parts = append(parts, "client="+e.Client)
+	}
+	if e.RequestID != "" {
+		parts = append(parts, "id="+e.RequestID)
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.Error != "" {
+		parts = append(parts, "error="+e.Error)
+	}
+	return strings.Join(parts, " ")
+}
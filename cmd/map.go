@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var mapCmd = &cobra.Command{
+	Use:   "map <model>",
+	Short: "Show which model each provider would receive",
+	Long: `Dry-run model mapping: show, for each provider in a profile's chain, the
+model a request for <model> would be rewritten to, and which rule chose it.
+Scenario routing is applied; nothing is sent.
+
+Examples:
+  opencc map claude-opus-4-5 -p work
+  opencc map claude-sonnet-4-5 --thinking    # As a thinking request`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMap,
+}
+
+var (
+	mapProfile  string
+	mapCLI      string
+	mapThinking bool
+)
+
+func init() {
+	mapCmd.Flags().StringVarP(&mapProfile, "profile", "p", "", "profile to map (default: bound or default profile)")
+	mapCmd.Flags().StringVar(&mapCLI, "cli", "", "CLI whose API format the request uses (claude, codex, opencode)")
+	mapCmd.Flags().BoolVar(&mapThinking, "thinking", false, "map a request with thinking enabled")
+}
+
+func runMap(cmd *cobra.Command, args []string) error {
+	names, profile, cli, err := resolveProviderNamesAndCLI(mapProfile, mapCLI)
+	if err != nil {
+		return err
+	}
+	scenario, plan, err := planProfileModels(names, profile, config.GetProfileConfig(profile), cli, args[0], mapThinking)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile '%s', model %s, scenario %s\n", profile, args[0], scenario)
+	for i, m := range plan {
+		if m.Fallback && (i == 0 || !plan[i-1].Fallback) {
+			fmt.Println("If the scenario route fails:")
+		}
+		fmt.Printf("  %d. %-16s → %-32s (%s)\n", i+1, m.Provider, m.Model, m.Rule)
+	}
+	return nil
+}
+
+// planProfileModels dry-runs model mapping for a request for model through
+// the profile's providers.
+func planProfileModels(names []string, profile string, pc *config.ProfileConfig, cli, model string, thinking bool) (config.Scenario, []proxy.ModelMapping, error) {
+	body := map[string]interface{}{
+		"model":    model,
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+	if thinking {
+		body["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": 1024}
+	}
+	scenario, plan, _, err := planRequest(names, profile, pc, cli, body)
+	return scenario, plan, err
+}
+
+// planRequest dry-runs routing and model mapping for body through the
+// profile's providers, also returning the providers it built.
+func planRequest(names []string, profile string, pc *config.ProfileConfig, cli string, body map[string]interface{}) (config.Scenario, []proxy.ModelMapping, []*proxy.Provider, error) {
+	providers, err := buildProviders(names)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	srv, err := buildProxyServer(providers, profile, pc, cli, log.New(io.Discard, "", 0))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	scenario, plan := srv.PlanModels(body)
+	return scenario, plan, providers, nil
+}
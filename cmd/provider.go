@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var providerCmd = &cobra.Command{
+	Use:   "provider",
+	Short: "Manage provider availability",
+}
+
+var providerCooldownCmd = &cobra.Command{
+	Use:   "cooldown <name>",
+	Short: "Mark a provider unavailable for a period",
+	Long: `Mark a provider unavailable for a period without removing it from any profile.
+The proxy skips the provider until the cooldown ends. The cooldown is saved in
+the config file, so it survives restarts and applies to running sessions.
+
+Examples:
+  opencc provider cooldown work --for 2h    # Skip 'work' for two hours
+  opencc provider cooldown work --clear     # Make 'work' available again`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigNames,
+	RunE:              runProviderCooldown,
+}
+
+var providerStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show provider availability and rate limits",
+	Long: `Show each provider's availability and the rate limits it reported in its
+latest response: remaining requests and tokens, and when they reset. Rate
+limits are recorded by every proxy, so this shows what running sessions see.`,
+	Args: cobra.NoArgs,
+	RunE: runProviderStatus,
+}
+
+var (
+	cooldownFor   time.Duration
+	cooldownClear bool
+)
+
+func init() {
+	providerCooldownCmd.Flags().DurationVar(&cooldownFor, "for", 0, "how long to keep the provider unavailable (e.g. 30m, 2h)")
+	providerCooldownCmd.Flags().BoolVar(&cooldownClear, "clear", false, "end the cooldown now")
+	providerCmd.AddCommand(providerCooldownCmd)
+	providerCmd.AddCommand(providerStatusCmd)
+}
+
+func runProviderCooldown(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if config.GetProvider(name) == nil {
+		return fmt.Errorf(i18n.T("provider '%s' not found"), name)
+	}
+
+	if cooldownClear {
+		if err := config.SetProviderCooldown(name, time.Time{}); err != nil {
+			return err
+		}
+		fmt.Printf("Cleared cooldown for provider '%s'\n", name)
+		return nil
+	}
+
+	if cooldownFor <= 0 {
+		return errors.New(i18n.T("specify a positive --for duration or --clear"))
+	}
+	until := time.Now().Add(cooldownFor).Truncate(time.Second)
+	if err := config.SetProviderCooldown(name, until); err != nil {
+		return err
+	}
+	fmt.Printf("Provider '%s' unavailable until %s\n", name, until.Format("2006-01-02 15:04"))
+	return nil
+}
+
+func runProviderStatus(cmd *cobra.Command, args []string) error {
+	names := config.ProviderNames()
+	if len(names) == 0 {
+		fmt.Println("No providers configured.")
+		return nil
+	}
+	sort.Strings(names)
+
+	db, err := proxy.OpenLogDB(config.ConfigDirPath())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	limits, err := db.RateLimits()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fmt.Printf("%-16s %-24s %-18s %-22s %s\n", "PROVIDER", "STATUS", "REQUESTS", "TOKENS", "OBSERVED")
+	for _, name := range names {
+		status := "available"
+		if p := config.GetProvider(name); p != nil {
+			if reason := p.UnavailableReason(now); reason != "" {
+				status = reason
+			}
+		}
+		requests, tokens, observed := "-", "-", "-"
+		if rl := limits[name]; rl != nil {
+			requests = formatRateLimitWindow(rl.Requests, now)
+			tokens = formatRateLimitWindow(rl.Tokens, now)
+			observed = now.Sub(rl.ObservedAt).Round(time.Second).String() + " ago"
+		}
+		fmt.Printf("%-16s %-24s %-18s %-22s %s\n", name, status, requests, tokens, observed)
+	}
+	return nil
+}
+
+// formatRateLimitWindow renders a window as "remaining/limit", with the time
+// left until it resets if that is still ahead.
+func formatRateLimitWindow(w *proxy.RateLimitWindow, now time.Time) string {
+	if w == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%d/%d", w.Remaining, w.Limit)
+	if w.Reset.After(now) {
+		s += fmt.Sprintf(" (%v)", w.Reset.Sub(now).Round(time.Second))
+	}
+	return s
+}
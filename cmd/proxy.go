@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/daemon"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Manage the shared proxy",
+	Long: fmt.Sprintf(`Run one long-lived proxy in the background on 127.0.0.1:%d. Sessions
+started with the same profile and CLI use it instead of starting their own, so
+they share provider health, session state and metrics.
+
+Examples:
+  opencc proxy start -p work    # Start the shared proxy for 'work'
+  opencc proxy status           # Show its providers and their health
+  opencc proxy stop             # Stop it; new sessions start their own again`, config.DefaultProxyPort),
+}
+
+var proxyStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the shared proxy in the background",
+	Args:  cobra.NoArgs,
+	RunE:  runProxyStart,
+}
+
+var proxyStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the shared proxy",
+	Args:  cobra.NoArgs,
+	RunE:  runProxyStop,
+}
+
+var proxyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the shared proxy's profile and provider health",
+	Args:  cobra.NoArgs,
+	RunE:  runProxyStatus,
+}
+
+var (
+	proxyProfile string
+	proxyCLI     string
+)
+
+func init() {
+	proxyStartCmd.Flags().StringVarP(&proxyProfile, "profile", "p", "", "profile to serve (default: bound or default profile)")
+	proxyStartCmd.Flags().StringVar(&proxyCLI, "cli", "", "CLI whose sessions use the proxy (claude, codex, opencode)")
+	proxyCmd.AddCommand(proxyStartCmd)
+	proxyCmd.AddCommand(proxyStopCmd)
+	proxyCmd.AddCommand(proxyStatusCmd)
+}
+
+// sharedProxyAddr is where the shared proxy listens.
+func sharedProxyAddr() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(config.DefaultProxyPort))
+}
+
+// proxyStatusClient returns the URL scheme and a client for reaching a
+// proxy started with the current config.
+func proxyStatusClient() (string, *http.Client, error) {
+	client := &http.Client{Timeout: 500 * time.Millisecond}
+	_, certFile, err := proxyTLS()
+	if err != nil || certFile == "" {
+		return "http", client, err
+	}
+	tc, err := proxy.ClientTLSConfig(certFile)
+	if err != nil {
+		return "", nil, err
+	}
+	client.Transport = &http.Transport{TLSClientConfig: tc}
+	return "https", client, nil
+}
+
+// fetchProxyStatus asks the proxy at addr for its status.
+func fetchProxyStatus(addr string) (*proxy.ProxyStatus, error) {
+	scheme, client, err := proxyStatusClient()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, scheme+"://"+addr+proxy.StatusPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := config.GetProxyAuthToken(); token != "" {
+		req.Header.Set(proxy.AuthHeader, token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status endpoint returned %s", resp.Status)
+	}
+	var st proxy.ProxyStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// sharedProxyURL returns the URL of the proxy at addr if it serves profile
+// for cli, so a session can use it instead of starting its own.
+func sharedProxyURL(addr, profile, cli string) (string, bool) {
+	st, err := fetchProxyStatus(addr)
+	if err != nil || st.Profile != profile || st.CLI != cli {
+		return "", false
+	}
+	scheme, _, _ := proxyStatusClient()
+	return scheme + "://" + addr, true
+}
+
+func runProxyStart(cmd *cobra.Command, args []string) error {
+	names, profile, cli, err := resolveProviderNamesAndCLI(proxyProfile, proxyCLI)
+	if err != nil {
+		return err
+	}
+	if _, err := validateProviderNames(names, profile); err != nil {
+		return err
+	}
+	if cli == "" {
+		cli = config.DefaultCLIName
+	}
+
+	addr := sharedProxyAddr()
+	if st, err := fetchProxyStatus(addr); err == nil {
+		fmt.Printf("Shared proxy already running on %s for profile '%s' (%s).\n", addr, st.Profile, st.CLI)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot determine executable path: %w", err)
+	}
+	logDir := config.ConfigDirPath()
+	os.MkdirAll(logDir, 0755)
+	logFile, err := os.OpenFile(filepath.Join(logDir, "proxy.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	child := exec.Command(exe, "serve", "--profile", profile, "--cli", cli, "--listen", addr)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.SysProcAttr = daemon.DaemonSysProcAttr()
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start proxy: %w", err)
+	}
+	daemon.WriteProxyPid(child.Process.Pid)
+
+	exited := make(chan struct{})
+	go func() {
+		child.Wait()
+		close(exited)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		if _, err := fetchProxyStatus(addr); err == nil {
+			break
+		}
+		select {
+		case <-exited:
+			daemon.RemoveProxyPid()
+			return fmt.Errorf(i18n.T("shared proxy exited on startup; see %s"), logFile.Name())
+		case <-ctx.Done():
+			return fmt.Errorf(i18n.T("shared proxy started but did not become ready; see %s"), logFile.Name())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	fmt.Printf("Shared proxy started in background (PID %d) on %s for profile '%s' (%s).\n", child.Process.Pid, addr, profile, cli)
+	return nil
+}
+
+func runProxyStop(cmd *cobra.Command, args []string) error {
+	pid, err := daemon.ReadProxyPid()
+	if err != nil {
+		fmt.Println("Shared proxy is not running.")
+		return nil
+	}
+	if _, err := fetchProxyStatus(sharedProxyAddr()); err != nil {
+		daemon.RemoveProxyPid()
+		fmt.Println("Shared proxy is not running.")
+		return nil
+	}
+	if err := daemon.StopProcess(pid); err != nil {
+		return err
+	}
+	daemon.RemoveProxyPid()
+	fmt.Println("Shared proxy stopped.")
+	return nil
+}
+
+func runProxyStatus(cmd *cobra.Command, args []string) error {
+	addr := sharedProxyAddr()
+	st, err := fetchProxyStatus(addr)
+	if err != nil {
+		fmt.Println("Shared proxy is not running.")
+		return nil
+	}
+	running := fmt.Sprintf("Shared proxy is running on %s", addr)
+	if pid, err := daemon.ReadProxyPid(); err == nil {
+		running = fmt.Sprintf("Shared proxy is running (PID %d) on %s", pid, addr)
+	}
+	fmt.Printf("%s for profile '%s' (%s).\n\n", running, st.Profile, st.CLI)
+
+	now := time.Now()
+	fmt.Printf("%-16s %-32s %s\n", "PROVIDER", "STATUS", "REQUESTS")
+	for _, ps := range st.Providers {
+		status := "healthy"
+		switch {
+		case ps.Unavailable != "":
+			status = ps.Unavailable
+		case !ps.Healthy && ps.RetryAt != nil:
+			status = fmt.Sprintf("backoff (retry in %v)", ps.RetryAt.Sub(now).Round(time.Second))
+		}
+		var requests uint64
+		for _, n := range ps.Requests {
+			requests += n
+		}
+		fmt.Printf("%-16s %-32s %d\n", ps.Name, status, requests)
+	}
+	return nil
+}
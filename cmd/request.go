@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// requestTemplateDir is the directory under the config dir holding saved
+// request templates, one <name>.json each.
+const requestTemplateDir = "requests"
+
+var requestTemplateName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// requestTemplate is a saved request, stored as JSON.
+type requestTemplate struct {
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+var requestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Save and send reusable test requests",
+	Long: `Save request bodies as named templates and send them through the proxy,
+to check provider behavior (tool calls, images, thinking) after config changes.
+Templates are stored in ~/.opencc/requests/<name>.json.`,
+}
+
+var requestSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a request body as a template",
+	Long: `Save a JSON request body, read from --file or stdin, as a named template.
+An existing template with the same name is replaced.
+
+Examples:
+  opencc request save tools --file tools.json
+  cat thinking.json | opencc request save thinking --header anthropic-beta:interleaved-thinking-2025-05-14
+  opencc request save chat --path /v1/chat/completions --file chat.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRequestSave,
+}
+
+var requestSendCmd = &cobra.Command{
+	Use:   "send <name>",
+	Short: "Send a saved request through the proxy",
+	Long: `Send a saved request through the proxy in-process, with the profile's
+failover, routing and model mapping, and print the response. Use --provider to
+send it to a single provider. Exits non-zero unless a provider answers 2xx.
+
+Examples:
+  opencc request send tools                   # Default or bound profile
+  opencc request send tools -p work           # The 'work' profile
+  opencc request send tools --provider backup # Only the 'backup' provider`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRequestTemplates,
+	RunE:              runRequestSend,
+}
+
+var requestListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved request templates",
+	Args:  cobra.NoArgs,
+	RunE:  runRequestList,
+}
+
+var (
+	requestFile     string
+	requestPath     string
+	requestHeaders  []string
+	requestProfile  string
+	requestProvider string
+	requestCLI      string
+)
+
+func init() {
+	requestSaveCmd.Flags().StringVarP(&requestFile, "file", "F", "", "read the request body from a file (default: stdin)")
+	requestSaveCmd.Flags().StringVar(&requestPath, "path", "/v1/messages", "API path the request is sent to")
+	requestSaveCmd.Flags().StringArrayVarP(&requestHeaders, "header", "H", nil, "extra request header as name:value (repeatable)")
+	requestSendCmd.Flags().StringVarP(&requestProfile, "profile", "p", "", "profile to send through (default: bound or default profile)")
+	requestSendCmd.Flags().StringVar(&requestProvider, "provider", "", "send to this provider only")
+	requestSendCmd.Flags().StringVar(&requestCLI, "cli", "", "CLI whose API format the template uses (claude, codex, opencode)")
+	requestCmd.AddCommand(requestSaveCmd)
+	requestCmd.AddCommand(requestSendCmd)
+	requestCmd.AddCommand(requestListCmd)
+}
+
+func runRequestSave(cmd *cobra.Command, args []string) error {
+	var body []byte
+	var err error
+	if requestFile != "" {
+		body, err = os.ReadFile(requestFile)
+	} else {
+		body, err = io.ReadAll(stdinReader)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	tmpl := &requestTemplate{Path: requestPath, Body: bytes.TrimSpace(body)}
+	for _, h := range requestHeaders {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf(i18n.T("invalid header '%s', expected name:value"), h)
+		}
+		if tmpl.Headers == nil {
+			tmpl.Headers = make(map[string]string)
+		}
+		tmpl.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if err := saveRequestTemplate(args[0], tmpl); err != nil {
+		return err
+	}
+	fmt.Printf("Saved request template '%s' (%s)\n", args[0], tmpl.Path)
+	return nil
+}
+
+func runRequestSend(cmd *cobra.Command, args []string) error {
+	tmpl, err := loadRequestTemplate(args[0])
+	if err != nil {
+		return err
+	}
+
+	if requestProvider != "" && requestProfile != "" {
+		return errors.New(i18n.T("use either --provider or --profile, not both"))
+	}
+
+	var names []string
+	var pc *config.ProfileConfig
+	cli := requestCLI
+	if requestProvider != "" {
+		names = []string{requestProvider}
+	} else {
+		var profile string
+		names, profile, cli, err = resolveProviderNamesAndCLI(requestProfile, requestCLI)
+		if err != nil {
+			return err
+		}
+		pc = config.GetProfileConfig(profile)
+	}
+	if cli == "" {
+		cli = config.DefaultCLIName
+	}
+
+	res, err := sendRequestTemplate(tmpl, names, pc, cli)
+	if err != nil {
+		return err
+	}
+	for _, line := range res.Trace {
+		fmt.Printf("  %s\n", line)
+	}
+	if res.ServedBy != "" {
+		fmt.Printf("Served by '%s' (%d)\n", res.ServedBy, res.Status)
+	} else {
+		fmt.Printf("Status %d\n", res.Status)
+	}
+	fmt.Println(string(res.Body))
+	if res.Status < 200 || res.Status >= 300 {
+		return fmt.Errorf(i18n.T("request '%s' failed with status %d"), args[0], res.Status)
+	}
+	return nil
+}
+
+func runRequestList(cmd *cobra.Command, args []string) error {
+	names, err := requestTemplateNames()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("No request templates saved. Run 'opencc request save <name>' to add one.")
+		return nil
+	}
+	for _, name := range names {
+		tmpl, err := loadRequestTemplate(name)
+		if err != nil {
+			fmt.Printf("  %-20s (%v)\n", name, err)
+			continue
+		}
+		fmt.Printf("  %-20s %s\n", name, tmpl.Path)
+	}
+	return nil
+}
+
+// requestResult is the outcome of sending a request template.
+type requestResult struct {
+	Status   int
+	ServedBy string   // provider that answered; "" if none did
+	Body     []byte   // response body, indented if JSON
+	Trace    []string // proxy log lines for the request
+}
+
+// sendRequestTemplate runs the proxy in-process for the providers and sends
+// the template through it.
+func sendRequestTemplate(tmpl *requestTemplate, names []string, pc *config.ProfileConfig, cli string) (*requestResult, error) {
+	providers, err := buildProviders(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace, access bytes.Buffer
+	srv, err := buildProxyServer(providers, "", pc, cli, log.New(&trace, "", 0))
+	if err != nil {
+		return nil, err
+	}
+	srv.ClientName = "request"
+	srv.StructuredLogger = nil
+	srv.LogDB = nil
+	srv.AccessLog = proxy.NewAccessLoggerWriter(&access, config.AccessLogJSON)
+
+	req := httptest.NewRequest(http.MethodPost, tmpl.Path, bytes.NewReader(tmpl.Body))
+	req.Header.Set("Content-Type", "application/json")
+	if srv.ClientFormat == config.ProviderTypeAnthropic {
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+	for name, value := range tmpl.Headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	res := &requestResult{Status: rec.Code, Body: rec.Body.Bytes()}
+	var indented bytes.Buffer
+	if json.Indent(&indented, res.Body, "", "  ") == nil {
+		res.Body = indented.Bytes()
+	}
+	for _, line := range strings.Split(strings.TrimSpace(trace.String()), "\n") {
+		if line != "" {
+			res.Trace = append(res.Trace, line)
+		}
+	}
+	var entry struct {
+		Provider string `json:"provider"`
+	}
+	json.Unmarshal(access.Bytes(), &entry)
+	res.ServedBy = entry.Provider
+	return res, nil
+}
+
+// requestTemplatePath returns the file of a template, rejecting names that
+// would leave the templates directory.
+func requestTemplatePath(name string) (string, error) {
+	if !requestTemplateName.MatchString(name) {
+		return "", fmt.Errorf(i18n.T("invalid template name '%s': use letters, digits, '.', '_' and '-'"), name)
+	}
+	return filepath.Join(config.ConfigDirPath(), requestTemplateDir, name+".json"), nil
+}
+
+func saveRequestTemplate(name string, tmpl *requestTemplate) error {
+	path, err := requestTemplatePath(name)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(tmpl.Body) {
+		return errors.New(i18n.T("request body is not valid JSON"))
+	}
+	if !strings.HasPrefix(tmpl.Path, "/") {
+		return fmt.Errorf(i18n.T("invalid path '%s': must start with '/'"), tmpl.Path)
+	}
+	data, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Templates may carry headers with credentials
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+func loadRequestTemplate(name string) (*requestTemplate, error) {
+	path, err := requestTemplatePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf(i18n.T("request template '%s' not found"), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tmpl requestTemplate
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf(i18n.T("invalid request template '%s': %w"), name, err)
+	}
+	if tmpl.Path == "" {
+		tmpl.Path = "/v1/messages"
+	}
+	return &tmpl, nil
+}
+
+// requestTemplateNames returns the saved template names, sorted.
+func requestTemplateNames() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(config.ConfigDirPath(), requestTemplateDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() && requestTemplateName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func completeRequestTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, _ := requestTemplateNames()
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -2,19 +2,29 @@ package cmd
 
 import (
 	"bufio"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/dopejs/opencc/internal/proxy"
 	"github.com/dopejs/opencc/tui"
 	"github.com/spf13/cobra"
@@ -34,10 +44,23 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:       true,
 	SilenceErrors:      true,
 	RunE:               runProxy,
+	PersistentPreRunE:  setupCommand,
 }
 
 var cliFlag string
 var legacyTUI bool
+var strictEnvFlag bool
+var metricsListen string
+var debugCaptureFlag bool
+var proxyPortFlag int
+var headlessFlag bool
+
+// headlessEnv enables headless mode like --headless, e.g. in a container image.
+const headlessEnv = "OPENCC_HEADLESS"
+
+// interactiveAnnotation marks commands that need a terminal; headless mode
+// refuses them and their subcommands, unless a subcommand sets it to "false".
+const interactiveAnnotation = "interactive"
 
 func init() {
 	// -p/--profile is the new flag, -f/--fallback is kept for backward compatibility but hidden
@@ -48,6 +71,11 @@ func init() {
 	rootCmd.Flags().Lookup("fallback").Hidden = true
 	rootCmd.Flags().StringVar(&cliFlag, "cli", "", "CLI to use (claude, codex, opencode)")
 	rootCmd.Flags().BoolVar(&legacyTUI, "legacy", false, "use legacy TUI interface")
+	rootCmd.Flags().BoolVar(&strictEnvFlag, "strict-env", false, "refuse to start when shell env vars conflict with opencc")
+	rootCmd.Flags().IntVar(&proxyPortFlag, "port", 0, "listen on this port instead of a random one (overrides proxy_port)")
+	rootCmd.Flags().StringVar(&metricsListen, "metrics", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9464 (overrides metrics_listen)")
+	rootCmd.Flags().BoolVar(&debugCaptureFlag, "debug-capture", false, "write every provider request and response, with credentials redacted, to ~/.opencc/captures")
+	rootCmd.PersistentFlags().BoolVar(&headlessFlag, "headless", false, "never open interactive pickers; fail on misconfiguration (also "+headlessEnv+"=1)")
 	rootCmd.AddCommand(useCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(listCmd)
@@ -58,7 +86,28 @@ func init() {
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(bindCmd)
 	rootCmd.AddCommand(unbindCmd)
+	rootCmd.AddCommand(trustCmd)
+	rootCmd.AddCommand(untrustCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(providerCmd)
+	rootCmd.AddCommand(drillCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(proxyCmd)
+	rootCmd.AddCommand(requestCmd)
+	rootCmd.AddCommand(mapCmd)
+	rootCmd.AddCommand(routeCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(smokeTestCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(logsCmd)
+
+	for _, c := range []*cobra.Command{configCmd, listCmd, pickCmd} {
+		if c.Annotations == nil {
+			c.Annotations = map[string]string{}
+		}
+		c.Annotations[interactiveAnnotation] = "true"
+	}
 
 	// Set custom help function only for root command
 	defaultHelp := rootCmd.HelpFunc()
@@ -90,6 +139,9 @@ Configuration:
   config add profile [name]    Add a new profile
   config edit provider <name>  Edit an existing provider
   config delete provider <name> Delete a provider
+  provider cooldown <name>     Mark a provider unavailable (--for 2h)
+  provider status              Show provider availability and rate limits
+  stats [--since 1h]           Show per-provider request metrics
 
 Project Binding:
   bind <profile>               Bind current directory to a profile
@@ -97,6 +149,17 @@ Project Binding:
   unbind                       Remove binding for current directory
   status                       Show binding status
 
+Headless / Containers:
+  serve -p <profile>           Run the proxy in the foreground for other clients
+  serve --listen 0.0.0.0       Listen on all interfaces (e.g. in Docker)
+  --metrics 127.0.0.1:9464     Serve Prometheus metrics of the proxy
+  --headless                   Never open a TUI; fail on misconfiguration
+
+Shared Proxy:
+  proxy start -p <profile>     Run one background proxy that sessions share
+  proxy stop                   Stop the shared proxy
+  proxy status                 Show the shared proxy's providers and health
+
 Web Interface:
   web                          Start web UI (foreground, opens browser)
   web -d                       Start web UI (background daemon)
@@ -108,6 +171,10 @@ Web Interface:
 Other Commands:
   list                         List all providers and profiles
   pick                         Interactively select providers
+  drill -p <profile>           Simulate a primary outage and test failover
+  request save|send <name>     Save and replay test requests
+  map <model> -p <profile>     Show which model each provider would receive
+  compare "<prompt>" -p <p>    Send a prompt to several providers and compare
   use <provider>               Use a specific provider directly
   upgrade                      Upgrade to latest version
   version                      Show version
@@ -123,6 +190,38 @@ Use "%s [command] --help" for more information about a command.`,
 		cmd.CommandPath())
 }
 
+// isHeadless reports whether --headless or OPENCC_HEADLESS is set.
+func isHeadless() bool {
+	if headlessFlag {
+		return true
+	}
+	on, _ := strconv.ParseBool(os.Getenv(headlessEnv))
+	return on
+}
+
+// checkHeadless refuses TUI commands in headless mode.
+// setupCommand selects the UI language and applies headless restrictions
+// before any command runs.
+func setupCommand(cmd *cobra.Command, args []string) error {
+	i18n.SetLocale(i18n.Detect(config.GetLocale()))
+	return checkHeadless(cmd, args)
+}
+
+func checkHeadless(cmd *cobra.Command, args []string) error {
+	if !isHeadless() {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if v, ok := c.Annotations[interactiveAnnotation]; ok {
+			if v == "true" {
+				return fmt.Errorf(i18n.T("'%s' needs an interactive terminal; not available in headless mode"), cmd.CommandPath())
+			}
+			break
+		}
+	}
+	return nil
+}
+
 func Execute() error {
 	// Pre-process: when -p/--profile or -f/--fallback uses NoOptDefVal, cobra won't consume
 	// the next arg as its value. Merge "-p <name>" into "-p=<name>" so that
@@ -162,17 +261,20 @@ func runProxy(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Get the full profile config for routing support
-	pc := config.GetProfileConfig(profile)
+	// Get the full profile config for routing support, with the project's
+	// scenario routes, unless the binding pins a provider
+	pc := projectConfig().WithRouting(config.GetProfileConfig(profile))
+	providerNames, pc = pinBindingProvider(profile, providerNames, pc)
 
-	return startProxy(providerNames, pc, cli, args)
+	return startProxy(providerNames, profile, pc, cli, args)
 }
 
-func startProxy(names []string, pc *config.ProfileConfig, cli string, args []string) error {
+func startProxy(names []string, profile string, pc *config.ProfileConfig, cli string, args []string) error {
 	providers, err := buildProviders(names)
 	if err != nil {
 		return err
 	}
+	pinBindingModel(profile, providers)
 
 	// Set up logger
 	logDir := config.ConfigDirPath()
@@ -194,6 +296,7 @@ func startProxy(names []string, pc *config.ProfileConfig, cli string, args []str
 	if err := proxy.InitGlobalLogger(logDir); err != nil {
 		logger.Printf("Warning: failed to initialize structured logger: %v", err)
 	}
+	defer startAutoOrder(logger)()
 
 	logger.Printf("Starting proxy with %d providers:", len(providers))
 	for i, p := range providers {
@@ -210,47 +313,111 @@ func startProxy(names []string, pc *config.ProfileConfig, cli string, args []str
 	clientFormat := GetCLIClientFormat(GetCLIType(cliBin))
 	logger.Printf("CLI: %s, Client format: %s", cliBin, clientFormat)
 
-	// Start proxy — with routing if configured, otherwise plain
-	var port int
-	if pc != nil && len(pc.Routing) > 0 {
-		routingCfg, err := buildRoutingConfig(pc, providers, logger)
+	// Merge env_vars from all providers for this specific CLI
+	// For numeric values like ANTHROPIC_MAX_CONTEXT_WINDOW, use the minimum value
+	// This ensures the CLI respects the most restrictive provider's limit
+	// Profile env_vars are applied last and override provider values
+	mergedEnvVars := mergeProviderEnvVarsForCLI(providers, cliBin, pc.GetEnvVarsForCLI(cliBin))
+
+	// Extra args and env for the CLI from global, profile and binding templates
+	launch := resolveLaunchTemplate(cliBin, pc)
+
+	// Report shell variables that will be overridden or may bypass the proxy
+	managedEnv := make(map[string]string, len(mergedEnvVars)+len(launch.Env))
+	for k, v := range mergedEnvVars {
+		managedEnv[k] = v
+	}
+	for k, v := range launch.Env {
+		managedEnv[k] = v
+	}
+	if conflicts := detectEnvConflicts(cliBin, managedEnv); len(conflicts) > 0 {
+		report := formatEnvConflicts(conflicts)
+		logger.Printf("Environment: %s", report)
+		if strictEnvFlag || config.GetStrictEnv() {
+			return fmt.Errorf(i18n.T("refusing to start (strict env): %s"), strings.TrimSuffix(report, "\n"))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s", report)
+	}
+
+	// Use the shared proxy if it serves this profile and CLI, unless the
+	// binding pins a provider or model it may not; otherwise start one —
+	// with routing if configured, otherwise plain
+	var proxyURL string
+	var shared bool
+	if provider, model := bindingPin(profile); provider == "" && model == "" {
+		proxyURL, shared = sharedProxyURL(sharedProxyAddr(), profile, cliBin)
+	}
+	if shared {
+		logger.Printf("Using the shared proxy on %s", proxyURL)
+	} else {
+		srv, cleanup, err := newProxyServer(providers, profile, pc, cliBin, logger, logDir)
 		if err != nil {
-			return fmt.Errorf("failed to build routing config: %w", err)
+			return err
 		}
-		port, err = proxy.StartProxyWithRouting(routingCfg, clientFormat, "127.0.0.1:0", logger)
+		defer cleanup()
+		// Only the launched CLI knows this proxy's random port
+		srv.ClientName = cliBin
+
+		addr, fixed, err := proxyListenAddr()
 		if err != nil {
-			return fmt.Errorf("failed to start proxy: %w", err)
+			return err
 		}
-	} else {
-		port, err = proxy.StartProxy(providers, clientFormat, "127.0.0.1:0", logger)
+		port, err := proxy.ServeProxy(srv, addr)
 		if err != nil {
+			if fixed {
+				return fmt.Errorf(i18n.T("cannot listen on %s (port in use?); choose another with --port or proxy_port: %w"), addr, err)
+			}
 			return fmt.Errorf("failed to start proxy: %w", err)
 		}
+
+		host, _, _ := net.SplitHostPort(addr)
+		logger.Printf("Proxy listening on %s", net.JoinHostPort(host, strconv.Itoa(port)))
+		proxyURL = proxyClientURL(addr, port, srv.TLS != nil)
 	}
 
-	logger.Printf("Proxy listening on 127.0.0.1:%d", port)
+	if config.GetPreflightCheck() {
+		if warning := preflightWarning(providers); warning != "" {
+			logger.Printf("Preflight: %s", warning)
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+	}
 
-	// Merge env_vars from all providers for this specific CLI
-	// For numeric values like ANTHROPIC_MAX_CONTEXT_WINDOW, use the minimum value
-	// This ensures the CLI respects the most restrictive provider's limit
-	mergedEnvVars := mergeProviderEnvVarsForCLI(providers, cliBin)
+	// Apply the merged provider and profile env_vars
 	for k, v := range mergedEnvVars {
 		os.Setenv(k, v)
 		logger.Printf("Setting env: %s=%s", k, v)
 	}
 
+	// Let Node-based CLIs trust the proxy's certificate
+	if strings.HasPrefix(proxyURL, "https://") && os.Getenv("NODE_EXTRA_CA_CERTS") == "" {
+		if _, certFile, err := proxyTLS(); err == nil && certFile != "" {
+			os.Setenv("NODE_EXTRA_CA_CERTS", certFile)
+			logger.Printf("Setting env: NODE_EXTRA_CA_CERTS=%s", certFile)
+		}
+	}
+
 	// Set environment variables based on CLI type
-	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", port)
 	setupCLIEnvironment(cliBin, proxyURL, logger)
 
 	// Find CLI binary
 	cliPath, err := exec.LookPath(cliBin)
 	if err != nil {
-		return fmt.Errorf("%s not found in PATH: %w", cliBin, err)
+		return fmt.Errorf(i18n.T("%s not found in PATH: %w"), cliBin, err)
 	}
 
 	// Start CLI as subprocess (not exec, so proxy stays alive)
-	cliCmd := exec.Command(cliPath, args...)
+	cliArgs := append(append([]string{}, launch.Args...), args...)
+	if len(launch.Args) > 0 {
+		logger.Printf("Launch args: %v", launch.Args)
+	}
+	cliCmd := exec.Command(cliPath, cliArgs...)
+	if len(launch.Env) > 0 {
+		cliCmd.Env = os.Environ()
+		for k, v := range launch.Env {
+			cliCmd.Env = append(cliCmd.Env, k+"="+v)
+			logger.Printf("Launch env: %s=%s", k, v)
+		}
+	}
 	cliCmd.Stdin = os.Stdin
 	cliCmd.Stdout = os.Stdout
 	cliCmd.Stderr = os.Stderr
@@ -275,6 +442,319 @@ func startProxy(names []string, pc *config.ProfileConfig, cli string, args []str
 	return nil
 }
 
+// newProxyServer builds the proxy for a provider chain with the profile's
+// routing and strategy and the global proxy settings. cleanup closes the
+// files opened for it.
+func newProxyServer(providers []*proxy.Provider, profile string, pc *config.ProfileConfig, cli string, logger *log.Logger, logDir string) (srv *proxy.ProxyServer, cleanup func(), err error) {
+	srv, err = buildProxyServer(providers, profile, pc, cli, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bq := config.GetBackoffQueue(); bq != nil {
+		srv.BackoffQueueWait = time.Duration(bq.MaxWaitSeconds) * time.Second
+		srv.BackoffQueueSize = bq.MaxQueued
+	}
+	srv.UsageWarnings = config.GetUsageWarnings()
+	srv.AuthToken = config.GetProxyAuthToken()
+	if srv.TLS, _, err = proxyTLS(); err != nil {
+		return nil, nil, err
+	}
+	if ldb := proxy.GetGlobalLogDB(); ldb != nil {
+		if err := srv.LoadBudgetSpend(ldb, time.Now()); err != nil {
+			logger.Printf("Warning: failed to load budget spend: %v", err)
+		}
+	}
+	if rs := config.GetRequestSize(); rs != nil {
+		if !rs.Oversized.IsValid() {
+			fmt.Fprintf(os.Stderr, "Warning: unknown oversized action '%s', using reject\n", rs.Oversized)
+		}
+		srv.MaxRequestBytes = rs.MaxBytes
+		srv.OversizedReroute = rs.Oversized == config.OversizedLongContext
+	}
+	if rc := config.GetResponseCache(); rc != nil {
+		srv.Cache = proxy.NewResponseCache(rc.TTL(), rc.Size(), rc.CachedPaths())
+	}
+	srv.Hooks = config.GetHooks()
+
+	var closers []func()
+	cleanup = func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	// A redactor that can't be built stops the proxy rather than let
+	// requests through unfiltered
+	if rc := config.RedactionFor(config.GetRedaction(), pc); rc != nil {
+		auditPath := rc.AuditLog
+		if auditPath == "" {
+			auditPath = filepath.Join(logDir, proxy.DefaultRedactionLogFile)
+		}
+		redactor, err := proxy.NewRedactor(rc, auditPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		srv.Redactor = redactor
+		closers = append(closers, func() { redactor.Close() })
+	}
+	if al := config.GetAccessLog(); al != nil {
+		accessLog, err := openAccessLog(al, logDir)
+		if err != nil {
+			logger.Printf("Warning: %v", err)
+		} else {
+			srv.AccessLog = accessLog
+			closers = append(closers, func() { accessLog.Close() })
+		}
+	}
+	if dc := debugCapture(); dc != nil {
+		capture, err := openCapture(dc, logDir)
+		if err != nil {
+			logger.Printf("Warning: %v", err)
+		} else {
+			srv.Capture = capture
+			logger.Printf("Capturing provider requests and responses to %s", capture.Dir())
+		}
+	}
+	if hc := config.GetHealthCheck(); hc != nil && hc.IntervalSeconds > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go srv.RunHealthChecks(ctx, proxy.HealthCheck{
+			Interval: time.Duration(hc.IntervalSeconds) * time.Second,
+			Timeout:  time.Duration(hc.TimeoutSeconds) * time.Second,
+			Path:     hc.Path,
+		})
+		closers = append(closers, cancel)
+		logger.Printf("Health checks every %ds", hc.IntervalSeconds)
+	}
+	if addr := metricsAddr(); addr != "" {
+		if closeMetrics, err := serveMetrics(srv, addr, logger); err != nil {
+			logger.Printf("Warning: %v", err)
+		} else {
+			closers = append(closers, closeMetrics)
+		}
+	}
+
+	// Pick up provider and routing changes without a restart
+	srv.Loader = profileChainLoader(profile, logger)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go srv.WatchConfig(watchCtx, config.ConfigFilePath(), proxy.DefaultConfigPollInterval)
+	closers = append(closers, stopWatch)
+	return srv, cleanup, nil
+}
+
+// profileChainLoader returns a loader that rebuilds the profile's providers
+// and routing from the current config, for reloading a running proxy.
+// Providers that no longer exist are dropped from the chain.
+func profileChainLoader(profile string, logger *log.Logger) proxy.ChainLoader {
+	return func() ([]*proxy.Provider, *proxy.RoutingConfig, error) {
+		names, err := config.ReadProfileOrder(profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		pc := projectConfig().WithRouting(config.GetProfileConfig(profile))
+		names, pc = pinBindingProvider(profile, names, pc)
+		var valid []string
+		for _, name := range names {
+			if config.GetProvider(name) == nil {
+				logger.Printf("[reload] profile %s references missing provider %q, skipping it", profile, name)
+				continue
+			}
+			valid = append(valid, name)
+		}
+		providers, err := buildProviders(valid)
+		if err != nil {
+			return nil, nil, err
+		}
+		pinBindingModel(profile, providers)
+		registerCustomScenarios()
+		if pc == nil || len(pc.Routing) == 0 {
+			return providers, nil, nil
+		}
+		routing, err := buildRoutingConfig(pc, providers, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return providers, routing, nil
+	}
+}
+
+// proxyListenAddr returns where a session's proxy listens: on the host of
+// proxy_listen (loopback by default), and on the --port flag, else
+// proxy_port, else the port of proxy_listen, else a random port. fixed
+// reports whether a port was given.
+func proxyListenAddr() (addr string, fixed bool, err error) {
+	host, port := "127.0.0.1", "0"
+	if listen := config.GetProxyListen(); listen != "" {
+		h, p, err := splitListen(listen)
+		if err != nil {
+			return "", false, err
+		}
+		if err := checkProxyListen(h); err != nil {
+			return "", false, err
+		}
+		host = h
+		if p != "" {
+			port = p
+		}
+	}
+	n := proxyPortFlag
+	if n == 0 {
+		n = config.GetProxyPort()
+	}
+	if n < 0 || n > 65535 {
+		return "", false, fmt.Errorf(i18n.T("invalid proxy port %d"), n)
+	}
+	if n != 0 {
+		port = strconv.Itoa(n)
+	}
+	return net.JoinHostPort(host, port), port != "0", nil
+}
+
+// proxyClientURL returns the URL the launched CLI uses for a proxy listening
+// on addr: a proxy on every interface is reached over loopback.
+func proxyClientURL(addr string, port int, https bool) string {
+	host, _, _ := net.SplitHostPort(addr)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if https {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// proxyTLS returns the HTTPS settings of proxies that listen for clients
+// and the certificate clients should trust, or nil if proxy_tls is unset.
+// Without a configured certificate it uses, and if needed creates, a
+// self-signed one under ~/.opencc/tls.
+func proxyTLS() (*tls.Config, string, error) {
+	tc := config.GetProxyTLS()
+	if tc == nil {
+		return nil, "", nil
+	}
+	if !tc.IsValid() {
+		return nil, "", errors.New(i18n.T("proxy_tls needs both cert_file and key_file, or neither for a self-signed certificate"))
+	}
+	return proxy.ServerTLS(tc)
+}
+
+// proxyAPIKey returns the API key the launched CLI sends to the proxy: the
+// proxy_auth_token if one is set, which the proxy accepts in place of the
+// X-OpenCC-Key header.
+func proxyAPIKey() string {
+	if token := config.GetProxyAuthToken(); token != "" {
+		return token
+	}
+	return "opencc-proxy"
+}
+
+// metricsAddr returns where to serve Prometheus metrics: the --metrics flag,
+// else metrics_listen, else "" for nowhere.
+func metricsAddr() string {
+	if metricsListen != "" {
+		return metricsListen
+	}
+	return config.GetMetricsListen()
+}
+
+// serveMetrics serves the proxy's Prometheus metrics on addr until the
+// returned func is called.
+func serveMetrics(srv *proxy.ProxyServer, addr string, logger *log.Logger) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(proxy.MetricsPath, srv.MetricsHandler())
+	metricsSrv := &http.Server{Handler: mux}
+	go metricsSrv.Serve(ln)
+	logger.Printf("Metrics on http://%s%s", ln.Addr(), proxy.MetricsPath)
+	return func() { metricsSrv.Close() }, nil
+}
+
+// buildProxyServer builds a proxy that routes, orders and fails over like a
+// session of the profile, without the files and queues of a long-running
+// one. Commands use it to run single requests in-process.
+func buildProxyServer(providers []*proxy.Provider, profile string, pc *config.ProfileConfig, cli string, logger *log.Logger) (*proxy.ProxyServer, error) {
+	registerCustomScenarios()
+	var srv *proxy.ProxyServer
+	if pc != nil && len(pc.Routing) > 0 {
+		routingCfg, err := buildRoutingConfig(pc, providers, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build routing config: %w", err)
+		}
+		srv = proxy.NewProxyServerWithRouting(routingCfg, logger)
+	} else {
+		srv = proxy.NewProxyServer(providers, logger)
+	}
+	srv.ClientFormat = GetCLIClientFormat(GetCLIType(cli))
+	srv.Profile = profile
+	srv.CLI = cli
+	if pc != nil {
+		srv.Strategy = pc.Strategy
+		srv.Schedule = pc.Schedule
+		srv.Weights = pc.Weights
+		srv.Retry = validRetryPolicy(pc.Retry, "profile "+profile)
+		srv.SessionAffinity = pc.SessionAffinity
+		srv.Hedge = pc.Hedge
+		if !pc.Hedge.IsValid() {
+			fmt.Fprintf(os.Stderr, "Warning: invalid hedge delays for profile %s, not hedging\n", profile)
+			srv.Hedge = nil
+		}
+		systemPrompt, err := pc.SystemPrompt()
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profile, err)
+		}
+		srv.SystemPrompt = systemPrompt
+	}
+	srv.FailoverPolicies = config.GetFailoverPolicies()
+	srv.StreamFailover = config.GetStreamFailover()
+	if !srv.StreamFailover.IsValid() {
+		fmt.Fprintf(os.Stderr, "Warning: unknown stream_failover mode '%s', using off\n", srv.StreamFailover)
+	}
+	if spec, ok := os.LookupEnv(proxy.FaultsEnv); ok {
+		faults, err := proxy.ParseFaults(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", proxy.FaultsEnv, err)
+		}
+		srv.Faults = faults
+		logger.Printf("Fault injection enabled (%s): %v", proxy.FaultsPath, faults.Pending())
+	}
+	return srv, nil
+}
+
+// registerCustomScenarios sets the detectors of the scenarios defined in the
+// config, dropping those removed since the last call and skipping invalid
+// ones with a warning.
+func registerCustomScenarios() {
+	for _, err := range proxy.SetCustomScenarios(config.GetCustomScenarios()) {
+		fmt.Fprintf(os.Stderr, "Warning: skipping custom scenario: %v\n", err)
+	}
+}
+
+// preflightTimeout bounds the pre-launch probe so a dead provider delays the
+// session start by at most this long.
+const preflightTimeout = 3 * time.Second
+
+// preflightWarning probes the primary provider and returns a one-line warning
+// if it looks down, or "" if it looks fine.
+func preflightWarning(providers []*proxy.Provider) string {
+	if len(providers) == 0 {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+
+	primary := providers[0]
+	problem := proxy.ProbeProvider(ctx, primary.HTTPClient(http.DefaultClient), primary).Problem()
+	if problem == "" {
+		return ""
+	}
+	if len(providers) == 1 {
+		return fmt.Sprintf("primary '%s' appears down (%s); no fallback configured", primary.Name, problem)
+	}
+	return fmt.Sprintf("primary '%s' appears down (%s); failover to '%s' likely", primary.Name, problem, providers[1].Name)
+}
+
 func buildProviders(names []string) ([]*proxy.Provider, error) {
 	var providers []*proxy.Provider
 
@@ -283,69 +763,370 @@ func buildProviders(names []string) ([]*proxy.Provider, error) {
 		if name == "" {
 			continue
 		}
-		p := config.GetProvider(name)
+		p, err := config.ResolveProvider(name)
+		if err != nil {
+			return nil, err
+		}
 		if p == nil {
-			return nil, fmt.Errorf("configuration '%s' not found", name)
+			return nil, fmt.Errorf(i18n.T("configuration '%s' not found"), name)
+		}
+		// Tokens may come from an environment variable or a secret manager
+		token, err := config.ResolveToken(p.AuthToken)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
+		}
+		// or be exchanged for short-lived ones at a token endpoint
+		var oauth *proxy.OAuthAuth
+		if p.OAuth != nil {
+			if t := p.GetType(); t == config.ProviderTypeBedrock || t == config.ProviderTypeVertex {
+				return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, fmt.Errorf("oauth is not supported for %s providers", t))
+			}
+			if err := p.OAuth.Validate(); err != nil {
+				return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
+			}
+			refreshToken, err := config.ResolveToken(p.OAuth.RefreshToken)
+			if err != nil {
+				return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
+			}
+			oauth = proxy.NewOAuthAuth(p.OAuth, refreshToken)
+			oauth.OnRotate = saveRefreshToken(name)
 		}
 
-		if p.BaseURL == "" || p.AuthToken == "" {
-			return nil, fmt.Errorf("%s missing base_url or auth_token", name)
+		// Bedrock and Vertex AI providers authorize requests with cloud
+		// credentials and default to their region's endpoint
+		var bedrock *config.BedrockConfig
+		var vertex *proxy.VertexAuth
+		baseURL := p.BaseURL
+		switch p.GetType() {
+		case config.ProviderTypeBedrock:
+			resolved, err := p.Bedrock.Resolve()
+			if err != nil {
+				return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
+			}
+			bedrock = resolved
+			if baseURL == "" {
+				baseURL = bedrock.Endpoint()
+			}
+		case config.ProviderTypeVertex:
+			resolved, err := p.Vertex.Resolve()
+			if err == nil {
+				vertex, err = proxy.NewVertexAuth(resolved)
+			}
+			if err != nil {
+				return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
+			}
+			if baseURL == "" {
+				baseURL = resolved.Endpoint()
+			}
+		case config.ProviderTypeLocal:
+			// Local servers rarely need a token and default to Ollama
+			if baseURL == "" {
+				baseURL = config.DefaultLocalBaseURL
+			}
+		default:
+			if p.BaseURL == "" || (token == "" && oauth == nil) {
+				return nil, fmt.Errorf(i18n.T("%s missing base_url or auth_token"), name)
+			}
 		}
 
-		model := p.Model
-		if model == "" {
-			model = "claude-sonnet-4-5"
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("invalid URL for provider %s: %w"), name, err)
 		}
-		reasoningModel := p.ReasoningModel
-		if reasoningModel == "" {
-			reasoningModel = "claude-sonnet-4-5-thinking"
+		if err := p.ValidateTimeouts(); err != nil {
+			return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
 		}
-		haikuModel := p.HaikuModel
-		if haikuModel == "" {
-			haikuModel = "claude-haiku-4-5"
+		transport, err := proxy.NewProviderTransport(p.ProxyURL, p.ConnectTimeout())
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
 		}
-		opusModel := p.OpusModel
-		if opusModel == "" {
-			opusModel = "claude-opus-4-5"
+		if err := config.ValidateHeaders(p.Headers); err != nil {
+			return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
 		}
-		sonnetModel := p.SonnetModel
-		if sonnetModel == "" {
-			sonnetModel = "claude-sonnet-4-5"
+		systemPrompt, err := p.SystemPrompt()
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("provider %s: %w"), name, err)
 		}
 
-		u, err := url.Parse(p.BaseURL)
-		if err != nil {
-			return nil, fmt.Errorf("invalid URL for provider %s: %w", name, err)
-		}
-
-		providers = append(providers, &proxy.Provider{
-			Name:            name,
-			Type:            p.GetType(),
-			BaseURL:         u,
-			Token:           p.AuthToken,
-			Model:           model,
-			ReasoningModel:  reasoningModel,
-			HaikuModel:      haikuModel,
-			OpusModel:       opusModel,
-			SonnetModel:     sonnetModel,
-			EnvVars:         p.EnvVars,
-			ClaudeEnvVars:   p.ClaudeEnvVars,
-			CodexEnvVars:    p.CodexEnvVars,
-			OpenCodeEnvVars: p.OpenCodeEnvVars,
-			Healthy:         true,
-		})
+		provider := &proxy.Provider{
+			Name:              name,
+			Type:              p.GetType(),
+			BaseURL:           u,
+			Token:             token,
+			Model:             p.Model,
+			ReasoningModel:    p.ReasoningModel,
+			HaikuModel:        p.HaikuModel,
+			OpusModel:         p.OpusModel,
+			SonnetModel:       p.SonnetModel,
+			EnvVars:           p.EnvVars,
+			ClaudeEnvVars:     p.ClaudeEnvVars,
+			CodexEnvVars:      p.CodexEnvVars,
+			OpenCodeEnvVars:   p.OpenCodeEnvVars,
+			Pricing:           p.Pricing,
+			Capabilities:      p.Capabilities,
+			MaxRequestBytes:   p.MaxRequestBytes,
+			MaxConcurrent:     p.MaxConcurrent,
+			Retry:             validRetryPolicy(p.Retry, "provider "+name),
+			DailyBudgetUSD:    p.DailyBudgetUSD,
+			MonthlyTokenLimit: p.MonthlyTokenLimit,
+			Transport:         transport,
+			Headers:           p.Headers,
+			SystemPrompt:      systemPrompt,
+			ResponseTimeout:   p.ResponseHeaderTimeout(),
+			IdleTimeout:       p.IdleTimeout(),
+			Bedrock:           bedrock,
+			Vertex:            vertex,
+			Azure:             p.Azure,
+			OAuth:             oauth,
+			Unavailable:       p.UnavailableFunc(),
+			CurrentToken:      providerToken(name),
+			Healthy:           true,
+		}
+		if p.DailyBudgetUSD > 0 && p.Pricing == nil {
+			fmt.Fprintf(os.Stderr, "Warning: provider %s has daily_budget_usd but no pricing; its spend can't be estimated\n", name)
+		}
+		provider.FillDefaultModels()
+		providers = append(providers, provider)
 	}
 
 	if len(providers) == 0 {
-		return nil, fmt.Errorf("no valid providers")
+		return nil, errors.New(i18n.T("no valid providers"))
 	}
 	return providers, nil
 }
 
+// validRetryPolicy returns rp, or nil with a warning if its settings are out
+// of range.
+func validRetryPolicy(rp *config.RetryPolicy, owner string) *config.RetryPolicy {
+	if !rp.IsValid() {
+		fmt.Fprintf(os.Stderr, "Warning: invalid retry policy for %s, not retrying\n", owner)
+		return nil
+	}
+	return rp
+}
+
+// openAccessLog opens the access log described by al, defaulting to
+// access.log in logDir. Unknown formats fall back to Common Log Format.
+func openAccessLog(al *config.AccessLogConfig, logDir string) (*proxy.AccessLogger, error) {
+	path := al.Path
+	if path == "" {
+		path = filepath.Join(logDir, proxy.DefaultAccessLogFile)
+	}
+	format := al.Format
+	if !format.IsValid() {
+		fmt.Fprintf(os.Stderr, "Warning: unknown access log format '%s', using clf\n", format)
+		format = config.AccessLogCLF
+	}
+	accessLog, err := proxy.NewAccessLogger(path, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return accessLog, nil
+}
+
+// debugCapture returns the debug capture settings: the config's, or the
+// defaults when --debug-capture is given; nil if capturing is off.
+func debugCapture() *config.DebugCaptureConfig {
+	if dc := config.GetDebugCapture(); dc != nil {
+		return dc
+	}
+	if debugCaptureFlag {
+		return &config.DebugCaptureConfig{}
+	}
+	return nil
+}
+
+// openCapture opens the capture directory described by dc, defaulting to
+// captures in logDir.
+func openCapture(dc *config.DebugCaptureConfig, logDir string) (*proxy.Capturer, error) {
+	dir := dc.Dir
+	if dir == "" {
+		dir = filepath.Join(logDir, proxy.DefaultCaptureDir)
+	}
+	capture, err := proxy.NewCapturer(dir, dc.MaxBodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture dir: %w", err)
+	}
+	return capture, nil
+}
+
+// resolveLaunchTemplate merges the global, profile and current directory's
+// binding launch templates for cli with the env of a trusted project file.
+// Whether the project env or the binding wins follows project_files.
+func resolveLaunchTemplate(cli string, pc *config.ProfileConfig) config.LaunchTemplate {
+	var profileTmpl, projectTmpl, bindingTmpl *config.LaunchTemplate
+	if pc != nil {
+		profileTmpl = pc.Launch[cli]
+	}
+	if env := projectEnv(cli); len(env) > 0 {
+		projectTmpl = &config.LaunchTemplate{Env: env}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if _, binding := config.FindProjectBinding(filepath.Clean(cwd)); binding != nil {
+			bindingTmpl = binding.Launch[cli]
+		}
+	}
+	if config.GetProjectFiles() == config.ProjectFilesFileFirst {
+		return config.MergeLaunchTemplates(config.GetLaunchTemplate(cli), profileTmpl, bindingTmpl, projectTmpl)
+	}
+	return config.MergeLaunchTemplates(config.GetLaunchTemplate(cli), profileTmpl, projectTmpl, bindingTmpl)
+}
+
+// warnedProjectFiles holds the project files already reported as broken, so
+// each is warned about once.
+var warnedProjectFiles sync.Map
+
+// projectConfig returns the .opencc.json of the project the current
+// directory is in, or nil if there is none or project_files is "ignore". A
+// file that can't be parsed is skipped with a warning.
+func projectConfig() *config.ProjectConfig {
+	project, _ := projectFile()
+	return project
+}
+
+// projectFile is projectConfig, also returning the directory the file is in.
+func projectFile() (*config.ProjectConfig, string) {
+	if config.GetProjectFiles() == config.ProjectFilesIgnore {
+		return nil, ""
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, ""
+	}
+	project, path, err := config.FindProjectConfig(filepath.Clean(cwd))
+	if err != nil {
+		if _, warned := warnedProjectFiles.LoadOrStore(path, true); !warned {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if project == nil {
+		return nil, ""
+	}
+	return project, filepath.Dir(path)
+}
+
+// projectEnv returns the env vars the current directory's project file sets
+// for cli. A project file comes with the repository, so they only apply once
+// its directory was trusted with 'opencc trust', and never replace the
+// variables that point the CLI at the proxy or would bypass it.
+func projectEnv(cli string) map[string]string {
+	project, dir := projectFile()
+	if project == nil || len(project.Env) == 0 {
+		return nil
+	}
+	if !config.IsProjectTrusted(dir) {
+		if _, warned := warnedProjectFiles.LoadOrStore(dir+"#env", true); !warned {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring the env of %s; run 'opencc trust' there to apply it\n", filepath.Join(dir, config.ProjectFile))
+		}
+		return nil
+	}
+	cliType := GetCLIType(cli)
+	reserved := append(proxyEnvKeys(cliType), interferingEnvKeys[cliType]...)
+	env := make(map[string]string, len(project.Env))
+	for k, v := range project.Env {
+		if slices.Contains(reserved, k) {
+			fmt.Fprintf(os.Stderr, "Warning: %s can't set %s, which opencc manages\n", filepath.Join(dir, config.ProjectFile), k)
+			continue
+		}
+		env[k] = v
+	}
+	return env
+}
+
+// projectBinding returns the profile and CLI bound to dir, combining the
+// binding that applies to it, its own or an ancestor's, with the project file: by default the binding wins
+// where both set one, and with project_files "file_first" the file does.
+func projectBinding(dir string) *config.ProjectBinding {
+	_, binding := config.FindProjectBinding(dir)
+	project := projectConfig()
+	if project == nil || (project.Profile == "" && project.CLI == "") {
+		return binding
+	}
+	if binding == nil {
+		return &config.ProjectBinding{Profile: project.Profile, CLI: project.CLI}
+	}
+	merged := *binding
+	if config.GetProjectFiles() == config.ProjectFilesFileFirst {
+		merged.Profile = cmp.Or(project.Profile, binding.Profile)
+		merged.CLI = cmp.Or(project.CLI, binding.CLI)
+	} else {
+		merged.Profile = cmp.Or(binding.Profile, project.Profile)
+		merged.CLI = cmp.Or(binding.CLI, project.CLI)
+	}
+	return &merged
+}
+
+// bindingPin returns the provider and model the binding of the current
+// directory pins, if profile is the one it binds; run with another profile,
+// they don't apply.
+func bindingPin(profile string) (provider, model string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+	_, binding := config.FindProjectBinding(filepath.Clean(cwd))
+	if binding == nil || cmp.Or(binding.Profile, config.GetDefaultProfile()) != profile {
+		return "", ""
+	}
+	return binding.Provider, binding.Model
+}
+
+// pinBindingProvider replaces the profile's provider chain with the
+// provider the binding pins, if any, and drops its routing so every
+// request goes there.
+func pinBindingProvider(profile string, names []string, pc *config.ProfileConfig) ([]string, *config.ProfileConfig) {
+	provider, _ := bindingPin(profile)
+	if provider == "" {
+		return names, pc
+	}
+	if pc != nil {
+		unrouted := *pc
+		unrouted.Routing = nil
+		pc = &unrouted
+	}
+	return []string{provider}, pc
+}
+
+// pinBindingModel makes the providers send the model the binding pins, if
+// any, for every request.
+func pinBindingModel(profile string, providers []*proxy.Provider) {
+	_, model := bindingPin(profile)
+	if model == "" {
+		return
+	}
+	for _, p := range providers {
+		p.Model, p.ReasoningModel, p.HaikuModel, p.OpusModel, p.SonnetModel = model, model, model, model, model
+	}
+}
+
+// providerToken returns a func reporting the provider's configured token,
+// read from the store so a token fixed while the proxy runs is picked up.
+func providerToken(name string) func() string {
+	return func() string {
+		p := config.GetProvider(name)
+		if p == nil {
+			return ""
+		}
+		token, _ := config.ResolveToken(p.AuthToken)
+		return token
+	}
+}
+
+// saveRefreshToken returns a func saving the refresh token the provider's
+// token endpoint rotated to, so the next run doesn't start from a revoked one.
+func saveRefreshToken(name string) func(string) {
+	return func(token string) {
+		if err := config.SetOAuthRefreshToken(name, token); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: saving the rotated refresh token of provider %s: %v\n", name, err)
+		}
+	}
+}
+
 // mergeProviderEnvVarsForCLI merges env_vars from all providers for a specific CLI.
 // For numeric values like ANTHROPIC_MAX_CONTEXT_WINDOW, uses the minimum value.
 // For other values, first provider's value takes precedence.
-func mergeProviderEnvVarsForCLI(providers []*proxy.Provider, cli string) map[string]string {
+// Profile overrides replace the merged provider values unconditionally.
+func mergeProviderEnvVarsForCLI(providers []*proxy.Provider, cli string, profileOverrides map[string]string) map[string]string {
 	result := make(map[string]string)
 
 	// Env vars where we should take the minimum numeric value
@@ -382,6 +1163,13 @@ func mergeProviderEnvVarsForCLI(providers []*proxy.Provider, cli string) map[str
 		}
 	}
 
+	for k, v := range profileOverrides {
+		if k == "" || v == "" {
+			continue
+		}
+		result[k] = v
+	}
+
 	return result
 }
 
@@ -424,8 +1212,10 @@ func buildRoutingConfig(pc *config.ProfileConfig, defaultProviders []*proxy.Prov
 		}
 		if len(chain) > 0 {
 			scenarioRoutes[scenario] = &proxy.ScenarioProviders{
-				Providers: chain,
-				Models:    models,
+				Providers:            chain,
+				Models:               models,
+				MaxRequestBytes:      route.MaxRequestBytes,
+				LongContextThreshold: route.LongContextThreshold,
 			}
 			logger.Printf("[routing] scenario %s: %d providers, %d model overrides", scenario, len(chain), len(models))
 		}
@@ -446,16 +1236,19 @@ func resolveProviderNamesAndCLI(profileFlag string, cliFlag string) ([]string, s
 
 	// -f (no value, NoOptDefVal=" ") → interactive profile picker
 	if profileFlag == " " {
+		if isHeadless() {
+			return nil, "", "", errors.New(i18n.T("no profile given; pass -p <profile> in headless mode"))
+		}
 		profile, err := tui.RunProfilePicker()
 		if err != nil {
 			return nil, "", "", err
 		}
 		names, err := config.ReadProfileOrder(profile)
 		if err != nil {
-			return nil, "", "", fmt.Errorf("profile '%s' has no providers configured", profile)
+			return nil, "", "", fmt.Errorf(i18n.T("profile '%s' has no providers configured"), profile)
 		}
 		if len(names) == 0 {
-			return nil, "", "", fmt.Errorf("profile '%s' has no providers configured", profile)
+			return nil, "", "", fmt.Errorf(i18n.T("profile '%s' has no providers configured"), profile)
 		}
 		if cli == "" {
 			cli = config.GetDefaultCLI()
@@ -467,10 +1260,10 @@ func resolveProviderNamesAndCLI(profileFlag string, cliFlag string) ([]string, s
 	if profileFlag != "" {
 		names, err := config.ReadProfileOrder(profileFlag)
 		if err != nil {
-			return nil, "", "", fmt.Errorf("profile '%s' not found", profileFlag)
+			return nil, "", "", fmt.Errorf(i18n.T("profile '%s' not found"), profileFlag)
 		}
 		if len(names) == 0 {
-			return nil, "", "", fmt.Errorf("profile '%s' has no providers configured", profileFlag)
+			return nil, "", "", fmt.Errorf(i18n.T("profile '%s' has no providers configured"), profileFlag)
 		}
 		if cli == "" {
 			cli = config.GetDefaultCLI()
@@ -482,8 +1275,8 @@ func resolveProviderNamesAndCLI(profileFlag string, cliFlag string) ([]string, s
 	cwd, err := os.Getwd()
 	if err == nil {
 		cwd = filepath.Clean(cwd)
-		if binding := config.GetProjectBinding(cwd); binding != nil {
-			// Found project binding
+		if binding := projectBinding(cwd); binding != nil {
+			// Found project binding or project file
 			profile := binding.Profile
 			if profile == "" {
 				profile = config.GetDefaultProfile()
@@ -519,6 +1312,9 @@ func resolveProviderNamesAndCLI(profileFlag string, cliFlag string) ([]string, s
 	}
 
 	// default profile missing or empty — interactive selection
+	if isHeadless() {
+		return nil, "", "", fmt.Errorf(i18n.T("default profile '%s' has no providers configured; pass -p <profile> or configure providers"), defaultProfile)
+	}
 	names, err := interactiveSelectProviders()
 	if err != nil {
 		return nil, "", "", err
@@ -591,6 +1387,10 @@ func validateProviderNames(names []string, profile string) ([]string, error) {
 		return names, nil
 	}
 
+	if isHeadless() {
+		return nil, fmt.Errorf(i18n.T("profile '%s' references missing provider(s): %s"), profile, strings.Join(missing, ", "))
+	}
+
 	fmt.Printf("%s provider(s) not found. Continue and remove from profile? (y/n): ", strings.Join(missing, ", "))
 	reader := bufio.NewReader(stdinReader)
 	line, err := reader.ReadString('\n')
@@ -600,7 +1400,7 @@ func validateProviderNames(names []string, profile string) ([]string, error) {
 
 	answer := strings.TrimSpace(strings.ToLower(line))
 	if answer != "y" && answer != "yes" {
-		return nil, fmt.Errorf("aborted")
+		return nil, errors.New(i18n.T("aborted"))
 	}
 
 	// Remove missing from profile
@@ -609,7 +1409,7 @@ func validateProviderNames(names []string, profile string) ([]string, error) {
 	}
 
 	if len(valid) == 0 {
-		return nil, fmt.Errorf("no valid providers remaining. Run 'opencc config' to set up providers")
+		return nil, errors.New(i18n.T("no valid providers remaining. Run 'opencc config' to set up providers"))
 	}
 
 	return valid, nil
@@ -650,27 +1450,28 @@ func GetCLIClientFormat(cliType CLIType) string {
 // setupCLIEnvironment sets the appropriate environment variables for the CLI.
 func setupCLIEnvironment(cliBin string, proxyURL string, logger *log.Logger) {
 	cliType := GetCLIType(cliBin)
+	apiKey := proxyAPIKey()
 
 	switch cliType {
 	case CLICodex:
 		// Codex uses OpenAI environment variables
 		os.Setenv("OPENAI_BASE_URL", proxyURL)
-		os.Setenv("OPENAI_API_KEY", "opencc-proxy")
+		os.Setenv("OPENAI_API_KEY", apiKey)
 		logger.Printf("Setting Codex env: OPENAI_BASE_URL=%s", proxyURL)
 
 	case CLIOpenCode:
 		// OpenCode supports multiple providers, set both
 		// It will use the appropriate one based on the model prefix
 		os.Setenv("ANTHROPIC_BASE_URL", proxyURL)
-		os.Setenv("ANTHROPIC_API_KEY", "opencc-proxy")
+		os.Setenv("ANTHROPIC_API_KEY", apiKey)
 		os.Setenv("OPENAI_BASE_URL", proxyURL)
-		os.Setenv("OPENAI_API_KEY", "opencc-proxy")
+		os.Setenv("OPENAI_API_KEY", apiKey)
 		logger.Printf("Setting OpenCode env: ANTHROPIC_BASE_URL=%s, OPENAI_BASE_URL=%s", proxyURL, proxyURL)
 
 	default:
 		// Claude Code uses Anthropic environment variables
 		os.Setenv("ANTHROPIC_BASE_URL", proxyURL)
-		os.Setenv("ANTHROPIC_AUTH_TOKEN", "opencc-proxy")
+		os.Setenv("ANTHROPIC_AUTH_TOKEN", apiKey)
 		logger.Printf("Setting Claude env: ANTHROPIC_BASE_URL=%s", proxyURL)
 	}
 }
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
 )
 
 func setTestHome(t *testing.T) string {
@@ -159,6 +163,22 @@ func TestBuildProvidersMissingToken(t *testing.T) {
 	}
 }
 
+func TestBuildProvidersLocalWithoutToken(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "ollama", &config.ProviderConfig{Type: config.ProviderTypeLocal, Model: "llama3.1"})
+
+	providers, err := buildProviders([]string{"ollama"})
+	if err != nil {
+		t.Fatalf("buildProviders() error: %v", err)
+	}
+	if got := providers[0].BaseURL.String(); got != config.DefaultLocalBaseURL {
+		t.Errorf("BaseURL = %q, want %q", got, config.DefaultLocalBaseURL)
+	}
+	if providers[0].Token != "" {
+		t.Errorf("Token = %q, want empty", providers[0].Token)
+	}
+}
+
 func TestBuildProvidersAllEmpty(t *testing.T) {
 	setTestHome(t)
 
@@ -584,3 +604,454 @@ func discardLogger() *log.Logger {
 	return log.New(io.Discard, "", 0)
 }
 
+
+func TestPreflightWarning(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("x-api-key") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer up.Close()
+
+	provider := func(name, rawURL string) *proxy.Provider {
+		u, _ := url.Parse(rawURL)
+		return &proxy.Provider{Name: name, BaseURL: u, Token: "tok", Healthy: true}
+	}
+
+	tests := []struct {
+		name      string
+		providers []*proxy.Provider
+		want      string
+	}{
+		{"primary up", []*proxy.Provider{provider("work-api", up.URL), provider("backup", down.URL)}, ""},
+		{"primary down", []*proxy.Provider{provider("work-api", down.URL), provider("backup", up.URL)}, "primary 'work-api' appears down (server error (503)); failover to 'backup' likely"},
+		{"no fallback", []*proxy.Provider{provider("work-api", down.URL)}, "primary 'work-api' appears down (server error (503)); no fallback configured"},
+		{"no providers", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preflightWarning(tt.providers); got != tt.want {
+				t.Errorf("preflightWarning = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeProviderEnvVarsForCLI(t *testing.T) {
+	providers := []*proxy.Provider{
+		{Name: "a", ClaudeEnvVars: map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "200000", "FOO": "a"}},
+		{Name: "b", ClaudeEnvVars: map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "128000", "FOO": "b", "BAR": "b"}},
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{
+			name: "providers only",
+			want: map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "128000", "FOO": "a", "BAR": "b"},
+		},
+		{
+			name:      "profile raises min-value key",
+			overrides: map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "1000000"},
+			want:      map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "1000000", "FOO": "a", "BAR": "b"},
+		},
+		{
+			name:      "profile overrides and adds",
+			overrides: map[string]string{"FOO": "profile", "NEW": "x", "EMPTY": ""},
+			want:      map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "128000", "FOO": "profile", "BAR": "b", "NEW": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeProviderEnvVarsForCLI(providers, "claude", tt.overrides)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	pc := &config.ProfileConfig{EnvVars: map[string]map[string]string{"codex": {"FOO": "codex"}}}
+	if got := pc.GetEnvVarsForCLI("claude"); got != nil {
+		t.Errorf("claude overrides = %v, want none", got)
+	}
+	if got := pc.GetEnvVarsForCLI("codex")["FOO"]; got != "codex" {
+		t.Errorf("codex FOO = %q", got)
+	}
+	var nilProfile *config.ProfileConfig
+	if got := nilProfile.GetEnvVarsForCLI("claude"); got != nil {
+		t.Errorf("nil profile overrides = %v", got)
+	}
+}
+
+func TestDetectEnvConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		cli     string
+		env     map[string]string
+		managed map[string]string
+		want    []envConflict
+	}{
+		{
+			name: "clean environment",
+			cli:  "claude",
+		},
+		{
+			name: "claude base url overridden, api key interferes",
+			cli:  "claude",
+			env:  map[string]string{"ANTHROPIC_BASE_URL": "https://api.example.com", "ANTHROPIC_API_KEY": "sk-1", "OPENAI_BASE_URL": "https://o.example.com"},
+			want: []envConflict{
+				{Key: "ANTHROPIC_BASE_URL", Value: "https://api.example.com", Override: true},
+				{Key: "ANTHROPIC_API_KEY", Value: "sk-1", Override: false},
+			},
+		},
+		{
+			name: "opencode overrides both families",
+			cli:  "opencode",
+			env:  map[string]string{"ANTHROPIC_API_KEY": "sk-1", "OPENAI_BASE_URL": "https://o.example.com"},
+			want: []envConflict{
+				{Key: "ANTHROPIC_API_KEY", Value: "sk-1", Override: true},
+				{Key: "OPENAI_BASE_URL", Value: "https://o.example.com", Override: true},
+			},
+		},
+		{
+			name:    "managed env var with a different value",
+			cli:     "codex",
+			env:     map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "100000", "SAME": "x"},
+			managed: map[string]string{"ANTHROPIC_MAX_CONTEXT_WINDOW": "200000", "SAME": "x"},
+			want:    []envConflict{{Key: "ANTHROPIC_MAX_CONTEXT_WINDOW", Value: "100000", Override: true}},
+		},
+	}
+	keys := []string{"ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_KEY", "OPENAI_BASE_URL", "OPENAI_API_KEY",
+		"CLAUDE_CODE_USE_BEDROCK", "CLAUDE_CODE_USE_VERTEX", "ANTHROPIC_MAX_CONTEXT_WINDOW", "SAME"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range keys {
+				t.Setenv(k, tt.env[k])
+			}
+			got := detectEnvConflicts(tt.cli, tt.managed)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatEnvConflicts(t *testing.T) {
+	report := formatEnvConflicts([]envConflict{
+		{Key: "ANTHROPIC_BASE_URL", Value: "https://api.example.com", Override: true},
+		{Key: "ANTHROPIC_API_KEY", Value: "sk-secret"},
+	})
+	if strings.Contains(report, "sk-secret") {
+		t.Errorf("report leaks secret: %s", report)
+	}
+	for _, want := range []string{"ANTHROPIC_BASE_URL=https://api.example.com (overridden by opencc)", "ANTHROPIC_API_KEY=(set) (may interfere)"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestResolveLaunchTemplate(t *testing.T) {
+	setTestHome(t)
+	project := t.TempDir()
+	project, _ = filepath.EvalSymlinks(project)
+	t.Chdir(project)
+
+	writeTestConfig(t, &config.OpenCCConfig{
+		Providers: map[string]*config.ProviderConfig{},
+		Launch: map[string]*config.LaunchTemplate{
+			"claude": {Args: []string{"--verbose"}, Env: map[string]string{"A": "global", "B": "global"}},
+		},
+		ProjectBindings: map[string]*config.ProjectBinding{
+			project: {Launch: map[string]*config.LaunchTemplate{
+				"claude": {Env: map[string]string{"B": "binding"}},
+				"codex":  {Args: []string{"--profile", "x"}},
+			}},
+		},
+	})
+	pc := &config.ProfileConfig{Launch: map[string]*config.LaunchTemplate{
+		"claude": {Args: []string{"--dangerously-skip-permissions"}},
+	}}
+
+	tests := []struct {
+		name     string
+		cli      string
+		pc       *config.ProfileConfig
+		wantArgs string
+		wantEnv  map[string]string
+	}{
+		{"global only", "claude", nil, "--verbose", map[string]string{"A": "global", "B": "binding"}},
+		{"profile args replace global", "claude", pc, "--dangerously-skip-permissions", map[string]string{"A": "global", "B": "binding"}},
+		{"binding for another cli", "codex", pc, "--profile x", nil},
+		{"nothing configured", "opencode", pc, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveLaunchTemplate(tt.cli, tt.pc)
+			if args := strings.Join(got.Args, " "); args != tt.wantArgs {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+			if len(got.Env) != len(tt.wantEnv) {
+				t.Fatalf("env = %v, want %v", got.Env, tt.wantEnv)
+			}
+			for k, v := range tt.wantEnv {
+				if got.Env[k] != v {
+					t.Errorf("env %s = %q, want %q", k, got.Env[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyListenAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      int
+		config    int
+		listen    string
+		token     string
+		want      string
+		wantFixed bool
+		wantErr   bool
+	}{
+		{"random", 0, 0, "", "", "127.0.0.1:0", false, false},
+		{"from config", 0, 19850, "", "", "127.0.0.1:19850", true, false},
+		{"flag overrides config", 19860, 19850, "", "", "127.0.0.1:19860", true, false},
+		{"out of range", 70000, 0, "", "", "", false, true},
+		{"listen host with token", 0, 0, "0.0.0.0", "secret", "0.0.0.0:0", false, false},
+		{"listen port", 0, 0, "0.0.0.0:19870", "secret", "0.0.0.0:19870", true, false},
+		{"proxy_port overrides listen port", 0, 19850, "0.0.0.0:19870", "secret", "0.0.0.0:19850", true, false},
+		{"remote without token", 0, 0, "0.0.0.0", "", "", false, true},
+		{"loopback without token", 0, 0, "localhost:19870", "", "localhost:19870", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestHome(t)
+			writeTestConfig(t, &config.OpenCCConfig{ProxyPort: tt.config, ProxyListen: tt.listen, ProxyAuthToken: tt.token})
+			proxyPortFlag = tt.flag
+			defer func() { proxyPortFlag = 0 }()
+
+			addr, fixed, err := proxyListenAddr()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("proxyListenAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if addr != tt.want || fixed != tt.wantFixed {
+				t.Errorf("proxyListenAddr() = %q, %v, want %q, %v", addr, fixed, tt.want, tt.wantFixed)
+			}
+		})
+	}
+}
+
+func TestProxyClientURL(t *testing.T) {
+	tests := []struct {
+		addr  string
+		https bool
+		want  string
+	}{
+		{"127.0.0.1:0", false, "http://127.0.0.1:4000"},
+		{"0.0.0.0:0", false, "http://127.0.0.1:4000"},
+		{"[::]:0", false, "http://127.0.0.1:4000"},
+		{":0", false, "http://127.0.0.1:4000"},
+		{"192.168.1.5:0", false, "http://192.168.1.5:4000"},
+		{"127.0.0.1:0", true, "https://127.0.0.1:4000"},
+	}
+	for _, tt := range tests {
+		if got := proxyClientURL(tt.addr, 4000, tt.https); got != tt.want {
+			t.Errorf("proxyClientURL(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestBuildProvidersTokenFromEnv(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "p1", &config.ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "env:OPENCC_TEST_P1_TOKEN"})
+
+	if _, err := buildProviders([]string{"p1"}); err == nil || !strings.Contains(err.Error(), "OPENCC_TEST_P1_TOKEN") {
+		t.Fatalf("buildProviders() error = %v, want one naming the unset variable", err)
+	}
+
+	t.Setenv("OPENCC_TEST_P1_TOKEN", "tok-env")
+	providers, err := buildProviders([]string{"p1"})
+	if err != nil {
+		t.Fatalf("buildProviders() error: %v", err)
+	}
+	if providers[0].Token != "tok-env" {
+		t.Errorf("Token = %q, want %q", providers[0].Token, "tok-env")
+	}
+	if got := providerToken("p1")(); got != "tok-env" {
+		t.Errorf("providerToken() = %q, want %q", got, "tok-env")
+	}
+}
+
+func TestBuildProvidersOAuth(t *testing.T) {
+	setTestHome(t)
+	t.Setenv("OPENCC_TEST_RT", "rt-env")
+	writeTestProvider(t, "gw", &config.ProviderConfig{
+		BaseURL: "https://api.example.com",
+		OAuth:   &config.OAuthConfig{TokenURL: "https://auth.example.com/token", RefreshToken: "env:OPENCC_TEST_RT"},
+	})
+	writeTestProvider(t, "bad", &config.ProviderConfig{
+		BaseURL: "https://api.example.com",
+		OAuth:   &config.OAuthConfig{TokenURL: "https://auth.example.com/token"},
+	})
+
+	providers, err := buildProviders([]string{"gw"})
+	if err != nil {
+		t.Fatalf("buildProviders() error: %v", err)
+	}
+	if providers[0].OAuth == nil || providers[0].OAuth.TokenURL != "https://auth.example.com/token" {
+		t.Errorf("OAuth = %+v, want the token endpoint set", providers[0].OAuth)
+	}
+	if _, err := buildProviders([]string{"bad"}); err == nil || !strings.Contains(err.Error(), "refresh_token") {
+		t.Errorf("buildProviders() error = %v, want one about the refresh token", err)
+	}
+}
+
+func TestResolveWithProjectFile(t *testing.T) {
+	setTestHome(t)
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	sub := filepath.Join(project, "src", "pkg")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(project, config.ProjectFile), []byte(`{
+		"profile": "work", "cli": "codex", "env": {"TEAM": "yes", "OPENAI_BASE_URL": "https://evil.example"},
+		"routing": {"think": {"providers": [{"name": "p2"}]}}
+	}`), 0644)
+	t.Chdir(sub)
+
+	tests := []struct {
+		name        string
+		mode        string
+		binding     *config.ProjectBinding // bound to sub
+		untrusted   bool
+		wantProfile string
+		wantCLI     string
+		wantEnv     bool
+		wantRouting bool
+	}{
+		{"file alone", "", nil, false, "work", "codex", true, true},
+		{"binding wins", "", &config.ProjectBinding{Profile: "default"}, false, "default", "codex", true, true},
+		{"file wins", config.ProjectFilesFileFirst, &config.ProjectBinding{Profile: "default", CLI: "opencode"}, false, "work", "codex", true, true},
+		{"untrusted", "", nil, true, "work", "codex", false, true},
+		{"ignored", config.ProjectFilesIgnore, nil, false, "default", "claude", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.OpenCCConfig{
+				ProjectFiles: tt.mode,
+				Providers: map[string]*config.ProviderConfig{
+					"p1": {BaseURL: "https://p1.example", AuthToken: "t"},
+					"p2": {BaseURL: "https://p2.example", AuthToken: "t"},
+				},
+				Profiles: map[string]*config.ProfileConfig{
+					"default": {Providers: []string{"p1"}},
+					"work":    {Providers: []string{"p2", "p1"}},
+				},
+			}
+			if tt.binding != nil {
+				cfg.ProjectBindings = map[string]*config.ProjectBinding{sub: tt.binding}
+			}
+			if !tt.untrusted {
+				cfg.TrustedProjects = []string{project}
+			}
+			writeTestConfig(t, cfg)
+
+			_, profile, cli, err := resolveProviderNamesAndCLI("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if profile != tt.wantProfile || cli != tt.wantCLI {
+				t.Errorf("profile, cli = %q, %q, want %q, %q", profile, cli, tt.wantProfile, tt.wantCLI)
+			}
+			env := resolveLaunchTemplate(cli, nil).Env
+			if got := env["TEAM"] == "yes"; got != tt.wantEnv {
+				t.Errorf("TEAM in env = %v, want %v", got, tt.wantEnv)
+			}
+			if v, ok := env["OPENAI_BASE_URL"]; ok {
+				t.Errorf("project file set the proxy's OPENAI_BASE_URL to %q", v)
+			}
+			pc := projectConfig().WithRouting(config.GetProfileConfig(profile))
+			if route := pc.Routing[config.ScenarioThink]; (route != nil) != tt.wantRouting {
+				t.Errorf("think route = %+v", route)
+			}
+		})
+	}
+}
+
+func TestBindingPin(t *testing.T) {
+	setTestHome(t)
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	writeTestConfig(t, &config.OpenCCConfig{
+		Providers: map[string]*config.ProviderConfig{
+			"p1":    {BaseURL: "https://p1.example", AuthToken: "t", Model: "big"},
+			"p2":    {BaseURL: "https://p2.example", AuthToken: "t"},
+			"local": {BaseURL: "http://localhost:11434", AuthToken: "t"},
+		},
+		Profiles: map[string]*config.ProfileConfig{
+			"default": {Providers: []string{"p1", "p2"}},
+			"work": {
+				Providers: []string{"p1"},
+				Routing: map[config.Scenario]*config.ScenarioRoute{
+					config.ScenarioThink: {Providers: []*config.ProviderRoute{{Name: "p2"}}},
+				},
+			},
+		},
+		ProjectBindings: map[string]*config.ProjectBinding{
+			dir: {Profile: "work", Provider: "local", Model: "qwen3-coder"},
+		},
+	})
+	logger := log.New(io.Discard, "", 0)
+
+	providers, routing, err := profileChainLoader("work", logger)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 || providers[0].Name != "local" {
+		t.Fatalf("providers = %v, want only local", providers)
+	}
+	if routing != nil {
+		t.Errorf("routing = %+v, want none with a pinned provider", routing)
+	}
+	p := providers[0]
+	for _, model := range []string{p.Model, p.ReasoningModel, p.HaikuModel, p.OpusModel, p.SonnetModel} {
+		if model != "qwen3-coder" {
+			t.Errorf("model mappings = %+v, want all qwen3-coder", p)
+			break
+		}
+	}
+
+	// Another profile than the bound one ignores the pin
+	providers, _, err = profileChainLoader("default", logger)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].Model != "big" {
+		t.Errorf("default profile providers = %v, want p1, p2 unpinned", providers)
+	}
+
+	// Deleting the provider drops the pin
+	if err := config.DeleteProviderByName("local"); err != nil {
+		t.Fatal(err)
+	}
+	if b := config.GetProjectBinding(dir); b.Provider != "" || b.Model != "" {
+		t.Errorf("binding after deleting its provider = %+v", b)
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// routeImagePixel is a 1x1 PNG, the image block --image adds.
+const routeImagePixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Inspect how requests are routed",
+}
+
+var routeExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show how a request would be routed, without sending it",
+	Long: `Dry-run routing for a request: show the scenario it would be detected as,
+the provider chain it would go to, the model each provider would receive and
+the env-var headers each would be sent. Nothing is sent.
+
+The request is read from --body (a JSON request body, or - for stdin), or is a
+one-line message. --think, --image and --tokens change it to look like a
+thinking request, one with an image, or one with about that many tokens.
+
+Examples:
+  opencc route explain -p work --body request.json
+  opencc route explain --think                  # A thinking request
+  opencc route explain --tokens 50000           # A long-context request`,
+	Args: cobra.NoArgs,
+	RunE: runRouteExplain,
+}
+
+var (
+	routeProfile string
+	routeCLI     string
+	routeBody    string
+	routeModel   string
+	routeThink   bool
+	routeImage   bool
+	routeTokens  int
+)
+
+func init() {
+	routeExplainCmd.Flags().StringVarP(&routeProfile, "profile", "p", "", "profile to route through (default: bound or default profile)")
+	routeExplainCmd.Flags().StringVar(&routeCLI, "cli", "", "CLI whose API format the request uses (claude, codex, opencode)")
+	routeExplainCmd.Flags().StringVar(&routeBody, "body", "", "file with the JSON request body, - for stdin")
+	routeExplainCmd.Flags().StringVar(&routeModel, "model", "", "model of the request (default: the body's, else claude-sonnet-4-5)")
+	routeExplainCmd.Flags().BoolVar(&routeThink, "think", false, "enable thinking")
+	routeExplainCmd.Flags().BoolVar(&routeImage, "image", false, "add an image")
+	routeExplainCmd.Flags().IntVar(&routeTokens, "tokens", 0, "add filler text of about this many tokens")
+	routeCmd.AddCommand(routeExplainCmd)
+}
+
+func runRouteExplain(cmd *cobra.Command, args []string) error {
+	names, profile, cli, err := resolveProviderNamesAndCLI(routeProfile, routeCLI)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	switch routeBody {
+	case "":
+	case "-":
+		data, err = io.ReadAll(stdinReader)
+	default:
+		data, err = os.ReadFile(routeBody)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	body, err := buildExplainBody(data, routeModel, routeThink, routeImage, routeTokens)
+	if err != nil {
+		return err
+	}
+	e, err := explainRoute(names, profile, config.GetProfileConfig(profile), cli, body)
+	if err != nil {
+		return err
+	}
+	printRouteExplanation(cmd.OutOrStdout(), e)
+	return nil
+}
+
+// buildExplainBody returns the request body to explain: data parsed, or a
+// one-line message when data is empty, with the flags applied.
+func buildExplainBody(data []byte, model string, think, image bool, tokens int) (map[string]interface{}, error) {
+	body := map[string]interface{}{
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		if body == nil {
+			return nil, errors.New("invalid request body: not a JSON object")
+		}
+	}
+	if model != "" {
+		body["model"] = model
+	} else if _, ok := body["model"].(string); !ok {
+		body["model"] = "claude-sonnet-4-5"
+	}
+	if think {
+		body["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": 1024}
+	}
+
+	var content []interface{}
+	if image {
+		content = append(content, map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": routeImagePixel},
+		})
+	}
+	if tokens > 0 {
+		// " word" is one token in common BPE encodings
+		content = append(content, map[string]interface{}{"type": "text", "text": strings.Repeat(" word", tokens)})
+	}
+	if len(content) > 0 {
+		messages, _ := body["messages"].([]interface{})
+		body["messages"] = append(messages, map[string]interface{}{"role": "user", "content": content})
+	}
+	return body, nil
+}
+
+// routeExplanation is how a request would be routed through a profile.
+type routeExplanation struct {
+	Profile  string
+	Model    string
+	Tokens   int             // estimated input tokens
+	Scenario config.Scenario // detected scenario
+	Routed   bool            // the profile has a route for Scenario
+	Plan     []proxy.ModelMapping
+	Headers  map[string]map[string]string // provider -> env-var headers
+}
+
+// explainRoute dry-runs routing for body through the profile's providers.
+func explainRoute(names []string, profile string, pc *config.ProfileConfig, cli string, body map[string]interface{}) (*routeExplanation, error) {
+	_, plan, providers, err := planRequest(names, profile, pc, cli, body)
+	if err != nil {
+		return nil, err
+	}
+	e := &routeExplanation{
+		Profile: profile,
+		Tokens:  proxy.EstimateTokens(body),
+		Plan:    plan,
+		Headers: make(map[string]map[string]string),
+	}
+	e.Model, _ = body["model"].(string)
+	threshold := 0
+	if pc != nil {
+		threshold = pc.LongContextThreshold
+	}
+	// Detected even without routes, so it shows what a route would catch
+	e.Scenario = proxy.DetectScenario(body, threshold, "")
+	if pc != nil {
+		_, e.Routed = pc.Routing[e.Scenario]
+	}
+
+	byName := make(map[string]*proxy.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name] = p
+	}
+	for _, m := range plan {
+		p, ok := byName[m.Provider]
+		if !ok {
+			// Only in a scenario route
+			built, err := buildProviders([]string{m.Provider})
+			if err != nil {
+				continue
+			}
+			p = built[0]
+			byName[p.Name] = p
+		}
+		if headers := proxy.EnvVarHeaders(p.EnvVars); len(headers) > 0 {
+			e.Headers[p.Name] = headers
+		}
+	}
+	return e, nil
+}
+
+func printRouteExplanation(w io.Writer, e *routeExplanation) {
+	fmt.Fprintf(w, "Profile '%s', model %s, ~%d input tokens\n", e.Profile, e.Model, e.Tokens)
+	if e.Routed {
+		fmt.Fprintf(w, "Scenario: %s (routed)\n", e.Scenario)
+	} else if e.Scenario != config.ScenarioDefault {
+		fmt.Fprintf(w, "Scenario: %s (no route, default chain)\n", e.Scenario)
+	} else {
+		fmt.Fprintf(w, "Scenario: %s\n", e.Scenario)
+	}
+	fmt.Fprintln(w, "Providers:")
+	for i, m := range e.Plan {
+		if m.Fallback && (i == 0 || !e.Plan[i-1].Fallback) {
+			fmt.Fprintln(w, "If the scenario route fails:")
+		}
+		fmt.Fprintf(w, "  %d. %-16s → %-32s (%s)\n", i+1, m.Provider, m.Model, m.Rule)
+		headers := e.Headers[m.Provider]
+		keys := make([]string, 0, len(headers))
+		for k := range headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "       %s: %s\n", k, headers[k])
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the proxy in the foreground without launching a CLI",
+	Long: fmt.Sprintf(`Run the failover proxy for a profile in the foreground, without launching a
+CLI, for clients that share one proxy or for use as a sidecar container.
+Point clients at the listen address (e.g. ANTHROPIC_BASE_URL).
+
+The proxy logs to stdout; with --headless (or %s=1) each line is a JSON
+object. The proxy stops on SIGINT or SIGTERM.
+
+Examples:
+  opencc serve -p work                                # Listen on 127.0.0.1:%d
+  opencc serve -p work --listen 0.0.0.0 --headless    # Docker sidecar`, headlessEnv, config.DefaultProxyPort),
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveProfile string
+	serveCLI     string
+	serveListen  string
+)
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveProfile, "profile", "p", "", "profile to serve (default: bound or default profile)")
+	serveCmd.Flags().StringVar(&serveCLI, "cli", "", "CLI whose API format clients use (claude, codex, opencode)")
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", fmt.Sprintf("listen address, host or host:port (default proxy_listen, else 127.0.0.1:%d)", config.DefaultProxyPort))
+	serveCmd.Flags().StringVar(&metricsListen, "metrics", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9464 (overrides metrics_listen)")
+	serveCmd.Flags().BoolVar(&debugCaptureFlag, "debug-capture", false, "write every provider request and response, with credentials redacted, to ~/.opencc/captures")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	names, profile, cli, err := resolveProviderNamesAndCLI(serveProfile, serveCLI)
+	if err != nil {
+		return err
+	}
+	names, err = validateProviderNames(names, profile)
+	if err != nil {
+		return err
+	}
+	pc := projectConfig().WithRouting(config.GetProfileConfig(profile))
+	names, pc = pinBindingProvider(profile, names, pc)
+	if cli == "" {
+		cli = config.DefaultCLIName
+	}
+	providers, err := buildProviders(names)
+	if err != nil {
+		return err
+	}
+	pinBindingModel(profile, providers)
+	listen := serveListen
+	if listen == "" {
+		// proxy_listen applies when --listen is not given
+		listen = config.GetProxyListen()
+		if listen != "" {
+			host, _, err := splitListen(listen)
+			if err != nil {
+				return err
+			}
+			if err := checkProxyListen(host); err != nil {
+				return err
+			}
+		}
+	}
+	addr, err := listenAddr(listen)
+	if err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	if isHeadless() {
+		logger = log.New(jsonLogWriter{os.Stdout}, "", 0)
+	}
+
+	logDir := config.ConfigDirPath()
+	if err := proxy.InitGlobalLogger(logDir); err != nil {
+		logger.Printf("Warning: failed to initialize structured logger: %v", err)
+	}
+	defer startAutoOrder(logger)()
+
+	srv, cleanup, err := newProxyServer(providers, profile, pc, cli, logger, logDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ln, err := srv.Listen(addr)
+	if err != nil {
+		return fmt.Errorf("failed to start proxy: %w", err)
+	}
+	scheme := "http"
+	if srv.TLS != nil {
+		scheme = "https"
+	}
+	logger.Printf("Serving profile '%s' (%s) on %s://%s with %d providers", profile, cli, scheme, ln.Addr(), len(providers))
+	for i, p := range providers {
+		logger.Printf("  [%d] %s → %s (model=%s)", i+1, p.Name, p.BaseURL.String(), p.Model)
+	}
+
+	httpSrv := &http.Server{Handler: srv}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		logger.Printf("Received %v, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpSrv.Shutdown(ctx)
+	}()
+
+	if err := httpSrv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listenAddr completes a --listen value: empty means loopback, and a bare
+// host gets the default proxy port.
+func listenAddr(listen string) (string, error) {
+	if listen == "" {
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(config.DefaultProxyPort)), nil
+	}
+	host, port, err := splitListen(listen)
+	if err != nil {
+		return "", err
+	}
+	if port == "" {
+		port = strconv.Itoa(config.DefaultProxyPort)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// splitListen splits a listen address into host and port; port is "" for a
+// bare host.
+func splitListen(listen string) (host, port string, err error) {
+	if host, port, err := net.SplitHostPort(listen); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", "", fmt.Errorf(i18n.T("invalid --listen port '%s'"), port)
+		}
+		return host, port, nil
+	}
+	host = strings.Trim(listen, "[]")
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return "", "", fmt.Errorf(i18n.T("invalid --listen address '%s'"), listen)
+	}
+	return host, "", nil
+}
+
+// checkProxyListen refuses a configured proxy_listen host that accepts
+// remote connections when no proxy_auth_token guards it.
+func checkProxyListen(host string) error {
+	if !config.IsLoopbackHost(host) && config.GetProxyAuthToken() == "" {
+		return fmt.Errorf(i18n.T("proxy_listen '%s' accepts remote connections; set proxy_auth_token to require a key"), host)
+	}
+	return nil
+}
+
+// jsonLogWriter turns each log.Logger line into a JSON object, for log
+// collectors in headless mode.
+type jsonLogWriter struct {
+	w io.Writer
+}
+
+func (j jsonLogWriter) Write(p []byte) (int, error) {
+	line, err := json.Marshal(struct {
+		Time    time.Time `json:"time"`
+		Level   string    `json:"level"`
+		Message string    `json:"msg"`
+	}{time.Now(), "info", strings.TrimRight(string(p), "\n")})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := j.w.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/dopejs/opencc/internal/web"
+	"github.com/spf13/cobra"
+)
+
+// smokeTestPrompt is the tiny prompt `opencc test` sends.
+const smokeTestPrompt = "Reply with the single word OK."
+
+const (
+	smokeTestDefaultModel     = "claude-haiku-4-5"
+	smokeTestDefaultMaxTokens = 16
+)
+
+var smokeTestCmd = &cobra.Command{
+	Use:   "test [provider]",
+	Short: "Send a tiny real request to providers to verify they work",
+	Long: `Send a tiny /v1/messages request through the proxy, with model mapping
+and transforms, to one provider or to each provider of a profile, and report
+latency, the model that answered and token usage. Use it to verify credentials
+and mappings without launching a CLI.
+
+Each provider is tested on its own, without failover. The request uses a few
+tokens. Exits non-zero if any provider fails.
+
+Examples:
+  opencc test backup         # Test the 'backup' provider
+  opencc test -p work        # Test every provider of the 'work' profile
+  opencc test                # Test the bound or default profile`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeConfigNames,
+	RunE:              runSmokeTest,
+}
+
+var (
+	smokeTestProfile   string
+	smokeTestModel     string
+	smokeTestMaxTokens int
+)
+
+func init() {
+	smokeTestCmd.Flags().StringVarP(&smokeTestProfile, "profile", "p", "", "test the providers of this profile (default: bound or default profile)")
+	smokeTestCmd.Flags().StringVar(&smokeTestModel, "model", smokeTestDefaultModel, "model to request; each provider maps it as usual")
+	smokeTestCmd.Flags().IntVar(&smokeTestMaxTokens, "max-tokens", smokeTestDefaultMaxTokens, "max_tokens of the request")
+}
+
+func runSmokeTest(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 && smokeTestProfile != "" {
+		return errors.New(i18n.T("use either a provider or --profile, not both"))
+	}
+	names := args
+	if len(names) == 0 {
+		var err error
+		names, _, _, err = resolveProviderNamesAndCLI(smokeTestProfile, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := compareProvidersPrompt(names, smokeTestPrompt, smokeTestModel, smokeTestMaxTokens)
+	if err != nil {
+		return err
+	}
+	if failed := printSmokeTest(cmd.OutOrStdout(), results); failed > 0 {
+		return fmt.Errorf(i18n.T("%d of %d provider(s) failed"), failed, len(results))
+	}
+	return nil
+}
+
+// printSmokeTest writes one line per provider and returns how many failed.
+func printSmokeTest(w io.Writer, results []compareResult) int {
+	failed := 0
+	fmt.Fprintf(w, "%-16s %-6s %6s %9s %6s %6s  %s\n", "PROVIDER", "RESULT", "STATUS", "LATENCY", "IN", "OUT", "MODEL")
+	for _, r := range results {
+		result, detail := "ok", r.Model
+		if r.Status < 200 || r.Status >= 300 {
+			failed++
+			result, detail = "FAIL", firstLine(r.Text)
+		}
+		fmt.Fprintf(w, "%-16s %-6s %6d %9s %6d %6d  %s\n", r.Provider, result, r.Status, r.Latency.Round(time.Millisecond), r.InputTokens, r.OutputTokens, detail)
+	}
+	return failed
+}
+
+// firstLine returns the first line of s, for one-line error summaries.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// testProvider is the web UI's connectivity test of a provider: a probe, then
+// the smoke test request unless the probe shows the provider is unusable.
+func testProvider(ctx context.Context, name string) web.ProviderTestResult {
+	res := web.ProviderTestResult{Provider: name}
+	providers, err := buildProviders([]string{name})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	p := providers[0]
+	probe := proxy.ProbeProvider(ctx, p.HTTPClient(http.DefaultClient), p)
+	res.ProbeStatus = probe.StatusCode
+	res.ProbeLatencyMs = probe.Latency.Milliseconds()
+	if problem := probe.Problem(); problem != "" {
+		res.Error = problem
+		return res
+	}
+
+	results, err := compareProvidersPrompt([]string{name}, smokeTestPrompt, smokeTestDefaultModel, smokeTestDefaultMaxTokens)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	r := results[0]
+	res.Status = r.Status
+	res.LatencyMs = r.Latency.Milliseconds()
+	res.Model = r.Model
+	res.InputTokens = r.InputTokens
+	res.OutputTokens = r.OutputTokens
+	res.OK = r.Status >= 200 && r.Status < 300
+	if !res.OK {
+		res.Error = firstLine(r.Text)
+	}
+	return res
+}
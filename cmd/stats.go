@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+	"github.com/dopejs/opencc/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show per-provider request metrics",
+	Long: `Show, for each provider, the requests it was sent and how many failed, its
+median and 95th percentile latency to response headers, and the tokens it
+served. Metrics come from the request log every proxy writes, so they cover
+all sessions.
+
+Examples:
+  opencc stats                # Last 24 hours
+  opencc stats --since 1h`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+var statsSince time.Duration
+
+func init() {
+	statsCmd.Flags().DurationVar(&statsSince, "since", 24*time.Hour, "how far back to look (e.g. 1h, 168h)")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if statsSince <= 0 {
+		return errors.New(i18n.T("--since must be a positive duration"))
+	}
+	db, err := proxy.OpenLogDB(config.ConfigDirPath())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	metrics, err := db.ProviderMetrics(time.Now().Add(-statsSince))
+	if err != nil {
+		return err
+	}
+	printStats(cmd.OutOrStdout(), metrics)
+	return nil
+}
+
+// printStats writes metrics as a table.
+func printStats(w io.Writer, metrics []proxy.ProviderMetrics) {
+	if len(metrics) == 0 {
+		fmt.Fprintln(w, "No requests logged in this period.")
+		return
+	}
+	fmt.Fprintf(w, "%-16s %8s %7s %8s %8s %8s %12s %12s\n", "PROVIDER", "REQUESTS", "ERRORS", "SUCCESS", "P50", "P95", "TOKENS IN", "TOKENS OUT")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "%-16s %8d %7d %7.1f%% %8s %8s %12d %12d\n",
+			m.Provider, m.Requests, m.Errors, m.SuccessRate*100,
+			formatLatencyMs(m.P50LatencyMs), formatLatencyMs(m.P95LatencyMs),
+			m.InputTokens, m.OutputTokens)
+	}
+}
+
+// formatLatencyMs renders a latency in milliseconds, or "-" if unknown.
+func formatLatencyMs(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
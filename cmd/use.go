@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -55,7 +56,7 @@ func runUse(cmd *cobra.Command, args []string) error {
 	// Find CLI binary
 	cliPath, err := exec.LookPath(cliBin)
 	if err != nil {
-		return fmt.Errorf("%s not found in PATH: %w", cliBin, err)
+		return fmt.Errorf(i18n.T("%s not found in PATH: %w"), cliBin, err)
 	}
 
 	// Replace process with CLI (like shell exec)
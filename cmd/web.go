@@ -1,18 +1,23 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/dopejs/opencc/internal/config"
 	"github.com/dopejs/opencc/internal/daemon"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/dopejs/opencc/internal/proxy"
 	"github.com/dopejs/opencc/internal/web"
 	"github.com/spf13/cobra"
@@ -24,7 +29,7 @@ var webPortOverride int
 var webCmd = &cobra.Command{
 	Use:   "web",
 	Short: "Start the web configuration interface",
-	Long:  "Start an embedded HTTP server on 127.0.0.1:19840 for managing providers and profiles.",
+	Long:  "Start an embedded HTTP server on 127.0.0.1:19840 (see web_access for remote access) for managing providers and profiles.",
 	RunE:  runWeb,
 }
 
@@ -50,7 +55,8 @@ var webStatusCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		pid, running := daemon.IsRunning()
 		if running {
-			fmt.Printf("Web server is running (PID %d) on http://127.0.0.1:%d\n", pid, config.GetWebPort())
+			fmt.Printf("Web server is running (PID %d) on %s\n", pid, web.BaseURL(config.GetWebPort()))
+			fmt.Printf("Sign in: %s\n", webLoginURL(config.GetWebPort()))
 		} else {
 			fmt.Println("Web server is not running.")
 		}
@@ -81,6 +87,37 @@ var webRestartCmd = &cobra.Command{
 	},
 }
 
+var webPasswordClear bool
+
+var webPasswordCmd = &cobra.Command{
+	Use:   "password",
+	Short: "Set the web UI password",
+	Long: `Set a password for signing in to the web UI from a browser that didn't
+open the link 'opencc web' prints. Only a hash of it is saved. Without a
+password, the link is the only way in.
+
+The password is prompted for, or read from stdin when it isn't a terminal.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if webPasswordClear {
+			if err := config.SetWebPassword(""); err != nil {
+				return err
+			}
+			fmt.Println("Web UI password removed.")
+			return nil
+		}
+		password, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+		if err := config.SetWebPassword(password); err != nil {
+			return err
+		}
+		fmt.Println("Web UI password set.")
+		return nil
+	},
+}
+
 var webDisableCmd = &cobra.Command{
 	Use:   "disable",
 	Short: "Uninstall system service",
@@ -102,6 +139,52 @@ func init() {
 	webCmd.AddCommand(webRestartCmd)
 	webCmd.AddCommand(webEnableCmd)
 	webCmd.AddCommand(webDisableCmd)
+	webPasswordCmd.Flags().BoolVar(&webPasswordClear, "clear", false, "remove the password")
+	webCmd.AddCommand(webPasswordCmd)
+}
+
+// readNewPassword prompts for a new password twice on a terminal, or reads
+// one line from stdin otherwise.
+func readNewPassword() (string, error) {
+	if f, ok := stdinReader.(*os.File); ok && term.IsTerminal(f.Fd()) {
+		var entered [2]string
+		for i, prompt := range []string{"New password: ", "Repeat password: "} {
+			fmt.Fprint(os.Stderr, prompt)
+			b, err := term.ReadPassword(f.Fd())
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", err
+			}
+			entered[i] = string(b)
+		}
+		if entered[0] != entered[1] {
+			return "", errors.New(i18n.T("passwords don't match"))
+		}
+		if entered[0] == "" {
+			return "", errors.New(i18n.T("password must not be empty"))
+		}
+		return entered[0], nil
+	}
+	line, err := bufio.NewReader(stdinReader).ReadString('\n')
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		if err != nil {
+			return "", err
+		}
+		return "", errors.New(i18n.T("password must not be empty"))
+	}
+	return password, nil
+}
+
+// webLoginURL returns the web UI address on port that signs the browser in,
+// or the plain address if the session token can't be read.
+func webLoginURL(port int) string {
+	base := web.BaseURL(port)
+	token, err := web.LoadToken(config.ConfigDirPath())
+	if err != nil {
+		return base
+	}
+	return web.LoginURL(base, token)
 }
 
 func runWeb(cmd *cobra.Command, args []string) error {
@@ -129,8 +212,10 @@ func runWebServer(portOverride int) error {
 	if err := proxy.InitGlobalLogger(config.ConfigDirPath()); err != nil {
 		logger.Printf("Warning: failed to initialize structured logger: %v", err)
 	}
+	defer startAutoOrder(logger)()
 
 	srv := web.NewServer(Version, logger, portOverride)
+	srv.SetProviderTester(testProvider)
 
 	// Only manage PID file when running on the configured port (normal mode).
 	managePid := portOverride == 0
@@ -160,7 +245,7 @@ func runWebForeground(portOverride int) error {
 	if portOverride == 0 {
 		if pid, running := daemon.IsRunning(); running {
 			fmt.Printf("Web server already running (PID %d). Opening browser...\n", pid)
-			openBrowser(fmt.Sprintf("http://127.0.0.1:%d", config.GetWebPort()))
+			openBrowser(webLoginURL(config.GetWebPort()))
 			return nil
 		}
 	}
@@ -169,12 +254,14 @@ func runWebForeground(portOverride int) error {
 	if portOverride > 0 {
 		port = portOverride
 	}
-	fmt.Printf("Starting web server on http://127.0.0.1:%d\n", port)
+	loginURL := webLoginURL(port)
+	fmt.Printf("Starting web server on %s\n", web.BaseURL(port))
+	fmt.Printf("Sign in: %s\n", loginURL)
 
 	// Open browser after a short delay to let server start.
 	go func() {
 		time.Sleep(300 * time.Millisecond)
-		openBrowser(fmt.Sprintf("http://127.0.0.1:%d", port))
+		openBrowser(loginURL)
 	}()
 
 	return runWebServer(portOverride)
@@ -218,7 +305,8 @@ func startDaemon() error {
 		return fmt.Errorf("daemon started but server did not become ready: %w", err)
 	}
 
-	fmt.Printf("Web server started in background (PID %d) on http://127.0.0.1:%d\n", child.Process.Pid, config.GetWebPort())
+	fmt.Printf("Web server started in background (PID %d) on %s\n", child.Process.Pid, web.BaseURL(config.GetWebPort()))
+	fmt.Printf("Sign in: %s\n", webLoginURL(config.GetWebPort()))
 	return nil
 }
 
@@ -0,0 +1,36 @@
+package config
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version sent when a
+// provider doesn't set one.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureConfig holds the API version and deployments of an "azure-openai"
+// provider. Azure OpenAI serves each model from a named deployment, which
+// takes the place of the model in the request URL.
+type AzureConfig struct {
+	APIVersion  string            `json:"api_version,omitempty"` // defaults to DefaultAzureAPIVersion
+	Deployments map[string]string `json:"deployments,omitempty"` // model name or class ("reasoning", "haiku", "opus", "sonnet", "default") → deployment
+}
+
+// GetAPIVersion returns the API version to send. a may be nil.
+func (a *AzureConfig) GetAPIVersion() string {
+	if a == nil || a.APIVersion == "" {
+		return DefaultAzureAPIVersion
+	}
+	return a.APIVersion
+}
+
+// Deployment returns the deployment serving model, a request of the given
+// model class: the deployment named for the model, else for its class, else
+// the default deployment. Without one, the model name is used as the
+// deployment name. a may be nil.
+func (a *AzureConfig) Deployment(model, class string) string {
+	if a != nil {
+		for _, key := range []string{model, class, "default"} {
+			if d := a.Deployments[key]; d != "" {
+				return d
+			}
+		}
+	}
+	return model
+}
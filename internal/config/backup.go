@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupDir is the directory inside the config dir holding the snapshots of
+// the config file taken before destructive changes.
+const BackupDir = "backups"
+
+// backupIndexFile records why each backup was taken, one JSON line each.
+const backupIndexFile = "index.jsonl"
+
+// DefaultBackupRetention is how many backups are kept when backup_retention
+// is unset.
+const DefaultBackupRetention = 20
+
+// backupIDFormat is the time format backup IDs start with, so they sort by
+// age.
+const backupIDFormat = "20060102-150405.000"
+
+// Backup is a snapshot of the config file.
+type Backup struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason,omitempty"` // the change it was taken before, e.g. "delete provider work"
+	Size   int64     `json:"size,omitempty"`
+
+	file string // name in the backup dir, which keeps the config file's extension
+}
+
+func (s *Store) backupDir() string {
+	return filepath.Join(filepath.Dir(s.path), BackupDir)
+}
+
+// backupID returns the ID of a backup file name, which is the ID with the
+// extension of a config file.
+func backupID(name string) (string, bool) {
+	for _, ext := range configExts {
+		if id, ok := strings.CutSuffix(name, ext); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// backupLocked snapshots the config file as it is on disk before the change
+// described by reason, then removes the oldest backups beyond the retention
+// limit. Without a config file there is nothing to back up, and the zero
+// Backup is returned. Must be called with s.mu held.
+func (s *Store) backupLocked(reason string) (Backup, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Backup{}, nil
+	}
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to back up config: %w", err)
+	}
+	dir := s.backupDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Backup{}, fmt.Errorf("failed to back up config: %w", err)
+	}
+
+	now := time.Now()
+	ext := filepath.Ext(s.path)
+	id := now.Format(backupIDFormat)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+ext)); os.IsNotExist(err) {
+			break
+		}
+		id = now.Format(backupIDFormat) + "-" + strconv.Itoa(n)
+	}
+	// Backups hold tokens, so they are private like the config
+	if err := os.WriteFile(filepath.Join(dir, id+ext), data, 0600); err != nil {
+		return Backup{}, fmt.Errorf("failed to back up config: %w", err)
+	}
+	b := Backup{ID: id, Time: now, Reason: reason, file: id + ext}
+	line, _ := json.Marshal(b)
+	f, err := os.OpenFile(filepath.Join(dir, backupIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to back up config: %w", err)
+	}
+	f.Write(append(line, '\n'))
+	f.Close()
+	b.Size = int64(len(data))
+	return b, s.pruneBackupsLocked()
+}
+
+// Backup snapshots the config file before a change the caller is about to
+// make, described by reason. The zero Backup means there was no config file
+// to back up.
+func (s *Store) Backup(reason string) (Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backupLocked(reason)
+}
+
+// pruneBackupsLocked removes the oldest backups beyond the retention limit
+// and their index lines. Must be called with s.mu held.
+func (s *Store) pruneBackupsLocked() error {
+	keep := DefaultBackupRetention
+	if s.config != nil && s.config.BackupRetention > 0 {
+		keep = s.config.BackupRetention
+	}
+	backups, err := s.listBackups()
+	if err != nil || len(backups) <= keep {
+		return err
+	}
+	for _, b := range backups[keep:] {
+		os.Remove(filepath.Join(s.backupDir(), b.file))
+	}
+	var index []byte
+	for _, b := range backups[:keep] {
+		line, _ := json.Marshal(Backup{ID: b.ID, Time: b.Time, Reason: b.Reason})
+		index = append(index, line...)
+		index = append(index, '\n')
+	}
+	return os.WriteFile(filepath.Join(s.backupDir(), backupIndexFile), index, 0600)
+}
+
+// ListBackups returns the backups of the config file, newest first.
+func (s *Store) ListBackups() ([]Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listBackups()
+}
+
+func (s *Store) listBackups() ([]Backup, error) {
+	dir := s.backupDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[string]Backup)
+	if f, err := os.Open(filepath.Join(dir, backupIndexFile)); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var b Backup
+			if json.Unmarshal(scanner.Bytes(), &b) == nil {
+				reasons[b.ID] = b
+			}
+		}
+		f.Close()
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		id, ok := backupID(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		b := reasons[id]
+		b.ID, b.file = id, e.Name()
+		if info, err := e.Info(); err == nil {
+			b.Size = info.Size()
+			if b.Time.IsZero() {
+				b.Time = info.ModTime()
+			}
+		}
+		backups = append(backups, b)
+	}
+	slices.SortFunc(backups, func(a, b Backup) int { return strings.Compare(b.ID, a.ID) })
+	return backups, nil
+}
+
+// RestoreBackup replaces the config file with the backup id, converted to
+// the config file's format if it was taken in another. The config being
+// replaced is backed up first, so a restore can be undone too.
+func (s *Store) RestoreBackup(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backups, err := s.listBackups()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(backups, func(b Backup) bool { return b.ID == id })
+	if i < 0 {
+		return fmt.Errorf("backup '%s' not found", id)
+	}
+	file := backups[i].file
+	data, err := os.ReadFile(filepath.Join(s.backupDir(), file))
+	if err != nil {
+		return err
+	}
+	doc, err := toJSON(data, FormatForPath(file))
+	var cfg OpenCCConfig
+	if err == nil {
+		err = json.Unmarshal(doc, &cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("backup '%s' is not a valid config: %w", id, err)
+	}
+	if format := FormatForPath(s.path); format != FormatForPath(file) {
+		if data, err = fromJSON(doc, format); err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+	}
+
+	if _, err := s.backupLocked("restore " + id); err != nil {
+		return err
+	}
+	tmp := s.path + ".restore"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return s.loadLocked()
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BedrockConfig holds the region and AWS credentials of a "bedrock"
+// provider. Unset values are read the way the AWS CLI reads them: from the
+// AWS_* environment variables, then from the shared config and credentials
+// files for the profile.
+type BedrockConfig struct {
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Profile         string `json:"profile,omitempty"` // shared credentials profile; empty = AWS_PROFILE or "default"
+}
+
+// Endpoint returns the Bedrock runtime endpoint for the region.
+func (b *BedrockConfig) Endpoint() string {
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", b.Region)
+}
+
+// Resolve returns the configuration with the region and credentials filled
+// in from the environment and the shared AWS files. Explicit keys are used
+// as they are; a named profile is read from the credentials file rather
+// than the environment. b may be nil.
+func (b *BedrockConfig) Resolve() (*BedrockConfig, error) {
+	r := &BedrockConfig{}
+	if b != nil {
+		*r = *b
+	}
+	profile := r.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	if r.Region == "" {
+		r.Region = firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+	if r.Region == "" {
+		section := "profile " + profile
+		if profile == "default" {
+			section = "default"
+		}
+		values, err := readAWSFile(awsFilePath("AWS_CONFIG_FILE", "config"), section)
+		if err != nil {
+			return nil, err
+		}
+		r.Region = values["region"]
+	}
+	if r.Region == "" {
+		return nil, errors.New("bedrock: no region configured")
+	}
+
+	switch {
+	case r.AccessKeyID != "" || r.SecretAccessKey != "":
+	case r.Profile == "" && os.Getenv("AWS_ACCESS_KEY_ID") != "":
+		r.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		r.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		r.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	default:
+		values, err := readAWSFile(awsFilePath("AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile)
+		if err != nil {
+			return nil, err
+		}
+		r.AccessKeyID = values["aws_access_key_id"]
+		r.SecretAccessKey = values["aws_secret_access_key"]
+		r.SessionToken = values["aws_session_token"]
+	}
+	if r.AccessKeyID == "" || r.SecretAccessKey == "" {
+		return nil, fmt.Errorf("bedrock: no AWS credentials for profile %q", profile)
+	}
+	return r, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// awsFilePath returns the shared AWS file named by env, or ~/.aws/name.
+func awsFilePath(env, name string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".aws", name)
+}
+
+// readAWSFile returns the keys of one section of a shared AWS config or
+// credentials file. A missing file has no sections.
+func readAWSFile(path, section string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	in := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			in = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); in && ok {
+			values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return values, scanner.Err()
+}
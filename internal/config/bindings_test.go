@@ -104,6 +104,92 @@ func TestProjectBindingsWithCLI(t *testing.T) {
 	}
 }
 
+func TestRebindKeepsLaunchTemplates(t *testing.T) {
+	home := setTestHome(t)
+	if err := SetProfileConfig("work", &ProfileConfig{Providers: []string{"p"}}); err != nil {
+		t.Fatal(err)
+	}
+	testPath := filepath.Join(home, "proj")
+	if err := BindProject(testPath, "work", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	GetProjectBinding(testPath).Launch = map[string]*LaunchTemplate{"claude": {Args: []string{"--verbose"}}}
+	if err := DefaultStore().Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BindProject(testPath, "work", "codex"); err != nil {
+		t.Fatal(err)
+	}
+	binding := GetProjectBinding(testPath)
+	if binding.CLI != "codex" {
+		t.Errorf("CLI = %q, want codex", binding.CLI)
+	}
+	if binding.Launch["claude"] == nil || binding.Launch["claude"].Args[0] != "--verbose" {
+		t.Errorf("launch template lost on rebind: %+v", binding.Launch)
+	}
+}
+
+func TestFindProjectBinding(t *testing.T) {
+	home := setTestHome(t)
+	for _, name := range []string{"repo", "sub"} {
+		if err := SetProfileConfig(name, &ProfileConfig{Providers: []string{"p"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := filepath.Join(home, "repo")
+	sub := filepath.Join(repo, "sub")
+	if err := BindProject(repo, "repo", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := BindProject(sub, "sub", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path        string
+		wantDir     string
+		wantProfile string
+	}{
+		{repo, repo, "repo"},
+		{filepath.Join(repo, "cmd", "tool"), repo, "repo"},
+		{sub, sub, "sub"},
+		{filepath.Join(sub, "deeper"), sub, "sub"},
+		{filepath.Join(home, "repo2"), "", ""},
+		{home, "", ""},
+	}
+	for _, tt := range tests {
+		dir, b := FindProjectBinding(tt.path)
+		profile := ""
+		if b != nil {
+			profile = b.Profile
+		}
+		if dir != tt.wantDir || profile != tt.wantProfile {
+			t.Errorf("FindProjectBinding(%q) = %q, %q; want %q, %q", tt.path, dir, profile, tt.wantDir, tt.wantProfile)
+		}
+	}
+
+	if err := SetProjectBindingExact(repo, true); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ := FindProjectBinding(filepath.Join(repo, "cmd")); dir != "" {
+		t.Errorf("exact binding applied to subdirectory, matched %q", dir)
+	}
+	if dir, _ := FindProjectBinding(repo); dir != repo {
+		t.Errorf("exact binding didn't apply to its directory, matched %q", dir)
+	}
+	if err := BindProject(repo, "repo", "codex"); err != nil {
+		t.Fatal(err)
+	}
+	if !GetProjectBinding(repo).Exact {
+		t.Error("rebinding cleared exact")
+	}
+	if err := SetProjectBindingExact(filepath.Join(home, "nope"), true); err == nil {
+		t.Error("SetProjectBindingExact() on unbound path should error")
+	}
+}
+
 func TestBindNonexistentProfile(t *testing.T) {
 	setTestHome(t)
 
@@ -1,6 +1,39 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckConfig loads the config file and environment overrides afresh and
+// returns the error the default store would only log.
+func CheckConfig() error {
+	return (&Store{path: ConfigFilePath()}).Load()
+}
+
+// ValidateConfigFile checks the config file on disk. A missing file has no
+// problems.
+func ValidateConfigFile() ([]ConfigProblem, error) {
+	problems, err := ValidateConfigPath(ConfigFilePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return problems, err
+}
+
+// ValidateConfigPath checks a config file in the format of its extension.
+func ValidateConfigPath(path string) ([]ConfigProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = toJSON(data, FormatForPath(path)); err != nil {
+		return []ConfigProblem{{Message: err.Error()}}, nil
+	}
+	return validateConfig(data, filepath.Dir(path)), nil
+}
 
 // --- Provider convenience functions (delegate to DefaultStore) ---
 
@@ -9,11 +42,28 @@ func GetProvider(name string) *ProviderConfig {
 	return DefaultStore().GetProvider(name)
 }
 
+// ResolveProvider returns the config for a named provider, or nil, and the
+// error resolving a token kept in the keychain or encrypted.
+func ResolveProvider(name string) (*ProviderConfig, error) {
+	return DefaultStore().ResolveProvider(name)
+}
+
 // SetProvider creates or updates a provider and saves.
 func SetProvider(name string, p *ProviderConfig) error {
 	return DefaultStore().SetProvider(name, p)
 }
 
+// SetProviderCooldown marks a provider unavailable until the given time.
+// A zero time clears the cooldown.
+func SetProviderCooldown(name string, until time.Time) error {
+	return DefaultStore().SetProviderCooldown(name, until)
+}
+
+// SetOAuthRefreshToken saves a provider's rotated OAuth refresh token.
+func SetOAuthRefreshToken(name, token string) error {
+	return DefaultStore().SetOAuthRefreshToken(name, token)
+}
+
 // DeleteProviderByName removes a provider and its references from all profiles.
 func DeleteProviderByName(name string) error {
 	return DefaultStore().DeleteProvider(name)
@@ -29,6 +79,16 @@ func ExportProviderToEnv(name string) error {
 	return DefaultStore().ExportProviderToEnv(name)
 }
 
+// GetSecretStore returns where provider tokens are saved.
+func GetSecretStore() string {
+	return DefaultStore().GetSecretStore()
+}
+
+// SetSecretStore moves every provider token to the given secret store.
+func SetSecretStore(store string) error {
+	return DefaultStore().SetSecretStore(store)
+}
+
 // --- Profile convenience functions ---
 
 // ReadProfileOrder returns the provider list for a profile.
@@ -60,11 +120,16 @@ func ListProfiles() []string {
 	return DefaultStore().ListProfiles()
 }
 
-// GetProfileConfig returns the full profile configuration.
+// GetProfileConfig returns the full profile configuration, inheritance applied.
 func GetProfileConfig(profile string) *ProfileConfig {
 	return DefaultStore().GetProfileConfig(profile)
 }
 
+// GetOwnProfileConfig returns the profile as configured, for editing.
+func GetOwnProfileConfig(profile string) *ProfileConfig {
+	return DefaultStore().GetOwnProfileConfig(profile)
+}
+
 // SetProfileConfig sets the full profile configuration.
 func SetProfileConfig(profile string, pc *ProfileConfig) error {
 	return DefaultStore().SetProfileConfig(profile, pc)
@@ -119,6 +184,152 @@ func SetWebPort(port int) error {
 	return DefaultStore().SetWebPort(port)
 }
 
+// HasWebPassword reports whether a web UI password is set.
+func HasWebPassword() bool {
+	return DefaultStore().HasWebPassword()
+}
+
+// CheckWebPassword reports whether password is the web UI password.
+func CheckWebPassword(password string) bool {
+	return DefaultStore().CheckWebPassword(password)
+}
+
+// SetWebPassword sets the web UI password; "" removes it.
+func SetWebPassword(password string) error {
+	return DefaultStore().SetWebPassword(password)
+}
+
+// GetPreflightCheck reports whether the primary provider should be probed
+// before launching a CLI session.
+func GetPreflightCheck() bool {
+	return DefaultStore().GetPreflightCheck()
+}
+
+// SetPreflightCheck enables or disables the pre-launch provider probe.
+func SetPreflightCheck(enabled bool) error {
+	return DefaultStore().SetPreflightCheck(enabled)
+}
+
+// GetStrictEnv reports whether launching should be refused when shell
+// environment variables conflict with the ones opencc sets.
+func GetStrictEnv() bool {
+	return DefaultStore().GetStrictEnv()
+}
+
+// SetStrictEnv enables or disables refusing to launch on env conflicts.
+func SetStrictEnv(enabled bool) error {
+	return DefaultStore().SetStrictEnv(enabled)
+}
+
+// GetLocale returns the configured UI language, or "" to follow LANG.
+func GetLocale() string {
+	return DefaultStore().GetLocale()
+}
+
+// SetLocale sets the UI language; "" follows LANG.
+func SetLocale(locale string) error {
+	return DefaultStore().SetLocale(locale)
+}
+
+// GetLaunchTemplate returns the global launch template for a CLI, or nil.
+func GetLaunchTemplate(cli string) *LaunchTemplate {
+	return DefaultStore().GetLaunchTemplate(cli)
+}
+
+// GetAccessLog returns the access log settings, or nil if disabled.
+func GetAccessLog() *AccessLogConfig {
+	return DefaultStore().GetAccessLog()
+}
+
+// GetUsageWarnings returns the session token warning settings, or nil if disabled.
+func GetUsageWarnings() *UsageWarningConfig {
+	return DefaultStore().GetUsageWarnings()
+}
+
+// GetRequestSize returns the global request size limits, or nil if unset.
+func GetRequestSize() *RequestSizeConfig {
+	return DefaultStore().GetRequestSize()
+}
+
+// GetCustomScenarios returns the user-defined scenarios.
+func GetCustomScenarios() []CustomScenario {
+	return DefaultStore().GetCustomScenarios()
+}
+
+// GetProxyPort returns the port for the proxy each session starts, or 0 for
+// a random one.
+func GetProxyPort() int {
+	return DefaultStore().GetProxyPort()
+}
+
+// GetProxyListen returns the host or host:port the proxy listens on, or ""
+// for loopback.
+func GetProxyListen() string {
+	return DefaultStore().GetProxyListen()
+}
+
+// GetProxyAuthToken returns the key proxy clients must present, or "" if
+// none is required.
+func GetProxyAuthToken() string {
+	return DefaultStore().GetProxyAuthToken()
+}
+
+// GetProxyTLS returns the proxy's HTTPS settings, or nil for plain HTTP.
+func GetProxyTLS() *ProxyTLSConfig {
+	return DefaultStore().GetProxyTLS()
+}
+
+// GetMetricsListen returns the address to serve Prometheus metrics on, or "".
+func GetMetricsListen() string {
+	return DefaultStore().GetMetricsListen()
+}
+
+// GetHealthCheck returns the background health check settings, or nil if unset.
+func GetHealthCheck() *HealthCheckConfig {
+	return DefaultStore().GetHealthCheck()
+}
+
+// GetResponseCache returns the response cache settings, or nil if disabled.
+func GetResponseCache() *ResponseCacheConfig {
+	return DefaultStore().GetResponseCache()
+}
+
+// GetWebAccess returns the web UI's remote access settings, or nil for
+// loopback only.
+func GetWebAccess() *WebAccessConfig {
+	return DefaultStore().GetWebAccess()
+}
+
+// GetDebugCapture returns the debug capture settings, or nil if disabled.
+func GetDebugCapture() *DebugCaptureConfig {
+	return DefaultStore().GetDebugCapture()
+}
+
+// GetHooks returns the proxy's hook commands, or nil if there are none.
+func GetHooks() *HooksConfig {
+	return DefaultStore().GetHooks()
+}
+
+// GetRedaction returns the global redaction settings, or nil if unset.
+func GetRedaction() *RedactionConfig {
+	return DefaultStore().GetRedaction()
+}
+
+// GetStreamFailover returns the configured stream failover mode.
+func GetStreamFailover() StreamFailoverMode {
+	return DefaultStore().GetStreamFailover()
+}
+
+// GetFailoverPolicies returns the configured per-path failover policies.
+func GetFailoverPolicies() map[string]FailoverPolicy {
+	return DefaultStore().GetFailoverPolicies()
+}
+
+// GetBackoffQueue returns the backoff wait queue settings, or nil if unset.
+func GetBackoffQueue() *BackoffQueueConfig {
+	return DefaultStore().GetBackoffQueue()
+}
+
 // --- Project Bindings convenience functions ---
 
 // BindProject binds a directory path to a profile and/or CLI.
@@ -136,7 +347,68 @@ func GetProjectBinding(path string) *ProjectBinding {
 	return DefaultStore().GetProjectBinding(path)
 }
 
+// FindProjectBinding returns the binding that applies to a directory path,
+// its own or an ancestor's, and the directory it is bound to.
+func FindProjectBinding(path string) (string, *ProjectBinding) {
+	return DefaultStore().FindProjectBinding(path)
+}
+
+// SetProjectBindingPin sets the provider and model a binding pins.
+func SetProjectBindingPin(path string, provider string, model string) error {
+	return DefaultStore().SetProjectBindingPin(path, provider, model)
+}
+
+// SetProjectBindingExact sets whether a binding applies to subdirectories.
+func SetProjectBindingExact(path string, exact bool) error {
+	return DefaultStore().SetProjectBindingExact(path, exact)
+}
+
+// GetProjectFiles returns how project files combine with project bindings.
+func GetProjectFiles() string {
+	return DefaultStore().GetProjectFiles()
+}
+
+// IsProjectTrusted reports whether the .opencc.json in dir may set env vars.
+func IsProjectTrusted(dir string) bool {
+	return DefaultStore().IsProjectTrusted(dir)
+}
+
+// SetProjectTrusted allows or stops the .opencc.json in dir setting env vars.
+func SetProjectTrusted(dir string, trusted bool) error {
+	return DefaultStore().SetProjectTrusted(dir, trusted)
+}
+
 // GetAllProjectBindings returns all project bindings.
 func GetAllProjectBindings() map[string]*ProjectBinding {
 	return DefaultStore().GetAllProjectBindings()
 }
+
+// --- Import/export convenience functions ---
+
+// ExportConfig returns the named providers and profiles as a shareable
+// document; with no names it exports all of them.
+func ExportConfig(providers, profiles []string, secrets bool) (*ExportDocument, error) {
+	return DefaultStore().Export(providers, profiles, secrets)
+}
+
+// ImportConfig adds the providers and profiles of doc to the config.
+func ImportConfig(doc *ExportDocument, resolve ImportResolver) (*ImportResult, error) {
+	return DefaultStore().Import(doc, resolve)
+}
+
+// --- Backup convenience functions ---
+
+// ListBackups returns the backups of the config file, newest first.
+func ListBackups() ([]Backup, error) {
+	return DefaultStore().ListBackups()
+}
+
+// BackupConfig snapshots the config file before a change.
+func BackupConfig(reason string) (Backup, error) {
+	return DefaultStore().Backup(reason)
+}
+
+// RestoreBackup replaces the config file with a backup.
+func RestoreBackup(id string) error {
+	return DefaultStore().RestoreBackup(id)
+}
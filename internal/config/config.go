@@ -2,7 +2,15 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
@@ -10,9 +18,11 @@ const (
 	ConfigFile = "opencc.json"
 	LegacyDir  = ".cc_envs"
 
-	DefaultWebPort = 19840
-	WebPidFile     = "web.pid"
-	WebLogFile     = "web.log"
+	DefaultWebPort   = 19840
+	DefaultProxyPort = 19841 // opencc serve and the shared proxy
+	WebPidFile       = "web.pid"
+	WebLogFile       = "web.log"
+	ProxyPidFile     = "proxy.pid"
 
 	DefaultProfileName = "default"
 	DefaultCLIName     = "claude"
@@ -23,8 +33,18 @@ const (
 	CLIOpenCode = "opencode"
 
 	// Provider API types
-	ProviderTypeAnthropic = "anthropic"
-	ProviderTypeOpenAI    = "openai"
+	ProviderTypeAnthropic       = "anthropic"
+	ProviderTypeOpenAI          = "openai"
+	ProviderTypeOpenAIResponses = "openai-responses"
+	ProviderTypeGemini          = "gemini"
+	ProviderTypeBedrock         = "bedrock"
+	ProviderTypeVertex          = "vertex"
+	ProviderTypeAzureOpenAI     = "azure-openai"
+	ProviderTypeLocal           = "local"
+
+	// DefaultLocalBaseURL is where "local" providers are reached by
+	// default: Ollama's OpenAI-compatible API.
+	DefaultLocalBaseURL = "http://localhost:11434/v1"
 )
 
 // AvailableCLIs is the canonical list of supported CLI names.
@@ -42,7 +62,7 @@ func IsValidCLI(cli string) bool {
 
 // ProviderConfig holds connection and model settings for a single API provider.
 type ProviderConfig struct {
-	Type           string            `json:"type,omitempty"` // "anthropic" (default) or "openai"
+	Type           string            `json:"type,omitempty"` // "anthropic" (default), "openai", "openai-responses", "azure-openai", "local", "gemini", "bedrock" or "vertex"
 	BaseURL        string            `json:"base_url"`
 	AuthToken      string            `json:"auth_token"`
 	Model          string            `json:"model,omitempty"`
@@ -54,6 +74,298 @@ type ProviderConfig struct {
 	ClaudeEnvVars  map[string]string `json:"claude_env_vars,omitempty"`   // Claude Code specific env vars
 	CodexEnvVars   map[string]string `json:"codex_env_vars,omitempty"`    // Codex specific env vars
 	OpenCodeEnvVars map[string]string `json:"opencode_env_vars,omitempty"` // OpenCode specific env vars
+
+	Pricing      *ProviderPricing      `json:"pricing,omitempty"`      // per-token prices, used by the cheapest strategy
+	Capabilities *ProviderCapabilities `json:"capabilities,omitempty"` // what the provider's models support
+
+	CooldownUntil      *time.Time          `json:"cooldown_until,omitempty"`      // manually marked unavailable until this time
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows,omitempty"` // recurring periods when the provider is unavailable
+
+	MaxRequestBytes int64 `json:"max_request_bytes,omitempty"` // larger request bodies skip this provider; 0 = unlimited
+	MaxConcurrent   int   `json:"max_concurrent,omitempty"`    // requests forwarded at once; 0 = unlimited
+
+	Retry *RetryPolicy `json:"retry,omitempty"` // retries on this provider; overrides the profile's
+
+	DailyBudgetUSD    float64 `json:"daily_budget_usd,omitempty"`    // estimated spend per local day before the provider is skipped; needs pricing
+	MonthlyTokenLimit int64   `json:"monthly_token_limit,omitempty"` // input plus output tokens per local month before the provider is skipped
+
+	ProxyURL string `json:"proxy_url,omitempty"` // HTTP(S) or SOCKS5 proxy to reach the provider through; empty = HTTPS_PROXY/HTTP_PROXY from the environment
+
+	Headers map[string]string `json:"headers,omitempty"` // extra HTTP headers sent with every request to the provider
+
+	SystemPromptPrefix string `json:"system_prompt_prefix,omitempty"` // text put before the system prompt of every request to the provider
+	SystemPromptFile   string `json:"system_prompt_file,omitempty"`   // file whose text follows system_prompt_prefix
+
+	ConnectTimeoutSeconds        int `json:"connect_timeout_seconds,omitempty"`         // time to open a connection (defaults to 10)
+	ResponseHeaderTimeoutSeconds int `json:"response_header_timeout_seconds,omitempty"` // time from sending a request to its response headers; 0 = the request deadline's share
+	IdleTimeoutSeconds           int `json:"idle_timeout_seconds,omitempty"`            // longest a response may go without data (defaults to 300)
+
+	Bedrock *BedrockConfig `json:"bedrock,omitempty"` // region and AWS credentials for "bedrock" providers
+	Vertex  *VertexConfig  `json:"vertex,omitempty"`  // project, region and Google credentials for "vertex" providers
+	Azure   *AzureConfig   `json:"azure,omitempty"`   // API version and deployments for "azure-openai" providers
+
+	OAuth *OAuthConfig `json:"oauth,omitempty"` // refresh token exchanged for short-lived access tokens, in place of auth_token
+}
+
+// Provider timeout defaults, used when the corresponding field is unset.
+const (
+	DefaultConnectTimeoutSeconds = 10
+	DefaultIdleTimeoutSeconds    = 300
+)
+
+// ConnectTimeout returns how long opening a connection to the provider may take.
+func (p *ProviderConfig) ConnectTimeout() time.Duration {
+	return time.Duration(orDefault(p.ConnectTimeoutSeconds, DefaultConnectTimeoutSeconds)) * time.Second
+}
+
+// ResponseHeaderTimeout returns how long the provider may take to send
+// response headers, or 0 for no limit beyond the request deadline.
+func (p *ProviderConfig) ResponseHeaderTimeout() time.Duration {
+	return time.Duration(p.ResponseHeaderTimeoutSeconds) * time.Second
+}
+
+// IdleTimeout returns how long a response from the provider may go without
+// data before the attempt is abandoned.
+func (p *ProviderConfig) IdleTimeout() time.Duration {
+	return time.Duration(orDefault(p.IdleTimeoutSeconds, DefaultIdleTimeoutSeconds)) * time.Second
+}
+
+// ValidateTimeouts checks that the provider's timeouts are not negative.
+func (p *ProviderConfig) ValidateTimeouts() error {
+	if p.ConnectTimeoutSeconds < 0 || p.ResponseHeaderTimeoutSeconds < 0 || p.IdleTimeoutSeconds < 0 {
+		return errors.New("timeouts must not be negative")
+	}
+	return nil
+}
+
+// ValidateHeaders checks a provider's headers: names must be HTTP tokens
+// and values must not contain line breaks.
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("invalid value for header %s", name)
+		}
+	}
+	return nil
+}
+
+// isTokenChar reports whether r may appear in an HTTP header name.
+func isTokenChar(r rune) bool {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// ValidateProxyURL checks a provider's proxy_url: an http, https, socks5 or
+// socks5h URL with a host. Empty is valid.
+func ValidateProxyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// The parse error quotes the URL, which may hold a password
+		return fmt.Errorf("invalid proxy_url: %w", errors.Unwrap(err))
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy_url %q: scheme must be http, https, socks5 or socks5h", u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy_url %q: missing host", u.Redacted())
+	}
+	return nil
+}
+
+// MaintenanceWindow is a recurring daily or weekly period during which a
+// provider is skipped. Start and End are "HH:MM"; an End at or before Start
+// crosses midnight.
+type MaintenanceWindow struct {
+	Days     []string `json:"days,omitempty"`     // "mon".."sun"; empty means every day
+	Start    string   `json:"start"`              // "HH:MM"
+	End      string   `json:"end"`                // "HH:MM"
+	Timezone string   `json:"timezone,omitempty"` // IANA name; empty means local time
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks the window's times, days and timezone.
+func (w MaintenanceWindow) Validate() error {
+	_, err := w.resolve()
+	return err
+}
+
+// resolvedWindow is a MaintenanceWindow with its times and timezone parsed,
+// so checking it doesn't repeat the parsing.
+type resolvedWindow struct {
+	MaintenanceWindow
+	loc              *time.Location // nil means local time
+	startMin, endMin int
+}
+
+func (w MaintenanceWindow) resolve() (resolvedWindow, error) {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return resolvedWindow{}, fmt.Errorf("invalid start %q: want HH:MM", w.Start)
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return resolvedWindow{}, fmt.Errorf("invalid end %q: want HH:MM", w.End)
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdayNames[strings.ToLower(d)]; !ok {
+			return resolvedWindow{}, fmt.Errorf("invalid day %q", d)
+		}
+	}
+	rw := resolvedWindow{
+		MaintenanceWindow: w,
+		startMin:          start.Hour()*60 + start.Minute(),
+		endMin:            end.Hour()*60 + end.Minute(),
+	}
+	if w.Timezone != "" {
+		if rw.loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return resolvedWindow{}, fmt.Errorf("invalid timezone %q", w.Timezone)
+		}
+	}
+	return rw, nil
+}
+
+// Active reports whether t falls inside the window. A window that crosses
+// midnight belongs to the day it starts on. Invalid windows are never active.
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	rw, err := w.resolve()
+	return err == nil && rw.active(t)
+}
+
+func (w resolvedWindow) active(t time.Time) bool {
+	if w.loc != nil {
+		t = t.In(w.loc)
+	}
+	nowMin := t.Hour()*60 + t.Minute()
+
+	day := t.Weekday()
+	if w.endMin <= w.startMin {
+		// Crosses midnight: before End we're in the window opened yesterday.
+		if nowMin < w.endMin {
+			return w.onDay((day + 6) % 7)
+		}
+		return nowMin >= w.startMin && w.onDay(day)
+	}
+	return nowMin >= w.startMin && nowMin < w.endMin && w.onDay(day)
+}
+
+func (w MaintenanceWindow) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if weekdayNames[strings.ToLower(name)] == d {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleRule moves providers to the front of a profile's chain during a
+// recurring window, such as the off-peak hours a provider gives discounts in.
+type ScheduleRule struct {
+	Days      []string `json:"days,omitempty"`     // "mon".."sun"; empty means every day
+	Start     string   `json:"start"`              // "HH:MM"
+	End       string   `json:"end"`                // "HH:MM"
+	Timezone  string   `json:"timezone,omitempty"` // IANA name; empty means local time
+	Providers []string `json:"providers"`          // tried first, in this order, while the window is open
+}
+
+// Window returns the rule's recurring period.
+func (r ScheduleRule) Window() MaintenanceWindow {
+	return MaintenanceWindow{Days: r.Days, Start: r.Start, End: r.End, Timezone: r.Timezone}
+}
+
+// UnavailableReason explains why the provider is administratively
+// unavailable at now, or returns "" if it is available.
+func (p *ProviderConfig) UnavailableReason(now time.Time) string {
+	return p.UnavailableFunc()(now)
+}
+
+// UnavailableFunc returns a func behaving like UnavailableReason for the
+// provider as configured now. The maintenance windows are resolved once, so
+// the func is cheap enough to call on every request.
+func (p *ProviderConfig) UnavailableFunc() func(now time.Time) string {
+	cooldown := p.CooldownUntil
+	var windows []resolvedWindow
+	for _, w := range p.MaintenanceWindows {
+		if rw, err := w.resolve(); err == nil {
+			windows = append(windows, rw)
+		}
+	}
+	return func(now time.Time) string {
+		if cooldown != nil && now.Before(*cooldown) {
+			return fmt.Sprintf("cooling down until %s", cooldown.Local().Format("2006-01-02 15:04"))
+		}
+		for _, w := range windows {
+			if w.active(now) {
+				return fmt.Sprintf("in maintenance window %s-%s", w.Start, w.End)
+			}
+		}
+		return ""
+	}
+}
+
+// ProviderPricing holds a provider's prices in USD per million tokens.
+type ProviderPricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// ProviderCapabilities describes what a provider's models can handle.
+// Unset fields mean "supported" or "no limit".
+type ProviderCapabilities struct {
+	SupportsThinking *bool `json:"supports_thinking,omitempty"`
+	SupportsVision   *bool `json:"supports_vision,omitempty"`
+	SupportsTools    *bool `json:"supports_tools,omitempty"`
+	ContextWindow    int   `json:"context_window,omitempty"`    // max input tokens
+	MaxOutputTokens  int   `json:"max_output_tokens,omitempty"` // largest max_tokens accepted
+}
+
+// Describe lists the declared capabilities for display, e.g. "thinking",
+// "no vision", "max output 8192". Undeclared ones are omitted.
+func (c *ProviderCapabilities) Describe() []string {
+	if c == nil {
+		return nil
+	}
+	var flags []string
+	for _, f := range []struct {
+		name string
+		v    *bool
+	}{
+		{"thinking", c.SupportsThinking},
+		{"vision", c.SupportsVision},
+		{"tools", c.SupportsTools},
+	} {
+		if f.v == nil {
+			continue
+		}
+		if *f.v {
+			flags = append(flags, f.name)
+		} else {
+			flags = append(flags, "no "+f.name)
+		}
+	}
+	if c.ContextWindow > 0 {
+		flags = append(flags, fmt.Sprintf("context %d", c.ContextWindow))
+	}
+	if c.MaxOutputTokens > 0 {
+		flags = append(flags, fmt.Sprintf("max output %d", c.MaxOutputTokens))
+	}
+	return flags
 }
 
 // GetType returns the provider type, defaulting to "anthropic".
@@ -133,32 +445,42 @@ type ProviderRoute struct {
 
 // ScenarioRoute defines providers and their model overrides for a scenario.
 type ScenarioRoute struct {
-	Providers []*ProviderRoute `json:"providers"`
+	Providers            []*ProviderRoute `json:"providers"`
+	MaxRequestBytes      int64            `json:"max_request_bytes,omitempty"`      // largest request body routed to this scenario; 0 = unlimited
+	LongContextThreshold int              `json:"long_context_threshold,omitempty"` // tokens at which this scenario's requests go to the longContext route; 0 = never
 }
 
 // UnmarshalJSON supports both old format (providers: ["p1"], model: "m") and new format (providers: [{name, model}]).
 func (sr *ScenarioRoute) UnmarshalJSON(data []byte) error {
 	// Try new format first
 	type scenarioRouteAlias struct {
-		Providers []*ProviderRoute `json:"providers"`
+		Providers            []*ProviderRoute `json:"providers"`
+		MaxRequestBytes      int64            `json:"max_request_bytes,omitempty"`
+		LongContextThreshold int              `json:"long_context_threshold,omitempty"`
 	}
 	var alias scenarioRouteAlias
 	if err := json.Unmarshal(data, &alias); err == nil && len(alias.Providers) > 0 {
 		// Check if first provider is actually a ProviderRoute (has Name field)
 		if alias.Providers[0].Name != "" {
 			sr.Providers = alias.Providers
+			sr.MaxRequestBytes = alias.MaxRequestBytes
+			sr.LongContextThreshold = alias.LongContextThreshold
 			return nil
 		}
 	}
 
 	// Try old format: {providers: ["p1", "p2"], model: "m"}
 	var oldFormat struct {
-		Providers []string `json:"providers"`
-		Model     string   `json:"model,omitempty"`
+		Providers            []string `json:"providers"`
+		Model                string   `json:"model,omitempty"`
+		MaxRequestBytes      int64    `json:"max_request_bytes,omitempty"`
+		LongContextThreshold int      `json:"long_context_threshold,omitempty"`
 	}
 	if err := json.Unmarshal(data, &oldFormat); err != nil {
 		return err
 	}
+	sr.MaxRequestBytes = oldFormat.MaxRequestBytes
+	sr.LongContextThreshold = oldFormat.LongContextThreshold
 
 	// Convert old format to new
 	sr.Providers = make([]*ProviderRoute, len(oldFormat.Providers))
@@ -190,11 +512,273 @@ func (sr *ScenarioRoute) ModelForProvider(name string) string {
 	return ""
 }
 
+// IsBuiltin reports whether s is one of the scenarios opencc detects itself.
+func (s Scenario) IsBuiltin() bool {
+	switch s {
+	case ScenarioThink, ScenarioImage, ScenarioLongContext, ScenarioWebSearch, ScenarioBackground, ScenarioDefault:
+		return true
+	}
+	return false
+}
+
+// MatchOp compares the text of a request field with a ScenarioMatcher's value.
+type MatchOp string
+
+const (
+	MatchContains MatchOp = "contains" // the field contains the value
+	MatchEquals   MatchOp = "equals"   // the field is the value
+	MatchRegex    MatchOp = "regex"    // the field matches the value as a regular expression
+)
+
+// ScenarioMatcher tests one field of a request body.
+type ScenarioMatcher struct {
+	// Path is the dotted field path, with "[]" after a name to look into
+	// every element of an array, e.g. "system", "tools[].name" or
+	// "metadata.user_id".
+	Path  string  `json:"path"`
+	Op    MatchOp `json:"op"`
+	Value string  `json:"value"`
+}
+
+// IsValid reports whether m has a path, a known operator and, for regex, a
+// pattern that compiles.
+func (m ScenarioMatcher) IsValid() bool {
+	if m.Path == "" {
+		return false
+	}
+	switch m.Op {
+	case MatchContains, MatchEquals:
+		return true
+	case MatchRegex:
+		_, err := regexp.Compile(m.Value)
+		return err == nil
+	}
+	return false
+}
+
+// CustomScenario is a scenario defined by the requests it matches. Profiles
+// route it under its name like the built-in scenarios.
+type CustomScenario struct {
+	Name   Scenario          `json:"name"`
+	Match  []ScenarioMatcher `json:"match"`            // a request must match all of them
+	Before Scenario          `json:"before,omitempty"` // detected ahead of this scenario; empty = after all others
+}
+
+// IsValid reports whether cs has a name of its own and valid matchers.
+func (cs CustomScenario) IsValid() bool {
+	if cs.Name == "" || cs.Name.IsBuiltin() || len(cs.Match) == 0 {
+		return false
+	}
+	for _, m := range cs.Match {
+		if !m.IsValid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Strategy selects how a profile orders its providers for each request.
+type Strategy string
+
+const (
+	// StrategyFailover tries providers in the configured order (default).
+	StrategyFailover Strategy = "failover"
+	// StrategyCheapest tries the least expensive capable provider first,
+	// moving up the price ladder on failure.
+	StrategyCheapest Strategy = "cheapest"
+	// StrategyRoundRobin starts each request at the next provider in turn,
+	// failing over through the rest of the chain.
+	StrategyRoundRobin Strategy = "round-robin"
+	// StrategyWeighted is round-robin where each provider starts a share of
+	// requests proportional to its weight in ProfileConfig.Weights.
+	StrategyWeighted Strategy = "weighted"
+	// StrategyLeastLatency tries the provider with the lowest recent response
+	// latency first. Providers not yet measured are tried before the rest.
+	StrategyLeastLatency Strategy = "least-latency"
+)
+
+// IsValid reports whether s is a known strategy. Empty means the default.
+func (s Strategy) IsValid() bool {
+	switch s {
+	case "", StrategyFailover, StrategyCheapest, StrategyRoundRobin, StrategyWeighted, StrategyLeastLatency:
+		return true
+	}
+	return false
+}
+
+// Retry policy defaults, used when the corresponding field is unset.
+const (
+	DefaultRetryInitialDelayMs = 500
+	DefaultRetryMaxDelayMs     = 10000
+)
+
+// DefaultRetryOn lists the status codes retried when a retry policy sets no
+// retry_on list.
+var DefaultRetryOn = []int{429, 500, 502, 503, 504, 529}
+
+// RetryPolicy retries a failed request on the same provider, with
+// exponential backoff, before the proxy moves on to the next provider.
+type RetryPolicy struct {
+	MaxAttempts    int     `json:"max_attempts"`               // tries per provider, the first included; 0 or 1 = no retries
+	InitialDelayMs int     `json:"initial_delay_ms,omitempty"` // delay before the first retry, doubled for each one after (defaults to 500)
+	MaxDelayMs     int     `json:"max_delay_ms,omitempty"`     // cap on a single delay (defaults to 10000)
+	Jitter         float64 `json:"jitter,omitempty"`           // fraction of each delay that is randomized, 0-1
+	RetryOn        []int   `json:"retry_on,omitempty"`         // status codes retried (defaults to DefaultRetryOn); connection errors always are
+}
+
+// IsValid reports whether the settings are in range. Nil means no retries.
+func (rp *RetryPolicy) IsValid() bool {
+	if rp == nil {
+		return true
+	}
+	for _, code := range rp.RetryOn {
+		if code < 100 || code > 599 {
+			return false
+		}
+	}
+	return rp.MaxAttempts >= 0 && rp.InitialDelayMs >= 0 && rp.MaxDelayMs >= 0 &&
+		rp.Jitter >= 0 && rp.Jitter <= 1
+}
+
+// InitialDelay returns the delay before the first retry.
+func (rp *RetryPolicy) InitialDelay() time.Duration {
+	return time.Duration(orDefault(rp.InitialDelayMs, DefaultRetryInitialDelayMs)) * time.Millisecond
+}
+
+// MaxDelay returns the cap on a single delay.
+func (rp *RetryPolicy) MaxDelay() time.Duration {
+	return time.Duration(orDefault(rp.MaxDelayMs, DefaultRetryMaxDelayMs)) * time.Millisecond
+}
+
+// RetriesStatus reports whether a response with the given status is retried.
+func (rp *RetryPolicy) RetriesStatus(code int) bool {
+	retryOn := rp.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+	return slices.Contains(retryOn, code)
+}
+
+// HedgeConfig races a second provider against a slow first one: when the
+// first hasn't sent response headers after the delay, the request also goes
+// to the next provider and whichever answers first is used.
+type HedgeConfig struct {
+	DelayMs          int `json:"delay_ms,omitempty"`           // delay for non-streaming requests; 0 = don't hedge them
+	StreamingDelayMs int `json:"streaming_delay_ms,omitempty"` // delay for streaming requests; 0 = don't hedge them
+}
+
+// IsValid reports whether the delays are not negative. Nil means no hedging.
+func (hc *HedgeConfig) IsValid() bool {
+	return hc == nil || hc.DelayMs >= 0 && hc.StreamingDelayMs >= 0
+}
+
+// Delay returns how long to wait for the first provider before hedging a
+// streaming or non-streaming request, or 0 if such requests aren't hedged.
+func (hc *HedgeConfig) Delay(streaming bool) time.Duration {
+	if hc == nil {
+		return 0
+	}
+	if streaming {
+		return time.Duration(hc.StreamingDelayMs) * time.Millisecond
+	}
+	return time.Duration(hc.DelayMs) * time.Millisecond
+}
+
+// Auto ordering defaults, used when the corresponding field is unset.
+const (
+	DefaultAutoOrderIntervalHours = 24
+	DefaultAutoOrderWindowHours   = 72
+	DefaultAutoOrderMinRequests   = 20
+	DefaultAutoOrderSuccessGain   = 0.05
+	DefaultAutoOrderLatencyGain   = 0.25
+)
+
+// AutoOrderConfig opts a profile in to periodic re-ordering of its providers
+// by recent success rate and latency.
+type AutoOrderConfig struct {
+	Enabled        bool    `json:"enabled"`
+	IntervalHours  int     `json:"interval_hours,omitempty"`   // how often the order is re-evaluated (defaults to 24)
+	WindowHours    int     `json:"window_hours,omitempty"`     // how far back statistics reach (defaults to 72)
+	MinRequests    int     `json:"min_requests,omitempty"`     // attempts a provider needs in the window to move (defaults to 20)
+	MinSuccessGain float64 `json:"min_success_gain,omitempty"` // success rate lead needed to overtake (defaults to 0.05)
+	MinLatencyGain float64 `json:"min_latency_gain,omitempty"` // latency reduction needed to overtake at similar success rate (defaults to 0.25)
+	PinFirst       bool    `json:"pin_first,omitempty"`        // never move the first provider
+}
+
+// IsValid reports whether the settings are in range. Nil means disabled.
+func (ao *AutoOrderConfig) IsValid() bool {
+	if ao == nil {
+		return true
+	}
+	return ao.IntervalHours >= 0 && ao.WindowHours >= 0 && ao.MinRequests >= 0 &&
+		ao.MinSuccessGain >= 0 && ao.MinSuccessGain <= 1 &&
+		ao.MinLatencyGain >= 0 && ao.MinLatencyGain < 1
+}
+
+// Interval returns how often the order is re-evaluated.
+func (ao *AutoOrderConfig) Interval() time.Duration {
+	return time.Duration(orDefault(ao.IntervalHours, DefaultAutoOrderIntervalHours)) * time.Hour
+}
+
+// Window returns how far back statistics reach.
+func (ao *AutoOrderConfig) Window() time.Duration {
+	return time.Duration(orDefault(ao.WindowHours, DefaultAutoOrderWindowHours)) * time.Hour
+}
+
+// MinRequestsOrDefault returns the attempts a provider needs to be moved.
+func (ao *AutoOrderConfig) MinRequestsOrDefault() int {
+	return orDefault(ao.MinRequests, DefaultAutoOrderMinRequests)
+}
+
+// SuccessGain returns the success rate lead needed to overtake.
+func (ao *AutoOrderConfig) SuccessGain() float64 {
+	if ao.MinSuccessGain > 0 {
+		return ao.MinSuccessGain
+	}
+	return DefaultAutoOrderSuccessGain
+}
+
+// LatencyGain returns the fractional latency reduction needed to overtake.
+func (ao *AutoOrderConfig) LatencyGain() float64 {
+	if ao.MinLatencyGain > 0 {
+		return ao.MinLatencyGain
+	}
+	return DefaultAutoOrderLatencyGain
+}
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
 // ProfileConfig holds a profile's provider list and optional scenario routing.
 type ProfileConfig struct {
-	Providers            []string                    `json:"providers"`
-	Routing              map[Scenario]*ScenarioRoute `json:"routing,omitempty"`
-	LongContextThreshold int                         `json:"long_context_threshold,omitempty"` // defaults to 32000 if not set
+	Extends              string                       `json:"extends,omitempty"` // profile whose providers and settings this one builds on
+	Providers            []string                     `json:"providers"`
+	Routing              map[Scenario]*ScenarioRoute  `json:"routing,omitempty"`
+	LongContextThreshold int                          `json:"long_context_threshold,omitempty"` // defaults to 32000 if not set
+	Strategy             Strategy                     `json:"strategy,omitempty"`               // provider ordering; defaults to failover
+	Weights              map[string]int               `json:"weights,omitempty"`                // provider name -> share of requests under the weighted strategy; unset = 1
+	Retry                *RetryPolicy                 `json:"retry,omitempty"`                  // retries on each provider before failing over; nil = none
+	SessionAffinity      bool                         `json:"session_affinity,omitempty"`       // keep each session on the provider that last served it
+	EnvVars              map[string]map[string]string `json:"env_vars,omitempty"`               // CLI name -> env vars; override provider values
+	Launch               map[string]*LaunchTemplate   `json:"launch,omitempty"`                 // CLI name -> launch args/env
+	AutoOrder            *AutoOrderConfig             `json:"auto_order,omitempty"`             // periodic re-ordering by provider statistics
+	Hedge                *HedgeConfig                 `json:"hedge,omitempty"`                  // race the next provider against a slow one; nil = off
+	Schedule             []ScheduleRule               `json:"schedule,omitempty"`               // provider orders for recurring windows; the first open one applies
+	SystemPromptPrefix   string                       `json:"system_prompt_prefix,omitempty"`   // text put before the system prompt of every request, ahead of the provider's
+	SystemPromptFile     string                       `json:"system_prompt_file,omitempty"`     // file whose text follows system_prompt_prefix
+	Redaction            *bool                        `json:"redaction,omitempty"`              // turns the redaction filter on or off for the profile; nil = on if redaction is configured
+}
+
+// GetEnvVarsForCLI returns the profile's env var overrides for a specific CLI.
+func (pc *ProfileConfig) GetEnvVarsForCLI(cli string) map[string]string {
+	if pc == nil {
+		return nil
+	}
+	return pc.EnvVars[cli]
 }
 
 // UnmarshalJSON supports both old format (["p1","p2"]) and new format ({providers: [...], routing: {...}}).
@@ -227,6 +811,292 @@ func (pc *ProfileConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// FailoverPolicy controls whether a failed request may be retried on the next
+// provider. Requests to endpoints with side effects (batches, files) can be
+// duplicated if retried after the first provider already processed them.
+type FailoverPolicy string
+
+const (
+	// FailoverAlways retries on the next provider after any failure (default).
+	FailoverAlways FailoverPolicy = "always"
+	// FailoverSafe retries only when the failed attempt provably did not reach
+	// the provider's processing (connection refused, 401-403, 429).
+	FailoverSafe FailoverPolicy = "safe"
+	// FailoverNever returns the first provider's failure to the client as-is.
+	FailoverNever FailoverPolicy = "never"
+)
+
+// IsValid reports whether p is a known failover policy.
+func (p FailoverPolicy) IsValid() bool {
+	switch p {
+	case FailoverAlways, FailoverSafe, FailoverNever:
+		return true
+	}
+	return false
+}
+
+// BackoffQueueConfig controls holding requests while every provider is in
+// backoff, instead of failing them immediately.
+type BackoffQueueConfig struct {
+	MaxWaitSeconds int `json:"max_wait_seconds"`     // longest a request may wait; 0 disables the queue
+	MaxQueued      int `json:"max_queued,omitempty"` // max requests waiting at once (defaults to 64)
+}
+
+// HealthCheckConfig enables background probes that mark providers healthy or
+// unhealthy before a real request has to find out.
+type HealthCheckConfig struct {
+	IntervalSeconds int    `json:"interval_seconds"`          // time between probe rounds; 0 disables health checks
+	TimeoutSeconds  int    `json:"timeout_seconds,omitempty"` // per-probe timeout (defaults to 5)
+	Path            string `json:"path,omitempty"`            // probe path (defaults to /v1/models)
+}
+
+// Response cache defaults.
+const (
+	DefaultResponseCacheTTLSeconds = 300
+	DefaultResponseCacheMaxEntries = 256
+)
+
+// DefaultResponseCachePaths are the request paths cached when a
+// ResponseCacheConfig lists none.
+var DefaultResponseCachePaths = []string{"/v1/messages/count_tokens", "/v1/messages"}
+
+// ResponseCacheConfig makes the proxy answer repeated identical requests from
+// a cache instead of the providers. Only non-streaming POST requests to the
+// listed paths that succeed are cached.
+type ResponseCacheConfig struct {
+	TTLSeconds int      `json:"ttl_seconds,omitempty"` // how long a response is reused (defaults to 300)
+	MaxEntries int      `json:"max_entries,omitempty"` // least recently used responses are evicted beyond this (defaults to 256)
+	Paths      []string `json:"paths,omitempty"`       // request paths cached (defaults to DefaultResponseCachePaths)
+}
+
+// TTL returns how long a cached response is reused.
+func (c *ResponseCacheConfig) TTL() time.Duration {
+	if c.TTLSeconds > 0 {
+		return time.Duration(c.TTLSeconds) * time.Second
+	}
+	return DefaultResponseCacheTTLSeconds * time.Second
+}
+
+// Size returns the number of responses kept.
+func (c *ResponseCacheConfig) Size() int {
+	if c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return DefaultResponseCacheMaxEntries
+}
+
+// CachedPaths returns the request paths whose responses are cached.
+func (c *ResponseCacheConfig) CachedPaths() []string {
+	if len(c.Paths) > 0 {
+		return c.Paths
+	}
+	return DefaultResponseCachePaths
+}
+
+// DebugCaptureConfig makes the proxy write every provider attempt's request
+// and response, with credentials redacted, to a capture directory for bug
+// reports.
+type DebugCaptureConfig struct {
+	Dir          string `json:"dir,omitempty"`            // defaults to captures in the config dir
+	MaxBodyBytes int    `json:"max_body_bytes,omitempty"` // longer bodies are cut; 0 keeps whole bodies
+}
+
+// DefaultHookTimeoutSeconds bounds a hook command whose timeout is unset.
+const DefaultHookTimeoutSeconds = 10
+
+// HooksConfig runs external commands on the JSON bodies passing through the
+// proxy. Each command gets a body on stdin and prints the body to use in its
+// place, or nothing to keep it.
+type HooksConfig struct {
+	Request  []HookCommand `json:"request,omitempty"`  // run in order on request bodies before routing
+	Response []HookCommand `json:"response,omitempty"` // run in order on non-streaming response bodies before they reach the client
+}
+
+// HookCommand is an external command run as a hook.
+type HookCommand struct {
+	Command        string   `json:"command"`                   // program to run, looked up in PATH unless a path
+	Args           []string `json:"args,omitempty"`            // arguments passed to it
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // longest a run may take (defaults to 10)
+}
+
+// Timeout returns how long one run of the hook may take.
+func (h HookCommand) Timeout() time.Duration {
+	if h.TimeoutSeconds > 0 {
+		return time.Duration(h.TimeoutSeconds) * time.Second
+	}
+	return DefaultHookTimeoutSeconds * time.Second
+}
+
+// RedactionAction is what the proxy does with a request that contains
+// secrets or personal data.
+type RedactionAction string
+
+const (
+	RedactionMask  RedactionAction = "mask"  // replace each match with [REDACTED:<pattern>] (default)
+	RedactionBlock RedactionAction = "block" // refuse the request
+	RedactionWarn  RedactionAction = "warn"  // forward the request as it is, logging a warning
+)
+
+// IsValid reports whether a is a known action; empty means the default.
+func (a RedactionAction) IsValid() bool {
+	switch a {
+	case "", RedactionMask, RedactionBlock, RedactionWarn:
+		return true
+	}
+	return false
+}
+
+// RedactionBuiltins are the names of the built-in redaction patterns.
+var RedactionBuiltins = []string{"api_keys", "aws", "private_keys", "emails"}
+
+// RedactionConfig makes the proxy scan request bodies for secrets and
+// personal data before forwarding them to providers.
+type RedactionConfig struct {
+	Action   RedactionAction    `json:"action,omitempty"`    // "mask" (default), "block" or "warn"
+	Builtin  []string           `json:"builtin,omitempty"`   // built-in patterns used; empty = all of them
+	Patterns []RedactionPattern `json:"patterns,omitempty"`  // custom patterns, checked after the built-in ones
+	AuditLog string             `json:"audit_log,omitempty"` // defaults to redactions.log in the log dir
+}
+
+// RedactionPattern is a custom regular expression to redact.
+type RedactionPattern struct {
+	Name  string `json:"name"`  // shown in masks and the audit log
+	Regex string `json:"regex"` // Go regexp syntax
+}
+
+// RedactionFor returns the redaction settings that apply to a profile: the
+// global ones unless the profile turns redaction off, or the defaults if
+// the profile turns it on without global settings. nil means no redaction.
+func RedactionFor(global *RedactionConfig, pc *ProfileConfig) *RedactionConfig {
+	switch {
+	case pc == nil || pc.Redaction == nil:
+		return global
+	case !*pc.Redaction:
+		return nil
+	case global == nil:
+		return &RedactionConfig{}
+	}
+	return global
+}
+
+// ProxyTLSConfig makes the proxy serve HTTPS. Without a certificate and key
+// the proxy uses a self-signed certificate kept under ~/.opencc/tls.
+type ProxyTLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"` // PEM certificate (chain)
+	KeyFile  string `json:"key_file,omitempty"`  // PEM private key
+}
+
+// IsValid reports whether the certificate and key are given together or
+// not at all.
+func (c *ProxyTLSConfig) IsValid() bool {
+	return (c.CertFile == "") == (c.KeyFile == "")
+}
+
+// WebAccessConfig makes the web UI reachable from other machines. Off
+// loopback the web server requires a web UI password to be set.
+type WebAccessConfig struct {
+	Listen    string          `json:"listen,omitempty"`     // host to bind; empty = 127.0.0.1, "0.0.0.0" = every interface
+	TLS       *ProxyTLSConfig `json:"tls,omitempty"`        // serve HTTPS, self-signed without cert_file and key_file; nil = plain HTTP
+	AllowFrom []string        `json:"allow_from,omitempty"` // client IPs or CIDR ranges let in besides loopback; empty = any
+}
+
+// IsLoopbackHost reports whether a host or host:port only accepts local
+// connections. The empty host listens on every interface.
+func IsLoopbackHost(listen string) bool {
+	host := listen
+	if h, _, err := net.SplitHostPort(listen); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AccessLogFormat selects the access log's line format.
+type AccessLogFormat string
+
+const (
+	// AccessLogCLF writes Common Log Format lines, followed by the provider
+	// and the duration in milliseconds (default).
+	AccessLogCLF AccessLogFormat = "clf"
+	// AccessLogJSON writes one JSON object per line.
+	AccessLogJSON AccessLogFormat = "json"
+)
+
+// IsValid reports whether f is a known access log format. Empty means CLF.
+func (f AccessLogFormat) IsValid() bool {
+	switch f {
+	case "", AccessLogCLF, AccessLogJSON:
+		return true
+	}
+	return false
+}
+
+// AccessLogConfig enables the proxy access log.
+type AccessLogConfig struct {
+	Format AccessLogFormat `json:"format,omitempty"` // "clf" (default) or "json"
+	Path   string          `json:"path,omitempty"`   // defaults to access.log in the config dir
+}
+
+// UsageWarningConfig sets cumulative per-session token thresholds at which
+// the proxy warns about runaway context growth.
+type UsageWarningConfig struct {
+	InputTokens []int `json:"input_tokens"`     // cumulative input token thresholds, e.g. [200000, 500000]
+	Inject      bool  `json:"inject,omitempty"` // also flag the session's next response with a notice header
+}
+
+// Oversized actions decide what happens to requests over a size limit.
+type OversizedAction string
+
+const (
+	OversizedReject      OversizedAction = "reject"       // answer with a 413 error (default)
+	OversizedLongContext OversizedAction = "long_context" // route to the profile's longContext scenario
+)
+
+// RequestSizeConfig caps request body sizes for all profiles. Providers and
+// scenario routes can set their own, stricter limits with max_request_bytes.
+type RequestSizeConfig struct {
+	MaxBytes  int64           `json:"max_bytes,omitempty"` // largest request body accepted; 0 = unlimited
+	Oversized OversizedAction `json:"oversized,omitempty"` // what to do with requests over a global or scenario limit
+}
+
+// IsValid reports whether a is a known oversized action. Empty means reject.
+func (a OversizedAction) IsValid() bool {
+	switch a {
+	case "", OversizedReject, OversizedLongContext:
+		return true
+	}
+	return false
+}
+
+// StreamFailoverMode decides what happens when a provider's SSE stream ends
+// before its final event.
+type StreamFailoverMode string
+
+const (
+	// StreamFailoverOff passes the truncated stream to the client (default).
+	StreamFailoverOff StreamFailoverMode = "off"
+	// StreamFailoverRestart closes the interrupted content block and appends
+	// the next provider's full answer to the same stream.
+	StreamFailoverRestart StreamFailoverMode = "restart"
+	// StreamFailoverReplay sends the text already streamed to the next
+	// provider as an assistant prefill, so it continues where the first one
+	// stopped. Falls back to restart when the cut isn't inside a text block.
+	StreamFailoverReplay StreamFailoverMode = "replay"
+)
+
+// IsValid reports whether m is a known stream failover mode. Empty means off.
+func (m StreamFailoverMode) IsValid() bool {
+	switch m {
+	case "", StreamFailoverOff, StreamFailoverRestart, StreamFailoverReplay:
+		return true
+	}
+	return false
+}
+
 // Config version history:
 // - Version 1 (implicit, no version field): profiles as string arrays
 // - Version 2 (v1.3.2+): profiles as objects with routing support
@@ -237,19 +1107,81 @@ const CurrentConfigVersion = 5
 
 // ProjectBinding holds the configuration for a project directory.
 type ProjectBinding struct {
-	Profile string `json:"profile,omitempty"` // profile name (empty = use default)
-	CLI     string `json:"cli,omitempty"`     // CLI name (empty = use default)
+	Profile  string                     `json:"profile,omitempty"`  // profile name (empty = use default)
+	CLI      string                     `json:"cli,omitempty"`      // CLI name (empty = use default)
+	Provider string                     `json:"provider,omitempty"` // single provider replacing the profile's chain and routing
+	Model    string                     `json:"model,omitempty"`    // model sent for every request, whatever the CLI asks for
+	Launch   map[string]*LaunchTemplate `json:"launch,omitempty"`   // CLI name -> launch args/env for this directory
+	Exact    bool                       `json:"exact,omitempty"`    // applies to this directory only, not its subdirectories
+}
+
+// LaunchTemplate holds extra arguments and environment used when launching
+// a CLI. Templates can be set globally, per profile and per project binding.
+type LaunchTemplate struct {
+	Args []string          `json:"args,omitempty"` // passed before the user's own CLI args
+	Env  map[string]string `json:"env,omitempty"`  // set in the CLI's environment only
+}
+
+// MergeLaunchTemplates combines templates from least to most specific
+// (global, profile, binding); nil templates are skipped. Non-empty Args
+// replace the less specific ones, while Env entries are merged with later
+// values winning.
+func MergeLaunchTemplates(templates ...*LaunchTemplate) LaunchTemplate {
+	var merged LaunchTemplate
+	for _, t := range templates {
+		if t == nil {
+			continue
+		}
+		if len(t.Args) > 0 {
+			merged.Args = t.Args
+		}
+		for k, v := range t.Env {
+			if merged.Env == nil {
+				merged.Env = make(map[string]string)
+			}
+			merged.Env[k] = v
+		}
+	}
+	return merged
 }
 
 // OpenCCConfig is the top-level configuration structure stored in opencc.json.
 type OpenCCConfig struct {
-	Version         int                         `json:"version,omitempty"`          // config file version
-	DefaultProfile  string                      `json:"default_profile,omitempty"`  // default profile name (defaults to "default")
-	DefaultCLI      string                      `json:"default_cli,omitempty"`      // default CLI (claude, codex, opencode)
-	WebPort         int                         `json:"web_port,omitempty"`         // web UI port (defaults to 19841)
-	Providers       map[string]*ProviderConfig  `json:"providers"`                  // provider configurations
-	Profiles        map[string]*ProfileConfig   `json:"profiles"`                   // profile configurations
-	ProjectBindings map[string]*ProjectBinding  `json:"project_bindings,omitempty"` // directory path -> binding config
+	Version          int                        `json:"version,omitempty"`           // config file version
+	DefaultProfile   string                     `json:"default_profile,omitempty"`   // default profile name (defaults to "default")
+	DefaultCLI       string                     `json:"default_cli,omitempty"`       // default CLI (claude, codex, opencode)
+	WebPort          int                        `json:"web_port,omitempty"`          // web UI port (defaults to 19841)
+	WebPassword      string                     `json:"web_password,omitempty"`      // hash of the web UI password; empty = sign in with the session token only
+	WebAccess        *WebAccessConfig           `json:"web_access,omitempty"`        // remote access to the web UI; nil = loopback only
+	ProxyPort        int                        `json:"proxy_port,omitempty"`        // port of the proxy each session starts; 0 = random
+	ProxyListen      string                     `json:"proxy_listen,omitempty"`      // host or host:port the proxy listens on; empty = loopback
+	ProxyAuthToken   string                     `json:"proxy_auth_token,omitempty"`  // key clients must send in X-OpenCC-Key; required off loopback
+	ProxyTLS         *ProxyTLSConfig            `json:"proxy_tls,omitempty"`         // serve the proxy over HTTPS; nil = plain HTTP
+	Providers        map[string]*ProviderConfig `json:"providers"`                   // provider configurations
+	Profiles         map[string]*ProfileConfig  `json:"profiles"`                    // profile configurations
+	ProjectBindings  map[string]*ProjectBinding `json:"project_bindings,omitempty"`  // directory path -> binding config
+	FailoverPolicies map[string]FailoverPolicy  `json:"failover_policies,omitempty"` // request path prefix -> failover policy
+	BackoffQueue     *BackoffQueueConfig        `json:"backoff_queue,omitempty"`     // wait queue used when all providers are in backoff
+	PreflightCheck   bool                       `json:"preflight_check,omitempty"`   // probe the primary provider before launching the CLI
+	StrictEnv        bool                       `json:"strict_env,omitempty"`        // refuse to launch when shell env vars conflict
+	Launch           map[string]*LaunchTemplate `json:"launch,omitempty"`            // CLI name -> default launch args/env
+	AccessLog        *AccessLogConfig           `json:"access_log,omitempty"`        // per-request access log; nil disables it
+	UsageWarnings    *UsageWarningConfig        `json:"usage_warnings,omitempty"`    // session token thresholds; nil disables them
+	RequestSize      *RequestSizeConfig         `json:"request_size,omitempty"`      // request body size limits; nil = unlimited
+	Locale           string                     `json:"locale,omitempty"`            // UI language ("en", "zh-CN"); empty = from LANG
+	StreamFailover   StreamFailoverMode         `json:"stream_failover,omitempty"`   // resuming SSE streams cut off mid-response; empty = off
+	HealthCheck      *HealthCheckConfig         `json:"health_check,omitempty"`      // background provider probes; nil disables them
+	MetricsListen    string                     `json:"metrics_listen,omitempty"`    // address serving Prometheus metrics, e.g. "127.0.0.1:9464"; empty disables it
+	ResponseCache    *ResponseCacheConfig       `json:"response_cache,omitempty"`    // reuse responses to repeated requests; nil disables it
+	DebugCapture     *DebugCaptureConfig        `json:"debug_capture,omitempty"`     // write provider requests and responses for debugging; nil disables it
+	Hooks            *HooksConfig               `json:"hooks,omitempty"`             // external commands that may rewrite request and response bodies; nil = none
+	Redaction        *RedactionConfig           `json:"redaction,omitempty"`         // filter for secrets and personal data in requests; nil disables it unless a profile turns it on
+	Scenarios        []CustomScenario           `json:"scenarios,omitempty"`         // user-defined scenarios, detected after the built-in ones unless placed before one
+	SecretStore      string                     `json:"secret_store,omitempty"`      // where provider tokens are saved: "keychain", "encrypted" (with OPENCC_PASSPHRASE) or empty for plain text
+	BackupRetention  int                        `json:"backup_retention,omitempty"`  // config backups kept in ~/.opencc/backups (defaults to 20)
+	Include          []string                   `json:"include,omitempty"`           // globs of files with more providers and profiles, relative to ~/.opencc
+	ProjectFiles     string                     `json:"project_files,omitempty"`     // how .opencc.json files in projects combine with bindings; empty = binding_first
+	TrustedProjects  []string                   `json:"trusted_projects,omitempty"`  // directories whose .opencc.json may set env vars
 }
 
 // UnmarshalJSON supports both current format (project_bindings as map[string]*ProjectBinding)
@@ -264,26 +1196,18 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 	}
 
 	// Standard unmarshal failed — likely v3 project_bindings with string values.
-	// Parse with raw messages for project_bindings.
+	// Parse with raw messages for project_bindings; the embedded alias keeps
+	// every other top-level field (the outer project_bindings shadows its own).
 	var raw struct {
-		Version         int                            `json:"version,omitempty"`
-		DefaultProfile  string                         `json:"default_profile,omitempty"`
-		DefaultCLI      string                         `json:"default_cli,omitempty"`
-		WebPort         int                            `json:"web_port,omitempty"`
-		Providers       map[string]*ProviderConfig     `json:"providers"`
-		Profiles        map[string]*ProfileConfig      `json:"profiles"`
-		ProjectBindings map[string]json.RawMessage     `json:"project_bindings,omitempty"`
+		openCCConfigAlias
+		ProjectBindings map[string]json.RawMessage `json:"project_bindings,omitempty"`
 	}
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
 
-	c.Version = raw.Version
-	c.DefaultProfile = raw.DefaultProfile
-	c.DefaultCLI = raw.DefaultCLI
-	c.WebPort = raw.WebPort
-	c.Providers = raw.Providers
-	c.Profiles = raw.Profiles
+	*c = OpenCCConfig(raw.openCCConfigAlias)
+	c.ProjectBindings = nil
 
 	if len(raw.ProjectBindings) > 0 {
 		c.ProjectBindings = make(map[string]*ProjectBinding, len(raw.ProjectBindings))
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Environment variables that configure opencc without a config file, e.g. in
+// containers or CI. They take precedence over opencc.json and are never
+// written back to it.
+const (
+	// EnvProvidersJSON holds a JSON object of provider name -> provider
+	// config, in the same shape as "providers" in opencc.json.
+	EnvProvidersJSON = "OPENCC_PROVIDERS_JSON"
+	// EnvProfilesJSON holds a JSON object of profile name -> profile config.
+	EnvProfilesJSON = "OPENCC_PROFILES_JSON"
+	// EnvDefaultProfile overrides default_profile.
+	EnvDefaultProfile = "OPENCC_DEFAULT_PROFILE"
+
+	// envProviderPrefix starts per-field provider variables:
+	// OPENCC_PROVIDER_<NAME>_BASE_URL, _AUTH_TOKEN, _MODEL, ...
+	envProviderPrefix = "OPENCC_PROVIDER_"
+	// envProfilePrefix starts profile variables listing providers in order:
+	// OPENCC_PROFILE_<NAME>=primary,backup
+	envProfilePrefix = "OPENCC_PROFILE_"
+)
+
+// envProviderFields maps per-field variable suffixes to provider fields.
+// Longer suffixes come first so _REASONING_MODEL isn't read as _MODEL.
+var envProviderFields = []struct {
+	suffix string
+	set    func(p *ProviderConfig, v string)
+}{
+	{"_REASONING_MODEL", func(p *ProviderConfig, v string) { p.ReasoningModel = v }},
+	{"_HAIKU_MODEL", func(p *ProviderConfig, v string) { p.HaikuModel = v }},
+	{"_OPUS_MODEL", func(p *ProviderConfig, v string) { p.OpusModel = v }},
+	{"_SONNET_MODEL", func(p *ProviderConfig, v string) { p.SonnetModel = v }},
+	{"_AUTH_TOKEN", func(p *ProviderConfig, v string) { p.AuthToken = v }},
+	{"_BASE_URL", func(p *ProviderConfig, v string) { p.BaseURL = v }},
+	{"_MODEL", func(p *ProviderConfig, v string) { p.Model = v }},
+	{"_TYPE", func(p *ProviderConfig, v string) { p.Type = v }},
+}
+
+// envField is one per-field provider variable.
+type envField struct {
+	set   func(p *ProviderConfig, v string)
+	value string
+}
+
+// envOverlay is the configuration read from the environment.
+type envOverlay struct {
+	providers      map[string]*ProviderConfig // from EnvProvidersJSON
+	fields         map[string][]envField      // provider name -> per-field values
+	profiles       map[string]*ProfileConfig  // from EnvProfilesJSON and OPENCC_PROFILE_*
+	defaultProfile string
+}
+
+// envShadow remembers the file's entries replaced by the environment so
+// saving writes them back unchanged. A nil entry didn't exist in the file.
+type envShadow struct {
+	providers      map[string]*ProviderConfig
+	profiles       map[string]*ProfileConfig
+	defaultProfile *string
+}
+
+// envOverlayFrom parses the opencc variables in environ ("KEY=value" pairs,
+// as from os.Environ). Names in per-field variables are lowercased.
+func envOverlayFrom(environ []string) (*envOverlay, error) {
+	o := &envOverlay{
+		fields:   make(map[string][]envField),
+		profiles: make(map[string]*ProfileConfig),
+	}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case key == EnvProvidersJSON:
+			if err := json.Unmarshal([]byte(value), &o.providers); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", EnvProvidersJSON, err)
+			}
+		case key == EnvProfilesJSON:
+			var profiles map[string]*ProfileConfig
+			if err := json.Unmarshal([]byte(value), &profiles); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", EnvProfilesJSON, err)
+			}
+			for name, pc := range profiles {
+				if _, ok := o.profiles[name]; !ok {
+					o.profiles[name] = pc
+				}
+			}
+		case key == EnvDefaultProfile:
+			o.defaultProfile = value
+		case strings.HasPrefix(key, envProviderPrefix):
+			rest := strings.TrimPrefix(key, envProviderPrefix)
+			for _, f := range envProviderFields {
+				if name, ok := strings.CutSuffix(rest, f.suffix); ok && name != "" {
+					name = strings.ToLower(name)
+					o.fields[name] = append(o.fields[name], envField{set: f.set, value: value})
+					break
+				}
+			}
+		case strings.HasPrefix(key, envProfilePrefix):
+			name := strings.ToLower(strings.TrimPrefix(key, envProfilePrefix))
+			if name == "" {
+				continue
+			}
+			var providers []string
+			for _, p := range strings.Split(value, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					providers = append(providers, p)
+				}
+			}
+			// Per-profile variables win over EnvProfilesJSON
+			o.profiles[name] = &ProfileConfig{Providers: providers}
+		}
+	}
+	return o, nil
+}
+
+func (o *envOverlay) empty() bool {
+	return len(o.providers) == 0 && len(o.fields) == 0 && len(o.profiles) == 0 && o.defaultProfile == ""
+}
+
+// apply overrides cfg with the environment's entries and returns the file
+// entries it replaced. cfg must have initialized maps.
+func (o *envOverlay) apply(cfg *OpenCCConfig) *envShadow {
+	shadow := &envShadow{
+		providers: make(map[string]*ProviderConfig),
+		profiles:  make(map[string]*ProfileConfig),
+	}
+	for name, p := range o.providers {
+		if _, done := shadow.providers[name]; !done {
+			shadow.providers[name] = cfg.Providers[name]
+		}
+		cfg.Providers[name] = p
+	}
+
+	names := make([]string, 0, len(o.fields))
+	for name := range o.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, done := shadow.providers[name]; !done {
+			shadow.providers[name] = cfg.Providers[name]
+		}
+		// Copy so the file's (or JSON's) entry isn't modified in place
+		p := &ProviderConfig{}
+		if existing := cfg.Providers[name]; existing != nil {
+			*p = *existing
+		}
+		for _, f := range o.fields[name] {
+			f.set(p, f.value)
+		}
+		cfg.Providers[name] = p
+	}
+
+	for name, pc := range o.profiles {
+		shadow.profiles[name] = cfg.Profiles[name]
+		cfg.Profiles[name] = pc
+	}
+
+	if o.defaultProfile != "" {
+		previous := cfg.DefaultProfile
+		shadow.defaultProfile = &previous
+		cfg.DefaultProfile = o.defaultProfile
+	}
+	return shadow
+}
+
+// restore returns a copy of cfg with the environment's entries replaced by
+// the file's, for saving.
+func (sh *envShadow) restore(cfg *OpenCCConfig) *OpenCCConfig {
+	out := *cfg
+	out.Providers = make(map[string]*ProviderConfig, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		out.Providers[name] = p
+	}
+	for name, p := range sh.providers {
+		if p == nil {
+			delete(out.Providers, name)
+		} else {
+			out.Providers[name] = p
+		}
+	}
+	out.Profiles = make(map[string]*ProfileConfig, len(cfg.Profiles))
+	for name, pc := range cfg.Profiles {
+		out.Profiles[name] = pc
+	}
+	for name, pc := range sh.profiles {
+		if pc == nil {
+			delete(out.Profiles, name)
+		} else {
+			out.Profiles[name] = pc
+		}
+	}
+	if sh.defaultProfile != nil {
+		out.DefaultProfile = *sh.defaultProfile
+	}
+	return &out
+}
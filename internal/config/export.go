@@ -0,0 +1,379 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Export document and config file formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ExportDocument is a shareable set of providers and profiles, written by
+// `opencc config export` and read by `opencc config import`.
+type ExportDocument struct {
+	Version   int                        `json:"version"` // CurrentConfigVersion of the exporting opencc
+	Providers map[string]*ProviderConfig `json:"providers,omitempty"`
+	Profiles  map[string]*ProfileConfig  `json:"profiles,omitempty"`
+}
+
+// FormatForPath returns the format of a file by its extension: YAML for
+// .yaml and .yml, TOML for .toml, JSON otherwise.
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// fromJSON converts a JSON document to format.
+func fromJSON(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return jsonToYAML(data)
+	case FormatTOML:
+		return jsonToTOML(data)
+	}
+	return data, nil
+}
+
+// toJSON converts a document in format to JSON.
+func toJSON(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yamlToJSON(data)
+	case FormatTOML:
+		return tomlToJSON(data)
+	}
+	return data, nil
+}
+
+// Encode returns the document in the given format.
+func (d *ExportDocument) Encode(format string) ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return fromJSON(append(data, '\n'), format)
+}
+
+// DecodeExportDocument parses a document in the given format.
+func DecodeExportDocument(data []byte, format string) (*ExportDocument, error) {
+	data, err := toJSON(data, format)
+	if err != nil {
+		return nil, err
+	}
+	var doc ExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version > CurrentConfigVersion {
+		return nil, fmt.Errorf("document version %d is newer than supported version %d, please upgrade opencc to the latest version",
+			doc.Version, CurrentConfigVersion)
+	}
+	return &doc, nil
+}
+
+// Export returns the named providers and profiles, with the profiles they
+// extend and the providers all of those use; with no names it returns all of
+// them. Cooldowns are left
+// out, and so are tokens and cloud credentials unless secrets is set, in
+// which case tokens in the keychain or encrypted are resolved.
+func (s *Store) Export(providers, profiles []string, secrets bool) (*ExportDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+
+	selected := &ExportDocument{
+		Version:   CurrentConfigVersion,
+		Providers: make(map[string]*ProviderConfig),
+		Profiles:  make(map[string]*ProfileConfig),
+	}
+	if len(providers) == 0 && len(profiles) == 0 {
+		selected.Providers = s.config.Providers
+		selected.Profiles = s.config.Profiles
+	}
+	for _, name := range profiles {
+		pc := s.config.Profiles[name]
+		if pc == nil {
+			return nil, fmt.Errorf("profile '%s' not found", name)
+		}
+		for ; pc != nil && selected.Profiles[name] == nil; name, pc = pc.Extends, s.config.Profiles[pc.Extends] {
+			selected.Profiles[name] = pc
+			providers = append(providers, pc.providerRefs()...)
+		}
+	}
+	for _, name := range providers {
+		p := s.config.Providers[name]
+		if p == nil {
+			return nil, fmt.Errorf("provider '%s' not found", name)
+		}
+		selected.Providers[name] = p
+	}
+
+	// Work on a copy so the stored config keeps its secrets
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return nil, err
+	}
+	var doc ExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for name, p := range doc.Providers {
+		p.CooldownUntil = nil
+		if !secrets {
+			p.clearSecrets()
+		} else if p.AuthToken, err = resolveSecret(p.AuthToken); err != nil {
+			return nil, fmt.Errorf("provider '%s': %w", name, err)
+		}
+	}
+	return &doc, nil
+}
+
+// ImportResolver decides what happens to an imported provider or profile
+// (kind is "provider" or "profile") whose name is taken. It returns the name
+// to import it under: the same name to overwrite, another to rename it, or
+// "" to skip it.
+type ImportResolver func(kind, name string) (string, error)
+
+// ImportResult lists what an import added, by the names it was added under.
+type ImportResult struct {
+	Providers []string
+	Profiles  []string
+	Skipped   []string // "provider x" or "profile x"
+}
+
+// Import adds the providers and profiles of doc to the config and saves,
+// calling resolve for each one whose name is taken. Imported profiles use the
+// new names of renamed providers and profiles. An overwritten provider keeps its
+// credentials where the document has none, so a document exported without
+// secrets can update providers that are already set up.
+func (s *Store) Import(doc *ExportDocument, resolve ImportResolver) (*ImportResult, error) {
+	s.mu.Lock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	existingProviders := make(map[string]bool)
+	for name := range s.config.Providers {
+		existingProviders[name] = true
+	}
+	existingProfiles := make(map[string]bool)
+	for name := range s.config.Profiles {
+		existingProfiles[name] = true
+	}
+	s.mu.Unlock()
+
+	// Resolve conflicts first: the resolver may prompt the user
+	result := &ImportResult{}
+	providerNames, err := resolveImportNames("provider", doc.Providers, existingProviders, resolve, result)
+	if err != nil {
+		return nil, err
+	}
+	profileNames, err := resolveImportNames("profile", doc.Profiles, existingProfiles, resolve, result)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if _, err := s.backupLocked("import"); err != nil {
+		return nil, err
+	}
+	importedProviders := make(map[string]bool)
+	for _, target := range providerNames {
+		importedProviders[target] = true
+	}
+	importedProfiles := make(map[string]bool)
+	for _, target := range profileNames {
+		importedProfiles[target] = true
+	}
+	for _, name := range sortedKeys(doc.Profiles) {
+		target, ok := profileNames[name]
+		if !ok {
+			continue
+		}
+		pc := doc.Profiles[name]
+		pc.renameProviders(providerNames)
+		for _, ref := range pc.providerRefs() {
+			if !importedProviders[ref] && s.config.Providers[ref] == nil {
+				return nil, fmt.Errorf("profile '%s' uses provider '%s', which is neither imported nor configured", target, ref)
+			}
+		}
+		if renamed, ok := profileNames[pc.Extends]; ok {
+			pc.Extends = renamed
+		}
+		if pc.Extends != "" && !importedProfiles[pc.Extends] && s.config.Profiles[pc.Extends] == nil {
+			return nil, fmt.Errorf("profile '%s' extends profile '%s', which is neither imported nor configured", target, pc.Extends)
+		}
+	}
+
+	for _, name := range sortedKeys(doc.Providers) {
+		target, ok := providerNames[name]
+		if !ok {
+			continue
+		}
+		p := doc.Providers[name]
+		if existing := s.config.Providers[target]; existing != nil {
+			p.keepSecrets(existing)
+		}
+		stored, err := s.protectLocked(target, p)
+		if err != nil {
+			return nil, err
+		}
+		s.config.Providers[target] = stored
+		result.Providers = append(result.Providers, target)
+	}
+	for _, name := range sortedKeys(doc.Profiles) {
+		if target, ok := profileNames[name]; ok {
+			s.config.Profiles[target] = doc.Profiles[name]
+			result.Profiles = append(result.Profiles, target)
+		}
+	}
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveImportNames returns the name each imported item is added under,
+// leaving out the items the resolver skips, which it records in result.
+func resolveImportNames[T any](kind string, items map[string]T, taken map[string]bool, resolve ImportResolver, result *ImportResult) (map[string]string, error) {
+	names := make(map[string]string)
+	for _, name := range sortedKeys(items) {
+		target := name
+		for target != "" && taken[target] {
+			next, err := resolve(kind, target)
+			if err != nil {
+				return nil, err
+			}
+			if next == target {
+				break // overwrite
+			}
+			target = next
+		}
+		if target == "" {
+			result.Skipped = append(result.Skipped, kind+" "+name)
+			continue
+		}
+		names[name] = target
+		taken[target] = true
+	}
+	return names, nil
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// providerRefs returns the names of the providers the profile uses, in its
+// provider list, its scenario routes or its schedule.
+func (pc *ProfileConfig) providerRefs() []string {
+	refs := slices.Clone(pc.Providers)
+	for _, scenario := range sortedKeys(pc.Routing) {
+		for _, pr := range pc.Routing[scenario].Providers {
+			if !slices.Contains(refs, pr.Name) {
+				refs = append(refs, pr.Name)
+			}
+		}
+	}
+	for _, rule := range pc.Schedule {
+		for _, name := range rule.Providers {
+			if !slices.Contains(refs, name) {
+				refs = append(refs, name)
+			}
+		}
+	}
+	return refs
+}
+
+// renameProviders replaces the provider names the profile uses by their
+// values in names.
+func (pc *ProfileConfig) renameProviders(names map[string]string) {
+	rename := func(name string) string {
+		if renamed, ok := names[name]; ok {
+			return renamed
+		}
+		return name
+	}
+	for i, name := range pc.Providers {
+		pc.Providers[i] = rename(name)
+	}
+	for _, route := range pc.Routing {
+		for _, pr := range route.Providers {
+			pr.Name = rename(pr.Name)
+		}
+	}
+	for _, rule := range pc.Schedule {
+		for i, name := range rule.Providers {
+			rule.Providers[i] = rename(name)
+		}
+	}
+	if len(pc.Weights) > 0 {
+		weights := make(map[string]int, len(pc.Weights))
+		for name, w := range pc.Weights {
+			weights[rename(name)] = w
+		}
+		pc.Weights = weights
+	}
+}
+
+// clearSecrets removes the provider's token and cloud credentials. An env:
+// or cmd: token reference holds no secret and is kept.
+func (p *ProviderConfig) clearSecrets() {
+	if !IsTokenRef(p.AuthToken) {
+		p.AuthToken = ""
+	}
+	if p.Bedrock != nil {
+		p.Bedrock.AccessKeyID = ""
+		p.Bedrock.SecretAccessKey = ""
+		p.Bedrock.SessionToken = ""
+	}
+	if p.Vertex != nil {
+		p.Vertex.CredentialsFile = ""
+	}
+	if p.OAuth != nil {
+		if !IsTokenRef(p.OAuth.RefreshToken) {
+			p.OAuth.RefreshToken = ""
+		}
+		p.OAuth.ClientSecret = ""
+	}
+}
+
+// keepSecrets fills in the credentials p lacks from the provider it
+// replaces.
+func (p *ProviderConfig) keepSecrets(old *ProviderConfig) {
+	if p.AuthToken == "" {
+		p.AuthToken = old.AuthToken
+	}
+	if p.Bedrock != nil && old.Bedrock != nil && p.Bedrock.AccessKeyID == "" && p.Bedrock.SecretAccessKey == "" {
+		p.Bedrock.AccessKeyID = old.Bedrock.AccessKeyID
+		p.Bedrock.SecretAccessKey = old.Bedrock.SecretAccessKey
+		p.Bedrock.SessionToken = old.Bedrock.SessionToken
+	}
+	if p.Vertex != nil && old.Vertex != nil && p.Vertex.CredentialsFile == "" {
+		p.Vertex.CredentialsFile = old.Vertex.CredentialsFile
+	}
+	if p.OAuth != nil && old.OAuth != nil && p.OAuth.RefreshToken == "" {
+		p.OAuth.RefreshToken = old.OAuth.RefreshToken
+		if p.OAuth.ClientSecret == "" {
+			p.OAuth.ClientSecret = old.OAuth.ClientSecret
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CheckExtends returns why profile can't extend parent: parent doesn't
+// exist, or builds on profile itself. An empty parent is always fine.
+func (s *Store) CheckExtends(profile, parent string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if parent == "" {
+		return nil
+	}
+	if s.config == nil || s.config.Profiles[parent] == nil {
+		return fmt.Errorf("profile '%s' does not exist", parent)
+	}
+	seen := make(map[string]bool)
+	for name := parent; name != "" && !seen[name] && s.config.Profiles[name] != nil; name = s.config.Profiles[name].Extends {
+		if name == profile {
+			return fmt.Errorf("profile '%s' can't extend '%s', which builds on it", profile, parent)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// resolveProfile returns the profile with what it inherits through extends
+// merged in, or the stored profile itself if it extends none. A missing
+// parent or a cycle ends the chain; validation reports both.
+func resolveProfile(profiles map[string]*ProfileConfig, name string) *ProfileConfig {
+	pc := profiles[name]
+	if pc == nil || pc.Extends == "" {
+		return pc
+	}
+	chain := []*ProfileConfig{pc}
+	seen := map[string]bool{name: true}
+	for parent := pc.Extends; parent != "" && !seen[parent] && profiles[parent] != nil; parent = profiles[parent].Extends {
+		seen[parent] = true
+		chain = append(chain, profiles[parent])
+	}
+	merged := chain[len(chain)-1]
+	for i := len(chain) - 2; i >= 0; i-- {
+		merged = inheritProfile(merged, chain[i])
+	}
+	return merged
+}
+
+// inheritProfile returns parent with child's settings applied on top:
+// child's providers are appended to parent's chain, its scenario routes
+// replace parent's for the same scenarios, its map entries replace parent's
+// for the same keys and its other settings replace parent's where set.
+// auto_order isn't inherited, as it rewrites the order of the profile's own
+// providers.
+func inheritProfile(parent, child *ProfileConfig) *ProfileConfig {
+	out := *child
+	out.Providers = append([]string{}, parent.Providers...)
+	for _, name := range child.Providers {
+		if !slices.Contains(out.Providers, name) {
+			out.Providers = append(out.Providers, name)
+		}
+	}
+	out.Routing = mergeMaps(parent.Routing, child.Routing)
+	out.Weights = mergeMaps(parent.Weights, child.Weights)
+	out.EnvVars = mergeMaps(parent.EnvVars, child.EnvVars)
+	for cli, vars := range parent.EnvVars {
+		out.EnvVars[cli] = mergeMaps(vars, child.EnvVars[cli])
+	}
+	out.Launch = mergeMaps(parent.Launch, child.Launch)
+	for cli, t := range parent.Launch {
+		merged := MergeLaunchTemplates(t, child.Launch[cli])
+		out.Launch[cli] = &merged
+	}
+	if out.LongContextThreshold == 0 {
+		out.LongContextThreshold = parent.LongContextThreshold
+	}
+	if out.Strategy == "" {
+		out.Strategy = parent.Strategy
+	}
+	if out.Retry == nil {
+		out.Retry = parent.Retry
+	}
+	if out.Hedge == nil {
+		out.Hedge = parent.Hedge
+	}
+	if out.Schedule == nil {
+		out.Schedule = parent.Schedule
+	}
+	if out.SystemPromptPrefix == "" {
+		out.SystemPromptPrefix = parent.SystemPromptPrefix
+	}
+	if out.SystemPromptFile == "" {
+		out.SystemPromptFile = parent.SystemPromptFile
+	}
+	if out.Redaction == nil {
+		out.Redaction = parent.Redaction
+	}
+	out.SessionAffinity = parent.SessionAffinity || child.SessionAffinity
+	return &out
+}
+
+// mergeMaps returns the entries of a with those of b added, b winning on
+// shared keys, or nil if both are empty.
+func mergeMaps[K comparable, V any](a, b map[K]V) map[K]V {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	out := make(map[K]V, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		out[k] = v
+	}
+	return out
+}
+
+// extendsCycle reports whether following extends from the named profile
+// leads back to a profile already visited.
+func extendsCycle(profiles map[string]*ProfileConfig, name string) bool {
+	seen := make(map[string]bool)
+	for name != "" && profiles[name] != nil {
+		if seen[name] {
+			return true
+		}
+		seen[name] = true
+		name = profiles[name].Extends
+	}
+	return false
+}
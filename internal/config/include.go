@@ -0,0 +1,206 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// configExts are the extensions of files in the config formats.
+var configExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// includeSet records which providers and profiles came from the files the
+// config includes, so saving writes them back there instead of into the
+// main config file.
+type includeSet struct {
+	files     []string          // included files, in the order they were merged
+	providers map[string]string // provider name -> file it came from
+	profiles  map[string]string // profile name -> file it came from
+	loaded    map[string][]byte // "provider:name" or "profile:name" -> JSON as last read or written
+}
+
+// loadIncludes merges the providers and profiles of the files cfg.Include
+// names into cfg. Patterns are globs, relative to dir unless absolute or
+// starting with ~/; files are merged in order, later ones replacing the
+// entries of earlier ones, and entries of the main file always win. Only
+// files with a config extension are read, in the format it names, and
+// patterns matching nothing are skipped, so a directory of fragments may be
+// empty. cfg must have initialized maps.
+func loadIncludes(cfg *OpenCCConfig, dir string) (*includeSet, error) {
+	if len(cfg.Include) == 0 {
+		return nil, nil
+	}
+	inc := &includeSet{
+		providers: make(map[string]string),
+		profiles:  make(map[string]string),
+		loaded:    make(map[string][]byte),
+	}
+	for _, pattern := range cfg.Include {
+		if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+			pattern = filepath.Join(os.Getenv("HOME"), rest)
+		} else if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include '%s': %w", pattern, err)
+		}
+		for _, file := range matches {
+			if !slices.Contains(configExts, strings.ToLower(filepath.Ext(file))) || slices.Contains(inc.files, file) {
+				continue
+			}
+			doc, err := readIncludedFile(file)
+			if err != nil {
+				return nil, err
+			}
+			inc.files = append(inc.files, file)
+			for name, p := range doc.Providers {
+				if _, inMain := cfg.Providers[name]; inMain && inc.providers[name] == "" {
+					continue
+				}
+				cfg.Providers[name] = p
+				inc.providers[name] = file
+			}
+			for name, pc := range doc.Profiles {
+				if _, inMain := cfg.Profiles[name]; inMain && inc.profiles[name] == "" {
+					continue
+				}
+				cfg.Profiles[name] = pc
+				inc.profiles[name] = file
+			}
+		}
+	}
+	for name := range inc.providers {
+		inc.loaded["provider:"+name], _ = json.Marshal(cfg.Providers[name])
+	}
+	for name := range inc.profiles {
+		inc.loaded["profile:"+name], _ = json.Marshal(cfg.Profiles[name])
+	}
+	return inc, nil
+}
+
+// readIncludedFile reads an included file, which has the shape of an
+// exported document.
+func readIncludedFile(file string) (*ExportDocument, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file %s: %w", file, err)
+	}
+	doc, err := DecodeExportDocument(data, FormatForPath(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse included file %s: %w", file, err)
+	}
+	return doc, nil
+}
+
+// writeBack writes the included entries of cfg that changed since they were
+// read back to their files, dropping deleted ones, and returns a copy of cfg
+// without included entries for saving to the main file.
+func (inc *includeSet) writeBack(cfg *OpenCCConfig) (*OpenCCConfig, error) {
+	changed := make(map[string]bool)
+	for name, file := range inc.providers {
+		if data, _ := json.Marshal(cfg.Providers[name]); string(data) != string(inc.loaded["provider:"+name]) {
+			changed[file] = true
+		}
+	}
+	for name, file := range inc.profiles {
+		if data, _ := json.Marshal(cfg.Profiles[name]); string(data) != string(inc.loaded["profile:"+name]) {
+			changed[file] = true
+		}
+	}
+	for _, file := range inc.files {
+		if changed[file] {
+			if err := inc.writeFile(file, cfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out := *cfg
+	out.Providers = make(map[string]*ProviderConfig, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		if inc.providers[name] == "" {
+			out.Providers[name] = p
+		}
+	}
+	out.Profiles = make(map[string]*ProfileConfig, len(cfg.Profiles))
+	for name, pc := range cfg.Profiles {
+		if inc.profiles[name] == "" {
+			out.Profiles[name] = pc
+		}
+	}
+	return &out, nil
+}
+
+// writeFile updates the entries file contributes to cfg in the file, leaving
+// the rest of it as it is, and forgets the deleted ones.
+func (inc *includeSet) writeFile(file string, cfg *OpenCCConfig) error {
+	doc, err := readIncludedFile(file)
+	if err != nil {
+		return err
+	}
+	if doc.Providers == nil {
+		doc.Providers = make(map[string]*ProviderConfig)
+	}
+	if doc.Profiles == nil {
+		doc.Profiles = make(map[string]*ProfileConfig)
+	}
+	for name, owner := range inc.providers {
+		if owner != file {
+			continue
+		}
+		if p := cfg.Providers[name]; p != nil {
+			doc.Providers[name] = p
+			inc.loaded["provider:"+name], _ = json.Marshal(p)
+		} else {
+			delete(doc.Providers, name)
+			delete(inc.providers, name)
+			delete(inc.loaded, "provider:"+name)
+		}
+	}
+	for name, owner := range inc.profiles {
+		if owner != file {
+			continue
+		}
+		if pc := cfg.Profiles[name]; pc != nil {
+			doc.Profiles[name] = pc
+			inc.loaded["profile:"+name], _ = json.Marshal(pc)
+		} else {
+			delete(doc.Profiles, name)
+			delete(inc.profiles, name)
+			delete(inc.loaded, "profile:"+name)
+		}
+	}
+
+	doc.Version = CurrentConfigVersion
+	data, err := doc.Encode(FormatForPath(file))
+	if err != nil {
+		return fmt.Errorf("failed to marshal included file %s: %w", file, err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write included file %s: %w", file, err)
+	}
+	return nil
+}
+
+// latestModTime returns the latest modification time of the included files.
+func (inc *includeSet) latestModTime() time.Time {
+	var latest time.Time
+	if inc == nil {
+		return latest
+	}
+	for _, file := range inc.files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
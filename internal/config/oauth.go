@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// OAuthConfig lets a provider authorize with short-lived access tokens it
+// exchanges a refresh token for at the gateway's token endpoint, in place of
+// a static auth_token.
+type OAuthConfig struct {
+	TokenURL     string `json:"token_url"`
+	RefreshToken string `json:"refresh_token"` // may be an env: or cmd: reference; rotated tokens are saved back unless it is one
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Scope        string `json:"scope,omitempty"` // space-separated; empty = the refresh token's scope
+}
+
+// Validate checks that the token endpoint is an absolute HTTP(S) URL and
+// that there is a refresh token. o may be nil.
+func (o *OAuthConfig) Validate() error {
+	if o == nil {
+		return nil
+	}
+	u, err := url.Parse(o.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("oauth: invalid token_url %q", o.TokenURL)
+	}
+	if o.RefreshToken == "" {
+		return errors.New("oauth: refresh_token is required")
+	}
+	return nil
+}
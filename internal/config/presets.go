@@ -0,0 +1,88 @@
+package config
+
+// ProviderPreset is a well-known endpoint a new provider can start from: its
+// API type, base URL and recommended model mappings, leaving only the token
+// to fill in.
+type ProviderPreset struct {
+	Name           string // used with --preset
+	Label          string
+	Type           string
+	BaseURL        string
+	Model          string
+	ReasoningModel string
+	HaikuModel     string
+	OpusModel      string
+	SonnetModel    string
+}
+
+// ProviderPresets is the catalog of built-in presets, in the order they're
+// offered.
+var ProviderPresets = []ProviderPreset{
+	{
+		Name: "anthropic", Label: "Anthropic (official)",
+		Type: ProviderTypeAnthropic, BaseURL: "https://api.anthropic.com",
+		Model: "claude-sonnet-4-5", ReasoningModel: "claude-sonnet-4-5",
+		HaikuModel: "claude-haiku-4-5", OpusModel: "claude-opus-4-5", SonnetModel: "claude-sonnet-4-5",
+	},
+	{
+		Name: "openrouter", Label: "OpenRouter",
+		Type: ProviderTypeOpenAI, BaseURL: "https://openrouter.ai/api/v1",
+		Model: "anthropic/claude-sonnet-4.5", ReasoningModel: "anthropic/claude-sonnet-4.5",
+		HaikuModel: "anthropic/claude-haiku-4.5", OpusModel: "anthropic/claude-opus-4.5", SonnetModel: "anthropic/claude-sonnet-4.5",
+	},
+	{
+		Name: "deepseek", Label: "DeepSeek",
+		Type: ProviderTypeAnthropic, BaseURL: "https://api.deepseek.com/anthropic",
+		Model: "deepseek-chat", ReasoningModel: "deepseek-reasoner",
+		HaikuModel: "deepseek-chat", OpusModel: "deepseek-reasoner", SonnetModel: "deepseek-chat",
+	},
+	{
+		Name: "moonshot", Label: "Moonshot Kimi",
+		Type: ProviderTypeAnthropic, BaseURL: "https://api.moonshot.ai/anthropic",
+		Model: "kimi-k2-turbo-preview", ReasoningModel: "kimi-k2-thinking",
+		HaikuModel: "kimi-k2-turbo-preview", OpusModel: "kimi-k2-thinking", SonnetModel: "kimi-k2-turbo-preview",
+	},
+	{
+		Name: "moonshot-cn", Label: "Moonshot Kimi (China)",
+		Type: ProviderTypeAnthropic, BaseURL: "https://api.moonshot.cn/anthropic",
+		Model: "kimi-k2-turbo-preview", ReasoningModel: "kimi-k2-thinking",
+		HaikuModel: "kimi-k2-turbo-preview", OpusModel: "kimi-k2-thinking", SonnetModel: "kimi-k2-turbo-preview",
+	},
+	{
+		Name: "glm", Label: "Z.ai GLM",
+		Type: ProviderTypeAnthropic, BaseURL: "https://api.z.ai/api/anthropic",
+		Model: "glm-4.6", ReasoningModel: "glm-4.6",
+		HaikuModel: "glm-4.5-air", OpusModel: "glm-4.6", SonnetModel: "glm-4.6",
+	},
+	{
+		Name: "glm-cn", Label: "Zhipu GLM (China)",
+		Type: ProviderTypeAnthropic, BaseURL: "https://open.bigmodel.cn/api/anthropic",
+		Model: "glm-4.6", ReasoningModel: "glm-4.6",
+		HaikuModel: "glm-4.5-air", OpusModel: "glm-4.6", SonnetModel: "glm-4.6",
+	},
+	{
+		Name: "ollama", Label: "Ollama (local)",
+		Type: ProviderTypeLocal, BaseURL: DefaultLocalBaseURL,
+		Model: "qwen3-coder", ReasoningModel: "qwen3-coder",
+		HaikuModel: "qwen3-coder", OpusModel: "qwen3-coder", SonnetModel: "qwen3-coder",
+	},
+}
+
+// GetProviderPreset returns the built-in preset with the given name, or nil.
+func GetProviderPreset(name string) *ProviderPreset {
+	for i := range ProviderPresets {
+		if ProviderPresets[i].Name == name {
+			return &ProviderPresets[i]
+		}
+	}
+	return nil
+}
+
+// ProviderPresetNames returns the names of the built-in presets.
+func ProviderPresetNames() []string {
+	names := make([]string, len(ProviderPresets))
+	for i, p := range ProviderPresets {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectFile holds a project's own opencc settings, checked into its
+// repository. opencc looks for it in the working directory and its parents.
+const ProjectFile = ".opencc.json"
+
+// How project files combine with the project bindings in the config.
+const (
+	ProjectFilesBindingFirst = "binding_first" // the binding wins on conflicts (the default)
+	ProjectFilesFileFirst    = "file_first"    // the project file wins on conflicts
+	ProjectFilesIgnore       = "ignore"        // project files aren't read
+)
+
+// IsValidProjectFiles reports whether mode is a project_files setting; empty
+// means the default.
+func IsValidProjectFiles(mode string) bool {
+	switch mode {
+	case "", ProjectFilesBindingFirst, ProjectFilesFileFirst, ProjectFilesIgnore:
+		return true
+	}
+	return false
+}
+
+// ProjectConfig is the content of a project file.
+type ProjectConfig struct {
+	Profile string                      `json:"profile,omitempty"` // profile to use (empty = binding's or default)
+	CLI     string                      `json:"cli,omitempty"`     // CLI to launch (empty = binding's or default)
+	Env     map[string]string           `json:"env,omitempty"`     // set in the environment of every CLI once the project is trusted
+	Routing map[Scenario]*ScenarioRoute `json:"routing,omitempty"` // scenario routes replacing the profile's
+}
+
+// FindProjectConfig returns the project file nearest to dir, looking in dir
+// and then its parents, and its path. It returns nil if there is none.
+func FindProjectConfig(dir string) (*ProjectConfig, string, error) {
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var pc ProjectConfig
+			if err := json.Unmarshal(data, &pc); err != nil {
+				return nil, path, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			return &pc, path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, "", nil
+		}
+		dir = parent
+	}
+}
+
+// WithRouting returns a copy of profile with the project's scenario routes
+// replacing its own, or profile itself if the project has none.
+func (pc *ProjectConfig) WithRouting(profile *ProfileConfig) *ProfileConfig {
+	if pc == nil || len(pc.Routing) == 0 {
+		return profile
+	}
+	out := &ProfileConfig{}
+	if profile != nil {
+		*out = *profile
+	}
+	out.Routing = make(map[Scenario]*ScenarioRoute, len(pc.Routing))
+	if profile != nil {
+		for scenario, route := range profile.Routing {
+			out.Routing[scenario] = route
+		}
+	}
+	for scenario, route := range pc.Routing {
+		out.Routing[scenario] = route
+	}
+	return out
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Secret stores: where provider tokens saved to the config are kept.
+const (
+	SecretStorePlain     = ""          // in the config file as they are
+	SecretStoreKeychain  = "keychain"  // in the OS keychain, referenced as "keychain:<provider>"
+	SecretStoreEncrypted = "encrypted" // in the config file, encrypted with OPENCC_PASSPHRASE
+)
+
+// PassphraseEnv names the environment variable holding the passphrase
+// encrypted tokens are protected with.
+const PassphraseEnv = "OPENCC_PASSPHRASE"
+
+const (
+	keychainPrefix  = "keychain:"
+	encryptedPrefix = "enc:v1:"
+	keychainService = "opencc"
+
+	pbkdf2Iterations = 600000
+	saltSize         = 16
+)
+
+// IsValidSecretStore reports whether store names a secret store.
+func IsValidSecretStore(store string) bool {
+	switch store {
+	case SecretStorePlain, SecretStoreKeychain, SecretStoreEncrypted:
+		return true
+	}
+	return false
+}
+
+// isSecretRef reports whether a stored token refers to a secret kept in the
+// keychain or is encrypted, rather than being the token itself.
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(v, keychainPrefix) || strings.HasPrefix(v, encryptedPrefix)
+}
+
+// resolvedSecrets caches the tokens secret references resolve to, since
+// keychain lookups and key derivation are slow.
+var resolvedSecrets sync.Map
+
+// resolveSecret returns the token a stored value stands for: the value
+// itself, or the secret it refers to.
+func resolveSecret(v string) (string, error) {
+	if !isSecretRef(v) {
+		return v, nil
+	}
+	if cached, ok := resolvedSecrets.Load(v); ok {
+		return cached.(string), nil
+	}
+	var secret string
+	var err error
+	if account, ok := strings.CutPrefix(v, keychainPrefix); ok {
+		secret, err = keyring.Get(keychainService, account)
+		if err != nil {
+			return "", fmt.Errorf("reading %q from the keychain: %w", account, err)
+		}
+	} else if secret, err = decryptSecret(strings.TrimPrefix(v, encryptedPrefix)); err != nil {
+		return "", err
+	}
+	resolvedSecrets.Store(v, secret)
+	return secret, nil
+}
+
+// protectSecret returns the value to store for the token of the named
+// provider: a reference to it in the keychain, the token encrypted, or the
+// token itself for the plain store.
+func protectSecret(store, name, token string) (string, error) {
+	if token == "" || isSecretRef(token) || IsTokenRef(token) {
+		return token, nil
+	}
+	switch store {
+	case SecretStoreKeychain:
+		if err := keyring.Set(keychainService, name, token); err != nil {
+			return "", fmt.Errorf("saving the token of %q to the keychain: %w", name, err)
+		}
+		ref := keychainPrefix + name
+		resolvedSecrets.Store(ref, token)
+		return ref, nil
+	case SecretStoreEncrypted:
+		sealed, err := encryptSecret(token)
+		if err != nil {
+			return "", err
+		}
+		ref := encryptedPrefix + sealed
+		resolvedSecrets.Store(ref, token)
+		return ref, nil
+	}
+	return token, nil
+}
+
+// forgetSecret removes the keychain entry a stored value refers to, if any.
+func forgetSecret(v string) {
+	if account, ok := strings.CutPrefix(v, keychainPrefix); ok {
+		keyring.Delete(keychainService, account)
+		resolvedSecrets.Delete(v)
+	}
+}
+
+// secretKey derives the encryption key from the passphrase and salt.
+func secretKey(salt []byte) ([]byte, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("tokens are encrypted; set %s to the passphrase", PassphraseEnv)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+}
+
+// encryptSecret seals plaintext with AES-256-GCM under a key derived from
+// the passphrase, returning the salt, nonce and ciphertext in base64.
+func encryptSecret(plaintext string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := secretKey(salt)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := append(salt, nonce...)
+	sealed = gcm.Seal(sealed, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret opens a value sealed by encryptSecret.
+func decryptSecret(encoded string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < saltSize {
+		return "", errors.New("malformed encrypted token")
+	}
+	key, err := secretKey(sealed[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted token")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting token: wrong %s?", PassphraseEnv)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// passwordHashPrefix marks a password hash: PBKDF2-SHA256 of the password,
+// followed by the salt and the key in base64.
+const passwordHashPrefix = "pbkdf2-sha256:v1:"
+
+// hashPassword returns a salted hash of password for storing in the config.
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return passwordHashPrefix + base64.RawStdEncoding.EncodeToString(salt) + ":" + base64.RawStdEncoding.EncodeToString(key), nil
+}
+
+// verifyPassword reports whether password matches a hash from hashPassword.
+func verifyPassword(hash, password string) bool {
+	encSalt, encKey, ok := strings.Cut(strings.TrimPrefix(hash, passwordHashPrefix), ":")
+	if !ok || !strings.HasPrefix(hash, passwordHashPrefix) {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(encSalt)
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(encKey)
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(key, want) == 1
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -17,9 +18,21 @@ func ConfigDirPath() string {
 	return filepath.Join(os.Getenv("HOME"), ConfigDir)
 }
 
-// ConfigFilePath returns ~/.opencc/opencc.json
+// configFileNames are the names the config file may have, in the order
+// they are looked for.
+var configFileNames = []string{ConfigFile, "opencc.yaml", "opencc.yml", "opencc.toml"}
+
+// ConfigFilePath returns ~/.opencc/opencc.json, or opencc.yaml, opencc.yml
+// or opencc.toml if one of those exists instead. The store reads and writes
+// the file in the format of its extension.
 func ConfigFilePath() string {
-	return filepath.Join(ConfigDirPath(), ConfigFile)
+	dir := ConfigDirPath()
+	for _, name := range configFileNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
+	}
+	return filepath.Join(dir, ConfigFile)
 }
 
 // LogPath returns ~/.opencc/proxy.log
@@ -34,12 +47,14 @@ func legacyDirPath() string {
 
 // --- Store ---
 
-// Store manages reading and writing the unified JSON config.
+// Store manages reading and writing the unified config.
 type Store struct {
-	mu       sync.Mutex
-	path     string
-	config   *OpenCCConfig
-	modTime  time.Time // last known modification time of config file
+	mu        sync.Mutex
+	path      string
+	config    *OpenCCConfig
+	modTime   time.Time   // last known modification time of config file and included files
+	envShadow *envShadow  // file entries overridden by environment variables; nil if none
+	included  *includeSet // entries read from included files; nil if none
 }
 
 var (
@@ -60,8 +75,8 @@ func DefaultStore() *Store {
 		defaultStore.Load()
 	} else {
 		// Check if config file has been modified since last load
-		if info, err := os.Stat(defaultStore.path); err == nil {
-			if info.ModTime().After(defaultStore.modTime) {
+		if modTime, ok := defaultStore.latestModTime(); ok {
+			if modTime.After(defaultStore.modTime) {
 				// File has been modified, reload
 				defaultStore.Load()
 			}
@@ -80,27 +95,82 @@ func ResetDefaultStore() {
 
 // --- Provider operations ---
 
-// GetProvider returns the config for a named provider, or nil.
+// GetProvider returns the config for a named provider, or nil. A token kept
+// in the keychain or encrypted is resolved in the returned copy, or left
+// empty if it can't be; ResolveProvider reports why.
 func (s *Store) GetProvider(name string) *ProviderConfig {
+	p, _ := s.ResolveProvider(name)
+	return p
+}
+
+// ResolveProvider returns the config for a named provider, or nil, with its
+// token resolved like GetProvider does, and the error resolving it.
+func (s *Store) ResolveProvider(name string) (*ProviderConfig, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.reloadIfModified()
-	if s.config == nil {
-		return nil
+	var p *ProviderConfig
+	if s.config != nil {
+		p = s.config.Providers[name]
+	}
+	s.mu.Unlock()
+	if p == nil || !isSecretRef(p.AuthToken) {
+		return p, nil
+	}
+
+	resolved := *p
+	token, err := resolveSecret(p.AuthToken)
+	resolved.AuthToken = token
+	if err != nil {
+		return &resolved, fmt.Errorf("provider '%s': %w", name, err)
 	}
-	return s.config.Providers[name]
+	return &resolved, nil
 }
 
-// SetProvider creates or updates a provider and saves.
+// SetProvider creates or updates a provider and saves. Its token is saved to
+// the configured secret store.
 func (s *Store) SetProvider(name string, p *ProviderConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 	s.ensureConfig()
-	s.config.Providers[name] = p
+	stored, err := s.protectLocked(name, p)
+	if err != nil {
+		return err
+	}
+	s.config.Providers[name] = stored
 	return s.saveLocked()
 }
 
+// protectLocked returns p as it should be stored under name: with its token
+// moved to the configured secret store, unless it's there already. A keychain
+// entry the provider no longer uses is removed. Must be called with s.mu held.
+func (s *Store) protectLocked(name string, p *ProviderConfig) (*ProviderConfig, error) {
+	stored := p
+	old := s.config.Providers[name]
+	if p.AuthToken != "" && !isSecretRef(p.AuthToken) && !IsTokenRef(p.AuthToken) && s.config.SecretStore != SecretStorePlain {
+		copied := *p
+		stored = &copied
+		// Keep the old reference if the token is the one it resolves to
+		var cached any
+		if old != nil {
+			cached, _ = resolvedSecrets.Load(old.AuthToken)
+		}
+		if cached == p.AuthToken {
+			stored.AuthToken = old.AuthToken
+		} else {
+			ref, err := protectSecret(s.config.SecretStore, name, p.AuthToken)
+			if err != nil {
+				return nil, err
+			}
+			stored.AuthToken = ref
+		}
+	}
+	if old != nil && old.AuthToken != stored.AuthToken {
+		forgetSecret(old.AuthToken)
+	}
+	return stored, nil
+}
+
 // DeleteProvider removes a provider and removes it from all profiles
 // (including routing scenarios), then saves.
 func (s *Store) DeleteProvider(name string) error {
@@ -108,6 +178,12 @@ func (s *Store) DeleteProvider(name string) error {
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 	s.ensureConfig()
+	if p := s.config.Providers[name]; p != nil {
+		if _, err := s.backupLocked("delete provider " + name); err != nil {
+			return err
+		}
+		forgetSecret(p.AuthToken)
+	}
 	delete(s.config.Providers, name)
 	for _, pc := range s.config.Profiles {
 		pc.Providers = removeString(pc.Providers, name)
@@ -117,10 +193,98 @@ func (s *Store) DeleteProvider(name string) error {
 				delete(pc.Routing, scenario)
 			}
 		}
+		pc.Schedule = filterScheduleRules(pc.Schedule, name)
+	}
+	// Its model is the provider's, so a pin goes as a whole
+	for _, b := range s.config.ProjectBindings {
+		if b != nil && b.Provider == name {
+			b.Provider, b.Model = "", ""
+		}
+	}
+	return s.saveLocked()
+}
+
+// SetProviderCooldown marks a provider unavailable until the given time and
+// saves. A zero time clears the cooldown.
+func (s *Store) SetProviderCooldown(name string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	p := s.config.Providers[name]
+	if p == nil {
+		return fmt.Errorf("provider '%s' not found", name)
+	}
+	if until.IsZero() {
+		p.CooldownUntil = nil
+	} else {
+		p.CooldownUntil = &until
+	}
+	return s.saveLocked()
+}
+
+// SetOAuthRefreshToken saves the refresh token a provider's token endpoint
+// rotated to, unless its configured refresh token is an env: or cmd:
+// reference, which opencc can't update.
+func (s *Store) SetOAuthRefreshToken(name, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	p := s.config.Providers[name]
+	if p == nil || p.OAuth == nil {
+		return fmt.Errorf("provider '%s' has no oauth settings", name)
+	}
+	if IsTokenRef(p.OAuth.RefreshToken) || p.OAuth.RefreshToken == token {
+		return nil
+	}
+	p.OAuth.RefreshToken = token
+	return s.saveLocked()
+}
+
+// SetSecretStore moves every provider token to the given secret store,
+// removing keychain entries that are no longer used, and saves.
+func (s *Store) SetSecretStore(store string) error {
+	if !IsValidSecretStore(store) {
+		return fmt.Errorf("invalid secret store %q", store)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	tokens := make(map[string]string)
+	for name, p := range s.config.Providers {
+		token, err := resolveSecret(p.AuthToken)
+		if err != nil {
+			return fmt.Errorf("provider '%s': %w", name, err)
+		}
+		tokens[name] = token
+	}
+	for name, p := range s.config.Providers {
+		stored, err := protectSecret(store, name, tokens[name])
+		if err != nil {
+			return err
+		}
+		if stored != p.AuthToken {
+			forgetSecret(p.AuthToken)
+			p.AuthToken = stored
+		}
 	}
+	s.config.SecretStore = store
 	return s.saveLocked()
 }
 
+// GetSecretStore returns where provider tokens are saved.
+func (s *Store) GetSecretStore() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return SecretStorePlain
+	}
+	return s.config.SecretStore
+}
+
 // ProviderNames returns sorted provider names.
 func (s *Store) ProviderNames() []string {
 	s.mu.Lock()
@@ -160,7 +324,8 @@ func (s *Store) ExportProviderToEnv(name string) error {
 
 // --- Profile operations ---
 
-// GetProfileOrder returns the provider list for a profile.
+// GetProfileOrder returns the provider list for a profile, those it
+// inherits first.
 func (s *Store) GetProfileOrder(profile string) []string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -168,7 +333,7 @@ func (s *Store) GetProfileOrder(profile string) []string {
 	if s.config == nil {
 		return nil
 	}
-	pc := s.config.Profiles[profile]
+	pc := resolveProfile(s.config.Profiles, profile)
 	if pc == nil {
 		return nil
 	}
@@ -194,8 +359,21 @@ func (s *Store) SetProfileOrder(profile string, names []string) error {
 	return s.saveLocked()
 }
 
-// GetProfileConfig returns the full profile configuration.
+// GetProfileConfig returns the full profile configuration, with what it
+// inherits through extends merged in.
 func (s *Store) GetProfileConfig(profile string) *ProfileConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return resolveProfile(s.config.Profiles, profile)
+}
+
+// GetOwnProfileConfig returns the profile as configured, without what it
+// inherits through extends, for editing.
+func (s *Store) GetOwnProfileConfig(profile string) *ProfileConfig {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
@@ -236,6 +414,7 @@ func (s *Store) RemoveFromProfile(profile, name string) error {
 			delete(pc.Routing, scenario)
 		}
 	}
+	pc.Schedule = filterScheduleRules(pc.Schedule, name)
 	return s.saveLocked()
 }
 
@@ -257,7 +436,17 @@ func (s *Store) DeleteProfile(profile string) error {
 	if profile == defaultProfile {
 		return fmt.Errorf("cannot delete the default profile '%s'", profile)
 	}
+	for _, name := range sortedKeys(s.config.Profiles) {
+		if pc := s.config.Profiles[name]; pc != nil && pc.Extends == profile {
+			return fmt.Errorf("cannot delete profile '%s': profile '%s' extends it", profile, name)
+		}
+	}
 
+	if _, ok := s.config.Profiles[profile]; ok {
+		if _, err := s.backupLocked("delete profile " + profile); err != nil {
+			return err
+		}
+	}
 	delete(s.config.Profiles, profile)
 	return s.saveLocked()
 }
@@ -346,13 +535,324 @@ func (s *Store) SetWebPort(port int) error {
 	return s.saveLocked()
 }
 
+// HasWebPassword reports whether a web UI password is set.
+func (s *Store) HasWebPassword() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	return s.config != nil && s.config.WebPassword != ""
+}
+
+// CheckWebPassword reports whether password is the web UI password. It is
+// false when none is set.
+func (s *Store) CheckWebPassword(password string) bool {
+	s.mu.Lock()
+	s.reloadIfModified()
+	hash := ""
+	if s.config != nil {
+		hash = s.config.WebPassword
+	}
+	s.mu.Unlock()
+	return hash != "" && verifyPassword(hash, password)
+}
+
+// SetWebPassword sets the web UI password, storing only its hash. An empty
+// password removes it.
+func (s *Store) SetWebPassword(password string) error {
+	hash := ""
+	if password != "" {
+		var err error
+		if hash, err = hashPassword(password); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.WebPassword = hash
+	return s.saveLocked()
+}
+
+// GetPreflightCheck reports whether the primary provider should be probed
+// before launching a CLI session.
+func (s *Store) GetPreflightCheck() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	return s.config != nil && s.config.PreflightCheck
+}
+
+// SetPreflightCheck enables or disables the pre-launch provider probe.
+func (s *Store) SetPreflightCheck(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.PreflightCheck = enabled
+	return s.saveLocked()
+}
+
+// GetStrictEnv reports whether launching should be refused when shell
+// environment variables conflict with the ones opencc sets.
+func (s *Store) GetStrictEnv() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	return s.config != nil && s.config.StrictEnv
+}
+
+// SetStrictEnv enables or disables refusing to launch on env conflicts.
+func (s *Store) SetStrictEnv(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.StrictEnv = enabled
+	return s.saveLocked()
+}
+
+// GetLocale returns the configured UI language, or "" to follow LANG.
+func (s *Store) GetLocale() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.Locale
+}
+
+// SetLocale sets the UI language; "" follows LANG.
+func (s *Store) SetLocale(locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.Locale = locale
+	return s.saveLocked()
+}
+
+// GetLaunchTemplate returns the global launch template for a CLI, or nil.
+func (s *Store) GetLaunchTemplate(cli string) *LaunchTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Launch[cli]
+}
+
+// GetAccessLog returns the access log settings, or nil if disabled.
+func (s *Store) GetAccessLog() *AccessLogConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.AccessLog
+}
+
+// GetUsageWarnings returns the session token warning settings, or nil if disabled.
+func (s *Store) GetUsageWarnings() *UsageWarningConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.UsageWarnings
+}
+
+// GetRequestSize returns the global request size limits, or nil if unset.
+func (s *Store) GetRequestSize() *RequestSizeConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.RequestSize
+}
+
+// GetProxyPort returns the port for the proxy each session starts, or 0 for
+// a random one.
+func (s *Store) GetProxyPort() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return 0
+	}
+	return s.config.ProxyPort
+}
+
+// GetProxyListen returns the host or host:port the proxy listens on, or ""
+// for loopback.
+func (s *Store) GetProxyListen() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.ProxyListen
+}
+
+// GetProxyAuthToken returns the key proxy clients must present, or "" if
+// none is required.
+func (s *Store) GetProxyAuthToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.ProxyAuthToken
+}
+
+// GetProxyTLS returns the proxy's HTTPS settings, or nil for plain HTTP.
+func (s *Store) GetProxyTLS() *ProxyTLSConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.ProxyTLS
+}
+
+// GetMetricsListen returns the address to serve Prometheus metrics on, or "".
+func (s *Store) GetMetricsListen() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.MetricsListen
+}
+
+// GetCustomScenarios returns the user-defined scenarios.
+func (s *Store) GetCustomScenarios() []CustomScenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Scenarios
+}
+
+// GetHealthCheck returns the background health check settings, or nil if unset.
+func (s *Store) GetHealthCheck() *HealthCheckConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.HealthCheck
+}
+
+// GetResponseCache returns the response cache settings, or nil if disabled.
+func (s *Store) GetResponseCache() *ResponseCacheConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.ResponseCache
+}
+
+// GetWebAccess returns the web UI's remote access settings, or nil for
+// loopback only.
+func (s *Store) GetWebAccess() *WebAccessConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.WebAccess
+}
+
+// GetDebugCapture returns the debug capture settings, or nil if disabled.
+func (s *Store) GetDebugCapture() *DebugCaptureConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.DebugCapture
+}
+
+// GetHooks returns the proxy's hook commands, or nil if there are none.
+func (s *Store) GetHooks() *HooksConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Hooks
+}
+
+// GetRedaction returns the global redaction settings, or nil if unset.
+func (s *Store) GetRedaction() *RedactionConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Redaction
+}
+
+// GetStreamFailover returns the configured stream failover mode.
+func (s *Store) GetStreamFailover() StreamFailoverMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.StreamFailover
+}
+
+// GetFailoverPolicies returns the configured per-path failover policies.
+func (s *Store) GetFailoverPolicies() map[string]FailoverPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.FailoverPolicies
+}
+
+// GetBackoffQueue returns the backoff wait queue settings, or nil if unset.
+func (s *Store) GetBackoffQueue() *BackoffQueueConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.BackoffQueue
+}
+
 // --- I/O ---
 
 // reloadIfModified checks if the config file has been modified since last load
 // and reloads if necessary. Must be called with s.mu held.
 func (s *Store) reloadIfModified() {
-	if info, err := os.Stat(s.path); err == nil {
-		if info.ModTime().After(s.modTime) {
+	if modTime, ok := s.latestModTime(); ok {
+		if modTime.After(s.modTime) {
 			// File has been modified, reload (ignore errors to avoid breaking operations)
 			s.loadLocked()
 		}
@@ -361,8 +861,36 @@ func (s *Store) reloadIfModified() {
 
 // loadLocked is the internal load implementation. Must be called with s.mu held.
 func (s *Store) loadLocked() error {
+	if err := s.loadFileLocked(); err != nil {
+		return err
+	}
+	return s.applyEnvLocked()
+}
+
+// applyEnvLocked overrides the loaded config with providers and profiles
+// defined in environment variables. Must be called with s.mu held.
+func (s *Store) applyEnvLocked() error {
+	s.envShadow = nil
+	overlay, err := envOverlayFrom(os.Environ())
+	if err != nil {
+		return err
+	}
+	if overlay.empty() {
+		return nil
+	}
+	s.ensureConfig()
+	s.envShadow = overlay.apply(s.config)
+	return nil
+}
+
+// loadFileLocked reads the config file, migrating or creating it as needed.
+// Must be called with s.mu held.
+func (s *Store) loadFileLocked() error {
 	data, err := os.ReadFile(s.path)
 	if err == nil {
+		if data, err = toJSON(data, FormatForPath(s.path)); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", s.path, err)
+		}
 		var cfg OpenCCConfig
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			return fmt.Errorf("failed to parse %s: %w", s.path, err)
@@ -385,13 +913,19 @@ func (s *Store) loadLocked() error {
 		if cfg.Profiles == nil {
 			cfg.Profiles = make(map[string]*ProfileConfig)
 		}
+		included, err := loadIncludes(&cfg, filepath.Dir(s.path))
+		if err != nil {
+			return err
+		}
 		s.config = &cfg
+		s.included = included
 		// Update modification time
-		if info, statErr := os.Stat(s.path); statErr == nil {
-			s.modTime = info.ModTime()
+		if modTime, ok := s.latestModTime(); ok {
+			s.modTime = modTime
 		}
 		return nil
 	}
+	s.included = nil
 
 	if !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read %s: %w", s.path, err)
@@ -420,7 +954,7 @@ func (s *Store) loadLocked() error {
 	return nil
 }
 
-// Load reads the JSON config from disk. If the file doesn't exist, it tries
+// Load reads the config from disk. If the file doesn't exist, it tries
 // to migrate from the legacy .cc_envs format. If neither exists, it creates
 // an empty config.
 func (s *Store) Load() error {
@@ -443,13 +977,45 @@ func (s *Store) saveLocked() error {
 		return fmt.Errorf("failed to create config dir: %w", err)
 	}
 
-	data, err := json.MarshalIndent(s.config, "", "  ")
+	// Environment-defined entries are never written to the file
+	cfg := s.config
+	if s.envShadow != nil {
+		cfg = s.envShadow.restore(s.config)
+	}
+	// Entries of included files are written back there
+	if s.included != nil {
+		var err error
+		if cfg, err = s.included.writeBack(cfg); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data, err = fromJSON(append(data, '\n'), FormatForPath(s.path))
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
-	data = append(data, '\n')
 
-	tmp, err := os.CreateTemp(dir, "opencc-*.json")
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return err
+	}
+	// Update modification time after successful save
+	if modTime, ok := s.latestModTime(); ok {
+		s.modTime = modTime
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data through a temp file
+// renamed over it, so readers never see it half written. A symlink is
+// written through, keeping it a link to the updated file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "opencc-*"+filepath.Ext(path))
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -460,7 +1026,7 @@ func (s *Store) saveLocked() error {
 		os.Remove(tmpName)
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
-	if err := tmp.Chmod(0600); err != nil {
+	if err := tmp.Chmod(perm); err != nil {
 		tmp.Close()
 		os.Remove(tmpName)
 		return fmt.Errorf("failed to chmod temp file: %w", err)
@@ -470,17 +1036,27 @@ func (s *Store) saveLocked() error {
 		return fmt.Errorf("failed to close temp file: %w", err)
 	}
 
-	if err := os.Rename(tmpName, s.path); err != nil {
+	if err := os.Rename(tmpName, path); err != nil {
 		os.Remove(tmpName)
 		return fmt.Errorf("failed to rename config file: %w", err)
 	}
-	// Update modification time after successful save
-	if info, statErr := os.Stat(s.path); statErr == nil {
-		s.modTime = info.ModTime()
-	}
 	return nil
 }
 
+// latestModTime returns the latest modification time of the config file and
+// the files it includes, and false if the config file doesn't exist.
+func (s *Store) latestModTime() (time.Time, bool) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	latest := info.ModTime()
+	if t := s.included.latestModTime(); t.After(latest) {
+		latest = t
+	}
+	return latest, true
+}
+
 // Reload re-reads the config from disk.
 func (s *Store) Reload() error {
 	return s.Load()
@@ -532,6 +1108,19 @@ func filterProviderRoutes(routes []*ProviderRoute, name string) []*ProviderRoute
 	return out
 }
 
+// filterScheduleRules removes name from the rules' providers, dropping the
+// rules left without any.
+func filterScheduleRules(rules []ScheduleRule, name string) []ScheduleRule {
+	var out []ScheduleRule
+	for _, rule := range rules {
+		rule.Providers = removeString(rule.Providers, name)
+		if len(rule.Providers) > 0 {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
 // --- Project Bindings ---
 
 // resolveProjectPath resolves symlinks and cleans the path to ensure
@@ -564,10 +1153,55 @@ func (s *Store) BindProject(path string, profile string, cli string) error {
 		return fmt.Errorf("invalid CLI '%s' (must be %v)", cli, AvailableCLIs)
 	}
 
-	s.config.ProjectBindings[path] = &ProjectBinding{
+	binding := &ProjectBinding{
 		Profile: profile,
 		CLI:     cli,
 	}
+	if existing := s.config.ProjectBindings[path]; existing != nil {
+		binding.Provider = existing.Provider
+		binding.Model = existing.Model
+		binding.Launch = existing.Launch
+		binding.Exact = existing.Exact
+	}
+	s.config.ProjectBindings[path] = binding
+	return s.saveLocked()
+}
+
+// SetProjectBindingPin sets the provider and model the binding of a
+// directory path pins; empty values clear them.
+func (s *Store) SetProjectBindingPin(path string, provider string, model string) error {
+	path = resolveProjectPath(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("no binding for %s", path)
+	}
+	if provider != "" {
+		if _, ok := s.config.Providers[provider]; !ok {
+			return fmt.Errorf("provider '%s' does not exist", provider)
+		}
+	}
+	binding.Provider = provider
+	binding.Model = model
+	return s.saveLocked()
+}
+
+// SetProjectBindingExact sets whether the binding of a directory path
+// applies to it only, or to its subdirectories too.
+func (s *Store) SetProjectBindingExact(path string, exact bool) error {
+	path = resolveProjectPath(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("no binding for %s", path)
+	}
+	binding.Exact = exact
 	return s.saveLocked()
 }
 
@@ -596,6 +1230,64 @@ func (s *Store) GetProjectBinding(path string) *ProjectBinding {
 	return s.config.ProjectBindings[path]
 }
 
+// FindProjectBinding returns the binding that applies to a directory path
+// and the directory it is bound to: the path's own binding, else that of
+// the nearest ancestor, unless it is exact. Returns nil if none applies.
+func (s *Store) FindProjectBinding(path string) (string, *ProjectBinding) {
+	path = resolveProjectPath(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil || s.config.ProjectBindings == nil {
+		return "", nil
+	}
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if b := s.config.ProjectBindings[dir]; b != nil && (dir == path || !b.Exact) {
+			return dir, b
+		}
+		if filepath.Dir(dir) == dir {
+			return "", nil
+		}
+	}
+}
+
+// GetProjectFiles returns how project files combine with project bindings.
+func (s *Store) GetProjectFiles() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil || s.config.ProjectFiles == "" {
+		return ProjectFilesBindingFirst
+	}
+	return s.config.ProjectFiles
+}
+
+// IsProjectTrusted reports whether the .opencc.json in dir may set env vars.
+func (s *Store) IsProjectTrusted(dir string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	return s.config != nil && slices.Contains(s.config.TrustedProjects, dir)
+}
+
+// SetProjectTrusted allows or stops the .opencc.json in dir setting env vars.
+func (s *Store) SetProjectTrusted(dir string, trusted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	i := slices.Index(s.config.TrustedProjects, dir)
+	switch {
+	case trusted && i < 0:
+		s.config.TrustedProjects = append(s.config.TrustedProjects, dir)
+	case !trusted && i >= 0:
+		s.config.TrustedProjects = slices.Delete(s.config.TrustedProjects, i, i+1)
+	default:
+		return nil
+	}
+	return s.saveLocked()
+}
+
 // GetAllProjectBindings returns all project bindings.
 func (s *Store) GetAllProjectBindings() map[string]*ProjectBinding {
 	s.mu.Lock()
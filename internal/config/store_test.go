@@ -4,7 +4,13 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
 )
 
 func newTestStore(t *testing.T) (*Store, string) {
@@ -213,7 +219,13 @@ func TestStoreDeleteProviderCascade(t *testing.T) {
 	s.SetProvider("y", &ProviderConfig{BaseURL: "https://y.com", AuthToken: "tok"})
 
 	s.SetProfileOrder("default", []string{"x", "y"})
-	s.SetProfileOrder("work", []string{"y", "x"})
+	s.SetProfileConfig("work", &ProfileConfig{
+		Providers: []string{"y", "x"},
+		Schedule: []ScheduleRule{
+			{Start: "22:00", End: "06:00", Providers: []string{"x", "y"}},
+			{Start: "12:00", End: "13:00", Providers: []string{"x"}},
+		},
+	})
 
 	// Delete provider x — should be removed from all profiles
 	s.DeleteProvider("x")
@@ -227,6 +239,9 @@ func TestStoreDeleteProviderCascade(t *testing.T) {
 	if len(workOrder) != 1 || workOrder[0] != "y" {
 		t.Errorf("work profile after cascade = %v", workOrder)
 	}
+	if schedule := s.GetProfileConfig("work").Schedule; len(schedule) != 1 || !slices.Equal(schedule[0].Providers, []string{"y"}) {
+		t.Errorf("work schedule after cascade = %+v, want one rule for y", schedule)
+	}
 }
 
 func TestStoreExportProviderToEnv(t *testing.T) {
@@ -329,3 +344,897 @@ func TestStoreSetProfileOrderNil(t *testing.T) {
 		t.Errorf("expected empty slice, got %v", order)
 	}
 }
+
+func TestStoreSetProviderCooldown(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Load()
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok-a"})
+
+	until := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	if err := s.SetProviderCooldown("a", until); err != nil {
+		t.Fatalf("SetProviderCooldown() error: %v", err)
+	}
+
+	// Survives a reload from disk
+	s2 := &Store{path: s.path}
+	s2.Load()
+	p := s2.GetProvider("a")
+	if p == nil || p.CooldownUntil == nil || !p.CooldownUntil.Equal(until) {
+		t.Fatalf("after reload, CooldownUntil = %v, want %v", p.CooldownUntil, until)
+	}
+
+	if err := s2.SetProviderCooldown("a", time.Time{}); err != nil {
+		t.Fatalf("clear cooldown error: %v", err)
+	}
+	if s2.GetProvider("a").CooldownUntil != nil {
+		t.Error("cooldown not cleared")
+	}
+
+	if err := s2.SetProviderCooldown("missing", until); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestStoreSetProjectTrusted(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Load()
+
+	if s.IsProjectTrusted("/work/app") {
+		t.Fatal("project trusted before opencc trust")
+	}
+	if err := s.SetProjectTrusted("/work/app", true); err != nil {
+		t.Fatalf("SetProjectTrusted() error: %v", err)
+	}
+	s.SetProjectTrusted("/work/app", true)
+
+	// Survives a reload from disk, recorded once
+	s2 := &Store{path: s.path}
+	s2.Load()
+	if !s2.IsProjectTrusted("/work/app") || s2.IsProjectTrusted("/work") {
+		t.Errorf("TrustedProjects = %v, want [/work/app]", s2.config.TrustedProjects)
+	}
+	if len(s2.config.TrustedProjects) != 1 {
+		t.Errorf("TrustedProjects = %v, want one entry", s2.config.TrustedProjects)
+	}
+
+	if err := s2.SetProjectTrusted("/work/app", false); err != nil {
+		t.Fatalf("untrust error: %v", err)
+	}
+	if s2.IsProjectTrusted("/work/app") {
+		t.Error("project still trusted")
+	}
+}
+
+func TestEnvOverlayFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		environ     []string
+		wantErr     bool
+		checkConfig func(t *testing.T, cfg *OpenCCConfig)
+	}{
+		{
+			name:    "per-field provider",
+			environ: []string{"OPENCC_PROVIDER_CI_BASE_URL=https://ci.example", "OPENCC_PROVIDER_CI_AUTH_TOKEN=tok", "OPENCC_PROVIDER_CI_REASONING_MODEL=deep", "OPENCC_PROVIDER_CI_MODEL=fast"},
+			checkConfig: func(t *testing.T, cfg *OpenCCConfig) {
+				p := cfg.Providers["ci"]
+				if p == nil || p.BaseURL != "https://ci.example" || p.AuthToken != "tok" || p.ReasoningModel != "deep" || p.Model != "fast" {
+					t.Errorf("provider ci = %+v", p)
+				}
+			},
+		},
+		{
+			name:    "providers JSON then field override",
+			environ: []string{`OPENCC_PROVIDERS_JSON={"work":{"base_url":"https://json.example","auth_token":"j"}}`, "OPENCC_PROVIDER_WORK_AUTH_TOKEN=field"},
+			checkConfig: func(t *testing.T, cfg *OpenCCConfig) {
+				p := cfg.Providers["work"]
+				if p == nil || p.BaseURL != "https://json.example" || p.AuthToken != "field" {
+					t.Errorf("provider work = %+v", p)
+				}
+			},
+		},
+		{
+			name:    "profiles",
+			environ: []string{"OPENCC_PROFILE_CI=a, b", `OPENCC_PROFILES_JSON={"ci":{"providers":["x"]},"other":["y"]}`, "OPENCC_DEFAULT_PROFILE=ci"},
+			checkConfig: func(t *testing.T, cfg *OpenCCConfig) {
+				if pc := cfg.Profiles["ci"]; pc == nil || len(pc.Providers) != 2 || pc.Providers[1] != "b" {
+					t.Errorf("profile ci = %+v, want [a b]", pc)
+				}
+				if pc := cfg.Profiles["other"]; pc == nil || len(pc.Providers) != 1 {
+					t.Errorf("profile other = %+v", pc)
+				}
+				if cfg.DefaultProfile != "ci" {
+					t.Errorf("DefaultProfile = %q", cfg.DefaultProfile)
+				}
+			},
+		},
+		{
+			name:    "unrelated and empty variables ignored",
+			environ: []string{"HOME=/root", "OPENCC_PROVIDER_X_UNKNOWN=1", "OPENCC_PROVIDER_Y_BASE_URL="},
+			checkConfig: func(t *testing.T, cfg *OpenCCConfig) {
+				if len(cfg.Providers) != 0 {
+					t.Errorf("providers = %v, want none", cfg.Providers)
+				}
+			},
+		},
+		{
+			name:    "invalid JSON",
+			environ: []string{"OPENCC_PROVIDERS_JSON={"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := envOverlayFrom(tt.environ)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("envOverlayFrom() error: %v", err)
+			}
+			cfg := &OpenCCConfig{Providers: map[string]*ProviderConfig{}, Profiles: map[string]*ProfileConfig{}}
+			o.apply(cfg)
+			tt.checkConfig(t, cfg)
+		})
+	}
+}
+
+func TestStoreEnvOverridesFileWithoutSaving(t *testing.T) {
+	s, _ := newTestStore(t)
+	os.MkdirAll(filepath.Dir(s.path), 0755)
+	os.WriteFile(s.path, []byte(`{"version":5,"providers":{"work":{"base_url":"https://file.example","auth_token":"f"}},"profiles":{"default":{"providers":["work"]}}}`), 0600)
+
+	t.Setenv("OPENCC_PROVIDER_WORK_BASE_URL", "https://env.example")
+	t.Setenv("OPENCC_PROVIDERS_JSON", `{"ci":{"base_url":"https://ci.example","auth_token":"c"}}`)
+	t.Setenv("OPENCC_PROFILE_DEFAULT", "ci,work")
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if p := s.GetProvider("work"); p == nil || p.BaseURL != "https://env.example" || p.AuthToken != "f" {
+		t.Errorf("work = %+v, want env base URL with file token", p)
+	}
+	if s.GetProvider("ci") == nil {
+		t.Error("env-only provider ci missing")
+	}
+	if pc := s.GetProfileConfig("default"); pc == nil || len(pc.Providers) != 2 {
+		t.Errorf("default profile = %+v, want [ci work]", pc)
+	}
+
+	// Saving keeps env entries out of the file
+	if err := s.SetProvider("extra", &ProviderConfig{BaseURL: "https://extra.example", AuthToken: "e"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved OpenCCConfig
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Providers["work"] == nil || saved.Providers["work"].BaseURL != "https://file.example" {
+		t.Errorf("saved work = %+v, want file values", saved.Providers["work"])
+	}
+	if saved.Providers["ci"] != nil {
+		t.Error("env-only provider ci was written to the file")
+	}
+	if saved.Providers["extra"] == nil {
+		t.Error("new provider extra not saved")
+	}
+	if pc := saved.Profiles["default"]; pc == nil || len(pc.Providers) != 1 {
+		t.Errorf("saved default profile = %+v, want [work]", pc)
+	}
+}
+
+func TestStoreExport(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Load()
+	until := time.Now().Add(time.Hour)
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok-a", CooldownUntil: &until})
+	s.SetProvider("b", &ProviderConfig{Type: ProviderTypeBedrock, Bedrock: &BedrockConfig{Region: "us-east-1", AccessKeyID: "AK", SecretAccessKey: "SK"}})
+	s.SetProvider("c", &ProviderConfig{BaseURL: "https://c.com", AuthToken: "tok-c"})
+	s.SetProfileConfig("work", &ProfileConfig{
+		Providers: []string{"a"},
+		Routing:   map[Scenario]*ScenarioRoute{ScenarioThink: {Providers: []*ProviderRoute{{Name: "b"}}}},
+	})
+	s.SetProfileConfig("child", &ProfileConfig{Providers: []string{"c"}, Extends: "work"})
+
+	tests := []struct {
+		name                string
+		providers, profiles []string
+		secrets             bool
+		wantProviders       []string
+		wantProfiles        []string
+	}{
+		{"everything", nil, nil, true, []string{"a", "b", "c"}, []string{"child", "work"}},
+		{"provider", []string{"c"}, nil, true, []string{"c"}, nil},
+		{"profile with its providers", nil, []string{"work"}, false, []string{"a", "b"}, []string{"work"}},
+		{"profile with its parent", nil, []string{"child"}, false, []string{"a", "b", "c"}, []string{"child", "work"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := s.Export(tt.providers, tt.profiles, tt.secrets)
+			if err != nil {
+				t.Fatalf("Export() error: %v", err)
+			}
+			if got := sortedKeys(doc.Providers); !slices.Equal(got, tt.wantProviders) {
+				t.Errorf("providers = %v, want %v", got, tt.wantProviders)
+			}
+			if got := sortedKeys(doc.Profiles); !slices.Equal(got, tt.wantProfiles) {
+				t.Errorf("profiles = %v, want %v", got, tt.wantProfiles)
+			}
+			for name, p := range doc.Providers {
+				if p.CooldownUntil != nil {
+					t.Errorf("provider %s exported with its cooldown", name)
+				}
+				if hasSecret := p.AuthToken != "" || (p.Bedrock != nil && p.Bedrock.SecretAccessKey != ""); hasSecret != tt.secrets {
+					t.Errorf("provider %s has secrets = %v, want %v", name, hasSecret, tt.secrets)
+				}
+			}
+		})
+	}
+
+	if s.GetProvider("a").AuthToken != "tok-a" || s.GetProvider("b").Bedrock.SecretAccessKey != "SK" {
+		t.Error("export without secrets changed the stored providers")
+	}
+	if _, err := s.Export(nil, []string{"nope"}, false); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestExportDocumentFormats(t *testing.T) {
+	doc := &ExportDocument{
+		Version:   CurrentConfigVersion,
+		Providers: map[string]*ProviderConfig{"a": {BaseURL: "https://a.com", MaxRequestBytes: 1000000, Headers: map[string]string{"X-Team": "yes"}}},
+		Profiles: map[string]*ProfileConfig{"work": {
+			Providers: []string{"a"},
+			Routing:   map[Scenario]*ScenarioRoute{ScenarioThink: {Providers: []*ProviderRoute{{Name: "a", Model: "m"}}}},
+			Weights:   map[string]int{"a": 2},
+		}},
+	}
+	for _, format := range []string{FormatJSON, FormatYAML, FormatTOML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := doc.Encode(format)
+			if err != nil {
+				t.Fatalf("Encode() error: %v", err)
+			}
+			got, err := DecodeExportDocument(data, format)
+			if err != nil {
+				t.Fatalf("DecodeExportDocument() error: %v\n%s", err, data)
+			}
+			want, _ := json.Marshal(doc)
+			if have, _ := json.Marshal(got); string(have) != string(want) {
+				t.Errorf("round trip = %s, want %s", have, want)
+			}
+		})
+	}
+
+	if _, err := DecodeExportDocument([]byte(`{"version": 99}`), FormatJSON); err == nil {
+		t.Error("expected error for a newer document version")
+	}
+	if got := FormatForPath("team.YML"); got != FormatYAML {
+		t.Errorf("FormatForPath(team.YML) = %q", got)
+	}
+	if got := FormatForPath("team.toml"); got != FormatTOML {
+		t.Errorf("FormatForPath(team.toml) = %q", got)
+	}
+}
+
+func TestStoreImport(t *testing.T) {
+	newDoc := func() *ExportDocument {
+		return &ExportDocument{
+			Providers: map[string]*ProviderConfig{
+				"a": {BaseURL: "https://new-a.com"},
+				"b": {BaseURL: "https://b.com", AuthToken: "tok-b"},
+			},
+			Profiles: map[string]*ProfileConfig{
+				"work":  {Providers: []string{"a", "b"}, Weights: map[string]int{"a": 3}},
+				"child": {Providers: []string{"b"}, Extends: "work", Schedule: []ScheduleRule{{Start: "22:00", End: "06:00", Providers: []string{"a"}}}},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		resolve     ImportResolver
+		wantA       string   // base URL of provider a afterwards
+		wantProfile string   // name the imported profile is added under
+		wantOrder   []string // its providers
+	}{
+		{"overwrite keeps token", func(kind, name string) (string, error) { return name, nil }, "https://new-a.com", "work", []string{"a", "b"}},
+		{"skip", func(kind, name string) (string, error) { return "", nil }, "https://a.com", "work", []string{"a"}},
+		{"rename", func(kind, name string) (string, error) { return name + "2", nil }, "https://a.com", "work2", []string{"a2", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			s.Load()
+			s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok-a"})
+			s.SetProfileOrder("work", []string{"a"})
+
+			if _, err := s.Import(newDoc(), tt.resolve); err != nil {
+				t.Fatalf("Import() error: %v", err)
+			}
+			a := s.GetProvider("a")
+			if a.BaseURL != tt.wantA || a.AuthToken != "tok-a" {
+				t.Errorf("provider a = %s %q, want %s with its token", a.BaseURL, a.AuthToken, tt.wantA)
+			}
+			if s.GetProvider("b") == nil {
+				t.Error("provider b not imported")
+			}
+			if got := s.GetProfileOrder(tt.wantProfile); !slices.Equal(got, tt.wantOrder) {
+				t.Errorf("profile %s providers = %v, want %v", tt.wantProfile, got, tt.wantOrder)
+			}
+			if tt.name == "rename" {
+				if s.GetProvider("a2") == nil || s.GetProfileConfig("work2").Weights["a2"] != 3 {
+					t.Error("renamed provider a2 missing from the config or the profile's weights")
+				}
+			}
+			if child := s.GetOwnProfileConfig("child"); child == nil || child.Extends != tt.wantProfile {
+				t.Errorf("child = %+v, want it to extend %s", child, tt.wantProfile)
+			} else if want := tt.wantOrder[0]; child.Schedule[0].Providers[0] != want {
+				t.Errorf("child schedule = %v, want [%s]", child.Schedule[0].Providers, want)
+			}
+		})
+	}
+}
+
+func TestStoreImportMissingProvider(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Load()
+	doc := &ExportDocument{Profiles: map[string]*ProfileConfig{"work": {Providers: []string{"nope"}}}}
+	if _, err := s.Import(doc, nil); err == nil {
+		t.Fatal("expected error for a profile using an unknown provider")
+	}
+	if s.GetProfileConfig("work") != nil {
+		t.Error("failed import added the profile")
+	}
+
+	doc = &ExportDocument{Profiles: map[string]*ProfileConfig{"work": {Extends: "nope"}}}
+	if _, err := s.Import(doc, nil); err == nil {
+		t.Error("expected error for a profile extending an unknown profile")
+	}
+}
+
+func TestStoreSecretStore(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	for _, store := range []string{SecretStoreKeychain, SecretStoreEncrypted} {
+		t.Run(store, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			s.Load()
+			s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok-a"})
+
+			if err := s.SetSecretStore(store); err != nil {
+				t.Fatalf("SetSecretStore() error: %v", err)
+			}
+			data, _ := os.ReadFile(s.path)
+			if strings.Contains(string(data), "tok-a") {
+				t.Fatalf("token saved in plain text:\n%s", data)
+			}
+			stored := s.config.Providers["a"].AuthToken
+			if got := s.GetProvider("a").AuthToken; got != "tok-a" {
+				t.Errorf("GetProvider() token = %q, want tok-a", got)
+			}
+
+			// Saving the resolved token keeps the reference; a new one is protected
+			s.SetProvider("a", s.GetProvider("a"))
+			if got := s.config.Providers["a"].AuthToken; got != stored {
+				t.Errorf("re-saving changed the stored token from %q to %q", stored, got)
+			}
+			s.SetProvider("b", &ProviderConfig{BaseURL: "https://b.com", AuthToken: "tok-b"})
+			if got := s.config.Providers["b"].AuthToken; !isSecretRef(got) {
+				t.Errorf("new provider token stored as %q", got)
+			}
+
+			if err := s.SetSecretStore(SecretStorePlain); err != nil {
+				t.Fatalf("SetSecretStore(plain) error: %v", err)
+			}
+			if got := s.config.Providers["b"].AuthToken; got != "tok-b" {
+				t.Errorf("plain token = %q, want tok-b", got)
+			}
+			if store == SecretStoreKeychain {
+				if _, err := keyring.Get(keychainService, "a"); err == nil {
+					t.Error("keychain entry left behind")
+				}
+			}
+		})
+	}
+}
+
+func TestStoreResolveProviderError(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	sealed, err := encryptSecret("tok-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestStore(t)
+	s.Load()
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: encryptedPrefix + sealed})
+
+	t.Setenv(PassphraseEnv, "wrong")
+	p, err := s.ResolveProvider("a")
+	if err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("ResolveProvider() error = %v, want one naming %s", err, PassphraseEnv)
+	}
+	if p == nil || p.AuthToken != "" {
+		t.Errorf("ResolveProvider() = %+v, want the provider without a token", p)
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	t.Setenv("OPENCC_TEST_TOKEN", " tok-env\n")
+	t.Setenv("OPENCC_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"plain", "sk-plain", "sk-plain", ""},
+		{"empty", "", "", ""},
+		{"env", "env:OPENCC_TEST_TOKEN", "tok-env", ""},
+		{"env unset", "env:OPENCC_TEST_EMPTY", "", "OPENCC_TEST_EMPTY is not set"},
+		{"env no name", "env:", "", "names no variable"},
+		{"cmd", "cmd:echo tok-cmd", "tok-cmd", ""},
+		{"cmd fails", "cmd:echo oops >&2; exit 3", "", "oops"},
+		{"cmd no output", "cmd:true", "", "printed no token"},
+		{"cmd empty", "cmd: ", "", "has no command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && strings.HasPrefix(tt.value, "cmd:") {
+				t.Skip("uses sh syntax")
+			}
+			got, err := ResolveToken(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveToken(%q) error = %v, want one containing %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveToken(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveToken(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTokenCacheExpires(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	file := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(file, []byte("tok-1"), 0600)
+	ref := "cmd:cat " + file
+
+	if got, _ := ResolveToken(ref); got != "tok-1" {
+		t.Fatalf("ResolveToken() = %q, want tok-1", got)
+	}
+	// Rotated in the secret manager: cached until the entry expires
+	os.WriteFile(file, []byte("tok-2"), 0600)
+	if got, _ := ResolveToken(ref); got != "tok-1" {
+		t.Errorf("ResolveToken() = %q, want the cached tok-1", got)
+	}
+	resolvedTokens.Store(ref, cachedToken{token: "tok-1", expires: time.Now().Add(-time.Second)})
+	if got, _ := ResolveToken(ref); got != "tok-2" {
+		t.Errorf("ResolveToken() after expiry = %q, want tok-2", got)
+	}
+}
+
+func TestStoreKeepsTokenRefs(t *testing.T) {
+	keyring.MockInit()
+	s, _ := newTestStore(t)
+	s.Load()
+	if err := s.SetSecretStore(SecretStoreKeychain); err != nil {
+		t.Fatal(err)
+	}
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", AuthToken: "env:A_TOKEN"})
+	if got := s.GetProvider("a").AuthToken; got != "env:A_TOKEN" {
+		t.Errorf("AuthToken = %q, want the env: reference kept", got)
+	}
+
+	doc, err := s.Export(nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Providers["a"].AuthToken; got != "env:A_TOKEN" {
+		t.Errorf("exported AuthToken = %q, want the env: reference kept", got)
+	}
+}
+
+func TestStoreSetOAuthRefreshToken(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Load()
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.com", OAuth: &OAuthConfig{TokenURL: "https://a.com/token", RefreshToken: "rt-1", ClientSecret: "cs"}})
+	s.SetProvider("b", &ProviderConfig{BaseURL: "https://b.com", OAuth: &OAuthConfig{TokenURL: "https://b.com/token", RefreshToken: "env:B_RT"}})
+
+	if err := s.SetOAuthRefreshToken("a", "rt-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetProvider("a").OAuth.RefreshToken; got != "rt-2" {
+		t.Errorf("a refresh token = %q, want rt-2", got)
+	}
+	if err := s.SetOAuthRefreshToken("b", "rt-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetProvider("b").OAuth.RefreshToken; got != "env:B_RT" {
+		t.Errorf("b refresh token = %q, want the env: reference kept", got)
+	}
+	if err := s.SetOAuthRefreshToken("missing", "rt"); err == nil {
+		t.Error("expected error for a provider without oauth settings")
+	}
+
+	doc, err := s.Export(nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := doc.Providers["a"].OAuth; o.RefreshToken != "" || o.ClientSecret != "" || o.TokenURL == "" {
+		t.Errorf("exported oauth = %+v, want the secrets cleared", o)
+	}
+}
+
+func TestStoreWebPassword(t *testing.T) {
+	s, _ := newTestStore(t)
+	if s.HasWebPassword() || s.CheckWebPassword("") {
+		t.Fatal("no password should be set initially")
+	}
+	if err := s.SetWebPassword("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasWebPassword() || strings.Contains(s.config.WebPassword, "hunter2") {
+		t.Errorf("stored password = %q, want a hash", s.config.WebPassword)
+	}
+	for _, tt := range []struct {
+		password string
+		want     bool
+	}{{"hunter2", true}, {"hunter3", false}, {"", false}} {
+		if got := s.CheckWebPassword(tt.password); got != tt.want {
+			t.Errorf("CheckWebPassword(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+	if err := s.SetWebPassword(""); err != nil || s.HasWebPassword() {
+		t.Errorf("clearing the password: err = %v, set = %v", err, s.HasWebPassword())
+	}
+}
+
+func TestStoreBackups(t *testing.T) {
+	s, _ := newTestStore(t)
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := s.SetProvider(name, &ProviderConfig{BaseURL: "https://" + name + ".example", AuthToken: "tok"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SetProfileOrder("work", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := s.ListBackups(); len(backups) != 0 {
+		t.Fatalf("backups before any destructive change = %v", backups)
+	}
+
+	if err := s.DeleteProvider("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteProvider("missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := s.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, b := range backups {
+		reasons = append(reasons, b.Reason)
+	}
+	if want := []string{"delete profile work", "delete provider a"}; !slices.Equal(reasons, want) {
+		t.Fatalf("reasons = %v, want %v", reasons, want)
+	}
+	info, err := os.Stat(filepath.Join(s.backupDir(), backups[0].ID+".json"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("backup file = %v, %v", info, err)
+	}
+
+	// Restoring the older backup brings the provider back
+	if err := s.RestoreBackup(backups[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetProvider("a") == nil || s.GetProfileConfig("work") == nil {
+		t.Error("restore did not bring back provider a and profile work")
+	}
+	if after, _ := s.ListBackups(); len(after) != 3 || after[0].Reason != "restore "+backups[1].ID {
+		t.Errorf("backups after restore = %v", after)
+	}
+	if err := s.RestoreBackup("../opencc"); err == nil {
+		t.Error("restoring a path outside the backup dir should fail")
+	}
+
+	// Callers can back up before changes the store doesn't snapshot itself
+	b, err := s.Backup("reorder profile default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, _ := s.ListBackups(); latest[0].ID != b.ID || latest[0].Reason != "reorder profile default" || b.Size == 0 {
+		t.Errorf("Backup() = %+v, newest backup = %+v", b, latest[0])
+	}
+
+	// Only the newest backup_retention backups are kept
+	s.config.BackupRetention = 2
+	if err := s.DeleteProvider("c"); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ = s.ListBackups()
+	if len(backups) != 2 || backups[0].Reason != "delete provider c" {
+		t.Errorf("backups after pruning = %v", backups)
+	}
+	index, _ := os.ReadFile(filepath.Join(s.backupDir(), backupIndexFile))
+	if lines := strings.Count(string(index), "\n"); lines != 2 {
+		t.Errorf("index has %d lines, want 2", lines)
+	}
+}
+
+func TestStoreFileFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		doc  string // hand-written config in the file's format
+		want string // a line the saved file must contain
+	}{
+		{"yaml", "opencc.yaml", "providers:\n  work:\n    base_url: https://work.example\n    auth_token: tok\nprofiles:\n  default:\n    providers: [work]\n", "base_url: https://"},
+		{"yml", "opencc.yml", "providers:\n  work:\n    base_url: https://work.example\n    auth_token: tok\n", "base_url: https://"},
+		{"toml", "opencc.toml", "[providers.work]\nbase_url = \"https://work.example\"\nauth_token = \"tok\"\n\n[profiles.default]\nproviders = [\"work\"]\n", "base_url = \"https://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, home := newTestStore(t)
+			dir := filepath.Join(home, ConfigDir)
+			os.MkdirAll(dir, 0700)
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.doc), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if got := ConfigFilePath(); got != path {
+				t.Fatalf("ConfigFilePath() = %q, want %q", got, path)
+			}
+
+			s := &Store{path: path}
+			if err := s.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if p := s.GetProvider("work"); p == nil || p.BaseURL != "https://work.example" {
+				t.Fatalf("GetProvider(work) = %+v", p)
+			}
+			if err := s.SetProvider("home", &ProviderConfig{BaseURL: "https://home.example", AuthToken: "tok2", Model: "m"}); err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := os.ReadFile(path)
+			if !strings.Contains(string(data), tt.want) || json.Valid(data) {
+				t.Errorf("saved file is not in its own format:\n%s", data)
+			}
+			if _, err := os.Stat(filepath.Join(dir, ConfigFile)); !os.IsNotExist(err) {
+				t.Errorf("%s was created next to %s", ConfigFile, tt.file)
+			}
+			reloaded := &Store{path: path}
+			if err := reloaded.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if p := reloaded.GetProvider("home"); p == nil || p.Model != "m" {
+				t.Errorf("reloaded provider home = %+v", p)
+			}
+			if problems, err := ValidateConfigFile(); err != nil || len(problems) != 0 {
+				t.Errorf("ValidateConfigFile() = %v, %v", problems, err)
+			}
+
+			// Backups keep the file's format and restore into it
+			if err := reloaded.DeleteProvider("home"); err != nil {
+				t.Fatal(err)
+			}
+			backups, _ := reloaded.ListBackups()
+			if len(backups) != 1 || backups[0].file != backups[0].ID+filepath.Ext(tt.file) {
+				t.Fatalf("backups = %+v", backups)
+			}
+			if err := reloaded.RestoreBackup(backups[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			if reloaded.GetProvider("home") == nil {
+				t.Error("restore did not bring back provider home")
+			}
+		})
+	}
+}
+
+func TestConfigFilePathPrefersJSON(t *testing.T) {
+	_, home := newTestStore(t)
+	dir := filepath.Join(home, ConfigDir)
+	if got := ConfigFilePath(); got != filepath.Join(dir, ConfigFile) {
+		t.Errorf("ConfigFilePath() without a config = %q", got)
+	}
+	os.MkdirAll(dir, 0700)
+	for _, name := range []string{"opencc.toml", ConfigFile} {
+		os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600)
+	}
+	if got := ConfigFilePath(); got != filepath.Join(dir, ConfigFile) {
+		t.Errorf("ConfigFilePath() with both = %q, want %s", got, ConfigFile)
+	}
+}
+
+func TestStoreIncludes(t *testing.T) {
+	s, home := newTestStore(t)
+	dir := filepath.Join(home, ConfigDir)
+	os.MkdirAll(filepath.Join(dir, "providers.d"), 0700)
+	main := `{"include": ["providers.d/*"], "providers": {"mine": {"base_url": "https://mine.example", "auth_token": "t"}}, "profiles": {"default": {"providers": ["team-a", "mine"]}}}`
+	team := "providers:\n  team-a:\n    base_url: https://a.example\n    auth_token: t\n  team-b:\n    base_url: https://b.example\n    auth_token: t\n  mine:\n    base_url: https://shadowed.example\n"
+	for name, content := range map[string]string{
+		ConfigFile:                 main,
+		"dotfiles/team.yaml":       team,
+		"providers.d/z-local.json": `{"providers": {"team-b": {"base_url": "https://b2.example", "auth_token": "t"}}}`,
+		"providers.d/README.md":    "not a config file",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Linked in by a dotfile manager
+	if err := os.Symlink(filepath.Join(dir, "dotfiles/team.yaml"), filepath.Join(dir, "providers.d/team.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The main file wins, then later files over earlier ones
+	for name, want := range map[string]string{"mine": "https://mine.example", "team-a": "https://a.example", "team-b": "https://b2.example"} {
+		if p := s.GetProvider(name); p == nil || p.BaseURL != want {
+			t.Errorf("provider %s = %+v, want base_url %s", name, p, want)
+		}
+	}
+	if problems, err := ValidateConfigFile(); err != nil || len(problems) != 0 {
+		t.Errorf("ValidateConfigFile() = %v, %v", problems, err)
+	}
+
+	// Edits go back to the file the entry came from
+	if err := s.SetProvider("team-a", &ProviderConfig{BaseURL: "https://a.example", AuthToken: "t", Model: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetProvider("new", &ProviderConfig{BaseURL: "https://new.example"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteProvider("team-b"); err != nil {
+		t.Fatal(err)
+	}
+	mainData, _ := os.ReadFile(filepath.Join(dir, ConfigFile))
+	teamData, _ := os.ReadFile(filepath.Join(dir, "providers.d/team.yaml"))
+	localData, _ := os.ReadFile(filepath.Join(dir, "providers.d/z-local.json"))
+	if strings.Contains(string(mainData), `"team-a": {`) || !strings.Contains(string(mainData), `"new": {`) {
+		t.Errorf("main file = %s", mainData)
+	}
+	if !strings.Contains(string(teamData), "model: changed") || !strings.Contains(string(teamData), "shadowed.example") {
+		t.Errorf("team.yaml = %s", teamData)
+	}
+	if info, err := os.Lstat(filepath.Join(dir, "providers.d/team.yaml")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("saving replaced the symlinked team.yaml with a regular file")
+	}
+	if strings.Contains(string(localData), "team-b") {
+		t.Errorf("z-local.json still has the deleted provider: %s", localData)
+	}
+
+	reloaded := &Store{path: filepath.Join(dir, ConfigFile)}
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if p := reloaded.GetProvider("team-a"); p == nil || p.Model != "changed" {
+		t.Errorf("reloaded team-a = %+v", p)
+	}
+	// team.yaml's team-b shows again once extra.json no longer overrides it
+	if p := reloaded.GetProvider("team-b"); p == nil || p.BaseURL != "https://b.example" {
+		t.Errorf("reloaded team-b = %+v", p)
+	}
+
+	// A changed included file is picked up like a changed main file
+	time.Sleep(10 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "providers.d/z-local.json"), []byte(`{"providers": {"team-c": {"base_url": "https://c.example"}}}`), 0600)
+	if reloaded.GetProvider("team-c") == nil {
+		t.Error("change to an included file was not reloaded")
+	}
+}
+
+func TestStoreProfileExtends(t *testing.T) {
+	s, _ := newTestStore(t)
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := s.SetProvider(name, &ProviderConfig{BaseURL: "https://" + name + ".example", AuthToken: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := &ProfileConfig{
+		Providers: []string{"a", "b"},
+		Routing: map[Scenario]*ScenarioRoute{
+			ScenarioThink: {Providers: []*ProviderRoute{{Name: "b"}}},
+			ScenarioImage: {Providers: []*ProviderRoute{{Name: "a"}}},
+		},
+		Strategy: StrategyRoundRobin,
+		Weights:  map[string]int{"a": 2},
+		EnvVars:  map[string]map[string]string{"claude": {"X": "base", "Y": "base"}},
+	}
+	child := &ProfileConfig{
+		Extends:   "default",
+		Providers: []string{"c", "a"},
+		Routing: map[Scenario]*ScenarioRoute{
+			ScenarioThink: {Providers: []*ProviderRoute{{Name: "c"}}},
+		},
+		EnvVars: map[string]map[string]string{"claude": {"Y": "child"}},
+	}
+	grandchild := &ProfileConfig{Extends: "child", Providers: []string{}, Strategy: StrategyFailover}
+	for name, pc := range map[string]*ProfileConfig{"default": base, "child": child, "grandchild": grandchild} {
+		if err := s.SetProfileConfig(name, pc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"child", "grandchild"} {
+		pc := s.GetProfileConfig(name)
+		if got := strings.Join(pc.Providers, ","); got != "a,b,c" {
+			t.Errorf("%s providers = %s, want a,b,c", name, got)
+		}
+		if pc.Routing[ScenarioThink].Providers[0].Name != "c" || pc.Routing[ScenarioImage] == nil {
+			t.Errorf("%s routing = %+v, want think overridden and image inherited", name, pc.Routing)
+		}
+		if env := pc.EnvVars["claude"]; env["X"] != "base" || env["Y"] != "child" {
+			t.Errorf("%s env = %v", name, env)
+		}
+		if pc.Weights["a"] != 2 {
+			t.Errorf("%s weights = %v", name, pc.Weights)
+		}
+	}
+	if got := s.GetProfileConfig("child").Strategy; got != StrategyRoundRobin {
+		t.Errorf("child strategy = %s, want inherited round-robin", got)
+	}
+	if got := s.GetProfileConfig("grandchild").Strategy; got != StrategyFailover {
+		t.Errorf("grandchild strategy = %s, want its own failover", got)
+	}
+	if own := s.GetOwnProfileConfig("child"); strings.Join(own.Providers, ",") != "c,a" {
+		t.Errorf("own providers = %v, want c,a", own.Providers)
+	}
+	if got := strings.Join(s.GetProfileOrder("child"), ","); got != "a,b,c" {
+		t.Errorf("GetProfileOrder(child) = %s", got)
+	}
+
+	if err := s.CheckExtends("default", "grandchild"); err == nil {
+		t.Error("CheckExtends() allowed a cycle")
+	}
+	if err := s.CheckExtends("other", "missing"); err == nil {
+		t.Error("CheckExtends() allowed a missing parent")
+	}
+	if err := s.CheckExtends("other", "child"); err != nil {
+		t.Errorf("CheckExtends() = %v", err)
+	}
+	if err := s.DeleteProfile("child"); err == nil {
+		t.Error("DeleteProfile() deleted an extended profile")
+	}
+
+	problems := ValidateConfig([]byte(`{"providers": {"a": {"base_url": "https://a.example", "auth_token": "t"}},
+		"profiles": {"default": {"providers": ["a"]}, "x": {"extends": "y", "providers": [], "weights": {"a": 1}}, "y": {"extends": "x", "providers": []}, "z": {"extends": "nope", "providers": []}}}`))
+	var paths []string
+	for _, p := range problems {
+		paths = append(paths, p.Path)
+	}
+	if got := strings.Join(paths, " "); got != "profiles.x.weights.a profiles.x.extends profiles.y.extends profiles.z.extends" {
+		t.Errorf("problems = %v", problems)
+	}
+}
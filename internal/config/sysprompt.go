@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SystemPrompt returns the text the proxy puts before the system prompt of
+// the provider's requests, or "" if none is set.
+func (p *ProviderConfig) SystemPrompt() (string, error) {
+	if p == nil {
+		return "", nil
+	}
+	return systemPrompt(p.SystemPromptPrefix, p.SystemPromptFile)
+}
+
+// SystemPrompt returns the text the proxy puts before the system prompt of
+// every request of the profile, or "" if none is set.
+func (pc *ProfileConfig) SystemPrompt() (string, error) {
+	if pc == nil {
+		return "", nil
+	}
+	return systemPrompt(pc.SystemPromptPrefix, pc.SystemPromptFile)
+}
+
+// systemPrompt joins prefix and the text of file, separated by a blank line.
+// file is relative to the config directory unless absolute or starting with
+// ~/.
+func systemPrompt(prefix, file string) (string, error) {
+	if file == "" {
+		return prefix, nil
+	}
+	if rest, ok := strings.CutPrefix(file, "~/"); ok {
+		file = filepath.Join(os.Getenv("HOME"), rest)
+	} else if !filepath.IsAbs(file) {
+		file = filepath.Join(ConfigDirPath(), file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read system prompt file: %w", err)
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if prefix == "" {
+		return text, nil
+	}
+	if text == "" {
+		return prefix, nil
+	}
+	return prefix + "\n\n" + text, nil
+}
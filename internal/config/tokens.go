@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token references: an auth_token that names where the token comes from
+// rather than holding it.
+const (
+	envTokenPrefix = "env:" // env:NAME reads environment variable NAME
+	cmdTokenPrefix = "cmd:" // cmd:<command> runs the command and reads its output
+)
+
+// tokenCommandTimeout bounds how long a cmd: token may take, since secret
+// managers can prompt or hang on a locked vault.
+const tokenCommandTimeout = 30 * time.Second
+
+// IsTokenRef reports whether an auth token is an env: or cmd: reference.
+func IsTokenRef(v string) bool {
+	return strings.HasPrefix(v, envTokenPrefix) || strings.HasPrefix(v, cmdTokenPrefix)
+}
+
+// tokenCacheTTL is how long the output of a token command is reused, so a
+// token rotated in the secret manager is picked up after auth failures.
+const tokenCacheTTL = 5 * time.Minute
+
+// resolvedTokens caches the output of token commands by reference, so a
+// command doesn't run on every rebuild of the proxy or token check.
+var resolvedTokens sync.Map // string -> cachedToken
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// ResolveToken returns the token an auth_token stands for: the value of the
+// environment variable for env:NAME, the trimmed output of the command for
+// cmd:<command>, or the value itself otherwise.
+func ResolveToken(v string) (string, error) {
+	if name, ok := strings.CutPrefix(v, envTokenPrefix); ok {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return "", errors.New("auth_token env: reference names no variable")
+		}
+		token := strings.TrimSpace(os.Getenv(name))
+		if token == "" {
+			return "", fmt.Errorf("auth_token: environment variable %s is not set", name)
+		}
+		return token, nil
+	}
+	command, ok := strings.CutPrefix(v, cmdTokenPrefix)
+	if !ok {
+		return v, nil
+	}
+	if cached, ok := resolvedTokens.Load(v); ok && time.Now().Before(cached.(cachedToken).expires) {
+		return cached.(cachedToken).token, nil
+	}
+	token, err := runTokenCommand(strings.TrimSpace(command))
+	if err != nil {
+		return "", err
+	}
+	resolvedTokens.Store(v, cachedToken{token: token, expires: time.Now().Add(tokenCacheTTL)})
+	return token, nil
+}
+
+// runTokenCommand runs command with the system shell and returns its
+// trimmed output.
+func runTokenCommand(command string) (string, error) {
+	if command == "" {
+		return "", errors.New("auth_token cmd: reference has no command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("auth_token command %q timed out after %s", command, tokenCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("auth_token command %q failed: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("auth_token command %q failed: %w", command, err)
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("auth_token command %q printed no token", command)
+	}
+	return token, nil
+}
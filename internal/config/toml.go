@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/BurntSushi/toml"
+)
+
+// jsonToTOML converts a JSON object to TOML. TOML has no null, so null
+// values are left out, which the config types read as unset anyway.
+func jsonToTOML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(tomlValue(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlValue prepares a decoded JSON value for the TOML encoder: numbers
+// become integers where they are whole and nulls are dropped.
+func tomlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = tomlValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = tomlValue(e)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// tomlToJSON converts a TOML document to JSON so it can be decoded with the
+// JSON field names and custom unmarshalers of the config types.
+func tomlToJSON(data []byte) ([]byte, error) {
+	var v map[string]interface{}
+	if err := toml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
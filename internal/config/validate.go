@@ -0,0 +1,483 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ConfigProblem is one thing wrong with a config file.
+type ConfigProblem struct {
+	Path    string `json:"path,omitempty"` // dotted path of the offending value, e.g. "providers.work.base_url"
+	Message string `json:"message"`
+}
+
+func (p ConfigProblem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// configValidator collects the problems found in a config.
+type configValidator struct {
+	problems []ConfigProblem
+}
+
+func (v *configValidator) add(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateConfig checks an opencc.json document and returns every problem
+// found: unknown and duplicated fields, values of the wrong type, references
+// to providers, profiles and scenarios that don't exist, invalid URLs and
+// settings that conflict. Loading the config only fails on the first of
+// them, and the rest surface at runtime. Included files are read from the
+// config dir.
+func ValidateConfig(data []byte) []ConfigProblem {
+	return validateConfig(data, ConfigDirPath())
+}
+
+// validateConfig is ValidateConfig with included files read relative to dir.
+func validateConfig(data []byte, dir string) []ConfigProblem {
+	v := &configValidator{}
+	if err := json.Unmarshal(data, new(json.RawMessage)); err != nil {
+		v.add("", "invalid JSON: %v", err)
+		return v.problems
+	}
+	if err := v.walk(json.NewDecoder(bytes.NewReader(data)), "", reflect.TypeOf(OpenCCConfig{})); err != nil {
+		v.add("", "invalid JSON: %v", err)
+		return v.problems
+	}
+
+	var cfg OpenCCConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			v.add(te.Field, "expected %s, got %s", te.Type, te.Value)
+		} else {
+			v.add("", "%v", err)
+		}
+		return v.problems
+	}
+	// Profiles may use providers defined in included files
+	if cfg.Providers == nil {
+		cfg.Providers = make(map[string]*ProviderConfig)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*ProfileConfig)
+	}
+	if _, err := loadIncludes(&cfg, dir); err != nil {
+		v.add("include", "%v", err)
+	}
+	v.check(&cfg)
+	return v.problems
+}
+
+// legacyFields are keys the custom decoders still accept from older config
+// formats, beyond the struct fields.
+var legacyFields = map[reflect.Type][]string{
+	reflect.TypeOf(ScenarioRoute{}): {"model"},
+}
+
+// walk reads the next value from dec, reporting object keys that t, the type
+// it decodes into, has no field for, and keys given twice. t is nil where
+// the shape is unknown, e.g. under an unknown key or in a legacy format.
+func (v *configValidator) walk(dec *json.Decoder, path string, t reflect.Type) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '[':
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			if err := v.walk(dec, fmt.Sprintf("%s[%d]", path, i), elem); err != nil {
+				return err
+			}
+		}
+	case '{':
+		seen := make(map[string]bool)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := tok.(string)
+			child := joinConfigPath(path, key)
+			if seen[key] {
+				v.add(child, "given more than once; only the last one is used")
+			}
+			seen[key] = true
+
+			var elem reflect.Type
+			switch {
+			case t == nil:
+			case t.Kind() == reflect.Map:
+				elem = t.Elem()
+			case t.Kind() == reflect.Struct:
+				var found bool
+				elem, found = fieldType(t, key)
+				if !found {
+					v.add(child, "unknown field")
+				}
+			}
+			if err := v.walk(dec, child, elem); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = dec.Token() // closing delimiter
+	return err
+}
+
+// fieldType returns the type of the field of struct t that the JSON key
+// decodes into, matching names like encoding/json does.
+func fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	var fold reflect.Type
+	foldFound := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft, ok := fieldType(f.Type, key); ok {
+				return ft, true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f.Type, true
+		}
+		if !foldFound && strings.EqualFold(name, key) {
+			fold, foldFound = f.Type, true
+		}
+	}
+	if foldFound {
+		return fold, true
+	}
+	for _, legacy := range legacyFields[t] {
+		if legacy == key {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// check reports problems with the values of a decoded config.
+func (v *configValidator) check(cfg *OpenCCConfig) {
+	if cfg.Version > CurrentConfigVersion {
+		v.add("version", "%d is newer than supported version %d; upgrade opencc", cfg.Version, CurrentConfigVersion)
+	}
+	if cfg.DefaultProfile != "" && cfg.Profiles[cfg.DefaultProfile] == nil {
+		v.add("default_profile", "profile '%s' does not exist", cfg.DefaultProfile)
+	}
+	if cfg.DefaultCLI != "" && !IsValidCLI(cfg.DefaultCLI) {
+		v.add("default_cli", "unknown CLI '%s', expected one of %s", cfg.DefaultCLI, strings.Join(AvailableCLIs, ", "))
+	}
+	if cfg.WebPort < 0 || cfg.WebPort > 65535 {
+		v.add("web_port", "port %d is out of range", cfg.WebPort)
+	}
+	if cfg.ProxyPort < 0 || cfg.ProxyPort > 65535 {
+		v.add("proxy_port", "port %d is out of range", cfg.ProxyPort)
+	}
+	if cfg.BackupRetention < 0 {
+		v.add("backup_retention", "must not be negative")
+	}
+	if !IsValidSecretStore(cfg.SecretStore) {
+		v.add("secret_store", "unknown secret store '%s'", cfg.SecretStore)
+	}
+	if !IsValidProjectFiles(cfg.ProjectFiles) {
+		v.add("project_files", "unknown mode '%s', expected %s, %s or %s", cfg.ProjectFiles, ProjectFilesBindingFirst, ProjectFilesFileFirst, ProjectFilesIgnore)
+	}
+	if !cfg.StreamFailover.IsValid() {
+		v.add("stream_failover", "unknown mode '%s'", cfg.StreamFailover)
+	}
+	if cfg.AccessLog != nil && !cfg.AccessLog.Format.IsValid() {
+		v.add("access_log.format", "unknown format '%s'", cfg.AccessLog.Format)
+	}
+	if cfg.Hooks != nil {
+		v.checkHooks("hooks.request", cfg.Hooks.Request)
+		v.checkHooks("hooks.response", cfg.Hooks.Response)
+	}
+	if cfg.Redaction != nil {
+		v.checkRedaction(cfg.Redaction)
+	}
+	if cfg.RequestSize != nil && !cfg.RequestSize.Oversized.IsValid() {
+		v.add("request_size.oversized", "unknown action '%s'", cfg.RequestSize.Oversized)
+	}
+	for _, prefix := range sortedKeys(cfg.FailoverPolicies) {
+		if policy := cfg.FailoverPolicies[prefix]; !policy.IsValid() {
+			v.add(joinConfigPath("failover_policies", prefix), "unknown policy '%s'", policy)
+		}
+	}
+	v.checkListeners(cfg)
+
+	scenarios := make(map[Scenario]bool)
+	for i, cs := range cfg.Scenarios {
+		path := fmt.Sprintf("scenarios[%d]", i)
+		if !cs.IsValid() {
+			v.add(path, "needs a name that isn't a built-in scenario and valid matchers")
+		}
+		if scenarios[cs.Name] {
+			v.add(path+".name", "scenario '%s' is defined more than once", cs.Name)
+		}
+		scenarios[cs.Name] = true
+	}
+
+	for _, name := range sortedKeys(cfg.Providers) {
+		v.checkProvider(joinConfigPath("providers", name), cfg.Providers[name])
+	}
+	for _, name := range sortedKeys(cfg.Profiles) {
+		path := joinConfigPath("profiles", name)
+		v.checkProfile(path, cfg.Profiles[name], cfg.Providers, scenarios, resolveProfile(cfg.Profiles, name))
+		if pc := cfg.Profiles[name]; pc != nil && pc.Extends != "" {
+			if cfg.Profiles[pc.Extends] == nil {
+				v.add(path+".extends", "profile '%s' does not exist", pc.Extends)
+			} else if extendsCycle(cfg.Profiles, name) {
+				v.add(path+".extends", "profile '%s' extends itself through '%s'", name, pc.Extends)
+			}
+		}
+	}
+	for _, dir := range sortedKeys(cfg.ProjectBindings) {
+		b := cfg.ProjectBindings[dir]
+		path := joinConfigPath("project_bindings", dir)
+		if b == nil {
+			continue
+		}
+		if b.Profile != "" && cfg.Profiles[b.Profile] == nil {
+			v.add(path+".profile", "profile '%s' does not exist", b.Profile)
+		}
+		if b.CLI != "" && !IsValidCLI(b.CLI) {
+			v.add(path+".cli", "unknown CLI '%s'", b.CLI)
+		}
+		if b.Provider != "" && cfg.Providers[b.Provider] == nil {
+			v.add(path+".provider", "provider '%s' does not exist", b.Provider)
+		}
+	}
+}
+
+// checkListeners reports addresses that accept remote connections without
+// the credential that has to guard them, and invalid TLS settings.
+func (v *configValidator) checkListeners(cfg *OpenCCConfig) {
+	if cfg.ProxyTLS != nil && !cfg.ProxyTLS.IsValid() {
+		v.add("proxy_tls", "needs both cert_file and key_file, or neither")
+	}
+	if cfg.ProxyListen != "" && !IsLoopbackHost(cfg.ProxyListen) && cfg.ProxyAuthToken == "" {
+		v.add("proxy_listen", "'%s' accepts remote connections but proxy_auth_token is not set", cfg.ProxyListen)
+	}
+	if a := cfg.WebAccess; a != nil {
+		if a.TLS != nil && !a.TLS.IsValid() {
+			v.add("web_access.tls", "needs both cert_file and key_file, or neither")
+		}
+		if a.Listen != "" && !IsLoopbackHost(a.Listen) && cfg.WebPassword == "" {
+			v.add("web_access.listen", "'%s' accepts remote connections but no web password is set", a.Listen)
+		}
+		for i, entry := range a.AllowFrom {
+			entry = strings.TrimSpace(entry)
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				v.add(fmt.Sprintf("web_access.allow_from[%d]", i), "'%s' is not an IP address or CIDR range", entry)
+			}
+		}
+	}
+}
+
+// checkHooks reports hook commands that can't run.
+func (v *configValidator) checkHooks(path string, hooks []HookCommand) {
+	for i, h := range hooks {
+		hp := fmt.Sprintf("%s[%d]", path, i)
+		if h.Command == "" {
+			v.add(hp+".command", "is required")
+		}
+		if h.TimeoutSeconds < 0 {
+			v.add(hp+".timeout_seconds", "must not be negative")
+		}
+	}
+}
+
+// checkRedaction reports unknown actions and patterns and invalid regexes.
+func (v *configValidator) checkRedaction(rc *RedactionConfig) {
+	if !rc.Action.IsValid() {
+		v.add("redaction.action", "unknown action '%s'", rc.Action)
+	}
+	for i, name := range rc.Builtin {
+		if !slices.Contains(RedactionBuiltins, name) {
+			v.add(fmt.Sprintf("redaction.builtin[%d]", i), "unknown pattern '%s', expected one of %s", name, strings.Join(RedactionBuiltins, ", "))
+		}
+	}
+	for i, p := range rc.Patterns {
+		pp := fmt.Sprintf("redaction.patterns[%d]", i)
+		if p.Name == "" {
+			v.add(pp+".name", "is required")
+		}
+		if _, err := regexp.Compile(p.Regex); err != nil || p.Regex == "" {
+			v.add(pp+".regex", "is not a valid regular expression")
+		}
+	}
+}
+
+// checkProvider reports problems with one provider's settings.
+func (v *configValidator) checkProvider(path string, p *ProviderConfig) {
+	if p == nil {
+		v.add(path, "is empty")
+		return
+	}
+	typ := p.GetType()
+	switch typ {
+	case ProviderTypeAnthropic, ProviderTypeOpenAI, ProviderTypeOpenAIResponses, ProviderTypeGemini, ProviderTypeAzureOpenAI:
+		if p.BaseURL == "" {
+			v.add(path+".base_url", "is required")
+		}
+		if p.AuthToken == "" && p.OAuth == nil {
+			v.add(path+".auth_token", "is required unless oauth is set")
+		}
+	case ProviderTypeBedrock, ProviderTypeVertex:
+		if p.OAuth != nil {
+			v.add(path+".oauth", "is not supported for %s providers", typ)
+		}
+	case ProviderTypeLocal:
+	default:
+		v.add(path+".type", "unknown provider type '%s'", typ)
+	}
+	if p.BaseURL != "" {
+		if u, err := url.Parse(p.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add(path+".base_url", "'%s' is not an http(s) URL", p.BaseURL)
+		}
+	}
+	if p.ProxyURL != "" {
+		if err := ValidateProxyURL(p.ProxyURL); err != nil {
+			v.add(path+".proxy_url", "%v", err)
+		}
+	}
+	if err := ValidateHeaders(p.Headers); err != nil {
+		v.add(path+".headers", "%v", err)
+	}
+	if err := p.ValidateTimeouts(); err != nil {
+		v.add(path, "%v", err)
+	}
+	for i, w := range p.MaintenanceWindows {
+		if err := w.Validate(); err != nil {
+			v.add(fmt.Sprintf("%s.maintenance_windows[%d]", path, i), "%v", err)
+		}
+	}
+	if p.OAuth != nil {
+		if err := p.OAuth.Validate(); err != nil {
+			v.add(path+".oauth", "%v", err)
+		}
+	}
+	if p.Retry != nil && !p.Retry.IsValid() {
+		v.add(path+".retry", "invalid retry policy")
+	}
+	if p.DailyBudgetUSD > 0 && p.Pricing == nil {
+		v.add(path+".daily_budget_usd", "needs pricing to estimate spend")
+	}
+	if _, err := p.SystemPrompt(); err != nil {
+		v.add(path+".system_prompt_file", "%v", err)
+	}
+}
+
+// checkProfile reports problems with one profile's settings and the
+// providers and scenarios it refers to.
+func (v *configValidator) checkProfile(path string, pc *ProfileConfig, providers map[string]*ProviderConfig, custom map[Scenario]bool, resolved *ProfileConfig) {
+	if pc == nil {
+		v.add(path, "is empty")
+		return
+	}
+	listed := make(map[string]bool)
+	for i, name := range pc.Providers {
+		p := fmt.Sprintf("%s.providers[%d]", path, i)
+		if providers[name] == nil {
+			v.add(p, "provider '%s' does not exist", name)
+		}
+		if listed[name] {
+			v.add(p, "provider '%s' is listed more than once", name)
+		}
+		listed[name] = true
+	}
+	for _, scenario := range sortedKeys(pc.Routing) {
+		route := pc.Routing[scenario]
+		rp := joinConfigPath(path+".routing", string(scenario))
+		if !scenario.IsBuiltin() && !custom[scenario] {
+			v.add(rp, "unknown scenario '%s'", scenario)
+		}
+		if route == nil {
+			continue
+		}
+		for i, pr := range route.Providers {
+			if pr != nil && providers[pr.Name] == nil {
+				v.add(fmt.Sprintf("%s.providers[%d]", rp, i), "provider '%s' does not exist", pr.Name)
+			}
+		}
+	}
+	if !pc.Strategy.IsValid() {
+		v.add(path+".strategy", "unknown strategy '%s'", pc.Strategy)
+	}
+	for _, name := range sortedKeys(pc.Weights) {
+		if !slices.Contains(resolved.Providers, name) {
+			v.add(joinConfigPath(path+".weights", name), "provider '%s' is not in the profile", name)
+		}
+	}
+	if pc.Retry != nil && !pc.Retry.IsValid() {
+		v.add(path+".retry", "invalid retry policy")
+	}
+	if pc.Hedge != nil && !pc.Hedge.IsValid() {
+		v.add(path+".hedge", "invalid hedge delays")
+	}
+	for i, rule := range pc.Schedule {
+		rp := fmt.Sprintf("%s.schedule[%d]", path, i)
+		if err := rule.Window().Validate(); err != nil {
+			v.add(rp, "%v", err)
+		}
+		if len(rule.Providers) == 0 {
+			v.add(rp+".providers", "is empty")
+		}
+		for j, name := range rule.Providers {
+			if !slices.Contains(resolved.Providers, name) {
+				v.add(fmt.Sprintf("%s.providers[%d]", rp, j), "provider '%s' is not in the profile", name)
+			}
+		}
+	}
+	if pc.AutoOrder != nil && !pc.AutoOrder.IsValid() {
+		v.add(path+".auto_order", "invalid auto order settings")
+	}
+	if _, err := pc.SystemPrompt(); err != nil {
+		v.add(path+".system_prompt_file", "%v", err)
+	}
+	for _, cli := range sortedKeys(pc.EnvVars) {
+		if !IsValidCLI(cli) {
+			v.add(joinConfigPath(path+".env_vars", cli), "unknown CLI '%s'", cli)
+		}
+	}
+}
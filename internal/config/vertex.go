@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// VertexConfig holds the Google Cloud project, region and credentials of a
+// "vertex" provider. Unset values are read from the environment the way
+// Claude Code and the Google client libraries read them.
+type VertexConfig struct {
+	ProjectID       string `json:"project_id,omitempty"`       // empty = ANTHROPIC_VERTEX_PROJECT_ID, GOOGLE_CLOUD_PROJECT or the credentials' project
+	Region          string `json:"region,omitempty"`           // e.g. "us-east5", or "global"; empty = CLOUD_ML_REGION
+	CredentialsFile string `json:"credentials_file,omitempty"` // service account or user credentials JSON; empty = application default credentials
+}
+
+// Endpoint returns the Vertex AI endpoint for the region.
+func (v *VertexConfig) Endpoint() string {
+	if v.Region == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com", v.Region)
+}
+
+// Resolve returns the configuration with the credentials file, project and
+// region filled in. Without a credentials file, GOOGLE_APPLICATION_CREDENTIALS
+// or the gcloud application default credentials are used. v may be nil.
+func (v *VertexConfig) Resolve() (*VertexConfig, error) {
+	r := &VertexConfig{}
+	if v != nil {
+		*r = *v
+	}
+	if r.CredentialsFile == "" {
+		r.CredentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if r.CredentialsFile == "" {
+		r.CredentialsFile = filepath.Join(os.Getenv("HOME"), ".config", "gcloud", "application_default_credentials.json")
+	}
+	data, err := os.ReadFile(r.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: reading credentials: %w", err)
+	}
+	var creds struct {
+		ProjectID      string `json:"project_id"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("vertex: parsing %s: %w", r.CredentialsFile, err)
+	}
+
+	if r.ProjectID == "" {
+		r.ProjectID = firstEnv("ANTHROPIC_VERTEX_PROJECT_ID", "GOOGLE_CLOUD_PROJECT")
+	}
+	if r.ProjectID == "" {
+		r.ProjectID = creds.ProjectID
+	}
+	if r.ProjectID == "" {
+		r.ProjectID = creds.QuotaProjectID
+	}
+	if r.ProjectID == "" {
+		return nil, errors.New("vertex: no project configured")
+	}
+	if r.Region == "" {
+		r.Region = os.Getenv("CLOUD_ML_REGION")
+	}
+	if r.Region == "" {
+		return nil, errors.New("vertex: no region configured")
+	}
+	return r, nil
+}
@@ -97,3 +97,36 @@ func ReadPid() (int, error) {
 func RemovePid() {
 	os.Remove(PidPath())
 }
+
+// ProxyPidPath returns the path to the PID file of the shared proxy started
+// by `opencc proxy start`. There is one per config directory, as the proxy
+// listens on a fixed port.
+func ProxyPidPath() string {
+	return filepath.Join(config.ConfigDirPath(), config.ProxyPidFile)
+}
+
+// WriteProxyPid writes the shared proxy's PID file.
+func WriteProxyPid(pid int) error {
+	if err := os.MkdirAll(config.ConfigDirPath(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ProxyPidPath(), []byte(strconv.Itoa(pid)+"\n"), 0600)
+}
+
+// ReadProxyPid reads the shared proxy's PID file.
+func ReadProxyPid() (int, error) {
+	data, err := os.ReadFile(ProxyPidPath())
+	if err != nil {
+		return 0, fmt.Errorf("PID file not found")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID file: %w", err)
+	}
+	return pid, nil
+}
+
+// RemoveProxyPid removes the shared proxy's PID file.
+func RemoveProxyPid() {
+	os.Remove(ProxyPidPath())
+}
@@ -38,6 +38,15 @@ func StopDaemon() error {
 		RemovePid()
 		return fmt.Errorf("daemon is not running")
 	}
+	if err := StopProcess(pid); err != nil {
+		return err
+	}
+	RemovePid()
+	return nil
+}
+
+// StopProcess asks the process with the given PID to shut down (SIGTERM).
+func StopProcess(pid int) error {
 	proc, err := os.FindProcess(pid)
 	if err != nil {
 		return err
@@ -45,7 +54,6 @@ func StopDaemon() error {
 	if err := proc.Signal(syscall.SIGTERM); err != nil {
 		return fmt.Errorf("failed to stop daemon (PID %d): %w", pid, err)
 	}
-	RemovePid()
 	return nil
 }
 
@@ -48,6 +48,15 @@ func StopDaemon() error {
 		RemovePid()
 		return fmt.Errorf("daemon is not running")
 	}
+	if err := StopProcess(pid); err != nil {
+		return err
+	}
+	RemovePid()
+	return nil
+}
+
+// StopProcess terminates the process with the given PID.
+func StopProcess(pid int) error {
 	proc, err := os.FindProcess(pid)
 	if err != nil {
 		return err
@@ -55,7 +64,6 @@ func StopDaemon() error {
 	if err := proc.Kill(); err != nil {
 		return fmt.Errorf("failed to stop daemon (PID %d): %w", pid, err)
 	}
-	RemovePid()
 	return nil
 }
 
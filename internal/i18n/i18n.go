@@ -0,0 +1,90 @@
+// Package i18n translates user-facing TUI and CLI text. Messages are keyed
+// by their English text, so English needs no catalog and a message missing
+// from a catalog falls back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Locale identifies a message catalog.
+type Locale string
+
+const (
+	English           Locale = "en"
+	SimplifiedChinese Locale = "zh-CN"
+)
+
+// Supported lists the locales that can be selected.
+var Supported = []Locale{English, SimplifiedChinese}
+
+var catalogs = map[Locale]map[string]string{
+	SimplifiedChinese: zhCN,
+}
+
+var current atomic.Value // Locale
+
+// Parse maps a locale name such as "zh-CN", "zh_CN.UTF-8" or "en_US" to a
+// supported locale. It reports false for empty or unsupported names.
+func Parse(name string) (Locale, bool) {
+	name, _, _ = strings.Cut(name, ".") // drop the encoding
+	name, _, _ = strings.Cut(name, "@") // and the modifier
+	name = strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	switch name {
+	case "c", "posix":
+		return English, true
+	case "zh", "zh-cn", "zh-sg", "zh-hans", "zh-hans-cn":
+		return SimplifiedChinese, true
+	}
+	if name == "en" || strings.HasPrefix(name, "en-") {
+		return English, true
+	}
+	return "", false
+}
+
+// Detect picks the locale from the configured name, falling back to the
+// first of LC_ALL, LC_MESSAGES and LANG that is set, then to English.
+func Detect(configured string) Locale {
+	if l, ok := Parse(configured); ok {
+		return l
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			if l, ok := Parse(v); ok {
+				return l
+			}
+			break
+		}
+	}
+	return English
+}
+
+// SetLocale selects the locale used by T and Tf.
+func SetLocale(l Locale) {
+	current.Store(l)
+}
+
+// Current returns the selected locale.
+func Current() Locale {
+	if l, ok := current.Load().(Locale); ok {
+		return l
+	}
+	return English
+}
+
+// T returns msg translated to the current locale, or msg itself if the
+// locale has no translation for it.
+func T(msg string) string {
+	if t, ok := catalogs[Current()][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// Tf translates format and formats it with args.
+func Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
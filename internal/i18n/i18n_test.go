@@ -0,0 +1,100 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Locale
+		ok   bool
+	}{
+		{"en", English, true},
+		{"en_US.UTF-8", English, true},
+		{"C", English, true},
+		{"POSIX", English, true},
+		{"zh-CN", SimplifiedChinese, true},
+		{"zh_CN.UTF-8", SimplifiedChinese, true},
+		{"zh_CN.utf8@pinyin", SimplifiedChinese, true},
+		{"zh", SimplifiedChinese, true},
+		{"zh-Hans", SimplifiedChinese, true},
+		{"zh_TW.UTF-8", "", false},
+		{"fr_FR.UTF-8", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Parse(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		lcAll      string
+		lcMessages string
+		lang       string
+		want       Locale
+	}{
+		{"default", "", "", "", "", English},
+		{"config wins", "zh-CN", "en_US.UTF-8", "", "en_US.UTF-8", SimplifiedChinese},
+		{"config english", "en", "", "", "zh_CN.UTF-8", English},
+		{"unknown config uses env", "xx", "", "", "zh_CN.UTF-8", SimplifiedChinese},
+		{"LANG", "", "", "", "zh_CN.UTF-8", SimplifiedChinese},
+		{"LC_ALL over LANG", "", "en_US.UTF-8", "", "zh_CN.UTF-8", English},
+		{"LC_MESSAGES over LANG", "", "", "zh_CN.UTF-8", "en_US.UTF-8", SimplifiedChinese},
+		{"unsupported first set", "", "fr_FR.UTF-8", "", "zh_CN.UTF-8", English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.lang)
+			if got := Detect(tt.configured); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.configured, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale(Current())
+
+	SetLocale(English)
+	if got := T("Settings"); got != "Settings" {
+		t.Errorf("en T(Settings) = %q", got)
+	}
+
+	SetLocale(SimplifiedChinese)
+	if got := T("Settings"); got != "设置" {
+		t.Errorf("zh-CN T(Settings) = %q, want 设置", got)
+	}
+	if got := T("not in any catalog"); got != "not in any catalog" {
+		t.Errorf("missing message should fall back to English, got %q", got)
+	}
+	if got := Tf("profile '%s' not found", "work"); got != "未找到配置组 'work'" {
+		t.Errorf("Tf = %q", got)
+	}
+}
+
+var formatVerb = regexp.MustCompile(`%[a-zA-Z]`)
+
+func TestCatalogsKeepFormatVerbs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for msg, translated := range catalog {
+			want := formatVerb.FindAllString(msg, -1)
+			got := formatVerb.FindAllString(translated, -1)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, want %v", locale, translated, got, want)
+			}
+		}
+	}
+}
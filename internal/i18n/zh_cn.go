@@ -0,0 +1,171 @@
+package i18n
+
+// zhCN is the Simplified Chinese catalog. Keys are the English messages;
+// translations must keep their format verbs in the same order.
+var zhCN = map[string]string{
+	// TUI help bars
+	"↑↓ navigate • Enter edit • a add • d delete • q quit":              "↑↓ 移动 • Enter 编辑 • a 添加 • d 删除 • q 退出",
+	"↑↓ navigate • Enter select • q quit":                               "↑↓ 移动 • Enter 选择 • q 退出",
+	"↑↓ navigate • Enter detail • q quit":                               "↑↓ 移动 • Enter 详情 • q 退出",
+	"↑↓ move • Enter select • Esc cancel":                               "↑↓ 移动 • Enter 选择 • Esc 取消",
+	"↑↓ move • Enter edit • x clear • s save • Esc back":                "↑↓ 移动 • Enter 编辑 • x 清除 • s 保存 • Esc 返回",
+	"↑↓ move • Enter edit/add • d delete • Esc done":                    "↑↓ 移动 • Enter 编辑/添加 • d 删除 • Esc 完成",
+	"↑↓ reorder • Enter/Esc drop":                                       "↑↓ 调整顺序 • Enter/Esc 放下",
+	"a add • e edit • d delete • u undo • Tab switch pane • Esc back":   "a 添加 • e 编辑 • d 删除 • u 撤销 • Tab 切换面板 • Esc 返回",
+	"a add • e/Enter edit • d delete • f fallback profiles • q quit":    "a 添加 • e/Enter 编辑 • d 删除 • f 故障转移配置 • q 退出",
+	"Enter create • Esc cancel":                                         "Enter 创建 • Esc 取消",
+	"Enter edit • a new • d delete • Esc back":                          "Enter 编辑 • a 新建 • d 删除 • Esc 返回",
+	"Enter save • Esc cancel":                                           "Enter 保存 • Esc 取消",
+	"Enter select • Esc back":                                           "Enter 选择 • Esc 返回",
+	"Enter select • q cancel":                                           "Enter 选择 • q 取消",
+	"Enter/Tab next • Esc cancel":                                       "Enter/Tab 下一项 • Esc 取消",
+	"Esc/Enter back • q quit":                                           "Esc/Enter 返回 • q 退出",
+	"Space toggle • Enter confirm • Esc skip":                           "空格 选择 • Enter 确认 • Esc 跳过",
+	"Space toggle • Enter reorder/confirm • %s confirm • q cancel":      "空格 选择 • Enter 排序/确认 • %s 确认 • q 取消",
+	"Space toggle • m edit model • t threshold • Enter save • Esc back": "空格 选择 • m 编辑模型 • t 阈值 • Enter 保存 • Esc 返回",
+	"Space toggle • m edit model • t threshold • enter save • esc back": "空格 选择 • m 编辑模型 • t 阈值 • Enter 保存 • Esc 返回",
+	"Tab next • %s save • Esc cancel":                                   "Tab 下一项 • %s 保存 • Esc 取消",
+	"Tab next • Shift+Tab prev • Ctrl+S save • Esc back":                "Tab 下一项 • Shift+Tab 上一项 • Ctrl+S 保存 • Esc 返回",
+	"Tab switch section • s save • Esc back":                            "Tab 切换区域 • s 保存 • Esc 返回",
+	"Tab/←→ switch pane • ↑↓ navigate • Enter launch • Esc back":        "Tab/←→ 切换面板 • ↑↓ 移动 • Enter 启动 • Esc 返回",
+	"Type model name • enter save • esc cancel":                         "输入模型名称 • Enter 保存 • Esc 取消",
+
+	// TUI titles and labels
+	"(none)":                            "（无）",
+	"(reordering)":                      "（排序中）",
+	"%d configured":                     "已配置 %d 个",
+	"Add":                               "添加",
+	"Add Provider":                      "添加供应商",
+	"Add to Profiles":                   "添加到配置组",
+	"Choose a profile to use":           "选择要使用的配置组",
+	"Choose providers for this session": "选择本次会话使用的供应商",
+	"Choose which CLI to launch":        "选择要启动的 CLI",
+	"Configure":                         "配置",
+	"Configure provider chains per request type": "按请求类型配置供应商链",
+	"Create New Group":                           "新建配置组",
+	"Custom":                                     "自定义",
+	"Custom HTTP Headers":                        "自定义 HTTP 请求头",
+	"Custom Environment Variables":               "自定义环境变量",
+	"Default CLI":                                "默认 CLI",
+	"Default Profile":                            "默认配置组",
+	"Default Providers":                          "默认供应商",
+	"Delete '%s'? (y/n)":                         "删除 '%s'？(y/n)",
+	"Detail":                                     "详情",
+	"Edit Provider: %s":                          "编辑供应商：%s",
+	"Edit Scenario: %s":                          "编辑场景：%s",
+	"Enter Header Name":                          "输入请求头名称",
+	"Enter Variable Name":                        "输入变量名",
+	"Enter to configure scenario":                "按 Enter 配置场景",
+	"Environment Switcher":                       "环境切换器",
+	"Environment Variables":                      "环境变量",
+	"Error: ":                                    "错误：",
+	"Group: %s":                                  "配置组：%s",
+	"Groups":                                     "配置组",
+	"HTTP Headers":                               "HTTP 请求头",
+	"Invalid port number":                        "端口号无效",
+	"Language":                                   "语言",
+	"Launch":                                     "启动",
+	"Leave empty to use provider's model mapping": "留空则使用供应商的模型映射",
+	"%d tokens":                              "%d 个 token",
+	"Long-Context Threshold":                 "长上下文阈值",
+	"Long-context threshold: %s (t to edit)": "长上下文阈值：%s（按 t 编辑）",
+	"Requests of this scenario with at least this many tokens go to %s; empty for none": "此场景中不少于该 token 数的请求将转到 %s；留空表示不设置",
+	"Extends '%s': its providers come first, and its routes apply unless set here":      "继承 '%s'：其供应商排在前面，其路由在此处未设置时生效",
+	"Model Override":           "模型覆盖",
+	"Models:":                  "模型：",
+	"No providers configured.": "尚未配置供应商。",
+	"No providers configured. Create one to get started:": "尚未配置供应商。创建一个以开始使用：",
+	"Port must be between 1024 and 65535":                 "端口必须在 1024 到 65535 之间",
+	"Preflight Check":                                     "启动前检查",
+	"Profile not found":                                   "未找到配置组",
+	"Profile: %s  |  CLI: %s":                             "配置组：%s  |  CLI：%s",
+	"Project Binding":                                     "项目绑定",
+	"Provider Settings":                                   "供应商设置",
+	"Provider not found":                                  "未找到供应商",
+	"Providers":                                           "供应商",
+	"Providers:":                                          "供应商：",
+	"Quit":                                                "退出",
+	"Routing: %s":                                         "路由：%s",
+	"Run 'opencc config add provider' to create one.": "运行 'opencc config add provider' 创建一个。",
+	"Saved":                                    "已保存",
+	"Scenario Routes":                          "场景路由",
+	"Scenario Routing":                         "场景路由",
+	"Scenario Routing:":                        "场景路由：",
+	"Select CLI":                               "选择 CLI",
+	"Select Profile":                           "选择配置组",
+	"Select Providers":                         "选择供应商",
+	"Select an item to view details":           "选择一项以查看详情",
+	"Select profiles for this provider":        "为该供应商选择配置组",
+	"Select provider group:":                   "选择供应商配置组：",
+	"Sent with every request to this provider": "随每个发往该供应商的请求发送",
+	"Settings":                                 "设置",
+	"Settings saved!":                          "设置已保存！",
+	"Space to toggle, Enter to confirm, Esc to skip":   "空格选择，Enter 确认，Esc 跳过",
+	"Space to toggle, Enter to reorder":                "空格选择，Enter 调整顺序",
+	"Strict Env":                                       "严格环境检查",
+	"These are passed as x-env-* headers to the proxy": "这些变量以 x-env-* 请求头传给代理",
+	"Undid the last change (%s)":                       "已撤销上一次修改（%s）",
+	"Used in profiles:":                                "所属配置组：",
+	"Web UI Port":                                      "Web UI 端口",
+	"auth token is required":                           "必须填写认证令牌",
+	"base URL is required":                             "必须填写 Base URL",
+	"name is required":                                 "必须填写名称",
+	"none":                                             "无",
+	"opencc configurations":                            "opencc 配置",
+
+	// CLI errors
+	"%d of %d provider(s) failed":                                        "%d/%d 个供应商失败",
+	"%d problem(s) found in %s":                                          "发现 %d 个问题（%s）",
+	"%s missing base_url or auth_token":                                  "%s 缺少 base_url 或 auth_token",
+	"%s not found in PATH: %w":                                           "在 PATH 中找不到 %s：%w",
+	"'%s' needs an interactive terminal; not available in headless mode": "'%s' 需要交互式终端，无头模式下不可用",
+	"--since must be a positive duration":                                "--since 必须是正数时长",
+	"aborted":                                                            "已中止",
+	"cannot listen on %s (port in use?); choose another with --port or proxy_port: %w": "无法监听 %s（端口被占用？）；请通过 --port 或 proxy_port 指定其他端口：%w",
+	"configuration '%s' not found": "未找到配置 '%s'",
+	"default profile '%s' has no providers configured; pass -p <profile> or configure providers": "默认配置组 '%s' 没有配置供应商；请使用 -p <配置组> 或先配置供应商",
+	"doctor found %d problem(s)":                                                                   "doctor 发现 %d 个问题",
+	"drill sends a real request; pass --yes to confirm in headless mode":                           "演练会发送真实请求；无头模式下请使用 --yes 确认",
+	"failover failed: no fallback provider answered (status %d)":                                   "故障转移失败：没有备用供应商响应（状态码 %d）",
+	"invalid --listen address '%s'":                                                                "--listen 地址 '%s' 无效",
+	"invalid --listen port '%s'":                                                                   "--listen 端口 '%s' 无效",
+	"invalid URL for provider %s: %w":                                                              "供应商 %s 的 URL 无效：%w",
+	"invalid header '%s', expected name:value":                                                     "请求头 '%s' 无效，应为 name:value",
+	"invalid path '%s': must start with '/'":                                                       "路径 '%s' 无效：必须以 '/' 开头",
+	"invalid proxy port %d":                                                                        "代理端口 %d 无效",
+	"invalid request template '%s': %w":                                                            "请求模板 '%s' 无效：%w",
+	"invalid template name '%s': use letters, digits, '.', '_' and '-'":                            "模板名 '%s' 无效：只能使用字母、数字、'.'、'_' 和 '-'",
+	"invalid web_access.allow_from entry '%s'":                                                     "web_access.allow_from 条目 '%s' 无效",
+	"no fallback provider to fail over to":                                                         "没有可故障转移的备用供应商",
+	"no %s found in %s or its parents":                                                             "未找到 %s（已查找 %s 及其上级目录）",
+	"no profile given; pass -p <profile> in headless mode":                                         "未指定配置组；无头模式下请使用 -p <配置组>",
+	"no valid providers":                                                                           "没有可用的供应商",
+	"no valid providers remaining. Run 'opencc config' to set up providers":                        "没有剩余可用的供应商。请运行 'opencc config' 配置供应商",
+	"nothing to undo":                                                                              "没有可撤销的操作",
+	"password must not be empty":                                                                   "密码不能为空",
+	"passwords don't match":                                                                        "两次输入的密码不一致",
+	"profile '%s' has no fallback provider to fail over to":                                        "配置组 '%s' 没有可故障转移的备用供应商",
+	"profile '%s' has no providers configured":                                                     "配置组 '%s' 没有配置供应商",
+	"profile '%s' not found":                                                                       "未找到配置组 '%s'",
+	"profile '%s' references missing provider(s): %s":                                              "配置组 '%s' 引用了不存在的供应商：%s",
+	"proxy_listen '%s' accepts remote connections; set proxy_auth_token to require a key":          "proxy_listen '%s' 接受远程连接；请设置 proxy_auth_token 以要求密钥",
+	"proxy_tls needs both cert_file and key_file, or neither for a self-signed certificate":        "proxy_tls 需要同时设置 cert_file 和 key_file，或都不设置以使用自签名证书",
+	"prompt is empty":                                                                              "提示词为空",
+	"provider %s: %w":                                                                              "供应商 %s：%w",
+	"provider '%s' not found":                                                                      "未找到供应商 '%s'",
+	"refusing to start (strict env): %s":                                                           "拒绝启动（严格环境检查）：%s",
+	"request '%s' failed with status %d":                                                           "请求 '%s' 失败，状态码 %d",
+	"request body is not valid JSON":                                                               "请求体不是有效的 JSON",
+	"request template '%s' not found":                                                              "未找到请求模板 '%s'",
+	"shared proxy exited on startup; see %s":                                                       "共享代理启动时退出；请查看 %s",
+	"shared proxy started but did not become ready; see %s":                                        "共享代理已启动但未就绪；请查看 %s",
+	"specify a positive --for duration or --clear":                                                 "请指定正数的 --for 时长或使用 --clear",
+	"specify a profile name and/or --cli flag":                                                     "请指定配置组名称和/或 --cli 参数",
+	"the config changed since; restore with 'opencc config backups' instead":                       "配置已被其他修改；请改用 'opencc config backups' 恢复",
+	"unknown log level '%s', expected info, warn or error":                                         "未知日志级别 '%s'，应为 info、warn 或 error",
+	"use either a provider or --profile, not both":                                                 "供应商和 --profile 只能指定其一",
+	"use either --provider or --profile, not both":                                                 "--provider 和 --profile 只能使用其一",
+	"use either --providers or --profile, not both":                                                "--providers 和 --profile 只能使用其一",
+	"web_access.listen '%s' accepts remote connections; set a password with 'opencc web password'": "web_access.listen '%s' 接受远程连接；请用 'opencc web password' 设置密码",
+	"web_access.tls needs both cert_file and key_file, or neither for a self-signed certificate":   "web_access.tls 需要同时设置 cert_file 和 key_file，或都不设置以使用自签名证书",
+}
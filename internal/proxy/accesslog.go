@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// DefaultAccessLogFile is the access log's file name inside the config dir.
+const DefaultAccessLogFile = "access.log"
+
+// clfTimeFormat is the timestamp layout of the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogger writes one line per proxied request, in Common Log Format or
+// JSON, for standard HTTP analytics tooling. It is separate from the
+// diagnostic proxy.log.
+type AccessLogger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format config.AccessLogFormat
+}
+
+// accessEntry is one access log record. It is also the JSON line format.
+type accessEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Client     string    `json:"client,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Protocol   string    `json:"protocol"`
+	Provider   string    `json:"provider"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// NewAccessLogger opens (or creates) the access log at path for appending.
+func NewAccessLogger(path string, format config.AccessLogFormat) (*AccessLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open access log: %w", err)
+	}
+	return &AccessLogger{out: f, format: format}, nil
+}
+
+// NewAccessLoggerWriter returns an access logger that writes to w, e.g. to
+// inspect a single in-process request.
+func NewAccessLoggerWriter(w io.Writer, format config.AccessLogFormat) *AccessLogger {
+	return &AccessLogger{out: w, format: format}
+}
+
+// Close closes the access log file, if the logger writes to one.
+func (l *AccessLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// log writes the record for a finished request.
+func (l *AccessLogger) log(e accessEntry) {
+	var line []byte
+	if l.format == config.AccessLogJSON {
+		line, _ = json.Marshal(e)
+		line = append(line, '\n')
+	} else {
+		line = []byte(formatCLF(e))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// formatCLF renders an entry as a Common Log Format line, with the client in
+// the ident field, followed by the provider and the duration in milliseconds.
+func formatCLF(e accessEntry) string {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		host = "-"
+	}
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = fmt.Sprintf("%d", e.Bytes)
+	}
+	provider := e.Provider
+	if provider == "" {
+		provider = "-"
+	}
+	client := e.Client
+	if client == "" {
+		client = "-"
+	}
+	return fmt.Sprintf("%s %s - [%s] \"%s %s %s\" %d %s \"%s\" %d\n",
+		host, client, e.Time.Format(clfTimeFormat), e.Method, e.Path, e.Protocol,
+		e.Status, bytes, provider, e.DurationMs)
+}
+
+// accessRecorder captures the status, size and serving provider of a
+// response for the access log.
+type accessRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	provider    string
+	wroteHeader bool
+}
+
+func (a *accessRecorder) WriteHeader(code int) {
+	if !a.wroteHeader {
+		a.status = code
+		a.wroteHeader = true
+	}
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *accessRecorder) Write(b []byte) (int, error) {
+	if !a.wroteHeader {
+		a.WriteHeader(http.StatusOK)
+	}
+	n, err := a.ResponseWriter.Write(b)
+	a.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses streaming through the recorder.
+func (a *accessRecorder) Flush() {
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// recordProvider notes which provider served the response, if w is recording.
+func recordProvider(w http.ResponseWriter, provider string) {
+	if c, ok := w.(*cacheRecorder); ok {
+		c.provider = provider
+		w = c.ResponseWriter
+	}
+	if a, ok := w.(*accessRecorder); ok {
+		a.provider = provider
+	}
+}
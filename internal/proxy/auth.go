@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthHeader carries the key clients present to a proxy with an AuthToken.
+const AuthHeader = "X-OpenCC-Key"
+
+const errTypeAuthentication = "authentication_error"
+
+// authorized reports whether r presents the server's AuthToken, in
+// AuthHeader or as the client's API key, so that CLIs which can only set an
+// API key can use the proxy too. Every request is authorized when the
+// server has no AuthToken.
+func (s *ProxyServer) authorized(r *http.Request) bool {
+	if s.AuthToken == "" {
+		return true
+	}
+	keys := []string{
+		r.Header.Get(AuthHeader),
+		r.Header.Get("x-api-key"),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+	}
+	for _, key := range keys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.AuthToken)) == 1 {
+			return true
+		}
+	}
+	return false
+}
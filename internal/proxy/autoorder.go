@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"slices"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// ProviderStats summarizes a provider's recent attempts.
+type ProviderStats struct {
+	Provider     string  `json:"provider"`
+	Requests     int     `json:"requests"`       // attempts that got a response or failed
+	Successes    int     `json:"successes"`      // attempts answered 2xx or 3xx
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // mean time to response headers of successful attempts
+}
+
+// SuccessRate returns the fraction of attempts that succeeded.
+func (ps ProviderStats) SuccessRate() float64 {
+	if ps.Requests == 0 {
+		return 0
+	}
+	return float64(ps.Successes) / float64(ps.Requests)
+}
+
+// OptimizeProviderOrder returns order re-arranged by provider statistics.
+// A provider only overtakes the one ahead of it when it is clearly better:
+// its success rate leads by the configured gain, or the rates are within
+// that gain and it is faster by the latency gain. The dead band keeps the
+// order from flapping between providers of similar quality. Providers with
+// too few attempts, and the first provider when pinned, stay where they are.
+func OptimizeProviderOrder(order []string, stats map[string]ProviderStats, ao *config.AutoOrderConfig) []string {
+	ordered := slices.Clone(order)
+	start := 0
+	if ao.PinFirst {
+		start = 1
+	}
+	// Only adjacent swaps: "clearly better" isn't transitive, so a full sort
+	// would be ill-defined.
+	for pass := 0; pass < len(ordered); pass++ {
+		swapped := false
+		for i := start; i+1 < len(ordered); i++ {
+			if clearlyBetter(stats[ordered[i+1]], stats[ordered[i]], ao) {
+				ordered[i], ordered[i+1] = ordered[i+1], ordered[i]
+				swapped = true
+			}
+		}
+		if !swapped {
+			break
+		}
+	}
+	return ordered
+}
+
+// clearlyBetter reports whether b should be tried before a.
+func clearlyBetter(b, a ProviderStats, ao *config.AutoOrderConfig) bool {
+	minRequests := ao.MinRequestsOrDefault()
+	if a.Requests < minRequests || b.Requests < minRequests {
+		return false
+	}
+	ar, br := a.SuccessRate(), b.SuccessRate()
+	gain := ao.SuccessGain()
+	if br >= ar+gain {
+		return true
+	}
+	if br <= ar-gain || a.AvgLatencyMs <= 0 || b.AvgLatencyMs <= 0 {
+		return false
+	}
+	return b.AvgLatencyMs <= a.AvgLatencyMs*(1-ao.LatencyGain())
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Model classes an Azure OpenAI provider can name deployments for, chosen
+// like the provider's model mappings.
+const (
+	modelClassReasoning = "reasoning"
+	modelClassHaiku     = "haiku"
+	modelClassOpus      = "opus"
+	modelClassSonnet    = "sonnet"
+	modelClassDefault   = "default"
+)
+
+// modelClass returns the class of a request for model: reasoning if it
+// enables thinking, else the Claude family in the model name.
+func modelClass(model string, body map[string]interface{}) string {
+	lower := strings.ToLower(model)
+	switch {
+	case hasThinkingEnabled(body):
+		return modelClassReasoning
+	case strings.Contains(lower, "haiku"):
+		return modelClassHaiku
+	case strings.Contains(lower, "opus"):
+		return modelClassOpus
+	case strings.Contains(lower, "sonnet"):
+		return modelClassSonnet
+	}
+	return modelClassDefault
+}
+
+// azureDeployment returns the Azure OpenAI deployment a request is sent to
+// on p: the one for the model it is sent with, after the override or model
+// mapping, or for the class of the model the client asked for.
+func (s *ProxyServer) azureDeployment(body *parsedRequest, p *Provider, modelOverride string) string {
+	model := modelOverride
+	if model == "" {
+		model = s.mapModel(body.model, body.data, p)
+	}
+	return p.Azure.Deployment(model, modelClass(body.model, body.data))
+}
+
+// azureQuery returns the query string of requests to an Azure OpenAI
+// provider, which carries the API version.
+func (p *Provider) azureQuery() string {
+	return url.Values{"api-version": {p.Azure.GetAPIVersion()}}.Encode()
+}
@@ -0,0 +1,314 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// bedrockService is the SigV4 service name of the Bedrock runtime API.
+const bedrockService = "bedrock"
+
+// bedrockRegion returns the AWS region of a "bedrock" provider.
+func (p *Provider) bedrockRegion() string {
+	if p.Bedrock == nil {
+		return ""
+	}
+	return p.Bedrock.Region
+}
+
+// signAWSRequest adds an AWS Signature Version 4 Authorization header to req,
+// signing its method, path, query, host, date and content type along with a
+// hash of body.
+func signAWSRequest(req *http.Request, body []byte, creds *config.BedrockConfig, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	headers := map[string]string{"host": host, "x-amz-date": amzDate}
+	for _, name := range []string{"Content-Type", "X-Amz-Security-Token"} {
+		if v := req.Header.Get(name); v != "" {
+			headers[strings.ToLower(name)] = strings.TrimSpace(v)
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalPath(req.URL.EscapedPath()),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + creds.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, creds.Region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsCanonicalPath encodes each segment of an escaped path again, as SigV4
+// does for every service but S3.
+func awsCanonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery returns the query parameters sorted and encoded for
+// signing.
+func awsCanonicalQuery(query map[string][]string) string {
+	var params []string
+	for k, vs := range query {
+		for _, v := range vs {
+			params = append(params, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes every byte of s but the RFC 3986 unreserved
+// characters.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// bedrockResponse adapts a Bedrock response to what the Anthropic API sends:
+// event streams in the AWS binary framing become server-sent events, and
+// errors become Anthropic error bodies.
+func bedrockResponse(resp *http.Response) {
+	switch {
+	case strings.HasPrefix(resp.Header.Get("Content-Type"), "application/vnd.amazon.eventstream"):
+		resp.Body = &bedrockEventStream{body: resp.Body}
+		resp.Header.Set("Content-Type", "text/event-stream")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+	case resp.StatusCode >= 400:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if err != nil {
+			return
+		}
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			errType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+			body, _ = json.Marshal(bedrockError(errType, e.Message))
+			resp.Header.Set("Content-Type", "application/json")
+		}
+		resp.Body = readCloser{bytes.NewReader(body), resp.Body}
+		resp.Header.Set("Content-Length", fmt.Sprint(len(body)))
+		resp.ContentLength = int64(len(body))
+	}
+}
+
+// bedrockError returns the Anthropic error for a Bedrock exception.
+func bedrockError(exception, message string) map[string]interface{} {
+	errType := "api_error"
+	switch strings.ToLower(exception) {
+	case "validationexception":
+		errType = "invalid_request_error"
+	case "accessdeniedexception", "unrecognizedclientexception":
+		errType = "permission_error"
+	case "resourcenotfoundexception":
+		errType = "not_found_error"
+	case "throttlingexception", "servicequotaexceededexception":
+		errType = "rate_limit_error"
+	case "serviceunavailableexception", "modelnotreadyexception":
+		errType = "overloaded_error"
+	}
+	return map[string]interface{}{
+		"type":  "error",
+		"error": map[string]interface{}{"type": errType, "message": message},
+	}
+}
+
+// bedrockEventStream reads a Bedrock response stream, a sequence of AWS
+// event stream messages whose chunks each carry one Anthropic stream event,
+// as server-sent events.
+type bedrockEventStream struct {
+	body io.ReadCloser
+	buf  bytes.Buffer // converted events not yet read
+}
+
+// errBadEventStream is returned for a malformed event stream message.
+var errBadEventStream = errors.New("malformed AWS event stream")
+
+func (s *bedrockEventStream) Read(p []byte) (int, error) {
+	for s.buf.Len() == 0 {
+		headers, payload, err := readEventStreamMessage(s.body)
+		if err != nil {
+			return 0, err
+		}
+		s.buf.Write(bedrockEvent(headers, payload))
+	}
+	return s.buf.Read(p)
+}
+
+func (s *bedrockEventStream) Close() error {
+	return s.body.Close()
+}
+
+// bedrockEvent returns the server-sent event for one event stream message:
+// the Anthropic event of a chunk, or an error event for an exception.
+func bedrockEvent(headers map[string]string, payload []byte) []byte {
+	var data []byte
+	switch headers[":message-type"] {
+	case "event":
+		var chunk struct {
+			Bytes []byte `json:"bytes"`
+		}
+		if headers[":event-type"] != "chunk" || json.Unmarshal(payload, &chunk) != nil {
+			return nil
+		}
+		data = chunk.Bytes
+	default:
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(payload, &e)
+		exception := headers[":exception-type"]
+		if exception == "" {
+			exception = headers[":error-code"]
+		}
+		if e.Message == "" {
+			e.Message = headers[":error-message"]
+		}
+		data, _ = json.Marshal(bedrockError(exception, e.Message))
+	}
+	var ev struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &ev) != nil || ev.Type == "" {
+		return nil
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", ev.Type, data))
+}
+
+// readEventStreamMessage reads one message of the AWS event stream encoding:
+// a prelude with the total and headers lengths and its checksum, the headers,
+// the payload and a checksum of the whole message. Only string headers are
+// returned.
+func readEventStreamMessage(r io.Reader) (map[string]string, []byte, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errBadEventStream
+		}
+		return nil, nil, err
+	}
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) ||
+		total < 16 || total > 16<<20 || headersLen > total-16 {
+		return nil, nil, errBadEventStream
+	}
+	msg := make([]byte, total)
+	copy(msg, prelude[:])
+	if _, err := io.ReadFull(r, msg[12:]); err != nil {
+		return nil, nil, errBadEventStream
+	}
+	if crc32.ChecksumIEEE(msg[:total-4]) != binary.BigEndian.Uint32(msg[total-4:]) {
+		return nil, nil, errBadEventStream
+	}
+
+	headers := make(map[string]string)
+	h := msg[12 : 12+headersLen]
+	for len(h) > 0 {
+		nameLen := int(h[0])
+		if len(h) < 2+nameLen {
+			return nil, nil, errBadEventStream
+		}
+		name := string(h[1 : 1+nameLen])
+		valueType := h[1+nameLen]
+		h = h[2+nameLen:]
+		var size int
+		switch valueType {
+		case 0, 1: // bool
+		case 2: // byte
+			size = 1
+		case 3: // short
+			size = 2
+		case 4: // int
+			size = 4
+		case 5, 8: // long, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // bytes, string
+			if len(h) < 2 {
+				return nil, nil, errBadEventStream
+			}
+			size = 2 + int(binary.BigEndian.Uint16(h))
+		default:
+			return nil, nil, errBadEventStream
+		}
+		if len(h) < size {
+			return nil, nil, errBadEventStream
+		}
+		if valueType == 7 {
+			headers[name] = string(h[2:size])
+		}
+		h = h[size:]
+	}
+	return headers, msg[12+headersLen : total-4], nil
+}
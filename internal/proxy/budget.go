@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// budgetSpend is a provider's usage in the current budget windows: the
+// local calendar day for cost and the local calendar month for tokens.
+// The zero value is ready to use.
+type budgetSpend struct {
+	mu          sync.Mutex
+	day         string // window of dayCost, as "2006-01-02"
+	dayCost     float64
+	month       string // window of monthTokens, as "2006-01"
+	monthTokens int64
+}
+
+// roll starts new windows when now is past the current ones.
+func (b *budgetSpend) roll(now time.Time) {
+	if day := now.Format("2006-01-02"); b.day != day {
+		b.day = day
+		b.dayCost = 0
+	}
+	if month := now.Format("2006-01"); b.month != month {
+		b.month = month
+		b.monthTokens = 0
+	}
+}
+
+func (b *budgetSpend) add(now time.Time, tokens int64, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	b.dayCost += cost
+	b.monthTokens += tokens
+}
+
+func (b *budgetSpend) totals(now time.Time) (dayCost float64, monthTokens int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	return b.dayCost, b.monthTokens
+}
+
+// hasBudget reports whether the provider has a spending or token limit.
+func (p *Provider) hasBudget() bool {
+	return p.DailyBudgetUSD > 0 || p.MonthlyTokenLimit > 0
+}
+
+// recordSpend counts a response's token usage against the provider's
+// budgets. Cost needs the provider's pricing; without it only tokens count.
+func (p *Provider) recordSpend(now time.Time, inputTokens, outputTokens int) {
+	if !p.hasBudget() || (inputTokens <= 0 && outputTokens <= 0) {
+		return
+	}
+	cost, _ := p.estimatedCost(requestNeeds{inputTokens: inputTokens, outputTokens: outputTokens})
+	p.spend.add(now, int64(inputTokens+outputTokens), cost)
+}
+
+// budgetReason returns which budget the provider has used up at now, or ""
+// if it may still be used.
+func (p *Provider) budgetReason(now time.Time) string {
+	if !p.hasBudget() {
+		return ""
+	}
+	dayCost, monthTokens := p.spend.totals(now)
+	if p.DailyBudgetUSD > 0 && dayCost >= p.DailyBudgetUSD {
+		return fmt.Sprintf("daily budget of $%.2f reached", p.DailyBudgetUSD)
+	}
+	if p.MonthlyTokenLimit > 0 && monthTokens >= p.MonthlyTokenLimit {
+		return fmt.Sprintf("monthly limit of %d tokens reached", p.MonthlyTokenLimit)
+	}
+	return ""
+}
+
+// LoadBudgetSpend counts the usage logged earlier in the current day and
+// month against the providers' budgets, so a restarted proxy doesn't start
+// them from zero.
+func (s *ProxyServer) LoadBudgetSpend(ldb *LogDB, now time.Time) error {
+	var budgeted []*Provider
+	for _, p := range s.allProviders() {
+		if p.hasBudget() {
+			budgeted = append(budgeted, p)
+		}
+	}
+	if len(budgeted) == 0 {
+		return nil
+	}
+
+	year, month, day := now.Date()
+	today, err := ldb.ProviderMetrics(time.Date(year, month, day, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return err
+	}
+	thisMonth, err := ldb.ProviderMetrics(time.Date(year, month, 1, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range budgeted {
+		for _, m := range today {
+			if m.Provider == p.Name {
+				cost, _ := p.estimatedCost(requestNeeds{inputTokens: int(m.InputTokens), outputTokens: int(m.OutputTokens)})
+				p.spend.add(now, 0, cost)
+			}
+		}
+		for _, m := range thisMonth {
+			if m.Provider == p.Name {
+				p.spend.add(now, m.InputTokens+m.OutputTokens, 0)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheHeader set to "bypass" on a request skips the response cache. On
+// responses to cacheable requests it reports "hit", "miss" or "bypass".
+const CacheHeader = "X-OpenCC-Cache"
+
+// maxCachedResponseBytes is the largest response body kept in the cache.
+const maxCachedResponseBytes = 1 << 20
+
+// ResponseCache keeps successful responses to non-streaming POST requests on
+// a set of paths, so identical requests sent again to the same provider chain
+// are answered without reaching a provider. The least recently used response
+// is evicted once it holds its maximum number of entries.
+type ResponseCache struct {
+	ttl   time.Duration
+	size  int
+	paths map[string]bool
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key → element of lru
+	lru     *list.List               // *cachedResponse, most recently used first
+}
+
+type cachedResponse struct {
+	key      string
+	status   int
+	header   http.Header
+	body     []byte
+	provider string
+	expires  time.Time
+}
+
+// NewResponseCache returns a cache keeping up to size responses to requests
+// on paths for ttl each.
+func NewResponseCache(ttl time.Duration, size int, paths []string) *ResponseCache {
+	c := &ResponseCache{
+		ttl:     ttl,
+		size:    size,
+		paths:   make(map[string]bool, len(paths)),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+	for _, p := range paths {
+		c.paths[p] = true
+	}
+	return c
+}
+
+// cacheable reports whether the response to r may be cached.
+func (c *ResponseCache) cacheable(r *http.Request, req *parsedRequest) bool {
+	if r.Method != http.MethodPost || !c.paths[r.URL.Path] || req.data == nil {
+		return false
+	}
+	streaming, _ := req.data["stream"].(bool)
+	return !streaming
+}
+
+// cacheKey identifies a request by its path, body and the provider chain
+// (with model overrides) it would be sent to.
+func cacheKey(r *http.Request, req *parsedRequest, providers []*Provider, modelOverrides map[string]string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", r.URL.Path, r.URL.RawQuery)
+	for _, p := range providers {
+		fmt.Fprintf(h, "%s=%s\x00", p.Name, modelOverrides[p.Name])
+	}
+	h.Write(req.raw)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the unexpired response stored under key, or nil.
+func (c *ResponseCache) get(key string, now time.Time) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*cachedResponse)
+	if !now.Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return entry
+}
+
+// put stores entry, evicting the least recently used responses over size.
+func (c *ResponseCache) put(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[entry.key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// store caches the response rec recorded if it is a complete success.
+func (c *ResponseCache) store(rec *cacheRecorder, now time.Time) {
+	if rec.status != http.StatusOK || rec.skip || strings.HasPrefix(rec.header.Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	c.put(&cachedResponse{
+		key:      rec.key,
+		status:   rec.status,
+		header:   rec.header,
+		body:     rec.body.Bytes(),
+		provider: rec.provider,
+		expires:  now.Add(c.ttl),
+	})
+}
+
+// serveCached answers r from the response cache when it holds a response for
+// it. Otherwise, if r's response may be cached, it returns a recorder wrapping
+// w for the response to be written through and stored from.
+func (s *ProxyServer) serveCached(w http.ResponseWriter, r *http.Request, req *parsedRequest, providers []*Provider, modelOverrides map[string]string, bypass bool) (*cacheRecorder, bool) {
+	if !s.Cache.cacheable(r, req) {
+		return nil, false
+	}
+	if bypass {
+		w.Header().Set(CacheHeader, "bypass")
+		return nil, false
+	}
+	key := cacheKey(r, req, providers, modelOverrides)
+	entry := s.Cache.get(key, time.Now())
+	s.metrics.cacheLookup(entry != nil)
+	if entry == nil {
+		return &cacheRecorder{ResponseWriter: w, key: key}, false
+	}
+
+	s.Logger.Printf("[cache] hit for %s %s (from %s)", r.Method, r.URL.Path, entry.provider)
+	for k, v := range entry.header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.Header().Set(CacheHeader, "hit")
+	recordProvider(w, entry.provider)
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+	return nil, true
+}
+
+// cacheRecorder writes a response through to the client while keeping a copy
+// for the response cache.
+type cacheRecorder struct {
+	http.ResponseWriter
+	key      string
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	provider string
+	skip     bool // body too large or not fully delivered
+}
+
+func (c *cacheRecorder) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+		c.Header().Set(CacheHeader, "miss")
+		c.header = c.Header().Clone()
+		for _, h := range []string{RequestIDHeader, UsageWarningHeader, CacheHeader} {
+			c.header.Del(h)
+		}
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *cacheRecorder) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	n, err := c.ResponseWriter.Write(b)
+	switch {
+	case c.skip:
+	case err != nil || c.body.Len()+n > maxCachedResponseBytes:
+		c.skip = true
+		c.body = bytes.Buffer{}
+	default:
+		c.body.Write(b[:n])
+	}
+	return n, err
+}
+
+// Flush keeps streaming responses streaming through the recorder.
+func (c *cacheRecorder) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
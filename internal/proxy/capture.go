@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCaptureDir is the capture directory's name inside the config dir.
+const DefaultCaptureDir = "captures"
+
+// CaptureIndexFile lists the captures in a capture directory, one JSON line
+// each, in the order they were written.
+const CaptureIndexFile = "index.jsonl"
+
+// redactedValue replaces the values of credential headers in captures.
+const redactedValue = "[REDACTED]"
+
+// Capturer writes each provider attempt's request and response to a file,
+// with credentials redacted, so transform and failover bugs can be reported
+// with the exact payloads. Files hold prompts, so they are private to the
+// user.
+type Capturer struct {
+	dir          string
+	maxBodyBytes int // 0 = whole bodies
+
+	mu  sync.Mutex
+	seq int
+}
+
+// captureMessage is a captured request or response.
+type captureMessage struct {
+	Method    string              `json:"method,omitempty"`
+	URL       string              `json:"url,omitempty"`
+	Status    int                 `json:"status,omitempty"`
+	Headers   map[string][]string `json:"headers"`
+	Body      string              `json:"body"`
+	BodyBytes int                 `json:"body_bytes"`
+	Truncated bool                `json:"truncated,omitempty"`
+}
+
+// captureRecord is the content of one capture file.
+type captureRecord struct {
+	Time       time.Time       `json:"time"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Provider   string          `json:"provider"`
+	Request    captureMessage  `json:"request"`
+	Response   *captureMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// CaptureIndexEntry is one line of the capture index.
+type CaptureIndexEntry struct {
+	Time       time.Time `json:"time"`
+	File       string    `json:"file"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Provider   string    `json:"provider"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// NewCapturer returns a capturer writing to dir, creating it if needed.
+// Bodies longer than maxBodyBytes are cut; 0 keeps whole bodies.
+func NewCapturer(dir string, maxBodyBytes int) (*Capturer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create capture dir: %w", err)
+	}
+	return &Capturer{dir: dir, maxBodyBytes: maxBodyBytes}, nil
+}
+
+// Dir returns the directory captures are written to.
+func (c *Capturer) Dir() string {
+	return c.dir
+}
+
+// capture records an attempt sent as req with body. A failed attempt is
+// written right away; otherwise the response body is recorded as the proxy
+// reads it, and the capture is written when it is closed.
+func (c *Capturer) capture(r *http.Request, p *Provider, req *http.Request, body []byte, resp *http.Response, err error, start time.Time) {
+	rec := &captureRecord{
+		Time:      start,
+		RequestID: requestIDOf(r),
+		Provider:  p.Name,
+		Request: captureMessage{
+			Method:  req.Method,
+			URL:     redactURL(req.URL.String()),
+			Headers: redactHeaders(req.Header),
+		},
+	}
+	c.setBody(&rec.Request, body, len(body))
+	if err != nil {
+		rec.Error = err.Error()
+		rec.DurationMs = time.Since(start).Milliseconds()
+		c.write(rec, req)
+		return
+	}
+	rec.Response = &captureMessage{Status: resp.StatusCode, Headers: redactHeaders(resp.Header)}
+	resp.Body = &captureBody{ReadCloser: resp.Body, limit: c.maxBodyBytes, done: func(data []byte, n int) {
+		c.setBody(rec.Response, data, n)
+		rec.DurationMs = time.Since(start).Milliseconds()
+		c.write(rec, req)
+	}}
+}
+
+// setBody stores data, the first bytes of a body n bytes long, on m.
+func (c *Capturer) setBody(m *captureMessage, data []byte, n int) {
+	if c.maxBodyBytes > 0 && len(data) > c.maxBodyBytes {
+		data = data[:c.maxBodyBytes]
+	}
+	m.Body = string(data)
+	m.BodyBytes = n
+	m.Truncated = len(data) < n
+}
+
+// write saves rec to its own file and adds it to the index. Failures are
+// ignored: capturing must never break a request.
+func (c *Capturer) write(rec *captureRecord, req *http.Request) {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	name := fmt.Sprintf("%s-%04d.json", rec.Time.Format("20060102-150405.000"), c.seq)
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0600); err != nil {
+		return
+	}
+	entry := CaptureIndexEntry{
+		Time:       rec.Time,
+		File:       name,
+		RequestID:  rec.RequestID,
+		Provider:   rec.Provider,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Error:      rec.Error,
+		DurationMs: rec.DurationMs,
+	}
+	if rec.Response != nil {
+		entry.Status = rec.Response.Status
+	}
+	line, _ := json.Marshal(entry)
+	f, err := os.OpenFile(filepath.Join(c.dir, CaptureIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+// captureBody records what is read of a response body, up to limit bytes
+// (0 = all), and hands it over once when the body is closed.
+type captureBody struct {
+	io.ReadCloser
+	limit int
+	buf   bytes.Buffer
+	n     int
+	once  sync.Once
+	done  func(data []byte, n int)
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += n
+	keep := n
+	if b.limit > 0 && b.buf.Len()+keep > b.limit {
+		keep = max(b.limit-b.buf.Len(), 0)
+	}
+	b.buf.Write(p[:keep])
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.n) })
+	return err
+}
+
+// redactHeaders returns a copy of h with the values of credential headers
+// replaced.
+func redactHeaders(h http.Header) map[string][]string {
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		if sensitiveHeader(name) {
+			out[name] = []string{redactedValue}
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// sensitiveHeader reports whether a header may carry a credential.
+func sensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range []string{"auth", "key", "secret", "cookie", "signature", "credential"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	// Not token counts such as x-env-claude-code-max-output-tokens
+	return strings.HasSuffix(lower, "token")
+}
+
+// redactURL removes credentials from a URL's user info and query.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	for name := range q {
+		if sensitiveHeader(name) {
+			q.Set(name, redactedValue)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.Redacted()
+}
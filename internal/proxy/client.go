@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ClientHeader names the client sending a request, so a proxy shared by
+// several tools can tell their traffic apart. It is not forwarded upstream.
+const ClientHeader = "X-OpenCC-Client"
+
+// unknownClient is recorded when a request doesn't identify its client.
+const unknownClient = "unknown"
+
+// knownUserAgents maps User-Agent product names of supported CLIs to their
+// client names.
+var knownUserAgents = map[string]string{
+	"claude-cli":   "claude",
+	"claude-code":  "claude",
+	"codex_cli_rs": "codex",
+	"codex":        "codex",
+	"opencode":     "opencode",
+}
+
+type clientKey struct{}
+
+// identifyClient names the client sending r: the X-OpenCC-Client header,
+// else the CLI this proxy was launched for, else the User-Agent's product.
+func (s *ProxyServer) identifyClient(r *http.Request) string {
+	if name := strings.TrimSpace(r.Header.Get(ClientHeader)); name != "" {
+		return strings.ToLower(name)
+	}
+	if s.ClientName != "" {
+		return s.ClientName
+	}
+	if name := clientFromUserAgent(r.UserAgent()); name != "" {
+		return name
+	}
+	return unknownClient
+}
+
+// clientFromUserAgent returns the lowercased product name of a User-Agent
+// ("claude-cli/1.0.3 (external, cli)" → "claude"), or "" if there is none.
+func clientFromUserAgent(ua string) string {
+	product, _, _ := strings.Cut(strings.TrimSpace(ua), " ")
+	product, _, _ = strings.Cut(product, "/")
+	product = strings.ToLower(product)
+	if name, ok := knownUserAgents[product]; ok {
+		return name
+	}
+	return product
+}
+
+// withClient returns r carrying the client name for logging.
+func withClient(r *http.Request, client string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientKey{}, client))
+}
+
+// clientOf returns the client name carried by r, or "".
+func clientOf(r *http.Request) string {
+	client, _ := r.Context().Value(clientKey{}).(string)
+	return client
+}
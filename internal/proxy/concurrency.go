@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// slots returns the provider's concurrency semaphore, or nil if it has no
+// limit.
+func (p *Provider) slots() chan struct{} {
+	if p.MaxConcurrent <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sem == nil {
+		p.sem = make(chan struct{}, p.MaxConcurrent)
+	}
+	return p.sem
+}
+
+// tryAcquire takes one of the provider's concurrency slots without waiting.
+// It reports false if all of them are in use.
+func (p *Provider) tryAcquire() bool {
+	sem := p.slots()
+	if sem == nil {
+		return true
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire waits for one of the provider's concurrency slots until ctx is
+// done.
+func (p *Provider) acquire(ctx context.Context) error {
+	sem := p.slots()
+	if sem == nil {
+		return nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a slot taken by tryAcquire or acquire.
+func (p *Provider) release() {
+	if sem := p.slots(); sem != nil {
+		<-sem
+	}
+}
+
+// releaseOnClose holds a provider's concurrency slot until the response body
+// is closed, so streamed responses count for as long as they are relayed.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (c *releaseOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxContinuitySessions bounds the continuity cache.
+const maxContinuitySessions = 1000
+
+// historyStrip selects which parts of a conversation history must be removed
+// before it can be sent to a provider.
+type historyStrip struct {
+	// thinkingBefore strips thinking blocks from messages before this index;
+	// -1 strips them from every message.
+	thinkingBefore int
+	noThinking     bool // the provider doesn't support thinking: drop the thinking parameter too
+	tools          bool // convert tool_use/tool_result blocks to plain text
+}
+
+func (h historyStrip) none() bool {
+	return h.thinkingBefore == 0 && !h.tools
+}
+
+// sessionProvenance records which provider produced a session's recent turns.
+type sessionProvenance struct {
+	provider string
+	// keepFrom is the first message produced by provider; thinking blocks in
+	// earlier messages were signed by a different provider.
+	keepFrom int
+}
+
+// continuityCache tracks, per session, which provider produced the thinking
+// blocks in the history. Thinking signatures only verify on the provider that
+// issued them, so after a failover the older blocks must be stripped for the
+// conversation to continue. The zero value is ready to use.
+type continuityCache struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionProvenance
+	order    []string
+}
+
+func (c *continuityCache) get(sessionID string) (sessionProvenance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sp, ok := c.sessions[sessionID]
+	if !ok {
+		return sessionProvenance{}, false
+	}
+	return *sp, true
+}
+
+// served records that provider answered a request carrying messageCount messages.
+func (c *continuityCache) served(sessionID, provider string, messageCount int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions == nil {
+		c.sessions = make(map[string]*sessionProvenance)
+	}
+	sp, ok := c.sessions[sessionID]
+	if !ok {
+		if len(c.order) >= maxContinuitySessions {
+			delete(c.sessions, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, sessionID)
+		c.sessions[sessionID] = &sessionProvenance{provider: provider}
+		return
+	}
+	if sp.provider != provider {
+		sp.provider = provider
+		sp.keepFrom = messageCount
+	}
+}
+
+// historyStripFor decides how a request's history must be sanitized for p.
+func (s *ProxyServer) historyStripFor(p *Provider, req *parsedRequest, sessionID string) historyStrip {
+	var strip historyStrip
+	if req.data == nil {
+		return strip
+	}
+	if c := p.Capabilities; c != nil {
+		if c.SupportsThinking != nil && !*c.SupportsThinking {
+			strip.thinkingBefore = -1
+			strip.noThinking = true
+		}
+		if c.SupportsTools != nil && !*c.SupportsTools {
+			strip.tools = true
+		}
+	}
+	if strip.thinkingBefore == 0 && sessionID != "" {
+		if sp, ok := s.continuity.get(sessionID); ok {
+			if sp.provider == p.Name {
+				strip.thinkingBefore = sp.keepFrom
+			} else {
+				strip.thinkingBefore = -1
+			}
+		}
+	}
+	return strip
+}
+
+// forwardWithContinuity forwards an attempt with the history sanitized for
+// the provider. If the provider still rejects the history (a 400 about
+// thinking blocks or signatures), the attempt is retried once with every
+// thinking block removed.
+func (s *ProxyServer) forwardWithContinuity(r *http.Request, p *Provider, req *parsedRequest, modelOverride string, providersLeft int, sessionID string) (*http.Response, bool, error) {
+	strip := s.historyStripFor(p, req, sessionID)
+	resp, timedOut, err := s.forwardAttempt(r, p, req, modelOverride, providersLeft, strip)
+	if err != nil || resp.StatusCode != http.StatusBadRequest || strip.thinkingBefore == -1 || req.data == nil {
+		return resp, timedOut, err
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !isHistoryError(body) {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, false, nil
+	}
+
+	s.Logger.Printf("[%s] history rejected (%s), retrying without thinking blocks", p.Name, truncateForError(string(body)))
+	strip.thinkingBefore = -1
+	return s.forwardAttempt(r, p, req, modelOverride, providersLeft, strip)
+}
+
+// historyErrors are the parts of the 400 messages providers return for
+// thinking blocks in the history they can't accept, lower-cased and without
+// backticks.
+var historyErrors = []string{
+	"invalid signature in thinking block",
+	"must start with a thinking block",
+	"blocks in the latest assistant message cannot be modified",
+	"input tag 'thinking'",
+	"input tag 'redacted_thinking'",
+	"thinking.signature: field required",
+}
+
+// isHistoryError reports whether a 400 body complains about history content
+// that sanitizing can fix. Other errors mentioning thinking, such as an
+// invalid budget_tokens, are the client's to see.
+func isHistoryError(body []byte) bool {
+	lower := strings.ReplaceAll(strings.ToLower(string(body)), "`", "")
+	for _, msg := range historyErrors {
+		if strings.Contains(lower, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeHistory applies strip to a JSON request body. Messages are copied
+// before modification so the shared parsed request is never mutated.
+func sanitizeHistory(body []byte, strip historyStrip) ([]byte, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	messages, _ := data["messages"].([]interface{})
+
+	lastAssistant := -1
+	for i, msg := range messages {
+		if m, ok := msg.(map[string]interface{}); ok && m["role"] == "assistant" {
+			lastAssistant = i
+		}
+	}
+
+	out := make([]interface{}, len(messages))
+	for i, msg := range messages {
+		out[i] = msg
+		m, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		blocks, ok := m["content"].([]interface{})
+		if !ok {
+			continue
+		}
+		stripThinking := strip.thinkingBefore == -1 || i < strip.thinkingBefore
+
+		var kept []interface{}
+		changed := false
+		for _, block := range blocks {
+			b, ok := block.(map[string]interface{})
+			if !ok {
+				kept = append(kept, block)
+				continue
+			}
+			switch t, _ := b["type"].(string); {
+			case stripThinking && (t == "thinking" || t == "redacted_thinking"):
+				changed = true
+				if i == lastAssistant {
+					// The final assistant turn must open with a thinking block
+					// when thinking is on; without it, thinking has to go.
+					delete(data, "thinking")
+				}
+			case strip.tools && (t == "tool_use" || t == "tool_result"):
+				changed = true
+				kept = append(kept, map[string]interface{}{"type": "text", "text": toolBlockText(b)})
+			default:
+				kept = append(kept, block)
+			}
+		}
+		if !changed {
+			continue
+		}
+		if len(kept) == 0 {
+			kept = []interface{}{map[string]interface{}{"type": "text", "text": "(omitted)"}}
+		}
+		copied := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			copied[k] = v
+		}
+		copied["content"] = kept
+		out[i] = copied
+	}
+	if messages != nil {
+		data["messages"] = out
+	}
+	if strip.noThinking {
+		delete(data, "thinking")
+	}
+	if strip.tools {
+		delete(data, "tools")
+		delete(data, "tool_choice")
+	}
+	return json.Marshal(data)
+}
+
+// toolBlockText renders a tool call or result as text so the model keeps the
+// context when the provider can't accept tool blocks.
+func toolBlockText(b map[string]interface{}) string {
+	if b["type"] == "tool_use" {
+		input, _ := json.Marshal(b["input"])
+		return fmt.Sprintf("[tool call %v: %s]", b["name"], input)
+	}
+	switch content := b["content"].(type) {
+	case string:
+		return "[tool result: " + content + "]"
+	case []interface{}:
+		var parts []string
+		for _, c := range content {
+			if cb, ok := c.(map[string]interface{}); ok {
+				if text, ok := cb["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return "[tool result: " + strings.Join(parts, "\n") + "]"
+	}
+	return "[tool result]"
+}
+
+// messageCount returns the number of messages in a parsed request.
+func messageCount(req *parsedRequest) int {
+	if req.data == nil {
+		return 0
+	}
+	messages, _ := req.data["messages"].([]interface{})
+	return len(messages)
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRequestTimeout bounds a whole client request, across every provider
+// attempt, when ProxyServer.RequestTimeout is unset.
+const DefaultRequestTimeout = 10 * time.Minute
+
+// minAttemptTimeout keeps early attempts from being starved when many
+// providers share the request deadline.
+const minAttemptTimeout = 30 * time.Second
+
+func (s *ProxyServer) requestTimeout() time.Duration {
+	if s.RequestTimeout > 0 {
+		return s.RequestTimeout
+	}
+	return DefaultRequestTimeout
+}
+
+// attemptTimeout splits the time left before the request deadline evenly
+// across the providers that may still be tried. It bounds how long an attempt
+// may wait for response headers; reading the body is bounded by the request
+// deadline only, so long streams aren't cut off.
+func attemptTimeout(ctx context.Context, providersLeft int) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	if providersLeft <= 1 {
+		return remaining
+	}
+	timeout := remaining / time.Duration(providersLeft)
+	if timeout < minAttemptTimeout {
+		timeout = minAttemptTimeout
+	}
+	if timeout > remaining {
+		timeout = remaining
+	}
+	return timeout
+}
+
+// cancelOnClose releases an attempt's context once its response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// errIdleTimeout is returned by a response body that went without data for
+// longer than its provider's idle timeout.
+var errIdleTimeout = errors.New("provider sent no data")
+
+// idleTimeoutBody cancels its attempt when a read waits on the provider for
+// longer than timeout. Time spent outside Read, such as writing to a slow
+// client, doesn't count.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	idle    atomic.Bool
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleTimeoutBody {
+	b := &idleTimeoutBody{ReadCloser: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.idle.Store(true)
+		cancel()
+	})
+	b.timer.Stop()
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+	if err != nil && err != io.EOF && b.idle.Load() {
+		err = fmt.Errorf("%w for %v", errIdleTimeout, b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
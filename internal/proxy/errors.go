@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// Error types used in client-facing error bodies. The names follow the
+// Anthropic error taxonomy; OpenAI clients receive the same strings in
+// error.type, which they treat as opaque.
+const (
+	errTypeAPI       = "api_error"
+	errTypeRateLimit = "rate_limit_error"
+)
+
+// providerErrorDetail describes one provider's outcome in an aggregated error.
+type providerErrorDetail struct {
+	Name       string `json:"name"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"` // not attempted because it was in backoff
+}
+
+// anthropicErrorBody mirrors the Anthropic Messages API error shape, with an
+// extra opencc-specific field listing per-provider failures.
+type anthropicErrorBody struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Providers []providerErrorDetail `json:"opencc_providers,omitempty"`
+}
+
+// openAIErrorBody mirrors the OpenAI API error shape.
+type openAIErrorBody struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+	} `json:"error"`
+	Providers []providerErrorDetail `json:"opencc_providers,omitempty"`
+}
+
+// writeError writes an error response in the client's native API format.
+func (s *ProxyServer) writeError(w http.ResponseWriter, status int, errType, message string, details []providerErrorDetail) {
+	var body interface{}
+	if s.ClientFormat == config.ProviderTypeOpenAI {
+		var e openAIErrorBody
+		e.Error.Message = message
+		e.Error.Type = errType
+		e.Providers = details
+		body = e
+	} else {
+		var e anthropicErrorBody
+		e.Type = "error"
+		e.Error.Type = errType
+		e.Error.Message = message
+		e.Providers = details
+		body = e
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeAllProvidersFailed summarizes every provider failure into a single
+// native-format error. When every attempted provider was rate limited the
+// client gets a 429 so its own retry logic kicks in; otherwise a 502.
+func (s *ProxyServer) writeAllProvidersFailed(w http.ResponseWriter, failures []providerFailure) {
+	status := http.StatusBadGateway
+	errType := errTypeAPI
+
+	details := make([]providerErrorDetail, 0, len(failures))
+	var parts []string
+	attempted, rateLimited, tooLarge := 0, 0, 0
+	for _, f := range failures {
+		if f.TooLarge {
+			tooLarge++
+		}
+		d := providerErrorDetail{Name: f.Name, StatusCode: f.StatusCode, Error: truncateForError(f.Body), Skipped: f.Skipped}
+		details = append(details, d)
+
+		switch {
+		case f.Skipped:
+			parts = append(parts, fmt.Sprintf("%s: skipped (%s)", f.Name, d.Error))
+			continue
+		case f.StatusCode > 0:
+			parts = append(parts, fmt.Sprintf("%s: %d", f.Name, f.StatusCode))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s", f.Name, d.Error))
+		}
+		attempted++
+		if f.StatusCode == http.StatusTooManyRequests {
+			rateLimited++
+		}
+	}
+	if attempted > 0 && attempted == rateLimited {
+		status = http.StatusTooManyRequests
+		errType = errTypeRateLimit
+	}
+	// No provider accepts a request this large; retrying won't help.
+	if tooLarge > 0 && tooLarge == len(failures) {
+		status = http.StatusRequestEntityTooLarge
+		errType = errTypeRequestTooLarge
+	}
+
+	msg := "all providers failed"
+	if len(parts) > 0 {
+		msg += " (" + strings.Join(parts, "; ") + ")"
+	}
+	s.writeError(w, status, errType, msg, details)
+}
+
+// truncateForError shortens upstream error bodies embedded in client errors.
+func truncateForError(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 300 {
+		return s[:300] + "..."
+	}
+	return s
+}
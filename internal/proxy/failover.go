@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// idempotencyKeyHeader is propagated unchanged to every provider attempt so
+// providers that honor it can deduplicate a request retried after failover.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// defaultFailoverPolicies protects endpoints whose requests have side effects
+// on the provider. User-configured policies take precedence.
+var defaultFailoverPolicies = map[string]config.FailoverPolicy{
+	"/v1/messages/batches": config.FailoverSafe,
+	"/v1/files":            config.FailoverSafe,
+}
+
+// failoverPolicyFor returns the failover policy for a request. Safe methods
+// always fail over; otherwise the longest matching path prefix wins, checking
+// configured policies before the built-in defaults.
+func (s *ProxyServer) failoverPolicyFor(method, path string) config.FailoverPolicy {
+	if method == http.MethodGet || method == http.MethodHead {
+		return config.FailoverAlways
+	}
+	if p, ok := longestPrefixPolicy(s.FailoverPolicies, path); ok {
+		return p
+	}
+	if p, ok := longestPrefixPolicy(defaultFailoverPolicies, path); ok {
+		return p
+	}
+	return config.FailoverAlways
+}
+
+func longestPrefixPolicy(policies map[string]config.FailoverPolicy, path string) (config.FailoverPolicy, bool) {
+	best := -1
+	var policy config.FailoverPolicy
+	for prefix, p := range policies {
+		if !p.IsValid() || !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		if len(prefix) > best {
+			best = len(prefix)
+			policy = p
+		}
+	}
+	return policy, best >= 0
+}
+
+// canFailover reports whether a failed attempt may be retried on the next
+// provider under the given policy. processed indicates the provider may have
+// acted on the request before failing.
+func canFailover(policy config.FailoverPolicy, processed bool) bool {
+	switch policy {
+	case config.FailoverNever:
+		return false
+	case config.FailoverSafe:
+		return !processed
+	default:
+		return true
+	}
+}
+
+// isDialError reports whether err happened while establishing the connection,
+// i.e. the request never reached the provider.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// ensureIdempotencyKey assigns an idempotency key to non-GET requests that
+// don't already carry one. It returns the key it generated, or "" when the
+// request kept its own key or needs none.
+func ensureIdempotencyKey(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return ""
+	}
+	if r.Header.Get(idempotencyKeyHeader) != "" {
+		return ""
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	key := "opencc-" + hex.EncodeToString(buf)
+	r.Header.Set(idempotencyKeyHeader, key)
+	return key
+}
+
+// writeUpstreamResponse relays an already-read provider error response to the
+// client unchanged, used when failover is not permitted.
+func writeUpstreamResponse(w http.ResponseWriter, resp *http.Response, provider string, body []byte) {
+	recordProvider(w, provider)
+	for k, vv := range resp.Header {
+		if strings.EqualFold(k, "Content-Length") {
+			continue
+		}
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
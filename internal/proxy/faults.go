@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FaultsEnv enables fault injection: the proxy makes the listed providers
+// fail instead of forwarding to them, e.g. "primary=429:3,backup=timeout".
+// Set it to an empty string to enable the admin endpoint with no faults.
+const FaultsEnv = "OPENCC_FAULTS"
+
+// FaultsPath is the admin endpoint for inspecting (GET), adding (POST, with a
+// fault spec as the body) and clearing (DELETE) injected faults. It is only
+// served while fault injection is enabled.
+const FaultsPath = "/_opencc/faults"
+
+// faultAnyProvider matches every provider in a fault spec.
+const faultAnyProvider = "*"
+
+// errInjectedTimeout is returned for an injected timeout.
+var errInjectedTimeout = errors.New("injected timeout")
+
+// Fault makes a provider's next Remaining attempts fail without contacting
+// it, with HTTP status Status, or with a timeout if Status is 0.
+type Fault struct {
+	Provider  string `json:"provider"`
+	Status    int    `json:"status,omitempty"`
+	Remaining int    `json:"remaining"`
+}
+
+func (f Fault) String() string {
+	if f.Status == 0 {
+		return "timeout"
+	}
+	return strconv.Itoa(f.Status)
+}
+
+// FaultInjector holds the pending faults. Faults for the same provider are
+// used up in the order they were added.
+type FaultInjector struct {
+	mu     sync.Mutex
+	faults []Fault
+}
+
+// ParseFaults parses a comma-separated fault spec into an injector. Each
+// item is provider=kind[:count], where kind is an HTTP status (400-599) or
+// "timeout", count defaults to 1, and provider "*" matches any provider.
+func ParseFaults(spec string) (*FaultInjector, error) {
+	fi := &FaultInjector{}
+	if err := fi.Add(spec); err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+// Add parses a fault spec and appends its faults.
+func (fi *FaultInjector) Add(spec string) error {
+	var faults []Fault
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		f, err := parseFault(item)
+		if err != nil {
+			return err
+		}
+		faults = append(faults, f)
+	}
+	fi.mu.Lock()
+	fi.faults = append(fi.faults, faults...)
+	fi.mu.Unlock()
+	return nil
+}
+
+func parseFault(item string) (Fault, error) {
+	provider, kind, ok := strings.Cut(item, "=")
+	provider = strings.TrimSpace(provider)
+	if !ok || provider == "" {
+		return Fault{}, fmt.Errorf("invalid fault '%s': expected provider=kind[:count]", item)
+	}
+	kind, countStr, hasCount := strings.Cut(strings.TrimSpace(kind), ":")
+	f := Fault{Provider: provider, Remaining: 1}
+	if kind != "timeout" {
+		status, err := strconv.Atoi(kind)
+		if err != nil || status < 400 || status > 599 {
+			return Fault{}, fmt.Errorf("invalid fault '%s': kind must be an HTTP status 400-599 or 'timeout'", item)
+		}
+		f.Status = status
+	}
+	if hasCount {
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			return Fault{}, fmt.Errorf("invalid fault '%s': count must be a positive integer", item)
+		}
+		f.Remaining = count
+	}
+	return f, nil
+}
+
+// Pending returns the faults not yet used up.
+func (fi *FaultInjector) Pending() []Fault {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return append([]Fault{}, fi.faults...)
+}
+
+// Clear removes every pending fault.
+func (fi *FaultInjector) Clear() {
+	fi.mu.Lock()
+	fi.faults = nil
+	fi.mu.Unlock()
+}
+
+// take uses up one attempt of the first fault matching the provider. It is
+// safe to call on a nil injector.
+func (fi *FaultInjector) take(provider string) (Fault, bool) {
+	if fi == nil {
+		return Fault{}, false
+	}
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	for i, f := range fi.faults {
+		if f.Provider != provider && f.Provider != faultAnyProvider {
+			continue
+		}
+		fi.faults[i].Remaining--
+		if fi.faults[i].Remaining == 0 {
+			fi.faults = append(fi.faults[:i], fi.faults[i+1:]...)
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+// result returns what the attempt yields for an injected fault: a synthetic
+// error response, or a timeout error.
+func (f Fault) result() (*http.Response, bool, error) {
+	if f.Status == 0 {
+		return nil, true, errInjectedTimeout
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    "injected_fault",
+			"message": fmt.Sprintf("injected %d by opencc fault injection", f.Status),
+		},
+	})
+	return &http.Response{
+		StatusCode: f.Status,
+		Status:     fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, false, nil
+}
+
+// ServeHTTP serves the faults admin endpoint.
+func (fi *FaultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		spec, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := fi.Add(string(spec)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		fi.Clear()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]Fault{"faults": fi.Pending()})
+}
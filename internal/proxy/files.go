@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// filesPathPrefix is the Anthropic Files API path. Files are stored by the
+// provider that received the upload, so later references must go back to it.
+const filesPathPrefix = "/v1/files"
+
+// filePinStore maps file IDs to the name of the provider that owns them.
+// The zero value is ready to use.
+type filePinStore struct {
+	mu     sync.Mutex
+	owners map[string]string // file ID → provider name
+}
+
+func (fs *filePinStore) pin(fileID, provider string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.owners == nil {
+		fs.owners = make(map[string]string)
+	}
+	fs.owners[fileID] = provider
+}
+
+func (fs *filePinStore) unpin(fileID string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.owners, fileID)
+}
+
+func (fs *filePinStore) owner(fileID string) (string, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name, ok := fs.owners[fileID]
+	return name, ok
+}
+
+// isFilesPath reports whether path targets the Files API.
+func isFilesPath(path string) bool {
+	return path == filesPathPrefix || strings.HasPrefix(path, filesPathPrefix+"/")
+}
+
+// fileIDFromPath returns the file ID in /v1/files/{id}[/content], or "".
+func fileIDFromPath(path string) string {
+	if !strings.HasPrefix(path, filesPathPrefix+"/") {
+		return ""
+	}
+	id := strings.TrimPrefix(path, filesPathPrefix+"/")
+	if i := strings.IndexByte(id, '/'); i >= 0 {
+		id = id[:i]
+	}
+	return id
+}
+
+// referencedFileIDs collects the file IDs a request depends on: the ID in a
+// Files API path, plus any "file_id" fields in the JSON body (e.g. document
+// and image content blocks with a file source).
+func referencedFileIDs(path string, bodyMap map[string]interface{}) []string {
+	var ids []string
+	if id := fileIDFromPath(path); id != "" {
+		ids = append(ids, id)
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, child := range t {
+				if id, ok := child.(string); ok && k == "file_id" && id != "" {
+					ids = append(ids, id)
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	if bodyMap != nil {
+		walk(bodyMap)
+	}
+	return ids
+}
+
+// pinnedProvider resolves the provider owning the request's referenced files.
+// It returns ok=false when none of the files are pinned. When files are pinned
+// to a provider that is no longer configured, the returned provider is nil.
+func (s *ProxyServer) pinnedProvider(fileIDs []string) (fileID string, p *Provider, ok bool) {
+	for _, id := range fileIDs {
+		name, found := s.filePins.owner(id)
+		if !found {
+			continue
+		}
+		return id, s.findProvider(name), true
+	}
+	return "", nil, false
+}
+
+// findProvider looks up a provider by name across the default and scenario chains.
+func (s *ProxyServer) findProvider(name string) *Provider {
+	providers, routing := s.chains()
+	for _, p := range providers {
+		if p.Name == name {
+			return p
+		}
+	}
+	if routing != nil {
+		for _, sp := range routing.ScenarioRoutes {
+			for _, p := range sp.Providers {
+				if p.Name == name {
+					return p
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// trackFileOwnership records which provider stored an uploaded file and forgets
+// files once they are deleted. Only successful Files API responses are inspected.
+func (s *ProxyServer) trackFileOwnership(r *http.Request, resp *http.Response, p *Provider) {
+	if !isFilesPath(r.URL.Path) || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if id := fileIDFromPath(r.URL.Path); id != "" {
+			s.filePins.unpin(id)
+			s.Logger.Printf("[files] unpinned %s", id)
+		}
+	case http.MethodPost:
+		if r.URL.Path != filesPathPrefix {
+			return
+		}
+		body, rest, overflow, err := readBodyLimited(resp.Body, s.maxResponseBytes())
+		if err != nil {
+			return
+		}
+		if overflow {
+			resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), rest), resp.Body}
+			return
+		}
+		resp.Body = readCloser{bytes.NewReader(body), resp.Body}
+
+		var file struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &file); err != nil || file.ID == "" {
+			return
+		}
+		s.filePins.pin(file.ID, p.Name)
+		s.Logger.Printf("[files] pinned %s to %s", file.ID, p.Name)
+	}
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds a single health probe when no timeout is
+// configured.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheck configures RunHealthChecks.
+type HealthCheck struct {
+	Interval time.Duration // time between probe rounds
+	Timeout  time.Duration // per-probe timeout; 0 = DefaultHealthCheckTimeout
+	Path     string        // probe path; empty = DefaultProbePath
+}
+
+// RunHealthChecks probes every provider of the server once per interval until
+// ctx is done. A failed probe marks a healthy provider failed, so requests
+// skip it without first waiting on a failed attempt; a passing probe clears
+// a provider's backoff as soon as it recovers.
+func (s *ProxyServer) RunHealthChecks(ctx context.Context, hc HealthCheck) {
+	if hc.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(hc.Interval)
+	defer ticker.Stop()
+	for {
+		s.checkHealth(ctx, hc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth runs one round of probes, one per provider in parallel.
+func (s *ProxyServer) checkHealth(ctx context.Context, hc HealthCheck) {
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	path := hc.Path
+	if path == "" {
+		path = DefaultProbePath
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range s.allProviders() {
+		wg.Add(1)
+		go func(p *Provider) {
+			defer wg.Done()
+			p.refreshToken()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			result := probePath(probeCtx, p.HTTPClient(s.healthClient()), p, path)
+			if ctx.Err() != nil {
+				return
+			}
+			if msg := applyProbe(p, result, time.Now()); msg != "" {
+				s.recordHealth(p)
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(nil, p.Name, 0, LogLevelInfo, msg)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// applyProbe updates p's health from a probe result and describes the
+// change, or returns "" if there was none. Failures only mark providers that
+// requests currently use: a provider already in backoff keeps its backoff
+// rather than having it extended by every probe.
+func applyProbe(p *Provider, result ProbeResult, now time.Time) string {
+	problem := result.Problem()
+	if problem == "" {
+		if p.CurrentBackoff() == 0 {
+			return ""
+		}
+		p.MarkHealthy()
+		return "health check passed, marked healthy"
+	}
+
+	if retryAt := p.RetryAt(); !retryAt.IsZero() && now.Before(retryAt) {
+		return ""
+	}
+	if result.StatusCode == http.StatusUnauthorized || result.StatusCode == http.StatusForbidden {
+		p.MarkAuthFailed()
+	} else {
+		p.MarkFailed()
+	}
+	return fmt.Sprintf("health check failed (%s), marked unhealthy for %v", problem, p.CurrentBackoff())
+}
+
+// healthClient returns the client used for probes.
+func (s *ProxyServer) healthClient() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// allProviders returns the providers of the default and scenario chains,
+// each once.
+func (s *ProxyServer) allProviders() []*Provider {
+	seen := make(map[*Provider]bool)
+	var all []*Provider
+	add := func(providers []*Provider) {
+		for _, p := range providers {
+			if !seen[p] {
+				seen[p] = true
+				all = append(all, p)
+			}
+		}
+	}
+	providers, routing := s.chains()
+	add(providers)
+	if routing != nil {
+		for _, sp := range routing.ScenarioRoutes {
+			add(sp.Providers)
+		}
+	}
+	return all
+}
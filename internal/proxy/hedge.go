@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// attemptResult is the outcome of one provider attempt.
+type attemptResult struct {
+	p        *Provider
+	resp     *http.Response
+	timedOut bool
+	err      error
+	start    time.Time
+}
+
+// answered reports whether the attempt got a response the client would be
+// given rather than one that fails over.
+func (a attemptResult) answered() bool {
+	if a.err != nil {
+		return false
+	}
+	switch code := a.resp.StatusCode; {
+	case code == 401 || code == 402 || code == 403 || code == 429 || code >= 500:
+		return false
+	}
+	return true
+}
+
+// discard closes the attempt's response, if any, and releases its provider's
+// concurrency slot.
+func (a attemptResult) discard() {
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+	a.p.release()
+}
+
+// hedgeDelay returns how long to wait for a provider before hedging req, or
+// 0 if it isn't hedged.
+func (s *ProxyServer) hedgeDelay(req *parsedRequest, stream *streamState) time.Duration {
+	if s.Hedge == nil || stream.resuming() {
+		return 0
+	}
+	streaming, _ := req.data["stream"].(bool)
+	return s.Hedge.Delay(streaming)
+}
+
+// hedgeCandidate returns the first provider after providers[i] that could be
+// tried right now, holding one of its concurrency slots, and the number of
+// providers left counting from it. It returns nil if there is none.
+func hedgeCandidate(providers []*Provider, i int, req *parsedRequest, gate *requestNeeds) (*Provider, int) {
+	for j := i + 1; j < len(providers); j++ {
+		p := providers[j]
+		if p.unavailableReason() != "" || providerSizeLimit(p, len(req.raw)) != "" || !p.IsHealthy() {
+			continue
+		}
+		if gate != nil && p.missingCapability(*gate) != "" {
+			continue
+		}
+		if p.tryAcquire() {
+			return p, len(providers) - j
+		}
+	}
+	return nil, 0
+}
+
+// raceAttempt forwards an attempt on p and, if it has no response headers
+// after delay, on hedge as well. The first attempt to answer is returned and
+// the other is canceled; if neither answers, p's result is returned. Both
+// providers hold a concurrency slot; the slot of the provider whose result is
+// not returned is released here.
+func (s *ProxyServer) raceAttempt(r *http.Request, p, hedge *Provider, modelOverrides map[string]string, providersLeft, hedgeLeft int, req *parsedRequest, sessionID string, delay time.Duration) attemptResult {
+	results := make(chan attemptResult, 2)
+	cancels := make(map[*Provider]context.CancelFunc, 2)
+	launch := func(p *Provider, left int) {
+		ctx, cancel := context.WithCancel(r.Context())
+		cancels[p] = cancel
+		go func(start time.Time) {
+			resp, timedOut, err := s.forwardWithContinuity(r.WithContext(ctx), p, req, modelOverrides[p.Name], left, sessionID)
+			results <- attemptResult{p: p, resp: resp, timedOut: timedOut, err: err, start: start}
+		}(time.Now())
+	}
+	// keep releases an attempt's context once its response is done with.
+	keep := func(a attemptResult) attemptResult {
+		if a.err != nil {
+			cancels[a.p]()
+		} else {
+			a.resp.Body = cancelOnClose{a.resp.Body, cancels[a.p]}
+		}
+		return a
+	}
+
+	launch(p, providersLeft)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case first := <-results:
+		hedge.release()
+		return keep(first)
+	case <-timer.C:
+	}
+
+	msg := fmt.Sprintf("no response after %v, hedging with %s", delay, hedge.Name)
+	s.Logger.Printf("[%s] %s", p.Name, msg)
+	s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+	launch(hedge, hedgeLeft)
+
+	first := <-results
+	if first.answered() {
+		loser := p
+		if first.p == p {
+			loser = hedge
+		}
+		cancelLoser := cancels[loser]
+		cancelLoser()
+		go func() {
+			lost := <-results
+			lost.discard()
+		}()
+		if first.p == hedge {
+			s.Logger.Printf("[%s] answered first, canceling %s", hedge.Name, p.Name)
+		}
+		return keep(first)
+	}
+
+	second := <-results
+	if second.answered() && second.p == hedge {
+		first.discard()
+		cancels[first.p]()
+		s.Logger.Printf("[%s] answered after %s failed", hedge.Name, p.Name)
+		return keep(second)
+	}
+	// Otherwise p's result is used, answer or failure; a failed hedge may
+	// still be tried in turn.
+	primary, other := first, second
+	if primary.p != p {
+		primary, other = second, first
+	}
+	other.discard()
+	cancels[other.p]()
+	return keep(primary)
+}
@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+const errTypePermission = "permission_error"
+
+// hookInfo describes the body a hook runs on. It reaches the command as
+// OPENCC_* environment variables.
+type hookInfo struct {
+	stage     string // "request" or "response"
+	path      string
+	requestID string
+	profile   string
+	provider  string // provider that served a response; "" for requests
+}
+
+func (h hookInfo) env() []string {
+	env := []string{
+		"OPENCC_HOOK=" + h.stage,
+		"OPENCC_PATH=" + h.path,
+		"OPENCC_REQUEST_ID=" + h.requestID,
+		"OPENCC_PROFILE=" + h.profile,
+	}
+	if h.provider != "" {
+		env = append(env, "OPENCC_PROVIDER="+h.provider)
+	}
+	return env
+}
+
+// hookRejection is a hook that exited with an error, such as one refusing a
+// request by policy. Its message is what the hook wrote to stderr.
+type hookRejection struct {
+	command string
+	message string
+}
+
+func (e *hookRejection) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("rejected by hook %s", e.command)
+	}
+	return fmt.Sprintf("rejected by hook %s: %s", e.command, e.message)
+}
+
+// runHooks passes body through hooks in order, each getting the previous
+// one's output. Bodies that aren't JSON objects, such as file uploads, are
+// returned as they are.
+func runHooks(ctx context.Context, hooks []config.HookCommand, body []byte, info hookInfo) ([]byte, error) {
+	if len(hooks) == 0 || !isJSONObject(body) {
+		return body, nil
+	}
+	for _, h := range hooks {
+		out, err := runHook(ctx, h, body, info)
+		if err != nil {
+			return nil, err
+		}
+		body = out
+	}
+	return body, nil
+}
+
+// runHook runs one hook on body and returns the body it printed, or body
+// itself if it printed nothing.
+func runHook(ctx context.Context, h config.HookCommand, body []byte, info hookInfo) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.Command, h.Args...)
+	cmd.Env = append(os.Environ(), info.env()...)
+	cmd.Stdin = bytes.NewReader(body)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("hook %s timed out after %s", h.Command, h.Timeout())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, &hookRejection{command: h.Command, message: strings.TrimSpace(stderr.String())}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: %w", h.Command, err)
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return body, nil
+	}
+	if !isJSONObject(out) {
+		return nil, fmt.Errorf("hook %s printed something other than a JSON object", h.Command)
+	}
+	return out, nil
+}
+
+// isJSONObject reports whether data is a JSON object.
+func isJSONObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{' && json.Valid(data)
+}
+
+// applyRequestHooks runs the request hooks on body, writing the error
+// response and returning false if one fails: 403 if a hook rejected the
+// request, 500 if one couldn't run.
+func (s *ProxyServer) applyRequestHooks(w http.ResponseWriter, r *http.Request, body []byte) ([]byte, bool) {
+	if s.Hooks == nil {
+		return body, true
+	}
+	info := hookInfo{stage: "request", path: r.URL.Path, requestID: requestIDOf(r), profile: s.Profile}
+	out, err := runHooks(r.Context(), s.Hooks.Request, body, info)
+	if err != nil {
+		s.Logger.Printf("[hooks] request %v", err)
+		var rejection *hookRejection
+		if errors.As(err, &rejection) {
+			s.writeError(w, http.StatusForbidden, errTypePermission, "request "+err.Error(), nil)
+		} else {
+			s.writeError(w, http.StatusInternalServerError, errTypeAPI, "request "+err.Error(), nil)
+		}
+		return nil, false
+	}
+	return out, true
+}
+
+// applyResponseHooks runs the response hooks on the body of a response from
+// p, writing a 502 and returning false if one fails, so a hook that redacts
+// responses never lets an unredacted one through.
+func (s *ProxyServer) applyResponseHooks(w http.ResponseWriter, r *http.Request, p *Provider, body []byte) ([]byte, bool) {
+	if s.Hooks == nil {
+		return body, true
+	}
+	info := hookInfo{stage: "response", path: r.URL.Path, requestID: requestIDOf(r), profile: s.Profile, provider: p.Name}
+	out, err := runHooks(r.Context(), s.Hooks.Response, body, info)
+	if err != nil {
+		s.Logger.Printf("[hooks] response from %s %v", p.Name, err)
+		s.writeError(w, http.StatusBadGateway, errTypeAPI, "response "+err.Error(), nil)
+		return nil, false
+	}
+	return out, true
+}
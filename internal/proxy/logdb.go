@@ -2,9 +2,11 @@ package proxy
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -46,9 +48,16 @@ func OpenLogDB(logDir string) (*LogDB, error) {
 			id            INTEGER PRIMARY KEY AUTOINCREMENT,
 			timestamp     DATETIME NOT NULL,
 			level         TEXT NOT NULL,
+			request_id    TEXT DEFAULT '',
 			provider      TEXT DEFAULT '',
+			client        TEXT DEFAULT '',
 			message       TEXT DEFAULT '',
 			status_code   INTEGER DEFAULT 0,
+			latency_ms    INTEGER DEFAULT 0,
+			scenario      TEXT DEFAULT '',
+			model         TEXT DEFAULT '',
+			input_tokens  INTEGER DEFAULT 0,
+			output_tokens INTEGER DEFAULT 0,
 			method        TEXT DEFAULT '',
 			path          TEXT DEFAULT '',
 			error         TEXT DEFAULT '',
@@ -59,10 +68,108 @@ func OpenLogDB(logDir string) (*LogDB, error) {
 		return nil, fmt.Errorf("create logs table: %w", err)
 	}
 
+	// Databases created before client identification, latency tracking and
+	// request tracing lack those columns
+	for _, c := range [][2]string{
+		{"client", "TEXT DEFAULT ''"},
+		{"latency_ms", "INTEGER DEFAULT 0"},
+		{"request_id", "TEXT DEFAULT ''"},
+		{"scenario", "TEXT DEFAULT ''"},
+		{"model", "TEXT DEFAULT ''"},
+		{"input_tokens", "INTEGER DEFAULT 0"},
+		{"output_tokens", "INTEGER DEFAULT 0"},
+	} {
+		if err := addColumnIfMissing(db, "logs", c[0], c[1]); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrate logs table: %w", err)
+		}
+	}
+
+	// Session snapshots: the configuration each session started with and the
+	// provider/model pairs that actually served it
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			session_id TEXT PRIMARY KEY,
+			started_at DATETIME NOT NULL,
+			profile    TEXT DEFAULT '',
+			cli        TEXT DEFAULT '',
+			providers  TEXT DEFAULT '',
+			routing    TEXT DEFAULT ''
+		);
+		CREATE TABLE IF NOT EXISTS session_models (
+			session_id TEXT NOT NULL,
+			provider   TEXT NOT NULL,
+			model      TEXT NOT NULL,
+			first_used DATETIME NOT NULL,
+			PRIMARY KEY (session_id, provider, model)
+		)
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sessions tables: %w", err)
+	}
+
+	// Latest rate-limit state reported by each provider, shared with the
+	// web server and other proxies
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limits (
+			provider    TEXT PRIMARY KEY,
+			observed_at DATETIME NOT NULL,
+			state       TEXT NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create rate_limits table: %w", err)
+	}
+
+	// Latest health of each provider whose health changed, shared like the
+	// rate limits
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS provider_health (
+			provider    TEXT PRIMARY KEY,
+			observed_at DATETIME NOT NULL,
+			state       TEXT NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create provider_health table: %w", err)
+	}
+
+	// Served requests, tokens and estimated cost per local day, provider and
+	// profile; kept apart from the logs so totals survive log pruning
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS usage (
+			day           TEXT NOT NULL,
+			provider      TEXT NOT NULL,
+			profile       TEXT NOT NULL,
+			requests      INTEGER NOT NULL DEFAULT 0,
+			input_tokens  INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cost_usd      REAL NOT NULL DEFAULT 0,
+			PRIMARY KEY (day, provider, profile)
+		)
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create usage table: %w", err)
+	}
+
+	// When each profile's provider order was last evaluated, shared so that
+	// concurrent sessions don't each re-order it
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS auto_order_runs (
+			profile TEXT PRIMARY KEY,
+			run_at  INTEGER NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create auto_order_runs table: %w", err)
+	}
+
 	for _, idx := range []string{
 		"CREATE INDEX IF NOT EXISTS idx_logs_timestamp ON logs(timestamp)",
 		"CREATE INDEX IF NOT EXISTS idx_logs_provider ON logs(provider)",
 		"CREATE INDEX IF NOT EXISTS idx_logs_level ON logs(level)",
+		"CREATE INDEX IF NOT EXISTS idx_logs_client ON logs(client)",
+		"CREATE INDEX IF NOT EXISTS idx_logs_request_id ON logs(request_id)",
 	} {
 		if _, err := db.Exec(idx); err != nil {
 			db.Close()
@@ -129,8 +236,9 @@ func (ldb *LogDB) flushBatch(batch []LogEntry) {
 	}
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO logs (timestamp, level, provider, message, status_code, method, path, error, response_body)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO logs (timestamp, level, request_id, provider, client, message, status_code, latency_ms,
+			scenario, model, input_tokens, output_tokens, method, path, error, response_body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -142,9 +250,16 @@ func (ldb *LogDB) flushBatch(batch []LogEntry) {
 		stmt.Exec(
 			e.Timestamp.UTC().Format(time.RFC3339Nano),
 			string(e.Level),
+			e.RequestID,
 			e.Provider,
+			e.Client,
 			e.Message,
 			e.StatusCode,
+			e.LatencyMs,
+			e.Scenario,
+			e.Model,
+			e.InputTokens,
+			e.OutputTokens,
 			e.Method,
 			e.Path,
 			e.Error,
@@ -157,13 +272,81 @@ func (ldb *LogDB) flushBatch(batch []LogEntry) {
 
 // Query returns log entries matching the filter, newest first.
 func (ldb *LogDB) Query(filter LogFilter) ([]LogEntry, error) {
+	conditions, args := filter.sqlConditions()
+
+	query := `SELECT timestamp, level, request_id, provider, client, message, status_code, latency_ms,
+		scenario, model, input_tokens, output_tokens, method, path, error, response_body FROM logs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY timestamp DESC, id DESC"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := ldb.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query logs: %w", err)
+	}
+	defer rows.Close()
+	return scanLogEntries(rows)
+}
+
+// Tail returns the entries matching the filter that were logged after the
+// one with ID afterID, oldest first and at most filter.Limit of the newest
+// (0 = all), and the ID of the last entry logged, to continue from.
+func (ldb *LogDB) Tail(filter LogFilter, afterID int64) ([]LogEntry, int64, error) {
+	var lastID sql.NullInt64
+	if err := ldb.db.QueryRow("SELECT MAX(id) FROM logs").Scan(&lastID); err != nil {
+		return nil, afterID, fmt.Errorf("query logs: %w", err)
+	}
+	if !lastID.Valid || lastID.Int64 <= afterID {
+		return nil, afterID, nil
+	}
+
+	conditions, args := filter.sqlConditions()
+	conditions = append(conditions, "id > ?", "id <= ?")
+	args = append(args, afterID, lastID.Int64)
+	query := `SELECT timestamp, level, request_id, provider, client, message, status_code, latency_ms,
+		scenario, model, input_tokens, output_tokens, method, path, error, response_body FROM logs
+		WHERE ` + strings.Join(conditions, " AND ") + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := ldb.db.Query(query, args...)
+	if err != nil {
+		return nil, afterID, fmt.Errorf("query logs: %w", err)
+	}
+	defer rows.Close()
+	entries, err := scanLogEntries(rows)
+	slices.Reverse(entries)
+	return entries, lastID.Int64, err
+}
+
+// sqlConditions returns the WHERE conditions selecting the entries the
+// filter matches, and their arguments.
+func (filter LogFilter) sqlConditions() ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 
+	if filter.RequestID != "" {
+		conditions = append(conditions, "request_id = ?")
+		args = append(args, filter.RequestID)
+	}
 	if filter.Provider != "" {
 		conditions = append(conditions, "provider = ?")
 		args = append(args, filter.Provider)
 	}
+	if filter.Client != "" {
+		conditions = append(conditions, "client = ?")
+		args = append(args, filter.Client)
+	}
 	if filter.Level != "" {
 		conditions = append(conditions, "level = ?")
 		args = append(args, string(filter.Level))
@@ -183,32 +366,22 @@ func (ldb *LogDB) Query(filter LogFilter) ([]LogEntry, error) {
 		conditions = append(conditions, "status_code <= ?")
 		args = append(args, filter.StatusMax)
 	}
-
-	query := "SELECT timestamp, level, provider, message, status_code, method, path, error, response_body FROM logs"
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.Since.UTC().Format(time.RFC3339Nano))
 	}
-	query += " ORDER BY timestamp DESC"
-
-	limit := filter.Limit
-	if limit <= 0 {
-		limit = 100
-	}
-	query += " LIMIT ?"
-	args = append(args, limit)
-
-	rows, err := ldb.db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query logs: %w", err)
-	}
-	defer rows.Close()
+	return conditions, args
+}
 
+// scanLogEntries reads the log entries selected by Query and Tail.
+func scanLogEntries(rows *sql.Rows) ([]LogEntry, error) {
 	var entries []LogEntry
 	for rows.Next() {
 		var e LogEntry
 		var tsStr string
 		var level string
-		if err := rows.Scan(&tsStr, &level, &e.Provider, &e.Message, &e.StatusCode, &e.Method, &e.Path, &e.Error, &e.ResponseBody); err != nil {
+		if err := rows.Scan(&tsStr, &level, &e.RequestID, &e.Provider, &e.Client, &e.Message, &e.StatusCode, &e.LatencyMs,
+			&e.Scenario, &e.Model, &e.InputTokens, &e.OutputTokens, &e.Method, &e.Path, &e.Error, &e.ResponseBody); err != nil {
 			continue
 		}
 		e.Level = LogLevel(level)
@@ -240,6 +413,373 @@ func (ldb *LogDB) GetProviders() ([]string, error) {
 	return providers, rows.Err()
 }
 
+// GetClients returns distinct client names from the log database.
+func (ldb *LogDB) GetClients() ([]string, error) {
+	rows, err := ldb.db.Query("SELECT DISTINCT client FROM logs WHERE client != '' ORDER BY client")
+	if err != nil {
+		return nil, fmt.Errorf("query clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			continue
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// ClientStats summarizes the logged traffic per client, ordered by client.
+// Entries are counted as in StructuredLogger.GetClientStats.
+func (ldb *LogDB) ClientStats() ([]ClientStats, error) {
+	rows, err := ldb.db.Query(`
+		SELECT client, provider,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 400 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN level = 'error' THEN 1 ELSE 0 END),
+			MAX(timestamp)
+		FROM logs WHERE client != ''
+		GROUP BY client, provider
+		ORDER BY client, provider`)
+	if err != nil {
+		return nil, fmt.Errorf("query client stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ClientStats
+	for rows.Next() {
+		var client, provider, tsStr string
+		var served, errs int
+		if err := rows.Scan(&client, &provider, &served, &errs, &tsStr); err != nil {
+			continue
+		}
+		if len(stats) == 0 || stats[len(stats)-1].Client != client {
+			stats = append(stats, ClientStats{Client: client, Providers: map[string]int{}})
+		}
+		cs := &stats[len(stats)-1]
+		cs.Requests += served
+		if provider != "" && served > 0 {
+			cs.Providers[provider] += served
+		}
+		cs.Errors += errs
+		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil && t.After(cs.LastSeen) {
+			cs.LastSeen = t
+		}
+	}
+	return stats, rows.Err()
+}
+
+// ProviderStats summarizes each provider's attempts logged since the given
+// time, keyed by provider. Skipped providers are not counted.
+func (ldb *LogDB) ProviderStats(since time.Time) (map[string]ProviderStats, error) {
+	rows, err := ldb.db.Query(`
+		SELECT provider, COUNT(*),
+			SUM(CASE WHEN status_code >= 200 AND status_code < 400 THEN 1 ELSE 0 END),
+			AVG(CASE WHEN status_code >= 200 AND status_code < 400 AND latency_ms > 0 THEN latency_ms END)
+		FROM logs
+		WHERE provider != '' AND timestamp >= ? AND (status_code > 0 OR level = 'error')
+		GROUP BY provider`, since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, fmt.Errorf("query provider stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]ProviderStats)
+	for rows.Next() {
+		var ps ProviderStats
+		var latency sql.NullFloat64
+		if err := rows.Scan(&ps.Provider, &ps.Requests, &ps.Successes, &latency); err != nil {
+			continue
+		}
+		ps.AvgLatencyMs = latency.Float64
+		stats[ps.Provider] = ps
+	}
+	return stats, rows.Err()
+}
+
+// ProviderMetrics returns per-provider metrics from the entries logged since
+// the given time.
+func (ldb *LogDB) ProviderMetrics(since time.Time) ([]ProviderMetrics, error) {
+	rows, err := ldb.db.Query(`
+		SELECT provider, level, message, status_code, latency_ms, input_tokens, output_tokens
+		FROM logs
+		WHERE provider != '' AND timestamp >= ?`, since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, fmt.Errorf("query provider metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var c metricsCollector
+	for rows.Next() {
+		var e LogEntry
+		var level string
+		if err := rows.Scan(&e.Provider, &level, &e.Message, &e.StatusCode, &e.LatencyMs, &e.InputTokens, &e.OutputTokens); err != nil {
+			continue
+		}
+		e.Level = LogLevel(level)
+		c.add(e)
+	}
+	return c.metrics(), rows.Err()
+}
+
+// addColumnIfMissing adds a column to an existing table.
+func addColumnIfMissing(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
+
+// RecordSession stores a session's snapshot. Only the first snapshot of a
+// session is kept.
+func (ldb *LogDB) RecordSession(snap SessionSnapshot) error {
+	providers, err := json.Marshal(snap.Providers)
+	if err != nil {
+		return err
+	}
+	var routing []byte
+	if len(snap.Routing) > 0 {
+		if routing, err = json.Marshal(snap.Routing); err != nil {
+			return err
+		}
+	}
+	_, err = ldb.db.Exec(`
+		INSERT OR IGNORE INTO sessions (session_id, started_at, profile, cli, providers, routing)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, snap.SessionID, snap.StartedAt.UTC().Format(time.RFC3339Nano), snap.Profile, snap.CLI, string(providers), string(routing))
+	return err
+}
+
+// RecordSessionModel notes that provider served the session with model.
+func (ldb *LogDB) RecordSessionModel(sessionID, provider, model string, at time.Time) error {
+	_, err := ldb.db.Exec(`
+		INSERT OR IGNORE INTO session_models (session_id, provider, model, first_used)
+		VALUES (?, ?, ?, ?)
+	`, sessionID, provider, model, at.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// QuerySessions returns session snapshots, newest first. A non-empty
+// sessionID returns only that session.
+func (ldb *LogDB) QuerySessions(sessionID string, limit int) ([]SessionSnapshot, error) {
+	query := "SELECT session_id, started_at, profile, cli, providers, routing FROM sessions"
+	var args []interface{}
+	if sessionID != "" {
+		query += " WHERE session_id = ?"
+		args = append(args, sessionID)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " ORDER BY started_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := ldb.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query sessions: %w", err)
+	}
+	var snaps []SessionSnapshot
+	for rows.Next() {
+		var snap SessionSnapshot
+		var startedAt, providers, routing string
+		if err := rows.Scan(&snap.SessionID, &startedAt, &snap.Profile, &snap.CLI, &providers, &routing); err != nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, startedAt); err == nil {
+			snap.StartedAt = t
+		}
+		json.Unmarshal([]byte(providers), &snap.Providers)
+		if routing != "" {
+			json.Unmarshal([]byte(routing), &snap.Routing)
+		}
+		snaps = append(snaps, snap)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range snaps {
+		served, err := ldb.sessionModels(snaps[i].SessionID)
+		if err != nil {
+			return nil, err
+		}
+		snaps[i].Served = served
+	}
+	return snaps, nil
+}
+
+// sessionModels returns the provider/model pairs that served a session, in
+// the order they were first used.
+func (ldb *LogDB) sessionModels(sessionID string) ([]ServedModel, error) {
+	rows, err := ldb.db.Query(`
+		SELECT provider, model, first_used FROM session_models
+		WHERE session_id = ? ORDER BY first_used
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query session models: %w", err)
+	}
+	defer rows.Close()
+
+	var served []ServedModel
+	for rows.Next() {
+		var m ServedModel
+		var firstUsed string
+		if err := rows.Scan(&m.Provider, &m.Model, &firstUsed); err != nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, firstUsed); err == nil {
+			m.FirstUsed = t
+		}
+		served = append(served, m)
+	}
+	return served, rows.Err()
+}
+
+// RecordRateLimit stores provider's latest rate-limit state, replacing any
+// older one.
+func (ldb *LogDB) RecordRateLimit(provider string, rl *RateLimit) error {
+	state, err := json.Marshal(rl)
+	if err != nil {
+		return err
+	}
+	_, err = ldb.db.Exec(`
+		INSERT INTO rate_limits (provider, observed_at, state) VALUES (?, ?, ?)
+		ON CONFLICT(provider) DO UPDATE SET observed_at = excluded.observed_at, state = excluded.state
+	`, provider, rl.ObservedAt.UTC().Format(time.RFC3339Nano), string(state))
+	return err
+}
+
+// RateLimits returns the latest rate-limit state of each provider that
+// reported one.
+func (ldb *LogDB) RateLimits() (map[string]*RateLimit, error) {
+	rows, err := ldb.db.Query("SELECT provider, state FROM rate_limits")
+	if err != nil {
+		return nil, fmt.Errorf("query rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	limits := make(map[string]*RateLimit)
+	for rows.Next() {
+		var provider, state string
+		if err := rows.Scan(&provider, &state); err != nil {
+			continue
+		}
+		var rl RateLimit
+		if json.Unmarshal([]byte(state), &rl) == nil {
+			limits[provider] = &rl
+		}
+	}
+	return limits, rows.Err()
+}
+
+// RecordProviderHealth stores provider's latest health, replacing any older
+// one.
+func (ldb *LogDB) RecordProviderHealth(provider string, h ProviderHealth) error {
+	state, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	_, err = ldb.db.Exec(`
+		INSERT INTO provider_health (provider, observed_at, state) VALUES (?, ?, ?)
+		ON CONFLICT(provider) DO UPDATE SET observed_at = excluded.observed_at, state = excluded.state
+	`, provider, h.ObservedAt.UTC().Format(time.RFC3339Nano), string(state))
+	return err
+}
+
+// ProviderHealth returns the latest recorded health of each provider whose
+// health has changed.
+func (ldb *LogDB) ProviderHealth() (map[string]ProviderHealth, error) {
+	rows, err := ldb.db.Query("SELECT provider, state FROM provider_health")
+	if err != nil {
+		return nil, fmt.Errorf("query provider health: %w", err)
+	}
+	defer rows.Close()
+
+	health := make(map[string]ProviderHealth)
+	for rows.Next() {
+		var provider, state string
+		if err := rows.Scan(&provider, &state); err != nil {
+			continue
+		}
+		var h ProviderHealth
+		if json.Unmarshal([]byte(state), &h) == nil {
+			health[provider] = h
+		}
+	}
+	return health, rows.Err()
+}
+
+// RecordUsage adds a served request to the usage of its day, provider and
+// profile.
+func (ldb *LogDB) RecordUsage(u UsageRecord) error {
+	_, err := ldb.db.Exec(`
+		INSERT INTO usage (day, provider, profile, requests, input_tokens, output_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(day, provider, profile) DO UPDATE SET
+			requests = requests + excluded.requests,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cost_usd = cost_usd + excluded.cost_usd
+	`, u.Day, u.Provider, u.Profile, u.Requests, u.InputTokens, u.OutputTokens, u.CostUSD)
+	return err
+}
+
+// Usage returns the usage recorded on sinceDay (YYYY-MM-DD) and later, by
+// day, provider and profile.
+func (ldb *LogDB) Usage(sinceDay string) ([]UsageRecord, error) {
+	rows, err := ldb.db.Query(`
+		SELECT day, provider, profile, requests, input_tokens, output_tokens, cost_usd
+		FROM usage WHERE day >= ? ORDER BY day, provider, profile`, sinceDay)
+	if err != nil {
+		return nil, fmt.Errorf("query usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []UsageRecord
+	for rows.Next() {
+		var u UsageRecord
+		if err := rows.Scan(&u.Day, &u.Provider, &u.Profile, &u.Requests, &u.InputTokens, &u.OutputTokens, &u.CostUSD); err != nil {
+			continue
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// ClaimAutoOrderRun records that profile's provider order is evaluated at
+// now, unless that already happened less than interval ago, in this process
+// or another. It reports whether the caller should evaluate it.
+func (ldb *LogDB) ClaimAutoOrderRun(profile string, now time.Time, interval time.Duration) (bool, error) {
+	res, err := ldb.db.Exec(`
+		INSERT INTO auto_order_runs (profile, run_at) VALUES (?, ?)
+		ON CONFLICT(profile) DO UPDATE SET run_at = excluded.run_at WHERE run_at <= ?
+	`, profile, now.Unix(), now.Add(-interval).Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 // Close stops the background writer and closes the database.
 func (ldb *LogDB) Close() error {
 	close(ldb.writeCh)
@@ -1,10 +1,14 @@
 package proxy
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/dopejs/opencc/internal/config"
 )
 
 func TestLogDBInsertAndQuery(t *testing.T) {
@@ -122,6 +126,73 @@ func TestLogDBNewestFirst(t *testing.T) {
 	}
 }
 
+func TestLogDBTail(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	messages := func(entries []LogEntry) []string {
+		var m []string
+		for _, e := range entries {
+			m = append(m, e.Message)
+		}
+		return m
+	}
+
+	entries, lastID, err := db.Tail(LogFilter{}, 0)
+	if err != nil || len(entries) != 0 || lastID != 0 {
+		t.Fatalf("empty Tail = %v, %d, %v", entries, lastID, err)
+	}
+
+	now := time.Now()
+	db.Insert(LogEntry{Timestamp: now.Add(-2 * time.Hour), Level: LogLevelInfo, Provider: "p1", Message: "old"})
+	db.Insert(LogEntry{Timestamp: now.Add(-2 * time.Second), Level: LogLevelInfo, Provider: "p1", Message: "first"})
+	db.Insert(LogEntry{Timestamp: now.Add(-1 * time.Second), Level: LogLevelError, Provider: "p2", Message: "second"})
+	db.Insert(LogEntry{Timestamp: now, Level: LogLevelInfo, Provider: "p1", Message: "third"})
+	time.Sleep(700 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   []string
+	}{
+		{"all, oldest first", LogFilter{}, []string{"old", "first", "second", "third"}},
+		{"newest two", LogFilter{Limit: 2}, []string{"second", "third"}},
+		{"provider", LogFilter{Provider: "p1"}, []string{"old", "first", "third"}},
+		{"since", LogFilter{Since: now.Add(-time.Hour)}, []string{"first", "second", "third"}},
+		{"errors", LogFilter{ErrorsOnly: true}, []string{"second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, lastID, err = db.Tail(tt.filter, 0)
+			if err != nil {
+				t.Fatalf("Tail: %v", err)
+			}
+			if got := messages(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tail = %v, want %v", got, tt.want)
+			}
+			if lastID != 4 {
+				t.Errorf("lastID = %d, want 4", lastID)
+			}
+		})
+	}
+
+	// Continuing from the last ID returns only newer entries
+	db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Provider: "p2", Message: "fourth"})
+	db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Provider: "p1", Message: "fifth"})
+	time.Sleep(700 * time.Millisecond)
+	entries, lastID, err = db.Tail(LogFilter{Provider: "p1"}, 4)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if got := messages(entries); !reflect.DeepEqual(got, []string{"fifth"}) || lastID != 6 {
+		t.Errorf("Tail after 4 = %v, %d, want [fifth], 6", got, lastID)
+	}
+}
+
 func TestLogDBGetProviders(t *testing.T) {
 	dir := t.TempDir()
 	db, err := OpenLogDB(dir)
@@ -258,3 +329,399 @@ func TestLogDBResponseBody(t *testing.T) {
 		t.Errorf("error = %q, want empty", results[0].Error)
 	}
 }
+
+func TestLogDBSessions(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	snap := SessionSnapshot{
+		SessionID: "s1",
+		StartedAt: start,
+		Profile:   "work",
+		CLI:       "claude",
+		Providers: []ProviderSnapshot{{Name: "p1", Type: "anthropic", SonnetModel: "sonnet-x"}},
+		Routing:   map[string]ScenarioSnapshot{"think": {Providers: []string{"p2"}, Models: map[string]string{"p2": "deep"}}},
+	}
+	if err := db.RecordSession(snap); err != nil {
+		t.Fatalf("RecordSession: %v", err)
+	}
+	// A later snapshot of the same session is ignored
+	if err := db.RecordSession(SessionSnapshot{SessionID: "s1", StartedAt: start, Profile: "other"}); err != nil {
+		t.Fatalf("RecordSession again: %v", err)
+	}
+	if err := db.RecordSession(SessionSnapshot{SessionID: "s2", StartedAt: start.Add(time.Minute)}); err != nil {
+		t.Fatalf("RecordSession s2: %v", err)
+	}
+	db.RecordSessionModel("s1", "p1", "sonnet-x", start)
+	db.RecordSessionModel("s1", "p2", "deep", start.Add(time.Second))
+	db.RecordSessionModel("s1", "p1", "sonnet-x", start.Add(2*time.Second))
+
+	all, err := db.QuerySessions("", 10)
+	if err != nil {
+		t.Fatalf("QuerySessions: %v", err)
+	}
+	if len(all) != 2 || all[0].SessionID != "s2" {
+		t.Fatalf("sessions = %+v, want s2 then s1", all)
+	}
+
+	got, err := db.QuerySessions("s1", 1)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("QuerySessions(s1) = %+v, %v", got, err)
+	}
+	s1 := got[0]
+	if s1.Profile != "work" || s1.CLI != "claude" || len(s1.Providers) != 1 || s1.Providers[0].SonnetModel != "sonnet-x" {
+		t.Errorf("snapshot = %+v", s1)
+	}
+	if s1.Routing["think"].Models["p2"] != "deep" {
+		t.Errorf("routing = %+v", s1.Routing)
+	}
+	if len(s1.Served) != 2 || s1.Served[0].Provider != "p1" || s1.Served[1].Model != "deep" {
+		t.Errorf("served = %+v", s1.Served)
+	}
+}
+
+func TestLogDBClientStats(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, e := range []LogEntry{
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", Client: "claude", StatusCode: 200, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p2", Client: "claude", StatusCode: 200, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelError, Provider: "p1", Client: "claude", StatusCode: 500, Message: "server error"},
+		{Timestamp: now.Add(time.Second), Level: LogLevelInfo, Provider: "p1", Client: "scripts", StatusCode: 200, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", StatusCode: 200, Message: "no client"},
+	} {
+		db.Insert(e)
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	clients, err := db.GetClients()
+	if err != nil {
+		t.Fatalf("GetClients: %v", err)
+	}
+	if len(clients) != 2 || clients[0] != "claude" || clients[1] != "scripts" {
+		t.Errorf("clients = %v", clients)
+	}
+
+	results, err := db.Query(LogFilter{Client: "claude", Limit: 100})
+	if err != nil {
+		t.Fatalf("Query client: %v", err)
+	}
+	if len(results) != 3 || results[0].Client != "claude" {
+		t.Errorf("got %d entries for claude, want 3", len(results))
+	}
+
+	stats, err := db.ClientStats()
+	if err != nil {
+		t.Fatalf("ClientStats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d clients, want 2: %+v", len(stats), stats)
+	}
+	claude := stats[0]
+	if claude.Client != "claude" || claude.Requests != 2 || claude.Errors != 1 || claude.Providers["p1"] != 1 || claude.Providers["p2"] != 1 {
+		t.Errorf("claude stats = %+v", claude)
+	}
+	if !stats[1].LastSeen.Equal(now.Add(time.Second)) {
+		t.Errorf("scripts last seen = %v, want %v", stats[1].LastSeen, now.Add(time.Second))
+	}
+}
+
+func TestLogDBAddsClientColumn(t *testing.T) {
+	dir := t.TempDir()
+	old, err := sql.Open("sqlite", filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`CREATE TABLE logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp DATETIME NOT NULL, level TEXT NOT NULL,
+		provider TEXT DEFAULT '', message TEXT DEFAULT '', status_code INTEGER DEFAULT 0,
+		method TEXT DEFAULT '', path TEXT DEFAULT '', error TEXT DEFAULT '', response_body TEXT DEFAULT '')`); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	db, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB on old schema: %v", err)
+	}
+	defer db.Close()
+	db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Client: "codex", Message: "ok", RequestID: "req_1", Model: "m", InputTokens: 3})
+	time.Sleep(700 * time.Millisecond)
+
+	results, err := db.Query(LogFilter{Client: "codex", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("got %d entries, want 1", len(results))
+	}
+
+	results, err = db.Query(LogFilter{RequestID: "req_1", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query request: %v", err)
+	}
+	if len(results) != 1 || results[0].Model != "m" || results[0].InputTokens != 3 {
+		t.Errorf("request entries = %+v", results)
+	}
+}
+
+func TestLogDBProviderStats(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, e := range []LogEntry{
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", StatusCode: 200, LatencyMs: 100, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", StatusCode: 200, LatencyMs: 300, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelError, Provider: "p1", StatusCode: 500, Message: "server error"},
+		{Timestamp: now, Level: LogLevelError, Provider: "p1", Message: "request failed", Error: "dial tcp"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", Message: "skipping (unhealthy)"},
+		{Timestamp: now.Add(-48 * time.Hour), Level: LogLevelError, Provider: "p2", StatusCode: 500, Message: "old"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p2", StatusCode: 200, LatencyMs: 50, Message: "success 200"},
+	} {
+		db.Insert(e)
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	stats, err := db.ProviderStats(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ProviderStats: %v", err)
+	}
+	if p1 := stats["p1"]; p1.Requests != 4 || p1.Successes != 2 || p1.AvgLatencyMs != 200 || p1.SuccessRate() != 0.5 {
+		t.Errorf("p1 = %+v", p1)
+	}
+	if p2 := stats["p2"]; p2.Requests != 1 || p2.Successes != 1 || p2.AvgLatencyMs != 50 {
+		t.Errorf("p2 = %+v", p2)
+	}
+
+	results, err := db.Query(LogFilter{Provider: "p2", StatusCode: 200, Limit: 10})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 || results[0].LatencyMs != 50 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestLogDBRateLimits(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	older := &RateLimit{Requests: &RateLimitWindow{Limit: 50, Remaining: 40}, ObservedAt: now.Add(-time.Minute)}
+	newer := &RateLimit{Requests: &RateLimitWindow{Limit: 50, Remaining: 3, Reset: now.Add(30 * time.Second)}, ObservedAt: now}
+	for _, rl := range []*RateLimit{older, newer} {
+		if err := db.RecordRateLimit("p1", rl); err != nil {
+			t.Fatalf("RecordRateLimit: %v", err)
+		}
+	}
+
+	limits, err := db.RateLimits()
+	if err != nil {
+		t.Fatalf("RateLimits: %v", err)
+	}
+	got := limits["p1"]
+	if len(limits) != 1 || got == nil || got.Requests == nil || got.Requests.Remaining != 3 || !got.Requests.Reset.Equal(newer.Requests.Reset) {
+		t.Errorf("limits = %+v", limits)
+	}
+	if got != nil && got.Tokens != nil {
+		t.Errorf("unreported tokens window = %+v, want nil", got.Tokens)
+	}
+}
+
+func TestLogDBProviderHealth(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	failing := &Provider{Name: "p1", Healthy: true}
+	failing.MarkFailed()
+	failing.MarkFailed()
+	recovered := &Provider{Name: "p2", Healthy: true}
+	recovered.MarkAuthFailed()
+	recovered.MarkHealthy()
+	now := time.Now()
+	for _, p := range []*Provider{failing, recovered} {
+		if err := db.RecordProviderHealth(p.Name, p.Health(now)); err != nil {
+			t.Fatalf("RecordProviderHealth: %v", err)
+		}
+	}
+
+	health, err := db.ProviderHealth()
+	if err != nil {
+		t.Fatalf("ProviderHealth: %v", err)
+	}
+	h := health["p1"]
+	if len(health) != 2 || h.Healthy || h.BackoffMs != (2*InitialBackoff).Milliseconds() || h.FailedAt == nil || h.RetryAt == nil {
+		t.Fatalf("health = %+v", health)
+	}
+	if !h.RetryAt.Equal(h.FailedAt.Add(2 * InitialBackoff)) {
+		t.Errorf("RetryAt = %v, want FailedAt + backoff", h.RetryAt)
+	}
+	if h.HealthyAt(now) || !h.HealthyAt(h.RetryAt.Add(time.Second)) {
+		t.Errorf("HealthyAt should turn true once the backoff expires")
+	}
+	if h := health["p2"]; !h.Healthy || h.AuthFailed || h.BackoffMs != 0 || h.RetryAt != nil || !h.HealthyAt(now) {
+		t.Errorf("recovered health = %+v", h)
+	}
+}
+
+func TestLogDBUsage(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	for _, u := range []UsageRecord{
+		{Day: "2026-01-01", Provider: "p1", Profile: "default", Requests: 1, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5},
+		{Day: "2026-01-02", Provider: "p1", Profile: "default", Requests: 1, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5},
+		{Day: "2026-01-02", Provider: "p1", Profile: "default", Requests: 1, InputTokens: 50, OutputTokens: 5, CostUSD: 0.25},
+		{Day: "2026-01-02", Provider: "p2", Profile: "work", Requests: 1, InputTokens: 7, OutputTokens: 3},
+	} {
+		if err := db.RecordUsage(u); err != nil {
+			t.Fatalf("RecordUsage: %v", err)
+		}
+	}
+
+	usage, err := db.Usage("2026-01-02")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	want := []UsageRecord{
+		{Day: "2026-01-02", Provider: "p1", Profile: "default", Requests: 2, InputTokens: 150, OutputTokens: 15, CostUSD: 0.75},
+		{Day: "2026-01-02", Provider: "p2", Profile: "work", Requests: 1, InputTokens: 7, OutputTokens: 3},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+}
+
+func TestLogDBProviderMetrics(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, e := range []LogEntry{
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", StatusCode: 200, LatencyMs: 100, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", StatusCode: 200, LatencyMs: 300, Message: "success 200"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", Message: usageMessage, InputTokens: 1000, OutputTokens: 50},
+		{Timestamp: now, Level: LogLevelError, Provider: "p1", StatusCode: 429, Message: "rate limited"},
+		{Timestamp: now, Level: LogLevelInfo, Provider: "p1", Message: "skipping (unhealthy)"},
+		{Timestamp: now.Add(-48 * time.Hour), Level: LogLevelInfo, Provider: "p2", StatusCode: 200, LatencyMs: 50, Message: "old"},
+	} {
+		db.Insert(e)
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	metrics, err := db.ProviderMetrics(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ProviderMetrics: %v", err)
+	}
+	want := []ProviderMetrics{{
+		Provider: "p1", Requests: 3, Errors: 1, SuccessRate: 2.0 / 3,
+		P50LatencyMs: 100, P95LatencyMs: 300, InputTokens: 1000, OutputTokens: 50,
+	}}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics = %+v, want %+v", metrics, want)
+	}
+}
+
+func TestLoadBudgetSpend(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+	for _, e := range []LogEntry{
+		{Timestamp: now.Add(-time.Hour), Level: LogLevelInfo, Provider: "p1", Message: usageMessage, InputTokens: 300, OutputTokens: 100},
+		{Timestamp: now.AddDate(0, 0, -3), Level: LogLevelInfo, Provider: "p1", Message: usageMessage, InputTokens: 500, OutputTokens: 100},
+		{Timestamp: now.AddDate(0, -1, 0), Level: LogLevelInfo, Provider: "p1", Message: usageMessage, InputTokens: 5000, OutputTokens: 5000},
+		{Timestamp: now.Add(-time.Hour), Level: LogLevelInfo, Provider: "p2", Message: usageMessage, InputTokens: 5000, OutputTokens: 5000},
+	} {
+		db.Insert(e)
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	p1 := &Provider{Name: "p1", DailyBudgetUSD: 10, MonthlyTokenLimit: 2000, Pricing: &config.ProviderPricing{InputPerMTok: 1e4, OutputPerMTok: 1e4}}
+	p2 := &Provider{Name: "p2"}
+	srv := NewProxyServer([]*Provider{p1, p2}, discardLogger())
+	if err := srv.LoadBudgetSpend(db, now); err != nil {
+		t.Fatalf("LoadBudgetSpend: %v", err)
+	}
+	if dayCost, monthTokens := p1.spend.totals(now); dayCost != 4 || monthTokens != 1000 {
+		t.Errorf("p1 spend = $%v, %d tokens, want $4, 1000 tokens", dayCost, monthTokens)
+	}
+	if _, monthTokens := p2.spend.totals(now); monthTokens != 0 {
+		t.Errorf("p2 without a budget counted %d tokens", monthTokens)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		values []int64
+		p      float64
+		want   int64
+	}{
+		{nil, 0.5, 0},
+		{[]int64{7}, 0.95, 7},
+		{[]int64{1, 2, 3, 4}, 0.5, 2},
+		{[]int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 0.95, 19},
+		{[]int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 0.5, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %d, want %d", tt.values, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestLogDBClaimAutoOrderRun(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, tt := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{now, true},
+		{now.Add(time.Hour), false}, // within the interval of the first run
+		{now.Add(24 * time.Hour), true},
+	} {
+		got, err := db.ClaimAutoOrderRun("work", tt.at, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("ClaimAutoOrderRun: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("ClaimAutoOrderRun(%v) = %v, want %v", tt.at.Sub(now), got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
@@ -22,9 +23,16 @@ const (
 type LogEntry struct {
 	Timestamp    time.Time `json:"timestamp"`
 	Level        LogLevel  `json:"level"`
+	RequestID    string    `json:"request_id,omitempty"`
 	Provider     string    `json:"provider,omitempty"`
+	Client       string    `json:"client,omitempty"`
 	Message      string    `json:"message"`
 	StatusCode   int       `json:"status_code,omitempty"`
+	LatencyMs    int64     `json:"latency_ms,omitempty"` // time to response headers of a successful attempt
+	Scenario     string    `json:"scenario,omitempty"`
+	Model        string    `json:"model,omitempty"` // model sent to the provider
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
 	Method       string    `json:"method,omitempty"`
 	Path         string    `json:"path,omitempty"`
 	Error        string    `json:"error,omitempty"`
@@ -154,6 +162,10 @@ func (l *StructuredLogger) formatEntry(entry LogEntry) string {
 		msg += fmt.Sprintf(" %s %s", entry.Method, entry.Path)
 	}
 
+	if entry.Client != "" {
+		msg += " client=" + entry.Client
+	}
+
 	if entry.StatusCode > 0 {
 		msg += fmt.Sprintf(" status=%d", entry.StatusCode)
 	}
@@ -231,12 +243,6 @@ func (l *StructuredLogger) RequestError(provider, method, path string, err error
 
 // RequestErrorWithResponse logs a request error with response details.
 func (l *StructuredLogger) RequestErrorWithResponse(provider, method, path string, statusCode int, message string, responseBody []byte) {
-	// Truncate response body if too long
-	bodyStr := string(responseBody)
-	if len(bodyStr) > 500 {
-		bodyStr = bodyStr[:500] + "..."
-	}
-
 	l.Log(LogEntry{
 		Level:        LogLevelError,
 		Provider:     provider,
@@ -244,10 +250,19 @@ func (l *StructuredLogger) RequestErrorWithResponse(provider, method, path strin
 		Path:         path,
 		StatusCode:   statusCode,
 		Message:      message,
-		ResponseBody: bodyStr,
+		ResponseBody: truncateResponseBody(responseBody),
 	})
 }
 
+// truncateResponseBody shortens a logged response body to 500 bytes.
+func truncateResponseBody(responseBody []byte) string {
+	bodyStr := string(responseBody)
+	if len(bodyStr) > 500 {
+		bodyStr = bodyStr[:500] + "..."
+	}
+	return bodyStr
+}
+
 // HasEntries returns true if the in-memory log buffer has entries.
 func (l *StructuredLogger) HasEntries() bool {
 	l.mu.Lock()
@@ -296,24 +311,107 @@ func (l *StructuredLogger) GetProviders() []string {
 	return providers
 }
 
+// GetClients returns a list of unique client names from the logs.
+func (l *StructuredLogger) GetClients() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var clients []string
+	for _, entry := range l.entries {
+		if entry.Client != "" && !seen[entry.Client] {
+			seen[entry.Client] = true
+			clients = append(clients, entry.Client)
+		}
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// GetClientStats summarizes the in-memory logs per client.
+func (l *StructuredLogger) GetClientStats() []ClientStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	byClient := make(map[string]*ClientStats)
+	var names []string
+	for _, entry := range l.entries {
+		if entry.Client == "" {
+			continue
+		}
+		cs, ok := byClient[entry.Client]
+		if !ok {
+			cs = &ClientStats{Client: entry.Client, Providers: map[string]int{}}
+			byClient[entry.Client] = cs
+			names = append(names, entry.Client)
+		}
+		cs.add(entry)
+	}
+	sort.Strings(names)
+	stats := make([]ClientStats, 0, len(names))
+	for _, name := range names {
+		stats = append(stats, *byClient[name])
+	}
+	return stats
+}
+
+// ClientStats summarizes the logged traffic of one client.
+type ClientStats struct {
+	Client    string         `json:"client"`
+	Requests  int            `json:"requests"`  // responses served successfully
+	Errors    int            `json:"errors"`    // failed attempts and requests
+	Providers map[string]int `json:"providers"` // provider → responses served
+	LastSeen  time.Time      `json:"last_seen"`
+}
+
+// add counts a log entry: a served response is an entry with a 2xx or 3xx
+// status, an error is an error-level entry.
+func (cs *ClientStats) add(entry LogEntry) {
+	if entry.StatusCode >= 200 && entry.StatusCode < 400 {
+		cs.Requests++
+		if entry.Provider != "" {
+			cs.Providers[entry.Provider]++
+		}
+	}
+	if entry.Level == LogLevelError {
+		cs.Errors++
+	}
+	if entry.Timestamp.After(cs.LastSeen) {
+		cs.LastSeen = entry.Timestamp
+	}
+}
+
 // LogFilter defines criteria for filtering log entries.
 type LogFilter struct {
-	Provider   string   `json:"provider,omitempty"`
-	Level      LogLevel `json:"level,omitempty"`      // empty means all levels
-	ErrorsOnly bool     `json:"errors_only,omitempty"` // only error and warn levels
-	StatusCode int      `json:"status_code,omitempty"` // filter by specific status code
-	StatusMin  int      `json:"status_min,omitempty"`  // filter by status code range (min)
-	StatusMax  int      `json:"status_max,omitempty"`  // filter by status code range (max)
-	Limit      int      `json:"limit,omitempty"`       // max entries to return
+	RequestID  string    `json:"request_id,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Client     string    `json:"client,omitempty"`
+	Level      LogLevel  `json:"level,omitempty"`       // empty means all levels
+	ErrorsOnly bool      `json:"errors_only,omitempty"` // only error and warn levels
+	StatusCode int       `json:"status_code,omitempty"` // filter by specific status code
+	StatusMin  int       `json:"status_min,omitempty"`  // filter by status code range (min)
+	StatusMax  int       `json:"status_max,omitempty"`  // filter by status code range (max)
+	Since      time.Time `json:"since,omitzero"`        // only entries logged at or after this time
+	Limit      int       `json:"limit,omitempty"`       // max entries to return
 }
 
 // Match checks if a log entry matches the filter criteria.
 func (f LogFilter) Match(entry LogEntry) bool {
+	// Request filter
+	if f.RequestID != "" && entry.RequestID != f.RequestID {
+		return false
+	}
+
 	// Provider filter
 	if f.Provider != "" && entry.Provider != f.Provider {
 		return false
 	}
 
+	// Client filter
+	if f.Client != "" && entry.Client != f.Client {
+		return false
+	}
+
 	// Level filter
 	if f.Level != "" && entry.Level != f.Level {
 		return false
@@ -337,6 +435,11 @@ func (f LogFilter) Match(entry LogEntry) bool {
 		return false
 	}
 
+	// Time filter
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+
 	return true
 }
 
@@ -345,6 +448,7 @@ type LogsResponse struct {
 	Entries   []LogEntry `json:"entries"`
 	Total     int        `json:"total"`
 	Providers []string   `json:"providers"`
+	Clients   []string   `json:"clients"`
 }
 
 // ToJSON serializes a log entry to JSON.
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// Models used for a provider's mappings that its config leaves empty.
+const (
+	DefaultModel          = "claude-sonnet-4-5"
+	DefaultReasoningModel = "claude-sonnet-4-5-thinking"
+	DefaultHaikuModel     = "claude-haiku-4-5"
+	DefaultOpusModel      = "claude-opus-4-5"
+	DefaultSonnetModel    = "claude-sonnet-4-5"
+)
+
+// Rules that choose the model sent to a provider, reported by PlanModels.
+const (
+	MappingRuleOverride  = "scenario override"
+	MappingRuleReasoning = "reasoning_model"
+	MappingRuleHaiku     = "haiku_model"
+	MappingRuleOpus      = "opus_model"
+	MappingRuleSonnet    = "sonnet_model"
+	MappingRuleDefault   = "model"
+	MappingRuleNone      = "unchanged"
+)
+
+// FillDefaultModels sets the provider's empty model mappings to the defaults.
+func (p *Provider) FillDefaultModels() {
+	for _, m := range []struct {
+		model *string
+		def   string
+	}{
+		{&p.Model, DefaultModel},
+		{&p.ReasoningModel, DefaultReasoningModel},
+		{&p.HaikuModel, DefaultHaikuModel},
+		{&p.OpusModel, DefaultOpusModel},
+		{&p.SonnetModel, DefaultSonnetModel},
+	} {
+		if *m.model == "" {
+			*m.model = m.def
+		}
+	}
+}
+
+// ModelMapping is the model one provider of the chain would be sent.
+type ModelMapping struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Rule     string `json:"rule"`               // which rule chose the model
+	Fallback bool   `json:"fallback,omitempty"` // tried only after the scenario route fails
+}
+
+// PlanModels dry-runs routing and model mapping for a request body: the
+// scenario it would be routed as and, in order, the model each provider
+// would receive. Strategy ordering and file pinning are not applied.
+func (s *ProxyServer) PlanModels(body map[string]interface{}) (config.Scenario, []ModelMapping) {
+	original, _ := body["model"].(string)
+	scenario := config.ScenarioDefault
+	defaults, routing := s.chains()
+	providers := defaults
+	var overrides map[string]string
+	scenarioRoute := false
+
+	if routing != nil && len(routing.ScenarioRoutes) > 0 {
+		threshold := routing.LongContextThreshold
+		if threshold <= 0 {
+			threshold = defaultLongContextThreshold
+		}
+		scenario = DetectScenario(body, threshold, "")
+		if sp, ok := routing.ScenarioRoutes[scenario]; ok {
+			providers = sp.Providers
+			overrides = sp.Models
+			scenarioRoute = true
+		}
+	}
+
+	var plan []ModelMapping
+	for _, p := range providers {
+		m := ModelMapping{Provider: p.Name}
+		if override := overrides[p.Name]; override != "" {
+			m.Model, m.Rule = override, MappingRuleOverride
+		} else if original != "" {
+			m.Model, m.Rule = s.mapModelRule(original, body, p)
+		} else {
+			m.Rule = MappingRuleNone
+		}
+		plan = append(plan, m)
+	}
+	if scenarioRoute {
+		for _, p := range defaults {
+			m := ModelMapping{Provider: p.Name, Fallback: true, Rule: MappingRuleNone}
+			if original != "" {
+				m.Model, m.Rule = s.mapModelRule(original, body, p)
+			}
+			plan = append(plan, m)
+		}
+	}
+	return scenario, plan
+}
+
+// mapModelRule is mapModel, also naming the rule that chose the model.
+func (s *ProxyServer) mapModelRule(original string, body map[string]interface{}, p *Provider) (string, string) {
+	// 1. Thinking mode → reasoning model
+	if hasThinkingEnabled(body) && p.ReasoningModel != "" {
+		return p.ReasoningModel, MappingRuleReasoning
+	}
+
+	// 2. Match by model type (case-insensitive)
+	lower := strings.ToLower(original)
+	if strings.Contains(lower, "haiku") && p.HaikuModel != "" {
+		return p.HaikuModel, MappingRuleHaiku
+	}
+	if strings.Contains(lower, "opus") && p.OpusModel != "" {
+		return p.OpusModel, MappingRuleOpus
+	}
+	if strings.Contains(lower, "sonnet") && p.SonnetModel != "" {
+		return p.SonnetModel, MappingRuleSonnet
+	}
+
+	// 3. Default model
+	if p.Model != "" {
+		return p.Model, MappingRuleDefault
+	}
+
+	// 4. No mapping — keep original
+	return original, MappingRuleNone
+}
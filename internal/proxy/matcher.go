@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// pathStep is one name of a matcher path. each is set for "name[]", which
+// looks into every element of the array at name.
+type pathStep struct {
+	key  string
+	each bool
+}
+
+// fieldMatcher is a compiled config.ScenarioMatcher.
+type fieldMatcher struct {
+	path  []pathStep
+	op    config.MatchOp
+	value string
+	re    *regexp.Regexp
+}
+
+// customScenarios holds the scenarios SetCustomScenarios registered, so the
+// next call can drop the ones no longer configured. Guarded by
+// scenarioDetectorsMu.
+var customScenarios []config.Scenario
+
+// SetCustomScenarios registers detectors for the user-defined scenarios,
+// each placed before its Before's detector if set, replacing the ones of the
+// previous call: a scenario removed from the config stops matching. A
+// scenario that can't be compiled is skipped, with an error returned for it.
+func SetCustomScenarios(scenarios []config.CustomScenario) []error {
+	var errs []error
+	detects := make([]ScenarioDetectFunc, len(scenarios))
+	for i, cs := range scenarios {
+		if cs.Name == "" || cs.Name.IsBuiltin() {
+			errs = append(errs, fmt.Errorf("scenario name %q is empty or built in", cs.Name))
+			continue
+		}
+		detect, err := CompileScenarioMatchers(cs.Match)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scenario %s: %w", cs.Name, err))
+			continue
+		}
+		detects[i] = detect
+	}
+
+	scenarioDetectorsMu.Lock()
+	defer scenarioDetectorsMu.Unlock()
+	detectors := slices.DeleteFunc(slices.Clone(scenarioDetectors), func(d scenarioDetector) bool {
+		return slices.Contains(customScenarios, d.scenario)
+	})
+	var names []config.Scenario
+	for i, cs := range scenarios {
+		if detects[i] != nil {
+			detectors = withScenarioDetector(detectors, cs.Before, cs.Name, detects[i])
+			names = append(names, cs.Name)
+		}
+	}
+	scenarioDetectors = detectors
+	customScenarios = names
+	return errs
+}
+
+// CompileScenarioMatchers returns a detector for requests that match all of
+// matchers. A matcher matches if any value at its path does.
+func CompileScenarioMatchers(matchers []config.ScenarioMatcher) (ScenarioDetectFunc, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("no matchers")
+	}
+	compiled := make([]fieldMatcher, len(matchers))
+	for i, m := range matchers {
+		fm, err := compileMatcher(m)
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = fm
+	}
+	return func(req ScenarioRequest) bool {
+		for _, fm := range compiled {
+			if !fm.matches(req.Body) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileMatcher(m config.ScenarioMatcher) (fieldMatcher, error) {
+	path, err := parseMatchPath(m.Path)
+	if err != nil {
+		return fieldMatcher{}, err
+	}
+	fm := fieldMatcher{path: path, op: m.Op, value: m.Value}
+	switch m.Op {
+	case config.MatchContains, config.MatchEquals:
+	case config.MatchRegex:
+		if fm.re, err = regexp.Compile(m.Value); err != nil {
+			return fieldMatcher{}, fmt.Errorf("path %s: %w", m.Path, err)
+		}
+	default:
+		return fieldMatcher{}, fmt.Errorf("path %s: unknown op %q", m.Path, m.Op)
+	}
+	return fm, nil
+}
+
+// parseMatchPath splits a path like "tools[].name" into its steps.
+func parseMatchPath(path string) ([]pathStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	var steps []pathStep
+	for _, name := range strings.Split(path, ".") {
+		step := pathStep{key: name}
+		if key, ok := strings.CutSuffix(name, "[]"); ok {
+			step = pathStep{key: key, each: true}
+		}
+		if step.key == "" || strings.ContainsAny(step.key, "[]") {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (fm fieldMatcher) matches(body map[string]interface{}) bool {
+	for _, v := range lookupPath(body, fm.path) {
+		text, ok := matchText(v)
+		if !ok {
+			continue
+		}
+		switch fm.op {
+		case config.MatchContains:
+			if strings.Contains(text, fm.value) {
+				return true
+			}
+		case config.MatchEquals:
+			if text == fm.value {
+				return true
+			}
+		case config.MatchRegex:
+			if fm.re.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupPath returns the values at path in v; more than one if the path
+// looks into arrays.
+func lookupPath(v interface{}, path []pathStep) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	next, ok := obj[path[0].key]
+	if !ok {
+		return nil
+	}
+	if !path[0].each {
+		return lookupPath(next, path[1:])
+	}
+	elems, ok := next.([]interface{})
+	if !ok {
+		return nil
+	}
+	var values []interface{}
+	for _, e := range elems {
+		values = append(values, lookupPath(e, path[1:])...)
+	}
+	return values
+}
+
+// matchText returns the text a matcher compares: strings as they are,
+// numbers and booleans formatted, and the strings inside objects and arrays
+// joined by newlines, so "system" matches whether it is a string or a list
+// of text blocks.
+func matchText(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case map[string]interface{}, []interface{}:
+		var texts []string
+		collectStrings(v, &texts)
+		return strings.Join(texts, "\n"), len(texts) > 0
+	}
+	return "", false
+}
+
+func collectStrings(v interface{}, texts *[]string) {
+	switch v := v.(type) {
+	case string:
+		*texts = append(*texts, v)
+	case []interface{}:
+		for _, e := range v {
+			collectStrings(e, texts)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			collectStrings(v[k], texts)
+		}
+	}
+}
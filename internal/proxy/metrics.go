@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ProviderMetrics summarizes a provider's logged attempts: how often it was
+// tried and failed, how fast it answered and how many tokens it served.
+type ProviderMetrics struct {
+	Provider     string  `json:"provider"`
+	Requests     int     `json:"requests"`       // attempts that got a response or failed
+	Errors       int     `json:"errors"`         // attempts that failed
+	SuccessRate  float64 `json:"success_rate"`   // fraction of attempts answered 2xx or 3xx
+	P50LatencyMs int64   `json:"p50_latency_ms"` // median time to response headers of successful attempts
+	P95LatencyMs int64   `json:"p95_latency_ms"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+}
+
+// metricsCollector builds ProviderMetrics from log entries. An attempt is an
+// entry with a status code or an error-level entry; skipped providers and
+// informational entries are not attempts. Token counts come from the usage
+// entries logged after each served response.
+type metricsCollector struct {
+	providers map[string]*providerSamples
+}
+
+type providerSamples struct {
+	metrics   ProviderMetrics
+	successes int
+	latencies []int64
+}
+
+func (c *metricsCollector) add(e LogEntry) {
+	if e.Provider == "" {
+		return
+	}
+	if c.providers == nil {
+		c.providers = make(map[string]*providerSamples)
+	}
+	ps, ok := c.providers[e.Provider]
+	if !ok {
+		ps = &providerSamples{metrics: ProviderMetrics{Provider: e.Provider}}
+		c.providers[e.Provider] = ps
+	}
+
+	if e.Message == usageMessage {
+		ps.metrics.InputTokens += int64(e.InputTokens)
+		ps.metrics.OutputTokens += int64(e.OutputTokens)
+		return
+	}
+	if e.StatusCode == 0 && e.Level != LogLevelError {
+		return
+	}
+	ps.metrics.Requests++
+	if e.StatusCode >= 200 && e.StatusCode < 400 {
+		ps.successes++
+		if e.LatencyMs > 0 {
+			ps.latencies = append(ps.latencies, e.LatencyMs)
+		}
+	} else {
+		ps.metrics.Errors++
+	}
+}
+
+// metrics returns the collected metrics sorted by provider name. Providers
+// that were only skipped are left out.
+func (c *metricsCollector) metrics() []ProviderMetrics {
+	result := []ProviderMetrics{}
+	for _, ps := range c.providers {
+		m := ps.metrics
+		if m.Requests == 0 && m.InputTokens == 0 && m.OutputTokens == 0 {
+			continue
+		}
+		if m.Requests > 0 {
+			m.SuccessRate = float64(ps.successes) / float64(m.Requests)
+		}
+		slices.Sort(ps.latencies)
+		m.P50LatencyMs = percentile(ps.latencies, 0.50)
+		m.P95LatencyMs = percentile(ps.latencies, 0.95)
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted values, or 0 if
+// there are none.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// GetProviderMetrics returns per-provider metrics from the in-memory entries
+// logged since the given time.
+func (l *StructuredLogger) GetProviderMetrics(since time.Time) []ProviderMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var c metricsCollector
+	for _, entry := range l.entries {
+		if !entry.Timestamp.Before(since) {
+			c.add(entry)
+		}
+	}
+	return c.metrics()
+}
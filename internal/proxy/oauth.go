@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+const (
+	// oauthRefreshMargin is how long before expiry an access token is
+	// replaced, so requests never go out with one about to lapse.
+	oauthRefreshMargin = time.Minute
+	// oauthDefaultLifetime is assumed for access tokens issued without an
+	// expires_in.
+	oauthDefaultLifetime = time.Hour
+)
+
+// OAuthAuth gets access tokens for a provider by exchanging its refresh
+// token at the token endpoint, reusing each one until shortly before it
+// expires.
+type OAuthAuth struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+
+	// OnRotate is called with the new refresh token when the endpoint
+	// issues one, so it can be saved. nil means rotated tokens are only
+	// kept in memory.
+	OnRotate func(refreshToken string)
+
+	configured string // the refresh token it was created with
+
+	mu           sync.Mutex
+	refreshToken string
+	token        string
+	expiry       time.Time
+}
+
+// NewOAuthAuth returns the token source for an OAuth configuration whose
+// refresh token is resolved.
+func NewOAuthAuth(cfg *config.OAuthConfig, refreshToken string) *OAuthAuth {
+	return &OAuthAuth{
+		TokenURL:     cfg.TokenURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Scope:        cfg.Scope,
+		configured:   refreshToken,
+		refreshToken: refreshToken,
+	}
+}
+
+// Token returns an access token, exchanging the refresh token for a new one
+// through client when there is none or it is about to expire.
+func (a *OAuthAuth) Token(ctx context.Context, client *http.Client) (string, error) {
+	// Report a rotated refresh token once the lock is released
+	var rotated string
+	defer func() {
+		if rotated != "" && a.OnRotate != nil {
+			a.OnRotate(rotated)
+		}
+	}()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.token != "" && now.Add(oauthRefreshMargin).Before(a.expiry) {
+		return a.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", a.refreshToken)
+	if a.ClientID != "" {
+		form.Set("client_id", a.ClientID)
+	}
+	if a.ClientSecret != "" {
+		form.Set("client_secret", a.ClientSecret)
+	}
+	if a.Scope != "" {
+		form.Set("scope", a.Scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth: token refresh: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&tok)
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return "", fmt.Errorf("oauth: token refresh failed (%d): %s %s", resp.StatusCode, tok.Error, tok.ErrorDescription)
+	}
+	lifetime := time.Duration(tok.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = oauthDefaultLifetime
+	}
+	a.token = tok.AccessToken
+	a.expiry = now.Add(lifetime)
+	if tok.RefreshToken != "" && tok.RefreshToken != a.refreshToken {
+		a.refreshToken = tok.RefreshToken
+		rotated = tok.RefreshToken
+	}
+	return a.token, nil
+}
+
+// Invalidate drops the cached access token, so the next request gets a new
+// one; the provider rejected it.
+func (a *OAuthAuth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// inherit takes over old's access and refresh tokens when a was created
+// with the refresh token old was, or rotated to, at the same endpoint: the
+// provider was rebuilt without new credentials, and a rotated refresh token
+// that couldn't be saved must not be lost.
+func (a *OAuthAuth) inherit(old *OAuthAuth) {
+	if old.TokenURL != a.TokenURL || old.ClientID != a.ClientID {
+		return
+	}
+	old.mu.Lock()
+	token, expiry, refreshToken := old.token, old.expiry, old.refreshToken
+	old.mu.Unlock()
+	if a.configured != old.configured && a.configured != refreshToken {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token, a.expiry, a.refreshToken = token, expiry, refreshToken
+}
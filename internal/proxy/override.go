@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ModelHeader forces the model sent upstream for a request, whatever the
+// provider's model mapping or the scenario route would choose. It is not
+// forwarded upstream.
+const ModelHeader = "X-OpenCC-Model"
+
+// ProviderHeader sends a request to the named provider alone, bypassing
+// routing, the strategy and failover. It is not forwarded upstream.
+const ProviderHeader = "X-OpenCC-Provider"
+
+const errTypeInvalidRequest = "invalid_request_error"
+
+// requestOverrides is what a request's override headers ask for.
+type requestOverrides struct {
+	model    string
+	provider string
+}
+
+// takeOverrides reads the override headers of r and removes them.
+func takeOverrides(r *http.Request) requestOverrides {
+	o := requestOverrides{
+		model:    strings.TrimSpace(r.Header.Get(ModelHeader)),
+		provider: strings.TrimSpace(r.Header.Get(ProviderHeader)),
+	}
+	r.Header.Del(ModelHeader)
+	r.Header.Del(ProviderHeader)
+	return o
+}
+
+// forcedModels maps every provider of the server to the model the request
+// forces, so it replaces mapping on whichever provider serves it. It
+// returns nil if no model is forced.
+func (s *ProxyServer) forcedModels(model string) map[string]string {
+	if model == "" {
+		return nil
+	}
+	models := make(map[string]string)
+	for _, p := range s.allProviders() {
+		models[p.Name] = model
+	}
+	return models
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultProbePath is requested by ProbeProvider. Listing models is cheap,
+// authenticated, and doesn't consume tokens.
+const DefaultProbePath = "/v1/models"
+
+// ProbeResult is the outcome of a lightweight provider reachability check.
+type ProbeResult struct {
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+}
+
+// Problem describes why the provider looks unusable, or "" if it looks fine.
+// Endpoints that don't implement the probe path (404, 405) count as reachable.
+func (r ProbeResult) Problem() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("unreachable: %v", r.Err)
+	case r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden:
+		return fmt.Sprintf("rejected credentials (%d)", r.StatusCode)
+	case r.StatusCode == http.StatusTooManyRequests:
+		return "rate limited (429)"
+	case r.StatusCode >= 500:
+		return fmt.Sprintf("server error (%d)", r.StatusCode)
+	}
+	return ""
+}
+
+// ProbeProvider sends a GET to the provider's probe path with its credentials.
+func ProbeProvider(ctx context.Context, client *http.Client, p *Provider) ProbeResult {
+	return probePath(ctx, client, p, DefaultProbePath)
+}
+
+// ProbeModels is ProbeProvider that also returns the IDs of the models the
+// provider lists, if it answers with an Anthropic- or OpenAI-style model
+// list; nil otherwise.
+func ProbeModels(ctx context.Context, client *http.Client, p *Provider) (ProbeResult, []string) {
+	var models []string
+	result := probeRequest(ctx, client, p, DefaultProbePath, func(r io.Reader) {
+		var list struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if json.NewDecoder(io.LimitReader(r, maxModelListBytes)).Decode(&list) != nil {
+			return
+		}
+		for _, m := range list.Data {
+			if m.ID != "" {
+				models = append(models, m.ID)
+			}
+		}
+	})
+	if result.StatusCode != http.StatusOK {
+		models = nil
+	}
+	return result, models
+}
+
+// maxModelListBytes bounds the model list ProbeModels reads; aggregators
+// list hundreds of models.
+const maxModelListBytes = 8 << 20
+
+// probePath is ProbeProvider for a given probe path.
+func probePath(ctx context.Context, client *http.Client, p *Provider, path string) ProbeResult {
+	return probeRequest(ctx, client, p, path, nil)
+}
+
+// probeRequest sends the probe and passes the response body to read, if
+// non-nil, before discarding the rest.
+func probeRequest(ctx context.Context, client *http.Client, p *Provider, path string, read func(io.Reader)) ProbeResult {
+	target := singleJoiningSlash(p.BaseURL.String(), path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return ProbeResult{Err: err}
+	}
+	p.applyAuth(req.Header)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	p.applyHeaders(req.Header)
+	if err := p.authorizeRequest(req, nil, client); err != nil {
+		return ProbeResult{Err: err}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Err: err}
+	}
+	if read != nil {
+		read(resp.Body)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return ProbeResult{StatusCode: resp.StatusCode, Latency: latency}
+}
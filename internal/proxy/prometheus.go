@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsPath is where the proxy's Prometheus metrics are served.
+const MetricsPath = "/metrics"
+
+// latencyBuckets are the upper bounds, in seconds, of the attempt latency
+// histogram.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// promMetrics counts proxy activity for the Prometheus endpoint. The zero
+// value is ready to use.
+type promMetrics struct {
+	mu          sync.Mutex
+	attempts    map[attemptKey]uint64 // provider attempts by status
+	failovers   map[string]uint64     // provider → attempts that failed over to the next provider
+	streamBytes map[string]uint64     // provider → SSE bytes relayed to clients
+	latency     map[string]*histogram // provider → time to response headers
+	cacheHits   uint64                // requests answered from the response cache
+	cacheMisses uint64                // cacheable requests sent on to a provider
+}
+
+type attemptKey struct {
+	provider string
+	status   string // HTTP status code, or "error" when no response arrived
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// attempt records a provider attempt. status is 0 when no response arrived.
+func (m *promMetrics) attempt(provider string, status int, latency time.Duration) {
+	key := attemptKey{provider: provider, status: "error"}
+	if status > 0 {
+		key.status = strconv.Itoa(status)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.attempts == nil {
+		m.attempts = make(map[attemptKey]uint64)
+		m.latency = make(map[string]*histogram)
+	}
+	m.attempts[key]++
+	if status == 0 {
+		return
+	}
+	h := m.latency[provider]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[provider] = h
+	}
+	seconds := latency.Seconds()
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// failover records a failed attempt after which the next provider is tried.
+func (m *promMetrics) failover(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failovers == nil {
+		m.failovers = make(map[string]uint64)
+	}
+	m.failovers[provider]++
+}
+
+// streamed records SSE bytes relayed to a client.
+func (m *promMetrics) streamed(provider string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.streamBytes == nil {
+		m.streamBytes = make(map[string]uint64)
+	}
+	m.streamBytes[provider] += uint64(n)
+}
+
+// cacheLookup records a response cache lookup.
+func (m *promMetrics) cacheLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// MetricsHandler serves the proxy's metrics in the Prometheus text format.
+func (s *ProxyServer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.writeMetrics(w, time.Now())
+	})
+}
+
+// writeMetrics writes every metric family in the Prometheus text format.
+func (s *ProxyServer) writeMetrics(w io.Writer, now time.Time) {
+	m := &s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP opencc_provider_requests_total Requests sent to a provider, by response status (\"error\" if none arrived).")
+	fmt.Fprintln(w, "# TYPE opencc_provider_requests_total counter")
+	keys := make([]attemptKey, 0, len(m.attempts))
+	for k := range m.attempts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "opencc_provider_requests_total{provider=%s,status=%s} %d\n", promLabel(k.provider), promLabel(k.status), m.attempts[k])
+	}
+
+	fmt.Fprintln(w, "# HELP opencc_provider_failovers_total Failed requests after which the next provider was tried.")
+	fmt.Fprintln(w, "# TYPE opencc_provider_failovers_total counter")
+	writeCounters(w, "opencc_provider_failovers_total", m.failovers)
+
+	fmt.Fprintln(w, "# HELP opencc_provider_stream_bytes_total Bytes of event streams relayed from a provider to clients.")
+	fmt.Fprintln(w, "# TYPE opencc_provider_stream_bytes_total counter")
+	writeCounters(w, "opencc_provider_stream_bytes_total", m.streamBytes)
+
+	fmt.Fprintln(w, "# HELP opencc_provider_latency_seconds Time from sending a request to a provider to its response headers.")
+	fmt.Fprintln(w, "# TYPE opencc_provider_latency_seconds histogram")
+	for _, name := range sortedKeys(m.latency) {
+		h := m.latency[name]
+		label := promLabel(name)
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "opencc_provider_latency_seconds_bucket{provider=%s,le=\"%s\"} %d\n", label, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "opencc_provider_latency_seconds_bucket{provider=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "opencc_provider_latency_seconds_sum{provider=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "opencc_provider_latency_seconds_count{provider=%s} %d\n", label, h.count)
+	}
+
+	if s.Cache != nil {
+		fmt.Fprintln(w, "# HELP opencc_cache_hits_total Requests answered from the response cache.")
+		fmt.Fprintln(w, "# TYPE opencc_cache_hits_total counter")
+		fmt.Fprintf(w, "opencc_cache_hits_total %d\n", m.cacheHits)
+		fmt.Fprintln(w, "# HELP opencc_cache_misses_total Cacheable requests the response cache could not answer.")
+		fmt.Fprintln(w, "# TYPE opencc_cache_misses_total counter")
+		fmt.Fprintf(w, "opencc_cache_misses_total %d\n", m.cacheMisses)
+	}
+
+	providers := s.allProviders()
+	fmt.Fprintln(w, "# HELP opencc_provider_healthy Whether a provider currently accepts requests (0 while in backoff).")
+	fmt.Fprintln(w, "# TYPE opencc_provider_healthy gauge")
+	for _, p := range providers {
+		healthy := 1
+		if retryAt := p.RetryAt(); !retryAt.IsZero() && now.Before(retryAt) {
+			healthy = 0
+		}
+		fmt.Fprintf(w, "opencc_provider_healthy{provider=%s} %d\n", promLabel(p.Name), healthy)
+	}
+	fmt.Fprintln(w, "# HELP opencc_provider_backoff_seconds Current backoff of a provider; 0 when it is healthy.")
+	fmt.Fprintln(w, "# TYPE opencc_provider_backoff_seconds gauge")
+	for _, p := range providers {
+		fmt.Fprintf(w, "opencc_provider_backoff_seconds{provider=%s} %s\n", promLabel(p.Name), strconv.FormatFloat(p.CurrentBackoff().Seconds(), 'g', -1, 64))
+	}
+}
+
+// writeCounters writes one provider-labelled sample per map entry.
+func writeCounters(w io.Writer, name string, values map[string]uint64) {
+	for _, provider := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s{provider=%s} %d\n", name, promLabel(provider), values[provider])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// promLabel quotes a label value, escaping as the text format requires.
+func promLabel(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
@@ -1,8 +1,10 @@
 package proxy
 
 import (
+	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dopejs/opencc/internal/config"
@@ -17,7 +19,7 @@ const (
 
 type Provider struct {
 	Name            string
-	Type            string // "anthropic" or "openai"
+	Type            string // "anthropic", "openai", "openai-responses", "azure-openai", "local", "gemini", "bedrock" or "vertex"
 	BaseURL         *url.URL
 	Token           string
 	Model           string
@@ -29,11 +31,157 @@ type Provider struct {
 	ClaudeEnvVars   map[string]string // Claude Code specific
 	CodexEnvVars    map[string]string // Codex specific
 	OpenCodeEnvVars map[string]string // OpenCode specific
+	Pricing         *config.ProviderPricing
+	Capabilities    *config.ProviderCapabilities
+	MaxRequestBytes int64               // larger request bodies skip this provider; 0 = unlimited
+	MaxConcurrent   int                 // requests forwarded at once; 0 = unlimited
+	Retry           *config.RetryPolicy // retries on this provider; nil = the server's policy
+	Transport       http.RoundTripper   // reaches the provider through its upstream proxy; nil = the server's client
+	Headers         map[string]string   // sent with every request to the provider
+	SystemPrompt    string              // put before the system prompt of every request; "" = none
+	ResponseTimeout time.Duration       // time to response headers; 0 = the request deadline's share
+	IdleTimeout     time.Duration       // longest a response body may go without data; 0 = no limit
 	Healthy         bool
 	AuthFailed      bool
 	FailedAt        time.Time
 	Backoff         time.Duration
 	mu              sync.Mutex
+
+	// Bedrock holds the resolved region and AWS credentials a "bedrock"
+	// provider signs its requests with.
+	Bedrock *config.BedrockConfig
+
+	// Vertex holds the project, region and OAuth2 credentials of a
+	// "vertex" provider.
+	Vertex *VertexAuth
+
+	// Azure holds the API version and deployments of an "azure-openai"
+	// provider; nil uses the default version and the model names.
+	Azure *config.AzureConfig
+
+	// OAuth gets the access tokens a provider authorizing with a refresh
+	// token sends in place of Token.
+	OAuth *OAuthAuth
+
+	// Budgets; a provider that has used one up is skipped until its window
+	// (the local day or month) ends. 0 = no limit.
+	DailyBudgetUSD    float64 // estimated spend, from Pricing
+	MonthlyTokenLimit int64   // input plus output tokens
+
+	// clear caches "Healthy with no backoff pending" so the per-request
+	// IsHealthy/MarkHealthy calls skip mu while the provider is fine. It is
+	// only set under mu after checking the fields and reset by every Mark*Failed.
+	clear atomic.Bool
+
+	// Unavailable reports why the provider is administratively unavailable
+	// at now (manual cooldown, maintenance window), or "" if it may be used.
+	// nil means always available.
+	Unavailable func(now time.Time) string
+
+	// CurrentToken returns the provider's token as currently configured, so
+	// a token fixed after auth failures is picked up without a restart.
+	// nil means the token never changes.
+	CurrentToken func() string
+
+	// rotated holds the token picked up from CurrentToken, if any; Token
+	// itself is never written after construction.
+	rotated atomic.Pointer[string]
+
+	// rateLimit holds the rate-limit state from the provider's latest
+	// response that reported one.
+	rateLimit atomic.Pointer[RateLimit]
+
+	// spend is the usage counted against the budgets.
+	spend budgetSpend
+
+	// sem holds a token per request in flight when MaxConcurrent is set.
+	sem chan struct{}
+
+	// latency is the smoothed time to response headers of successful
+	// attempts, in nanoseconds; 0 until the first one.
+	latency atomic.Int64
+}
+
+// authToken returns the token to send to the provider.
+func (p *Provider) authToken() string {
+	if t := p.rotated.Load(); t != nil {
+		return *t
+	}
+	return p.Token
+}
+
+// RateLimit returns the rate-limit state from the provider's latest
+// response that reported one, or nil if none has.
+func (p *Provider) RateLimit() *RateLimit {
+	return p.rateLimit.Load()
+}
+
+// refreshToken checks an auth-failed provider for a changed token. If the
+// configured token differs, the provider switches to it and its auth backoff
+// is cleared at once: the failures were about the old credentials. It
+// reports whether the token changed.
+func (p *Provider) refreshToken() bool {
+	if p.CurrentToken == nil || p.clear.Load() {
+		return false
+	}
+	p.mu.Lock()
+	authFailed := p.AuthFailed
+	p.mu.Unlock()
+	if !authFailed {
+		return false
+	}
+	token := p.CurrentToken()
+	if token == "" || token == p.authToken() {
+		return false
+	}
+	p.rotated.Store(&token)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Healthy = true
+	p.AuthFailed = false
+	p.Backoff = 0
+	p.clear.Store(true)
+	return true
+}
+
+// inheritState carries old's runtime state over to p, a rebuilt provider
+// of the same name: its health and backoff, latency, OAuth tokens, rate
+// limits and budget usage.
+func (p *Provider) inheritState(old *Provider) {
+	old.mu.Lock()
+	healthy, authFailed, failedAt, backoff := old.Healthy, old.AuthFailed, old.FailedAt, old.Backoff
+	old.mu.Unlock()
+
+	p.mu.Lock()
+	p.Healthy, p.AuthFailed, p.FailedAt, p.Backoff = healthy, authFailed, failedAt, backoff
+	p.clear.Store(false)
+	p.mu.Unlock()
+
+	p.latency.Store(old.latency.Load())
+	if p.OAuth != nil && old.OAuth != nil {
+		p.OAuth.inherit(old.OAuth)
+	}
+	if rl := old.rateLimit.Load(); rl != nil {
+		p.rateLimit.Store(rl)
+	}
+	old.spend.mu.Lock()
+	day, dayCost, month, monthTokens := old.spend.day, old.spend.dayCost, old.spend.month, old.spend.monthTokens
+	old.spend.mu.Unlock()
+	p.spend.mu.Lock()
+	p.spend.day, p.spend.dayCost, p.spend.month, p.spend.monthTokens = day, dayCost, month, monthTokens
+	p.spend.mu.Unlock()
+}
+
+// unavailableReason returns why the provider must be skipped right now, or "".
+func (p *Provider) unavailableReason() string {
+	now := time.Now()
+	if reason := p.budgetReason(now); reason != "" {
+		return reason
+	}
+	if p.Unavailable == nil {
+		return ""
+	}
+	return p.Unavailable(now)
 }
 
 // GetType returns the provider type, defaulting to "anthropic".
@@ -64,12 +212,18 @@ func (p *Provider) GetEnvVarsForCLI(cli string) map[string]string {
 }
 
 func (p *Provider) IsHealthy() bool {
+	if p.clear.Load() {
+		return true
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.Healthy {
+		p.clear.Store(p.Backoff == 0 && !p.AuthFailed)
 		return true
 	}
 	if time.Since(p.FailedAt) >= p.Backoff {
+		// Half-open: allow requests through, but keep the backoff until a
+		// success confirms recovery.
 		p.Healthy = true
 		return true
 	}
@@ -79,6 +233,7 @@ func (p *Provider) IsHealthy() bool {
 func (p *Provider) MarkFailed() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.clear.Store(false)
 	p.Healthy = false
 	p.FailedAt = time.Now()
 	if p.Backoff == 0 {
@@ -94,9 +249,14 @@ func (p *Provider) MarkFailed() {
 func (p *Provider) MarkAuthFailed() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.clear.Store(false)
 	p.Healthy = false
 	p.AuthFailed = true
 	p.FailedAt = time.Now()
+	if p.OAuth != nil {
+		// The access token was rejected; get a new one on the next try
+		p.OAuth.Invalidate()
+	}
 	if p.Backoff < AuthInitialBackoff {
 		p.Backoff = AuthInitialBackoff
 	} else {
@@ -108,9 +268,62 @@ func (p *Provider) MarkAuthFailed() {
 }
 
 func (p *Provider) MarkHealthy() {
+	if p.clear.Load() {
+		return
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.Healthy = true
 	p.AuthFailed = false
 	p.Backoff = 0
+	p.clear.Store(true)
+}
+
+// CurrentBackoff returns the backoff currently applied to the provider.
+func (p *Provider) CurrentBackoff() time.Duration {
+	if p.clear.Load() {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Backoff
+}
+
+// latencySmoothing is the inverse weight of each new sample in a provider's
+// smoothed latency.
+const latencySmoothing = 5
+
+// recordLatency folds a successful attempt's time to response headers into
+// the provider's smoothed latency.
+func (p *Provider) recordLatency(d time.Duration) {
+	for {
+		old := p.latency.Load()
+		next := int64(d)
+		if old > 0 {
+			next = old + (int64(d)-old)/latencySmoothing
+		}
+		if p.latency.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// Latency returns the provider's smoothed time to response headers, or 0 if
+// no attempt has succeeded yet.
+func (p *Provider) Latency() time.Duration {
+	return time.Duration(p.latency.Load())
+}
+
+// RetryAt returns when the provider's backoff expires, or the zero time if it
+// is currently accepting requests.
+func (p *Provider) RetryAt() time.Time {
+	if p.clear.Load() {
+		return time.Time{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Healthy {
+		return time.Time{}
+	}
+	return p.FailedAt.Add(p.Backoff)
 }
@@ -1,7 +1,13 @@
 package proxy
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -117,3 +123,144 @@ func TestProviderIsHealthyDuringBackoff(t *testing.T) {
 		t.Error("should not be healthy during backoff period")
 	}
 }
+
+func TestProviderConcurrentHealthTransitions(t *testing.T) {
+	p := newTestProvider("a")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				p.IsHealthy()
+				if i%8 == 0 && j%50 == 0 {
+					p.MarkFailed()
+				} else {
+					p.MarkHealthy()
+				}
+				p.CurrentBackoff()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	p.MarkHealthy()
+	if !p.IsHealthy() || p.CurrentBackoff() != 0 {
+		t.Errorf("after MarkHealthy: healthy=%v backoff=%v", p.IsHealthy(), p.CurrentBackoff())
+	}
+	p.MarkFailed()
+	if p.IsHealthy() {
+		t.Error("should not be healthy right after MarkFailed")
+	}
+	if p.CurrentBackoff() != InitialBackoff {
+		t.Errorf("CurrentBackoff = %v, want %v", p.CurrentBackoff(), InitialBackoff)
+	}
+}
+
+func TestProviderRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		authFailed bool
+		want       bool
+		wantToken  string
+	}{
+		{name: "changed after auth failure", configured: "new", authFailed: true, want: true, wantToken: "new"},
+		{name: "unchanged after auth failure", configured: "tok", authFailed: true, want: false, wantToken: "tok"},
+		{name: "removed from config", configured: "", authFailed: true, want: false, wantToken: "tok"},
+		{name: "changed while healthy", configured: "new", authFailed: false, want: false, wantToken: "tok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider("p")
+			p.CurrentToken = func() string { return tt.configured }
+			if tt.authFailed {
+				p.MarkAuthFailed()
+			}
+			if got := p.refreshToken(); got != tt.want {
+				t.Errorf("refreshToken() = %v, want %v", got, tt.want)
+			}
+			if got := p.authToken(); got != tt.wantToken {
+				t.Errorf("authToken() = %q, want %q", got, tt.wantToken)
+			}
+			if tt.want && (!p.IsHealthy() || p.CurrentBackoff() != 0) {
+				t.Errorf("auth backoff not cleared: backoff = %v", p.CurrentBackoff())
+			}
+			if tt.authFailed && !tt.want && p.IsHealthy() {
+				t.Error("provider left auth backoff without a token change")
+			}
+		})
+	}
+}
+
+func TestApplyProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(p *Provider)
+		result      ProbeResult
+		wantHealthy bool
+		wantAuth    bool
+		wantBackoff time.Duration
+		wantChange  bool
+	}{
+		{"healthy stays healthy", nil, ProbeResult{StatusCode: 200}, true, false, 0, false},
+		{"404 counts as reachable", nil, ProbeResult{StatusCode: 404}, true, false, 0, false},
+		{"healthy marked failed on 500", nil, ProbeResult{StatusCode: 500}, false, false, InitialBackoff, true},
+		{"healthy marked failed on error", nil, ProbeResult{Err: errors.New("refused")}, false, false, InitialBackoff, true},
+		{"auth failure gets auth backoff", nil, ProbeResult{StatusCode: 401}, false, true, AuthInitialBackoff, true},
+		{"backoff cleared on recovery", (*Provider).MarkFailed, ProbeResult{StatusCode: 200}, true, false, 0, true},
+		{"backoff not extended while down", (*Provider).MarkFailed, ProbeResult{StatusCode: 503}, false, false, InitialBackoff, false},
+		{"half-open provider marked failed again", func(p *Provider) {
+			p.MarkFailed()
+			p.FailedAt = time.Now().Add(-2 * InitialBackoff)
+			p.IsHealthy()
+		}, ProbeResult{StatusCode: 503}, false, false, 2 * InitialBackoff, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider("a")
+			if tt.setup != nil {
+				tt.setup(p)
+			}
+			msg := applyProbe(p, tt.result, time.Now())
+			if (msg != "") != tt.wantChange {
+				t.Errorf("change = %q, want change %v", msg, tt.wantChange)
+			}
+			if p.Healthy != tt.wantHealthy || p.AuthFailed != tt.wantAuth || p.Backoff != tt.wantBackoff {
+				t.Errorf("healthy=%v auth=%v backoff=%v, want %v %v %v", p.Healthy, p.AuthFailed, p.Backoff, tt.wantHealthy, tt.wantAuth, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var path atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path.Store(r.URL.Path)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer upstream.Close()
+
+	p := newTestProvider("a")
+	p.BaseURL, _ = url.Parse(upstream.URL)
+	srv := NewProxyServer([]*Provider{p}, discardLogger())
+	srv.StructuredLogger = nil
+	hc := HealthCheck{Path: "/health"}
+
+	srv.checkHealth(context.Background(), hc)
+	if p.IsHealthy() {
+		t.Fatal("provider should be unhealthy after a failed probe")
+	}
+	if got := path.Load(); got != "/health" {
+		t.Errorf("probe path = %v, want /health", got)
+	}
+
+	status.Store(http.StatusOK)
+	srv.checkHealth(context.Background(), hc)
+	if !p.IsHealthy() || p.CurrentBackoff() != 0 {
+		t.Errorf("provider should be healthy after a passing probe, backoff %v", p.CurrentBackoff())
+	}
+}
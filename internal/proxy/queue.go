@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBackoffQueueSize is the number of requests that may wait at once
+// when ProxyServer.BackoffQueueSize is unset.
+const DefaultBackoffQueueSize = 64
+
+// backoffQueue holds requests while every provider is in backoff. Waiters are
+// released in arrival order: each one waits for its predecessor to leave the
+// queue, so a burst that arrived during an outage is replayed first-come,
+// first-served once a provider becomes available. The zero value is ready to use.
+type backoffQueue struct {
+	mu      sync.Mutex
+	waiting int
+	tail    chan struct{} // closed when the most recently queued waiter leaves
+}
+
+// wait blocks until `until`, the context is done, and every earlier waiter
+// has left. It returns false without waiting if the queue already holds max
+// requests.
+func (q *backoffQueue) wait(ctx context.Context, until time.Time, max int) bool {
+	q.mu.Lock()
+	if q.waiting >= max {
+		q.mu.Unlock()
+		return false
+	}
+	q.waiting++
+	prev := q.tail
+	done := make(chan struct{})
+	q.tail = done
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		q.waiting--
+		q.mu.Unlock()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return true
+	}
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-ctx.Done():
+		}
+	}
+	return true
+}
+
+// waitForProviders holds the request when every provider in the chain is in
+// backoff and the earliest one recovers within the configured maximum wait.
+// It returns immediately when the queue is disabled or full.
+func (s *ProxyServer) waitForProviders(ctx context.Context, providers []*Provider) {
+	if s.BackoffQueueWait <= 0 || len(providers) == 0 {
+		return
+	}
+
+	var earliest time.Time
+	for _, p := range providers {
+		retryAt := p.RetryAt()
+		if retryAt.IsZero() || !time.Now().Before(retryAt) {
+			return // at least one provider is available
+		}
+		if earliest.IsZero() || retryAt.Before(earliest) {
+			earliest = retryAt
+		}
+	}
+
+	wait := time.Until(earliest)
+	if wait > s.BackoffQueueWait {
+		s.Logger.Printf("[queue] all providers in backoff for %v (max wait %v), not queueing", wait.Round(time.Second), s.BackoffQueueWait)
+		return
+	}
+
+	size := s.BackoffQueueSize
+	if size <= 0 {
+		size = DefaultBackoffQueueSize
+	}
+	s.Logger.Printf("[queue] all providers in backoff, waiting %v", wait.Round(time.Millisecond))
+	if !s.backoffQueue.wait(ctx, earliest, size) {
+		s.Logger.Printf("[queue] queue full (%d waiting), not queueing", size)
+	}
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimitLowFraction is the share of a limit below which the remaining
+// budget is logged as a warning.
+const rateLimitLowFraction = 0.1
+
+// RateLimitWindow is one limit reported by a provider: how much of it is
+// left and when it resets.
+type RateLimitWindow struct {
+	Limit     int64     `json:"limit,omitempty"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset,omitzero"`
+}
+
+// low reports whether less than rateLimitLowFraction of the window is left.
+func (w *RateLimitWindow) low() bool {
+	return w != nil && w.Limit > 0 && float64(w.Remaining) < float64(w.Limit)*rateLimitLowFraction
+}
+
+// RateLimit is the rate-limit state a provider reported in its latest
+// response headers. Windows the provider didn't report are nil.
+type RateLimit struct {
+	Requests     *RateLimitWindow `json:"requests,omitempty"`
+	Tokens       *RateLimitWindow `json:"tokens,omitempty"`
+	InputTokens  *RateLimitWindow `json:"input_tokens,omitempty"`
+	OutputTokens *RateLimitWindow `json:"output_tokens,omitempty"`
+	ObservedAt   time.Time        `json:"observed_at"`
+}
+
+// ParseRateLimit reads Anthropic (anthropic-ratelimit-*) or OpenAI
+// (x-ratelimit-*) rate-limit headers. Anthropic resets are RFC 3339 times,
+// OpenAI resets are durations from now. It returns nil if the response
+// carries no rate-limit headers.
+func ParseRateLimit(h http.Header, now time.Time) *RateLimit {
+	window := func(anthropic, openai string) *RateLimitWindow {
+		a := "anthropic-ratelimit-" + anthropic
+		if w := parseRateLimitWindow(h, now, a+"-limit", a+"-remaining", a+"-reset"); w != nil || openai == "" {
+			return w
+		}
+		return parseRateLimitWindow(h, now, "x-ratelimit-limit-"+openai, "x-ratelimit-remaining-"+openai, "x-ratelimit-reset-"+openai)
+	}
+	rl := &RateLimit{
+		Requests:     window("requests", "requests"),
+		Tokens:       window("tokens", "tokens"),
+		InputTokens:  window("input-tokens", ""),
+		OutputTokens: window("output-tokens", ""),
+		ObservedAt:   now,
+	}
+	if rl.Requests == nil && rl.Tokens == nil && rl.InputTokens == nil && rl.OutputTokens == nil {
+		return nil
+	}
+	return rl
+}
+
+// parseRateLimitWindow reads one window from the named headers, or returns
+// nil if the remaining count is missing.
+func parseRateLimitWindow(h http.Header, now time.Time, limit, remaining, reset string) *RateLimitWindow {
+	n, err := strconv.ParseInt(strings.TrimSpace(h.Get(remaining)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	w := &RateLimitWindow{Remaining: n, Reset: parseRateLimitReset(h.Get(reset), now)}
+	w.Limit, _ = strconv.ParseInt(strings.TrimSpace(h.Get(limit)), 10, 64)
+	return w
+}
+
+// parseRateLimitReset parses an RFC 3339 reset time or a duration such as
+// "6m0s" or "20ms" relative to now. Unparseable values give the zero time.
+func parseRateLimitReset(v string, now time.Time) time.Time {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(d)
+	}
+	return time.Time{}
+}
+
+// Low returns a description of the windows with less than a tenth of their
+// limit left, or "" if none are running low.
+func (rl *RateLimit) Low() string {
+	var parts []string
+	for _, w := range []struct {
+		name   string
+		window *RateLimitWindow
+	}{
+		{"requests", rl.Requests},
+		{"tokens", rl.Tokens},
+		{"input tokens", rl.InputTokens},
+		{"output tokens", rl.OutputTokens},
+	} {
+		if !w.window.low() {
+			continue
+		}
+		part := fmt.Sprintf("%d/%d %s remaining", w.window.Remaining, w.window.Limit, w.name)
+		if !w.window.Reset.IsZero() {
+			part += fmt.Sprintf(", resets in %v", w.window.Reset.Sub(rl.ObservedAt).Round(time.Second))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// recordRateLimit captures the rate-limit headers of a provider response,
+// keeps them on the provider and in the log database, and warns when a
+// limit is nearly used up.
+func (s *ProxyServer) recordRateLimit(r *http.Request, p *Provider, h http.Header) {
+	rl := ParseRateLimit(h, time.Now())
+	if rl == nil {
+		return
+	}
+	p.rateLimit.Store(rl)
+	if s.LogDB != nil {
+		if err := s.LogDB.RecordRateLimit(p.Name, rl); err != nil {
+			s.Logger.Printf("[%s] failed to record rate limit: %v", p.Name, err)
+		}
+	}
+	if low := rl.Low(); low != "" {
+		msg := "rate limit low: " + low
+		s.Logger.Printf("[%s] %s", p.Name, msg)
+		s.logStructured(r, p.Name, 0, LogLevelWarn, msg)
+	}
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// DefaultRedactionLogFile is the redaction audit log's file name inside the
+// log dir.
+const DefaultRedactionLogFile = "redactions.log"
+
+// redactionBuiltins are the expressions of the built-in patterns.
+var redactionBuiltins = map[string]*regexp.Regexp{
+	"api_keys":     regexp.MustCompile(`\b(?:sk-ant-[A-Za-z0-9_-]{20,}|sk-[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`),
+	"aws":          regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b|(?i)aws_secret_access_key\s*[=:]\s*["']?[A-Za-z0-9/+=]{40}`),
+	"private_keys": regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	"emails":       regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+}
+
+type redactionRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor scans request bodies for secrets and personal data and masks
+// them, blocks the request or only warns, recording each finding in an
+// audit log. The log holds pattern names and counts, never what matched.
+type Redactor struct {
+	action config.RedactionAction
+	rules  []redactionRule
+
+	mu    sync.Mutex
+	audit io.Writer
+}
+
+// redactionEntry is one audit log line.
+type redactionEntry struct {
+	Time      time.Time              `json:"time"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Profile   string                 `json:"profile,omitempty"`
+	Client    string                 `json:"client,omitempty"`
+	Path      string                 `json:"path"`
+	Action    config.RedactionAction `json:"action"`
+	Matches   map[string]int         `json:"matches"` // pattern name -> matches found
+}
+
+// NewRedactor builds a redactor from rc that appends its audit log to
+// auditPath; an empty auditPath keeps no log.
+func NewRedactor(rc *config.RedactionConfig, auditPath string) (*Redactor, error) {
+	rd := &Redactor{action: rc.Action}
+	if rd.action == "" {
+		rd.action = config.RedactionMask
+	}
+	builtins := rc.Builtin
+	if len(builtins) == 0 {
+		builtins = config.RedactionBuiltins
+	}
+	for _, name := range builtins {
+		re, ok := redactionBuiltins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction pattern '%s'", name)
+		}
+		rd.rules = append(rd.rules, redactionRule{name, re})
+	}
+	for _, p := range rc.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %w", p.Name, err)
+		}
+		rd.rules = append(rd.rules, redactionRule{p.Name, re})
+	}
+
+	if auditPath != "" {
+		if err := os.MkdirAll(filepath.Dir(auditPath), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(auditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("open redaction log: %w", err)
+		}
+		rd.audit = f
+	}
+	return rd, nil
+}
+
+// Close closes the audit log, if there is one.
+func (rd *Redactor) Close() error {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if c, ok := rd.audit.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// scan finds the patterns in the string values of a JSON body and returns
+// the body with them masked, if it found any and masks, and the matches per
+// pattern. Thinking blocks are left alone, as changing them breaks their
+// signatures.
+func (rd *Redactor) scan(body []byte) ([]byte, map[string]int) {
+	var data interface{}
+	if !isJSONObject(body) || json.Unmarshal(body, &data) != nil {
+		return body, nil
+	}
+	matches := make(map[string]int)
+	masked := rd.walk(data, matches)
+	if len(matches) == 0 {
+		return body, nil
+	}
+	if rd.action != config.RedactionMask {
+		return body, matches
+	}
+	out, err := json.Marshal(masked)
+	if err != nil {
+		return body, matches
+	}
+	return out, matches
+}
+
+// walk returns v with the patterns masked in its strings, counting matches.
+func (rd *Redactor) walk(v interface{}, matches map[string]int) interface{} {
+	switch v := v.(type) {
+	case string:
+		for _, rule := range rd.rules {
+			v = rule.re.ReplaceAllStringFunc(v, func(string) string {
+				matches[rule.name]++
+				return "[REDACTED:" + rule.name + "]"
+			})
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rd.walk(item, matches)
+		}
+		return v
+	case map[string]interface{}:
+		if t, _ := v["type"].(string); t == "thinking" || t == "redacted_thinking" {
+			return v
+		}
+		for key, item := range v {
+			v[key] = rd.walk(item, matches)
+		}
+		return v
+	}
+	return v
+}
+
+// record appends a finding to the audit log.
+func (rd *Redactor) record(e redactionEntry) {
+	if rd.audit == nil {
+		return
+	}
+	line, _ := json.Marshal(e)
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rd.audit.Write(append(line, '\n'))
+}
+
+// formatMatches lists matches per pattern as "emails=2, aws=1", by name.
+func formatMatches(matches map[string]int) string {
+	names := make([]string, 0, len(matches))
+	for name := range matches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, matches[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyRedaction runs the redactor on a request body before it is routed,
+// writing a 403 and returning false if it blocks the request.
+func (s *ProxyServer) applyRedaction(w http.ResponseWriter, r *http.Request, body []byte) ([]byte, bool) {
+	if s.Redactor == nil {
+		return body, true
+	}
+	out, matches := s.Redactor.scan(body)
+	if len(matches) == 0 {
+		return body, true
+	}
+	action := s.Redactor.action
+	s.Redactor.record(redactionEntry{
+		Time:      time.Now(),
+		RequestID: requestIDOf(r),
+		Profile:   s.Profile,
+		Client:    clientOf(r),
+		Path:      r.URL.Path,
+		Action:    action,
+		Matches:   matches,
+	})
+	s.Logger.Printf("[redaction] %s %s: %s", action, r.URL.Path, formatMatches(matches))
+	if action == config.RedactionBlock {
+		s.writeError(w, http.StatusForbidden, errTypePermission, "request blocked by redaction filter: contains "+formatMatches(matches), nil)
+		return nil, false
+	}
+	return out, true
+}
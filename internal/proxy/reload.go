@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ReloadPath is the admin endpoint that rebuilds the providers and routing
+// from the current config (POST). It is only served when the server has a
+// Loader.
+const ReloadPath = "/_opencc/reload"
+
+// DefaultConfigPollInterval is how often WatchConfig checks the config file
+// when no interval is given.
+const DefaultConfigPollInterval = 2 * time.Second
+
+// ChainLoader builds a server's providers and routing from the current
+// config. routing is nil when the profile has none.
+type ChainLoader func() (providers []*Provider, routing *RoutingConfig, err error)
+
+// chains returns the server's providers and routing. Reload replaces them
+// as a pair, so requests read them through here.
+func (s *ProxyServer) chains() ([]*Provider, *RoutingConfig) {
+	s.chainsMu.RLock()
+	defer s.chainsMu.RUnlock()
+	return s.Providers, s.Routing
+}
+
+// SetChains replaces the server's providers and routing. Requests already
+// running finish on the old ones. Providers with the name of a current one
+// keep its health, latency and budget usage.
+func (s *ProxyServer) SetChains(providers []*Provider, routing *RoutingConfig) {
+	current := make(map[string]*Provider)
+	for _, p := range s.allProviders() {
+		current[p.Name] = p
+	}
+	next := &ProxyServer{Providers: providers, Routing: routing}
+	for _, p := range next.allProviders() {
+		if old, ok := current[p.Name]; ok && old != p {
+			p.inheritState(old)
+		}
+	}
+
+	s.chainsMu.Lock()
+	defer s.chainsMu.Unlock()
+	s.Providers = providers
+	s.Routing = routing
+}
+
+// Reload rebuilds the providers and routing with the server's Loader.
+func (s *ProxyServer) Reload() error {
+	if s.Loader == nil {
+		return errors.New("reloading is not enabled")
+	}
+	providers, routing, err := s.Loader()
+	if err != nil {
+		return err
+	}
+	s.SetChains(providers, routing)
+	routes := 0
+	if routing != nil {
+		routes = len(routing.ScenarioRoutes)
+	}
+	s.Logger.Printf("[reload] providers: %v, scenario routes: %d", providerNames(providers), routes)
+	return nil
+}
+
+func providerNames(providers []*Provider) []string {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// serveReload serves the reload admin endpoint.
+func (s *ProxyServer) serveReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.Reload(); err != nil {
+		s.Logger.Printf("[reload] failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	providers, _ := s.chains()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"providers": providerNames(providers)})
+}
+
+// WatchConfig reloads the server whenever the file at path is modified,
+// checking every interval until ctx is done. A failed reload keeps the
+// current providers and is retried on the next change.
+func (s *ProxyServer) WatchConfig(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+		s.Logger.Printf("[reload] %s changed", path)
+		if err := s.Reload(); err != nil {
+			s.Logger.Printf("[reload] failed, keeping the current providers: %v", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// parsedRequest is a request body decoded once per incoming request and shared
+// by session extraction, scenario detection and model rewriting on every
+// provider attempt.
+type parsedRequest struct {
+	raw   []byte
+	data  map[string]interface{} // nil if the body is not a JSON object
+	model string                 // top-level "model", if present
+
+	// Byte span of the top-level "model" value in raw, located lazily the
+	// first time the model needs rewriting. Hedged attempts rewrite it
+	// concurrently.
+	modelSpanOnce        sync.Once
+	modelStart, modelEnd int
+	modelSpanFound       bool
+}
+
+func parseRequest(body []byte) *parsedRequest {
+	pr := &parsedRequest{raw: body}
+	if err := json.Unmarshal(body, &pr.data); err != nil {
+		pr.data = nil
+		return pr
+	}
+	pr.model, _ = pr.data["model"].(string)
+	return pr
+}
+
+// withModel returns the body with the top-level model replaced. The new value
+// is spliced into the original bytes so the rest of the body is neither
+// re-decoded nor re-encoded.
+func (pr *parsedRequest) withModel(model string) []byte {
+	pr.modelSpanOnce.Do(func() {
+		pr.modelStart, pr.modelEnd, pr.modelSpanFound = topLevelValueSpan(pr.raw, "model")
+	})
+	if !pr.modelSpanFound {
+		return pr.raw
+	}
+	value, err := json.Marshal(model)
+	if err != nil {
+		return pr.raw
+	}
+
+	out := make([]byte, 0, len(pr.raw)-(pr.modelEnd-pr.modelStart)+len(value))
+	out = append(out, pr.raw[:pr.modelStart]...)
+	out = append(out, value...)
+	out = append(out, pr.raw[pr.modelEnd:]...)
+	return out
+}
+
+// topLevelValueSpan finds the byte range of a top-level object member's value.
+// Nested values are skipped without being decoded into Go values.
+func topLevelValueSpan(data []byte, key string) (start, end int, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, 0, false
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		name, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return 0, 0, false
+		}
+		if name == key {
+			end = int(dec.InputOffset())
+			return end - len(value), end, true
+		}
+	}
+	return 0, 0, false
+}
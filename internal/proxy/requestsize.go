@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// errTypeRequestTooLarge is the error type of requests rejected for their
+// size, matching the Anthropic API's own 413 errors.
+const errTypeRequestTooLarge = "request_too_large"
+
+// sizeLimitExceeded returns why a body of n bytes is over the global limit
+// or the limit of route (nil for the default chain), or "" if it fits.
+func (s *ProxyServer) sizeLimitExceeded(n int, scenario config.Scenario, route *ScenarioProviders) string {
+	if s.MaxRequestBytes > 0 && int64(n) > s.MaxRequestBytes {
+		return fmt.Sprintf("request body of %d bytes exceeds the limit of %d bytes", n, s.MaxRequestBytes)
+	}
+	if route != nil && route.MaxRequestBytes > 0 && int64(n) > route.MaxRequestBytes {
+		return fmt.Sprintf("request body of %d bytes exceeds the %s route limit of %d bytes", n, scenario, route.MaxRequestBytes)
+	}
+	return ""
+}
+
+// oversizedRoute returns the longContext route an oversized body of n bytes
+// should be sent to instead, or nil if it has to be rejected.
+func (s *ProxyServer) oversizedRoute(n int, current *ScenarioProviders) *ScenarioProviders {
+	_, routing := s.chains()
+	if !s.OversizedReroute || routing == nil {
+		return nil
+	}
+	lc := routing.ScenarioRoutes[config.ScenarioLongContext]
+	if lc == nil || lc == current {
+		return nil
+	}
+	if lc.MaxRequestBytes > 0 && int64(n) > lc.MaxRequestBytes {
+		return nil
+	}
+	if chainSizeLimit(lc.Providers, n) != "" {
+		return nil
+	}
+	return lc
+}
+
+// chainSizeLimit returns why a body of n bytes fits no provider of the
+// chain, or "" if at least one accepts it.
+func chainSizeLimit(providers []*Provider, n int) string {
+	if len(providers) == 0 {
+		return ""
+	}
+	for _, p := range providers {
+		if providerSizeLimit(p, n) == "" {
+			return ""
+		}
+	}
+	return fmt.Sprintf("request body of %d bytes exceeds the limit of every provider", n)
+}
+
+// providerSizeLimit returns why a body of n bytes is over p's limit, or ""
+// if the provider accepts it.
+func providerSizeLimit(p *Provider, n int) string {
+	if p.MaxRequestBytes > 0 && int64(n) > p.MaxRequestBytes {
+		return fmt.Sprintf("request body of %d bytes exceeds limit of %d bytes", n, p.MaxRequestBytes)
+	}
+	return ""
+}
+
+// rejectOversized answers a request over a size limit with a 413.
+func (s *ProxyServer) rejectOversized(w http.ResponseWriter, r *http.Request, reason string) {
+	s.Logger.Printf("[request] %s %s rejected: %s", r.Method, r.URL.Path, reason)
+	s.logStructured(r, "", http.StatusRequestEntityTooLarge, LogLevelWarn, reason)
+	s.writeError(w, http.StatusRequestEntityTooLarge, errTypeRequestTooLarge, reason, nil)
+}
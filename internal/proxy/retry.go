@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// retryPolicyFor returns the retry policy for attempts on p: the provider's
+// own, else the profile's, else nil for a single try.
+func (s *ProxyServer) retryPolicyFor(p *Provider) *config.RetryPolicy {
+	if p.Retry != nil {
+		return p.Retry
+	}
+	return s.Retry
+}
+
+// retryDelay returns the delay before retry n (1-based): the initial delay
+// doubled for each retry before it, capped at the maximum, with up to the
+// jitter fraction of it taken off at random. rnd is in [0, 1).
+func retryDelay(rp *config.RetryPolicy, n int, rnd float64) time.Duration {
+	delay := rp.InitialDelay()
+	for i := 1; i < n && delay < rp.MaxDelay(); i++ {
+		delay *= 2
+	}
+	delay = min(delay, rp.MaxDelay())
+	return delay - time.Duration(float64(delay)*rp.Jitter*rnd)
+}
+
+// retryReason describes why a try on a provider should be retried, or
+// returns "" if it shouldn't. Retries follow the failover policy of the
+// request: a provider that may have acted on it is only retried when
+// failover would be allowed too.
+func retryReason(rp *config.RetryPolicy, failover config.FailoverPolicy, resp *http.Response, err error) string {
+	if err != nil {
+		if !canFailover(failover, !isDialError(err)) {
+			return ""
+		}
+		return fmt.Sprintf("request error: %v", err)
+	}
+	if !rp.RetriesStatus(resp.StatusCode) || !canFailover(failover, resp.StatusCode != http.StatusTooManyRequests) {
+		return ""
+	}
+	return fmt.Sprintf("got %d", resp.StatusCode)
+}
+
+// doWithRetries sends req to p, trying again under the provider's retry
+// policy until a try succeeds, the policy's attempts run out or the request
+// is canceled. body is the request body, resent on each retry.
+func (s *ProxyServer) doWithRetries(r, req *http.Request, p *Provider, body []byte) (*http.Response, error) {
+	client := p.HTTPClient(s.Client)
+	rp := s.retryPolicyFor(p)
+	if rp == nil || rp.MaxAttempts <= 1 {
+		return client.Do(req)
+	}
+	failover := s.failoverPolicyFor(r.Method, r.URL.Path)
+
+	for n := 1; ; n++ {
+		resp, err := client.Do(req)
+		if n >= rp.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		reason := retryReason(rp, failover, resp, err)
+		if reason == "" {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		delay := retryDelay(rp, n, rand.Float64())
+		s.Logger.Printf("[%s] %s, retry %d/%d in %v", p.Name, reason, n, rp.MaxAttempts-1, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"encoding/json"
 	"strings"
+	"sync"
 
 	"github.com/dopejs/opencc/internal/config"
 )
@@ -17,27 +18,133 @@ const (
 	sessionClearRatio = 0.2
 )
 
-// DetectScenario examines a parsed request body and returns the matching scenario.
-// Priority: webSearch > think > image > longContext > background > default.
-func DetectScenario(body map[string]interface{}, threshold int, sessionID string) config.Scenario {
-	if hasWebSearchTool(body) {
-		return config.ScenarioWebSearch
+// ScenarioRequest is what a scenario detector sees of a request.
+type ScenarioRequest struct {
+	Body                 map[string]interface{} // parsed request body
+	LongContextThreshold int                    // tokens; 0 = default
+	SessionID            string                 // "" if the request has none
+}
+
+// ScenarioDetectFunc reports whether a request belongs to a scenario.
+type ScenarioDetectFunc func(req ScenarioRequest) bool
+
+// scenarioDetector is one entry of the ordered detector list.
+type scenarioDetector struct {
+	scenario config.Scenario
+	detect   ScenarioDetectFunc
+}
+
+var (
+	scenarioDetectorsMu sync.RWMutex
+	scenarioDetectors   = builtinScenarioDetectors()
+)
+
+// builtinScenarioDetectors returns the built-in detectors in priority order:
+// webSearch > think > image > longContext > background.
+func builtinScenarioDetectors() []scenarioDetector {
+	return []scenarioDetector{
+		{config.ScenarioWebSearch, func(req ScenarioRequest) bool { return hasWebSearchTool(req.Body) }},
+		{config.ScenarioThink, func(req ScenarioRequest) bool { return hasThinkingEnabled(req.Body) }},
+		{config.ScenarioImage, func(req ScenarioRequest) bool { return hasImageContent(req.Body) }},
+		{config.ScenarioLongContext, func(req ScenarioRequest) bool {
+			return isLongContext(req.Body, req.LongContextThreshold, req.SessionID)
+		}},
+		{config.ScenarioBackground, func(req ScenarioRequest) bool { return isBackgroundRequest(req.Body) }},
 	}
-	if hasThinkingEnabled(body) {
-		return config.ScenarioThink
+}
+
+// RegisterScenarioDetector adds a detector for scenario with the lowest
+// priority, or replaces the detector already registered for it in place.
+// Profiles route the scenario like the built-in ones.
+func RegisterScenarioDetector(scenario config.Scenario, detect ScenarioDetectFunc) {
+	RegisterScenarioDetectorBefore("", scenario, detect)
+}
+
+// RegisterScenarioDetectorBefore adds a detector for scenario with priority
+// just above before's detector (lowest if before isn't registered). An
+// existing detector for scenario is removed first.
+func RegisterScenarioDetectorBefore(before, scenario config.Scenario, detect ScenarioDetectFunc) {
+	scenarioDetectorsMu.Lock()
+	defer scenarioDetectorsMu.Unlock()
+	scenarioDetectors = withScenarioDetector(scenarioDetectors, before, scenario, detect)
+}
+
+// withScenarioDetector returns a copy of list with the detector for scenario
+// placed as RegisterScenarioDetectorBefore does.
+func withScenarioDetector(list []scenarioDetector, before, scenario config.Scenario, detect ScenarioDetectFunc) []scenarioDetector {
+	detectors := make([]scenarioDetector, 0, len(list)+1)
+	replaced := -1
+	for _, d := range list {
+		if d.scenario == scenario {
+			replaced = len(detectors)
+			continue
+		}
+		detectors = append(detectors, d)
 	}
-	if hasImageContent(body) {
-		return config.ScenarioImage
+
+	at := len(detectors)
+	if before == "" && replaced >= 0 {
+		at = replaced
 	}
-	if isLongContext(body, threshold, sessionID) {
-		return config.ScenarioLongContext
+	for i, d := range detectors {
+		if before != "" && d.scenario == before {
+			at = i
+			break
+		}
 	}
-	if isBackgroundRequest(body) {
-		return config.ScenarioBackground
+	detectors = append(detectors, scenarioDetector{})
+	copy(detectors[at+1:], detectors[at:])
+	detectors[at] = scenarioDetector{scenario: scenario, detect: detect}
+	return detectors
+}
+
+// ScenarioDetectors returns the scenarios with a detector, highest priority
+// first.
+func ScenarioDetectors() []config.Scenario {
+	scenarioDetectorsMu.RLock()
+	defer scenarioDetectorsMu.RUnlock()
+	scenarios := make([]config.Scenario, len(scenarioDetectors))
+	for i, d := range scenarioDetectors {
+		scenarios[i] = d.scenario
+	}
+	return scenarios
+}
+
+// DetectScenario examines a parsed request body and returns the scenario of
+// the first detector that matches, or the default scenario.
+func DetectScenario(body map[string]interface{}, threshold int, sessionID string) config.Scenario {
+	scenarioDetectorsMu.RLock()
+	detectors := scenarioDetectors
+	scenarioDetectorsMu.RUnlock()
+
+	req := ScenarioRequest{Body: body, LongContextThreshold: threshold, SessionID: sessionID}
+	for _, d := range detectors {
+		if d.detect(req) {
+			return d.scenario
+		}
 	}
 	return config.ScenarioDefault
 }
 
+// exceedsScenarioThreshold reports whether a request detected as scenario
+// reaches the long-context threshold of the scenario's route, so it should go
+// to the longContext route instead. Routes without a threshold, and profiles
+// without a longContext route, never send requests there.
+func (s *ProxyServer) exceedsScenarioThreshold(scenario config.Scenario, body map[string]interface{}, sessionID string) bool {
+	_, routing := s.chains()
+	if body == nil || routing == nil || scenario == config.ScenarioLongContext {
+		return false
+	}
+	sp, ok := routing.ScenarioRoutes[scenario]
+	if !ok || sp.LongContextThreshold <= 0 {
+		return false
+	}
+	if _, ok := routing.ScenarioRoutes[config.ScenarioLongContext]; !ok {
+		return false
+	}
+	return isLongContext(body, sp.LongContextThreshold, sessionID)
+}
+
 // DetectScenarioFromJSON parses raw JSON and detects the scenario.
 func DetectScenarioFromJSON(data []byte, threshold int, sessionID string) (config.Scenario, map[string]interface{}) {
 	var body map[string]interface{}
@@ -76,7 +183,7 @@ func hasImageContent(body map[string]interface{}) bool {
 }
 
 // isLongContext checks if the total text content in messages exceeds the threshold.
-// It uses tiktoken for accurate token counting and considers session history.
+// It counts tokens with the package Tokenizer and considers session history.
 //
 // Session history logic:
 // - lastUsage.InputTokens represents the ACTUAL tokens sent to API (after any compaction)
@@ -89,11 +196,7 @@ func isLongContext(body map[string]interface{}, threshold int, sessionID string)
 	}
 
 	// Calculate current request token count
-	tokenCount, err := calculateTokenCount(body)
-	if err != nil {
-		// Fallback to character-based estimation on error
-		tokenCount = estimateTokensFromChars(body)
-	}
+	tokenCount := sessionTokenCount(body, sessionID)
 
 	// Check current request token count first
 	if tokenCount >= threshold {
@@ -412,13 +412,273 @@ func TestTokenCalculation(t *testing.T) {
 		},
 	}
 
-	tokens, err := calculateTokenCount(body)
-	if err != nil {
-		t.Fatalf("calculateTokenCount() error: %v", err)
-	}
+	tokens := calculateTokenCount(body)
 
 	// "Hello, how are you?" should be around 5-6 tokens
 	if tokens < 3 || tokens > 10 {
 		t.Errorf("calculateTokenCount() = %d, expected 3-10 tokens", tokens)
 	}
 }
+
+// wordTokenizer counts one token per space-separated word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestSetTokenizer(t *testing.T) {
+	t.Cleanup(func() { SetTokenizer(nil) })
+	body := map[string]interface{}{
+		"system": "be brief",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "one two three"},
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "four five"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+		want      int
+	}{
+		{"custom", wordTokenizer{}, 7},
+		{"character estimate", charTokenizer{}, 3 + 5 + 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetTokenizer(tt.tokenizer)
+			if got := calculateTokenCount(body); got != tt.want {
+				t.Errorf("calculateTokenCount() = %d, want %d", got, tt.want)
+			}
+			if got := sessionTokenCount(body, "set-tokenizer"); got != tt.want {
+				t.Errorf("sessionTokenCount() = %d, want %d", got, tt.want)
+			}
+			if !isLongContext(body, tt.want, "") || isLongContext(body, tt.want+1, "") {
+				t.Errorf("isLongContext() doesn't switch at the threshold of %d", tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionTokenCountIncremental(t *testing.T) {
+	msg := func(role, text string) interface{} {
+		return map[string]interface{}{"role": role, "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": text},
+		}}
+	}
+	cached := func(m interface{}) interface{} {
+		block := m.(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+		copied := map[string]interface{}{"cache_control": map[string]interface{}{"type": "ephemeral"}}
+		for k, v := range block {
+			copied[k] = v
+		}
+		return map[string]interface{}{"role": m.(map[string]interface{})["role"], "content": []interface{}{copied}}
+	}
+	m1, m2, m3 := msg("user", "Explain goroutines"), msg("assistant", generateLongText(2000)), msg("user", "And channels?")
+
+	turns := []struct {
+		name     string
+		system   string
+		messages []interface{}
+	}{
+		{"first turn", "You are helpful.", []interface{}{cached(m1)}},
+		{"history grows, cache_control moves", "You are helpful.", []interface{}{m1, m2, cached(m3)}},
+		{"system prompt changes", "You are terse.", []interface{}{m1, m2, m3}},
+		{"history compacted", "You are terse.", []interface{}{msg("user", "summary"), m3}},
+		{"history shrinks", "You are terse.", []interface{}{msg("user", "summary")}},
+	}
+	sessionID := "incremental-tokens"
+	for _, tt := range turns {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]interface{}{"system": tt.system, "messages": tt.messages}
+			want := calculateTokenCount(body)
+			if got := sessionTokenCount(body, sessionID); got != want {
+				t.Errorf("sessionTokenCount() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestFingerprintIgnoresCacheControl(t *testing.T) {
+	plain := map[string]interface{}{"type": "text", "text": "hi"}
+	marked := map[string]interface{}{"type": "text", "text": "hi", "cache_control": map[string]interface{}{"type": "ephemeral"}}
+	edited := map[string]interface{}{"type": "text", "text": "hi!"}
+
+	if fingerprint(plain) != fingerprint(marked) {
+		t.Error("cache_control changed the fingerprint")
+	}
+	if fingerprint(plain) == fingerprint(edited) {
+		t.Error("edited text kept the same fingerprint")
+	}
+}
+
+func TestRegisterScenarioDetector(t *testing.T) {
+	t.Cleanup(func() {
+		scenarioDetectorsMu.Lock()
+		scenarioDetectors = builtinScenarioDetectors()
+		scenarioDetectorsMu.Unlock()
+	})
+	const review config.Scenario = "review"
+	isReview := func(req ScenarioRequest) bool {
+		system, _ := req.Body["system"].(string)
+		return strings.Contains(system, "code review")
+	}
+	reviewThink := map[string]interface{}{
+		"system":   "You are doing a code review.",
+		"thinking": map[string]interface{}{"type": "enabled"},
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+	reviewOnly := map[string]interface{}{
+		"system":   "You are doing a code review.",
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+
+	tests := []struct {
+		name       string
+		register   func()
+		wantOrder  string
+		body       map[string]interface{}
+		wantDetect config.Scenario
+	}{
+		{
+			name:       "appended with lowest priority",
+			register:   func() { RegisterScenarioDetector(review, isReview) },
+			wantOrder:  "webSearch,think,image,longContext,background,review",
+			body:       reviewThink,
+			wantDetect: config.ScenarioThink,
+		},
+		{
+			name:       "custom scenario detected",
+			register:   func() {},
+			wantOrder:  "webSearch,think,image,longContext,background,review",
+			body:       reviewOnly,
+			wantDetect: review,
+		},
+		{
+			name:       "moved before think",
+			register:   func() { RegisterScenarioDetectorBefore(config.ScenarioThink, review, isReview) },
+			wantOrder:  "webSearch,review,think,image,longContext,background",
+			body:       reviewThink,
+			wantDetect: review,
+		},
+		{
+			name:       "built-in replaced in place",
+			register:   func() { RegisterScenarioDetector(config.ScenarioThink, func(ScenarioRequest) bool { return false }) },
+			wantOrder:  "webSearch,review,think,image,longContext,background",
+			body:       map[string]interface{}{"thinking": map[string]interface{}{"type": "enabled"}},
+			wantDetect: config.ScenarioDefault,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.register()
+			var order []string
+			for _, s := range ScenarioDetectors() {
+				order = append(order, string(s))
+			}
+			if got := strings.Join(order, ","); got != tt.wantOrder {
+				t.Errorf("ScenarioDetectors() = %s, want %s", got, tt.wantOrder)
+			}
+			if got := DetectScenario(tt.body, 0, ""); got != tt.wantDetect {
+				t.Errorf("DetectScenario() = %q, want %q", got, tt.wantDetect)
+			}
+		})
+	}
+}
+
+func TestCompileScenarioMatchers(t *testing.T) {
+	body := map[string]interface{}{
+		"model": "claude-sonnet-4-5",
+		"system": []interface{}{
+			map[string]interface{}{"type": "text", "text": "You are doing a code review."},
+		},
+		"tools": []interface{}{
+			map[string]interface{}{"name": "read"},
+			map[string]interface{}{"name": "bash"},
+		},
+		"metadata":   map[string]interface{}{"user_id": "user_abc123_session"},
+		"max_tokens": float64(8192),
+	}
+	match := func(path string, op config.MatchOp, value string) config.ScenarioMatcher {
+		return config.ScenarioMatcher{Path: path, Op: op, Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		matchers []config.ScenarioMatcher
+		want     bool
+		wantErr  bool
+	}{
+		{"system blocks contain", []config.ScenarioMatcher{match("system", config.MatchContains, "code review")}, true, false},
+		{"system doesn't contain", []config.ScenarioMatcher{match("system", config.MatchContains, "translate")}, false, false},
+		{"any tool name equals", []config.ScenarioMatcher{match("tools[].name", config.MatchEquals, "bash")}, true, false},
+		{"no tool name equals", []config.ScenarioMatcher{match("tools[].name", config.MatchEquals, "edit")}, false, false},
+		{"nested field regex", []config.ScenarioMatcher{match("metadata.user_id", config.MatchRegex, `^user_[a-z0-9]+_`)}, true, false},
+		{"number equals", []config.ScenarioMatcher{match("max_tokens", config.MatchEquals, "8192")}, true, false},
+		{"missing field", []config.ScenarioMatcher{match("metadata.team", config.MatchContains, "")}, false, false},
+		{"all must match", []config.ScenarioMatcher{
+			match("tools[].name", config.MatchEquals, "bash"),
+			match("model", config.MatchContains, "opus"),
+		}, false, false},
+		{"invalid regex", []config.ScenarioMatcher{match("model", config.MatchRegex, "(")}, false, true},
+		{"unknown op", []config.ScenarioMatcher{match("model", "startsWith", "claude")}, false, true},
+		{"invalid path", []config.ScenarioMatcher{match("tools[0].name", config.MatchEquals, "bash")}, false, true},
+		{"no matchers", nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detect, err := CompileScenarioMatchers(tt.matchers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileScenarioMatchers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := detect(ScenarioRequest{Body: body}); got != tt.want {
+				t.Errorf("detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetCustomScenarios(t *testing.T) {
+	t.Cleanup(func() {
+		scenarioDetectorsMu.Lock()
+		scenarioDetectors = builtinScenarioDetectors()
+		customScenarios = nil
+		scenarioDetectorsMu.Unlock()
+	})
+	review := config.CustomScenario{
+		Name:   "review",
+		Match:  []config.ScenarioMatcher{{Path: "system", Op: config.MatchContains, Value: "code review"}},
+		Before: config.ScenarioThink,
+	}
+	if errs := SetCustomScenarios([]config.CustomScenario{review}); len(errs) > 0 {
+		t.Fatalf("SetCustomScenarios: %v", errs)
+	}
+	body := map[string]interface{}{
+		"system":   "You are doing a code review.",
+		"thinking": map[string]interface{}{"type": "enabled"},
+	}
+	if got := DetectScenario(body, 0, ""); got != "review" {
+		t.Errorf("DetectScenario() = %q, want review", got)
+	}
+
+	builtin := config.CustomScenario{Name: config.ScenarioThink, Match: review.Match}
+	if errs := SetCustomScenarios([]config.CustomScenario{builtin}); len(errs) != 1 {
+		t.Errorf("SetCustomScenarios accepted a built-in name: %v", errs)
+	}
+
+	// Reloaded without review: it no longer matches, the built-ins remain
+	if got := DetectScenario(body, 0, ""); got != config.ScenarioThink {
+		t.Errorf("DetectScenario() after removal = %q, want think", got)
+	}
+	var order []string
+	for _, s := range ScenarioDetectors() {
+		order = append(order, string(s))
+	}
+	if got := strings.Join(order, ","); got != "webSearch,think,image,longContext,background" {
+		t.Errorf("ScenarioDetectors() after removal = %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,8 +11,10 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dopejs/opencc/internal/config"
@@ -71,15 +74,27 @@ type RoutingConfig struct {
 
 // ScenarioProviders defines the providers and per-provider model overrides for a scenario.
 type ScenarioProviders struct {
-	Providers []*Provider
-	Models    map[string]string // provider name → model override
+	Providers       []*Provider
+	Models          map[string]string // provider name → model override
+	MaxRequestBytes int64             // largest request body routed here; 0 = unlimited
+
+	// LongContextThreshold sends requests of this scenario with at least
+	// this many tokens to the longContext route instead; 0 = never.
+	LongContextThreshold int
 }
 
+// DefaultMaxResponseBytes is the largest non-streaming response body the proxy
+// will buffer in memory for transformation. Larger responses are streamed
+// through untouched when no transformation is needed, or rejected otherwise.
+const DefaultMaxResponseBytes int64 = 64 << 20
+
 // providerFailure tracks details of a failed provider attempt.
 type providerFailure struct {
 	Name       string
 	StatusCode int
 	Body       string
+	Skipped    bool // provider was in backoff and not attempted
+	TooLarge   bool // skipped because the request exceeds the provider's size limit
 }
 
 type ProxyServer struct {
@@ -89,6 +104,44 @@ type ProxyServer struct {
 	Logger           *log.Logger
 	StructuredLogger *StructuredLogger
 	Client           *http.Client
+	MaxResponseBytes int64                            // buffering ceiling for non-streaming responses; 0 = DefaultMaxResponseBytes
+	FailoverPolicies map[string]config.FailoverPolicy // request path prefix → failover policy (overrides defaults)
+	RequestTimeout   time.Duration                    // deadline for a request across all attempts; 0 = DefaultRequestTimeout
+	BackoffQueueWait time.Duration                    // max time to hold a request while all providers are in backoff; 0 = disabled
+	BackoffQueueSize int                              // max requests held at once; 0 = DefaultBackoffQueueSize
+	Strategy         config.Strategy                  // provider ordering; empty = configured order
+	Schedule         []config.ScheduleRule            // default chain orders for recurring windows; the first open one applies
+	Weights          map[string]int                   // provider name → share of requests under the weighted strategy; unset = 1
+	Retry            *config.RetryPolicy              // retries on each provider before failing over; nil = none
+	SessionAffinity  bool                             // try the provider that last served a session first
+	AccessLog        *AccessLogger                    // per-request access log; nil = disabled
+	UsageWarnings    *config.UsageWarningConfig       // session token thresholds; nil = disabled
+	Profile          string                           // active profile, recorded in session snapshots
+	CLI              string                           // launched CLI, recorded in session snapshots
+	ClientName       string                           // client name for requests without X-OpenCC-Client; empty = from User-Agent
+	LogDB            *LogDB                           // persistent store for session snapshots; nil = not recorded
+	Faults           *FaultInjector                   // injected provider failures for testing; nil = disabled
+	Loader           ChainLoader                      // rebuilds Providers and Routing for Reload; nil = reloading disabled
+	MaxRequestBytes  int64                            // largest request body accepted; 0 = unlimited
+	OversizedReroute bool                             // send requests over a global or scenario limit to the longContext route instead of rejecting them
+	StreamFailover   config.StreamFailoverMode        // resuming SSE streams cut off mid-response; empty = off
+	AuthToken        string                           // key clients must present in X-OpenCC-Key or as their API key; empty = none
+	TLS              *tls.Config                      // serve HTTPS with these settings; nil = plain HTTP
+	Hedge            *config.HedgeConfig              // racing the next provider against a slow one; nil = off
+	Cache            *ResponseCache                   // responses reused for repeated requests; nil = disabled
+	Capture          *Capturer                        // writes every provider attempt for debugging; nil = disabled
+	Hooks            *config.HooksConfig              // external commands that may rewrite request and response bodies; nil = none
+	Redactor         *Redactor                        // masks or blocks secrets and personal data in requests; nil = disabled
+	SystemPrompt     string                           // put before the system prompt of every request, ahead of the provider's; "" = none
+
+	chainsMu     sync.RWMutex // guards Providers and Routing, which Reload replaces
+	filePins     filePinStore // Files API file ID → owning provider
+	backoffQueue backoffQueue
+	continuity   continuityCache // session → provider that produced its thinking blocks
+	usage        usageTracker    // session → cumulative tokens for usage warnings
+	snapshots    snapshotTracker // sessions and provider/model pairs already stored
+	metrics      promMetrics     // counters for the Prometheus endpoint
+	turn         atomic.Uint64   // requests ordered by the round-robin and weighted strategies
 }
 
 func NewProxyServer(providers []*Provider, logger *log.Logger) *ProxyServer {
@@ -97,6 +150,7 @@ func NewProxyServer(providers []*Provider, logger *log.Logger) *ProxyServer {
 		ClientFormat:     config.ProviderTypeAnthropic, // Default: Claude Code uses Anthropic format
 		Logger:           logger,
 		StructuredLogger: GetGlobalLogger(),
+		LogDB:            GetGlobalLogDB(),
 		Client: &http.Client{
 			Timeout: 10 * time.Minute,
 		},
@@ -111,6 +165,7 @@ func NewProxyServerWithRouting(routing *RoutingConfig, logger *log.Logger) *Prox
 		ClientFormat:     config.ProviderTypeAnthropic, // Default: Claude Code uses Anthropic format
 		Logger:           logger,
 		StructuredLogger: GetGlobalLogger(),
+		LogDB:            GetGlobalLogDB(),
 		Client: &http.Client{
 			Timeout: 10 * time.Minute,
 		},
@@ -127,6 +182,7 @@ func NewProxyServerWithClientFormat(providers []*Provider, clientFormat string,
 		ClientFormat:     clientFormat,
 		Logger:           logger,
 		StructuredLogger: GetGlobalLogger(),
+		LogDB:            GetGlobalLogDB(),
 		Client: &http.Client{
 			Timeout: 10 * time.Minute,
 		},
@@ -134,33 +190,114 @@ func NewProxyServerWithClientFormat(providers []*Provider, clientFormat string,
 }
 
 func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		s.writeError(w, http.StatusUnauthorized, errTypeAuthentication, "missing or invalid "+AuthHeader+" header", nil)
+		return
+	}
+	r.Header.Del(AuthHeader)
+	if s.Faults != nil && r.URL.Path == FaultsPath {
+		s.Faults.ServeHTTP(w, r)
+		return
+	}
+	if s.Loader != nil && r.URL.Path == ReloadPath {
+		s.serveReload(w, r)
+		return
+	}
+	if r.URL.Path == StatusPath {
+		s.serveStatus(w, r)
+		return
+	}
+
+	client := s.identifyClient(r)
+	requestID := requestIDFor(r)
+	bypassCache := strings.EqualFold(r.Header.Get(CacheHeader), "bypass")
+	overrides := takeOverrides(r)
+	r.Header.Del(ClientHeader)
+	r.Header.Del(RequestIDHeader)
+	r.Header.Del(CacheHeader)
+	r = withRequestID(withClient(r, client), requestID)
+	w.Header().Set(RequestIDHeader, requestID)
+
+	if s.AccessLog != nil {
+		rec := &accessRecorder{ResponseWriter: w}
+		w = rec
+		start := time.Now()
+		defer func() {
+			status := rec.status
+			if !rec.wroteHeader {
+				status = 499 // client closed the request before any response
+			}
+			s.AccessLog.log(accessEntry{
+				Time:       start,
+				RequestID:  requestID,
+				RemoteAddr: r.RemoteAddr,
+				Client:     client,
+				Method:     r.Method,
+				Path:       r.URL.RequestURI(),
+				Protocol:   r.Proto,
+				Provider:   rec.provider,
+				Status:     status,
+				Bytes:      rec.bytes,
+				DurationMs: time.Since(start).Milliseconds(),
+			})
+		}()
+	}
+
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "failed to read request body", http.StatusBadGateway)
+		s.writeError(w, http.StatusBadGateway, errTypeAPI, "failed to read request body", nil)
 		return
 	}
 	r.Body.Close()
+	var ok bool
+	if bodyBytes, ok = s.applyRequestHooks(w, r, bodyBytes); !ok {
+		return
+	}
+	if bodyBytes, ok = s.applyRedaction(w, r, bodyBytes); !ok {
+		return
+	}
+
+	// Every attempt shares one deadline and stops as soon as the client goes away.
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout())
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	if key := ensureIdempotencyKey(r); key != "" {
+		s.Logger.Printf("[request] %s %s generated idempotency_key=%s", r.Method, r.URL.Path, key)
+	}
 
-	// Parse request body to extract session ID
-	var bodyMap map[string]interface{}
+	// Parse the body once; every later step works from the parsed form.
+	req := parseRequest(bodyBytes)
 	sessionID := ""
-	if err := json.Unmarshal(bodyBytes, &bodyMap); err == nil {
-		sessionID = extractSessionID(bodyMap)
+	if req.data != nil {
+		sessionID = extractSessionID(req.data)
 	}
 
 	// Determine provider chain and per-provider model overrides from routing
-	providers := s.Providers
+	defaults, routing := s.chains()
+	defaults = s.applySchedule(defaults, time.Now())
+	providers := defaults
 	var modelOverrides map[string]string
 	var detectedScenario config.Scenario
+	var route *ScenarioProviders
 	var usingScenarioRoute bool
 
-	if s.Routing != nil && len(s.Routing.ScenarioRoutes) > 0 {
-		threshold := s.Routing.LongContextThreshold
+	if routing != nil && len(routing.ScenarioRoutes) > 0 {
+		threshold := routing.LongContextThreshold
 		if threshold <= 0 {
 			threshold = defaultLongContextThreshold
 		}
-		detectedScenario, _ = DetectScenarioFromJSON(bodyBytes, threshold, sessionID)
-		if sp, ok := s.Routing.ScenarioRoutes[detectedScenario]; ok {
+		detectedScenario = config.ScenarioDefault
+		if req.data != nil {
+			detectedScenario = DetectScenario(req.data, threshold, sessionID)
+		}
+		if s.exceedsScenarioThreshold(detectedScenario, req.data, sessionID) {
+			s.Logger.Printf("[routing] scenario=%s over its long-context threshold, routing to %s", detectedScenario, config.ScenarioLongContext)
+			detectedScenario = config.ScenarioLongContext
+		}
+		s.logRouted(r, detectedScenario)
+		if sp, ok := routing.ScenarioRoutes[detectedScenario]; ok {
+			route = sp
 			providers = sp.Providers
 			modelOverrides = sp.Models
 			usingScenarioRoute = true
@@ -171,20 +308,97 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Oversized requests, including those no provider of the chain accepts,
+	// go straight to the longContext route when allowed; otherwise they are
+	// rejected before any provider sees them. Rerouted requests don't fall
+	// back to the default chain, which they exceed.
+	reason := s.sizeLimitExceeded(len(bodyBytes), detectedScenario, route)
+	if reason == "" {
+		chain := providers
+		if usingScenarioRoute {
+			chain = append(slices.Clip(providers), defaults...)
+		}
+		reason = chainSizeLimit(chain, len(bodyBytes))
+	}
+	if reason != "" {
+		lc := s.oversizedRoute(len(bodyBytes), route)
+		if lc == nil {
+			s.rejectOversized(w, r, reason)
+			return
+		}
+		s.Logger.Printf("[routing] %s, routing to %s", reason, config.ScenarioLongContext)
+		detectedScenario = config.ScenarioLongContext
+		providers = lc.Providers
+		modelOverrides = lc.Models
+		usingScenarioRoute = false
+	}
+
+	// Override headers replace whatever routing chose
+	if overrides.provider != "" {
+		forced := s.findProvider(overrides.provider)
+		if forced == nil {
+			s.writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "unknown provider in "+ProviderHeader+": "+overrides.provider, nil)
+			return
+		}
+		s.Logger.Printf("[override] provider %s forced by %s", forced.Name, ProviderHeader)
+		providers = []*Provider{forced}
+		modelOverrides = nil
+		usingScenarioRoute = false
+	}
+	if forced := s.forcedModels(overrides.model); forced != nil {
+		s.Logger.Printf("[override] model %s forced by %s", overrides.model, ModelHeader)
+		modelOverrides = forced
+	}
+
+	// Repeated requests may be answered from the response cache.
+	if s.Cache != nil {
+		rec, served := s.serveCached(w, r, req, providers, modelOverrides, bypassCache)
+		if served {
+			return
+		}
+		if rec != nil {
+			w = rec
+			defer func() { s.Cache.store(rec, time.Now()) }()
+		}
+	}
+
+	providers = s.applyAffinity(s.applyStrategy(providers, req), sessionID)
+
+	// Requests that reference uploaded files must go to the provider storing
+	// them; no other provider can serve them, so failover is disabled.
+	if fileID, owner, pinned := s.pinnedProvider(referencedFileIDs(r.URL.Path, req.data)); pinned {
+		if owner == nil {
+			s.Logger.Printf("[files] %s is pinned to a provider that is no longer configured", fileID)
+		} else {
+			s.Logger.Printf("[files] %s pinned to %s, failover disabled", fileID, owner.Name)
+			providers = []*Provider{owner}
+			usingScenarioRoute = false
+		}
+	}
+
+	// A token fixed in the config clears its auth backoff before the queue
+	// decides whether every provider is backed off
+	s.refreshTokens(r, providers)
+	if usingScenarioRoute {
+		s.refreshTokens(r, defaults)
+	}
+	s.waitForProviders(ctx, providers)
+
 	// Track provider failure details for error reporting
 	var failures []providerFailure
+	stream := s.newStreamState(req)
 
 	// Try scenario providers first, then fallback to default if all fail
-	success := s.tryProviders(w, r, providers, modelOverrides, bodyBytes, sessionID, &failures)
+	success := s.tryProviders(w, r, providers, modelOverrides, req, sessionID, stream, &failures)
 	if success {
 		return
 	}
 
 	// If scenario route failed and we have default providers to fallback to
-	if usingScenarioRoute && len(s.Providers) > 0 {
+	if usingScenarioRoute && len(defaults) > 0 && ctx.Err() == nil {
 		s.Logger.Printf("[routing] scenario=%s all providers failed, falling back to default providers", detectedScenario)
 		// Clear model overrides for default providers
-		success = s.tryProviders(w, r, s.Providers, nil, bodyBytes, sessionID, &failures)
+		success = s.tryProviders(w, r, s.applyAffinity(s.applyStrategy(defaults, req), sessionID), s.forcedModels(overrides.model), req, sessionID, stream, &failures)
 		if success {
 			return
 		}
@@ -194,7 +408,9 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var errMsg strings.Builder
 	errMsg.WriteString("all providers failed\n")
 	for _, f := range failures {
-		if f.StatusCode > 0 {
+		if f.Skipped {
+			errMsg.WriteString(fmt.Sprintf("[%s] %s\n", f.Name, f.Body))
+		} else if f.StatusCode > 0 {
 			errMsg.WriteString(fmt.Sprintf("[%s] %d %s\n", f.Name, f.StatusCode, f.Body))
 		} else {
 			errMsg.WriteString(fmt.Sprintf("[%s] error: %s\n", f.Name, f.Body))
@@ -203,27 +419,110 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	errStr := errMsg.String()
 	s.Logger.Printf("%s", errStr)
-	if s.StructuredLogger != nil {
-		s.StructuredLogger.Error("", errStr)
+	s.logStructured(r, "", 0, LogLevelError, errStr)
+	if s.stopAttempts(w, r, stream) {
+		return
+	}
+	if stream.resuming() {
+		stream.abort(w, "stream interrupted and no provider could resume it")
+		return
 	}
-	http.Error(w, errStr, http.StatusBadGateway)
+	s.writeAllProvidersFailed(w, failures)
+}
+
+// stopForContext reports whether the request's context is done and, if the
+// request deadline (rather than the client) ended it, answers with a 504.
+func (s *ProxyServer) stopForContext(w http.ResponseWriter, r *http.Request) bool {
+	err := r.Context().Err()
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg := fmt.Sprintf("request deadline of %v exceeded", s.requestTimeout())
+		s.Logger.Printf("[request] %s %s: %s", r.Method, r.URL.Path, msg)
+		s.writeError(w, http.StatusGatewayTimeout, errTypeAPI, msg, nil)
+		return true
+	}
+	s.Logger.Printf("[request] %s %s canceled by client", r.Method, r.URL.Path)
+	return true
 }
 
 // tryProviders attempts to forward the request to each provider in order.
-// Returns true if a provider successfully handled the request.
-func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, providers []*Provider, modelOverrides map[string]string, bodyBytes []byte, sessionID string, failures *[]providerFailure) bool {
+// Returns true if a provider successfully handled the request. A stream cut
+// off mid-response is resumed on the next provider when stream is non-nil.
+func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, providers []*Provider, modelOverrides map[string]string, req *parsedRequest, sessionID string, stream *streamState, failures *[]providerFailure) bool {
+	policy := s.failoverPolicyFor(r.Method, r.URL.Path)
+	gate := capabilityGate(providers, req)
+	var hedged *Provider // provider that won a hedged race; not tried again
+
 	for i, p := range providers {
 		isLast := i == len(providers)-1
+		if p == hedged {
+			continue
+		}
+
+		// Client gone or request deadline reached: don't start another attempt.
+		if s.stopAttempts(w, r, stream) {
+			return true
+		}
+
+		// Cooldowns and maintenance windows apply even to the last provider:
+		// the operator asked for it not to be used.
+		if reason := p.unavailableReason(); reason != "" {
+			msg := fmt.Sprintf("skipping (%s)", reason)
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+			*failures = append(*failures, providerFailure{Name: p.Name, Body: reason, Skipped: true})
+			continue
+		}
+
+		if reason := providerSizeLimit(p, len(req.raw)); reason != "" {
+			msg := fmt.Sprintf("skipping (%s)", reason)
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+			*failures = append(*failures, providerFailure{Name: p.Name, Body: reason, Skipped: true, TooLarge: true})
+			continue
+		}
+
+		// Don't forward requests a provider has declared it can't serve
+		if gate != nil {
+			if missing := p.missingCapability(*gate); missing != "" {
+				msg := fmt.Sprintf("skipping (%s)", missing)
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+				*failures = append(*failures, providerFailure{Name: p.Name, Body: missing, Skipped: true})
+				continue
+			}
+		}
 
 		if !p.IsHealthy() && !isLast {
-			msg := fmt.Sprintf("skipping (unhealthy, backoff %v)", p.Backoff)
+			backoff := p.CurrentBackoff()
+			msg := fmt.Sprintf("skipping (unhealthy, backoff %v)", backoff)
 			s.Logger.Printf("[%s] %s", p.Name, msg)
-			s.logStructured(p.Name, r.Method, r.URL.Path, 0, LogLevelInfo, msg)
+			s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+			*failures = append(*failures, providerFailure{Name: p.Name, Body: fmt.Sprintf("unhealthy, backoff %v", backoff), Skipped: true})
 			continue
 		}
 
 		if !p.IsHealthy() && isLast {
-			s.Logger.Printf("[%s] last provider, forcing request despite unhealthy (backoff %v)", p.Name, p.Backoff)
+			s.Logger.Printf("[%s] last provider, forcing request despite unhealthy (backoff %v)", p.Name, p.CurrentBackoff())
+		}
+
+		// A provider at its concurrency limit passes the request on; the last
+		// one queues it until a slot frees up.
+		if !p.tryAcquire() {
+			if !isLast {
+				msg := fmt.Sprintf("skipping (at concurrency limit of %d)", p.MaxConcurrent)
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+				*failures = append(*failures, providerFailure{Name: p.Name, Body: fmt.Sprintf("at concurrency limit of %d", p.MaxConcurrent), Skipped: true})
+				continue
+			}
+			s.Logger.Printf("[%s] last provider at concurrency limit of %d, queueing", p.Name, p.MaxConcurrent)
+			if err := p.acquire(r.Context()); err != nil {
+				s.stopAttempts(w, r, stream)
+				return true
+			}
 		}
 
 		// Get model override for this specific provider
@@ -232,22 +531,68 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			modelOverride = modelOverrides[p.Name]
 		}
 
+		if from := lastAttempted(*failures); from != "" {
+			s.metrics.failover(from)
+		}
+
 		s.Logger.Printf("[%s] trying %s %s", p.Name, r.Method, r.URL.Path)
-		resp, err := s.forwardRequest(r, p, bodyBytes, modelOverride)
+		attemptStart := time.Now()
+		var resp *http.Response
+		var attemptTimedOut bool
+		var err error
+		hedge, hedgeLeft := (*Provider)(nil), 0
+		delay := s.hedgeDelay(req, stream)
+		if delay > 0 {
+			hedge, hedgeLeft = hedgeCandidate(providers, i, req, gate)
+		}
+		if hedge != nil {
+			res := s.raceAttempt(r, p, hedge, modelOverrides, len(providers)-i, hedgeLeft, req, sessionID, delay)
+			if res.p != p {
+				p, hedged = res.p, res.p
+				modelOverride = modelOverrides[p.Name]
+			}
+			resp, attemptTimedOut, err, attemptStart = res.resp, res.timedOut, res.err, res.start
+		} else {
+			resp, attemptTimedOut, err = s.forwardWithContinuity(r, p, stream.request(req), modelOverride, len(providers)-i, sessionID)
+		}
+		if err != nil {
+			p.release()
+		} else {
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: p.release}
+		}
+		if err == nil {
+			latency := time.Since(attemptStart)
+			s.metrics.attempt(p.Name, resp.StatusCode, latency)
+			if resp.StatusCode < 400 {
+				p.recordLatency(latency)
+			}
+			s.recordRateLimit(r, p, resp.Header)
+		} else if r.Context().Err() == nil {
+			s.metrics.attempt(p.Name, 0, 0)
+		}
 		if err != nil {
-			// Check if client canceled the request - don't mark provider unhealthy
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				msg := fmt.Sprintf("request canceled by client: %v", err)
+			// Client canceled or request deadline reached - don't mark provider unhealthy
+			if r.Context().Err() != nil {
+				msg := fmt.Sprintf("request canceled: %v", err)
 				s.Logger.Printf("[%s] %s", p.Name, msg)
-				s.logStructured(p.Name, r.Method, r.URL.Path, 0, LogLevelInfo, msg)
-				// Return true to stop processing - client is gone
+				s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+				s.stopAttempts(w, r, stream)
 				return true
 			}
+			if attemptTimedOut {
+				err = fmt.Errorf("no response within attempt timeout: %w", err)
+			}
 			msg := fmt.Sprintf("request error: %v", err)
 			s.Logger.Printf("[%s] %s", p.Name, msg)
-			s.logStructuredError(p.Name, r.Method, r.URL.Path, err)
+			s.logStructuredError(r, p.Name, err)
 			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: 0, Body: err.Error()})
 			p.MarkFailed()
+			s.recordHealth(p)
+			if !canFailover(policy, !isDialError(err)) {
+				s.Logger.Printf("[%s] failover not allowed for %s (policy=%s)", p.Name, r.URL.Path, policy)
+				s.writeAllProvidersFailed(w, *failures)
+				return true
+			}
 			continue
 		}
 
@@ -257,9 +602,14 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			resp.Body.Close()
 			msg := fmt.Sprintf("got %d (auth/account error), failing over", resp.StatusCode)
 			s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
-			s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody)
+			s.logStructuredWithResponse(r, p.Name, resp.StatusCode, msg, errBody)
 			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: string(errBody)})
 			p.MarkAuthFailed()
+			s.recordHealth(p)
+			if !canFailover(policy, false) {
+				writeUpstreamResponse(w, resp, p.Name, errBody)
+				return true
+			}
 			continue
 		}
 
@@ -269,9 +619,14 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			resp.Body.Close()
 			msg := fmt.Sprintf("got %d (rate limited), failing over", resp.StatusCode)
 			s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
-			s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody)
+			s.logStructuredWithResponse(r, p.Name, resp.StatusCode, msg, errBody)
 			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: string(errBody)})
 			p.MarkFailed()
+			s.recordHealth(p)
+			if !canFailover(policy, false) {
+				writeUpstreamResponse(w, resp, p.Name, errBody)
+				return true
+			}
 			continue
 		}
 
@@ -283,69 +638,266 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 
 			if isRequestRelatedError(errBody) {
 				// Request-related error (e.g., context too long) - failover without marking unhealthy
-				msg := fmt.Sprintf("got %d (request-related error), failing over without backoff, request_body_size=%d", resp.StatusCode, len(bodyBytes))
+				msg := fmt.Sprintf("got %d (request-related error), failing over without backoff, request_body_size=%d", resp.StatusCode, len(req.raw))
 				s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
-				s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody)
+				s.logStructuredWithResponse(r, p.Name, resp.StatusCode, msg, errBody)
 				*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: string(errBody)})
+				if !canFailover(policy, true) {
+					writeUpstreamResponse(w, resp, p.Name, errBody)
+					return true
+				}
 				continue
 			}
 
 			// Server-side issue - mark as failed with backoff
 			msg := fmt.Sprintf("got %d (server error), failing over", resp.StatusCode)
 			s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
-			s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody)
+			s.logStructuredWithResponse(r, p.Name, resp.StatusCode, msg, errBody)
 			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: string(errBody)})
 			p.MarkFailed()
+			s.recordHealth(p)
+			if !canFailover(policy, true) {
+				s.Logger.Printf("[%s] failover not allowed for %s (policy=%s), returning provider error", p.Name, r.URL.Path, policy)
+				writeUpstreamResponse(w, resp, p.Name, errBody)
+				return true
+			}
+			continue
+		}
+
+		// Claude Code fails when token counting isn't supported, so
+		// providers without the endpoint get a local estimate instead.
+		if (resp.StatusCode == 404 || resp.StatusCode == 405) && r.URL.Path == countTokensPath && req.data != nil {
+			resp.Body.Close()
+			recordProvider(w, p.Name)
+			n := writeTokenEstimate(w, req.data)
+			msg := fmt.Sprintf("got %d for token counting, answered with a local estimate of %d tokens", resp.StatusCode, n)
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, resp.StatusCode, LogLevelInfo, msg)
+			return true
+		}
+
+		// A stream under way can only be continued by another stream.
+		if stream.resuming() && !isEventStream(resp) {
+			resp.Body.Close()
+			msg := fmt.Sprintf("got %d without an event stream, cannot resume stream", resp.StatusCode)
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, 0, LogLevelError, msg)
+			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: msg})
 			continue
 		}
 
+		recovered := p.CurrentBackoff() > 0
 		p.MarkHealthy()
+		if recovered {
+			s.recordHealth(p)
+		}
 		msg := fmt.Sprintf("success %d", resp.StatusCode)
 		s.Logger.Printf("[%s] %s", p.Name, msg)
-		s.logStructured(p.Name, r.Method, r.URL.Path, resp.StatusCode, LogLevelInfo, msg)
+		model := s.servedModel(req, p, modelOverride)
+		s.logStructuredSuccess(r, p.Name, resp.StatusCode, msg, model, time.Since(attemptStart))
 
 		// Update session cache with token usage from response
 		s.updateSessionCache(sessionID, resp)
+		if sessionID != "" {
+			s.continuity.served(sessionID, p.Name, messageCount(req))
+			s.recordSnapshot(sessionID, p, model)
+		}
+		recordProvider(w, p.Name)
+		s.trackFileOwnership(r, resp, p)
+		if notice := s.usage.takeNotice(sessionID); notice != "" {
+			w.Header().Set(UsageWarningHeader, notice)
+		}
 
-		s.copyResponse(w, resp, p)
+		inputTokens, outputTokens := s.copyResponse(w, r, resp, p, sessionID, stream)
+		s.logUsage(r, p.Name, inputTokens, outputTokens)
+		p.recordSpend(time.Now(), inputTokens, outputTokens)
+		s.recordUsage(p, time.Now(), inputTokens, outputTokens)
+
+		if stream.cutOff() && r.Context().Err() == nil && !isLast && canFailover(policy, true) {
+			if !stream.canResume() {
+				msg := fmt.Sprintf("stream ended inside a %s block, which can't be resumed", stream.openType)
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(r, p.Name, 0, LogLevelWarn, msg)
+				p.MarkFailed()
+				s.recordHealth(p)
+				stream.abort(w, msg)
+				return true
+			}
+			mode := stream.resume(w, req)
+			msg := fmt.Sprintf("stream ended before message_stop, resuming on next provider (%s)", mode)
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, 0, LogLevelWarn, msg)
+			*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: resp.StatusCode, Body: "stream cut off"})
+			p.MarkFailed()
+			s.recordHealth(p)
+			continue
+		}
 		return true
 	}
 
 	return false
 }
 
-// logStructured logs to the structured logger if available.
-func (s *ProxyServer) logStructured(provider, method, path string, statusCode int, level LogLevel, message string) {
+// lastAttempted returns the provider of the latest failure that was an
+// attempt rather than a skip, or "" if there is none.
+func lastAttempted(failures []providerFailure) string {
+	for i := len(failures) - 1; i >= 0; i-- {
+		if !failures[i].Skipped {
+			return failures[i].Name
+		}
+	}
+	return ""
+}
+
+// refreshTokens switches auth-failed providers whose token was changed in
+// the config to the new token, clearing their auth backoff.
+func (s *ProxyServer) refreshTokens(r *http.Request, providers []*Provider) {
+	for _, p := range providers {
+		if p.refreshToken() {
+			s.recordHealth(p)
+			msg := "token changed, auth backoff cleared"
+			s.Logger.Printf("[%s] %s", p.Name, msg)
+			s.logStructured(r, p.Name, 0, LogLevelInfo, msg)
+		}
+	}
+}
+
+// logStructured logs to the structured logger if available. r supplies the
+// method, path and client and may be nil.
+func (s *ProxyServer) logStructured(r *http.Request, provider string, statusCode int, level LogLevel, message string) {
 	if s.StructuredLogger == nil {
 		return
 	}
-	s.StructuredLogger.Log(LogEntry{
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
 		Level:      level,
 		Provider:   provider,
-		Method:     method,
-		Path:       path,
 		StatusCode: statusCode,
 		Message:    message,
-	})
+	}))
+}
+
+// logStructuredSuccess logs a successful attempt with the model sent and its
+// latency to the structured logger.
+func (s *ProxyServer) logStructuredSuccess(r *http.Request, provider string, statusCode int, message, model string, latency time.Duration) {
+	if s.StructuredLogger == nil {
+		return
+	}
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
+		Level:      LogLevelInfo,
+		Provider:   provider,
+		StatusCode: statusCode,
+		Message:    message,
+		Model:      model,
+		LatencyMs:  latency.Milliseconds(),
+	}))
+}
+
+// logRouted logs the scenario detected for a request, for its trace.
+func (s *ProxyServer) logRouted(r *http.Request, scenario config.Scenario) {
+	if s.StructuredLogger == nil {
+		return
+	}
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
+		Level:    LogLevelInfo,
+		Message:  routedMessage,
+		Scenario: string(scenario),
+	}))
+}
+
+// logUsage logs the token usage of a served request, for its trace.
+func (s *ProxyServer) logUsage(r *http.Request, provider string, inputTokens, outputTokens int) {
+	if s.StructuredLogger == nil || (inputTokens <= 0 && outputTokens <= 0) {
+		return
+	}
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
+		Level:        LogLevelInfo,
+		Provider:     provider,
+		Message:      usageMessage,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}))
 }
 
 // logStructuredError logs an error to the structured logger.
-func (s *ProxyServer) logStructuredError(provider, method, path string, err error) {
+func (s *ProxyServer) logStructuredError(r *http.Request, provider string, err error) {
 	if s.StructuredLogger == nil {
 		return
 	}
-	s.StructuredLogger.RequestError(provider, method, path, err)
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
+		Level:    LogLevelError,
+		Provider: provider,
+		Message:  "request failed",
+		Error:    err.Error(),
+	}))
 }
 
 // logStructuredWithResponse logs an error with response body to the structured logger.
-func (s *ProxyServer) logStructuredWithResponse(provider, method, path string, statusCode int, message string, responseBody []byte) {
+func (s *ProxyServer) logStructuredWithResponse(r *http.Request, provider string, statusCode int, message string, responseBody []byte) {
 	if s.StructuredLogger == nil {
 		return
 	}
-	s.StructuredLogger.RequestErrorWithResponse(provider, method, path, statusCode, message, responseBody)
+	s.StructuredLogger.Log(requestEntry(r, LogEntry{
+		Level:        LogLevelError,
+		Provider:     provider,
+		StatusCode:   statusCode,
+		Message:      message,
+		ResponseBody: truncateResponseBody(responseBody),
+	}))
+}
+
+// requestEntry fills in e's request ID, method, path and client from r, if any.
+func requestEntry(r *http.Request, e LogEntry) LogEntry {
+	if r != nil {
+		e.RequestID = requestIDOf(r)
+		e.Method = r.Method
+		e.Path = r.URL.Path
+		e.Client = clientOf(r)
+	}
+	return e
 }
 
-func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte, modelOverride string) (*http.Response, error) {
+// forwardAttempt forwards one attempt with a timeout on receiving response
+// headers, derived from the request deadline and the providers still left to
+// try, or the provider's own if shorter. A response body that goes idle for
+// longer than the provider's idle timeout fails. The attempt's context is
+// released when the response body is closed.
+func (s *ProxyServer) forwardAttempt(r *http.Request, p *Provider, body *parsedRequest, modelOverride string, providersLeft int, strip historyStrip) (resp *http.Response, timedOut bool, err error) {
+	if f, ok := s.Faults.take(p.Name); ok {
+		s.Logger.Printf("[%s] injecting fault: %s", p.Name, f)
+		return f.result()
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var timer *time.Timer
+	timeout := attemptTimeout(r.Context(), providersLeft)
+	if p.ResponseTimeout > 0 && (timeout <= 0 || p.ResponseTimeout < timeout) {
+		timeout = p.ResponseTimeout
+	}
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, cancel)
+	}
+
+	resp, err = s.forwardRequest(r.WithContext(ctx), p, body, modelOverride, strip)
+	if timer != nil && !timer.Stop() {
+		timedOut = true
+		if err == nil {
+			// Headers raced the timer; the body is already unusable.
+			resp.Body.Close()
+			resp, err = nil, context.Canceled
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, timedOut, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	if p.IdleTimeout > 0 {
+		resp.Body = newIdleTimeoutBody(resp.Body, p.IdleTimeout, cancel)
+	}
+	return resp, false, nil
+}
+
+func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body *parsedRequest, modelOverride string, strip historyStrip) (*http.Response, error) {
 	var modifiedBody []byte
 	if modelOverride != "" {
 		// Scenario routing: skip model mapping, use the override model directly
@@ -354,9 +906,40 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		// Normal: apply per-provider model mapping
 		modifiedBody = s.applyModelMapping(body, p)
 	}
+	modifiedBody = s.applySystemPrompt(modifiedBody, p)
+
+	// Remove history the provider can't accept (foreign thinking signatures,
+	// unsupported tool blocks)
+	if !strip.none() {
+		if sanitized, err := sanitizeHistory(modifiedBody, strip); err == nil {
+			s.Logger.Printf("[%s] sanitized history (thinking_before=%d, tools=%v)", p.Name, strip.thinkingBefore, strip.tools)
+			modifiedBody = sanitized
+		}
+	}
 
-	// Apply request transformation if needed
+	// The Responses API has its own path, Azure OpenAI names a deployment
+	// and API version in the URL, local servers are sent Chat Completions
+	// under their base URL, and Gemini, Bedrock and Vertex AI take the model
+	// and streaming mode in the URL rather than the body
 	providerFormat := p.GetType()
+	path, query := r.URL.Path, r.URL.RawQuery
+	switch {
+	case providerFormat == config.ProviderTypeGemini && path == "/v1/messages":
+		path, query = transform.GeminiPath(modifiedBody)
+	case providerFormat == config.ProviderTypeOpenAIResponses:
+		path = transform.ResponsesPath(path)
+	case providerFormat == config.ProviderTypeAzureOpenAI:
+		path = transform.AzurePath(path, s.azureDeployment(body, p, modelOverride))
+		query = p.azureQuery()
+	case providerFormat == config.ProviderTypeLocal:
+		path = transform.LocalPath(p.BaseURL.Path, path)
+	case providerFormat == config.ProviderTypeBedrock && path == "/v1/messages":
+		path = transform.BedrockPath(modifiedBody, p.bedrockRegion())
+	case providerFormat == config.ProviderTypeVertex && path == "/v1/messages" && p.Vertex != nil:
+		path = transform.VertexPath(modifiedBody, p.Vertex.ProjectID, p.Vertex.Region)
+	}
+
+	// Apply request transformation if needed
 	if transform.NeedsTransform(s.ClientFormat, providerFormat) {
 		transformer := transform.GetTransformer(providerFormat)
 		transformed, err := transformer.TransformRequest(modifiedBody, s.ClientFormat)
@@ -368,9 +951,9 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		}
 	}
 
-	targetURL := singleJoiningSlash(p.BaseURL.String(), r.URL.Path)
-	if r.URL.RawQuery != "" {
-		targetURL += "?" + r.URL.RawQuery
+	targetURL := singleJoiningSlash(p.BaseURL.String(), path)
+	if query != "" {
+		targetURL += "?" + query
 	}
 
 	req, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, bytes.NewReader(modifiedBody))
@@ -386,55 +969,95 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 	}
 
 	// Override auth
-	req.Header.Set("x-api-key", p.Token)
-	req.Header.Set("Authorization", "Bearer "+p.Token)
+	p.applyAuth(req.Header)
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(modifiedBody)))
 
 	// Apply environment variable headers
 	s.applyEnvVarsHeaders(req, p.EnvVars)
 
-	return s.Client.Do(req)
+	// Provider headers last, so they can replace any of the above
+	p.applyHeaders(req.Header)
+	if err := p.authorizeRequest(req, modifiedBody, p.HTTPClient(s.Client)); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := s.doWithRetries(r, req, p, modifiedBody)
+	if err == nil && providerFormat == config.ProviderTypeBedrock {
+		bedrockResponse(resp)
+	}
+	if s.Capture != nil {
+		s.Capture.capture(r, p, req, modifiedBody, resp, err, start)
+	}
+	return resp, err
 }
 
-func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p *Provider) {
+// copyResponse writes the provider response to the client and returns its
+// token usage, if reported. Event streams are followed by stream, if
+// non-nil, and continue the client's stream when it is being resumed.
+func (s *ProxyServer) copyResponse(w http.ResponseWriter, r *http.Request, resp *http.Response, p *Provider, sessionID string, stream *streamState) (inputTokens, outputTokens int) {
 	defer resp.Body.Close()
 
 	// Check if response transformation is needed
 	providerFormat := p.GetType()
 	needsTransform := transform.NeedsTransform(s.ClientFormat, providerFormat)
 
-	// Stream SSE responses (no transformation for streaming)
-	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
-		for k, vv := range resp.Header {
-			for _, v := range vv {
-				w.Header().Add(k, v)
+	// Stream SSE responses, converting the events of providers whose streams
+	// differ from the client's
+	if isEventStream(resp) {
+		if !stream.resuming() {
+			for k, vv := range resp.Header {
+				for _, v := range vv {
+					w.Header().Add(k, v)
+				}
 			}
+			w.WriteHeader(resp.StatusCode)
 		}
-		w.WriteHeader(resp.StatusCode)
 
-		flusher, ok := w.(http.Flusher)
-		buf := make([]byte, 4096)
-		for {
-			n, err := resp.Body.Read(buf)
-			if n > 0 {
-				w.Write(buf[:n])
-				if ok {
-					flusher.Flush()
-				}
+		var usage sseUsageTracker
+		var relayed int
+		if stream != nil {
+			stream.sent = true
+		}
+		convert := transform.GetStreamTransformer(providerFormat, s.ClientFormat)
+		relay := func(event []byte) []byte {
+			usage.observe(event)
+			if stream != nil {
+				event = stream.relay(event)
+			}
+			relayed += len(event)
+			return event
+		}
+		err := relayEvents(w, resp.Body, func(event []byte) []byte {
+			if convert == nil {
+				return relay(event)
 			}
-			if err != nil {
-				break
+			var out []byte
+			for _, ev := range convert.TransformEvent(event) {
+				out = append(out, relay(ev)...)
 			}
+			return out
+		})
+		s.metrics.streamed(p.Name, relayed)
+		if err != nil {
+			s.Logger.Printf("[%s] stream relay ended: %v", p.Name, err)
 		}
-		return
+		s.recordSessionUsage(sessionID, usage.inputTokens, usage.outputTokens)
+		return usage.inputTokens, usage.outputTokens
 	}
 
-	// Non-streaming response - can apply transformation
-	body, err := io.ReadAll(resp.Body)
+	// Non-streaming response - can apply transformation.
+	// Buffer at most the configured ceiling to avoid OOM on pathological responses.
+	body, rest, overflow, err := readBodyLimited(resp.Body, s.maxResponseBytes())
 	if err != nil {
-		http.Error(w, "failed to read response", http.StatusBadGateway)
-		return
+		s.writeError(w, http.StatusBadGateway, errTypeAPI, "failed to read response", nil)
+		return 0, 0
+	}
+	if overflow {
+		s.copyOversizedResponse(w, resp, p, body, rest, needsTransform)
+		return 0, 0
 	}
+	inputTokens, outputTokens = responseUsage(body)
 
 	// Apply response transformation if needed
 	if needsTransform && len(body) > 0 {
@@ -447,6 +1070,10 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 			body = transformed
 		}
 	}
+	var ok bool
+	if body, ok = s.applyResponseHooks(w, r, p, body); !ok {
+		return inputTokens, outputTokens
+	}
 
 	// Copy headers (except Content-Length which may have changed)
 	for k, vv := range resp.Header {
@@ -460,27 +1087,64 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
 	w.WriteHeader(resp.StatusCode)
 	w.Write(body)
+	return inputTokens, outputTokens
 }
 
-// applyModelOverride replaces the model in the request body with the given override.
-func (s *ProxyServer) applyModelOverride(body []byte, override string, providerName string) []byte {
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return body
+// copyOversizedResponse handles a non-streaming response that exceeds the
+// buffering ceiling. Responses that need no transformation are streamed through
+// as-is; responses that would need transformation cannot be converted without
+// buffering, so the client receives a clear error instead.
+func (s *ProxyServer) copyOversizedResponse(w http.ResponseWriter, resp *http.Response, p *Provider, prefix []byte, rest io.Reader, needsTransform bool) {
+	limit := s.maxResponseBytes()
+	if needsTransform {
+		msg := fmt.Sprintf("response exceeds %d bytes and cannot be transformed from %s to %s", limit, p.GetType(), s.ClientFormat)
+		s.Logger.Printf("[%s] %s", p.Name, msg)
+		s.logStructured(nil, p.Name, resp.StatusCode, LogLevelError, msg)
+		s.writeError(w, http.StatusBadGateway, errTypeAPI, msg, nil)
+		return
+	}
+
+	s.Logger.Printf("[%s] response exceeds %d bytes, streaming through untransformed", p.Name, limit)
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
 	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(prefix)
+	io.Copy(w, rest)
+}
 
-	originalModel, _ := data["model"].(string)
-	if originalModel == override {
-		return body
+// maxResponseBytes returns the effective buffering ceiling for non-streaming responses.
+func (s *ProxyServer) maxResponseBytes() int64 {
+	if s.MaxResponseBytes > 0 {
+		return s.MaxResponseBytes
 	}
+	return DefaultMaxResponseBytes
+}
 
-	s.Logger.Printf("[%s] model override: %s → %s", providerName, originalModel, override)
-	data["model"] = override
-	modified, err := json.Marshal(data)
+// readBodyLimited reads at most limit bytes from r. If the body is larger,
+// overflow is true and rest yields the unread remainder (the returned data
+// holds everything read so far).
+func readBodyLimited(r io.Reader, limit int64) (data []byte, rest io.Reader, overflow bool, err error) {
+	data, err = io.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
-		return body
+		return data, nil, false, err
 	}
-	return modified
+	if int64(len(data)) > limit {
+		return data, r, true, nil
+	}
+	return data, nil, false, nil
+}
+
+// applyModelOverride replaces the model in the request body with the given override.
+func (s *ProxyServer) applyModelOverride(body *parsedRequest, override string, providerName string) []byte {
+	if body.data == nil || body.model == override {
+		return body.raw
+	}
+
+	s.Logger.Printf("[%s] model override: %s → %s", providerName, body.model, override)
+	return body.withModel(override)
 }
 
 // applyModelMapping detects the model type in the request and maps it to
@@ -493,72 +1157,46 @@ func (s *ProxyServer) applyModelOverride(body []byte, override string, providerN
 //  3. Model name contains "opus" → OpusModel
 //  4. Model name contains "sonnet" → SonnetModel
 //  5. Fallback → Model (default model)
-func (s *ProxyServer) applyModelMapping(body []byte, p *Provider) []byte {
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return body
-	}
-
-	originalModel, ok := data["model"].(string)
-	if !ok || originalModel == "" {
-		return body
+func (s *ProxyServer) applyModelMapping(body *parsedRequest, p *Provider) []byte {
+	originalModel := body.model
+	if originalModel == "" {
+		return body.raw
 	}
 
-	mapped := s.mapModel(originalModel, data, p)
+	mapped := s.mapModel(originalModel, body.data, p)
 	if mapped == originalModel {
-		return body
+		return body.raw
 	}
 
 	s.Logger.Printf("[%s] model mapping: %s → %s", p.Name, originalModel, mapped)
-	data["model"] = mapped
-	modified, err := json.Marshal(data)
-	if err != nil {
-		return body
-	}
-	return modified
+	return body.withModel(mapped)
 }
 
 // mapModel determines which provider model to use based on the request.
 func (s *ProxyServer) mapModel(original string, body map[string]interface{}, p *Provider) string {
-	// 1. Thinking mode → reasoning model
-	if hasThinkingEnabled(body) && p.ReasoningModel != "" {
-		return p.ReasoningModel
-	}
-
-	// 2. Match by model type (case-insensitive)
-	lower := strings.ToLower(original)
-	if strings.Contains(lower, "haiku") && p.HaikuModel != "" {
-		return p.HaikuModel
-	}
-	if strings.Contains(lower, "opus") && p.OpusModel != "" {
-		return p.OpusModel
-	}
-	if strings.Contains(lower, "sonnet") && p.SonnetModel != "" {
-		return p.SonnetModel
-	}
-
-	// 3. Default model
-	if p.Model != "" {
-		return p.Model
-	}
-
-	// 4. No mapping — keep original
-	return original
+	model, _ := s.mapModelRule(original, body, p)
+	return model
 }
 
 // updateSessionCache extracts token usage from the response and updates the session cache.
+// Streaming responses are skipped here; their usage is collected while relaying.
 func (s *ProxyServer) updateSessionCache(sessionID string, resp *http.Response) {
-	if sessionID == "" {
+	if sessionID == "" || isEventStream(resp) {
 		return
 	}
 
-	// Read response body to extract usage information
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Read response body to extract usage information, bounded by the
+	// buffering ceiling so huge responses aren't held in memory twice.
+	bodyBytes, rest, overflow, err := readBodyLimited(resp.Body, s.maxResponseBytes())
 	if err != nil {
 		return
 	}
 	// Restore body for copyResponse
-	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	if overflow {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(bodyBytes), rest), resp.Body}
+		return
+	}
+	resp.Body = readCloser{bytes.NewReader(bodyBytes), resp.Body}
 
 	var respData map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &respData); err != nil {
@@ -573,15 +1211,33 @@ func (s *ProxyServer) updateSessionCache(sessionID string, resp *http.Response)
 
 	inputTokens, _ := usage["input_tokens"].(float64)
 	outputTokens, _ := usage["output_tokens"].(float64)
+	s.recordSessionUsage(sessionID, int(inputTokens), int(outputTokens))
+}
 
-	if inputTokens > 0 || outputTokens > 0 {
-		UpdateSessionUsage(sessionID, &SessionUsage{
-			InputTokens:  int(inputTokens),
-			OutputTokens: int(outputTokens),
-		})
-		s.Logger.Printf("[session] updated cache for %s: input=%d, output=%d",
-			sessionID, int(inputTokens), int(outputTokens))
+// recordSessionUsage stores a response's token usage in the session cache.
+func (s *ProxyServer) recordSessionUsage(sessionID string, inputTokens, outputTokens int) {
+	if sessionID == "" || (inputTokens <= 0 && outputTokens <= 0) {
+		return
 	}
+	UpdateSessionUsage(sessionID, &SessionUsage{
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	})
+	s.Logger.Printf("[session] updated cache for %s: input=%d, output=%d",
+		sessionID, inputTokens, outputTokens)
+	s.checkUsageWarnings(sessionID, inputTokens)
+}
+
+// isEventStream reports whether resp is a server-sent event stream.
+func isEventStream(resp *http.Response) bool {
+	return strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// readCloser pairs a replacement reader with the original body's closer so
+// restored bodies still release the underlying connection.
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 func singleJoiningSlash(a, b string) string {
@@ -637,10 +1293,15 @@ func isRequestRelatedError(body []byte) bool {
 // Environment variable names are converted to lowercase and prefixed with "x-env-".
 // For example: CLAUDE_CODE_MAX_OUTPUT_TOKENS -> x-env-claude-code-max-output-tokens
 func (s *ProxyServer) applyEnvVarsHeaders(req *http.Request, envVars map[string]string) {
-	if envVars == nil {
-		return
+	for name, v := range EnvVarHeaders(envVars) {
+		req.Header.Set(name, v)
 	}
+}
 
+// EnvVarHeaders returns the headers applyEnvVarsHeaders sets for envVars,
+// by header name. Empty names and values are skipped.
+func EnvVarHeaders(envVars map[string]string) map[string]string {
+	headers := make(map[string]string, len(envVars))
 	for k, v := range envVars {
 		if k == "" || v == "" {
 			continue
@@ -648,24 +1309,83 @@ func (s *ProxyServer) applyEnvVarsHeaders(req *http.Request, envVars map[string]
 		// Convert env var name to HTTP header format
 		// CLAUDE_CODE_MAX_OUTPUT_TOKENS -> x-env-claude-code-max-output-tokens
 		headerName := "x-env-" + strings.ToLower(strings.ReplaceAll(k, "_", "-"))
-		req.Header.Set(headerName, v)
+		headers[headerName] = v
 	}
+	return headers
 }
 
-// StartProxy starts the proxy server and returns the port.
-func StartProxy(providers []*Provider, clientFormat string, listenAddr string, logger *log.Logger) (int, error) {
-	srv := NewProxyServerWithClientFormat(providers, clientFormat, logger)
+// applyAuth sets the provider's credentials on h in the headers its API
+// reads them from.
+func (p *Provider) applyAuth(h http.Header) {
+	p.applyToken(h, p.authToken())
+}
 
-	ln, err := net.Listen("tcp", listenAddr)
-	if err != nil {
-		return 0, fmt.Errorf("listen: %w", err)
+// applyToken sets token on h in the headers the provider's API reads it
+// from.
+func (p *Provider) applyToken(h http.Header, token string) {
+	switch p.GetType() {
+	case config.ProviderTypeGemini:
+		h.Del("x-api-key")
+		h.Del("Authorization")
+		h.Set("x-goog-api-key", token)
+		return
+	case config.ProviderTypeAzureOpenAI:
+		h.Del("x-api-key")
+		h.Del("Authorization")
+		h.Set("api-key", token)
+		return
+	case config.ProviderTypeLocal:
+		// Local servers usually take no token, or a bearer token
+		h.Del("x-api-key")
+		h.Del("Authorization")
+		if token != "" {
+			h.Set("Authorization", "Bearer "+token)
+		}
+		return
+	case config.ProviderTypeBedrock, config.ProviderTypeVertex:
+		// Set by authorizeRequest once the request is complete
+		h.Del("x-api-key")
+		h.Del("Authorization")
+		return
 	}
+	h.Set("x-api-key", token)
+	h.Set("Authorization", "Bearer "+token)
+}
 
-	port := ln.Addr().(*net.TCPAddr).Port
+// authorizeRequest adds the credentials of providers that authorize each
+// request rather than sending a fixed token: a SigV4 signature over body for
+// Bedrock, and an OAuth2 access token, obtained through client, for Vertex AI
+// and providers with a refresh token.
+func (p *Provider) authorizeRequest(req *http.Request, body []byte, client *http.Client) error {
+	switch {
+	case p.GetType() == config.ProviderTypeBedrock && p.Bedrock != nil:
+		signAWSRequest(req, body, p.Bedrock, bedrockService, time.Now())
+	case p.GetType() == config.ProviderTypeVertex && p.Vertex != nil:
+		token, err := p.Vertex.Token(req.Context(), client)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case p.OAuth != nil:
+		token, err := p.OAuth.Token(req.Context(), client)
+		if err != nil {
+			return err
+		}
+		p.applyToken(req.Header, token)
+	}
+	return nil
+}
 
-	go http.Serve(ln, srv)
+// applyHeaders sets the provider's configured headers on h.
+func (p *Provider) applyHeaders(h http.Header) {
+	for k, v := range p.Headers {
+		h.Set(k, v)
+	}
+}
 
-	return port, nil
+// StartProxy starts the proxy server and returns the port.
+func StartProxy(providers []*Provider, clientFormat string, listenAddr string, logger *log.Logger) (int, error) {
+	return ServeProxy(NewProxyServerWithClientFormat(providers, clientFormat, logger), listenAddr)
 }
 
 // StartProxyWithRouting starts the proxy server with scenario-based routing.
@@ -675,10 +1395,16 @@ func StartProxyWithRouting(routing *RoutingConfig, clientFormat string, listenAd
 	if srv.ClientFormat == "" {
 		srv.ClientFormat = config.ProviderTypeAnthropic
 	}
+	return ServeProxy(srv, listenAddr)
+}
 
-	ln, err := net.Listen("tcp", listenAddr)
+// ServeProxy starts serving an already configured proxy server in the
+// background and returns the port it listens on. It serves HTTPS if the
+// server has TLS settings.
+func ServeProxy(srv *ProxyServer, listenAddr string) (int, error) {
+	ln, err := srv.Listen(listenAddr)
 	if err != nil {
-		return 0, fmt.Errorf("listen: %w", err)
+		return 0, err
 	}
 
 	port := ln.Addr().(*net.TCPAddr).Port
@@ -687,3 +1413,16 @@ func StartProxyWithRouting(routing *RoutingConfig, clientFormat string, listenAd
 
 	return port, nil
 }
+
+// Listen opens the server's listener on addr, with TLS if the server has
+// TLS settings.
+func (s *ProxyServer) Listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	if s.TLS != nil {
+		ln = tls.NewListener(ln, s.TLS)
+	}
+	return ln, nil
+}
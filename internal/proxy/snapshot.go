@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// maxSnapshotSessions bounds the in-memory record of sessions already stored.
+const maxSnapshotSessions = 1000
+
+// SessionSnapshot records which profile, providers, models and routing a
+// session ran with, so output can be compared across gateways later.
+type SessionSnapshot struct {
+	SessionID string                      `json:"session_id"`
+	StartedAt time.Time                   `json:"started_at"`
+	Profile   string                      `json:"profile,omitempty"`
+	CLI       string                      `json:"cli,omitempty"`
+	Providers []ProviderSnapshot          `json:"providers"`
+	Routing   map[string]ScenarioSnapshot `json:"routing,omitempty"`
+	Served    []ServedModel               `json:"served"`
+}
+
+// ProviderSnapshot is a provider's model mapping when the session started.
+type ProviderSnapshot struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Model          string `json:"model,omitempty"`
+	ReasoningModel string `json:"reasoning_model,omitempty"`
+	HaikuModel     string `json:"haiku_model,omitempty"`
+	OpusModel      string `json:"opus_model,omitempty"`
+	SonnetModel    string `json:"sonnet_model,omitempty"`
+}
+
+// ScenarioSnapshot is a scenario route when the session started.
+type ScenarioSnapshot struct {
+	Providers []string          `json:"providers"`
+	Models    map[string]string `json:"models,omitempty"`
+}
+
+// ServedModel is a provider/model pair that answered requests in a session.
+type ServedModel struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	FirstUsed time.Time `json:"first_used"`
+}
+
+// snapshotTracker remembers which sessions and provider/model pairs have
+// been stored, so the database is only written when something is new. The
+// zero value is ready to use.
+type snapshotTracker struct {
+	mu       sync.Mutex
+	sessions map[string]map[ServedModel]bool // FirstUsed is left zero in keys
+	order    []string
+}
+
+// observe records a served request and reports whether the session and the
+// provider/model pair are new.
+func (t *snapshotTracker) observe(sessionID, provider, model string) (newSession, newModel bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessions == nil {
+		t.sessions = make(map[string]map[ServedModel]bool)
+	}
+	served, ok := t.sessions[sessionID]
+	if !ok {
+		if len(t.order) >= maxSnapshotSessions {
+			delete(t.sessions, t.order[0])
+			t.order = t.order[1:]
+		}
+		t.order = append(t.order, sessionID)
+		served = make(map[ServedModel]bool)
+		t.sessions[sessionID] = served
+		newSession = true
+	}
+	key := ServedModel{Provider: provider, Model: model}
+	if !served[key] {
+		served[key] = true
+		newModel = true
+	}
+	return newSession, newModel
+}
+
+// snapshot describes the proxy's current configuration for a session.
+func (s *ProxyServer) snapshot(sessionID string) SessionSnapshot {
+	snap := SessionSnapshot{
+		SessionID: sessionID,
+		StartedAt: time.Now(),
+		Profile:   s.Profile,
+		CLI:       s.CLI,
+	}
+	providers, routing := s.chains()
+	for _, p := range providers {
+		snap.Providers = append(snap.Providers, ProviderSnapshot{
+			Name:           p.Name,
+			Type:           p.GetType(),
+			Model:          p.Model,
+			ReasoningModel: p.ReasoningModel,
+			HaikuModel:     p.HaikuModel,
+			OpusModel:      p.OpusModel,
+			SonnetModel:    p.SonnetModel,
+		})
+	}
+	if routing != nil && len(routing.ScenarioRoutes) > 0 {
+		snap.Routing = make(map[string]ScenarioSnapshot, len(routing.ScenarioRoutes))
+		for scenario, sp := range routing.ScenarioRoutes {
+			var names []string
+			for _, p := range sp.Providers {
+				names = append(names, p.Name)
+			}
+			snap.Routing[string(scenario)] = ScenarioSnapshot{Providers: names, Models: sp.Models}
+		}
+	}
+	return snap
+}
+
+// servedModel returns the model sent to p for req after overrides and mapping.
+func (s *ProxyServer) servedModel(req *parsedRequest, p *Provider, modelOverride string) string {
+	if modelOverride != "" {
+		return modelOverride
+	}
+	if req.model == "" {
+		return ""
+	}
+	return s.mapModel(req.model, req.data, p)
+}
+
+// recordSnapshot stores the session's snapshot on its first request and
+// each provider/model pair the first time it serves the session.
+func (s *ProxyServer) recordSnapshot(sessionID string, p *Provider, model string) {
+	if s.LogDB == nil || sessionID == "" {
+		return
+	}
+	newSession, newModel := s.snapshots.observe(sessionID, p.Name, model)
+	if newSession {
+		if err := s.LogDB.RecordSession(s.snapshot(sessionID)); err != nil {
+			s.Logger.Printf("[session] failed to record snapshot for %s: %v", sessionID, err)
+		}
+	}
+	if newModel {
+		if err := s.LogDB.RecordSessionModel(sessionID, p.Name, model, time.Now()); err != nil {
+			s.Logger.Printf("[session] failed to record model for %s: %v", sessionID, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// sseReadBufferSize is the initial read buffer for SSE relays. Events larger
+// than this are still relayed intact; the buffer only bounds a single read.
+const sseReadBufferSize = 32 * 1024
+
+// relaySSE copies a server-sent event stream to the client one complete event
+// at a time. Events are written to a buffered writer and flushed unless
+// another complete event is already buffered from upstream, so bursts of small
+// events go out in a single write while each event is still delivered as soon
+// as the next one isn't ready.
+// Writes block while the client is slow to read, which in turn stops reads
+// from upstream; a failed write ends the relay.
+//
+// onEvent, if non-nil, is called with each complete event (including its
+// trailing blank line) before it is forwarded.
+func relaySSE(w http.ResponseWriter, body io.Reader, onEvent func(event []byte)) error {
+	return relayEvents(w, body, func(event []byte) []byte {
+		if onEvent != nil {
+			onEvent(event)
+		}
+		return event
+	})
+}
+
+// relayEvents is relaySSE with each event passed through filter, which
+// returns the bytes to forward in its place; nil drops the event.
+func relayEvents(w http.ResponseWriter, body io.Reader, filter func(event []byte) []byte) error {
+	br := bufio.NewReaderSize(body, sseReadBufferSize)
+	bw := bufio.NewWriterSize(w, sseReadBufferSize)
+	flusher, canFlush := w.(http.Flusher)
+
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	var event []byte
+	for {
+		line, readErr := br.ReadBytes('\n')
+		event = append(event, line...)
+
+		// A blank line terminates an event.
+		if readErr == nil && isBlankSSELine(line) {
+			if _, err := bw.Write(filter(event)); err != nil {
+				return err
+			}
+			event = event[:0]
+			if !hasBufferedEvent(br) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr != nil {
+			// Forward whatever is left, even an unterminated event.
+			if len(event) > 0 {
+				if _, err := bw.Write(filter(event)); err != nil {
+					return err
+				}
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if readErr == io.EOF {
+				return nil
+			}
+			return readErr
+		}
+	}
+}
+
+// hasBufferedEvent reports whether br holds a complete event, so the events
+// written so far can wait to go out with it.
+func hasBufferedEvent(br *bufio.Reader) bool {
+	buffered, _ := br.Peek(br.Buffered())
+	return bytes.Contains(buffered, []byte("\n\n")) || bytes.Contains(buffered, []byte("\n\r\n"))
+}
+
+func isBlankSSELine(line []byte) bool {
+	return len(bytes.TrimRight(line, "\r\n")) == 0
+}
+
+// sseEventData returns the concatenated data fields of an SSE event.
+func sseEventData(event []byte) []byte {
+	var data []byte
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		if len(data) > 0 {
+			data = append(data, '\n')
+		}
+		data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+	}
+	return data
+}
+
+// sseUsageTracker accumulates token usage from Anthropic streaming events:
+// message_start carries input usage, message_delta carries output usage.
+type sseUsageTracker struct {
+	inputTokens  int
+	outputTokens int
+}
+
+func (t *sseUsageTracker) observe(event []byte) {
+	data := sseEventData(event)
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+
+	var ev struct {
+		Type    string `json:"type"`
+		Message struct {
+			Usage *sseUsage `json:"usage"`
+		} `json:"message"`
+		Usage *sseUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return
+	}
+
+	usage := ev.Usage
+	if ev.Type == "message_start" {
+		usage = ev.Message.Usage
+	}
+	if usage == nil {
+		return
+	}
+	if usage.InputTokens > 0 {
+		t.inputTokens = usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		t.outputTokens = usage.OutputTokens
+	}
+}
+
+type sseUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
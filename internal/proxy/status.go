@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// StatusPath is the admin endpoint that reports the running proxy's state
+// (GET): its providers' health, the routes and request counters.
+const StatusPath = "/_opencc/status"
+
+// ProxyStatus is the state reported at StatusPath.
+type ProxyStatus struct {
+	Profile   string                          `json:"profile,omitempty"`
+	CLI       string                          `json:"cli,omitempty"`
+	Strategy  config.Strategy                 `json:"strategy,omitempty"`
+	Default   []string                        `json:"default"`
+	Routes    map[config.Scenario]RouteStatus `json:"routes,omitempty"`
+	Providers []ProviderStatus                `json:"providers"`
+}
+
+// RouteStatus is a scenario route in a ProxyStatus.
+type RouteStatus struct {
+	Providers            []string          `json:"providers"`
+	Models               map[string]string `json:"models,omitempty"`
+	LongContextThreshold int               `json:"long_context_threshold,omitempty"`
+}
+
+// ProviderStatus is a provider's state in a ProxyStatus. Requests counts the
+// attempts sent to it by response status ("error" if none arrived).
+type ProviderStatus struct {
+	Name        string            `json:"name"`
+	Healthy     bool              `json:"healthy"`
+	AuthFailed  bool              `json:"auth_failed,omitempty"`
+	BackoffMs   int64             `json:"backoff_ms,omitempty"`
+	RetryAt     *time.Time        `json:"retry_at,omitempty"`
+	Unavailable string            `json:"unavailable,omitempty"`
+	LatencyMs   int64             `json:"latency_ms,omitempty"`
+	RateLimit   *RateLimit        `json:"rate_limit,omitempty"`
+	Requests    map[string]uint64 `json:"requests,omitempty"`
+	Failovers   uint64            `json:"failovers,omitempty"`
+}
+
+// Status returns the proxy's current state.
+func (s *ProxyServer) Status(now time.Time) ProxyStatus {
+	providers, routing := s.chains()
+	st := ProxyStatus{
+		Profile:  s.Profile,
+		CLI:      s.CLI,
+		Strategy: s.Strategy,
+		Default:  providerNames(providers),
+	}
+	if routing != nil && len(routing.ScenarioRoutes) > 0 {
+		st.Routes = make(map[config.Scenario]RouteStatus, len(routing.ScenarioRoutes))
+		for scenario, sp := range routing.ScenarioRoutes {
+			st.Routes[scenario] = RouteStatus{
+				Providers:            providerNames(sp.Providers),
+				Models:               sp.Models,
+				LongContextThreshold: sp.LongContextThreshold,
+			}
+		}
+	}
+
+	for _, p := range s.allProviders() {
+		ps := ProviderStatus{
+			Name:        p.Name,
+			BackoffMs:   p.CurrentBackoff().Milliseconds(),
+			Unavailable: p.unavailableReason(),
+			LatencyMs:   p.Latency().Milliseconds(),
+			RateLimit:   p.RateLimit(),
+		}
+		retryAt := p.RetryAt()
+		ps.Healthy = retryAt.IsZero() || !now.Before(retryAt)
+		if !ps.Healthy {
+			ps.RetryAt = &retryAt
+		}
+		p.mu.Lock()
+		ps.AuthFailed = p.AuthFailed
+		p.mu.Unlock()
+		st.Providers = append(st.Providers, ps)
+	}
+
+	m := &s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range st.Providers {
+		ps := &st.Providers[i]
+		for k, n := range m.attempts {
+			if k.provider == ps.Name {
+				if ps.Requests == nil {
+					ps.Requests = make(map[string]uint64)
+				}
+				ps.Requests[k.status] = n
+			}
+		}
+		ps.Failovers = m.failovers[ps.Name]
+	}
+	return st
+}
+
+// serveStatus serves the status admin endpoint.
+func (s *ProxyServer) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Status(time.Now()))
+}
+
+// ProviderHealth is a provider's health as a proxy last recorded it. It is
+// shared through the LogDB so the web server and other processes can show it.
+type ProviderHealth struct {
+	Healthy    bool       `json:"healthy"`
+	AuthFailed bool       `json:"auth_failed,omitempty"`
+	BackoffMs  int64      `json:"backoff_ms,omitempty"`
+	FailedAt   *time.Time `json:"failed_at,omitempty"`
+	RetryAt    *time.Time `json:"retry_at,omitempty"`
+	ObservedAt time.Time  `json:"observed_at"`
+}
+
+// Health returns p's current health.
+func (p *Provider) Health(now time.Time) ProviderHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := ProviderHealth{
+		Healthy:    p.Healthy,
+		AuthFailed: p.AuthFailed,
+		BackoffMs:  p.Backoff.Milliseconds(),
+		ObservedAt: now,
+	}
+	if !p.FailedAt.IsZero() {
+		failedAt := p.FailedAt
+		h.FailedAt = &failedAt
+	}
+	if !p.Healthy {
+		retryAt := p.FailedAt.Add(p.Backoff)
+		h.RetryAt = &retryAt
+	}
+	return h
+}
+
+// HealthyAt reports whether the provider accepts requests at now: it was
+// healthy, or its backoff has expired since.
+func (h ProviderHealth) HealthyAt(now time.Time) bool {
+	return h.Healthy || h.RetryAt == nil || !now.Before(*h.RetryAt)
+}
+
+// recordHealth stores p's health in the LogDB after it changed.
+func (s *ProxyServer) recordHealth(p *Provider) {
+	if s.LogDB == nil {
+		return
+	}
+	if err := s.LogDB.RecordProviderHealth(p.Name, p.Health(time.Now())); err != nil {
+		s.Logger.Printf("[%s] failed to record health: %v", p.Name, err)
+	}
+}
@@ -0,0 +1,298 @@
+package proxy
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// defaultExpectedOutputTokens is assumed for cost estimates when a request
+// doesn't set max_tokens.
+const defaultExpectedOutputTokens = 4096
+
+// requestNeeds summarizes what a request requires from a provider.
+type requestNeeds struct {
+	thinking     bool
+	vision       bool
+	tools        bool // request declares tools
+	inputTokens  int
+	outputTokens int
+	maxTokens    int // max_tokens as sent; 0 if unset
+}
+
+// needsOf derives a request's requirements from its parsed body.
+func needsOf(body map[string]interface{}) requestNeeds {
+	if body == nil {
+		return requestNeeds{outputTokens: defaultExpectedOutputTokens}
+	}
+	n := requestNeeds{
+		thinking:     hasThinkingEnabled(body),
+		vision:       hasImageContent(body),
+		outputTokens: defaultExpectedOutputTokens,
+	}
+	if tools, ok := body["tools"].([]interface{}); ok && len(tools) > 0 {
+		n.tools = true
+	}
+	if maxTokens, ok := body["max_tokens"].(float64); ok && maxTokens > 0 {
+		n.outputTokens = int(maxTokens)
+		n.maxTokens = int(maxTokens)
+	}
+	n.inputTokens = calculateTokenCount(body)
+	return n
+}
+
+// satisfies reports whether the provider's declared capabilities cover the
+// request. Providers without capability metadata are assumed capable.
+func (p *Provider) satisfies(n requestNeeds) bool {
+	return p.missingCapability(n) == ""
+}
+
+// missingCapability names the first capability the request needs that the
+// provider declares it lacks, or "" if none.
+func (p *Provider) missingCapability(n requestNeeds) string {
+	c := p.Capabilities
+	if c == nil {
+		return ""
+	}
+	if n.thinking && c.SupportsThinking != nil && !*c.SupportsThinking {
+		return "no thinking support"
+	}
+	if n.vision && c.SupportsVision != nil && !*c.SupportsVision {
+		return "no vision support"
+	}
+	if n.tools && c.SupportsTools != nil && !*c.SupportsTools {
+		return "no tool support"
+	}
+	if c.ContextWindow > 0 && n.inputTokens > c.ContextWindow {
+		return fmt.Sprintf("input of %d tokens exceeds context window %d", n.inputTokens, c.ContextWindow)
+	}
+	if c.MaxOutputTokens > 0 && n.maxTokens > c.MaxOutputTokens {
+		return fmt.Sprintf("max_tokens %d exceeds limit %d", n.maxTokens, c.MaxOutputTokens)
+	}
+	return ""
+}
+
+// capabilityGate returns the request's needs if the chain should be filtered
+// by capability, or nil. Chains without capability metadata aren't gated,
+// and neither are chains where no provider qualifies: the request is then
+// forwarded as configured rather than rejected outright.
+func capabilityGate(providers []*Provider, req *parsedRequest) *requestNeeds {
+	if req == nil || req.data == nil {
+		return nil
+	}
+	declared := false
+	for _, p := range providers {
+		if p.Capabilities != nil {
+			declared = true
+			break
+		}
+	}
+	if !declared {
+		return nil
+	}
+	n := needsOf(req.data)
+	for _, p := range providers {
+		if p.satisfies(n) {
+			return &n
+		}
+	}
+	return nil
+}
+
+// estimatedCost returns the request's expected cost in USD on this provider,
+// and false if the provider has no pricing configured.
+func (p *Provider) estimatedCost(n requestNeeds) (float64, bool) {
+	if p.Pricing == nil {
+		return 0, false
+	}
+	cost := float64(n.inputTokens)*p.Pricing.InputPerMTok + float64(n.outputTokens)*p.Pricing.OutputPerMTok
+	return cost / 1e6, true
+}
+
+// orderByCost returns the capable providers sorted from cheapest to most
+// expensive, followed by capable providers without pricing in their original
+// order. If no provider is capable the original chain is returned unchanged,
+// so the request still gets a chance rather than failing outright.
+func orderByCost(providers []*Provider, n requestNeeds) []*Provider {
+	type candidate struct {
+		p      *Provider
+		cost   float64
+		priced bool
+	}
+	var candidates []candidate
+	for _, p := range providers {
+		if !p.satisfies(n) {
+			continue
+		}
+		cost, priced := p.estimatedCost(n)
+		candidates = append(candidates, candidate{p, cost, priced})
+	}
+	if len(candidates) == 0 {
+		return providers
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.priced != b.priced {
+			return a.priced
+		}
+		return a.priced && a.cost < b.cost
+	})
+
+	ordered := make([]*Provider, len(candidates))
+	for i, c := range candidates {
+		ordered[i] = c.p
+	}
+	return ordered
+}
+
+// rotate returns the chain starting at providers[start], wrapping around to
+// the providers before it.
+func rotate(providers []*Provider, start int) []*Provider {
+	if start == 0 {
+		return providers
+	}
+	rotated := make([]*Provider, 0, len(providers))
+	rotated = append(rotated, providers[start:]...)
+	return append(rotated, providers[:start]...)
+}
+
+// weightedStart returns the index of the provider that starts the chain on
+// the given turn. Each provider starts a run of turns as long as its weight,
+// so over a full cycle requests are shared in proportion to the weights.
+// Providers with weight 0 only serve as fallbacks.
+func weightedStart(providers []*Provider, weights map[string]int, turn uint64) int {
+	weight := func(p *Provider) uint64 {
+		w, ok := weights[p.Name]
+		if !ok {
+			return 1
+		}
+		return uint64(max(w, 0))
+	}
+	var total uint64
+	for _, p := range providers {
+		total += weight(p)
+	}
+	if total == 0 {
+		return 0
+	}
+	slot := turn % total
+	for i, p := range providers {
+		w := weight(p)
+		if slot < w {
+			return i
+		}
+		slot -= w
+	}
+	return 0
+}
+
+// orderByLatency returns the chain sorted by smoothed latency, fastest
+// first. Providers without a measurement keep their configured order ahead
+// of the rest, so each gets measured before the chain settles.
+func orderByLatency(providers []*Provider) []*Provider {
+	ordered := make([]*Provider, len(providers))
+	copy(ordered, providers)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i].Latency(), ordered[j].Latency()
+		if a == 0 || b == 0 {
+			return a == 0 && b != 0
+		}
+		return a < b
+	})
+	return ordered
+}
+
+// applySchedule moves the providers named by the first schedule rule open at
+// now to the front of the chain, in the rule's order; the strategy then
+// orders the chain from there.
+func (s *ProxyServer) applySchedule(providers []*Provider, now time.Time) []*Provider {
+	for _, rule := range s.Schedule {
+		if !rule.Window().Active(now) {
+			continue
+		}
+		ordered := make([]*Provider, 0, len(providers))
+		for _, name := range rule.Providers {
+			i := slices.IndexFunc(providers, func(p *Provider) bool { return p.Name == name })
+			if i >= 0 && !slices.Contains(ordered, providers[i]) {
+				ordered = append(ordered, providers[i])
+			}
+		}
+		for _, p := range providers {
+			if !slices.Contains(ordered, p) {
+				ordered = append(ordered, p)
+			}
+		}
+		names := make([]string, len(ordered))
+		for i, p := range ordered {
+			names[i] = p.Name
+		}
+		s.Logger.Printf("[schedule] window %s-%s open, order: %v", rule.Start, rule.End, names)
+		return ordered
+	}
+	return providers
+}
+
+// applyStrategy orders a provider chain according to the server's strategy.
+func (s *ProxyServer) applyStrategy(providers []*Provider, req *parsedRequest) []*Provider {
+	if len(providers) < 2 {
+		return providers
+	}
+	var ordered []*Provider
+	switch s.Strategy {
+	case config.StrategyCheapest:
+		ordered = orderByCost(providers, needsOf(req.data))
+	case config.StrategyRoundRobin:
+		ordered = rotate(providers, int(s.nextTurn()%uint64(len(providers))))
+	case config.StrategyWeighted:
+		ordered = rotate(providers, weightedStart(providers, s.Weights, s.nextTurn()))
+	case config.StrategyLeastLatency:
+		ordered = orderByLatency(providers)
+	default:
+		return providers
+	}
+	names := make([]string, len(ordered))
+	for i, p := range ordered {
+		names[i] = p.Name
+	}
+	s.Logger.Printf("[strategy] %s order: %v", s.Strategy, names)
+	return ordered
+}
+
+// applyAffinity moves the provider that last served the session to the
+// front of the chain, so the session keeps its prompt cache. The affinity
+// only breaks when that provider fails or is skipped and another one serves
+// the session instead.
+func (s *ProxyServer) applyAffinity(providers []*Provider, sessionID string) []*Provider {
+	if !s.SessionAffinity || sessionID == "" {
+		return providers
+	}
+	sp, ok := s.continuity.get(sessionID)
+	if !ok {
+		return providers
+	}
+	for i, p := range providers {
+		if p.Name != sp.provider {
+			continue
+		}
+		if i == 0 {
+			return providers
+		}
+		ordered := make([]*Provider, 0, len(providers))
+		ordered = append(ordered, p)
+		ordered = append(ordered, providers[:i]...)
+		ordered = append(ordered, providers[i+1:]...)
+		s.Logger.Printf("[affinity] session %s stays on %s", sessionID, p.Name)
+		return ordered
+	}
+	return providers
+}
+
+// nextTurn returns the number of requests ordered by the round-robin or
+// weighted strategy before this one.
+func (s *ProxyServer) nextTurn() uint64 {
+	return s.turn.Add(1) - 1
+}
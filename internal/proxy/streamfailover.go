@@ -0,0 +1,268 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// streamState follows an Anthropic event stream as the client sees it, so a
+// stream its provider cut off can be resumed on the next provider. The
+// resumed stream is rewritten to continue the first one: its message_start
+// is dropped and its content block indexes are shifted past the blocks the
+// client already has.
+type streamState struct {
+	mode config.StreamFailoverMode
+
+	sent       bool            // response headers are sent to the client
+	relayed    bool            // an event was relayed
+	msgStarted bool            // message_start delivered
+	done       bool            // message_stop or an error event delivered
+	blocks     int             // content blocks started
+	open       bool            // the last block started has not been stopped
+	openType   string          // type of the open block
+	openText   strings.Builder // text delivered in the open block
+	thinking   bool            // a thinking block was delivered
+
+	// How the stream currently relayed maps onto the client's stream.
+	offset    int            // added to upstream block indexes
+	skipFirst bool           // upstream block 0 continues the open block
+	req       *parsedRequest // request resuming the stream; nil before a cut
+}
+
+// newStreamState returns the state for tracking a streaming request, or nil
+// if stream failover is off or doesn't apply to the request.
+func (s *ProxyServer) newStreamState(req *parsedRequest) *streamState {
+	mode := s.StreamFailover
+	if mode != config.StreamFailoverRestart && mode != config.StreamFailoverReplay {
+		return nil
+	}
+	if s.ClientFormat != "" && s.ClientFormat != config.ProviderTypeAnthropic {
+		return nil
+	}
+	if stream, _ := req.data["stream"].(bool); !stream {
+		return nil
+	}
+	return &streamState{mode: mode}
+}
+
+// resuming reports whether the client already has part of the stream, so
+// later attempts must continue it rather than answer from scratch.
+func (st *streamState) resuming() bool {
+	return st != nil && st.sent
+}
+
+// cutOff reports whether the relayed stream ended before its final event.
+// Streams that never looked like Anthropic events are not resumed.
+func (st *streamState) cutOff() bool {
+	return st != nil && st.sent && !st.done && (st.msgStarted || !st.relayed)
+}
+
+// request returns the body to send on the next attempt.
+func (st *streamState) request(req *parsedRequest) *parsedRequest {
+	if st != nil && st.req != nil {
+		return st.req
+	}
+	return req
+}
+
+// streamEvent holds the fields of an Anthropic stream event used to follow
+// and rewrite the stream.
+type streamEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+	} `json:"content_block"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+}
+
+// relay rewrites one upstream event for the client and records what it
+// delivers. It returns nil to drop the event.
+func (st *streamState) relay(event []byte) []byte {
+	// An unterminated event at the end of a cut stream is useless to the
+	// client and would corrupt the resumed stream that follows it.
+	if !bytes.HasSuffix(bytes.ReplaceAll(event, []byte("\r"), nil), []byte("\n\n")) {
+		return nil
+	}
+	st.relayed = true
+	data := sseEventData(event)
+	var ev streamEvent
+	if len(data) == 0 || data[0] != '{' || json.Unmarshal(data, &ev) != nil {
+		return event
+	}
+
+	switch ev.Type {
+	case "message_start":
+		if st.msgStarted {
+			return nil
+		}
+		st.msgStarted = true
+	case "content_block_start":
+		if st.skipFirst && ev.Index == 0 {
+			return nil
+		}
+		index := ev.Index + st.offset
+		st.blocks = max(st.blocks, index+1)
+		st.open = true
+		st.openType = ev.ContentBlock.Type
+		st.openText.Reset()
+		if ev.ContentBlock.Type == "thinking" || ev.ContentBlock.Type == "redacted_thinking" {
+			st.thinking = true
+		}
+		return st.reindex(ev.Type, data, index)
+	case "content_block_delta":
+		index := ev.Index + st.offset
+		if ev.Delta.Type == "text_delta" && index == st.blocks-1 {
+			st.openText.WriteString(ev.Delta.Text)
+		}
+		return st.reindex(ev.Type, data, index)
+	case "content_block_stop":
+		index := ev.Index + st.offset
+		if index == st.blocks-1 {
+			st.open = false
+		}
+		return st.reindex(ev.Type, data, index)
+	case "message_stop", "error":
+		st.done = true
+	}
+	return event
+}
+
+// reindex returns the event with its block index set to index, unchanged if
+// it already has that index.
+func (st *streamState) reindex(eventType string, data []byte, index int) []byte {
+	start, end, ok := topLevelValueSpan(data, "index")
+	if !ok {
+		return sseEvent(eventType, data)
+	}
+	var out []byte
+	out = append(out, data[:start]...)
+	out = strconv.AppendInt(out, int64(index), 10)
+	out = append(out, data[end:]...)
+	return sseEvent(eventType, out)
+}
+
+// canResume reports whether the stream can be continued by another
+// provider: a cut inside a text block or between blocks can, but one inside
+// a tool_use or thinking block would leave the client with a truncated tool
+// input or thinking block.
+func (st *streamState) canResume() bool {
+	return !st.open || st.openType == "text"
+}
+
+// resume prepares the stream to be continued by another provider and
+// returns how it will be resumed; canResume must hold. In restart mode, or
+// when the text can't be replayed, the open block is closed and the next
+// provider's blocks follow it. In replay mode the streamed text is sent as
+// an assistant prefill and the next provider's text continues the open block.
+func (st *streamState) resume(w http.ResponseWriter, req *parsedRequest) config.StreamFailoverMode {
+	if st.mode == config.StreamFailoverReplay {
+		if prefilled := st.replayRequest(req); prefilled != nil {
+			st.req = prefilled
+			st.offset = st.blocks - 1
+			st.skipFirst = true
+			return config.StreamFailoverReplay
+		}
+	}
+
+	if st.open {
+		st.write(w, sseEvent("content_block_stop", fmt.Appendf(nil, `{"type":"content_block_stop","index":%d}`, st.blocks-1)))
+		st.open = false
+	}
+	st.req = nil
+	st.offset = st.blocks
+	st.skipFirst = false
+	return config.StreamFailoverRestart
+}
+
+// replayRequest returns req with the open block's text appended as an
+// assistant prefill, or nil if the stream can't be continued that way.
+// Prefills can't follow thinking blocks, and a request that already ends
+// with an assistant turn would need the two merged.
+func (st *streamState) replayRequest(req *parsedRequest) *parsedRequest {
+	if !st.open || st.openType != "text" || st.thinking || req.data == nil {
+		return nil
+	}
+	// The API rejects prefills that end in whitespace.
+	text := strings.TrimRight(st.openText.String(), " \t\r\n")
+	if text == "" {
+		return nil
+	}
+	messages, _ := req.data["messages"].([]interface{})
+	if len(messages) == 0 {
+		return nil
+	}
+	if last, ok := messages[len(messages)-1].(map[string]interface{}); !ok || last["role"] != "user" {
+		return nil
+	}
+
+	data := make(map[string]interface{}, len(req.data))
+	for k, v := range req.data {
+		data[k] = v
+	}
+	resumed := make([]interface{}, len(messages), len(messages)+1)
+	copy(resumed, messages)
+	data["messages"] = append(resumed, map[string]interface{}{"role": "assistant", "content": text})
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return parseRequest(body)
+}
+
+// abort ends the client's stream with an error event, the way the API
+// reports errors once a stream is under way.
+func (st *streamState) abort(w http.ResponseWriter, message string) {
+	var e anthropicErrorBody
+	e.Type = "error"
+	e.Error.Type = errTypeAPI
+	e.Error.Message = message
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	st.write(w, sseEvent("error", data))
+	st.done = true
+}
+
+// write sends an event to the client straight away.
+func (st *streamState) write(w http.ResponseWriter, event []byte) {
+	w.Write(event)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// sseEvent formats a server-sent event.
+func sseEvent(eventType string, data []byte) []byte {
+	out := make([]byte, 0, len(eventType)+len(data)+16)
+	out = append(out, "event: "...)
+	out = append(out, eventType...)
+	out = append(out, "\ndata: "...)
+	out = append(out, data...)
+	return append(out, "\n\n"...)
+}
+
+// stopAttempts is stopForContext for requests whose stream may already be
+// under way; such streams end with an error event instead of a 504.
+func (s *ProxyServer) stopAttempts(w http.ResponseWriter, r *http.Request, stream *streamState) bool {
+	if !stream.resuming() {
+		return s.stopForContext(w, r)
+	}
+	err := r.Context().Err()
+	if err == nil {
+		return false
+	}
+	s.Logger.Printf("[request] %s %s: stream not resumed: %v", r.Method, r.URL.Path, err)
+	stream.abort(w, fmt.Sprintf("stream interrupted and not resumed: %v", err))
+	return true
+}
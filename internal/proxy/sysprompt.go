@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"encoding/json"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// applySystemPrompt puts the server's and p's system prompts before the
+// system prompt of body, in the client's format. Bodies without a
+// conversation, such as those of the Files API, are returned as they are.
+func (s *ProxyServer) applySystemPrompt(body []byte, p *Provider) []byte {
+	prompt := joinPrompts(s.SystemPrompt, p.SystemPrompt)
+	if prompt == "" {
+		return body
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	if !injectSystemPrompt(data, prompt, s.ClientFormat) {
+		return body
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	s.Logger.Printf("[%s] system prompt injected (%d chars)", p.Name, len(prompt))
+	return out
+}
+
+// injectSystemPrompt puts prompt before the system prompt of a request body
+// in the given format and reports whether it did: the "system" field of
+// Anthropic messages, the "instructions" of OpenAI Responses requests, or a
+// leading system message of OpenAI chat completions.
+func injectSystemPrompt(data map[string]interface{}, prompt, format string) bool {
+	if format == config.ProviderTypeOpenAI {
+		if _, ok := data["input"]; ok {
+			data["instructions"] = joinPrompts(prompt, stringField(data, "instructions"))
+			return true
+		}
+		messages, ok := data["messages"].([]interface{})
+		if !ok {
+			return false
+		}
+		if len(messages) > 0 {
+			if first, ok := messages[0].(map[string]interface{}); ok && first["role"] == "system" {
+				if content, ok := first["content"].(string); ok {
+					first["content"] = joinPrompts(prompt, content)
+					return true
+				}
+			}
+		}
+		system := map[string]interface{}{"role": "system", "content": prompt}
+		data["messages"] = append([]interface{}{system}, messages...)
+		return true
+	}
+
+	if _, ok := data["messages"]; !ok {
+		return false
+	}
+	switch system := data["system"].(type) {
+	case []interface{}:
+		block := map[string]interface{}{"type": "text", "text": prompt}
+		data["system"] = append([]interface{}{block}, system...)
+	case string:
+		data["system"] = joinPrompts(prompt, system)
+	default:
+		data["system"] = prompt
+	}
+	return true
+}
+
+// joinPrompts joins the non-empty prompts, separated by a blank line.
+func joinPrompts(first, second string) string {
+	switch {
+	case first == "":
+		return second
+	case second == "":
+		return first
+	}
+	return first + "\n\n" + second
+}
+
+// stringField returns the string value of key in data, or "".
+func stringField(data map[string]interface{}, key string) string {
+	v, _ := data[key].(string)
+	return v
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// Self-signed certificate files kept in the directory given to
+// EnsureSelfSignedCert.
+const (
+	SelfSignedCertFile = "proxy.crt"
+	SelfSignedKeyFile  = "proxy.key"
+)
+
+// selfSignedValidity is how long a generated certificate is valid.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// LoadTLSConfig returns the server TLS settings for a PEM certificate and key.
+func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// ServerTLS returns the server TLS settings for tc and the certificate file
+// they serve, using a self-signed certificate under ~/.opencc/tls without a
+// configured one. tc must be valid.
+func ServerTLS(tc *config.ProxyTLSConfig) (*tls.Config, string, error) {
+	certFile, keyFile := tc.CertFile, tc.KeyFile
+	if certFile == "" {
+		var err error
+		certFile, keyFile, err = EnsureSelfSignedCert(filepath.Join(config.ConfigDirPath(), "tls"))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create a self-signed certificate: %w", err)
+		}
+	}
+	cfg, err := LoadTLSConfig(certFile, keyFile)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, certFile, nil
+}
+
+// EnsureSelfSignedCert returns the paths of a self-signed certificate for
+// localhost in dir, generating one if there is none, it expires within a day
+// or it lacks the name constraints below.
+func EnsureSelfSignedCert(dir string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, SelfSignedCertFile)
+	keyFile = filepath.Join(dir, SelfSignedKeyFile)
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Until(leaf.NotAfter) > 24*time.Hour &&
+			leaf.MaxPathLenZero && len(leaf.PermittedDNSDomains) > 0 {
+			return certFile, keyFile, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "opencc proxy"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(selfSignedValidity),
+		// The certificate is its own CA, so clients can trust it directly.
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, err := os.Hostname(); err == nil && host != "" && host != "localhost" {
+		tmpl.DNSNames = append(tmpl.DNSNames, host)
+	}
+	// CLIs trust the certificate as a root, so it may only vouch for its own
+	// names: no intermediates, and name constraints limited to its SANs, so
+	// its key can't mint a trusted certificate for any other host.
+	tmpl.MaxPathLenZero = true
+	tmpl.PermittedDNSDomainsCritical = true
+	tmpl.PermittedDNSDomains = tmpl.DNSNames
+	for _, ip := range tmpl.IPAddresses {
+		bits := 8 * len(ip)
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		tmpl.PermittedIPRanges = append(tmpl.PermittedIPRanges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// ClientTLSConfig returns client TLS settings that trust the system roots
+// and the certificate in certFile, for connecting to a proxy that uses it.
+func ClientTLSConfig(certFile string) (*tls.Config, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate in %s", certFile)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
@@ -1,16 +1,36 @@
 package proxy
 
 import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pkoukk/tiktoken-go"
 )
 
+// countTokensPath is the Anthropic token counting endpoint. Requests to it
+// are answered with a local estimate when a provider doesn't implement it.
+const countTokensPath = "/v1/messages/count_tokens"
+
+// Tokenizer counts the tokens in a piece of text. Scenario routing
+// thresholds, capability checks and cost estimates count request tokens
+// through the package's tokenizer.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
 var (
 	// Global tiktoken encoder for cl100k_base (used by Claude models)
 	tokenEncoder     *tiktoken.Tiktoken
 	tokenEncoderOnce sync.Once
 	tokenEncoderErr  error
+
+	// customTokenizer replaces the default tokenizer when set.
+	customTokenizer atomic.Pointer[Tokenizer]
 )
 
 // getTokenEncoder returns the global tiktoken encoder instance.
@@ -22,57 +42,119 @@ func getTokenEncoder() (*tiktoken.Tiktoken, error) {
 	return tokenEncoder, tokenEncoderErr
 }
 
-// calculateTokenCount calculates the total token count for a request body.
-// It counts tokens in messages, system prompt, and tools.
-func calculateTokenCount(body map[string]interface{}) (int, error) {
+// bpeTokenizer counts tokens with a tiktoken BPE encoding.
+type bpeTokenizer struct {
+	enc *tiktoken.Tiktoken
+}
+
+func (t bpeTokenizer) CountTokens(text string) int {
+	return len(t.enc.Encode(text, nil, nil))
+}
+
+// charTokenizer estimates tokens from the text length, for when no BPE
+// encoding can be loaded (e.g. offline, with no cached encoding file).
+// 1 token is about 4 characters of English and 1.5 of Chinese; 3 keeps the
+// estimate conservative.
+type charTokenizer struct{}
+
+func (charTokenizer) CountTokens(text string) int {
+	return (len(text) + 2) / 3
+}
+
+// SetTokenizer replaces the tokenizer used for request token counts; nil
+// restores the default cl100k_base encoding. Cached session counts are
+// dropped, since they were made with the previous tokenizer.
+func SetTokenizer(t Tokenizer) {
+	if t == nil {
+		customTokenizer.Store(nil)
+	} else {
+		customTokenizer.Store(&t)
+	}
+	globalTokenCounts.reset()
+}
+
+// currentTokenizer returns the tokenizer set with SetTokenizer, else the
+// cl100k_base encoding, else the character estimate if the encoding can't
+// be loaded.
+func currentTokenizer() Tokenizer {
+	if t := customTokenizer.Load(); t != nil {
+		return *t
+	}
 	enc, err := getTokenEncoder()
 	if err != nil {
-		// Fallback to character-based estimation if tiktoken fails
-		return estimateTokensFromChars(body), nil
+		return charTokenizer{}
 	}
+	return bpeTokenizer{enc}
+}
 
+// calculateTokenCount calculates the total token count for a request body.
+// It counts tokens in messages, system prompt, and tools.
+func calculateTokenCount(body map[string]interface{}) int {
+	tok := currentTokenizer()
 	totalTokens := 0
 
 	// Count tokens in messages
 	if messages, ok := body["messages"].([]interface{}); ok {
 		for _, msg := range messages {
-			m, ok := msg.(map[string]interface{})
+			totalTokens += messageTokens(tok, msg)
+		}
+	}
+
+	return totalTokens + extraTokens(tok, body)
+}
+
+// EstimateTokens returns the input tokens of a request body as scenario
+// routing counts them.
+func EstimateTokens(body map[string]interface{}) int {
+	return calculateTokenCount(body)
+}
+
+// writeTokenEstimate answers a token counting request with the locally
+// estimated input tokens of body, in the Anthropic response format.
+func writeTokenEstimate(w http.ResponseWriter, body map[string]interface{}) int {
+	n := calculateTokenCount(body)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"input_tokens": n})
+	return n
+}
+
+// messageTokens counts the tokens in a single message's content.
+func messageTokens(tok Tokenizer, msg interface{}) int {
+	m, ok := msg.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	totalTokens := 0
+	switch content := m["content"].(type) {
+	case string:
+		totalTokens += tok.CountTokens(content)
+	case []interface{}:
+		for _, block := range content {
+			b, ok := block.(map[string]interface{})
 			if !ok {
 				continue
 			}
-
-			// Count content tokens
-			switch content := m["content"].(type) {
-			case string:
-				totalTokens += len(enc.Encode(content, nil, nil))
-			case []interface{}:
-				for _, block := range content {
-					b, ok := block.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					blockType, _ := b["type"].(string)
-					switch blockType {
-					case "text":
-						if text, ok := b["text"].(string); ok {
-							totalTokens += len(enc.Encode(text, nil, nil))
-						}
-					case "tool_use":
-						// Count tool use input as JSON string
-						if input, ok := b["input"]; ok {
-							totalTokens += estimateJSONTokens(enc, input)
-						}
-					case "tool_result":
-						// Count tool result content
-						if resultContent, ok := b["content"].(string); ok {
-							totalTokens += len(enc.Encode(resultContent, nil, nil))
-						} else if resultContent, ok := b["content"].([]interface{}); ok {
-							for _, rc := range resultContent {
-								if rcMap, ok := rc.(map[string]interface{}); ok {
-									if text, ok := rcMap["text"].(string); ok {
-										totalTokens += len(enc.Encode(text, nil, nil))
-									}
-								}
+			blockType, _ := b["type"].(string)
+			switch blockType {
+			case "text":
+				if text, ok := b["text"].(string); ok {
+					totalTokens += tok.CountTokens(text)
+				}
+			case "tool_use":
+				// Count tool use input as JSON string
+				if input, ok := b["input"]; ok {
+					totalTokens += estimateJSONTokens(tok, input)
+				}
+			case "tool_result":
+				// Count tool result content
+				if resultContent, ok := b["content"].(string); ok {
+					totalTokens += tok.CountTokens(resultContent)
+				} else if resultContent, ok := b["content"].([]interface{}); ok {
+					for _, rc := range resultContent {
+						if rcMap, ok := rc.(map[string]interface{}); ok {
+							if text, ok := rcMap["text"].(string); ok {
+								totalTokens += tok.CountTokens(text)
 							}
 						}
 					}
@@ -80,17 +162,23 @@ func calculateTokenCount(body map[string]interface{}) (int, error) {
 			}
 		}
 	}
+	return totalTokens
+}
+
+// extraTokens counts the tokens outside the messages: system prompt and tools.
+func extraTokens(tok Tokenizer, body map[string]interface{}) int {
+	totalTokens := 0
 
 	// Count tokens in system prompt
 	switch system := body["system"].(type) {
 	case string:
-		totalTokens += len(enc.Encode(system, nil, nil))
+		totalTokens += tok.CountTokens(system)
 	case []interface{}:
 		for _, item := range system {
 			if itemMap, ok := item.(map[string]interface{}); ok {
 				if itemType, ok := itemMap["type"].(string); ok && itemType == "text" {
 					if text, ok := itemMap["text"].(string); ok {
-						totalTokens += len(enc.Encode(text, nil, nil))
+						totalTokens += tok.CountTokens(text)
 					}
 				}
 			}
@@ -106,40 +194,185 @@ func calculateTokenCount(body map[string]interface{}) (int, error) {
 			}
 			// Count tool name and description
 			if name, ok := t["name"].(string); ok {
-				totalTokens += len(enc.Encode(name, nil, nil))
+				totalTokens += tok.CountTokens(name)
 			}
 			if desc, ok := t["description"].(string); ok {
-				totalTokens += len(enc.Encode(desc, nil, nil))
+				totalTokens += tok.CountTokens(desc)
 			}
 			// Count input schema (approximate)
 			if schema, ok := t["input_schema"]; ok {
-				totalTokens += estimateJSONTokens(enc, schema)
+				totalTokens += estimateJSONTokens(tok, schema)
 			}
 		}
 	}
 
-	return totalTokens, nil
+	return totalTokens
+}
+
+// sessionTokenCounts caches the token count of a session's conversation so
+// the next turn only has to tokenize the messages appended since.
+type sessionTokenCounts struct {
+	messages      int    // number of messages counted
+	messageTokens int    // tokens in those messages
+	first, last   uint64 // fingerprints of the first and last counted message
+	extras        uint64 // fingerprint of system prompt and tools
+	extraTokens   int
+}
+
+// tokenCountCache holds sessionTokenCounts per session with FIFO eviction.
+type tokenCountCache struct {
+	mu       sync.Mutex
+	sessions map[string]sessionTokenCounts
+	order    []string
+}
+
+var globalTokenCounts = &tokenCountCache{}
+
+func (c *tokenCountCache) get(sessionID string) (sessionTokenCounts, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts, ok := c.sessions[sessionID]
+	return counts, ok
+}
+
+func (c *tokenCountCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions = nil
+	c.order = nil
+}
+
+func (c *tokenCountCache) put(sessionID string, counts sessionTokenCounts) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions == nil {
+		c.sessions = make(map[string]sessionTokenCounts)
+	}
+	if _, exists := c.sessions[sessionID]; !exists {
+		if len(c.order) >= defaultMaxCacheSize {
+			delete(c.sessions, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, sessionID)
+	}
+	c.sessions[sessionID] = counts
+}
+
+// sessionTokenCount returns the same count as calculateTokenCount, reusing
+// the previous turn's count when the conversation only grew. Claude Code
+// resends the full history every turn, so this keeps tokenization
+// proportional to the new content. The cached prefix is trusted when its
+// first and last messages are unchanged; compaction or /clear rewrites the
+// history and forces a full count.
+func sessionTokenCount(body map[string]interface{}, sessionID string) int {
+	if sessionID == "" {
+		return calculateTokenCount(body)
+	}
+	tok := currentTokenizer()
+
+	messages, _ := body["messages"].([]interface{})
+	prev, ok := globalTokenCounts.get(sessionID)
+
+	var counts sessionTokenCounts
+	if ok && prev.messages > 0 && prev.messages <= len(messages) &&
+		fingerprint(messages[0]) == prev.first &&
+		fingerprint(messages[prev.messages-1]) == prev.last {
+		counts.messages = prev.messages
+		counts.messageTokens = prev.messageTokens
+	}
+	for _, msg := range messages[counts.messages:] {
+		counts.messageTokens += messageTokens(tok, msg)
+	}
+	counts.messages = len(messages)
+	if len(messages) > 0 {
+		counts.first = fingerprint(messages[0])
+		counts.last = fingerprint(messages[len(messages)-1])
+	}
+
+	counts.extras = fingerprint([]interface{}{body["system"], body["tools"]})
+	if ok && prev.extras == counts.extras {
+		counts.extraTokens = prev.extraTokens
+	} else {
+		counts.extraTokens = extraTokens(tok, body)
+	}
+
+	globalTokenCounts.put(sessionID, counts)
+	return counts.messageTokens + counts.extraTokens
+}
+
+// fingerprint hashes a decoded JSON value. cache_control markers are ignored
+// because clients move them to the newest message every turn.
+func fingerprint(v interface{}) uint64 {
+	h := fnv.New64a()
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				if k != "cache_control" {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			h.Write([]byte{'{'})
+			for _, k := range keys {
+				h.Write([]byte(k))
+				h.Write([]byte{':'})
+				walk(v[k])
+			}
+			h.Write([]byte{'}'})
+		case []interface{}:
+			h.Write([]byte{'['})
+			for _, item := range v {
+				walk(item)
+				h.Write([]byte{','})
+			}
+			h.Write([]byte{']'})
+		case string:
+			h.Write([]byte{'"'})
+			h.Write([]byte(v))
+			h.Write([]byte{'"'})
+		case float64:
+			var b [8]byte
+			bits := math.Float64bits(v)
+			for i := range b {
+				b[i] = byte(bits >> (8 * i))
+			}
+			h.Write(b[:])
+		case bool:
+			if v {
+				h.Write([]byte{'t'})
+			} else {
+				h.Write([]byte{'f'})
+			}
+		default:
+			h.Write([]byte{'n'})
+		}
+	}
+	walk(v)
+	return h.Sum64()
 }
 
 // estimateJSONTokens estimates token count for a JSON object by encoding it as string.
-func estimateJSONTokens(enc *tiktoken.Tiktoken, obj interface{}) int {
+func estimateJSONTokens(tok Tokenizer, obj interface{}) int {
 	// Simple approximation: convert to string and count
 	// This is not perfect but good enough for schema/input objects
 	switch v := obj.(type) {
 	case string:
-		return len(enc.Encode(v, nil, nil))
+		return tok.CountTokens(v)
 	case map[string]interface{}:
 		total := 2 // {} brackets
 		for key, val := range v {
-			total += len(enc.Encode(key, nil, nil))
-			total += estimateJSONTokens(enc, val)
+			total += tok.CountTokens(key)
+			total += estimateJSONTokens(tok, val)
 			total += 2 // : and ,
 		}
 		return total
 	case []interface{}:
 		total := 2 // [] brackets
 		for _, item := range v {
-			total += estimateJSONTokens(enc, item)
+			total += estimateJSONTokens(tok, item)
 			total += 1 // ,
 		}
 		return total
@@ -148,52 +381,3 @@ func estimateJSONTokens(enc *tiktoken.Tiktoken, obj interface{}) int {
 		return 5
 	}
 }
-
-// estimateTokensFromChars provides a fallback character-based estimation.
-// This is used when tiktoken is unavailable.
-func estimateTokensFromChars(body map[string]interface{}) int {
-	totalChars := 0
-
-	// Count characters in messages
-	if messages, ok := body["messages"].([]interface{}); ok {
-		for _, msg := range messages {
-			m, ok := msg.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			switch content := m["content"].(type) {
-			case string:
-				totalChars += len(content)
-			case []interface{}:
-				for _, block := range content {
-					b, ok := block.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					if text, ok := b["text"].(string); ok {
-						totalChars += len(text)
-					}
-				}
-			}
-		}
-	}
-
-	// Count characters in system prompt
-	if system, ok := body["system"].(string); ok {
-		totalChars += len(system)
-	} else if systemBlocks, ok := body["system"].([]interface{}); ok {
-		for _, block := range systemBlocks {
-			b, ok := block.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			if text, ok := b["text"].(string); ok {
-				totalChars += len(text)
-			}
-		}
-	}
-
-	// Rough estimation: 1 token ≈ 4 characters for English, 1.5 for Chinese
-	// Use conservative estimate of 3 characters per token
-	return totalChars / 3
-}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader carries a request's correlation ID. The proxy returns it on
+// every response and tags the request's log entries with it; a client may
+// supply its own. It is not forwarded upstream.
+const RequestIDHeader = "X-OpenCC-Request-Id"
+
+// Messages of the log entries that only exist to build request traces.
+const (
+	routedMessage = "routed"
+	usageMessage  = "usage"
+)
+
+// Trace outcomes.
+const (
+	TraceServed  = "served"
+	TraceFailed  = "failed"
+	TracePending = "pending" // no outcome logged (yet)
+)
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// requestIDFor returns the client-supplied request ID if it is usable, or a
+// new one.
+func requestIDFor(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID.MatchString(id) {
+		return id
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "req_" + hex.EncodeToString(b)
+}
+
+// withRequestID returns r carrying the request ID for logging.
+func withRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// requestIDOf returns the request ID carried by r, or "".
+func requestIDOf(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// responseUsage extracts token usage from a non-streaming response body in
+// Anthropic or OpenAI format.
+func responseUsage(body []byte) (inputTokens, outputTokens int) {
+	var resp struct {
+		Usage struct {
+			InputTokens      int `json:"input_tokens"`
+			OutputTokens     int `json:"output_tokens"`
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return 0, 0
+	}
+	u := resp.Usage
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		return u.PromptTokens, u.CompletionTokens
+	}
+	return u.InputTokens, u.OutputTokens
+}
+
+// TraceAttempt is one provider considered for a request.
+type TraceAttempt struct {
+	Time      time.Time `json:"time"`
+	Provider  string    `json:"provider"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMs int64     `json:"latency_ms,omitempty"`
+	Skipped   bool      `json:"skipped,omitempty"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// RequestTrace is the lifecycle of one request, assembled from its log entries.
+type RequestTrace struct {
+	RequestID    string         `json:"request_id"`
+	Started      time.Time      `json:"started"`
+	Finished     time.Time      `json:"finished"`
+	Method       string         `json:"method,omitempty"`
+	Path         string         `json:"path,omitempty"`
+	Client       string         `json:"client,omitempty"`
+	Scenario     string         `json:"scenario,omitempty"` // only detected when the profile routes scenarios
+	Outcome      string         `json:"outcome"`
+	Attempts     []TraceAttempt `json:"attempts"`
+	Provider     string         `json:"provider,omitempty"` // provider that served the request
+	Model        string         `json:"model,omitempty"`    // model sent to that provider
+	InputTokens  int            `json:"input_tokens"`
+	OutputTokens int            `json:"output_tokens"`
+	Entries      int            `json:"entries"` // log entries the trace was built from
+}
+
+// BuildRequestTrace assembles the trace of a request from its log entries,
+// in any order. It returns nil if there are none.
+func BuildRequestTrace(requestID string, entries []LogEntry) *RequestTrace {
+	if len(entries) == 0 {
+		return nil
+	}
+	sorted := append([]LogEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	t := &RequestTrace{
+		RequestID: requestID,
+		Started:   sorted[0].Timestamp,
+		Finished:  sorted[len(sorted)-1].Timestamp,
+		Outcome:   TracePending,
+		Attempts:  []TraceAttempt{},
+		Entries:   len(sorted),
+	}
+	for _, e := range sorted {
+		if t.Method == "" {
+			t.Method, t.Path, t.Client = e.Method, e.Path, e.Client
+		}
+		switch {
+		case e.Message == routedMessage:
+			t.Scenario = e.Scenario
+		case e.Message == usageMessage:
+			t.InputTokens += e.InputTokens
+			t.OutputTokens += e.OutputTokens
+		case e.Provider == "":
+			// The summary logged once every provider has failed
+			if e.Level == LogLevelError {
+				t.Outcome = TraceFailed
+			}
+		default:
+			t.Attempts = append(t.Attempts, TraceAttempt{
+				Time:      e.Timestamp,
+				Provider:  e.Provider,
+				Status:    e.StatusCode,
+				LatencyMs: e.LatencyMs,
+				Skipped:   strings.HasPrefix(e.Message, "skipping"),
+				Message:   e.Message,
+				Error:     e.Error,
+			})
+			if e.Level == LogLevelInfo && e.StatusCode >= 200 && e.StatusCode < 400 {
+				t.Outcome = TraceServed
+				t.Provider, t.Model = e.Provider, e.Model
+			} else if e.Level == LogLevelError && t.Outcome != TraceServed {
+				t.Outcome = TraceFailed
+			}
+		}
+	}
+	return t
+}
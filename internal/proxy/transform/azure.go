@@ -0,0 +1,16 @@
+package transform
+
+import (
+	"net/url"
+	"strings"
+)
+
+// AzurePath returns the Azure OpenAI Chat Completions path of a deployment
+// for a Messages or Chat Completions request path; other paths are
+// unchanged.
+func AzurePath(path, deployment string) string {
+	if strings.HasSuffix(path, "/messages") || strings.HasSuffix(path, "/chat/completions") {
+		return "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions"
+	}
+	return path
+}
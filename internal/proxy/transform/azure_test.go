@@ -0,0 +1,20 @@
+package transform
+
+import (
+	"testing"
+)
+
+func TestAzurePath(t *testing.T) {
+	tests := []struct {
+		path, deployment, want string
+	}{
+		{"/v1/messages", "gpt-4o", "/openai/deployments/gpt-4o/chat/completions"},
+		{"/v1/chat/completions", "prod 4o", "/openai/deployments/prod%204o/chat/completions"},
+		{"/v1/messages/count_tokens", "gpt-4o", "/v1/messages/count_tokens"},
+	}
+	for _, tt := range tests {
+		if got := AzurePath(tt.path, tt.deployment); got != tt.want {
+			t.Errorf("AzurePath(%q, %q) = %q, want %q", tt.path, tt.deployment, got, tt.want)
+		}
+	}
+}
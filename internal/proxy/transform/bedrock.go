@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bedrockAnthropicVersion is the Messages API version Bedrock expects in the
+// request body.
+const bedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// BedrockTransformer handles Anthropic models on Amazon Bedrock, which take
+// Messages API bodies with the model and streaming mode in the URL instead.
+type BedrockTransformer struct{}
+
+func (t *BedrockTransformer) Name() string {
+	return "bedrock"
+}
+
+// TransformRequest converts a request into a Bedrock InvokeModel body.
+func (t *BedrockTransformer) TransformRequest(body []byte, clientFormat string) ([]byte, error) {
+	body, err := (&AnthropicTransformer{}).TransformRequest(body, clientFormat)
+	if err != nil {
+		return body, err
+	}
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+	delete(data, "model")
+	delete(data, "stream")
+	if _, ok := data["anthropic_version"]; !ok {
+		data["anthropic_version"] = bedrockAnthropicVersion
+	}
+	return toJSON(data)
+}
+
+// TransformResponse converts a Bedrock response, which is an Anthropic
+// Messages response, for the client.
+func (t *BedrockTransformer) TransformResponse(body []byte, clientFormat string) ([]byte, error) {
+	return (&AnthropicTransformer{}).TransformResponse(body, clientFormat)
+}
+
+// BedrockPath returns the InvokeModel path for a Messages request body: the
+// streaming variant if the request streams.
+func BedrockPath(body []byte, region string) string {
+	data, err := parseJSON(body)
+	if err != nil {
+		return "/"
+	}
+	model, _ := data["model"].(string)
+	action := "invoke"
+	if streaming, _ := data["stream"].(bool); streaming {
+		action = "invoke-with-response-stream"
+	}
+	return "/model/" + bedrockEscape(BedrockModelID(model, region)) + "/" + action
+}
+
+// bedrockEscape escapes a model ID for a URL path the way the AWS SDKs do,
+// including the colon in its version.
+func bedrockEscape(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// modelAliases maps Anthropic model aliases to the dated versions Bedrock and
+// Vertex AI name their models by.
+var modelAliases = map[string]string{
+	"claude-opus-4-5":          "claude-opus-4-5-20251101",
+	"claude-opus-4-1":          "claude-opus-4-1-20250805",
+	"claude-opus-4-0":          "claude-opus-4-20250514",
+	"claude-opus-4":            "claude-opus-4-20250514",
+	"claude-sonnet-4-5":        "claude-sonnet-4-5-20250929",
+	"claude-sonnet-4-0":        "claude-sonnet-4-20250514",
+	"claude-sonnet-4":          "claude-sonnet-4-20250514",
+	"claude-haiku-4-5":         "claude-haiku-4-5-20251001",
+	"claude-3-7-sonnet-latest": "claude-3-7-sonnet-20250219",
+	"claude-3-5-sonnet-latest": "claude-3-5-sonnet-20241022",
+	"claude-3-5-haiku-latest":  "claude-3-5-haiku-20241022",
+}
+
+// bedrockVersions holds the models whose Bedrock version isn't v1:0.
+var bedrockVersions = map[string]string{
+	"claude-3-5-sonnet-20241022": "v2:0",
+}
+
+// BedrockModelID returns the Bedrock model ID for an Anthropic model name,
+// as the cross-region inference profile of the region's geography (us, eu,
+// apac) where there is one. Bedrock model IDs and ARNs are returned as is.
+func BedrockModelID(model, region string) string {
+	if strings.Contains(model, "anthropic.") || strings.HasPrefix(model, "arn:") {
+		return model
+	}
+	if dated, ok := modelAliases[model]; ok {
+		model = dated
+	}
+	version := "v1:0"
+	if v, ok := bedrockVersions[model]; ok {
+		version = v
+	}
+	id := "anthropic." + model + "-" + version
+	for _, geo := range []struct{ prefix, profile string }{
+		{"us-gov-", "us-gov"},
+		{"us-", "us"},
+		{"eu-", "eu"},
+		{"ap-", "apac"},
+	} {
+		if strings.HasPrefix(region, geo.prefix) {
+			return geo.profile + "." + id
+		}
+	}
+	return id
+}
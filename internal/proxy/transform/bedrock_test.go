@@ -0,0 +1,75 @@
+package transform
+
+import (
+	"testing"
+)
+
+func TestBedrockModelID(t *testing.T) {
+	tests := []struct {
+		model, region, want string
+	}{
+		{"claude-sonnet-4-5", "us-east-1", "us.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"claude-sonnet-4-5-20250929", "eu-west-1", "eu.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"claude-haiku-4-5", "ap-northeast-1", "apac.anthropic.claude-haiku-4-5-20251001-v1:0"},
+		{"claude-opus-4-1", "us-gov-west-1", "us-gov.anthropic.claude-opus-4-1-20250805-v1:0"},
+		{"claude-3-5-sonnet-latest", "us-west-2", "us.anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"claude-sonnet-4", "ca-central-1", "anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"global.anthropic.claude-sonnet-4-5-20250929-v1:0", "us-east-1", "global.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc", "us-east-1", "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc"},
+	}
+	for _, tt := range tests {
+		if got := BedrockModelID(tt.model, tt.region); got != tt.want {
+			t.Errorf("BedrockModelID(%q, %q) = %q, want %q", tt.model, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestBedrockPath(t *testing.T) {
+	tests := map[string]string{
+		`{"model":"claude-sonnet-4-5"}`:                                             "/model/us.anthropic.claude-sonnet-4-5-20250929-v1%3A0/invoke",
+		`{"model":"claude-sonnet-4-5","stream":true}`:                               "/model/us.anthropic.claude-sonnet-4-5-20250929-v1%3A0/invoke-with-response-stream",
+		`{"model":"arn:aws:bedrock:us-east-1:1:application-inference-profile/abc"}`: "/model/arn%3Aaws%3Abedrock%3Aus-east-1%3A1%3Aapplication-inference-profile%2Fabc/invoke",
+	}
+	for body, want := range tests {
+		if got := BedrockPath([]byte(body), "us-east-1"); got != want {
+			t.Errorf("BedrockPath(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestBedrockTransformer_TransformRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientFormat string
+		input        string
+		want         string
+	}{
+		{
+			name:         "anthropic",
+			clientFormat: "anthropic",
+			input:        `{"model":"claude-sonnet-4-5","stream":true,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+			want:         `{"anthropic_version":"bedrock-2023-05-31","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:         "version kept",
+			clientFormat: "anthropic",
+			input:        `{"model":"claude-sonnet-4-5","anthropic_version":"bedrock-2024-01-01","messages":[]}`,
+			want:         `{"anthropic_version":"bedrock-2024-01-01","messages":[]}`,
+		},
+		{
+			name:         "chat completions",
+			clientFormat: "openai",
+			input:        `{"model":"claude-sonnet-4-5","max_completion_tokens":100,"stop":["END"],"messages":[{"role":"user","content":"hi"}]}`,
+			want:         `{"anthropic_version":"bedrock-2023-05-31","max_tokens":100,"stop_sequences":["END"],"messages":[{"role":"user","content":"hi"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&BedrockTransformer{}).TransformRequest([]byte(tt.input), tt.clientFormat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
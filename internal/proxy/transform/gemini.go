@@ -0,0 +1,579 @@
+package transform
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GeminiTransformer handles the Google Gemini generateContent API format.
+// Only Anthropic Messages clients are supported.
+type GeminiTransformer struct{}
+
+func (t *GeminiTransformer) Name() string {
+	return "gemini"
+}
+
+// GeminiPath returns the Gemini API path and query for an Anthropic Messages
+// request body: generateContent for the request's model, or
+// streamGenerateContent with server-sent events when it streams.
+func GeminiPath(body []byte) (path, query string) {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(body, &req)
+	path = "/v1beta/models/" + url.PathEscape(req.Model)
+	if req.Stream {
+		return path + ":streamGenerateContent", "alt=sse"
+	}
+	return path + ":generateContent", ""
+}
+
+// TransformRequest converts an Anthropic Messages request into a Gemini
+// generateContent request. The model and stream flag are not part of the
+// body; see GeminiPath.
+func (t *GeminiTransformer) TransformRequest(body []byte, clientFormat string) ([]byte, error) {
+	if clientFormat == "gemini" {
+		return body, nil
+	}
+	if clientFormat != "" && clientFormat != "anthropic" {
+		return nil, fmt.Errorf("gemini providers can't serve %s clients", clientFormat)
+	}
+
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+
+	gemini := map[string]interface{}{}
+	if system := geminiSystemInstruction(data["system"]); system != nil {
+		gemini["systemInstruction"] = system
+	}
+	messages, _ := data["messages"].([]interface{})
+	gemini["contents"] = geminiContents(messages)
+	if tools := geminiTools(data["tools"]); tools != nil {
+		gemini["tools"] = tools
+	}
+	if toolConfig := geminiToolConfig(data["tool_choice"]); toolConfig != nil {
+		gemini["toolConfig"] = toolConfig
+	}
+	if config := geminiGenerationConfig(data); len(config) > 0 {
+		gemini["generationConfig"] = config
+	}
+	return toJSON(gemini)
+}
+
+// geminiSystemInstruction converts an Anthropic system prompt, a string or
+// text blocks.
+func geminiSystemInstruction(system interface{}) map[string]interface{} {
+	var parts []interface{}
+	switch s := system.(type) {
+	case string:
+		if s != "" {
+			parts = append(parts, map[string]interface{}{"text": s})
+		}
+	case []interface{}:
+		for _, item := range s {
+			if block, ok := item.(map[string]interface{}); ok && block["type"] == "text" {
+				if text, ok := block["text"].(string); ok && text != "" {
+					parts = append(parts, map[string]interface{}{"text": text})
+				}
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"parts": parts}
+}
+
+// geminiContents converts Anthropic messages into Gemini contents.
+func geminiContents(messages []interface{}) []interface{} {
+	toolNames := make(map[string]string) // tool_use ID → tool name, for function responses
+	contents := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		m, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role := "user"
+		if m["role"] == "assistant" {
+			role = "model"
+		}
+
+		var parts []interface{}
+		switch content := m["content"].(type) {
+		case string:
+			if content != "" {
+				parts = append(parts, map[string]interface{}{"text": content})
+			}
+		case []interface{}:
+			for _, block := range content {
+				if b, ok := block.(map[string]interface{}); ok {
+					if part := geminiPart(b, toolNames); part != nil {
+						parts = append(parts, part)
+					}
+				}
+			}
+		}
+		if len(parts) > 0 {
+			contents = append(contents, map[string]interface{}{"role": role, "parts": parts})
+		}
+	}
+	return contents
+}
+
+// geminiPart converts one Anthropic content block into a Gemini part, or
+// returns nil for blocks Gemini has no equivalent for (such as thinking).
+func geminiPart(block map[string]interface{}, toolNames map[string]string) map[string]interface{} {
+	switch block["type"] {
+	case "text":
+		if text, _ := block["text"].(string); text != "" {
+			return map[string]interface{}{"text": text}
+		}
+	case "image", "document":
+		source, _ := block["source"].(map[string]interface{})
+		switch source["type"] {
+		case "base64":
+			return map[string]interface{}{"inlineData": map[string]interface{}{
+				"mimeType": source["media_type"],
+				"data":     source["data"],
+			}}
+		case "url":
+			fileData := map[string]interface{}{"fileUri": source["url"]}
+			if mediaType, ok := source["media_type"]; ok {
+				fileData["mimeType"] = mediaType
+			}
+			return map[string]interface{}{"fileData": fileData}
+		}
+	case "tool_use":
+		name, _ := block["name"].(string)
+		if id, ok := block["id"].(string); ok {
+			toolNames[id] = name
+		}
+		args := block["input"]
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		return map[string]interface{}{"functionCall": map[string]interface{}{"name": name, "args": args}}
+	case "tool_result":
+		id, _ := block["tool_use_id"].(string)
+		key := "content"
+		if isError, _ := block["is_error"].(bool); isError {
+			key = "error"
+		}
+		return map[string]interface{}{"functionResponse": map[string]interface{}{
+			"name":     toolNames[id],
+			"response": map[string]interface{}{key: toolResultText(block["content"])},
+		}}
+	}
+	return nil
+}
+
+// toolResultText returns the text of a tool result's content, a string or
+// content blocks.
+func toolResultText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []interface{}:
+		var texts []string
+		for _, item := range c {
+			if block, ok := item.(map[string]interface{}); ok && block["type"] == "text" {
+				if text, ok := block["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// geminiTools converts Anthropic tool definitions into Gemini function
+// declarations. Server tools, which have no input schema, are dropped.
+func geminiTools(tools interface{}) []interface{} {
+	list, _ := tools.([]interface{})
+	var declarations []interface{}
+	for _, tool := range list {
+		t, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := t["name"].(string)
+		schema, hasSchema := t["input_schema"]
+		if name == "" || !hasSchema {
+			continue
+		}
+		declaration := map[string]interface{}{
+			"name":       name,
+			"parameters": geminiSchema(schema),
+		}
+		if description, ok := t["description"].(string); ok && description != "" {
+			declaration["description"] = description
+		}
+		declarations = append(declarations, declaration)
+	}
+	if len(declarations) == 0 {
+		return nil
+	}
+	return []interface{}{map[string]interface{}{"functionDeclarations": declarations}}
+}
+
+// geminiSchema copies a JSON schema without the keywords Gemini rejects.
+func geminiSchema(schema interface{}) interface{} {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(s))
+		for k, v := range s {
+			switch k {
+			case "$schema", "$id", "additionalProperties", "default", "examples":
+			case "properties":
+				props, _ := v.(map[string]interface{})
+				cleaned := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					cleaned[name] = geminiSchema(prop)
+				}
+				out[k] = cleaned
+			case "items", "anyOf":
+				out[k] = geminiSchema(v)
+			default:
+				out[k] = v
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(s))
+		for i, v := range s {
+			out[i] = geminiSchema(v)
+		}
+		return out
+	}
+	return schema
+}
+
+// geminiToolConfig converts an Anthropic tool_choice.
+func geminiToolConfig(choice interface{}) map[string]interface{} {
+	c, ok := choice.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	config := map[string]interface{}{}
+	switch c["type"] {
+	case "auto":
+		config["mode"] = "AUTO"
+	case "any":
+		config["mode"] = "ANY"
+	case "none":
+		config["mode"] = "NONE"
+	case "tool":
+		config["mode"] = "ANY"
+		config["allowedFunctionNames"] = []interface{}{c["name"]}
+	default:
+		return nil
+	}
+	return map[string]interface{}{"functionCallingConfig": config}
+}
+
+// geminiGenerationConfig collects the Anthropic sampling settings Gemini
+// supports.
+func geminiGenerationConfig(data map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{}
+	for from, to := range map[string]string{
+		"max_tokens":     "maxOutputTokens",
+		"temperature":    "temperature",
+		"top_p":          "topP",
+		"top_k":          "topK",
+		"stop_sequences": "stopSequences",
+	} {
+		if v, ok := data[from]; ok {
+			config[to] = v
+		}
+	}
+	if thinking, ok := data["thinking"].(map[string]interface{}); ok && thinking["type"] == "enabled" {
+		if budget, ok := thinking["budget_tokens"]; ok {
+			config["thinkingConfig"] = map[string]interface{}{"thinkingBudget": budget}
+		}
+	}
+	return config
+}
+
+// TransformResponse converts a Gemini generateContent response, or error,
+// into an Anthropic Messages response.
+func (t *GeminiTransformer) TransformResponse(body []byte, clientFormat string) ([]byte, error) {
+	if clientFormat == "gemini" {
+		return body, nil
+	}
+
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+	if e, ok := data["error"].(map[string]interface{}); ok {
+		return toJSON(anthropicError(e))
+	}
+
+	content := []interface{}{}
+	stopReason := "end_turn"
+	if candidate := firstCandidate(data); candidate != nil {
+		toolUse := false
+		for _, part := range candidateParts(candidate) {
+			block := anthropicBlock(part)
+			if block == nil {
+				continue
+			}
+			if block["type"] == "tool_use" {
+				toolUse = true
+			}
+			content = append(content, block)
+		}
+		finishReason, _ := candidate["finishReason"].(string)
+		stopReason = anthropicStopReason(finishReason, toolUse)
+	}
+
+	inputTokens, outputTokens := geminiUsage(data)
+	return toJSON(map[string]interface{}{
+		"id":            geminiMessageID(data),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         data["modelVersion"],
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage": map[string]interface{}{
+			"input_tokens":  inputTokens,
+			"output_tokens": outputTokens,
+		},
+	})
+}
+
+func firstCandidate(data map[string]interface{}) map[string]interface{} {
+	candidates, _ := data["candidates"].([]interface{})
+	if len(candidates) == 0 {
+		return nil
+	}
+	candidate, _ := candidates[0].(map[string]interface{})
+	return candidate
+}
+
+func candidateParts(candidate map[string]interface{}) []map[string]interface{} {
+	content, _ := candidate["content"].(map[string]interface{})
+	list, _ := content["parts"].([]interface{})
+	parts := make([]map[string]interface{}, 0, len(list))
+	for _, p := range list {
+		if part, ok := p.(map[string]interface{}); ok {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// anthropicBlock converts a Gemini part into an Anthropic content block, or
+// returns nil for parts that have none. Thought summaries are dropped: they
+// carry no signature an Anthropic client could send back.
+func anthropicBlock(part map[string]interface{}) map[string]interface{} {
+	if thought, _ := part["thought"].(bool); thought {
+		return nil
+	}
+	if text, ok := part["text"].(string); ok {
+		return map[string]interface{}{"type": "text", "text": text}
+	}
+	if call, ok := part["functionCall"].(map[string]interface{}); ok {
+		id, _ := call["id"].(string)
+		if id == "" {
+			id = newID("toolu_")
+		}
+		input := call["args"]
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		return map[string]interface{}{"type": "tool_use", "id": id, "name": call["name"], "input": input}
+	}
+	return nil
+}
+
+// anthropicStopReason maps a Gemini finish reason to an Anthropic stop
+// reason; toolUse reports whether the response calls a tool.
+func anthropicStopReason(finishReason string, toolUse bool) string {
+	switch finishReason {
+	case "MAX_TOKENS":
+		return "max_tokens"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "refusal"
+	}
+	if toolUse {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// geminiUsage returns the input and output tokens of a Gemini response.
+// Thinking tokens count as output.
+func geminiUsage(data map[string]interface{}) (input, output int) {
+	usage, _ := data["usageMetadata"].(map[string]interface{})
+	prompt, _ := usage["promptTokenCount"].(float64)
+	candidates, _ := usage["candidatesTokenCount"].(float64)
+	thoughts, _ := usage["thoughtsTokenCount"].(float64)
+	return int(prompt), int(candidates + thoughts)
+}
+
+func geminiMessageID(data map[string]interface{}) string {
+	if id, ok := data["responseId"].(string); ok && id != "" {
+		return "msg_" + id
+	}
+	return newID("msg_")
+}
+
+// anthropicError converts a Gemini error object into an Anthropic error
+// response.
+func anthropicError(e map[string]interface{}) map[string]interface{} {
+	errType := "api_error"
+	switch e["status"] {
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		errType = "invalid_request_error"
+	case "UNAUTHENTICATED":
+		errType = "authentication_error"
+	case "PERMISSION_DENIED":
+		errType = "permission_error"
+	case "NOT_FOUND":
+		errType = "not_found_error"
+	case "RESOURCE_EXHAUSTED":
+		errType = "rate_limit_error"
+	case "UNAVAILABLE":
+		errType = "overloaded_error"
+	}
+	return map[string]interface{}{
+		"type":  "error",
+		"error": map[string]interface{}{"type": errType, "message": e["message"]},
+	}
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// GeminiStream converts a Gemini streamGenerateContent event stream into an
+// Anthropic Messages event stream.
+type GeminiStream struct {
+	started bool
+	index   int    // index of the next content block
+	open    string // type of the open content block; empty if none
+	toolUse bool   // a tool_use block was sent
+}
+
+// TransformEvent returns the Anthropic events for one Gemini event. Events
+// without a JSON payload yield none.
+func (s *GeminiStream) TransformEvent(event []byte) [][]byte {
+	var chunk map[string]interface{}
+	if err := json.Unmarshal(eventData(event), &chunk); err != nil {
+		return nil
+	}
+
+	var out [][]byte
+	emit := func(payload map[string]interface{}) {
+		data, _ := json.Marshal(payload)
+		out = append(out, []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", payload["type"], data)))
+	}
+	if e, ok := chunk["error"].(map[string]interface{}); ok {
+		emit(anthropicError(e))
+		return out
+	}
+
+	inputTokens, outputTokens := geminiUsage(chunk)
+	if !s.started {
+		s.started = true
+		emit(map[string]interface{}{
+			"type": "message_start",
+			"message": map[string]interface{}{
+				"id":            geminiMessageID(chunk),
+				"type":          "message",
+				"role":          "assistant",
+				"model":         chunk["modelVersion"],
+				"content":       []interface{}{},
+				"stop_reason":   nil,
+				"stop_sequence": nil,
+				"usage":         map[string]interface{}{"input_tokens": inputTokens, "output_tokens": 0},
+			},
+		})
+	}
+
+	candidate := firstCandidate(chunk)
+	if candidate == nil {
+		return out
+	}
+	closeBlock := func() {
+		if s.open != "" {
+			emit(map[string]interface{}{"type": "content_block_stop", "index": s.index})
+			s.index++
+			s.open = ""
+		}
+	}
+	for _, part := range candidateParts(candidate) {
+		block := anthropicBlock(part)
+		if block == nil {
+			continue
+		}
+		switch block["type"] {
+		case "text":
+			if s.open != "text" {
+				closeBlock()
+				s.open = "text"
+				emit(map[string]interface{}{
+					"type":          "content_block_start",
+					"index":         s.index,
+					"content_block": map[string]interface{}{"type": "text", "text": ""},
+				})
+			}
+			emit(map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": s.index,
+				"delta": map[string]interface{}{"type": "text_delta", "text": block["text"]},
+			})
+		case "tool_use":
+			closeBlock()
+			s.open = "tool_use"
+			s.toolUse = true
+			input, _ := json.Marshal(block["input"])
+			emit(map[string]interface{}{
+				"type":          "content_block_start",
+				"index":         s.index,
+				"content_block": map[string]interface{}{"type": "tool_use", "id": block["id"], "name": block["name"], "input": map[string]interface{}{}},
+			})
+			emit(map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": s.index,
+				"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": string(input)},
+			})
+		}
+	}
+
+	if finishReason, ok := candidate["finishReason"].(string); ok && finishReason != "" {
+		closeBlock()
+		emit(map[string]interface{}{
+			"type":  "message_delta",
+			"delta": map[string]interface{}{"stop_reason": anthropicStopReason(finishReason, s.toolUse), "stop_sequence": nil},
+			"usage": map[string]interface{}{"output_tokens": outputTokens},
+		})
+		emit(map[string]interface{}{"type": "message_stop"})
+	}
+	return out
+}
+
+// eventData returns the concatenated data fields of an SSE event.
+func eventData(event []byte) []byte {
+	var data [][]byte
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if rest, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			data = append(data, bytes.TrimPrefix(rest, []byte(" ")))
+		}
+	}
+	return bytes.Join(data, []byte("\n"))
+}
@@ -0,0 +1,164 @@
+package transform
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGeminiPath(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantPath  string
+		wantQuery string
+	}{
+		{`{"model":"gemini-2.5-pro","messages":[]}`, "/v1beta/models/gemini-2.5-pro:generateContent", ""},
+		{`{"model":"gemini-2.5-flash","stream":true}`, "/v1beta/models/gemini-2.5-flash:streamGenerateContent", "alt=sse"},
+	}
+	for _, tt := range tests {
+		path, query := GeminiPath([]byte(tt.body))
+		if path != tt.wantPath || query != tt.wantQuery {
+			t.Errorf("GeminiPath(%s) = %q, %q, want %q, %q", tt.body, path, query, tt.wantPath, tt.wantQuery)
+		}
+	}
+}
+
+func TestGeminiTransformer_TransformRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "text and system",
+			input: `{"model":"m","max_tokens":1024,"temperature":0.5,"stop_sequences":["END"],"system":"be brief","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"hello"}]}]}`,
+			want:  `{"systemInstruction":{"parts":[{"text":"be brief"}]},"contents":[{"role":"user","parts":[{"text":"hi"}]},{"role":"model","parts":[{"text":"hello"}]}],"generationConfig":{"maxOutputTokens":1024,"temperature":0.5,"stopSequences":["END"]}}`,
+		},
+		{
+			name:  "images",
+			input: `{"model":"m","messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBO"}},{"type":"image","source":{"type":"url","url":"https://x/cat.jpg"}}]}]}`,
+			want:  `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"iVBO"}},{"fileData":{"fileUri":"https://x/cat.jpg"}}]}]}`,
+		},
+		{
+			name: "tools",
+			input: `{"model":"m","tool_choice":{"type":"tool","name":"Read"},
+				"tools":[{"name":"Read","description":"read a file","input_schema":{"$schema":"x","type":"object","additionalProperties":false,"properties":{"default":{"type":"string","default":"a"}}}},{"type":"web_search_20250305","name":"web_search"}],
+				"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"path":"a.go"}}]},
+					{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package a"}]}]},
+					{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","is_error":true,"content":"denied"}]}]}`,
+			want: `{"contents":[{"role":"model","parts":[{"functionCall":{"name":"Read","args":{"path":"a.go"}}}]},
+				{"role":"user","parts":[{"functionResponse":{"name":"Read","response":{"content":"package a"}}}]},
+				{"role":"user","parts":[{"functionResponse":{"name":"Read","response":{"error":"denied"}}}]}],
+				"tools":[{"functionDeclarations":[{"name":"Read","description":"read a file","parameters":{"type":"object","properties":{"default":{"type":"string"}}}}]}],
+				"toolConfig":{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["Read"]}}}`,
+		},
+		{
+			name:  "thinking budget",
+			input: `{"model":"m","thinking":{"type":"enabled","budget_tokens":2048},"messages":[]}`,
+			want:  `{"contents":[],"generationConfig":{"thinkingConfig":{"thinkingBudget":2048}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&GeminiTransformer{}).TransformRequest([]byte(tt.input), "anthropic")
+			if err != nil {
+				t.Fatalf("TransformRequest() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+
+	if _, err := (&GeminiTransformer{}).TransformRequest([]byte(`{}`), "openai"); err == nil {
+		t.Error("TransformRequest() from openai clients should fail")
+	}
+}
+
+func TestGeminiTransformer_TransformResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "text",
+			input: `{"responseId":"r1","modelVersion":"gemini-2.5-pro","candidates":[{"content":{"role":"model","parts":[{"text":"thinking...","thought":true},{"text":"hello"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":3,"thoughtsTokenCount":4}}`,
+			want:  `{"id":"msg_r1","type":"message","role":"assistant","model":"gemini-2.5-pro","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":7}}`,
+		},
+		{
+			name:  "tool call",
+			input: `{"responseId":"r2","candidates":[{"content":{"parts":[{"functionCall":{"id":"call_1","name":"Read","args":{"path":"a.go"}}}]},"finishReason":"STOP"}]}`,
+			want:  `{"id":"msg_r2","type":"message","role":"assistant","model":null,"content":[{"type":"tool_use","id":"call_1","name":"Read","input":{"path":"a.go"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}`,
+		},
+		{
+			name:  "max tokens",
+			input: `{"responseId":"r3","candidates":[{"content":{"parts":[{"text":"cut"}]},"finishReason":"MAX_TOKENS"}]}`,
+			want:  `{"id":"msg_r3","type":"message","role":"assistant","model":null,"content":[{"type":"text","text":"cut"}],"stop_reason":"max_tokens","stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}`,
+		},
+		{
+			name:  "error",
+			input: `{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`,
+			want:  `{"type":"error","error":{"type":"rate_limit_error","message":"quota exceeded"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&GeminiTransformer{}).TransformResponse([]byte(tt.input), "anthropic")
+			if err != nil {
+				t.Fatalf("TransformResponse() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestGeminiStream(t *testing.T) {
+	chunks := []string{
+		`data: {"responseId":"r1","modelVersion":"gemini-2.5-pro","candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"usageMetadata":{"promptTokenCount":12}}` + "\r\n\r\n",
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}]}` + "\r\n\r\n",
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"call_1","name":"Read","args":{"path":"a.go"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":9}}` + "\r\n\r\n",
+	}
+	want := []string{
+		`message_start {"message":{"content":[],"id":"msg_r1","model":"gemini-2.5-pro","role":"assistant","stop_reason":null,"stop_sequence":null,"type":"message","usage":{"input_tokens":12,"output_tokens":0}},"type":"message_start"}`,
+		`content_block_start {"content_block":{"text":"","type":"text"},"index":0,"type":"content_block_start"}`,
+		`content_block_delta {"delta":{"text":"Hel","type":"text_delta"},"index":0,"type":"content_block_delta"}`,
+		`content_block_delta {"delta":{"text":"lo","type":"text_delta"},"index":0,"type":"content_block_delta"}`,
+		`content_block_stop {"index":0,"type":"content_block_stop"}`,
+		`content_block_start {"content_block":{"id":"call_1","input":{},"name":"Read","type":"tool_use"},"index":1,"type":"content_block_start"}`,
+		`content_block_delta {"delta":{"partial_json":"{\"path\":\"a.go\"}","type":"input_json_delta"},"index":1,"type":"content_block_delta"}`,
+		`content_block_stop {"index":1,"type":"content_block_stop"}`,
+		`message_delta {"delta":{"stop_reason":"tool_use","stop_sequence":null},"type":"message_delta","usage":{"output_tokens":9}}`,
+		`message_stop {"type":"message_stop"}`,
+	}
+
+	s := GetStreamTransformer("gemini", "anthropic")
+	var got []string
+	for _, chunk := range chunks {
+		for _, event := range s.TransformEvent([]byte(chunk)) {
+			lines := strings.SplitN(strings.TrimSuffix(string(event), "\n\n"), "\n", 2)
+			got = append(got, strings.TrimPrefix(lines[0], "event: ")+" "+strings.TrimPrefix(lines[1], "data: "))
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if GetStreamTransformer("openai", "anthropic") != nil || GetStreamTransformer("anthropic", "anthropic") != nil {
+		t.Error("GetStreamTransformer() should be nil for providers without stream conversion")
+	}
+}
+
+// assertJSONEqual fails t if got and want are not the same JSON value.
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid want JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
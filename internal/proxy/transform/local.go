@@ -0,0 +1,19 @@
+package transform
+
+import (
+	"strings"
+)
+
+// LocalPath returns the Chat Completions path for a Messages or Chat
+// Completions request to a local OpenAI-compatible server whose base URL has
+// basePath; other paths are unchanged. Base URLs such as Ollama's
+// http://localhost:11434/v1 already include the API version.
+func LocalPath(basePath, path string) string {
+	if !strings.HasSuffix(path, "/messages") && !strings.HasSuffix(path, "/chat/completions") {
+		return path
+	}
+	if strings.HasSuffix(strings.TrimSuffix(basePath, "/"), "/v1") {
+		return "/chat/completions"
+	}
+	return "/v1/chat/completions"
+}
@@ -0,0 +1,21 @@
+package transform
+
+import (
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		basePath, path, want string
+	}{
+		{"/v1", "/v1/messages", "/chat/completions"},
+		{"/v1/", "/v1/chat/completions", "/chat/completions"},
+		{"", "/v1/messages", "/v1/chat/completions"},
+		{"/v1", "/v1/messages/count_tokens", "/v1/messages/count_tokens"},
+	}
+	for _, tt := range tests {
+		if got := LocalPath(tt.basePath, tt.path); got != tt.want {
+			t.Errorf("LocalPath(%q, %q) = %q, want %q", tt.basePath, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,626 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResponsesTransformer handles the OpenAI Responses API format, for both
+// Anthropic Messages and OpenAI Chat Completions clients.
+type ResponsesTransformer struct{}
+
+func (t *ResponsesTransformer) Name() string {
+	return "openai-responses"
+}
+
+// ResponsesPath returns the Responses API path for a Messages or Chat
+// Completions request path, keeping its prefix; other paths are unchanged.
+func ResponsesPath(path string) string {
+	for _, suffix := range []string{"/messages", "/chat/completions"} {
+		if prefix, ok := strings.CutSuffix(path, suffix); ok {
+			return prefix + "/responses"
+		}
+	}
+	return path
+}
+
+// TransformRequest converts an Anthropic Messages or Chat Completions request
+// into a Responses API request.
+func (t *ResponsesTransformer) TransformRequest(body []byte, clientFormat string) ([]byte, error) {
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+
+	out := map[string]interface{}{"store": false}
+	for _, key := range []string{"model", "stream", "temperature", "top_p"} {
+		if v, ok := data[key]; ok {
+			out[key] = v
+		}
+	}
+	for _, key := range []string{"max_tokens", "max_completion_tokens"} {
+		if v, ok := data[key]; ok {
+			out["max_output_tokens"] = v
+		}
+	}
+	messages, _ := data["messages"].([]interface{})
+
+	if clientFormat == "openai" {
+		out["input"] = chatInput(messages)
+		if tools := chatResponsesTools(data["tools"]); tools != nil {
+			out["tools"] = tools
+		}
+		switch choice := data["tool_choice"].(type) {
+		case string:
+			out["tool_choice"] = choice
+		case map[string]interface{}:
+			fn, _ := choice["function"].(map[string]interface{})
+			out["tool_choice"] = map[string]interface{}{"type": "function", "name": fn["name"]}
+		}
+		if effort, ok := data["reasoning_effort"]; ok {
+			out["reasoning"] = map[string]interface{}{"effort": effort}
+		}
+		return toJSON(out)
+	}
+
+	if instructions := systemText(data["system"]); instructions != "" {
+		out["instructions"] = instructions
+	}
+	out["input"] = anthropicInput(messages)
+	if tools := anthropicResponsesTools(data["tools"]); tools != nil {
+		out["tools"] = tools
+	}
+	if choice, ok := data["tool_choice"].(map[string]interface{}); ok {
+		switch choice["type"] {
+		case "auto", "none":
+			out["tool_choice"] = choice["type"]
+		case "any":
+			out["tool_choice"] = "required"
+		case "tool":
+			out["tool_choice"] = map[string]interface{}{"type": "function", "name": choice["name"]}
+		}
+	}
+	if thinking, ok := data["thinking"].(map[string]interface{}); ok && thinking["type"] == "enabled" {
+		out["reasoning"] = map[string]interface{}{"effort": reasoningEffort(thinking["budget_tokens"])}
+	}
+	return toJSON(out)
+}
+
+// systemText joins an Anthropic system prompt, a string or text blocks.
+func systemText(system interface{}) string {
+	if s, ok := system.(string); ok {
+		return s
+	}
+	return toolResultText(system)
+}
+
+// reasoningEffort maps an Anthropic thinking budget to a reasoning effort.
+func reasoningEffort(budget interface{}) string {
+	tokens, _ := budget.(float64)
+	switch {
+	case tokens < 4096:
+		return "low"
+	case tokens < 16384:
+		return "medium"
+	}
+	return "high"
+}
+
+// anthropicInput converts Anthropic messages into Responses input items.
+// Tool calls and results become items of their own between the messages.
+func anthropicInput(messages []interface{}) []interface{} {
+	input := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		m, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := m["role"].(string)
+		textType := "input_text"
+		if role == "assistant" {
+			textType = "output_text"
+		}
+
+		var content []interface{}
+		flush := func() {
+			if len(content) > 0 {
+				input = append(input, map[string]interface{}{"role": role, "content": content})
+				content = nil
+			}
+		}
+		blocks, _ := m["content"].([]interface{})
+		if text, ok := m["content"].(string); ok {
+			blocks = []interface{}{map[string]interface{}{"type": "text", "text": text}}
+		}
+		for _, block := range blocks {
+			b, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch b["type"] {
+			case "text":
+				if text, _ := b["text"].(string); text != "" {
+					content = append(content, map[string]interface{}{"type": textType, "text": text})
+				}
+			case "image":
+				source, _ := b["source"].(map[string]interface{})
+				imageURL := source["url"]
+				if source["type"] == "base64" {
+					imageURL = fmt.Sprintf("data:%v;base64,%v", source["media_type"], source["data"])
+				}
+				content = append(content, map[string]interface{}{"type": "input_image", "image_url": imageURL})
+			case "tool_use":
+				flush()
+				arguments, _ := json.Marshal(b["input"])
+				input = append(input, map[string]interface{}{
+					"type":      "function_call",
+					"call_id":   b["id"],
+					"name":      b["name"],
+					"arguments": string(arguments),
+				})
+			case "tool_result":
+				flush()
+				input = append(input, map[string]interface{}{
+					"type":    "function_call_output",
+					"call_id": b["tool_use_id"],
+					"output":  toolResultText(b["content"]),
+				})
+			}
+		}
+		flush()
+	}
+	return input
+}
+
+// chatInput converts Chat Completions messages into Responses input items.
+func chatInput(messages []interface{}) []interface{} {
+	input := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		m, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := m["role"].(string)
+		if role == "tool" {
+			input = append(input, map[string]interface{}{
+				"type":    "function_call_output",
+				"call_id": m["tool_call_id"],
+				"output":  chatText(m["content"]),
+			})
+			continue
+		}
+
+		switch content := m["content"].(type) {
+		case string:
+			if content != "" {
+				input = append(input, map[string]interface{}{"role": role, "content": content})
+			}
+		case []interface{}:
+			var parts []interface{}
+			for _, part := range content {
+				p, ok := part.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch p["type"] {
+				case "text":
+					textType := "input_text"
+					if role == "assistant" {
+						textType = "output_text"
+					}
+					parts = append(parts, map[string]interface{}{"type": textType, "text": p["text"]})
+				case "image_url":
+					image, _ := p["image_url"].(map[string]interface{})
+					parts = append(parts, map[string]interface{}{"type": "input_image", "image_url": image["url"]})
+				}
+			}
+			if len(parts) > 0 {
+				input = append(input, map[string]interface{}{"role": role, "content": parts})
+			}
+		}
+
+		calls, _ := m["tool_calls"].([]interface{})
+		for _, call := range calls {
+			c, ok := call.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fn, _ := c["function"].(map[string]interface{})
+			input = append(input, map[string]interface{}{
+				"type":      "function_call",
+				"call_id":   c["id"],
+				"name":      fn["name"],
+				"arguments": fn["arguments"],
+			})
+		}
+	}
+	return input
+}
+
+// chatText returns the text of Chat Completions message content, a string
+// or content parts.
+func chatText(content interface{}) string {
+	if s, ok := content.(string); ok {
+		return s
+	}
+	return toolResultText(content)
+}
+
+// anthropicResponsesTools converts Anthropic tool definitions into Responses
+// function tools. Server tools, which have no input schema, are dropped.
+func anthropicResponsesTools(tools interface{}) []interface{} {
+	list, _ := tools.([]interface{})
+	var out []interface{}
+	for _, tool := range list {
+		t, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schema, ok := t["input_schema"]
+		if !ok {
+			continue
+		}
+		out = append(out, map[string]interface{}{
+			"type":        "function",
+			"name":        t["name"],
+			"description": t["description"],
+			"parameters":  schema,
+		})
+	}
+	return out
+}
+
+// chatResponsesTools converts Chat Completions tools, which nest the
+// function definition, into Responses function tools.
+func chatResponsesTools(tools interface{}) []interface{} {
+	list, _ := tools.([]interface{})
+	var out []interface{}
+	for _, tool := range list {
+		t, ok := tool.(map[string]interface{})
+		if !ok || t["type"] != "function" {
+			continue
+		}
+		fn, _ := t["function"].(map[string]interface{})
+		converted := map[string]interface{}{"type": "function", "name": fn["name"], "parameters": fn["parameters"]}
+		if description, ok := fn["description"]; ok {
+			converted["description"] = description
+		}
+		out = append(out, converted)
+	}
+	return out
+}
+
+// responsesOutput is the content of a Responses API response.
+type responsesOutput struct {
+	text      string
+	calls     []interface{} // function_call items
+	truncated bool          // stopped at max_output_tokens
+}
+
+func parseResponsesOutput(data map[string]interface{}) responsesOutput {
+	var out responsesOutput
+	items, _ := data["output"].([]interface{})
+	for _, item := range items {
+		it, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch it["type"] {
+		case "message":
+			parts, _ := it["content"].([]interface{})
+			for _, part := range parts {
+				if p, ok := part.(map[string]interface{}); ok && p["type"] == "output_text" {
+					text, _ := p["text"].(string)
+					out.text += text
+				}
+			}
+		case "function_call":
+			out.calls = append(out.calls, it)
+		}
+	}
+	if details, ok := data["incomplete_details"].(map[string]interface{}); ok && details["reason"] == "max_output_tokens" {
+		out.truncated = true
+	}
+	return out
+}
+
+func responsesUsage(data map[string]interface{}) (input, output float64) {
+	usage, _ := data["usage"].(map[string]interface{})
+	input, _ = usage["input_tokens"].(float64)
+	output, _ = usage["output_tokens"].(float64)
+	return input, output
+}
+
+// TransformResponse converts a Responses API response into an Anthropic
+// Messages or Chat Completions response.
+func (t *ResponsesTransformer) TransformResponse(body []byte, clientFormat string) ([]byte, error) {
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+	if e, ok := data["error"].(map[string]interface{}); ok {
+		if clientFormat == "openai" {
+			return body, nil
+		}
+		return toJSON(responsesError(e))
+	}
+
+	out := parseResponsesOutput(data)
+	input, output := responsesUsage(data)
+	if clientFormat == "openai" {
+		message := map[string]interface{}{"role": "assistant", "content": nil}
+		if out.text != "" {
+			message["content"] = out.text
+		}
+		if len(out.calls) > 0 {
+			toolCalls := make([]interface{}, len(out.calls))
+			for i, call := range out.calls {
+				c := call.(map[string]interface{})
+				toolCalls[i] = map[string]interface{}{
+					"id":       c["call_id"],
+					"type":     "function",
+					"function": map[string]interface{}{"name": c["name"], "arguments": c["arguments"]},
+				}
+			}
+			message["tool_calls"] = toolCalls
+		}
+		return toJSON(map[string]interface{}{
+			"id":      data["id"],
+			"object":  "chat.completion",
+			"created": data["created_at"],
+			"model":   data["model"],
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"message":       message,
+				"finish_reason": chatFinishReason(out.truncated, len(out.calls) > 0),
+			}},
+			"usage": map[string]interface{}{
+				"prompt_tokens":     input,
+				"completion_tokens": output,
+				"total_tokens":      input + output,
+			},
+		})
+	}
+
+	content := []interface{}{}
+	if out.text != "" {
+		content = append(content, map[string]interface{}{"type": "text", "text": out.text})
+	}
+	for _, call := range out.calls {
+		c := call.(map[string]interface{})
+		content = append(content, map[string]interface{}{
+			"type":  "tool_use",
+			"id":    c["call_id"],
+			"name":  c["name"],
+			"input": callInput(c["arguments"]),
+		})
+	}
+	return toJSON(map[string]interface{}{
+		"id":            data["id"],
+		"type":          "message",
+		"role":          "assistant",
+		"model":         data["model"],
+		"content":       content,
+		"stop_reason":   anthropicResponsesStopReason(out.truncated, len(out.calls) > 0),
+		"stop_sequence": nil,
+		"usage":         map[string]interface{}{"input_tokens": input, "output_tokens": output},
+	})
+}
+
+// callInput parses a function call's JSON arguments into a tool_use input.
+func callInput(arguments interface{}) interface{} {
+	var input interface{} = map[string]interface{}{}
+	if s, ok := arguments.(string); ok && s != "" {
+		json.Unmarshal([]byte(s), &input)
+	}
+	return input
+}
+
+func anthropicResponsesStopReason(truncated, toolUse bool) string {
+	switch {
+	case truncated:
+		return "max_tokens"
+	case toolUse:
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+func chatFinishReason(truncated, toolCalls bool) string {
+	switch {
+	case truncated:
+		return "length"
+	case toolCalls:
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// responsesError converts an OpenAI error object into an Anthropic error
+// response.
+func responsesError(e map[string]interface{}) map[string]interface{} {
+	errType := "api_error"
+	switch e["type"] {
+	case "invalid_request_error", "authentication_error", "permission_error", "not_found_error", "rate_limit_error":
+		errType = e["type"].(string)
+	}
+	if e["code"] == "rate_limit_exceeded" || e["code"] == "insufficient_quota" {
+		errType = "rate_limit_error"
+	}
+	return map[string]interface{}{
+		"type":  "error",
+		"error": map[string]interface{}{"type": errType, "message": e["message"]},
+	}
+}
+
+// ResponsesStream converts a Responses API event stream into an Anthropic
+// Messages or Chat Completions event stream.
+type ResponsesStream struct {
+	chat bool // convert to Chat Completions chunks rather than Anthropic events
+
+	id, model string
+	index     int    // Anthropic: index of the next content block
+	open      string // Anthropic: type of the open content block; empty if none
+	toolCalls int    // number of function calls started
+}
+
+// TransformEvent returns the client events for one Responses API event.
+func (s *ResponsesStream) TransformEvent(event []byte) [][]byte {
+	var ev map[string]interface{}
+	if err := json.Unmarshal(eventData(event), &ev); err != nil {
+		return nil
+	}
+	if s.chat {
+		return s.chatEvents(ev)
+	}
+	return s.anthropicEvents(ev)
+}
+
+func (s *ResponsesStream) anthropicEvents(ev map[string]interface{}) [][]byte {
+	var out [][]byte
+	emit := func(payload map[string]interface{}) {
+		data, _ := json.Marshal(payload)
+		out = append(out, []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", payload["type"], data)))
+	}
+	closeBlock := func() {
+		if s.open != "" {
+			emit(map[string]interface{}{"type": "content_block_stop", "index": s.index})
+			s.index++
+			s.open = ""
+		}
+	}
+	response, _ := ev["response"].(map[string]interface{})
+	item, _ := ev["item"].(map[string]interface{})
+
+	switch ev["type"] {
+	case "response.created":
+		emit(map[string]interface{}{
+			"type": "message_start",
+			"message": map[string]interface{}{
+				"id":            response["id"],
+				"type":          "message",
+				"role":          "assistant",
+				"model":         response["model"],
+				"content":       []interface{}{},
+				"stop_reason":   nil,
+				"stop_sequence": nil,
+				"usage":         map[string]interface{}{"input_tokens": 0, "output_tokens": 0},
+			},
+		})
+	case "response.output_text.delta":
+		if s.open != "text" {
+			closeBlock()
+			s.open = "text"
+			emit(map[string]interface{}{
+				"type":          "content_block_start",
+				"index":         s.index,
+				"content_block": map[string]interface{}{"type": "text", "text": ""},
+			})
+		}
+		emit(map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": s.index,
+			"delta": map[string]interface{}{"type": "text_delta", "text": ev["delta"]},
+		})
+	case "response.output_item.added":
+		if item["type"] != "function_call" {
+			break
+		}
+		closeBlock()
+		s.open = "tool_use"
+		s.toolCalls++
+		emit(map[string]interface{}{
+			"type":          "content_block_start",
+			"index":         s.index,
+			"content_block": map[string]interface{}{"type": "tool_use", "id": item["call_id"], "name": item["name"], "input": map[string]interface{}{}},
+		})
+	case "response.function_call_arguments.delta":
+		if s.open == "tool_use" {
+			emit(map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": s.index,
+				"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": ev["delta"]},
+			})
+		}
+	case "response.output_item.done":
+		closeBlock()
+	case "response.completed", "response.incomplete":
+		closeBlock()
+		input, output := responsesUsage(response)
+		truncated := parseResponsesOutput(response).truncated
+		emit(map[string]interface{}{
+			"type":  "message_delta",
+			"delta": map[string]interface{}{"stop_reason": anthropicResponsesStopReason(truncated, s.toolCalls > 0), "stop_sequence": nil},
+			"usage": map[string]interface{}{"input_tokens": input, "output_tokens": output},
+		})
+		emit(map[string]interface{}{"type": "message_stop"})
+	case "response.failed":
+		e, _ := response["error"].(map[string]interface{})
+		emit(responsesError(e))
+	case "error":
+		emit(responsesError(ev))
+	}
+	return out
+}
+
+func (s *ResponsesStream) chatEvents(ev map[string]interface{}) [][]byte {
+	var out [][]byte
+	chunk := func(delta map[string]interface{}, finishReason interface{}, usage map[string]interface{}) {
+		payload := map[string]interface{}{
+			"id":      s.id,
+			"object":  "chat.completion.chunk",
+			"model":   s.model,
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+		if usage != nil {
+			payload["usage"] = usage
+		}
+		data, _ := json.Marshal(payload)
+		out = append(out, []byte(fmt.Sprintf("data: %s\n\n", data)))
+	}
+	response, _ := ev["response"].(map[string]interface{})
+	item, _ := ev["item"].(map[string]interface{})
+
+	switch ev["type"] {
+	case "response.created":
+		s.id, _ = response["id"].(string)
+		s.model, _ = response["model"].(string)
+		chunk(map[string]interface{}{"role": "assistant", "content": ""}, nil, nil)
+	case "response.output_text.delta":
+		chunk(map[string]interface{}{"content": ev["delta"]}, nil, nil)
+	case "response.output_item.added":
+		if item["type"] != "function_call" {
+			break
+		}
+		s.toolCalls++
+		chunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index":    s.toolCalls - 1,
+			"id":       item["call_id"],
+			"type":     "function",
+			"function": map[string]interface{}{"name": item["name"], "arguments": ""},
+		}}}, nil, nil)
+	case "response.function_call_arguments.delta":
+		chunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index":    s.toolCalls - 1,
+			"function": map[string]interface{}{"arguments": ev["delta"]},
+		}}}, nil, nil)
+	case "response.completed", "response.incomplete":
+		input, output := responsesUsage(response)
+		truncated := parseResponsesOutput(response).truncated
+		chunk(map[string]interface{}{}, chatFinishReason(truncated, s.toolCalls > 0), map[string]interface{}{
+			"prompt_tokens":     input,
+			"completion_tokens": output,
+			"total_tokens":      input + output,
+		})
+		out = append(out, []byte("data: [DONE]\n\n"))
+	case "response.failed", "error":
+		e, _ := response["error"].(map[string]interface{})
+		if e == nil {
+			e = ev
+		}
+		data, _ := json.Marshal(map[string]interface{}{"error": map[string]interface{}{"message": e["message"], "code": e["code"]}})
+		out = append(out, []byte(fmt.Sprintf("data: %s\n\n", data)))
+	}
+	return out
+}
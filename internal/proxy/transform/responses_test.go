@@ -0,0 +1,173 @@
+package transform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResponsesPath(t *testing.T) {
+	tests := map[string]string{
+		"/v1/messages":              "/v1/responses",
+		"/v1/chat/completions":      "/v1/responses",
+		"/chat/completions":         "/responses",
+		"/v1/messages/count_tokens": "/v1/messages/count_tokens",
+	}
+	for path, want := range tests {
+		if got := ResponsesPath(path); got != want {
+			t.Errorf("ResponsesPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestResponsesTransformer_TransformRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientFormat string
+		input        string
+		want         string
+	}{
+		{
+			name:         "anthropic text",
+			clientFormat: "anthropic",
+			input:        `{"model":"gpt-5","max_tokens":1024,"stream":true,"system":[{"type":"text","text":"be brief"}],"thinking":{"type":"enabled","budget_tokens":8000},"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"hello"}]}]}`,
+			want:         `{"store":false,"model":"gpt-5","stream":true,"max_output_tokens":1024,"instructions":"be brief","reasoning":{"effort":"medium"},"input":[{"role":"user","content":[{"type":"input_text","text":"hi"}]},{"role":"assistant","content":[{"type":"output_text","text":"hello"}]}]}`,
+		},
+		{
+			name:         "anthropic tools",
+			clientFormat: "anthropic",
+			input: `{"model":"gpt-5","tool_choice":{"type":"any"},"tools":[{"name":"Read","description":"read","input_schema":{"type":"object"}},{"type":"web_search_20250305","name":"web_search"}],
+				"messages":[{"role":"assistant","content":[{"type":"text","text":"reading"},{"type":"tool_use","id":"call_1","name":"Read","input":{"path":"a.go"}}]},
+					{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":"package a"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBO"}}]}]}`,
+			want: `{"store":false,"model":"gpt-5","tool_choice":"required","tools":[{"type":"function","name":"Read","description":"read","parameters":{"type":"object"}}],
+				"input":[{"role":"assistant","content":[{"type":"output_text","text":"reading"}]},
+					{"type":"function_call","call_id":"call_1","name":"Read","arguments":"{\"path\":\"a.go\"}"},
+					{"type":"function_call_output","call_id":"call_1","output":"package a"},
+					{"role":"user","content":[{"type":"input_image","image_url":"data:image/png;base64,iVBO"}]}]}`,
+		},
+		{
+			name:         "chat completions",
+			clientFormat: "openai",
+			input: `{"model":"gpt-5","max_completion_tokens":512,"n":1,"tool_choice":{"type":"function","function":{"name":"Read"}},
+				"tools":[{"type":"function","function":{"name":"Read","parameters":{"type":"object"}}}],
+				"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"},
+					{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"Read","arguments":"{}"}}]},
+					{"role":"tool","tool_call_id":"call_1","content":"package a"}]}`,
+			want: `{"store":false,"model":"gpt-5","max_output_tokens":512,"tool_choice":{"type":"function","name":"Read"},
+				"tools":[{"type":"function","name":"Read","parameters":{"type":"object"}}],
+				"input":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"},
+					{"type":"function_call","call_id":"call_1","name":"Read","arguments":"{}"},
+					{"type":"function_call_output","call_id":"call_1","output":"package a"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&ResponsesTransformer{}).TransformRequest([]byte(tt.input), tt.clientFormat)
+			if err != nil {
+				t.Fatalf("TransformRequest() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestResponsesTransformer_TransformResponse(t *testing.T) {
+	const toolCall = `{"id":"resp_1","created_at":1700000000,"model":"gpt-5","status":"completed","output":[{"type":"reasoning","summary":[]},{"type":"message","role":"assistant","content":[{"type":"output_text","text":"reading"}]},{"type":"function_call","call_id":"call_1","name":"Read","arguments":"{\"path\":\"a.go\"}"}],"usage":{"input_tokens":10,"output_tokens":5}}`
+	tests := []struct {
+		name         string
+		clientFormat string
+		input        string
+		want         string
+	}{
+		{
+			name:         "anthropic tool call",
+			clientFormat: "anthropic",
+			input:        toolCall,
+			want:         `{"id":"resp_1","type":"message","role":"assistant","model":"gpt-5","content":[{"type":"text","text":"reading"},{"type":"tool_use","id":"call_1","name":"Read","input":{"path":"a.go"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":5}}`,
+		},
+		{
+			name:         "anthropic truncated",
+			clientFormat: "anthropic",
+			input:        `{"id":"resp_2","model":"gpt-5","status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"output":[{"type":"message","content":[{"type":"output_text","text":"cut"}]}]}`,
+			want:         `{"id":"resp_2","type":"message","role":"assistant","model":"gpt-5","content":[{"type":"text","text":"cut"}],"stop_reason":"max_tokens","stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}`,
+		},
+		{
+			name:         "anthropic error",
+			clientFormat: "anthropic",
+			input:        `{"error":{"message":"slow down","type":"requests","code":"rate_limit_exceeded"}}`,
+			want:         `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`,
+		},
+		{
+			name:         "chat completions tool call",
+			clientFormat: "openai",
+			input:        toolCall,
+			want:         `{"id":"resp_1","object":"chat.completion","created":1700000000,"model":"gpt-5","choices":[{"index":0,"message":{"role":"assistant","content":"reading","tool_calls":[{"id":"call_1","type":"function","function":{"name":"Read","arguments":"{\"path\":\"a.go\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&ResponsesTransformer{}).TransformResponse([]byte(tt.input), tt.clientFormat)
+			if err != nil {
+				t.Fatalf("TransformResponse() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestResponsesStream(t *testing.T) {
+	events := []string{
+		"event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_1\",\"model\":\"gpt-5\"}}\n\n",
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"item\":{\"type\":\"message\"}}\n\n",
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"Hi\"}\n\n",
+		"event: response.output_item.done\ndata: {\"type\":\"response.output_item.done\",\"item\":{\"type\":\"message\"}}\n\n",
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"item\":{\"type\":\"function_call\",\"call_id\":\"call_1\",\"name\":\"Read\"}}\n\n",
+		"event: response.function_call_arguments.delta\ndata: {\"type\":\"response.function_call_arguments.delta\",\"delta\":\"{}\"}\n\n",
+		"event: response.output_item.done\ndata: {\"type\":\"response.output_item.done\",\"item\":{\"type\":\"function_call\"}}\n\n",
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"status\":\"completed\",\"usage\":{\"input_tokens\":10,\"output_tokens\":5}}}\n\n",
+	}
+	tests := []struct {
+		clientFormat string
+		want         []string
+	}{
+		{
+			clientFormat: "anthropic",
+			want: []string{
+				`event: message_start data: {"message":{"content":[],"id":"resp_1","model":"gpt-5","role":"assistant","stop_reason":null,"stop_sequence":null,"type":"message","usage":{"input_tokens":0,"output_tokens":0}},"type":"message_start"}`,
+				`event: content_block_start data: {"content_block":{"text":"","type":"text"},"index":0,"type":"content_block_start"}`,
+				`event: content_block_delta data: {"delta":{"text":"Hi","type":"text_delta"},"index":0,"type":"content_block_delta"}`,
+				`event: content_block_stop data: {"index":0,"type":"content_block_stop"}`,
+				`event: content_block_start data: {"content_block":{"id":"call_1","input":{},"name":"Read","type":"tool_use"},"index":1,"type":"content_block_start"}`,
+				`event: content_block_delta data: {"delta":{"partial_json":"{}","type":"input_json_delta"},"index":1,"type":"content_block_delta"}`,
+				`event: content_block_stop data: {"index":1,"type":"content_block_stop"}`,
+				`event: message_delta data: {"delta":{"stop_reason":"tool_use","stop_sequence":null},"type":"message_delta","usage":{"input_tokens":10,"output_tokens":5}}`,
+				`event: message_stop data: {"type":"message_stop"}`,
+			},
+		},
+		{
+			clientFormat: "openai",
+			want: []string{
+				`data: {"choices":[{"delta":{"content":"","role":"assistant"},"finish_reason":null,"index":0}],"id":"resp_1","model":"gpt-5","object":"chat.completion.chunk"}`,
+				`data: {"choices":[{"delta":{"content":"Hi"},"finish_reason":null,"index":0}],"id":"resp_1","model":"gpt-5","object":"chat.completion.chunk"}`,
+				`data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"","name":"Read"},"id":"call_1","index":0,"type":"function"}]},"finish_reason":null,"index":0}],"id":"resp_1","model":"gpt-5","object":"chat.completion.chunk"}`,
+				`data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"{}"},"index":0}]},"finish_reason":null,"index":0}],"id":"resp_1","model":"gpt-5","object":"chat.completion.chunk"}`,
+				`data: {"choices":[{"delta":{},"finish_reason":"tool_calls","index":0}],"id":"resp_1","model":"gpt-5","object":"chat.completion.chunk","usage":{"completion_tokens":5,"prompt_tokens":10,"total_tokens":15}}`,
+				`data: [DONE]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clientFormat, func(t *testing.T) {
+			s := GetStreamTransformer("openai-responses", tt.clientFormat)
+			var got []string
+			for _, event := range events {
+				for _, out := range s.TransformEvent([]byte(event)) {
+					got = append(got, strings.ReplaceAll(strings.TrimSuffix(string(out), "\n\n"), "\n", " "))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
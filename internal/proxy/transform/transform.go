@@ -24,13 +24,41 @@ type Transformer interface {
 // GetTransformer returns the appropriate transformer for the given provider type.
 func GetTransformer(providerType string) Transformer {
 	switch providerType {
-	case "openai":
+	case "openai", "azure-openai", "local":
 		return &OpenAITransformer{}
+	case "gemini":
+		return &GeminiTransformer{}
+	case "openai-responses":
+		return &ResponsesTransformer{}
+	case "bedrock":
+		return &BedrockTransformer{}
+	case "vertex":
+		return &VertexTransformer{}
 	default:
 		return &AnthropicTransformer{}
 	}
 }
 
+// StreamTransformer converts a provider's event stream to the client's
+// format one event at a time.
+type StreamTransformer interface {
+	// TransformEvent returns the client events for one complete provider
+	// event, each including its trailing blank line.
+	TransformEvent(event []byte) [][]byte
+}
+
+// GetStreamTransformer returns a new stream converter from providerType to
+// clientFormat, or nil if streams are relayed unchanged.
+func GetStreamTransformer(providerType, clientFormat string) StreamTransformer {
+	switch {
+	case providerType == "gemini" && (clientFormat == "" || clientFormat == "anthropic"):
+		return &GeminiStream{}
+	case providerType == "openai-responses":
+		return &ResponsesStream{chat: clientFormat == "openai"}
+	}
+	return nil
+}
+
 // NeedsTransform returns true if transformation is needed between client and provider formats.
 func NeedsTransform(clientFormat, providerFormat string) bool {
 	// Normalize empty to anthropic (default)
@@ -12,6 +12,8 @@ func TestGetTransformer(t *testing.T) {
 	}{
 		{"anthropic", "anthropic"},
 		{"openai", "openai"},
+		{"gemini", "gemini"},
+		{"openai-responses", "openai-responses"},
 		{"", "anthropic"}, // default
 		{"unknown", "anthropic"}, // fallback to default
 	}
@@ -0,0 +1,90 @@
+package transform
+
+import (
+	"regexp"
+)
+
+// vertexAnthropicVersion is the Messages API version Vertex AI expects in
+// the request body.
+const vertexAnthropicVersion = "vertex-2023-10-16"
+
+// VertexTransformer handles Anthropic models on Google Vertex AI, which take
+// Messages API bodies with the model in the URL instead.
+type VertexTransformer struct{}
+
+func (t *VertexTransformer) Name() string {
+	return "vertex"
+}
+
+// TransformRequest converts a request into a Vertex AI rawPredict body.
+func (t *VertexTransformer) TransformRequest(body []byte, clientFormat string) ([]byte, error) {
+	body, err := (&AnthropicTransformer{}).TransformRequest(body, clientFormat)
+	if err != nil {
+		return body, err
+	}
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+	delete(data, "model")
+	if _, ok := data["anthropic_version"]; !ok {
+		data["anthropic_version"] = vertexAnthropicVersion
+	}
+	return toJSON(data)
+}
+
+// TransformResponse converts a Vertex AI response for the client. Errors
+// from Vertex AI itself, rather than the model, come in the Google API
+// format and are converted to Anthropic errors.
+func (t *VertexTransformer) TransformResponse(body []byte, clientFormat string) ([]byte, error) {
+	if clientFormat == "" || clientFormat == "anthropic" {
+		data, err := parseJSON(body)
+		if err != nil {
+			return body, nil
+		}
+		if e, ok := data["error"].(map[string]interface{}); ok && e["status"] != nil {
+			return toJSON(anthropicError(e))
+		}
+	}
+	return (&AnthropicTransformer{}).TransformResponse(body, clientFormat)
+}
+
+// VertexPath returns the rawPredict path for a Messages request body in the
+// project and region: the streaming variant if the request streams.
+func VertexPath(body []byte, project, region string) string {
+	data, err := parseJSON(body)
+	if err != nil {
+		return "/"
+	}
+	model, _ := data["model"].(string)
+	method := "rawPredict"
+	if streaming, _ := data["stream"].(bool); streaming {
+		method = "streamRawPredict"
+	}
+	return "/v1/projects/" + project + "/locations/" + region +
+		"/publishers/anthropic/models/" + VertexModelID(model) + ":" + method
+}
+
+// vertexNames holds the models whose Vertex AI names don't follow the
+// name@date pattern of their Anthropic names.
+var vertexNames = map[string]string{
+	"claude-3-5-sonnet-20241022": "claude-3-5-sonnet-v2@20241022",
+}
+
+var datedModel = regexp.MustCompile(`^(.+)-(\d{8})$`)
+
+// VertexModelID returns the Vertex AI model ID for an Anthropic model name,
+// which puts the version date after an @. Vertex AI model IDs are returned
+// as is.
+func VertexModelID(model string) string {
+	if dated, ok := modelAliases[model]; ok {
+		model = dated
+	}
+	if name, ok := vertexNames[model]; ok {
+		return name
+	}
+	if m := datedModel.FindStringSubmatch(model); m != nil {
+		return m[1] + "@" + m[2]
+	}
+	return model
+}
@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"testing"
+)
+
+func TestVertexModelID(t *testing.T) {
+	tests := map[string]string{
+		"claude-sonnet-4-5":          "claude-sonnet-4-5@20250929",
+		"claude-opus-4-1-20250805":   "claude-opus-4-1@20250805",
+		"claude-3-5-sonnet-20241022": "claude-3-5-sonnet-v2@20241022",
+		"claude-haiku-4-5@20251001":  "claude-haiku-4-5@20251001",
+		"claude-custom":              "claude-custom",
+	}
+	for model, want := range tests {
+		if got := VertexModelID(model); got != want {
+			t.Errorf("VertexModelID(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestVertexPath(t *testing.T) {
+	tests := map[string]string{
+		`{"model":"claude-sonnet-4-5"}`:               "/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:rawPredict",
+		`{"model":"claude-sonnet-4-5","stream":true}`: "/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:streamRawPredict",
+	}
+	for body, want := range tests {
+		if got := VertexPath([]byte(body), "proj", "us-east5"); got != want {
+			t.Errorf("VertexPath(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestVertexTransformer(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     bool
+		clientFormat string
+		input        string
+		want         string
+	}{
+		{
+			name:         "request",
+			clientFormat: "anthropic",
+			input:        `{"model":"claude-sonnet-4-5","stream":true,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+			want:         `{"anthropic_version":"vertex-2023-10-16","stream":true,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:         "chat completions request",
+			clientFormat: "openai",
+			input:        `{"model":"claude-sonnet-4-5","max_completion_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+			want:         `{"anthropic_version":"vertex-2023-10-16","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:         "message response",
+			response:     true,
+			clientFormat: "anthropic",
+			input:        `{"id":"msg_1","type":"message","content":[{"type":"text","text":"hi"}]}`,
+			want:         `{"id":"msg_1","type":"message","content":[{"type":"text","text":"hi"}]}`,
+		},
+		{
+			name:         "google error",
+			response:     true,
+			clientFormat: "anthropic",
+			input:        `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`,
+			want:         `{"type":"error","error":{"type":"rate_limit_error","message":"Quota exceeded"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform := (&VertexTransformer{}).TransformRequest
+			if tt.response {
+				transform = (&VertexTransformer{}).TransformResponse
+			}
+			got, err := transform([]byte(tt.input), tt.clientFormat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
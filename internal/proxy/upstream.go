@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// NewProviderTransport returns a transport for a provider that opens
+// connections within connectTimeout (0 = the default transport's) and, if
+// proxyURL is set, reaches the provider through that HTTP(S) or SOCKS5
+// proxy, checked with config.ValidateProxyURL. socks5h resolves host names
+// on the proxy. Without proxyURL, HTTPS_PROXY and friends still apply.
+func NewProviderTransport(proxyURL string, connectTimeout time.Duration) (*http.Transport, error) {
+	if err := config.ValidateProxyURL(proxyURL); err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if connectTimeout > 0 {
+		t.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return t, nil
+}
+
+// HTTPClient returns the client for requests to the provider: base, or a
+// copy of it that uses the provider's transport.
+func (p *Provider) HTTPClient(base *http.Client) *http.Client {
+	if p.Transport == nil {
+		return base
+	}
+	c := *base
+	c.Transport = p.Transport
+	return &c
+}
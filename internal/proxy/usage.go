@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UsageWarningHeader carries a token usage warning on the first response
+// sent after a session crosses a threshold, when UsageWarnings.Inject is set.
+// Streaming responses report usage at their end, so their warning arrives on
+// the session's next response.
+const UsageWarningHeader = "X-OpenCC-Usage-Warning"
+
+// maxUsageSessions bounds the usage warning tracker.
+const maxUsageSessions = 1000
+
+// sessionTotals is a session's cumulative token usage.
+type sessionTotals struct {
+	inputTokens int
+	warned      int    // highest threshold already reported
+	notice      string // warning waiting for the next response
+}
+
+// usageTracker sums input tokens per session across requests and reports
+// configured thresholds once each. The zero value is ready to use.
+type usageTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionTotals
+	order    []string
+}
+
+// add records a response's input tokens and returns the session total and the
+// highest threshold crossed for the first time, or 0. If inject is set, a
+// crossing also leaves a notice for takeNotice.
+func (u *usageTracker) add(sessionID string, inputTokens int, thresholds []int, inject bool) (total, crossed int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.sessions == nil {
+		u.sessions = make(map[string]*sessionTotals)
+	}
+	st, ok := u.sessions[sessionID]
+	if !ok {
+		if len(u.order) >= maxUsageSessions {
+			delete(u.sessions, u.order[0])
+			u.order = u.order[1:]
+		}
+		u.order = append(u.order, sessionID)
+		st = &sessionTotals{}
+		u.sessions[sessionID] = st
+	}
+
+	st.inputTokens += inputTokens
+	for _, t := range thresholds {
+		if t > st.warned && t <= st.inputTokens && t > crossed {
+			crossed = t
+		}
+	}
+	if crossed > 0 {
+		st.warned = crossed
+		if inject {
+			st.notice = fmt.Sprintf("session passed %d cumulative input tokens (now %d)", crossed, st.inputTokens)
+		}
+	}
+	return st.inputTokens, crossed
+}
+
+// takeNotice returns and clears the session's pending notice.
+func (u *usageTracker) takeNotice(sessionID string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	st, ok := u.sessions[sessionID]
+	if !ok {
+		return ""
+	}
+	notice := st.notice
+	st.notice = ""
+	return notice
+}
+
+// checkUsageWarnings adds a response's input tokens to the session total and
+// warns when the total crosses a configured threshold.
+func (s *ProxyServer) checkUsageWarnings(sessionID string, inputTokens int) {
+	if s.UsageWarnings == nil || len(s.UsageWarnings.InputTokens) == 0 || inputTokens <= 0 {
+		return
+	}
+	total, crossed := s.usage.add(sessionID, inputTokens, s.UsageWarnings.InputTokens, s.UsageWarnings.Inject)
+	if crossed == 0 {
+		return
+	}
+	msg := fmt.Sprintf("session %s passed %d cumulative input tokens (now %d)", sessionID, crossed, total)
+	s.Logger.Printf("[usage] warning: %s", msg)
+	s.logStructured(nil, "", 0, LogLevelWarn, msg)
+}
+
+// UsageDayFormat is the layout of UsageRecord.Day.
+const UsageDayFormat = "2006-01-02"
+
+// UsageRecord is the usage of one provider through one profile on one local
+// day, as stored in the LogDB.
+type UsageRecord struct {
+	Day          string  `json:"day"`
+	Provider     string  `json:"provider"`
+	Profile      string  `json:"profile"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"` // from the provider's pricing; 0 without it
+}
+
+// recordUsage adds a served request to the usage store.
+func (s *ProxyServer) recordUsage(p *Provider, now time.Time, inputTokens, outputTokens int) {
+	if s.LogDB == nil {
+		return
+	}
+	cost, _ := p.estimatedCost(requestNeeds{inputTokens: inputTokens, outputTokens: outputTokens})
+	err := s.LogDB.RecordUsage(UsageRecord{
+		Day:          now.Format(UsageDayFormat),
+		Provider:     p.Name,
+		Profile:      s.Profile,
+		Requests:     1,
+		InputTokens:  int64(inputTokens),
+		OutputTokens: int64(outputTokens),
+		CostUSD:      cost,
+	})
+	if err != nil {
+		s.Logger.Printf("[%s] failed to record usage: %v", p.Name, err)
+	}
+}
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+const (
+	// vertexScope is the OAuth2 scope Vertex AI requests need.
+	vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+	// googleTokenURL exchanges credentials for access tokens.
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// VertexAuth holds the project and region of a "vertex" provider and gets
+// OAuth2 access tokens for it from a service account key or gcloud user
+// credentials, reusing each token until shortly before it expires.
+type VertexAuth struct {
+	ProjectID string
+	Region    string
+
+	creds vertexCredentials
+	key   *rsa.PrivateKey // service account signing key
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// vertexCredentials is a Google credentials file.
+type vertexCredentials struct {
+	Type         string `json:"type"` // "service_account" or "authorized_user"
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// NewVertexAuth reads the credentials of a resolved Vertex configuration.
+func NewVertexAuth(cfg *config.VertexConfig) (*VertexAuth, error) {
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: reading credentials: %w", err)
+	}
+	a := &VertexAuth{ProjectID: cfg.ProjectID, Region: cfg.Region}
+	if err := json.Unmarshal(data, &a.creds); err != nil {
+		return nil, fmt.Errorf("vertex: parsing %s: %w", cfg.CredentialsFile, err)
+	}
+	switch a.creds.Type {
+	case "service_account":
+		if a.key, err = parseRSAKey(a.creds.PrivateKey); err != nil {
+			return nil, fmt.Errorf("vertex: service account key: %w", err)
+		}
+		if a.creds.TokenURI == "" {
+			a.creds.TokenURI = googleTokenURL
+		}
+	case "authorized_user":
+		if a.creds.RefreshToken == "" {
+			return nil, errors.New("vertex: user credentials have no refresh token")
+		}
+		a.creds.TokenURI = googleTokenURL
+	default:
+		return nil, fmt.Errorf("vertex: unsupported credentials type %q", a.creds.Type)
+	}
+	return a, nil
+}
+
+func parseRSAKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+// Token returns an access token, exchanging the credentials for a new one
+// through client when there is none or it is about to expire.
+func (a *VertexAuth) Token(ctx context.Context, client *http.Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.token != "" && now.Add(time.Minute).Before(a.expiry) {
+		return a.token, nil
+	}
+
+	form := url.Values{}
+	if a.key != nil {
+		assertion, err := a.assertion(now)
+		if err != nil {
+			return "", err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	} else {
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", a.creds.ClientID)
+		form.Set("client_secret", a.creds.ClientSecret)
+		form.Set("refresh_token", a.creds.RefreshToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.creds.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vertex: token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&tok)
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return "", fmt.Errorf("vertex: token exchange failed (%d): %s %s", resp.StatusCode, tok.Error, tok.ErrorDescription)
+	}
+	a.token = tok.AccessToken
+	a.expiry = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return a.token, nil
+}
+
+// assertion returns the signed JWT a service account exchanges for an
+// access token.
+func (a *VertexAuth) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   a.creds.ClientEmail,
+		"scope": vertexScope,
+		"aud":   a.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
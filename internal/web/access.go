@@ -0,0 +1,125 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+)
+
+// listenHost returns the host the web UI binds to.
+func listenHost(access *config.WebAccessConfig) string {
+	if access == nil || access.Listen == "" {
+		return "127.0.0.1"
+	}
+	return strings.Trim(access.Listen, "[]")
+}
+
+// BaseURL returns the address local commands reach the web UI on: over
+// HTTPS when it serves TLS, and on loopback unless it is bound to one
+// specific address.
+func BaseURL(port int) string {
+	access := config.GetWebAccess()
+	host := listenHost(access)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if access != nil && access.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// parseAllowList parses client IPs and CIDR ranges.
+func parseAllowList(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return nil, fmt.Errorf(i18n.T("invalid web_access.allow_from entry '%s'"), e)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("invalid web_access.allow_from entry '%s'"), e)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// clientIP returns the address r came from. Forwarding headers are ignored:
+// the checks apply to the connection itself.
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// localClient reports whether r came over loopback.
+func localClient(r *http.Request) bool {
+	ip := clientIP(r)
+	return ip != nil && ip.IsLoopback()
+}
+
+// allowClients refuses connections from addresses outside the allow list.
+// Loopback is always let in, so local commands keep working.
+func (s *Server) allowClients(next http.Handler) http.Handler {
+	if len(s.allow) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, allowed := clientIP(r), localClient(r)
+		for _, n := range s.allow {
+			if ip != nil && n.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			writeError(w, http.StatusForbidden, "client address not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setupAccess applies the remote access settings to s: which clients it
+// lets in and whether it serves TLS. Remote access needs a web UI password,
+// so other machines can sign in.
+func (s *Server) setupAccess(access *config.WebAccessConfig) error {
+	host := listenHost(access)
+	s.remote = !config.IsLoopbackHost(host)
+	if access == nil {
+		return nil
+	}
+	allow, err := parseAllowList(access.AllowFrom)
+	if err != nil {
+		return err
+	}
+	s.allow = allow
+	if access.TLS != nil && !access.TLS.IsValid() {
+		return errors.New(i18n.T("web_access.tls needs both cert_file and key_file, or neither for a self-signed certificate"))
+	}
+	s.tls = access.TLS
+	if s.remote && !config.HasWebPassword() {
+		return fmt.Errorf(i18n.T("web_access.listen '%s' accepts remote connections; set a password with 'opencc web password'"), host)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// clientsResponse is the JSON shape for per-client statistics.
+type clientsResponse struct {
+	Clients []proxy.ClientStats `json:"clients"`
+}
+
+func (s *Server) handleClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Like logs: in-memory logger first (same process as proxy), then SQLite.
+	stats := []proxy.ClientStats{}
+	if logger := proxy.GetGlobalLogger(); logger != nil && logger.HasEntries() {
+		stats = logger.GetClientStats()
+	} else if db := proxy.GetGlobalLogDB(); db != nil {
+		dbStats, err := db.ClientStats()
+		if err != nil {
+			s.logger.Printf("Failed to query client stats: %v", err)
+		} else if dbStats != nil {
+			stats = dbStats
+		}
+	}
+	writeJSON(w, http.StatusOK, clientsResponse{Clients: stats})
+}
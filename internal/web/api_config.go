@@ -0,0 +1,44 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// validateResponse is the JSON shape of the config validation endpoint.
+type validateResponse struct {
+	Valid    bool                   `json:"valid"`
+	Problems []config.ConfigProblem `json:"problems"`
+}
+
+// handleConfigValidate handles POST /api/v1/config/validate. It checks the
+// opencc.json document in the request body, or the config file when the
+// body is empty.
+func (s *Server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	var problems []config.ConfigProblem
+	if len(bytes.TrimSpace(data)) > 0 {
+		problems = config.ValidateConfig(data)
+	} else if problems, err = config.ValidateConfigFile(); err != nil {
+		s.logger.Printf("Failed to validate config: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read config file")
+		return
+	}
+	if problems == nil {
+		problems = []config.ConfigProblem{}
+	}
+	writeJSON(w, http.StatusOK, validateResponse{Valid: len(problems) == 0, Problems: problems})
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// mapResponse is the JSON shape of a model mapping dry run.
+type mapResponse struct {
+	Profile  string               `json:"profile"`
+	Model    string               `json:"model"`
+	Scenario config.Scenario      `json:"scenario"`
+	Mappings []proxy.ModelMapping `json:"mappings"`
+}
+
+// handleMap dry-runs model mapping:
+// GET /api/v1/map?model=<model>[&profile=<name>][&thinking=true]
+func (s *Server) handleMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	model := query.Get("model")
+	if model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	store := config.DefaultStore()
+	profile := query.Get("profile")
+	if profile == "" {
+		profile = store.GetDefaultProfile()
+	}
+	pc := store.GetProfileConfig(profile)
+	if pc == nil {
+		writeError(w, http.StatusNotFound, "profile not found")
+		return
+	}
+
+	// Only the model mappings matter for a dry run
+	providers := make(map[string]*proxy.Provider)
+	provider := func(name string) *proxy.Provider {
+		if p, ok := providers[name]; ok {
+			return p
+		}
+		cfg := store.GetProvider(name)
+		if cfg == nil {
+			return nil
+		}
+		p := &proxy.Provider{
+			Name:           name,
+			Type:           cfg.GetType(),
+			Model:          cfg.Model,
+			ReasoningModel: cfg.ReasoningModel,
+			HaikuModel:     cfg.HaikuModel,
+			OpusModel:      cfg.OpusModel,
+			SonnetModel:    cfg.SonnetModel,
+		}
+		p.FillDefaultModels()
+		providers[name] = p
+		return p
+	}
+
+	routing := &proxy.RoutingConfig{
+		ScenarioRoutes:       make(map[config.Scenario]*proxy.ScenarioProviders),
+		LongContextThreshold: pc.LongContextThreshold,
+	}
+	for _, name := range pc.Providers {
+		if p := provider(name); p != nil {
+			routing.DefaultProviders = append(routing.DefaultProviders, p)
+		}
+	}
+	for scenario, route := range pc.Routing {
+		sp := &proxy.ScenarioProviders{Models: make(map[string]string)}
+		for _, pr := range route.Providers {
+			if p := provider(pr.Name); p != nil {
+				sp.Providers = append(sp.Providers, p)
+				if pr.Model != "" {
+					sp.Models[pr.Name] = pr.Model
+				}
+			}
+		}
+		if len(sp.Providers) > 0 {
+			routing.ScenarioRoutes[scenario] = sp
+		}
+	}
+
+	body := map[string]interface{}{
+		"model":    model,
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+	if query.Get("thinking") == "true" {
+		body["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": 1024}
+	}
+
+	srv := proxy.NewProxyServerWithRouting(routing, log.New(io.Discard, "", 0))
+	scenario, mappings := srv.PlanModels(body)
+	if mappings == nil {
+		mappings = []proxy.ModelMapping{}
+	}
+	writeJSON(w, http.StatusOK, mapResponse{Profile: profile, Model: model, Scenario: scenario, Mappings: mappings})
+}
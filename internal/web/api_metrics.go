@@ -0,0 +1,49 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// defaultMetricsWindow is how far back metrics look when no since is given.
+const defaultMetricsWindow = 24 * time.Hour
+
+// metricsResponse is the JSON shape for per-provider metrics.
+type metricsResponse struct {
+	Since     time.Time               `json:"since"`
+	Providers []proxy.ProviderMetrics `json:"providers"`
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	window := defaultMetricsWindow
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "since must be a positive duration, e.g. 1h")
+			return
+		}
+		window = d
+	}
+	since := time.Now().Add(-window)
+
+	// SQLite holds the full window; the in-memory logger only recent entries.
+	metrics := []proxy.ProviderMetrics{}
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		m, err := db.ProviderMetrics(since)
+		if err != nil {
+			s.logger.Printf("Failed to query provider metrics: %v", err)
+		} else {
+			metrics = m
+		}
+	} else if logger := proxy.GetGlobalLogger(); logger != nil {
+		metrics = logger.GetProviderMetrics(since)
+	}
+	writeJSON(w, http.StatusOK, metricsResponse{Since: since, Providers: metrics})
+}
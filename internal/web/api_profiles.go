@@ -16,25 +16,46 @@ type providerRouteResponse struct {
 
 // scenarioRouteResponse is the JSON shape for a scenario route.
 type scenarioRouteResponse struct {
-	Providers []*providerRouteResponse `json:"providers"`
+	Providers            []*providerRouteResponse `json:"providers"`
+	MaxRequestBytes      int64                    `json:"max_request_bytes,omitempty"`
+	LongContextThreshold int                      `json:"long_context_threshold,omitempty"`
 }
 
 // profileResponse is the JSON shape returned for a single profile.
 type profileResponse struct {
-	Name      string                                    `json:"name"`
-	Providers []string                                  `json:"providers"`
-	Routing   map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Name            string                                     `json:"name"`
+	Extends         string                                     `json:"extends,omitempty"`
+	Providers       []string                                   `json:"providers"`
+	Effective       []string                                   `json:"effective_providers,omitempty"` // providers with inherited ones, when extending
+	Routing         map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Strategy        config.Strategy                            `json:"strategy,omitempty"`
+	Weights         map[string]int                             `json:"weights,omitempty"`
+	EnvVars         map[string]map[string]string               `json:"env_vars,omitempty"`
+	AutoOrder       *config.AutoOrderConfig                    `json:"auto_order,omitempty"`
+	SessionAffinity bool                                       `json:"session_affinity,omitempty"`
 }
 
 type createProfileRequest struct {
-	Name      string                                    `json:"name"`
-	Providers []string                                  `json:"providers"`
-	Routing   map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Name            string                                     `json:"name"`
+	Extends         string                                     `json:"extends,omitempty"`
+	Providers       []string                                   `json:"providers"`
+	Routing         map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Strategy        config.Strategy                            `json:"strategy,omitempty"`
+	Weights         map[string]int                             `json:"weights,omitempty"`
+	EnvVars         map[string]map[string]string               `json:"env_vars,omitempty"`
+	AutoOrder       *config.AutoOrderConfig                    `json:"auto_order,omitempty"`
+	SessionAffinity bool                                       `json:"session_affinity,omitempty"`
 }
 
 type updateProfileRequest struct {
-	Providers []string                                  `json:"providers"`
-	Routing   map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Extends         *string                                    `json:"extends,omitempty"`
+	Providers       []string                                   `json:"providers"`
+	Routing         map[config.Scenario]*scenarioRouteResponse `json:"routing,omitempty"`
+	Strategy        config.Strategy                            `json:"strategy,omitempty"`
+	Weights         map[string]int                             `json:"weights,omitempty"`
+	EnvVars         map[string]map[string]string               `json:"env_vars,omitempty"`
+	AutoOrder       *config.AutoOrderConfig                    `json:"auto_order,omitempty"`
+	SessionAffinity bool                                       `json:"session_affinity,omitempty"`
 }
 
 // profileConfigToResponse converts a ProfileConfig to a profileResponse.
@@ -44,8 +65,14 @@ func profileConfigToResponse(name string, pc *config.ProfileConfig) profileRespo
 		providers = []string{}
 	}
 	resp := profileResponse{
-		Name:      name,
-		Providers: providers,
+		Name:            name,
+		Extends:         pc.Extends,
+		Providers:       providers,
+		Strategy:        pc.Strategy,
+		Weights:         pc.Weights,
+		EnvVars:         pc.EnvVars,
+		AutoOrder:       pc.AutoOrder,
+		SessionAffinity: pc.SessionAffinity,
 	}
 	if len(pc.Routing) > 0 {
 		resp.Routing = make(map[config.Scenario]*scenarioRouteResponse)
@@ -58,13 +85,24 @@ func profileConfigToResponse(name string, pc *config.ProfileConfig) profileRespo
 				})
 			}
 			resp.Routing[scenario] = &scenarioRouteResponse{
-				Providers: providerRoutes,
+				Providers:            providerRoutes,
+				MaxRequestBytes:      route.MaxRequestBytes,
+				LongContextThreshold: route.LongContextThreshold,
 			}
 		}
 	}
 	return resp
 }
 
+// withEffectiveProviders adds the provider chain with inherited providers to
+// the response of a profile that extends another.
+func withEffectiveProviders(resp profileResponse) profileResponse {
+	if resp.Extends != "" {
+		resp.Effective = config.DefaultStore().GetProfileOrder(resp.Name)
+	}
+	return resp
+}
+
 // routingResponseToConfig converts routing response data to config ScenarioRoutes.
 func routingResponseToConfig(routing map[config.Scenario]*scenarioRouteResponse) map[config.Scenario]*config.ScenarioRoute {
 	if len(routing) == 0 {
@@ -81,7 +119,9 @@ func routingResponseToConfig(routing map[config.Scenario]*scenarioRouteResponse)
 				})
 			}
 			result[scenario] = &config.ScenarioRoute{
-				Providers: providerRoutes,
+				Providers:            providerRoutes,
+				MaxRequestBytes:      route.MaxRequestBytes,
+				LongContextThreshold: route.LongContextThreshold,
 			}
 		}
 	}
@@ -128,23 +168,23 @@ func (s *Server) listProfiles(w http.ResponseWriter, r *http.Request) {
 	names := store.ListProfiles()
 	profiles := make([]profileResponse, 0, len(names))
 	for _, name := range names {
-		pc := store.GetProfileConfig(name)
+		pc := store.GetOwnProfileConfig(name)
 		if pc == nil {
 			pc = &config.ProfileConfig{Providers: []string{}}
 		}
-		profiles = append(profiles, profileConfigToResponse(name, pc))
+		profiles = append(profiles, withEffectiveProviders(profileConfigToResponse(name, pc)))
 	}
 	writeJSON(w, http.StatusOK, profiles)
 }
 
 func (s *Server) getProfile(w http.ResponseWriter, r *http.Request, name string) {
 	store := config.DefaultStore()
-	pc := store.GetProfileConfig(name)
+	pc := store.GetOwnProfileConfig(name)
 	if pc == nil {
 		writeError(w, http.StatusNotFound, "profile not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, profileConfigToResponse(name, pc))
+	writeJSON(w, http.StatusOK, withEffectiveProviders(profileConfigToResponse(name, pc)))
 }
 
 func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
@@ -157,13 +197,33 @@ func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "name is required")
 		return
 	}
+	if !req.Strategy.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid strategy")
+		return
+	}
+	if !validWeights(req.Weights) {
+		writeError(w, http.StatusBadRequest, "invalid weights")
+		return
+	}
+	if !validEnvVarCLIs(req.EnvVars) {
+		writeError(w, http.StatusBadRequest, "invalid CLI in env_vars")
+		return
+	}
+	if !req.AutoOrder.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid auto_order")
+		return
+	}
 
 	store := config.DefaultStore()
-	existing := store.GetProfileConfig(req.Name)
+	existing := store.GetOwnProfileConfig(req.Name)
 	if existing != nil {
 		writeError(w, http.StatusConflict, "profile already exists")
 		return
 	}
+	if err := store.CheckExtends(req.Name, req.Extends); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	providers := req.Providers
 	if providers == nil {
@@ -171,20 +231,26 @@ func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	pc := &config.ProfileConfig{
-		Providers: providers,
-		Routing:   routingResponseToConfig(req.Routing),
+		Extends:         req.Extends,
+		Providers:       providers,
+		Routing:         routingResponseToConfig(req.Routing),
+		Strategy:        req.Strategy,
+		Weights:         req.Weights,
+		EnvVars:         req.EnvVars,
+		AutoOrder:       req.AutoOrder,
+		SessionAffinity: req.SessionAffinity,
 	}
 
 	if err := store.SetProfileConfig(req.Name, pc); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusCreated, profileConfigToResponse(req.Name, pc))
+	writeJSON(w, http.StatusCreated, withEffectiveProviders(profileConfigToResponse(req.Name, pc)))
 }
 
 func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request, name string) {
 	store := config.DefaultStore()
-	existing := store.GetProfileConfig(name)
+	existing := store.GetOwnProfileConfig(name)
 	if existing == nil {
 		writeError(w, http.StatusNotFound, "profile not found")
 		return
@@ -195,20 +261,59 @@ func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request, name stri
 		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
+	if !req.Strategy.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid strategy")
+		return
+	}
+	if !validWeights(req.Weights) {
+		writeError(w, http.StatusBadRequest, "invalid weights")
+		return
+	}
+	if !validEnvVarCLIs(req.EnvVars) {
+		writeError(w, http.StatusBadRequest, "invalid CLI in env_vars")
+		return
+	}
+	if !req.AutoOrder.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid auto_order")
+		return
+	}
+	if req.Extends != nil {
+		if err := store.CheckExtends(name, *req.Extends); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	providers := req.Providers
 	if providers == nil {
 		providers = []string{}
 	}
 
+	// extends too is only replaced when the request includes it
+	if req.Extends != nil {
+		existing.Extends = *req.Extends
+	}
 	existing.Providers = providers
 	existing.Routing = routingResponseToConfig(req.Routing)
+	existing.Strategy = req.Strategy
+	existing.SessionAffinity = req.SessionAffinity
+	// weights and env_vars are only replaced when the request includes them
+	if req.Weights != nil {
+		existing.Weights = req.Weights
+	}
+	if req.EnvVars != nil {
+		existing.EnvVars = req.EnvVars
+	}
+	// auto_order too
+	if req.AutoOrder != nil {
+		existing.AutoOrder = req.AutoOrder
+	}
 
 	if err := store.SetProfileConfig(name, existing); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, profileConfigToResponse(name, existing))
+	writeJSON(w, http.StatusOK, withEffectiveProviders(profileConfigToResponse(name, existing)))
 }
 
 func (s *Server) deleteProfile(w http.ResponseWriter, r *http.Request, name string) {
@@ -233,3 +338,23 @@ func (s *Server) deleteProfile(w http.ResponseWriter, r *http.Request, name stri
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
+
+// validEnvVarCLIs reports whether every key of a profile's env_vars is a known CLI.
+// validWeights reports whether every weight is zero or positive.
+func validWeights(weights map[string]int) bool {
+	for _, w := range weights {
+		if w < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func validEnvVarCLIs(envVars map[string]map[string]string) bool {
+	for cli := range envVars {
+		if !config.IsValidCLI(cli) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// providerTestTimeout bounds a connectivity test, probe and request together.
+const providerTestTimeout = 60 * time.Second
+
+// ProviderTestResult is the outcome of a provider connectivity test: a probe
+// of the provider, then a tiny real request unless the probe failed.
+type ProviderTestResult struct {
+	Provider       string `json:"provider"`
+	OK             bool   `json:"ok"`
+	ProbeStatus    int    `json:"probe_status,omitempty"`
+	ProbeLatencyMs int64  `json:"probe_latency_ms"`
+	Status         int    `json:"status,omitempty"` // of the request
+	LatencyMs      int64  `json:"latency_ms"`
+	Model          string `json:"model,omitempty"` // model that answered
+	InputTokens    int    `json:"input_tokens,omitempty"`
+	OutputTokens   int    `json:"output_tokens,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ProviderTester runs a connectivity test of the named provider. Building
+// providers lives with the CLI, which hands the server its `opencc test`.
+type ProviderTester func(ctx context.Context, name string) ProviderTestResult
+
+// SetProviderTester enables POST /api/v1/providers/{name}/test.
+func (s *Server) SetProviderTester(t ProviderTester) {
+	s.tester = t
+}
+
+// handleProviderTest handles POST /api/v1/providers/{name}/test.
+func (s *Server) handleProviderTest(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if config.GetProvider(name) == nil {
+		writeError(w, http.StatusNotFound, "provider not found")
+		return
+	}
+	if s.tester == nil {
+		writeError(w, http.StatusNotImplemented, "provider testing is not available")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), providerTestTimeout)
+	defer cancel()
+	writeJSON(w, http.StatusOK, s.tester(ctx, name))
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// defaultHealthWindow is how far back recent error counts look when no since
+// is given.
+const defaultHealthWindow = 15 * time.Minute
+
+// providerHealthResponse is a provider's health and recent errors.
+type providerHealthResponse struct {
+	Name        string     `json:"name"`
+	Healthy     bool       `json:"healthy"`
+	AuthFailed  bool       `json:"auth_failed,omitempty"`
+	BackoffMs   int64      `json:"backoff_ms,omitempty"`
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	RetryAt     *time.Time `json:"retry_at,omitempty"`
+	Unavailable string     `json:"unavailable,omitempty"` // cooldown or maintenance window
+	Requests    int        `json:"requests"`              // attempts since Since
+	Errors      int        `json:"errors"`                // failed attempts since Since
+}
+
+// providersHealthResponse is the JSON shape of the provider health endpoint.
+type providersHealthResponse struct {
+	Since     time.Time                `json:"since"`
+	Providers []providerHealthResponse `json:"providers"`
+}
+
+// handleProvidersHealth handles GET /api/v1/providers/health: the health and
+// backoff each proxy last recorded for the providers, with recent error
+// counts from the request log.
+func (s *Server) handleProvidersHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	window := defaultHealthWindow
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "since must be a positive duration, e.g. 15m")
+			return
+		}
+		window = d
+	}
+	now := time.Now()
+	since := now.Add(-window)
+
+	var health map[string]proxy.ProviderHealth
+	var stats map[string]proxy.ProviderStats
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		var err error
+		if health, err = db.ProviderHealth(); err != nil {
+			s.logger.Printf("Failed to query provider health: %v", err)
+		}
+		if stats, err = db.ProviderStats(since); err != nil {
+			s.logger.Printf("Failed to query provider stats: %v", err)
+		}
+	}
+	writeJSON(w, http.StatusOK, providersHealthResponse{
+		Since:     since,
+		Providers: providersHealth(config.DefaultStore(), health, stats, now),
+	})
+}
+
+// providersHealth combines the recorded health and request stats of every
+// configured provider. Providers with no recorded health never failed and
+// are healthy.
+func providersHealth(store *config.Store, health map[string]proxy.ProviderHealth, stats map[string]proxy.ProviderStats, now time.Time) []providerHealthResponse {
+	names := store.ProviderNames()
+	providers := make([]providerHealthResponse, 0, len(names))
+	for _, name := range names {
+		ph := providerHealthResponse{Name: name, Healthy: true}
+		if h, ok := health[name]; ok {
+			ph.Healthy = h.HealthyAt(now)
+			ph.AuthFailed = h.AuthFailed
+			ph.BackoffMs = h.BackoffMs
+			ph.FailedAt = h.FailedAt
+			if !ph.Healthy {
+				ph.RetryAt = h.RetryAt
+			}
+		}
+		if pc := store.GetProvider(name); pc != nil {
+			ph.Unavailable = pc.UnavailableReason(now)
+		}
+		if st, ok := stats[name]; ok {
+			ph.Requests = st.Requests
+			ph.Errors = st.Requests - st.Successes
+		}
+		providers = append(providers, ph)
+	}
+	return providers
+}
@@ -1,8 +1,11 @@
 package web
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/dopejs/opencc/internal/config"
 )
@@ -22,6 +25,34 @@ type providerResponse struct {
 	ClaudeEnvVars   map[string]string `json:"claude_env_vars,omitempty"`
 	CodexEnvVars    map[string]string `json:"codex_env_vars,omitempty"`
 	OpenCodeEnvVars map[string]string `json:"opencode_env_vars,omitempty"`
+
+	Pricing      *config.ProviderPricing      `json:"pricing,omitempty"`
+	Capabilities *config.ProviderCapabilities `json:"capabilities,omitempty"`
+
+	CooldownUntil      *time.Time                 `json:"cooldown_until,omitempty"`
+	MaintenanceWindows []config.MaintenanceWindow `json:"maintenance_windows,omitempty"`
+
+	MaxRequestBytes int64 `json:"max_request_bytes,omitempty"`
+	MaxConcurrent   int   `json:"max_concurrent,omitempty"`
+
+	Retry *config.RetryPolicy `json:"retry,omitempty"`
+
+	DailyBudgetUSD    float64 `json:"daily_budget_usd,omitempty"`
+	MonthlyTokenLimit int64   `json:"monthly_token_limit,omitempty"`
+
+	ProxyURL string `json:"proxy_url,omitempty"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+
+	ConnectTimeoutSeconds        int `json:"connect_timeout_seconds,omitempty"`
+	ResponseHeaderTimeoutSeconds int `json:"response_header_timeout_seconds,omitempty"`
+	IdleTimeoutSeconds           int `json:"idle_timeout_seconds,omitempty"`
+
+	Bedrock *config.BedrockConfig `json:"bedrock,omitempty"`
+	Vertex  *config.VertexConfig  `json:"vertex,omitempty"`
+	Azure   *config.AzureConfig   `json:"azure,omitempty"`
+
+	OAuth *config.OAuthConfig `json:"oauth,omitempty"`
 }
 
 type createProviderRequest struct {
@@ -31,9 +62,28 @@ type createProviderRequest struct {
 }
 
 func toProviderResponse(name string, p *config.ProviderConfig, mask bool) providerResponse {
-	token := p.AuthToken
+	token, proxyURL, bedrock, oauth := p.AuthToken, p.ProxyURL, p.Bedrock, p.OAuth
 	if mask {
 		token = maskToken(token)
+		proxyURL = maskProxyURL(proxyURL)
+		if bedrock != nil {
+			masked := *bedrock
+			if masked.SecretAccessKey != "" {
+				masked.SecretAccessKey = maskToken(masked.SecretAccessKey)
+			}
+			if masked.SessionToken != "" {
+				masked.SessionToken = maskToken(masked.SessionToken)
+			}
+			bedrock = &masked
+		}
+		if oauth != nil {
+			masked := *oauth
+			masked.RefreshToken = maskToken(masked.RefreshToken)
+			if masked.ClientSecret != "" {
+				masked.ClientSecret = maskToken(masked.ClientSecret)
+			}
+			oauth = &masked
+		}
 	}
 	return providerResponse{
 		Name:            name,
@@ -49,7 +99,53 @@ func toProviderResponse(name string, p *config.ProviderConfig, mask bool) provid
 		ClaudeEnvVars:   p.ClaudeEnvVars,
 		CodexEnvVars:    p.CodexEnvVars,
 		OpenCodeEnvVars: p.OpenCodeEnvVars,
+		Pricing:         p.Pricing,
+		Capabilities:    p.Capabilities,
+
+		CooldownUntil:      p.CooldownUntil,
+		MaintenanceWindows: p.MaintenanceWindows,
+
+		MaxRequestBytes: p.MaxRequestBytes,
+		MaxConcurrent:   p.MaxConcurrent,
+
+		Retry: p.Retry,
+
+		DailyBudgetUSD:    p.DailyBudgetUSD,
+		MonthlyTokenLimit: p.MonthlyTokenLimit,
+
+		ProxyURL: proxyURL,
+
+		Headers: p.Headers,
+
+		ConnectTimeoutSeconds:        p.ConnectTimeoutSeconds,
+		ResponseHeaderTimeoutSeconds: p.ResponseHeaderTimeoutSeconds,
+		IdleTimeoutSeconds:           p.IdleTimeoutSeconds,
+
+		Bedrock: bedrock,
+		Vertex:  p.Vertex,
+		Azure:   p.Azure,
+
+		OAuth: oauth,
+	}
+}
+
+// maskProxyURL hides the password in a proxy URL.
+func maskProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "****"
+	}
+	return u.Redacted()
+}
+
+// validateMaintenanceWindows returns the first invalid window's error.
+func validateMaintenanceWindows(windows []config.MaintenanceWindow) error {
+	for _, w := range windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid maintenance window: %w", err)
+		}
 	}
+	return nil
 }
 
 // handleProviders handles GET /api/v1/providers and POST /api/v1/providers.
@@ -71,6 +167,10 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "provider name required")
 		return
 	}
+	if name, ok := strings.CutSuffix(name, "/test"); ok {
+		s.handleProviderTest(w, r, name)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -118,6 +218,35 @@ func (s *Server) createProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateMaintenanceWindows(req.Config.MaintenanceWindows); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !req.Config.Retry.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid retry policy")
+		return
+	}
+	if req.Config.DailyBudgetUSD < 0 || req.Config.MonthlyTokenLimit < 0 {
+		writeError(w, http.StatusBadRequest, "budget limits must not be negative")
+		return
+	}
+	if err := config.ValidateProxyURL(req.Config.ProxyURL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateHeaders(req.Config.Headers); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Config.ValidateTimeouts(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Config.OAuth.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	store := config.DefaultStore()
 	if store.GetProvider(req.Name) != nil {
 		writeError(w, http.StatusConflict, "provider already exists")
@@ -155,6 +284,31 @@ func (s *Server) updateProvider(w http.ResponseWriter, r *http.Request, name str
 		return
 	}
 
+	if err := validateMaintenanceWindows(update.MaintenanceWindows); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !update.Retry.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid retry policy")
+		return
+	}
+	if update.DailyBudgetUSD < 0 || update.MonthlyTokenLimit < 0 {
+		writeError(w, http.StatusBadRequest, "budget limits must not be negative")
+		return
+	}
+	if err := config.ValidateProxyURL(update.ProxyURL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateHeaders(update.Headers); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := update.ValidateTimeouts(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// If token is empty, keep the original.
 	if update.AuthToken == "" {
 		update.AuthToken = existing.AuthToken
@@ -176,6 +330,55 @@ func (s *Server) updateProvider(w http.ResponseWriter, r *http.Request, name str
 	existing.ClaudeEnvVars = update.ClaudeEnvVars
 	existing.CodexEnvVars = update.CodexEnvVars
 	existing.OpenCodeEnvVars = update.OpenCodeEnvVars
+	existing.Pricing = update.Pricing
+	existing.Capabilities = update.Capabilities
+	existing.MaxRequestBytes = update.MaxRequestBytes
+	existing.MaxConcurrent = update.MaxConcurrent
+	existing.Retry = update.Retry
+	existing.DailyBudgetUSD = update.DailyBudgetUSD
+	existing.MonthlyTokenLimit = update.MonthlyTokenLimit
+	existing.ProxyURL = update.ProxyURL
+	existing.Headers = update.Headers
+	existing.ConnectTimeoutSeconds = update.ConnectTimeoutSeconds
+	existing.ResponseHeaderTimeoutSeconds = update.ResponseHeaderTimeoutSeconds
+	existing.IdleTimeoutSeconds = update.IdleTimeoutSeconds
+	// Bedrock, Vertex AI and Azure settings are only replaced when the
+	// request includes them; the stored AWS secret key is kept if it isn't
+	// resent for the same key ID.
+	if update.Bedrock != nil {
+		if old := existing.Bedrock; old != nil && update.Bedrock.SecretAccessKey == "" && update.Bedrock.AccessKeyID == old.AccessKeyID {
+			update.Bedrock.SecretAccessKey = old.SecretAccessKey
+		}
+		existing.Bedrock = update.Bedrock
+	}
+	if update.Vertex != nil {
+		existing.Vertex = update.Vertex
+	}
+	if update.Azure != nil {
+		existing.Azure = update.Azure
+	}
+	// OAuth settings likewise, keeping the stored refresh token and client
+	// secret if they aren't resent
+	if update.OAuth != nil {
+		if old := existing.OAuth; old != nil {
+			if update.OAuth.RefreshToken == "" {
+				update.OAuth.RefreshToken = old.RefreshToken
+			}
+			if update.OAuth.ClientSecret == "" {
+				update.OAuth.ClientSecret = old.ClientSecret
+			}
+		}
+		if err := update.OAuth.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		existing.OAuth = update.OAuth
+	}
+	// Cooldowns are managed with `opencc provider cooldown`; windows are
+	// only replaced when the request includes them.
+	if update.MaintenanceWindows != nil {
+		existing.MaintenanceWindows = update.MaintenanceWindows
+	}
 
 	if err := store.SetProvider(name, existing); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
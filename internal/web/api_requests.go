@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// maxTraceEntries bounds the log entries read to build one request trace.
+const maxTraceEntries = 500
+
+// requestTraceResponse is the JSON shape for a request trace.
+type requestTraceResponse struct {
+	*proxy.RequestTrace
+	Logs string `json:"logs"` // logs API URL listing the request's entries
+}
+
+func (s *Server) handleRequestTrace(w http.ResponseWriter, r *http.Request) {
+	// Extract request ID from URL: /api/v1/requests/{id}
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/requests/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "request id required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Like logs: in-memory logger first (same process as proxy), then SQLite.
+	filter := proxy.LogFilter{RequestID: id, Limit: maxTraceEntries}
+	var entries []proxy.LogEntry
+	if logger := proxy.GetGlobalLogger(); logger != nil && logger.HasEntries() {
+		entries = logger.GetEntries(filter)
+	}
+	if len(entries) == 0 {
+		if db := proxy.GetGlobalLogDB(); db != nil {
+			var err error
+			entries, err = db.Query(filter)
+			if err != nil {
+				s.logger.Printf("Failed to query request %s: %v", id, err)
+				writeError(w, http.StatusInternalServerError, "failed to query request")
+				return
+			}
+		}
+	}
+
+	trace := proxy.BuildRequestTrace(id, entries)
+	if trace == nil {
+		writeError(w, http.StatusNotFound, "request not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, requestTraceResponse{
+		RequestTrace: trace,
+		Logs:         "/api/v1/logs?request_id=" + url.QueryEscape(id),
+	})
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// sessionsResponse is the JSON shape for listing session snapshots.
+type sessionsResponse struct {
+	Sessions []proxy.SessionSnapshot `json:"sessions"`
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	sessions := []proxy.SessionSnapshot{}
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		snaps, err := db.QuerySessions("", limit)
+		if err != nil {
+			s.logger.Printf("Failed to query sessions: %v", err)
+		} else if snaps != nil {
+			sessions = snaps
+		}
+	}
+	writeJSON(w, http.StatusOK, sessionsResponse{Sessions: sessions})
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	// Extract session ID from URL: /api/v1/sessions/{id}
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/sessions/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session id required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	snaps, err := db.QuerySessions(id, 1)
+	if err != nil {
+		s.logger.Printf("Failed to query session %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to query session")
+		return
+	}
+	if len(snaps) == 0 {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, snaps[0])
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // settingsResponse is the JSON shape for global settings.
@@ -12,15 +13,22 @@ type settingsResponse struct {
 	DefaultProfile string   `json:"default_profile"`
 	DefaultCLI     string   `json:"default_cli"`
 	WebPort        int      `json:"web_port"`
-	Profiles       []string `json:"profiles"`       // available profiles for selection
-	CLIs           []string `json:"clis"`           // available CLIs
+	PreflightCheck bool     `json:"preflight_check"`
+	StrictEnv      bool     `json:"strict_env"`
+	Locale         string   `json:"locale"`   // UI language; empty = from LANG
+	Profiles       []string `json:"profiles"` // available profiles for selection
+	CLIs           []string `json:"clis"`     // available CLIs
+	Locales        []string `json:"locales"`  // available UI languages
 }
 
 // settingsRequest is the JSON shape for updating settings.
 type settingsRequest struct {
-	DefaultProfile string `json:"default_profile,omitempty"`
-	DefaultCLI     string `json:"default_cli,omitempty"`
-	WebPort        int    `json:"web_port,omitempty"`
+	DefaultProfile string  `json:"default_profile,omitempty"`
+	DefaultCLI     string  `json:"default_cli,omitempty"`
+	WebPort        int     `json:"web_port,omitempty"`
+	PreflightCheck *bool   `json:"preflight_check,omitempty"`
+	StrictEnv      *bool   `json:"strict_env,omitempty"`
+	Locale         *string `json:"locale,omitempty"` // "" follows LANG
 }
 
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -42,9 +50,15 @@ func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
 		DefaultProfile: store.GetDefaultProfile(),
 		DefaultCLI:     store.GetDefaultCLI(),
 		WebPort:        store.GetWebPort(),
+		PreflightCheck: store.GetPreflightCheck(),
+		StrictEnv:      store.GetStrictEnv(),
+		Locale:         store.GetLocale(),
 		Profiles:       profiles,
 		CLIs:           config.AvailableCLIs,
 	}
+	for _, l := range i18n.Supported {
+		resp.Locales = append(resp.Locales, string(l))
+	}
 	writeJSON(w, http.StatusOK, resp)
 }
 
@@ -95,6 +109,39 @@ func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Update preflight check if provided
+	if req.PreflightCheck != nil {
+		if err := store.SetPreflightCheck(*req.PreflightCheck); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// Update strict env if provided
+	if req.StrictEnv != nil {
+		if err := store.SetStrictEnv(*req.StrictEnv); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// Update locale if provided
+	if req.Locale != nil {
+		locale := *req.Locale
+		if locale != "" {
+			l, ok := i18n.Parse(locale)
+			if !ok {
+				writeError(w, http.StatusBadRequest, "unsupported locale")
+				return
+			}
+			locale = string(l)
+		}
+		if err := store.SetLocale(locale); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
 	// Return updated settings
 	s.getSettings(w, r)
 }
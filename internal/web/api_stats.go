@@ -0,0 +1,111 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// defaultStatsDays is how many days stats cover when no days is given.
+const defaultStatsDays = 30
+
+// usageTotals sums requests, tokens and estimated cost.
+type usageTotals struct {
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+func (t *usageTotals) add(u proxy.UsageRecord) {
+	t.Requests += u.Requests
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.CostUSD += u.CostUSD
+}
+
+// namedUsage is the totals of one provider, profile or day.
+type namedUsage struct {
+	Name string `json:"name"`
+	usageTotals
+}
+
+// statsResponse is the JSON shape of the stats endpoint. Providers and
+// profiles are sorted by name, days from oldest to newest.
+type statsResponse struct {
+	Since     string              `json:"since"` // first day covered, YYYY-MM-DD
+	Total     usageTotals         `json:"total"`
+	Providers []namedUsage        `json:"providers"`
+	Profiles  []namedUsage        `json:"profiles"`
+	Days      []namedUsage        `json:"days"`
+	Records   []proxy.UsageRecord `json:"records"` // per day, provider and profile
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	days := defaultStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, 1-days).Format(proxy.UsageDayFormat)
+
+	var records []proxy.UsageRecord
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		var err error
+		if records, err = db.Usage(since); err != nil {
+			s.logger.Printf("Failed to query usage: %v", err)
+		}
+	}
+	writeJSON(w, http.StatusOK, usageStats(since, records))
+}
+
+// usageStats sums records per provider, per profile and per day.
+func usageStats(since string, records []proxy.UsageRecord) statsResponse {
+	resp := statsResponse{Since: since, Records: records}
+	if resp.Records == nil {
+		resp.Records = []proxy.UsageRecord{}
+	}
+	providers := make(map[string]*usageTotals)
+	profiles := make(map[string]*usageTotals)
+	days := make(map[string]*usageTotals)
+	add := func(m map[string]*usageTotals, key string, u proxy.UsageRecord) {
+		t, ok := m[key]
+		if !ok {
+			t = &usageTotals{}
+			m[key] = t
+		}
+		t.add(u)
+	}
+	for _, u := range records {
+		resp.Total.add(u)
+		add(providers, u.Provider, u)
+		add(profiles, u.Profile, u)
+		add(days, u.Day, u)
+	}
+	resp.Providers = sortedUsage(providers)
+	resp.Profiles = sortedUsage(profiles)
+	resp.Days = sortedUsage(days)
+	return resp
+}
+
+// sortedUsage returns the totals in m sorted by name.
+func sortedUsage(m map[string]*usageTotals) []namedUsage {
+	out := make([]namedUsage, 0, len(m))
+	for name, t := range m {
+		out = append(out, namedUsage{Name: name, usageTotals: *t})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,236 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+// TokenFile holds the web UI's session token inside the config dir. It is
+// generated on first start and readable only by the user.
+const TokenFile = "web-token"
+
+// TokenHeader carries the session token for API clients that don't use the
+// session cookie.
+const TokenHeader = "X-OpenCC-Token"
+
+const (
+	sessionCookie = "opencc_session"
+	csrfCookie    = "opencc_csrf"          // readable by the UI, which echoes it in csrfHeader
+	csrfHeader    = "X-CSRF-Token"         // required with the session cookie on mutating requests
+	tokenParam    = "token"                // query parameter of the sign-in link opencc opens
+	loginDelay    = 500 * time.Millisecond // slows down password guessing
+)
+
+// LoadToken returns the session token stored in dir, generating and saving
+// one if there is none yet.
+func LoadToken(dir string) (string, error) {
+	path := filepath.Join(dir, TokenFile)
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("save web token: %w", err)
+	}
+	return token, nil
+}
+
+// LoginURL returns the address of the web UI at base that signs the browser
+// in with token.
+func LoginURL(base, token string) string {
+	return base + "/?" + tokenParam + "=" + url.QueryEscape(token)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// csrfToken is the value mutating requests authenticated by the session
+// cookie must send in csrfHeader. It is derived from the session token, so
+// it needs no server state, and a cross-site page can't read it.
+func (s *Server) csrfToken() string {
+	mac := hmac.New(sha256.New, []byte(s.token))
+	mac.Write([]byte("csrf"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validToken reports whether v is the session token.
+func (s *Server) validToken(v string) bool {
+	return v != "" && subtle.ConstantTimeCompare([]byte(v), []byte(s.token)) == 1
+}
+
+// requireAuth lets API requests through only with the session token, in
+// TokenHeader, as a bearer token, or in the session cookie. Requests using
+// the cookie must also pass the CSRF checks when they change anything. The
+// page itself and the auth endpoints are public, as is the health endpoint
+// unless remote clients ask; opening the page with the token in the query
+// signs the browser in.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			if token := r.URL.Query().Get(tokenParam); token != "" {
+				if s.validToken(token) {
+					s.setSessionCookies(w, r)
+				}
+				// Drop the token from the address bar and history
+				q := r.URL.Query()
+				q.Del(tokenParam)
+				target := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+				http.Redirect(w, r, target.String(), http.StatusSeeOther)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/auth", "/api/v1/auth/login", "/api/v1/auth/logout":
+			next.ServeHTTP(w, r)
+			return
+		case "/api/v1/health":
+			// Readiness checks run locally; remote clients must sign in
+			if !s.remote || localClient(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if s.validToken(r.Header.Get(TokenHeader)) || s.validToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+			// Browsers can't attach these headers cross-site
+			next.ServeHTTP(w, r)
+			return
+		}
+		c, err := r.Cookie(sessionCookie)
+		if err != nil || !s.validToken(c.Value) {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !safeMethod(r.Method) {
+			if !sameOrigin(r) {
+				writeError(w, http.StatusForbidden, "cross-origin request refused")
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeader)), []byte(s.csrfToken())) != 1 {
+				writeError(w, http.StatusForbidden, "missing or invalid CSRF token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// sameOrigin reports whether r's Origin header, if any, names the host it
+// was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// setSessionCookies signs the browser in: the session cookie authenticates
+// it and the CSRF cookie gives the UI the token to echo.
+func (s *Server) setSessionCookies(w http.ResponseWriter, r *http.Request) {
+	secure := r.TLS != nil
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie, Value: s.token, Path: "/",
+		HttpOnly: true, Secure: secure, SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name: csrfCookie, Value: s.csrfToken(), Path: "/",
+		Secure: secure, SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// authResponse is the JSON shape of the auth status endpoint.
+type authResponse struct {
+	Authenticated bool `json:"authenticated"`
+	Password      bool `json:"password"` // signing in with a password is possible
+}
+
+// handleAuth handles GET /api/v1/auth: whether the browser is signed in.
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	resp := authResponse{Password: config.HasWebPassword()}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		resp.Authenticated = s.validToken(c.Value)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleLogin handles POST /api/v1/auth/login with the web UI password.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, "cross-origin request refused")
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if !config.HasWebPassword() {
+		writeError(w, http.StatusForbidden, "no password is set; open the link 'opencc web' prints, or set one with 'opencc web password'")
+		return
+	}
+	if !config.CheckWebPassword(req.Password) {
+		time.Sleep(loginDelay)
+		writeError(w, http.StatusUnauthorized, "wrong password")
+		return
+	}
+	s.setSessionCookies(w, r)
+	writeJSON(w, http.StatusOK, authResponse{Authenticated: true, Password: true})
+}
+
+// handleLogout handles POST /api/v1/auth/logout.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	for _, name := range []string{sessionCookie, csrfCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1})
+	}
+	writeJSON(w, http.StatusOK, authResponse{Password: config.HasWebPassword()})
+}
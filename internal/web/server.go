@@ -2,12 +2,14 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -21,32 +23,66 @@ type Server struct {
 	logger     *log.Logger
 	version    string
 	port       int
+	token      string          // session token API requests must present
+	streams    context.Context // canceled on shutdown, ending open log streams
+
+	remote bool                   // bound off loopback
+	allow  []*net.IPNet           // clients let in besides loopback; nil = any
+	tls    *config.ProxyTLSConfig // nil = plain HTTP
+	err    error                  // invalid access settings, reported by Start
+
+	tester ProviderTester // nil = provider tests unavailable
 }
 
-// NewServer creates a new web server bound to 127.0.0.1 on the configured port.
-// If portOverride > 0, it is used instead of the configured port.
+// NewServer creates a new web server on the configured port, bound to
+// 127.0.0.1 unless web_access says otherwise. If portOverride > 0, it is used
+// instead of the configured port.
 func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	port := config.GetWebPort()
 	if portOverride > 0 {
 		port = portOverride
 	}
+	token, err := LoadToken(config.ConfigDirPath())
+	if err != nil {
+		// Still locked down; only this process knows the token
+		logger.Printf("Failed to load web token, using a temporary one: %v", err)
+		token, _ = newToken()
+	}
+	streams, stopStreams := context.WithCancel(context.Background())
 	s := &Server{
 		logger:  logger,
 		version: version,
 		port:    port,
+		token:   token,
+		streams: streams,
 	}
+	access := config.GetWebAccess()
+	s.err = s.setupAccess(access)
 
 	mux := http.NewServeMux()
 
 	// API routes
 	mux.HandleFunc("/api/v1/health", s.handleHealth)
+	mux.HandleFunc("/api/v1/auth", s.handleAuth)
+	mux.HandleFunc("/api/v1/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/v1/auth/logout", s.handleLogout)
 	mux.HandleFunc("/api/v1/reload", s.handleReload)
 	mux.HandleFunc("/api/v1/providers", s.handleProviders)
 	mux.HandleFunc("/api/v1/providers/", s.handleProvider)
+	mux.HandleFunc("/api/v1/providers/health", s.handleProvidersHealth)
 	mux.HandleFunc("/api/v1/profiles", s.handleProfiles)
 	mux.HandleFunc("/api/v1/profiles/", s.handleProfile)
 	mux.HandleFunc("/api/v1/logs", s.handleLogs)
+	mux.HandleFunc("/api/v1/logs/stream", s.handleLogsStream)
+	mux.HandleFunc("/api/v1/clients", s.handleClients)
+	mux.HandleFunc("/api/v1/metrics", s.handleMetrics)
+	mux.HandleFunc("/api/v1/stats", s.handleStats)
+	mux.HandleFunc("/api/v1/requests/", s.handleRequestTrace)
+	mux.HandleFunc("/api/v1/map", s.handleMap)
+	mux.HandleFunc("/api/v1/sessions", s.handleSessions)
+	mux.HandleFunc("/api/v1/sessions/", s.handleSession)
 	mux.HandleFunc("/api/v1/settings", s.handleSettings)
+	mux.HandleFunc("/api/v1/config/validate", s.handleConfigValidate)
 	mux.HandleFunc("/api/v1/bindings", s.handleBindings)
 	mux.HandleFunc("/api/v1/bindings/", s.handleBinding)
 
@@ -56,9 +92,10 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	mux.Handle("/", fileServer)
 
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf("127.0.0.1:%d", port),
-		Handler: s.securityHeaders(mux),
+		Addr:    net.JoinHostPort(listenHost(access), strconv.Itoa(port)),
+		Handler: s.securityHeaders(s.allowClients(s.requireAuth(mux))),
 	}
+	s.httpServer.RegisterOnShutdown(stopStreams)
 
 	return s
 }
@@ -66,11 +103,26 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 // Start begins listening. Returns an error if the port is already in use.
 // Returns nil on graceful shutdown (http.ErrServerClosed).
 func (s *Server) Start() error {
+	if s.err != nil {
+		return s.err
+	}
 	ln, err := net.Listen("tcp", s.httpServer.Addr)
 	if err != nil {
 		return fmt.Errorf("port %d is already in use: %w", s.port, err)
 	}
-	s.logger.Printf("Web server listening on %s", s.httpServer.Addr)
+	scheme := "http"
+	if s.tls != nil {
+		cfg, _, err := proxy.ServerTLS(s.tls)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, cfg)
+		scheme = "https"
+	} else if s.remote {
+		s.logger.Printf("Warning: web UI accepts remote connections over plain HTTP; set web_access.tls")
+	}
+	s.logger.Printf("Web server listening on %s://%s", scheme, s.httpServer.Addr)
 	err = s.httpServer.Serve(ln)
 	if err == http.ErrServerClosed {
 		return nil // graceful shutdown
@@ -78,6 +130,11 @@ func (s *Server) Start() error {
 	return err
 }
 
+// Token returns the session token API requests must present.
+func (s *Server) Token() string {
+	return s.token
+}
+
 // Shutdown gracefully stops the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
@@ -94,15 +151,27 @@ func (s *Server) securityHeaders(next http.Handler) http.Handler {
 
 // --- health & reload ---
 
+// healthResponse is the JSON shape returned by the health endpoint.
+type healthResponse struct {
+	Status     string                      `json:"status"`
+	Version    string                      `json:"version"`
+	RateLimits map[string]*proxy.RateLimit `json:"rate_limits,omitempty"` // provider → latest reported rate limits
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{
-		"status":  "ok",
-		"version": s.version,
-	})
+	resp := healthResponse{Status: "ok", Version: s.version}
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		limits, err := db.RateLimits()
+		if err != nil {
+			s.logger.Printf("Failed to query rate limits: %v", err)
+		}
+		resp.RateLimits = limits
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
@@ -152,15 +221,22 @@ func WaitForReady(ctx context.Context, portOverride int) error {
 	if portOverride > 0 {
 		port = portOverride
 	}
-	url := fmt.Sprintf("http://127.0.0.1:%d/api/v1/health", port)
-	client := &http.Client{Timeout: 500 * time.Millisecond}
+	url := BaseURL(port) + "/api/v1/health"
+	token, _ := LoadToken(config.ConfigDirPath())
+	client := &http.Client{
+		Timeout: 500 * time.Millisecond,
+		// Only readiness is checked, so a self-signed certificate is fine
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		resp, err := client.Get(url)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		req.Header.Set(TokenHeader, token)
+		resp, err := client.Do(req)
 		if err == nil {
 			resp.Body.Close()
 			if resp.StatusCode == http.StatusOK {
@@ -179,10 +255,54 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters
-	query := r.URL.Query()
+	filter := logFilterFromQuery(r.URL.Query())
+	if filter.Limit <= 0 {
+		filter.Limit = 100 // default limit
+	}
+
+	// Try in-memory logger first (same process as proxy), then SQLite (cross-process).
+	var entries []proxy.LogEntry
+	var providers, clients []string
+
+	logger := proxy.GetGlobalLogger()
+	if logger != nil && logger.HasEntries() {
+		entries = logger.GetEntries(filter)
+		providers = logger.GetProviders()
+		clients = logger.GetClients()
+	} else if db := proxy.GetGlobalLogDB(); db != nil {
+		var err error
+		entries, err = db.Query(filter)
+		if err != nil {
+			s.logger.Printf("Failed to query log database: %v", err)
+			entries = []proxy.LogEntry{}
+		}
+		providers, err = db.GetProviders()
+		if err != nil {
+			s.logger.Printf("Failed to query log providers: %v", err)
+			providers = []string{}
+		}
+		clients, err = db.GetClients()
+		if err != nil {
+			s.logger.Printf("Failed to query log clients: %v", err)
+			clients = []string{}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, proxy.LogsResponse{
+		Entries:   entries,
+		Total:     len(entries),
+		Providers: providers,
+		Clients:   clients,
+	})
+}
+
+// logFilterFromQuery returns the log filter given by the query parameters
+// of the logs endpoints.
+func logFilterFromQuery(query url.Values) proxy.LogFilter {
 	filter := proxy.LogFilter{
-		Provider: query.Get("provider"),
+		RequestID: query.Get("request_id"),
+		Provider:  query.Get("provider"),
+		Client:    query.Get("client"),
 	}
 
 	if query.Get("errors_only") == "true" {
@@ -216,36 +336,94 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 			filter.Limit = l
 		}
 	}
+	return filter
+}
 
-	if filter.Limit <= 0 {
-		filter.Limit = 100 // default limit
+// handleLogsStream pushes new log entries to the client as server-sent
+// events, one JSON LogEntry per event, until it disconnects. It reads the
+// log database, so it sees the entries of every proxy.
+func (s *Server) handleLogsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
 	}
 
-	// Try in-memory logger first (same process as proxy), then SQLite (cross-process).
-	var entries []proxy.LogEntry
-	var providers []string
+	// A reconnecting EventSource resumes after the last entry it received
+	var afterID int64 = -1
+	if id, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil && id >= 0 {
+		afterID = id
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.streams, cancel)
+	defer stop()
+	filter := logFilterFromQuery(r.URL.Query())
+	filter.Limit = 0
+	streamLogs(ctx, w, db, filter, afterID, logStreamInterval)
+}
 
-	logger := proxy.GetGlobalLogger()
-	if logger != nil && logger.HasEntries() {
-		entries = logger.GetEntries(filter)
-		providers = logger.GetProviders()
-	} else if db := proxy.GetGlobalLogDB(); db != nil {
+// logStreamInterval is how often the log stream checks for new entries.
+var logStreamInterval = time.Second
+
+// logStreamKeepAlive is how often an idle log stream sends a comment, so
+// proxies and browsers keep the connection open.
+const logStreamKeepAlive = 15 * time.Second
+
+// streamLogs writes the entries matching filter logged after the one with ID
+// afterID, or after now if afterID is negative, as server-sent events, every
+// interval until ctx is done.
+func streamLogs(ctx context.Context, w http.ResponseWriter, db *proxy.LogDB, filter proxy.LogFilter, afterID int64, interval time.Duration) {
+	if afterID < 0 {
 		var err error
-		entries, err = db.Query(filter)
-		if err != nil {
-			s.logger.Printf("Failed to query log database: %v", err)
-			entries = []proxy.LogEntry{}
+		if _, afterID, err = db.Tail(proxy.LogFilter{Limit: 1}, 0); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to query log database")
+			return
 		}
-		providers, err = db.GetProviders()
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher := w.(http.Flusher)
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	idle := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		entries, lastID, err := db.Tail(filter, afterID)
 		if err != nil {
-			s.logger.Printf("Failed to query log providers: %v", err)
-			providers = []string{}
+			return
+		}
+		afterID = lastID
+		if len(entries) == 0 {
+			if idle += interval; idle < logStreamKeepAlive {
+				continue
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
 		}
+		for _, e := range entries {
+			data, err := e.ToJSON()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", lastID, data)
+		}
+		idle = 0
+		flusher.Flush()
 	}
-
-	writeJSON(w, http.StatusOK, proxy.LogsResponse{
-		Entries:   entries,
-		Total:     len(entries),
-		Providers: providers,
-	})
 }
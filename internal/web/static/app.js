@@ -8,6 +8,7 @@
     server: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><rect x="2" y="2" width="20" height="8" rx="2"/><rect x="2" y="14" width="20" height="8" rx="2"/><circle cx="6" cy="6" r="1"/><circle cx="6" cy="18" r="1"/></svg>',
     layers: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M12 2L2 7l10 5 10-5-10-5z"/><path d="M2 17l10 5 10-5"/><path d="M2 12l10 5 10-5"/></svg>',
     edit: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M11 4H4a2 2 0 0 0-2 2v14a2 2 0 0 0 2 2h14a2 2 0 0 0 2-2v-7"/><path d="M18.5 2.5a2.121 2.121 0 0 1 3 3L12 15l-4 1 1-4 9.5-9.5z"/></svg>',
+    activity: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polyline points="22 12 18 12 15 21 9 3 6 12 2 12"/></svg>',
     trash: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polyline points="3 6 5 6 21 6"/><path d="M19 6l-1 14a2 2 0 0 1-2 2H8a2 2 0 0 1-2-2L5 6"/><path d="M10 11v6"/><path d="M14 11v6"/><path d="M9 6V4a1 1 0 0 1 1-1h4a1 1 0 0 1 1 1v2"/></svg>',
     plus: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><line x1="12" y1="5" x2="12" y2="19"/><line x1="5" y1="12" x2="19" y2="12"/></svg>',
     chevronUp: '<svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polyline points="18 15 12 9 6 15"/></svg>',
@@ -22,6 +23,7 @@
 
   // --- State ---
   var providers = [];
+  var providerHealth = {};
   var profiles = [];
   var allProviderNames = [];
   var editingProvider = null;
@@ -49,12 +51,44 @@
     document.getElementById("btn-reload").addEventListener("click", reloadConfig);
     document.getElementById("atp-skip").addEventListener("click", function() { closeModal("add-to-profiles-modal") });
     document.getElementById("atp-confirm").addEventListener("click", confirmAddToProfiles);
+    document.getElementById("login-form").addEventListener("submit", function(e) { e.preventDefault(); submitLogin(); });
     loadHealth();
+    api("GET", "/auth").then(function(data) {
+      if (data.authenticated) loadAll();
+      else showLogin(data.password);
+    }).catch(function() { showLogin(false) });
+  }
+
+  function loadAll() {
     loadProviders();
     loadProfiles();
     loadSettings();
   }
 
+  // --- Sign-in ---
+  function showLogin(password) {
+    document.getElementById("login-form").style.display = password ? "block" : "none";
+    document.getElementById("login-hint").textContent = password
+      ? "Enter the web UI password, or open the link printed by 'opencc web'."
+      : "Open the link printed by 'opencc web', or set a password with 'opencc web password'.";
+    openModal("login-modal");
+  }
+
+  function submitLogin() {
+    var input = document.getElementById("login-password");
+    api("POST", "/auth/login", { password: input.value }).then(function() {
+      input.value = "";
+      closeModal("login-modal");
+      loadAll();
+    }).catch(function(err) { toast(err.message, "error") });
+  }
+
+  // csrfToken returns the token mutating requests echo from the CSRF cookie.
+  function csrfToken() {
+    var m = document.cookie.match(/(?:^|; )opencc_csrf=([^;]*)/);
+    return m ? decodeURIComponent(m[1]) : "";
+  }
+
   // --- Navigation (hash-based routing) ---
   function setupNav() {
     document.querySelectorAll(".nav-item[data-tab]").forEach(function(btn) {
@@ -80,9 +114,11 @@
     if (window.location.hash !== "#" + tab) {
       history.replaceState(null, "", "#" + tab);
     }
-    // Load logs when switching to logs tab
+    // Load logs when switching to logs tab; stop the live feed when leaving it
     if (tab === "logs") {
       loadLogs();
+    } else {
+      stopLiveLogs();
     }
     // Load settings when switching to settings tab
     if (tab === "settings") {
@@ -100,6 +136,7 @@
   // --- Modals ---
   function setupModals() {
     document.querySelectorAll(".modal-overlay").forEach(function(overlay) {
+      if (overlay.id === "login-modal") return; // stays open until signed in
       overlay.querySelectorAll(".modal-close, .modal-cancel").forEach(function(btn) {
         btn.addEventListener("click", function() { closeModal(overlay) });
       });
@@ -121,6 +158,12 @@
     document.getElementById("prov-back").addEventListener("click", function() { switchTab("providers") });
     document.getElementById("prov-save").addEventListener("click", function(e) { submitProvider(e) });
 
+    // Local servers default to Ollama's OpenAI-compatible endpoint
+    document.getElementById("prov-type").addEventListener("change", function() {
+      var baseURL = document.getElementById("prov-base-url");
+      if (this.value === "local" && !baseURL.value.trim()) baseURL.value = "http://localhost:11434/v1";
+    });
+
     // Env tabs
     document.querySelectorAll(".env-tab").forEach(function(tab) {
       tab.addEventListener("click", function() {
@@ -474,9 +517,12 @@
 
   // --- API helpers ---
   function api(method, path, body) {
-    var opts = { method: method, headers: { "Content-Type": "application/json" } };
+    var opts = { method: method, headers: { "Content-Type": "application/json", "X-CSRF-Token": csrfToken() } };
     if (body) opts.body = JSON.stringify(body);
     return fetch(API + path, opts).then(function(r) {
+      if (r.status === 401 && path !== "/auth/login") {
+        api("GET", "/auth").then(function(data) { showLogin(data.password) });
+      }
       return r.json().then(function(data) {
         if (!r.ok) throw new Error(data.error || "Request failed");
         return data;
@@ -506,9 +552,33 @@
       providers = data || [];
       allProviderNames = providers.map(function(p) { return p.name });
       renderProviders();
+      loadProviderHealth();
     }).catch(function(err) { toast("Failed to load providers: " + err.message, "error") });
   }
 
+  function loadProviderHealth() {
+    api("GET", "/providers/health").then(function(data) {
+      providerHealth = {};
+      (data.providers || []).forEach(function(h) { providerHealth[h.name] = h });
+      renderProviders();
+    }).catch(function() {});
+  }
+
+  function healthDot(name) {
+    var h = providerHealth[name];
+    if (!h) return "";
+    var cls = "ok", title = "Healthy";
+    if (h.unavailable) {
+      cls = "off";
+      title = "Unavailable: " + h.unavailable;
+    } else if (!h.healthy) {
+      cls = "down";
+      title = (h.auth_failed ? "Auth failed" : "Failing") + ", retry at " + new Date(h.retry_at).toLocaleTimeString();
+    }
+    if (h.errors > 0) title += " (" + h.errors + "/" + h.requests + " recent errors)";
+    return '<span class="health-dot ' + cls + '" title="' + esc(title) + '"></span>';
+  }
+
   function renderProviders() {
     var container = document.getElementById("providers-list");
     if (providers.length === 0) {
@@ -521,16 +591,17 @@
     }
     var html = '<div class="card-grid">';
     providers.forEach(function(p) {
-      var typeLabel = (p.type === "openai") ? "OpenAI" : "Anthropic";
+      var typeLabel = {openai: "OpenAI", "openai-responses": "OpenAI Responses", "azure-openai": "Azure OpenAI", local: "Local", gemini: "Gemini", bedrock: "Bedrock", vertex: "Vertex AI"}[p.type] || "Anthropic";
       html += '<div class="card" data-provider="' + esc(p.name) + '">';
       html += '<div class="card-icon teal">' + ICONS.server + '</div>';
       html += '<div class="card-body">';
-      html += '<div class="card-title">' + esc(p.name) + ' <span class="badge badge-muted">' + typeLabel + '</span></div>';
+      html += '<div class="card-title">' + healthDot(p.name) + esc(p.name) + ' <span class="badge badge-muted">' + typeLabel + '</span></div>';
       html += '<div class="card-meta">' + esc(p.base_url);
       if (p.model) html += ' &middot; ' + esc(p.model);
       html += '</div>';
       html += '</div>';
       html += '<div class="card-actions">';
+      html += '<button class="btn-icon" data-action="test-provider" data-name="' + esc(p.name) + '" title="Test connectivity">' + ICONS.activity + '</button>';
       html += '<button class="btn-icon danger" data-action="delete-provider" data-name="' + esc(p.name) + '" title="Delete">' + ICONS.trash + '</button>';
       html += '</div>';
       html += '</div>';
@@ -541,6 +612,12 @@
     container.querySelectorAll(".card[data-provider]").forEach(function(card) {
       card.addEventListener("click", function() { editProvider(card.dataset.provider) });
     });
+    container.querySelectorAll('[data-action="test-provider"]').forEach(function(btn) {
+      btn.addEventListener("click", function(e) {
+        e.stopPropagation();
+        testProvider(btn.dataset.name, btn);
+      });
+    });
     container.querySelectorAll('[data-action="delete-provider"]').forEach(function(btn) {
       btn.addEventListener("click", function(e) {
         e.stopPropagation();
@@ -549,6 +626,19 @@
     });
   }
 
+  function testProvider(name, btn) {
+    btn.disabled = true;
+    toast('Testing "' + name + '"...');
+    api("POST", "/providers/" + encodeURIComponent(name) + "/test").then(function(r) {
+      if (r.ok) {
+        toast('"' + name + '" OK: ' + r.status + " in " + r.latency_ms + " ms (" + (r.model || "unknown model") + ")");
+      } else {
+        toast('"' + name + '" failed: ' + (r.error || "status " + r.status), "error");
+      }
+      loadProviderHealth();
+    }).catch(function(err) { toast(err.message, "error") }).then(function() { btn.disabled = false });
+  }
+
   function openAddProvider() {
     editingProvider = null;
     document.getElementById("prov-title").textContent = "Add Provider";
@@ -674,17 +764,21 @@
     }
     var html = '<div class="card-grid">';
     profiles.forEach(function(p) {
-      var provs = p.providers || [];
+      var own = p.providers || [];
+      var provs = p.effective_providers || own;
       html += '<div class="card" data-profile="' + esc(p.name) + '">';
       html += '<div class="card-icon lavender">' + ICONS.layers + '</div>';
       html += '<div class="card-body">';
       html += '<div class="card-title">' + esc(p.name);
       if (p.name === "default") html += ' <span class="badge badge-muted">default</span>';
+      if (p.extends) html += ' <span class="badge badge-muted">extends ' + esc(p.extends) + '</span>';
       html += '</div>';
       if (provs.length > 0) {
         html += '<div class="card-badges">';
         provs.forEach(function(n) {
-          html += '<span class="badge badge-teal">' + esc(n) + '</span>';
+          // Inherited providers are muted
+          var cls = own.indexOf(n) !== -1 ? "badge-teal" : "badge-muted";
+          html += '<span class="badge ' + cls + '">' + esc(n) + '</span>';
         });
         if (p.routing && Object.keys(p.routing).length > 0) {
           html += '<span class="badge badge-lavender">' + Object.keys(p.routing).length + ' route(s)</span>';
@@ -722,6 +816,7 @@
     document.getElementById("pe-name").value = "";
     document.getElementById("pe-name").disabled = false;
     document.getElementById("pe-name-group").style.display = "block";
+    buildExtendsSelect(null, "");
     buildProviderSelector([]);
     buildRoutingSection(null);
     switchTab("profile-edit");
@@ -736,6 +831,7 @@
     document.getElementById("pe-name").value = name;
     document.getElementById("pe-name").disabled = true;
     document.getElementById("pe-name-group").style.display = "none";
+    buildExtendsSelect(name, p.extends || "");
     buildProviderSelector(p.providers || []);
     buildRoutingSection(p.routing || null);
     switchTab("profile-edit");
@@ -751,6 +847,19 @@
     });
   }
 
+  function buildExtendsSelect(name, current) {
+    var select = document.getElementById("pe-extends");
+    select.innerHTML = '<option value="">(none)</option>';
+    profiles.forEach(function(p) {
+      if (p.name === name) return;
+      var opt = document.createElement("option");
+      opt.value = p.name;
+      opt.textContent = p.name;
+      if (p.name === current) opt.selected = true;
+      select.appendChild(opt);
+    });
+  }
+
   function buildProviderSelector(selected) {
     var container = document.getElementById("pe-providers");
     var ordered = selected.slice();
@@ -856,9 +965,11 @@
 
   // --- Routing UI ---
   var SCENARIOS = [
+    { key: "webSearch", label: "Web Search", desc: "Requests with web_search tools" },
     { key: "think", label: "Think", desc: "Thinking mode requests" },
     { key: "image", label: "Image", desc: "Requests with images" },
-    { key: "longContext", label: "Long Context", desc: ">32k chars total" }
+    { key: "longContext", label: "Long Context", desc: ">32k chars total" },
+    { key: "background", label: "Background", desc: "Haiku model requests" }
   ];
 
   function buildRoutingSection(routing) {
@@ -979,16 +1090,17 @@
     e.preventDefault();
     var selected = getSelectedProviders();
     var routing = getRoutingConfig();
+    var extendsProfile = document.getElementById("pe-extends").value;
 
     var promise;
     if (editingProfile) {
-      var body = { providers: selected };
+      var body = { extends: extendsProfile, providers: selected };
       if (routing) body.routing = routing;
       promise = api("PUT", "/profiles/" + encodeURIComponent(editingProfile), body);
     } else {
       var name = document.getElementById("pe-name").value.trim();
       if (!name) { toast("Name is required", "error"); return; }
-      var body = { name: name, providers: selected };
+      var body = { name: name, extends: extendsProfile, providers: selected };
       if (routing) body.routing = routing;
       promise = api("POST", "/profiles", body);
     }
@@ -1078,20 +1190,29 @@
 
   // --- Logs ---
   var logsProviders = [];
+  var logsClients = [];
+  var logsEntries = [];
+  var logsStream = null; // EventSource of the live feed, while it is on
+  var maxLiveLogs = 500;
 
   function setupLogs() {
     document.getElementById("btn-refresh-logs").addEventListener("click", loadLogs);
+    document.getElementById("btn-live-logs").addEventListener("click", toggleLiveLogs);
     document.getElementById("logs-provider-filter").addEventListener("change", loadLogs);
+    document.getElementById("logs-client-filter").addEventListener("change", loadLogs);
     document.getElementById("logs-type-filter").addEventListener("change", loadLogs);
     document.getElementById("logs-status-filter").addEventListener("change", loadLogs);
   }
 
-  function loadLogs() {
+  function logsParams() {
     var params = new URLSearchParams();
 
     var provider = document.getElementById("logs-provider-filter").value;
     if (provider) params.set("provider", provider);
 
+    var client = document.getElementById("logs-client-filter").value;
+    if (client) params.set("client", client);
+
     var logType = document.getElementById("logs-type-filter").value;
     if (logType === "errors") params.set("errors_only", "true");
 
@@ -1105,19 +1226,65 @@
     } else if (statusFilter) {
       params.set("status_code", statusFilter);
     }
+    return params;
+  }
 
+  function loadLogs() {
+    var params = logsParams();
     params.set("limit", "200");
 
-    var url = "/logs" + (params.toString() ? "?" + params.toString() : "");
+    var url = "/logs?" + params.toString();
     api("GET", url).then(function(data) {
       logsProviders = data.providers || [];
+      logsClients = data.clients || [];
       updateProviderFilter();
-      renderLogs(data.entries || []);
+      updateClientFilter();
+      logsEntries = data.entries || [];
+      renderLogs(logsEntries);
+      // Filters changed: follow the new ones
+      if (logsStream) startLiveLogs();
     }).catch(function(err) {
       toast("Failed to load logs: " + err.message, "error");
     });
   }
 
+  function toggleLiveLogs() {
+    if (logsStream) {
+      stopLiveLogs();
+    } else {
+      startLiveLogs();
+    }
+  }
+
+  function startLiveLogs() {
+    if (logsStream) logsStream.close();
+    var params = logsParams().toString();
+    logsStream = new EventSource(API + "/logs/stream" + (params ? "?" + params : ""));
+    logsStream.onmessage = function(ev) {
+      var entry;
+      try { entry = JSON.parse(ev.data); } catch (e) { return; }
+      logsEntries.unshift(entry);
+      if (logsEntries.length > maxLiveLogs) logsEntries.length = maxLiveLogs;
+      renderLogs(logsEntries);
+    };
+    logsStream.onerror = function() {
+      // EventSource reconnects by itself unless the server refused the stream
+      if (logsStream && logsStream.readyState === EventSource.CLOSED) {
+        stopLiveLogs();
+        toast("Live logs unavailable", "error");
+      }
+    };
+    document.getElementById("btn-live-logs").classList.add("active");
+  }
+
+  function stopLiveLogs() {
+    if (logsStream) {
+      logsStream.close();
+      logsStream = null;
+    }
+    document.getElementById("btn-live-logs").classList.remove("active");
+  }
+
   function updateProviderFilter() {
     var select = document.getElementById("logs-provider-filter");
     var currentValue = select.value;
@@ -1131,6 +1298,19 @@
     });
   }
 
+  function updateClientFilter() {
+    var select = document.getElementById("logs-client-filter");
+    var currentValue = select.value;
+    select.innerHTML = '<option value="">All Clients</option>';
+    logsClients.forEach(function(c) {
+      var opt = document.createElement("option");
+      opt.value = c;
+      opt.textContent = c;
+      if (c === currentValue) opt.selected = true;
+      select.appendChild(opt);
+    });
+  }
+
   function renderLogs(entries) {
     var container = document.getElementById("logs-list");
 
@@ -1155,6 +1335,9 @@
       if (entry.provider) {
         html += '<span class="log-provider">' + esc(entry.provider) + '</span>';
       }
+      if (entry.client) {
+        html += '<span class="log-client">' + esc(entry.client) + '</span>';
+      }
       if (entry.status_code) {
         html += '<span class="log-status ' + statusClass + '">' + entry.status_code + '</span>';
       }
@@ -1242,7 +1425,7 @@
 
     fetch(API + "/settings", {
       method: "PUT",
-      headers: { "Content-Type": "application/json" },
+      headers: { "Content-Type": "application/json", "X-CSRF-Token": csrfToken() },
       body: JSON.stringify(payload)
     })
       .then(function(r) {
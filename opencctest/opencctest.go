@@ -0,0 +1,251 @@
+// Package opencctest provides fixtures for testing code built on the opencc
+// proxy: fake providers that play back scripted responses, a throwaway
+// config store and a ready-to-use proxy instance.
+//
+// A typical failover test scripts a failing primary and a healthy backup:
+//
+//	primary := opencctest.NewFakeProvider(t, opencctest.Status(http.StatusServiceUnavailable))
+//	backup := opencctest.NewFakeProvider(t, opencctest.OK())
+//	srv := opencctest.NewProxy(t, primary.Provider("primary"), backup.Provider("backup"))
+//	w := opencctest.PostMessages(srv, `{"model":"claude-sonnet-4","messages":[]}`)
+package opencctest
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/proxy"
+)
+
+// DefaultMessage is the body OK responds with: a minimal Anthropic
+// Messages API reply.
+const DefaultMessage = `{"id":"msg_test","type":"message","role":"assistant","model":"test-model",` +
+	`"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",` +
+	`"usage":{"input_tokens":10,"output_tokens":1}}`
+
+// Event is one server-sent event of a streamed response.
+type Event struct {
+	Name string // "event:" line; omitted when empty
+	Data string // "data:" line
+}
+
+// Response is one scripted reply of a FakeProvider.
+type Response struct {
+	Status     int           // HTTP status; 0 means 200
+	Header     http.Header   // extra response headers
+	Body       string        // response body, ignored when Events is set
+	Events     []Event       // streamed as text/event-stream when set
+	Latency    time.Duration // delay before the response headers are sent
+	EventDelay time.Duration // delay between streamed events
+}
+
+// OK returns a 200 response carrying DefaultMessage.
+func OK() Response {
+	return Response{Status: http.StatusOK, Body: DefaultMessage}
+}
+
+// Status returns a response with the given status and an Anthropic-style
+// error body.
+func Status(code int) Response {
+	return Response{
+		Status: code,
+		Body:   `{"type":"error","error":{"type":"api_error","message":"` + http.StatusText(code) + `"}}`,
+	}
+}
+
+// SSE returns a 200 streaming response made of the given events.
+func SSE(events ...Event) Response {
+	return Response{Status: http.StatusOK, Events: events}
+}
+
+// Request is a request received by a FakeProvider.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// FakeProvider is an HTTP server that stands in for an upstream provider.
+// It answers requests with its scripted responses in order and keeps
+// repeating the last one once the script runs out.
+type FakeProvider struct {
+	URL string
+
+	server    *httptest.Server
+	mu        sync.Mutex
+	responses []Response
+	requests  []Request
+}
+
+// NewFakeProvider starts a fake provider playing back responses. With no
+// responses it always answers OK. The server is closed when the test ends.
+func NewFakeProvider(t testing.TB, responses ...Response) *FakeProvider {
+	t.Helper()
+	f := &FakeProvider{responses: responses}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	f.URL = f.server.URL
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// Script replaces the remaining responses.
+func (f *FakeProvider) Script(responses ...Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = responses
+}
+
+// Requests returns the requests received so far.
+func (f *FakeProvider) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// Calls returns the number of requests received so far.
+func (f *FakeProvider) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// Provider returns a healthy proxy provider pointing at the fake.
+func (f *FakeProvider) Provider(name string) *proxy.Provider {
+	u, _ := url.Parse(f.URL)
+	return &proxy.Provider{
+		Name:    name,
+		Type:    config.ProviderTypeAnthropic,
+		BaseURL: u,
+		Token:   "test-token",
+		Model:   "test-model",
+		Healthy: true,
+	}
+}
+
+// ProviderConfig returns a provider config pointing at the fake, for
+// saving into a store.
+func (f *FakeProvider) ProviderConfig() *config.ProviderConfig {
+	return &config.ProviderConfig{
+		BaseURL:   f.URL,
+		AuthToken: "test-token",
+		Model:     "test-model",
+	}
+}
+
+// next records r and pops the response to send.
+func (f *FakeProvider) next(r *http.Request) Response {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   string(body),
+	})
+	if len(f.responses) == 0 {
+		return OK()
+	}
+	resp := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	return resp
+}
+
+func (f *FakeProvider) serve(w http.ResponseWriter, r *http.Request) {
+	resp := f.next(r)
+	if !sleep(r, resp.Latency) {
+		return
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if resp.Events == nil {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, resp.Body)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	for i, ev := range resp.Events {
+		if i > 0 && !sleep(r, resp.EventDelay) {
+			return
+		}
+		var sb strings.Builder
+		if ev.Name != "" {
+			sb.WriteString("event: " + ev.Name + "\n")
+		}
+		sb.WriteString("data: " + ev.Data + "\n\n")
+		io.WriteString(w, sb.String())
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// sleep waits for d, returning false if the client went away first.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// TempStore points HOME at a temporary directory and returns a fresh
+// default config store there. The previous store is restored when the test
+// ends. Tests using it must not run in parallel.
+func TempStore(t testing.TB) *config.Store {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+	return config.DefaultStore()
+}
+
+// NewProxy returns a proxy server that fails over across providers in
+// order. It logs nowhere and records nothing to the log database.
+func NewProxy(t testing.TB, providers ...*proxy.Provider) *proxy.ProxyServer {
+	t.Helper()
+	srv := proxy.NewProxyServer(providers, log.New(io.Discard, "", 0))
+	srv.LogDB = nil
+	srv.StructuredLogger = nil
+	return srv
+}
+
+// PostMessages sends body to the Messages API endpoint of h and returns
+// the recorded response.
+func PostMessages(h http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
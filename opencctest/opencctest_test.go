@@ -0,0 +1,103 @@
+package opencctest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/opencc/internal/config"
+)
+
+const testBody = `{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`
+
+func TestFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		primary     []Response
+		backup      []Response
+		wantStatus  int
+		wantPrimary int
+		wantBackup  int
+	}{
+		{"primary ok", []Response{OK()}, nil, http.StatusOK, 1, 0},
+		{"primary 500", []Response{Status(http.StatusInternalServerError)}, []Response{OK()}, http.StatusOK, 1, 1},
+		{"primary 429", []Response{Status(http.StatusTooManyRequests)}, []Response{OK()}, http.StatusOK, 1, 1},
+		{"all fail", []Response{Status(http.StatusBadGateway)}, []Response{Status(http.StatusServiceUnavailable)}, http.StatusBadGateway, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := NewFakeProvider(t, tt.primary...)
+			backup := NewFakeProvider(t, tt.backup...)
+			srv := NewProxy(t, primary.Provider("primary"), backup.Provider("backup"))
+
+			w := PostMessages(srv, testBody)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if primary.Calls() != tt.wantPrimary || backup.Calls() != tt.wantBackup {
+				t.Errorf("calls = %d/%d, want %d/%d", primary.Calls(), backup.Calls(), tt.wantPrimary, tt.wantBackup)
+			}
+		})
+	}
+}
+
+func TestFakeProviderScript(t *testing.T) {
+	f := NewFakeProvider(t, Status(http.StatusServiceUnavailable), OK())
+	want := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}
+	for i, code := range want {
+		resp, err := http.Post(f.URL+"/v1/messages", "application/json", strings.NewReader(testBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("request %d: status = %d, want %d", i, resp.StatusCode, code)
+		}
+	}
+	reqs := f.Requests()
+	if len(reqs) != 3 || reqs[0].Path != "/v1/messages" || reqs[0].Body != testBody {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestFakeProviderSSE(t *testing.T) {
+	f := NewFakeProvider(t, SSE(
+		Event{Name: "message_start", Data: `{"type":"message_start"}`},
+		Event{Name: "message_stop", Data: `{"type":"message_stop"}`},
+	))
+	srv := NewProxy(t, f.Provider("p"))
+
+	w := PostMessages(srv, testBody)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "event: message_start\ndata: {\"type\":\"message_start\"}\n\n") ||
+		!strings.Contains(body, "event: message_stop") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestFakeProviderLatency(t *testing.T) {
+	f := NewFakeProvider(t, Response{Body: DefaultMessage, Latency: 50 * time.Millisecond})
+	start := time.Now()
+	w := PostMessages(NewProxy(t, f.Provider("p")), testBody)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("elapsed = %v, want >= 50ms", elapsed)
+	}
+}
+
+func TestTempStore(t *testing.T) {
+	f := NewFakeProvider(t)
+	store := TempStore(t)
+	if err := store.SetProvider("fake", f.ProviderConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if p := config.GetProvider("fake"); p == nil || p.BaseURL != f.URL {
+		t.Errorf("provider not saved to the default store: %+v", p)
+	}
+}
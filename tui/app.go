@@ -7,6 +7,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type view int
@@ -171,7 +172,7 @@ func (m createFirstModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m createFirstModel) View() string {
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("  No providers configured. Create one to get started:"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("No providers configured. Create one to get started:")))
 	b.WriteString("\n\n")
 	b.WriteString(m.editor.view(m.width, m.height))
 	return b.String()
@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // configMainModel is the main config TUI showing providers and groups.
@@ -103,7 +104,7 @@ func (m configMainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m configMainModel) startDelete() (configMainModel, tea.Cmd) {
 	if m.inProviders {
 		if len(m.providers) <= 1 {
-			m.status = "Cannot delete the last provider"
+			m.status = i18n.T("Cannot delete the last provider")
 			return m, nil
 		}
 		m.deleting = true
@@ -241,7 +242,7 @@ func (m configMainModel) View() string {
 			Border(lipgloss.ThickBorder()).
 			BorderForeground(errorColor).
 			Padding(0, 1).
-			Render(errorStyle.Render(fmt.Sprintf(" Delete '%s'? (y/n) ", name)))
+			Render(errorStyle.Render(fmt.Sprintf(" %s ", i18n.Tf("Delete '%s'? (y/n)", name))))
 		b.WriteString(confirmBox)
 	} else {
 		if m.status != "" {
@@ -267,7 +268,7 @@ func (m configMainModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("↑↓ navigate • Enter edit • a add • d delete • q quit", width)
+	helpBar := RenderHelpBar(i18n.T("↑↓ navigate • Enter edit • a add • d delete • q quit"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -275,11 +276,11 @@ func (m configMainModel) View() string {
 
 func (m configMainModel) renderProviders() string {
 	var b strings.Builder
-	b.WriteString(sectionTitleStyle.Render(" Providers"))
+	b.WriteString(sectionTitleStyle.Render(" " + i18n.T("Providers")))
 	b.WriteString("\n")
 
 	if len(m.providers) == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
+		b.WriteString(dimStyle.Render("  " + i18n.T("(none)")))
 	} else {
 		// Header
 		header := fmt.Sprintf("  %-14s %-24s", "NAME", "MODEL")
@@ -314,11 +315,11 @@ func (m configMainModel) renderProviders() string {
 
 func (m configMainModel) renderGroups() string {
 	var b strings.Builder
-	b.WriteString(sectionTitleStyle.Render(" Groups"))
+	b.WriteString(sectionTitleStyle.Render(" " + i18n.T("Groups")))
 	b.WriteString("\n")
 
 	if len(m.groups) == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
+		b.WriteString(dimStyle.Render("  " + i18n.T("(none)")))
 	} else {
 		// Header
 		header := fmt.Sprintf("  %-14s %-20s", "NAME", "PROVIDERS")
@@ -666,7 +667,7 @@ func (s *addTypeSelector) View() string {
 
 	sidePadding := 2
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("  Add"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("Add")))
 	b.WriteString("\n\n")
 	for i, item := range s.items {
 		cursor := "  "
@@ -697,7 +698,7 @@ func (s *addTypeSelector) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Enter select • Esc back", width)
+	helpBar := RenderHelpBar(i18n.T("Enter select • Esc back"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
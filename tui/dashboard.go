@@ -2,11 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/dopejs/opencc/tui/components"
 )
 
@@ -18,6 +20,8 @@ type DashboardModel struct {
 	focusLeft   bool // true = sidebar focused, false = detail focused
 	selectedID  string
 	selectedType string // "provider", "profile", "binding"
+	undo        undoStack // deletions and reorders this session, undone with u
+	status      string    // outcome of the last undo, shown in the help bar
 
 	// Styles
 	borderStyle lipgloss.Style
@@ -166,9 +170,18 @@ func (m DashboardModel) Update(msg tea.Msg) (DashboardModel, tea.Cmd) {
 		// List size accounts for border (2) and internal padding (2)
 		m.list.SetSize(leftWidth-4, paneHeight-2)
 	case tea.KeyMsg:
+		m.status = ""
 		switch msg.String() {
 		case "esc", "q":
 			return m, func() tea.Msg { return DashboardBackMsg{} }
+		case "u":
+			reason, err := m.undo.undo()
+			if err != nil {
+				m.status = err.Error()
+			} else {
+				m.status = i18n.Tf("Undid the last change (%s)", reason)
+			}
+			m.refreshList()
 		case "tab":
 			m.focusLeft = !m.focusLeft
 		case "a":
@@ -205,12 +218,15 @@ func (m DashboardModel) Update(msg tea.Msg) (DashboardModel, tea.Cmd) {
 				if len(parts) == 2 {
 					switch parts[0] {
 					case "provider":
-						config.DeleteProviderByName(parts[1])
+						if config.DeleteProviderByName(parts[1]) == nil {
+							m.undo.record("delete provider " + parts[1])
+						}
 						m.refreshList()
 					case "profile":
 						if err := config.DeleteProfile(parts[1]); err != nil {
 							// Can't delete default profile - ignore
 						} else {
+							m.undo.record("delete profile " + parts[1])
 							m.refreshList()
 						}
 					case "binding":
@@ -308,7 +324,11 @@ func (m DashboardModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("a add • e edit • d delete • Tab switch pane • Esc back", m.width)
+	help := i18n.T("a add • e edit • d delete • u undo • Tab switch pane • Esc back")
+	if m.status != "" {
+		help = m.status + " • " + help
+	}
+	helpBar := RenderHelpBar(help, m.width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -316,7 +336,7 @@ func (m DashboardModel) View() string {
 
 func (m DashboardModel) renderDetail() string {
 	if m.selectedID == "" {
-		return m.labelStyle.Render("Select an item to view details")
+		return m.labelStyle.Render(i18n.T("Select an item to view details"))
 	}
 
 	parts := strings.SplitN(m.selectedID, ":", 2)
@@ -333,7 +353,7 @@ func (m DashboardModel) renderDetail() string {
 	case "provider":
 		p := config.GetProvider(itemName)
 		if p == nil {
-			return m.labelStyle.Render("Provider not found")
+			return m.labelStyle.Render(i18n.T("Provider not found"))
 		}
 
 		b.WriteString(m.titleStyle.Render("Provider: " + itemName))
@@ -351,7 +371,7 @@ func (m DashboardModel) renderDetail() string {
 		}
 		b.WriteString("\n\n")
 
-		b.WriteString(m.labelStyle.Render("Models:"))
+		b.WriteString(m.labelStyle.Render(i18n.T("Models:")))
 		b.WriteString("\n")
 		if p.Model != "" {
 			b.WriteString("  Default: " + p.Model + "\n")
@@ -369,6 +389,13 @@ func (m DashboardModel) renderDetail() string {
 			b.WriteString("  Sonnet: " + p.SonnetModel + "\n")
 		}
 
+		if flags := p.Capabilities.Describe(); len(flags) > 0 {
+			b.WriteString("\n")
+			b.WriteString(m.labelStyle.Render("Capabilities: "))
+			b.WriteString(m.valueStyle.Render(strings.Join(flags, ", ")))
+			b.WriteString("\n")
+		}
+
 		if len(p.EnvVars) > 0 {
 			b.WriteString("\n")
 			b.WriteString(m.labelStyle.Render(fmt.Sprintf("Env Vars: %d configured", len(p.EnvVars))))
@@ -394,7 +421,7 @@ func (m DashboardModel) renderDetail() string {
 		}
 		if len(usedIn) > 0 {
 			b.WriteString("\n\n")
-			b.WriteString(m.labelStyle.Render("Used in profiles:"))
+			b.WriteString(m.labelStyle.Render(i18n.T("Used in profiles:")))
 			b.WriteString("\n")
 			for _, u := range usedIn {
 				b.WriteString("  " + u + "\n")
@@ -404,7 +431,7 @@ func (m DashboardModel) renderDetail() string {
 	case "profile":
 		pc := config.GetProfileConfig(itemName)
 		if pc == nil {
-			return m.labelStyle.Render("Profile not found")
+			return m.labelStyle.Render(i18n.T("Profile not found"))
 		}
 
 		defaultProfile := config.GetDefaultProfile()
@@ -415,13 +442,24 @@ func (m DashboardModel) renderDetail() string {
 		b.WriteString(m.titleStyle.Render(title))
 		b.WriteString("\n\n")
 
-		b.WriteString(m.labelStyle.Render("Providers:"))
+		var inherited []string
+		if pc.Extends != "" {
+			b.WriteString(m.labelStyle.Render("Extends: "))
+			b.WriteString(m.valueStyle.Render(pc.Extends))
+			b.WriteString("\n\n")
+			inherited, _ = config.ReadProfileOrder(pc.Extends)
+		}
+
+		b.WriteString(m.labelStyle.Render(i18n.T("Providers:")))
 		b.WriteString("\n")
 		for i, prov := range pc.Providers {
 			pos := "fallback"
 			if i == 0 {
 				pos = "primary"
 			}
+			if slices.Contains(inherited, prov) {
+				pos += ", inherited"
+			}
 			b.WriteString(fmt.Sprintf("  %d. %s (%s)\n", i+1, prov, pos))
 		}
 
@@ -432,7 +470,7 @@ func (m DashboardModel) renderDetail() string {
 
 		if len(pc.Routing) > 0 {
 			b.WriteString("\n\n")
-			b.WriteString(m.labelStyle.Render("Scenario Routing:"))
+			b.WriteString(m.labelStyle.Render(i18n.T("Scenario Routing:")))
 			b.WriteString("\n")
 			for scenario, route := range pc.Routing {
 				if len(route.Providers) > 0 {
@@ -448,7 +486,7 @@ func (m DashboardModel) renderDetail() string {
 
 	case "binding":
 		binding := config.GetProjectBinding(itemName)
-		b.WriteString(m.titleStyle.Render("Project Binding"))
+		b.WriteString(m.titleStyle.Render(i18n.T("Project Binding")))
 		b.WriteString("\n\n")
 		b.WriteString(m.labelStyle.Render("Path: "))
 		b.WriteString(m.valueStyle.Render(itemName))
@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type listViewMode int
@@ -182,6 +183,7 @@ func (m detailListModel) buildDetail() string {
 			b.WriteString(fmt.Sprintf("Haiku Model:     %s\n", valueOrDash(p.config.HaikuModel)))
 			b.WriteString(fmt.Sprintf("Opus Model:      %s\n", valueOrDash(p.config.OpusModel)))
 			b.WriteString(fmt.Sprintf("Sonnet Model:    %s\n", valueOrDash(p.config.SonnetModel)))
+			b.WriteString(fmt.Sprintf("Capabilities:    %s\n", valueOrDash(strings.Join(p.config.Capabilities.Describe(), ", "))))
 		}
 		if p.fbIdx > 0 {
 			b.WriteString(fmt.Sprintf("\nDefault Group:   #%d\n", p.fbIdx))
@@ -279,7 +281,7 @@ func (m detailListModel) viewList() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("↑↓ navigate • Enter detail • q quit", width)
+	helpBar := RenderHelpBar(i18n.T("↑↓ navigate • Enter detail • q quit"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -287,11 +289,11 @@ func (m detailListModel) viewList() string {
 
 func (m detailListModel) renderProviderList() string {
 	var b strings.Builder
-	b.WriteString(sectionTitleStyle.Render(" Providers"))
+	b.WriteString(sectionTitleStyle.Render(" " + i18n.T("Providers")))
 	b.WriteString("\n")
 
 	if len(m.providers) == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
+		b.WriteString(dimStyle.Render("  " + i18n.T("(none)")))
 	} else {
 		header := fmt.Sprintf("  %-12s %-4s %-20s %s", "NAME", "GRP", "MODEL", "BASE URL")
 		b.WriteString(dimStyle.Render(header))
@@ -341,11 +343,11 @@ func (m detailListModel) renderProviderList() string {
 
 func (m detailListModel) renderGroupList() string {
 	var b strings.Builder
-	b.WriteString(sectionTitleStyle.Render(" Groups"))
+	b.WriteString(sectionTitleStyle.Render(" " + i18n.T("Groups")))
 	b.WriteString("\n")
 
 	if len(m.groups) == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
+		b.WriteString(dimStyle.Render("  " + i18n.T("(none)")))
 	} else {
 		header := fmt.Sprintf("  %-14s %-30s", "NAME", "PROVIDERS")
 		b.WriteString(dimStyle.Render(header))
@@ -392,7 +394,7 @@ func (m detailListModel) viewDetail() string {
 		Foreground(primaryColor).
 		Background(headerBgColor).
 		Padding(0, 2).
-		Render("🔍 Detail")
+		Render("🔍 " + i18n.T("Detail"))
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
@@ -422,7 +424,7 @@ func (m detailListModel) viewDetail() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Esc/Enter back • q quit", width)
+	helpBar := RenderHelpBar(i18n.T("Esc/Enter back • q quit"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -8,13 +8,15 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type editorField int
 
 const (
 	fieldName editorField = iota
-	fieldType  // API type: anthropic or openai
+	fieldPreset // built-in preset to start from; new providers only
+	fieldType   // API type, one of providerTypes
 	fieldBaseURL
 	fieldAuthToken
 	fieldModel
@@ -23,6 +25,7 @@ const (
 	fieldOpusModel
 	fieldSonnetModel
 	fieldEnvVars // special field - opens env vars editor
+	fieldHeaders // special field - opens the same editor for HTTP headers
 	fieldCount
 )
 
@@ -39,9 +42,30 @@ type editorModel struct {
 	codexEnvVars    map[string]string // Codex environment variables
 	opencodeEnvVars map[string]string // OpenCode environment variables
 	currentEnvCLI   int               // 0=claude, 1=codex, 2=opencode
-	envVarsEdit     bool              // true = editing env vars
+	headers         map[string]string // extra HTTP headers sent to the provider
+	envVarsEdit     bool              // true = editing env vars (or headers)
+	headersEdit     bool              // true = the env vars editor is editing headers
 	envVarsModel    envVarsEditorModel
-	providerType    int // 0 = anthropic, 1 = openai
+	providerType    int // index into providerTypes
+	preset          int // 1 + index into config.ProviderPresets; 0 = none
+}
+
+// providerTypes are the API types the type field cycles through.
+var providerTypes = []struct{ name, label string }{
+	{config.ProviderTypeAnthropic, "Anthropic Messages API"},
+	{config.ProviderTypeOpenAI, "OpenAI Chat Completions API"},
+	{config.ProviderTypeOpenAIResponses, "OpenAI Responses API"},
+	{config.ProviderTypeAzureOpenAI, "Azure OpenAI"},
+	{config.ProviderTypeLocal, "Local (Ollama, llama.cpp)"},
+	{config.ProviderTypeGemini, "Google Gemini API"},
+	{config.ProviderTypeBedrock, "Amazon Bedrock"},
+	{config.ProviderTypeVertex, "Google Vertex AI"},
+}
+
+// providerTypeBaseURLs are the base URLs filled in when a type is chosen for
+// a provider without one.
+var providerTypeBaseURLs = map[string]string{
+	config.ProviderTypeLocal: config.DefaultLocalBaseURL,
 }
 
 func newEditorModel(configName string) editorModel {
@@ -58,7 +82,9 @@ func newEditorModelWithPreset(configName string, presetName string) editorModel
 
 	fields[fieldName].Placeholder = "config name (e.g. work)"
 	fields[fieldName].Prompt = "  Name:             "
-	// fieldType is handled specially (not a textinput)
+	// fieldPreset and fieldType are handled specially (not textinputs)
+	fields[fieldPreset].Placeholder = ""
+	fields[fieldPreset].Prompt = ""
 	fields[fieldType].Placeholder = ""
 	fields[fieldType].Prompt = ""
 	fields[fieldBaseURL].Placeholder = "https://api.example.com"
@@ -83,6 +109,7 @@ func newEditorModelWithPreset(configName string, presetName string) editorModel
 		claudeEnvVars:   make(map[string]string),
 		codexEnvVars:    make(map[string]string),
 		opencodeEnvVars: make(map[string]string),
+		headers:         make(map[string]string),
 		currentEnvCLI:   0, // default to claude
 		providerType:    0, // default to anthropic
 	}
@@ -100,8 +127,10 @@ func newEditorModelWithPreset(configName string, presetName string) editorModel
 			m.fields[fieldOpusModel].SetValue(p.OpusModel)
 			m.fields[fieldSonnetModel].SetValue(p.SonnetModel)
 			// Load provider type
-			if p.GetType() == config.ProviderTypeOpenAI {
-				m.providerType = 1
+			for i, pt := range providerTypes {
+				if p.GetType() == pt.name {
+					m.providerType = i
+				}
 			}
 			// Load env vars for each CLI
 			if p.ClaudeEnvVars != nil {
@@ -124,23 +153,32 @@ func newEditorModelWithPreset(configName string, presetName string) editorModel
 					m.opencodeEnvVars[k] = v
 				}
 			}
+			for k, v := range p.Headers {
+				m.headers[k] = v
+			}
 		}
 		// Disable name field when editing
 		m.focus = fieldType
 	} else if presetName != "" {
 		// New provider with pre-filled name — skip to next field
 		m.fields[fieldName].SetValue(presetName)
-		m.focus = fieldType
+		m.focus = fieldPreset
 	} else {
 		m.focus = fieldName
 	}
 
-	if m.focus != fieldType && m.focus < fieldEnvVars {
+	if textField(m.focus) {
 		m.fields[m.focus].Focus()
 	}
 	return m
 }
 
+// textField reports whether f is edited with a textinput rather than
+// handled specially.
+func textField(f editorField) bool {
+	return f != fieldPreset && f != fieldType && f < fieldEnvVars
+}
+
 func (m editorModel) init() tea.Cmd {
 	return textinput.Blink
 }
@@ -159,6 +197,11 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 		switch msg := msg.(type) {
 		case envVarsExitMsg:
 			m.envVarsEdit = false
+			if m.headersEdit {
+				m.headersEdit = false
+				m.headers = msg.envVars
+				return m, nil
+			}
 			// Save to the current CLI's env vars
 			switch m.currentEnvCLI {
 			case 0:
@@ -182,27 +225,21 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 		case "esc":
 			return m, func() tea.Msg { return switchToListMsg{} }
 		case "tab", "down":
-			m.blurCurrentField()
-			m.focus = (m.focus + 1) % fieldCount
-			if m.editing != "" && m.focus == fieldName {
-				m.focus = fieldType
-			}
-			m.focusCurrentField()
+			m.moveFocus(1)
 			return m, textinput.Blink
 		case "shift+tab", "up":
-			m.blurCurrentField()
-			m.focus = (m.focus - 1 + fieldCount) % fieldCount
-			if m.editing != "" && m.focus == fieldName {
-				m.focus = fieldEnvVars
-			}
-			m.focusCurrentField()
+			m.moveFocus(-1)
 			return m, textinput.Blink
 		case "ctrl+s", "cmd+s":
 			return m.save()
 		case "enter":
+			if m.focus == fieldPreset {
+				m.nextPreset(1)
+				return m, nil
+			}
 			if m.focus == fieldType {
 				// Toggle type on enter
-				m.providerType = (m.providerType + 1) % 2
+				m.nextProviderType()
 				return m, nil
 			}
 			if m.focus == fieldEnvVars {
@@ -220,22 +257,33 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 				m.envVarsModel = newEnvVarsEditorModel(envVars)
 				return m, nil
 			}
+			if m.focus == fieldHeaders {
+				m.envVarsEdit = true
+				m.headersEdit = true
+				m.envVarsModel = newEnvVarsEditorModel(m.headers)
+				m.envVarsModel.headers = true
+				return m, nil
+			}
 			// Enter on last text field = save
 			if m.focus == fieldSonnetModel {
 				return m.save()
 			}
 			// Enter on non-last field = move to next
-			m.blurCurrentField()
-			m.focus = (m.focus + 1) % fieldCount
-			if m.editing != "" && m.focus == fieldName {
-				m.focus = fieldType
-			}
-			m.focusCurrentField()
+			m.moveFocus(1)
 			return m, textinput.Blink
 		case "left", "right":
+			// Cycle presets with left/right when focused on preset field
+			if m.focus == fieldPreset {
+				if msg.String() == "left" {
+					m.nextPreset(-1)
+				} else {
+					m.nextPreset(1)
+				}
+				return m, nil
+			}
 			// Toggle type with left/right when focused on type field
 			if m.focus == fieldType {
-				m.providerType = (m.providerType + 1) % 2
+				m.nextProviderType()
 				return m, nil
 			}
 			// Switch CLI with left/right when focused on env vars field
@@ -251,7 +299,7 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 	}
 
 	// Update focused field (only if it's a textinput field)
-	if m.focus != fieldType && m.focus < fieldEnvVars {
+	if textField(m.focus) {
 		var cmd tea.Cmd
 		m.fields[m.focus], cmd = m.fields[m.focus].Update(msg)
 		return m, cmd
@@ -259,14 +307,75 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 	return m, nil
 }
 
+// nextProviderType cycles the API type, filling in the new type's base URL
+// if the field is empty.
+func (m *editorModel) nextProviderType() {
+	m.providerType = (m.providerType + 1) % len(providerTypes)
+	baseURL := providerTypeBaseURLs[providerTypes[m.providerType].name]
+	if baseURL != "" && strings.TrimSpace(m.fields[fieldBaseURL].Value()) == "" {
+		m.fields[fieldBaseURL].SetValue(baseURL)
+	}
+}
+
+// nextPreset cycles the preset by step and fills in its type, base URL and
+// models. Going back to no preset leaves the form as it is.
+func (m *editorModel) nextPreset(step int) {
+	n := len(config.ProviderPresets) + 1
+	m.preset = (m.preset + step + n) % n
+	if m.preset == 0 {
+		return
+	}
+	p := config.ProviderPresets[m.preset-1]
+	for i, pt := range providerTypes {
+		if pt.name == p.Type {
+			m.providerType = i
+		}
+	}
+	m.fields[fieldBaseURL].SetValue(p.BaseURL)
+	m.fields[fieldModel].SetValue(p.Model)
+	m.fields[fieldReasoningModel].SetValue(p.ReasoningModel)
+	m.fields[fieldHaikuModel].SetValue(p.HaikuModel)
+	m.fields[fieldOpusModel].SetValue(p.OpusModel)
+	m.fields[fieldSonnetModel].SetValue(p.SonnetModel)
+}
+
+// selectPreset fills in the named preset and moves to the first field left
+// to fill: the name, or else the token.
+func (m *editorModel) selectPreset(name string) {
+	for i, p := range config.ProviderPresets {
+		if p.Name == name {
+			m.nextPreset(i + 1 - m.preset)
+		}
+	}
+	m.blurCurrentField()
+	m.focus = fieldAuthToken
+	if strings.TrimSpace(m.fields[fieldName].Value()) == "" {
+		m.focus = fieldName
+	}
+	m.focusCurrentField()
+}
+
+// moveFocus moves the focus by step fields, skipping the name and preset of
+// an existing provider.
+func (m *editorModel) moveFocus(step int) {
+	m.blurCurrentField()
+	for {
+		m.focus = (m.focus + editorField(step) + fieldCount) % fieldCount
+		if m.editing == "" || (m.focus != fieldName && m.focus != fieldPreset) {
+			break
+		}
+	}
+	m.focusCurrentField()
+}
+
 func (m *editorModel) blurCurrentField() {
-	if m.focus != fieldType && m.focus < fieldEnvVars {
+	if textField(m.focus) {
 		m.fields[m.focus].Blur()
 	}
 }
 
 func (m *editorModel) focusCurrentField() {
-	if m.focus != fieldType && m.focus < fieldEnvVars {
+	if textField(m.focus) {
 		m.fields[m.focus].Focus()
 	}
 }
@@ -277,19 +386,34 @@ func (m editorModel) save() (editorModel, tea.Cmd) {
 	token := strings.TrimSpace(m.fields[fieldAuthToken].Value())
 
 	if name == "" {
-		m.err = "name is required"
+		m.err = i18n.T("name is required")
 		return m, nil
 	}
 	if m.editing == "" && config.GetProvider(name) != nil {
 		m.err = fmt.Sprintf("provider %q already exists", name)
 		return m, nil
 	}
-	if baseURL == "" {
-		m.err = "base URL is required"
+	// Bedrock and Vertex AI providers default to their region's endpoint
+	// and authorize requests with cloud credentials instead of a token;
+	// local servers rarely need one
+	typeName := providerTypes[m.providerType].name
+	cloud := typeName == config.ProviderTypeBedrock || typeName == config.ProviderTypeVertex
+	if baseURL == "" && !cloud {
+		m.err = i18n.T("base URL is required")
 		return m, nil
 	}
-	if token == "" {
-		m.err = "auth token is required"
+	// Providers with OAuth settings get their tokens from the token endpoint
+	var existing *config.ProviderConfig
+	if m.editing != "" {
+		existing = config.GetProvider(m.editing)
+	}
+	oauth := existing != nil && existing.OAuth != nil
+	if token == "" && !cloud && !oauth && typeName != config.ProviderTypeLocal {
+		m.err = i18n.T("auth token is required")
+		return m, nil
+	}
+	if err := config.ValidateHeaders(m.headers); err != nil {
+		m.err = err.Error()
 		return m, nil
 	}
 
@@ -315,10 +439,7 @@ func (m editorModel) save() (editorModel, tea.Cmd) {
 	}
 
 	// Determine provider type
-	providerType := config.ProviderTypeAnthropic
-	if m.providerType == 1 {
-		providerType = config.ProviderTypeOpenAI
-	}
+	providerType := providerTypes[m.providerType].name
 
 	p := &config.ProviderConfig{
 		Type:           providerType,
@@ -350,6 +471,35 @@ func (m editorModel) save() (editorModel, tea.Cmd) {
 			p.OpenCodeEnvVars[k] = v
 		}
 	}
+	if len(m.headers) > 0 {
+		p.Headers = make(map[string]string)
+		for k, v := range m.headers {
+			p.Headers[k] = v
+		}
+	}
+
+	// Keep settings the form doesn't edit
+	if existing != nil {
+		p.Pricing = existing.Pricing
+		p.Capabilities = existing.Capabilities
+		p.CooldownUntil = existing.CooldownUntil
+		p.MaintenanceWindows = existing.MaintenanceWindows
+		p.MaxRequestBytes = existing.MaxRequestBytes
+		p.MaxConcurrent = existing.MaxConcurrent
+		p.Retry = existing.Retry
+		p.DailyBudgetUSD = existing.DailyBudgetUSD
+		p.MonthlyTokenLimit = existing.MonthlyTokenLimit
+		p.ProxyURL = existing.ProxyURL
+		p.ConnectTimeoutSeconds = existing.ConnectTimeoutSeconds
+		p.ResponseHeaderTimeoutSeconds = existing.ResponseHeaderTimeoutSeconds
+		p.IdleTimeoutSeconds = existing.IdleTimeoutSeconds
+		p.SystemPromptPrefix = existing.SystemPromptPrefix
+		p.SystemPromptFile = existing.SystemPromptFile
+		p.Bedrock = existing.Bedrock
+		p.Vertex = existing.Vertex
+		p.Azure = existing.Azure
+		p.OAuth = existing.OAuth
+	}
 
 	if err := config.SetProvider(name, p); err != nil {
 		m.err = err.Error()
@@ -364,7 +514,7 @@ func (m editorModel) save() (editorModel, tea.Cmd) {
 	}
 
 	m.saved = true
-	m.status = "Saved"
+	m.status = i18n.T("Saved")
 	m.err = ""
 	m.createdName = name
 	return m, saveExitTick()
@@ -383,10 +533,10 @@ func (m editorModel) view(width, height int) string {
 	var b strings.Builder
 
 	// Header
-	title := "Add Provider"
+	title := i18n.T("Add Provider")
 	icon := "➕"
 	if m.editing != "" {
-		title = fmt.Sprintf("Edit Provider: %s", m.editing)
+		title = i18n.Tf("Edit Provider: %s", m.editing)
 		icon = "✏️"
 	}
 	header := lipgloss.NewStyle().
@@ -400,10 +550,28 @@ func (m editorModel) view(width, height int) string {
 
 	// Form content
 	var content strings.Builder
-	content.WriteString(sectionTitleStyle.Render(" Provider Settings"))
+	content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Provider Settings")))
 	content.WriteString("\n\n")
 
 	for i := range m.fields {
+		if editorField(i) == fieldPreset {
+			if m.editing != "" {
+				continue
+			}
+			cursor := "  "
+			style := dimStyle
+			if m.focus == fieldPreset {
+				cursor = "▸ "
+				style = lipgloss.NewStyle().Foreground(accentColor).Bold(true)
+			}
+			presetLabel := i18n.T("Custom")
+			if m.preset > 0 {
+				presetLabel = config.ProviderPresets[m.preset-1].Label
+			}
+			content.WriteString(style.Render(fmt.Sprintf("%sPreset:           [%s] (←/→ to change)", cursor, presetLabel)))
+			content.WriteString("\n")
+			continue
+		}
 		if editorField(i) == fieldType {
 			// Special handling for type field
 			cursor := "  "
@@ -412,10 +580,7 @@ func (m editorModel) view(width, height int) string {
 				cursor = "▸ "
 				style = lipgloss.NewStyle().Foreground(accentColor).Bold(true)
 			}
-			typeLabel := "Anthropic Messages API"
-			if m.providerType == 1 {
-				typeLabel = "OpenAI Chat Completions API"
-			}
+			typeLabel := providerTypes[m.providerType].label
 			content.WriteString(style.Render(fmt.Sprintf("%sAPI Type:         [%s] (←/→ to change)", cursor, typeLabel)))
 			content.WriteString("\n")
 			continue
@@ -442,9 +607,9 @@ func (m editorModel) view(width, height int) string {
 				envCount = len(m.opencodeEnvVars)
 			}
 
-			envLabel := fmt.Sprintf("%d configured", envCount)
+			envLabel := i18n.Tf("%d configured", envCount)
 			if envCount == 0 {
-				envLabel = "none"
+				envLabel = i18n.T("none")
 			}
 
 			// Show CLI selector and env var count
@@ -452,6 +617,21 @@ func (m editorModel) view(width, height int) string {
 			content.WriteString("\n")
 			continue
 		}
+		if editorField(i) == fieldHeaders {
+			cursor := "  "
+			style := dimStyle
+			if m.focus == fieldHeaders {
+				cursor = "▸ "
+				style = lipgloss.NewStyle().Foreground(accentColor).Bold(true)
+			}
+			headersLabel := i18n.Tf("%d configured", len(m.headers))
+			if len(m.headers) == 0 {
+				headersLabel = i18n.T("none")
+			}
+			content.WriteString(style.Render(fmt.Sprintf("%sHeaders:          [%s] (enter edit)", cursor, headersLabel)))
+			content.WriteString("\n")
+			continue
+		}
 		if m.editing != "" && editorField(i) == fieldName {
 			content.WriteString(dimStyle.Render(fmt.Sprintf("  Name:             %s", m.editing)))
 			content.WriteString("\n")
@@ -506,7 +686,7 @@ func (m editorModel) view(width, height int) string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Tab next • "+saveKeyHint()+" save • Esc cancel", width)
+	helpBar := RenderHelpBar(i18n.Tf("Tab next • %s save • Esc cancel", saveKeyHint()), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -561,12 +741,24 @@ func (m standaloneEditorModel) View() string {
 // After saving, if profiles exist, it runs a profile multi-select so the user can
 // choose which profiles to add the new provider to.
 func RunAddProvider(presetName string) (string, error) {
+	return RunAddProviderFromPreset(presetName, "")
+}
+
+// RunAddProviderFromPreset is RunAddProvider with the form filled in from the
+// named built-in preset (see config.ProviderPresets), if preset is non-empty.
+func RunAddProviderFromPreset(presetName, preset string) (string, error) {
 	if presetName != "" && config.GetProvider(presetName) != nil {
 		return "", fmt.Errorf("provider %q already exists", presetName)
 	}
+	if preset != "" && config.GetProviderPreset(preset) == nil {
+		return "", fmt.Errorf("unknown preset %q", preset)
+	}
 	m := standaloneEditorModel{
 		editor: newEditorModelWithPreset("", presetName),
 	}
+	if preset != "" {
+		m.editor.selectPreset(preset)
+	}
 	p := tea.NewProgram(m)
 	result, err := p.Run()
 	if err != nil {
@@ -624,6 +816,7 @@ type envVarsEditorModel struct {
 	keyInput    string
 	valueInput  string
 	editingIdx  int // index being edited, -1 for new
+	headers     bool // entries are HTTP headers rather than env vars
 }
 
 type envVarEntry struct {
@@ -766,6 +959,15 @@ func (m envVarsEditorModel) updateValueEdit(msg tea.KeyMsg) (envVarsEditorModel,
 	return m, nil
 }
 
+// label returns the translated envVars text, or headers when the editor
+// is editing HTTP headers.
+func (m envVarsEditorModel) label(envVars, headers string) string {
+	if m.headers {
+		return i18n.T(headers)
+	}
+	return i18n.T(envVars)
+}
+
 func (m envVarsEditorModel) view(width, height int) string {
 	// Use global layout dimensions
 	contentWidth, _, _, _ := LayoutDimensions(width, height)
@@ -778,7 +980,7 @@ func (m envVarsEditorModel) view(width, height int) string {
 		Foreground(primaryColor).
 		Background(headerBgColor).
 		Padding(0, 2).
-		Render("🔧 Environment Variables")
+		Render("🔧 " + m.label("Environment Variables", "HTTP Headers"))
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
@@ -786,9 +988,13 @@ func (m envVarsEditorModel) view(width, height int) string {
 
 	if m.phase == 1 {
 		// Key editing
-		content.WriteString(sectionTitleStyle.Render(" Enter Variable Name"))
+		content.WriteString(sectionTitleStyle.Render(" " + m.label("Enter Variable Name", "Enter Header Name")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" e.g. CLAUDE_CODE_MAX_OUTPUT_TOKENS"))
+		if m.headers {
+			content.WriteString(dimStyle.Render(" e.g. CF-Access-Client-Id"))
+		} else {
+			content.WriteString(dimStyle.Render(" e.g. CLAUDE_CODE_MAX_OUTPUT_TOKENS"))
+		}
 		content.WriteString("\n\n")
 		content.WriteString(lipgloss.NewStyle().Foreground(accentColor).Render("  " + m.keyInput + "█"))
 	} else if m.phase == 2 {
@@ -798,9 +1004,9 @@ func (m envVarsEditorModel) view(width, height int) string {
 		content.WriteString(lipgloss.NewStyle().Foreground(accentColor).Render("  " + m.valueInput + "█"))
 	} else {
 		// List view
-		content.WriteString(sectionTitleStyle.Render(" Custom Environment Variables"))
+		content.WriteString(sectionTitleStyle.Render(" " + m.label("Custom Environment Variables", "Custom HTTP Headers")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" These are passed as x-env-* headers to the proxy"))
+		content.WriteString(dimStyle.Render(" " + m.label("These are passed as x-env-* headers to the proxy", "Sent with every request to this provider")))
 		content.WriteString("\n\n")
 
 		for i, e := range m.entries {
@@ -822,7 +1028,11 @@ func (m envVarsEditorModel) view(width, height int) string {
 			cursor = "▸ "
 			style = tableSelectedRowStyle
 		}
-		content.WriteString(style.Render(cursor + "[+ Add new variable]"))
+		if m.headers {
+			content.WriteString(style.Render(cursor + "[+ Add new header]"))
+		} else {
+			content.WriteString(style.Render(cursor + "[+ Add new variable]"))
+		}
 	}
 
 	// Content box with proper width
@@ -862,11 +1072,11 @@ func (m envVarsEditorModel) view(width, height int) string {
 	// Help bar at bottom
 	var helpText string
 	if m.phase == 1 {
-		helpText = "Enter/Tab next • Esc cancel"
+		helpText = i18n.T("Enter/Tab next • Esc cancel")
 	} else if m.phase == 2 {
-		helpText = "Enter save • Esc cancel"
+		helpText = i18n.T("Enter save • Esc cancel")
 	} else {
-		helpText = "↑↓ move • Enter edit/add • d delete • Esc done"
+		helpText = i18n.T("↑↓ move • Enter edit/add • d delete • Esc done")
 	}
 	helpBar := RenderHelpBar(helpText, width)
 	view.WriteString(helpBar)
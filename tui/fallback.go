@@ -2,15 +2,18 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type fallbackModel struct {
 	profile    string   // profile name ("default", "work", etc.)
+	extends    string   // profile this one extends, whose providers come first
 	allConfigs []string // all available providers
 	order      []string // current fallback order (selected providers)
 	cursor     int      // cursor position in allConfigs
@@ -22,9 +25,11 @@ type fallbackModel struct {
 	routingExpanded map[config.Scenario]bool            // which scenarios are expanded
 	routingOrder    map[config.Scenario][]string        // provider order per scenario
 	routingModels   map[config.Scenario]map[string]string // per-provider models per scenario
+	scenarios       []scenarioOption                    // scenarios offered for routing
 
 	status string
-	saved  bool // true = save succeeded, waiting to exit
+	saved  bool   // true = save succeeded, waiting to exit
+	undo   string // backup reason of a saved order change, undoable from the dashboard
 }
 
 func newFallbackModel(profile string) fallbackModel {
@@ -41,22 +46,26 @@ func newFallbackModel(profile string) fallbackModel {
 
 type fallbackLoadedMsg struct {
 	allConfigs []string
+	extends    string
 	order      []string
 	routing    map[config.Scenario]*config.ScenarioRoute
+	scenarios  []scenarioOption
 }
 
 func (m fallbackModel) init() tea.Cmd {
 	profile := m.profile
 	return func() tea.Msg {
 		names := config.ProviderNames()
-		pc := config.GetProfileConfig(profile)
+		pc := config.GetOwnProfileConfig(profile)
 		var order []string
 		var routing map[config.Scenario]*config.ScenarioRoute
+		var extends string
 		if pc != nil {
 			order = pc.Providers
 			routing = pc.Routing
+			extends = pc.Extends
 		}
-		return fallbackLoadedMsg{allConfigs: names, order: order, routing: routing}
+		return fallbackLoadedMsg{allConfigs: names, extends: extends, order: order, routing: routing, scenarios: routingScenarios()}
 	}
 }
 
@@ -72,7 +81,10 @@ func (m fallbackModel) update(msg tea.Msg) (fallbackModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case fallbackLoadedMsg:
 		m.allConfigs = msg.allConfigs
-		m.order = msg.order
+		m.extends = msg.extends
+		// Reordering swaps in place; don't touch the store's copy
+		m.order = slices.Clone(msg.order)
+		m.scenarios = msg.scenarios
 		m.cursor = 0
 		// Load routing data
 		if msg.routing != nil {
@@ -138,7 +150,7 @@ func (m fallbackModel) handleKey(msg tea.KeyMsg) (fallbackModel, tea.Cmd) {
 				m.cursor++
 			}
 		} else {
-			if m.routingCursor < len(knownScenarios)-1 {
+			if m.routingCursor < len(m.scenarios)-1 {
 				m.routingCursor++
 			}
 		}
@@ -167,8 +179,8 @@ func (m fallbackModel) handleKey(msg tea.KeyMsg) (fallbackModel, tea.Cmd) {
 			}
 		} else {
 			// Toggle scenario expansion or enter scenario editor
-			if m.routingCursor < len(knownScenarios) {
-				scenario := knownScenarios[m.routingCursor].scenario
+			if m.routingCursor < len(m.scenarios) {
+				scenario := m.scenarios[m.routingCursor].scenario
 				// Enter scenario editor
 				return m, func() tea.Msg {
 					return switchToScenarioEditMsg{
@@ -188,6 +200,23 @@ func (m fallbackModel) saveAndExit() (fallbackModel, tea.Cmd) {
 	pc := &config.ProfileConfig{
 		Providers: m.order,
 	}
+	// Keep settings this screen doesn't edit
+	existing := config.GetOwnProfileConfig(m.profile)
+	if existing != nil {
+		pc.Extends = existing.Extends
+		pc.LongContextThreshold = existing.LongContextThreshold
+		pc.Strategy = existing.Strategy
+		pc.Weights = existing.Weights
+		pc.Retry = existing.Retry
+		pc.SessionAffinity = existing.SessionAffinity
+		pc.EnvVars = existing.EnvVars
+		pc.Launch = existing.Launch
+		pc.AutoOrder = existing.AutoOrder
+		pc.Schedule = existing.Schedule
+		pc.SystemPromptPrefix = existing.SystemPromptPrefix
+		pc.SystemPromptFile = existing.SystemPromptFile
+		pc.Redaction = existing.Redaction
+	}
 
 	// Build routing config
 	if len(m.routingOrder) > 0 {
@@ -206,16 +235,31 @@ func (m fallbackModel) saveAndExit() (fallbackModel, tea.Cmd) {
 				}
 				providerRoutes = append(providerRoutes, pr)
 			}
-			pc.Routing[scenario] = &config.ScenarioRoute{Providers: providerRoutes}
+			route := &config.ScenarioRoute{Providers: providerRoutes}
+			if existing != nil && existing.Routing[scenario] != nil {
+				route.MaxRequestBytes = existing.Routing[scenario].MaxRequestBytes
+			}
+			pc.Routing[scenario] = route
+		}
+	}
+
+	// Order changes can be undone from the dashboard, by restoring a backup
+	var undo string
+	if existing != nil && !slices.Equal(existing.Providers, pc.Providers) {
+		undo = "reorder profile " + m.profile
+		if _, err := config.BackupConfig(undo); err != nil {
+			m.status = i18n.T("Error: ") + err.Error()
+			return m, nil
 		}
 	}
 
 	if err := config.SetProfileConfig(m.profile, pc); err != nil {
-		m.status = "Error: " + err.Error()
+		m.status = i18n.T("Error: ") + err.Error()
 		return m, nil
 	}
+	m.undo = undo
 	m.saved = true
-	m.status = "Saved"
+	m.status = i18n.T("Saved")
 	return m, saveExitTick()
 }
 
@@ -275,9 +319,9 @@ func (m fallbackModel) view(width, height int) string {
 	var b strings.Builder
 
 	// Header
-	title := "Group: default"
+	title := i18n.Tf("Group: %s", "default")
 	if m.profile != "" && m.profile != "default" {
-		title = fmt.Sprintf("Group: %s", m.profile)
+		title = i18n.Tf("Group: %s", m.profile)
 	}
 	header := lipgloss.NewStyle().
 		Bold(true).
@@ -287,6 +331,10 @@ func (m fallbackModel) view(width, height int) string {
 		Render("📦 " + title)
 	b.WriteString(header)
 	b.WriteString("\n\n")
+	if m.extends != "" {
+		b.WriteString(dimStyle.Render(i18n.Tf("Extends '%s': its providers come first, and its routes apply unless set here", m.extends)))
+		b.WriteString("\n\n")
+	}
 
 	// Content box with proper width
 	boxWidth := contentWidth * 60 / 100
@@ -299,17 +347,17 @@ func (m fallbackModel) view(width, height int) string {
 
 	var content strings.Builder
 	if len(m.allConfigs) == 0 {
-		content.WriteString(dimStyle.Render("No providers configured.\n"))
-		content.WriteString(dimStyle.Render("Run 'opencc config add provider' to create one."))
+		content.WriteString(dimStyle.Render(i18n.T("No providers configured.") + "\n"))
+		content.WriteString(dimStyle.Render(i18n.T("Run 'opencc config add provider' to create one.")))
 	} else {
 		// Default Providers Section
 		sectionStyle := sectionTitleStyle
 		if m.section != 0 {
 			sectionStyle = dimStyle
 		}
-		content.WriteString(sectionStyle.Render(" Default Providers"))
+		content.WriteString(sectionStyle.Render(" " + i18n.T("Default Providers")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Space to toggle, Enter to reorder"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Space to toggle, Enter to reorder")))
 		content.WriteString("\n\n")
 
 		for i, name := range m.allConfigs {
@@ -334,7 +382,7 @@ func (m fallbackModel) view(width, height int) string {
 			if m.grabbed && m.section == 0 && i == m.cursor {
 				grabIndicator = " " + lipgloss.NewStyle().
 					Foreground(accentColor).
-					Render("(reordering)")
+					Render(i18n.T("(reordering)"))
 			}
 
 			line := fmt.Sprintf("%s%s %s%s", cursor, checkbox, name, grabIndicator)
@@ -350,12 +398,12 @@ func (m fallbackModel) view(width, height int) string {
 		if m.section != 1 {
 			sectionStyle = dimStyle
 		}
-		content.WriteString(sectionStyle.Render(" Scenario Routing"))
+		content.WriteString(sectionStyle.Render(" " + i18n.T("Scenario Routing")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Enter to configure scenario"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Enter to configure scenario")))
 		content.WriteString("\n\n")
 
-		for i, ks := range knownScenarios {
+		for i, ks := range m.scenarios {
 			cursor := "  "
 			style := tableRowStyle
 			if m.section == 1 && i == m.routingCursor {
@@ -377,7 +425,7 @@ func (m fallbackModel) view(width, height int) string {
 
 			line := fmt.Sprintf("%s%s%s", cursor, ks.label, countInfo)
 			content.WriteString(style.Render(line))
-			if i < len(knownScenarios)-1 {
+			if i < len(m.scenarios)-1 {
 				content.WriteString("\n")
 			}
 		}
@@ -420,7 +468,7 @@ func (m fallbackModel) view(width, height int) string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Tab switch section • s save • Esc back", width)
+	helpBar := RenderHelpBar(i18n.T("Tab switch section • s save • Esc back"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // groupCreateModel handles standalone group creation.
@@ -43,7 +44,7 @@ func (m groupCreateModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			name := strings.TrimSpace(m.nameInput.Value())
 			if name == "" {
-				m.err = "name is required"
+				m.err = i18n.T("name is required")
 				return m, nil
 			}
 			// Check if group already exists
@@ -76,7 +77,7 @@ func (m groupCreateModel) View() string {
 	sidePadding := 2
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("  Create New Group"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("Create New Group")))
 	b.WriteString("\n\n")
 	b.WriteString(m.nameInput.View())
 	b.WriteString("\n\n")
@@ -103,7 +104,7 @@ func (m groupCreateModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Enter create • Esc cancel", width)
+	helpBar := RenderHelpBar(i18n.T("Enter create • Esc cancel"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // LaunchBackMsg is sent when user wants to go back from launch wizard.
@@ -155,9 +156,9 @@ func (m LaunchModel) View() string {
 
 	// Build left pane content (Profiles)
 	var leftContent strings.Builder
-	leftContent.WriteString(titleStyle.Render("Select Profile"))
+	leftContent.WriteString(titleStyle.Render(i18n.T("Select Profile")))
 	leftContent.WriteString("\n")
-	leftContent.WriteString(labelStyle.Render("Choose a profile to use"))
+	leftContent.WriteString(labelStyle.Render(i18n.T("Choose a profile to use")))
 	leftContent.WriteString("\n\n")
 
 	for i, p := range m.profiles {
@@ -181,9 +182,9 @@ func (m LaunchModel) View() string {
 
 	// Build right pane content (CLI)
 	var rightContent strings.Builder
-	rightContent.WriteString(titleStyle.Render("Select CLI"))
+	rightContent.WriteString(titleStyle.Render(i18n.T("Select CLI")))
 	rightContent.WriteString("\n")
-	rightContent.WriteString(labelStyle.Render("Choose which CLI to launch"))
+	rightContent.WriteString(labelStyle.Render(i18n.T("Choose which CLI to launch")))
 	rightContent.WriteString("\n\n")
 
 	cliDescriptions := map[string]string{
@@ -249,7 +250,7 @@ func (m LaunchModel) View() string {
 	}
 
 	// Help bar at bottom - full terminal width
-	helpBar := RenderHelpBar("Tab/←→ switch pane • ↑↓ navigate • Enter launch • Esc back", m.width)
+	helpBar := RenderHelpBar(i18n.T("Tab/←→ switch pane • ↑↓ navigate • Enter launch • Esc back"), m.width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -7,6 +7,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type listItem struct {
@@ -134,7 +135,7 @@ func (m listModel) view(width, height int) string {
 	sidePadding := 2
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("  opencc configurations"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("opencc configurations")))
 	b.WriteString("\n\n")
 
 	if len(m.configs) == 0 {
@@ -194,7 +195,7 @@ func (m listModel) view(width, height int) string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("a add • e/Enter edit • d delete • f fallback profiles • q quit", width)
+	helpBar := RenderHelpBar(i18n.T("a add • e/Enter edit • d delete • f fallback profiles • q quit"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
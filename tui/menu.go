@@ -1,12 +1,12 @@
 package tui
 
 import (
-	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // MenuAction represents a menu item action.
@@ -45,10 +45,10 @@ type MenuModel struct {
 func NewMenuModel() MenuModel {
 	return MenuModel{
 		items: []menuItem{
-			{label: "Launch", action: MenuLaunch},
-			{label: "Configure", action: MenuConfigure},
-			{label: "Settings", action: MenuSettings},
-			{label: "Quit", action: MenuQuit},
+			{label: i18n.T("Launch"), action: MenuLaunch},
+			{label: i18n.T("Configure"), action: MenuConfigure},
+			{label: i18n.T("Settings"), action: MenuSettings},
+			{label: i18n.T("Quit"), action: MenuQuit},
 		},
 		profile: config.GetDefaultProfile(),
 		cli:     config.GetDefaultCLI(),
@@ -121,7 +121,7 @@ func (m MenuModel) View() string {
 	title := m.titleStyle.Render("OpenCC")
 	subtitle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("8")).
-		Render("Environment Switcher")
+		Render(i18n.T("Environment Switcher"))
 
 	// Menu items - fixed width, cursor doesn't shift text
 	var menuItems strings.Builder
@@ -135,7 +135,7 @@ func (m MenuModel) View() string {
 	}
 
 	// Status line - centered below menu
-	status := m.statusStyle.Render(fmt.Sprintf("Profile: %s  |  CLI: %s", m.profile, m.cli))
+	status := m.statusStyle.Render(i18n.Tf("Profile: %s  |  CLI: %s", m.profile, m.cli))
 
 	// Calculate box width
 	boxWidth := 36
@@ -194,7 +194,7 @@ func (m MenuModel) View() string {
 	}
 
 	// Help bar at bottom - full terminal width with background
-	helpBar := RenderHelpBar("↑↓ navigate • Enter select • q quit", m.width)
+	helpBar := RenderHelpBar(i18n.T("↑↓ navigate • Enter select • q quit"), m.width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -158,6 +158,9 @@ func (m NewAppModel) updateProfileEdit(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle messages from fallback editor
 	switch msg.(type) {
 	case switchToListMsg:
+		if m.fallback.undo != "" {
+			m.dashboard.undo.record(m.fallback.undo)
+		}
 		m.screen = ScreenDashboard
 		m.dashboard.Refresh()
 		return m, nil
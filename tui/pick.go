@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type pickModel struct {
@@ -135,15 +136,15 @@ func (m pickModel) View() string {
 		Foreground(primaryColor).
 		Background(headerBgColor).
 		Padding(0, 2).
-		Render("🎯 Select Providers")
+		Render("🎯 " + i18n.T("Select Providers"))
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
 	// Content box
 	var content strings.Builder
-	content.WriteString(sectionTitleStyle.Render(" Choose providers for this session"))
+	content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Choose providers for this session")))
 	content.WriteString("\n")
-	content.WriteString(dimStyle.Render(" Space to toggle, Enter to reorder"))
+	content.WriteString(dimStyle.Render(" " + i18n.T("Space to toggle, Enter to reorder")))
 	content.WriteString("\n\n")
 
 	for i, name := range m.choices {
@@ -204,9 +205,9 @@ func (m pickModel) View() string {
 	// Help bar at bottom
 	var helpText string
 	if m.grabbed {
-		helpText = "↑↓ reorder • Enter/Esc drop"
+		helpText = i18n.T("↑↓ reorder • Enter/Esc drop")
 	} else {
-		helpText = "Space toggle • Enter reorder/confirm • " + saveKeyHint() + " confirm • q cancel"
+		helpText = i18n.Tf("Space toggle • Enter reorder/confirm • %s confirm • q cancel", saveKeyHint())
 	}
 	helpBar := RenderHelpBar(helpText, width)
 	view.WriteString(helpBar)
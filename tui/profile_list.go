@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 type profileListModel struct {
@@ -146,7 +147,7 @@ func (m profileListModel) view(width, height int) string {
 	sidePadding := 2
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("  Groups"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("Groups")))
 	b.WriteString("\n\n")
 
 	if len(m.profiles) == 0 {
@@ -197,9 +198,9 @@ func (m profileListModel) view(width, height int) string {
 	// Help bar at bottom
 	var helpText string
 	if m.creating {
-		helpText = "Enter create • Esc cancel"
+		helpText = i18n.T("Enter create • Esc cancel")
 	} else {
-		helpText = "Enter edit • a new • d delete • Esc back"
+		helpText = i18n.T("Enter edit • a new • d delete • Esc back")
 	}
 	helpBar := RenderHelpBar(helpText, width)
 	view.WriteString(helpBar)
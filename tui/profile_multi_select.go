@@ -7,6 +7,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // profileMultiSelectModel is a checkbox-based profile multi-select TUI.
@@ -80,15 +81,15 @@ func (m profileMultiSelectModel) View() string {
 		Foreground(primaryColor).
 		Background(headerBgColor).
 		Padding(0, 2).
-		Render("📂 Add to Profiles")
+		Render("📂 " + i18n.T("Add to Profiles"))
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
 	// Content box
 	var content strings.Builder
-	content.WriteString(sectionTitleStyle.Render(" Select profiles for this provider"))
+	content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Select profiles for this provider")))
 	content.WriteString("\n")
-	content.WriteString(dimStyle.Render(" Space to toggle, Enter to confirm, Esc to skip"))
+	content.WriteString(dimStyle.Render(" " + i18n.T("Space to toggle, Enter to confirm, Esc to skip")))
 	content.WriteString("\n\n")
 
 	for i, name := range m.profiles {
@@ -140,7 +141,7 @@ func (m profileMultiSelectModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Space toggle • Enter confirm • Esc skip", width)
+	helpBar := RenderHelpBar(i18n.T("Space toggle • Enter confirm • Esc skip"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
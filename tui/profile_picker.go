@@ -5,6 +5,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // profilePickerModel is a standalone TUI for selecting a fallback profile.
@@ -57,7 +58,7 @@ func (m profilePickerModel) View() string {
 	sidePadding := 2
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("  Select provider group:"))
+	b.WriteString(titleStyle.Render("  " + i18n.T("Select provider group:")))
 	b.WriteString("\n\n")
 
 	if len(m.profiles) == 0 {
@@ -93,7 +94,7 @@ func (m profilePickerModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Enter select • q cancel", width)
+	helpBar := RenderHelpBar(i18n.T("Enter select • q cancel"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
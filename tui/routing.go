@@ -2,11 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // switchToRoutingMsg triggers opening the routing editor from the fallback editor.
@@ -46,16 +48,21 @@ type scenarioEditModel struct {
 	order           []string          // selected providers for this scenario
 	providerModels  map[string]string // provider name → model override
 	cursor          int
-	phase           int    // 0=select providers, 1=edit provider model
+	phase           int    // 0=select providers, 1=edit provider model, 2=edit threshold
 	editingProvider string // provider being edited in phase 1
 	modelInput      string
 	modelCursor     int
+	threshold       int    // long-context threshold in tokens; 0 = none
+	thresholdInput  string // threshold being typed in phase 2
 }
 
-var knownScenarios = []struct {
+// scenarioOption is a scenario the routing editors offer.
+type scenarioOption struct {
 	scenario config.Scenario
 	label    string
-}{
+}
+
+var knownScenarios = []scenarioOption{
 	{config.ScenarioWebSearch, "webSearch   (requests with web_search tools)"},
 	{config.ScenarioThink, "think       (thinking mode requests)"},
 	{config.ScenarioImage, "image       (requests with images)"},
@@ -63,6 +70,18 @@ var knownScenarios = []struct {
 	{config.ScenarioBackground, "background  (haiku model requests)"},
 }
 
+// routingScenarios returns the built-in scenarios followed by the valid
+// custom ones defined in the config.
+func routingScenarios() []scenarioOption {
+	scenarios := append([]scenarioOption(nil), knownScenarios...)
+	for _, cs := range config.GetCustomScenarios() {
+		if cs.IsValid() {
+			scenarios = append(scenarios, scenarioOption{cs.Name, fmt.Sprintf("%-11s (custom)", cs.Name)})
+		}
+	}
+	return scenarios
+}
+
 func newRoutingModel(profile string) routingModel {
 	return routingModel{
 		profile: profile,
@@ -78,7 +97,7 @@ type routingLoadedMsg struct {
 func (m routingModel) init() tea.Cmd {
 	profile := m.profile
 	return func() tea.Msg {
-		pc := config.GetProfileConfig(profile)
+		pc := config.GetOwnProfileConfig(profile)
 		allProviders := config.ProviderNames()
 
 		var routing map[config.Scenario]*config.ScenarioRoute
@@ -87,7 +106,7 @@ func (m routingModel) init() tea.Cmd {
 		}
 
 		var scenarios []scenarioEntry
-		for _, ks := range knownScenarios {
+		for _, ks := range routingScenarios() {
 			configured := false
 			if routing != nil {
 				if _, ok := routing[ks.scenario]; ok {
@@ -156,7 +175,7 @@ func (m routingModel) handleKey(msg tea.KeyMsg) (routingModel, tea.Cmd) {
 		// Clear route for current scenario
 		if m.cursor < len(m.scenarios) {
 			s := m.scenarios[m.cursor]
-			pc := config.GetProfileConfig(m.profile)
+			pc := config.GetOwnProfileConfig(m.profile)
 			if pc != nil && pc.Routing != nil {
 				delete(pc.Routing, s.scenario)
 				if len(pc.Routing) == 0 {
@@ -169,7 +188,7 @@ func (m routingModel) handleKey(msg tea.KeyMsg) (routingModel, tea.Cmd) {
 		}
 	case "s", "ctrl+s", "cmd+s":
 		m.saved = true
-		m.status = "Saved"
+		m.status = i18n.T("Saved")
 		return m, saveExitTick()
 	}
 	return m, nil
@@ -178,6 +197,10 @@ func (m routingModel) handleKey(msg tea.KeyMsg) (routingModel, tea.Cmd) {
 func (m routingModel) updateEditScenario(msg tea.KeyMsg) (routingModel, tea.Cmd) {
 	em := &m.editModel
 
+	if em.phase == 2 {
+		em.updateThreshold(msg)
+		return m, nil
+	}
 	if em.phase == 1 {
 		// Model editing phase for a specific provider
 		switch msg.String() {
@@ -254,6 +277,8 @@ func (m routingModel) updateEditScenario(msg tea.KeyMsg) (routingModel, tea.Cmd)
 				}
 			}
 		}
+	case "t":
+		em.editThreshold()
 	case "enter":
 		// Save this scenario route
 		m.saveScenarioRoute()
@@ -265,7 +290,7 @@ func (m routingModel) updateEditScenario(msg tea.KeyMsg) (routingModel, tea.Cmd)
 
 func (m *routingModel) saveScenarioRoute() {
 	em := m.editModel
-	pc := config.GetProfileConfig(m.profile)
+	pc := config.GetOwnProfileConfig(m.profile)
 	if pc == nil {
 		pc = &config.ProfileConfig{Providers: []string{}}
 	}
@@ -290,9 +315,11 @@ func (m *routingModel) saveScenarioRoute() {
 			}
 			providerRoutes = append(providerRoutes, pr)
 		}
-		pc.Routing[em.scenario] = &config.ScenarioRoute{
-			Providers: providerRoutes,
+		route := &config.ScenarioRoute{Providers: providerRoutes, LongContextThreshold: em.threshold}
+		if existing := pc.Routing[em.scenario]; existing != nil {
+			route.MaxRequestBytes = existing.MaxRequestBytes
 		}
+		pc.Routing[em.scenario] = route
 	}
 	config.SetProfileConfig(m.profile, pc)
 }
@@ -305,10 +332,11 @@ func newScenarioEditModel(scenario config.Scenario, allProviders []string, profi
 	}
 
 	// Load existing route data
-	pc := config.GetProfileConfig(profile)
+	pc := config.GetOwnProfileConfig(profile)
 	if pc != nil && pc.Routing != nil {
 		if route, ok := pc.Routing[scenario]; ok {
 			em.order = route.ProviderNames()
+			em.threshold = route.LongContextThreshold
 			for _, pr := range route.Providers {
 				if pr.Model != "" {
 					em.providerModels[pr.Name] = pr.Model
@@ -319,6 +347,62 @@ func newScenarioEditModel(scenario config.Scenario, allProviders []string, profi
 	return em
 }
 
+// editThreshold starts editing the scenario's long-context threshold.
+func (em *scenarioEditModel) editThreshold() {
+	em.phase = 2
+	em.thresholdInput = ""
+	if em.threshold > 0 {
+		em.thresholdInput = strconv.Itoa(em.threshold)
+	}
+}
+
+// updateThreshold handles a key while the threshold is being edited.
+func (em *scenarioEditModel) updateThreshold(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc":
+		em.phase = 0
+	case "enter":
+		n, err := strconv.Atoi(em.thresholdInput)
+		if err != nil || n < 0 {
+			n = 0
+		}
+		em.threshold = n
+		em.phase = 0
+	case "backspace":
+		if len(em.thresholdInput) > 0 {
+			em.thresholdInput = em.thresholdInput[:len(em.thresholdInput)-1]
+		}
+	default:
+		if s := msg.String(); len(s) == 1 && s[0] >= '0' && s[0] <= '9' {
+			em.thresholdInput += s
+		}
+	}
+}
+
+// renderThreshold renders the threshold line of the provider list, or its
+// input while it is being edited.
+func (em scenarioEditModel) renderThreshold() string {
+	if em.phase == 2 {
+		var b strings.Builder
+		b.WriteString(sectionTitleStyle.Render(" " + i18n.T("Long-Context Threshold")))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render(" " + i18n.Tf("Requests of this scenario with at least this many tokens go to %s; empty for none", config.ScenarioLongContext)))
+		b.WriteString("\n\n")
+		b.WriteString(lipgloss.NewStyle().
+			Foreground(accentColor).
+			Render("  " + em.thresholdInput + "█"))
+		return b.String()
+	}
+	if em.scenario == config.ScenarioLongContext {
+		return ""
+	}
+	value := i18n.T("none")
+	if em.threshold > 0 {
+		value = i18n.Tf("%d tokens", em.threshold)
+	}
+	return dimStyle.Render(" " + i18n.Tf("Long-context threshold: %s (t to edit)", value))
+}
+
 func scenarioOrderIndex(order []string, name string) int {
 	for i, n := range order {
 		if n == name {
@@ -346,7 +430,7 @@ func (m routingModel) view(width, height int) string {
 	var b strings.Builder
 
 	// Header
-	title := fmt.Sprintf("Routing: %s", m.profile)
+	title := i18n.Tf("Routing: %s", m.profile)
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(primaryColor).
@@ -395,9 +479,9 @@ func (m routingModel) view(width, height int) string {
 	// Help bar at bottom
 	var helpText string
 	if m.phase == 0 {
-		helpText = "↑↓ move • Enter edit • x clear • s save • Esc back"
+		helpText = i18n.T("↑↓ move • Enter edit • x clear • s save • Esc back")
 	} else {
-		helpText = "Space toggle • m edit model • Enter save • Esc back"
+		helpText = i18n.T("Space toggle • m edit model • t threshold • Enter save • Esc back")
 	}
 	helpBar := RenderHelpBar(helpText, width)
 	view.WriteString(helpBar)
@@ -415,9 +499,9 @@ func (m routingModel) renderScenarioList(contentWidth int) string {
 	}
 
 	var content strings.Builder
-	content.WriteString(sectionTitleStyle.Render(" Scenario Routes"))
+	content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Scenario Routes")))
 	content.WriteString("\n")
-	content.WriteString(dimStyle.Render(" Configure provider chains per request type"))
+	content.WriteString(dimStyle.Render(" " + i18n.T("Configure provider chains per request type")))
 	content.WriteString("\n\n")
 
 	for i, s := range m.scenarios {
@@ -466,8 +550,10 @@ func (m routingModel) renderScenarioEdit(contentWidth int) string {
 	content.WriteString(sectionTitleStyle.Render(fmt.Sprintf(" Edit: %s", scenarioLabel)))
 	content.WriteString("\n")
 
-	if em.phase == 0 {
-		content.WriteString(dimStyle.Render(" Space toggle • m edit model • enter save • esc back"))
+	if em.phase == 2 {
+		content.WriteString(em.renderThreshold())
+	} else if em.phase == 0 {
+		content.WriteString(dimStyle.Render(" " + i18n.T("Space toggle • m edit model • t threshold • enter save • esc back")))
 		content.WriteString("\n\n")
 
 		// Provider list with per-provider models
@@ -503,16 +589,20 @@ func (m routingModel) renderScenarioEdit(contentWidth int) string {
 				content.WriteString("\n")
 			}
 		}
+		if threshold := em.renderThreshold(); threshold != "" {
+			content.WriteString("\n\n")
+			content.WriteString(threshold)
+		}
 	} else {
 		// Model editing phase for specific provider
 		content.WriteString(dimStyle.Render(fmt.Sprintf(" Editing model for: %s", em.editingProvider)))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Type model name • enter save • esc cancel"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Type model name • enter save • esc cancel")))
 		content.WriteString("\n\n")
 
-		content.WriteString(sectionTitleStyle.Render(" Model Override"))
+		content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Model Override")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Leave empty to use provider's model mapping"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Leave empty to use provider's model mapping")))
 		content.WriteString("\n\n")
 
 		modelDisplay := em.modelInput + "█"
@@ -561,6 +651,10 @@ func (w *scenarioEditWrapper) Init() tea.Cmd {
 func (w *scenarioEditWrapper) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if w.edit.phase == 2 {
+			w.edit.updateThreshold(msg)
+			return w, nil
+		}
 		if w.edit.phase == 1 {
 			// Model editing phase
 			switch msg.String() {
@@ -602,7 +696,7 @@ func (w *scenarioEditWrapper) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return w, func() tea.Msg { return switchToFallbackMsg{profile: w.profile} }
 		case "enter":
 			// Save and return
-			pc := config.GetProfileConfig(w.profile)
+			pc := config.GetOwnProfileConfig(w.profile)
 			if pc == nil {
 				pc = &config.ProfileConfig{Providers: []string{}}
 			}
@@ -626,9 +720,11 @@ func (w *scenarioEditWrapper) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 					providerRoutes = append(providerRoutes, pr)
 				}
-				pc.Routing[w.edit.scenario] = &config.ScenarioRoute{
-					Providers: providerRoutes,
+				route := &config.ScenarioRoute{Providers: providerRoutes, LongContextThreshold: w.edit.threshold}
+				if existing := pc.Routing[w.edit.scenario]; existing != nil {
+					route.MaxRequestBytes = existing.MaxRequestBytes
 				}
+				pc.Routing[w.edit.scenario] = route
 			}
 			config.SetProfileConfig(w.profile, pc)
 			return w, func() tea.Msg { return switchToFallbackMsg{profile: w.profile} }
@@ -652,6 +748,8 @@ func (w *scenarioEditWrapper) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					w.edit.order = append(w.edit.order, name)
 				}
 			}
+		case "t":
+			w.edit.editThreshold()
 		case "m":
 			if w.edit.cursor < len(w.edit.allProviders) {
 				name := w.edit.allProviders[w.edit.cursor]
@@ -689,7 +787,7 @@ func (w *scenarioEditWrapper) View() string {
 	var b strings.Builder
 
 	scenarioLabel := string(w.edit.scenario)
-	title := fmt.Sprintf("Edit Scenario: %s", scenarioLabel)
+	title := i18n.Tf("Edit Scenario: %s", scenarioLabel)
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(primaryColor).
@@ -701,7 +799,9 @@ func (w *scenarioEditWrapper) View() string {
 
 	var content strings.Builder
 
-	if w.edit.phase == 0 {
+	if w.edit.phase == 2 {
+		content.WriteString(w.edit.renderThreshold())
+	} else if w.edit.phase == 0 {
 		for i, name := range w.edit.allProviders {
 			cursor := "  "
 			style := tableRowStyle
@@ -733,15 +833,19 @@ func (w *scenarioEditWrapper) View() string {
 				content.WriteString("\n")
 			}
 		}
+		if threshold := w.edit.renderThreshold(); threshold != "" {
+			content.WriteString("\n\n")
+			content.WriteString(threshold)
+		}
 	} else {
 		content.WriteString(dimStyle.Render(fmt.Sprintf(" Editing model for: %s", w.edit.editingProvider)))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Type model name • enter save • esc cancel"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Type model name • enter save • esc cancel")))
 		content.WriteString("\n\n")
 
-		content.WriteString(sectionTitleStyle.Render(" Model Override"))
+		content.WriteString(sectionTitleStyle.Render(" " + i18n.T("Model Override")))
 		content.WriteString("\n")
-		content.WriteString(dimStyle.Render(" Leave empty to use provider's model mapping"))
+		content.WriteString(dimStyle.Render(" " + i18n.T("Leave empty to use provider's model mapping")))
 		content.WriteString("\n\n")
 
 		modelDisplay := w.edit.modelInput + "█"
@@ -779,9 +883,9 @@ func (w *scenarioEditWrapper) View() string {
 	// Help bar at bottom
 	var helpText string
 	if w.edit.phase == 0 {
-		helpText = "Space toggle • m edit model • Enter save • Esc back"
+		helpText = i18n.T("Space toggle • m edit model • t threshold • Enter save • Esc back")
 	} else {
-		helpText = "Enter save • Esc cancel"
+		helpText = i18n.T("Enter save • Esc cancel")
 	}
 	helpBar := RenderHelpBar(helpText, width)
 	view.WriteString(helpBar)
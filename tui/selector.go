@@ -6,6 +6,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dopejs/opencc/internal/i18n"
 )
 
 // selectorItem represents an item in the selector list.
@@ -148,7 +149,7 @@ func (m selectorModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("↑↓ move • Enter select • Esc cancel", width)
+	helpBar := RenderHelpBar(i18n.T("↑↓ move • Enter select • Esc cancel"), width)
 	view.WriteString(helpBar)
 
 	return view.String()
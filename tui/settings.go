@@ -8,6 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
 	"github.com/dopejs/opencc/tui/components"
 )
 
@@ -37,29 +38,52 @@ func NewSettingsModel() SettingsModel {
 	currentProfile := config.GetDefaultProfile()
 	currentCLI := config.GetDefaultCLI()
 	currentPort := config.GetWebPort()
+	currentPreflight := onOff(config.GetPreflightCheck())
+	currentStrictEnv := onOff(config.GetStrictEnv())
 
 	fields := []components.Field{
 		{
 			Key:     "default_cli",
-			Label:   "Default CLI",
+			Label:   i18n.T("Default CLI"),
 			Type:    components.FieldSelect,
 			Value:   currentCLI,
 			Options: config.AvailableCLIs,
 		},
 		{
 			Key:     "default_profile",
-			Label:   "Default Profile",
+			Label:   i18n.T("Default Profile"),
 			Type:    components.FieldSelect,
 			Value:   currentProfile,
 			Options: profiles,
 		},
 		{
 			Key:         "web_port",
-			Label:       "Web UI Port",
+			Label:       i18n.T("Web UI Port"),
 			Type:        components.FieldText,
 			Value:       strconv.Itoa(currentPort),
 			Placeholder: "19840",
 		},
+		{
+			Key:     "preflight_check",
+			Label:   i18n.T("Preflight Check"),
+			Type:    components.FieldSelect,
+			Value:   currentPreflight,
+			Options: []string{"off", "on"},
+		},
+		{
+			Key:     "strict_env",
+			Label:   i18n.T("Strict Env"),
+			Type:    components.FieldSelect,
+			Value:   currentStrictEnv,
+			Options: []string{"off", "on"},
+		},
+		{
+			Key:     "locale",
+			Label:   i18n.T("Language"),
+			Type:    components.FieldSelect,
+			Value:   localeOption(config.GetLocale()),
+			Options: localeOptions(),
+		},
 	}
 
 	form := components.NewForm(fields)
@@ -125,11 +149,11 @@ func (m SettingsModel) save() tea.Cmd {
 	if portStr := values["web_port"]; portStr != "" {
 		port, err := strconv.Atoi(portStr)
 		if err != nil {
-			m.err = "Invalid port number"
+			m.err = i18n.T("Invalid port number")
 			return nil
 		}
 		if port < 1024 || port > 65535 {
-			m.err = "Port must be between 1024 and 65535"
+			m.err = i18n.T("Port must be between 1024 and 65535")
 			return nil
 		}
 		if err := config.SetWebPort(port); err != nil {
@@ -138,17 +162,72 @@ func (m SettingsModel) save() tea.Cmd {
 		}
 	}
 
+	// Save preflight check
+	if preflight := values["preflight_check"]; preflight != "" {
+		if err := config.SetPreflightCheck(preflight == "on"); err != nil {
+			m.err = err.Error()
+			return nil
+		}
+	}
+
+	// Save strict env
+	if strictEnv := values["strict_env"]; strictEnv != "" {
+		if err := config.SetStrictEnv(strictEnv == "on"); err != nil {
+			m.err = err.Error()
+			return nil
+		}
+	}
+
+	// Save language and switch to it right away
+	if locale := values["locale"]; locale != "" {
+		if locale == localeAuto {
+			locale = ""
+		}
+		if err := config.SetLocale(locale); err != nil {
+			m.err = err.Error()
+			return nil
+		}
+		i18n.SetLocale(i18n.Detect(locale))
+	}
+
 	m.saved = true
 	return func() tea.Msg { return SettingsSavedMsg{} }
 }
 
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// localeAuto is the language option that follows LANG.
+const localeAuto = "auto"
+
+// localeOptions lists the selectable languages.
+func localeOptions() []string {
+	options := []string{localeAuto}
+	for _, l := range i18n.Supported {
+		options = append(options, string(l))
+	}
+	return options
+}
+
+// localeOption returns the option for a configured locale.
+func localeOption(locale string) string {
+	if l, ok := i18n.Parse(locale); ok {
+		return string(l)
+	}
+	return localeAuto
+}
+
 // View implements tea.Model.
 func (m SettingsModel) View() string {
 	// Use global layout dimensions
 	contentWidth, _, _, _ := LayoutDimensions(m.width, m.height)
 	sidePadding := 2
 
-	title := m.titleStyle.Render("Settings")
+	title := m.titleStyle.Render(i18n.T("Settings"))
 
 	formView := m.form.View()
 
@@ -170,10 +249,10 @@ func (m SettingsModel) View() string {
 
 	var status string
 	if m.saved {
-		status = m.statusStyle.Render("Settings saved!")
+		status = m.statusStyle.Render(i18n.T("Settings saved!"))
 	}
 	if m.err != "" {
-		status = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("Error: " + m.err)
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(i18n.T("Error: ") + m.err)
 	}
 
 	mainContent := fmt.Sprintf("%s\n\n%s", title, formBox)
@@ -198,7 +277,7 @@ func (m SettingsModel) View() string {
 	}
 
 	// Help bar at bottom
-	helpBar := RenderHelpBar("Tab next • Shift+Tab prev • Ctrl+S save • Esc back", m.width)
+	helpBar := RenderHelpBar(i18n.T("Tab next • Shift+Tab prev • Ctrl+S save • Esc back"), m.width)
 	view.WriteString(helpBar)
 
 	return view.String()
@@ -210,6 +289,9 @@ func (m *SettingsModel) Refresh() {
 	m.form.SetValue("default_cli", config.GetDefaultCLI())
 	m.form.SetValue("default_profile", config.GetDefaultProfile())
 	m.form.SetValue("web_port", strconv.Itoa(config.GetWebPort()))
+	m.form.SetValue("preflight_check", onOff(config.GetPreflightCheck()))
+	m.form.SetValue("strict_env", onOff(config.GetStrictEnv()))
+	m.form.SetValue("locale", localeOption(config.GetLocale()))
 	m.saved = false
 	m.err = ""
 }
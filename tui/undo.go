@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+
+	"github.com/dopejs/opencc/internal/config"
+	"github.com/dopejs/opencc/internal/i18n"
+)
+
+// undoEntry is a config change made in this session that can be undone by
+// restoring the backup taken right before it.
+type undoEntry struct {
+	backup string            // backup ID
+	reason string            // e.g. "delete provider work"
+	after  [sha256.Size]byte // the config file right after the change
+}
+
+// undoStack holds the undoable changes of a config TUI session, newest last.
+type undoStack []undoEntry
+
+// configSum fingerprints the config file on disk.
+func configSum() [sha256.Size]byte {
+	data, _ := os.ReadFile(config.ConfigFilePath())
+	return sha256.Sum256(data)
+}
+
+// record pushes the change described by reason, which has just been made,
+// if the newest backup is the one taken for it.
+func (s *undoStack) record(reason string) {
+	backups, err := config.ListBackups()
+	if err != nil || len(backups) == 0 || backups[0].Reason != reason {
+		return
+	}
+	*s = append(*s, undoEntry{backup: backups[0].ID, reason: reason, after: configSum()})
+}
+
+// undo reverts the newest change and returns its description. Restoring a
+// backup replaces the whole file, so a change is only undone while the
+// config still looks the way it left it; other edits would be lost otherwise.
+func (s *undoStack) undo() (string, error) {
+	if len(*s) == 0 {
+		return "", errors.New(i18n.T("nothing to undo"))
+	}
+	e := (*s)[len(*s)-1]
+	if configSum() != e.after {
+		*s = nil
+		return "", errors.New(i18n.T("the config changed since; restore with 'opencc config backups' instead"))
+	}
+	if err := config.RestoreBackup(e.backup); err != nil {
+		return "", err
+	}
+	*s = (*s)[:len(*s)-1]
+	return e.reason, nil
+}